@@ -0,0 +1,452 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	v := new(VersionInfo)
+	return v, c.do(ctx, request{method: http.MethodGet, path: "/version"}, v)
+}
+
+// Login exchanges an account number and password for a JWT, which the client
+// sends with every account request from then on.
+func (c *Client) Login(ctx context.Context, number int64, password string) (*LoginResponse, error) {
+	res := new(LoginResponse)
+	body := map[string]any{"number": number, "password": password}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/login", body: body}, res); err != nil {
+		return nil, err
+	}
+	c.setToken(res.Token)
+	return res, nil
+}
+
+func (c *Client) CreateAccount(ctx context.Context, req CreateAccountRequest) (*Account, error) {
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodPost, path: "/account", body: req}, account)
+}
+
+// LookupAccount tells whether an account number exists and whose it is,
+// masked, to confirm the recipient before a Transfer.
+func (c *Client) LookupAccount(ctx context.Context, number int64) (*AccountLookup, error) {
+	res := new(AccountLookup)
+	query := url.Values{"number": {strconv.FormatInt(number, 10)}}
+	return res, c.do(ctx, request{method: http.MethodGet, path: "/account/lookup", query: query}, res)
+}
+
+func (c *Client) GetAccount(ctx context.Context, id int) (*Account, error) {
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, ""), auth: authAccount}, account)
+}
+
+// UpdateAccount patches the account. A version other than 0 is sent as
+// If-Match, so the update fails with 412 if someone else changed the account
+// in the meantime.
+func (c *Client) UpdateAccount(ctx context.Context, id, version int, req UpdateAccountRequest) (*Account, error) {
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodPatch, path: accountPath(id, ""), auth: authAccount, body: req, header: ifMatch(version)}, account)
+}
+
+// DeleteAccount deletes the account, guarded by If-Match like UpdateAccount.
+func (c *Client) DeleteAccount(ctx context.Context, id, version int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, ""), auth: authAccount, header: ifMatch(version)}, nil)
+}
+
+// UploadAvatar sets the account's picture from a PNG, JPEG or GIF, which the
+// server scales down to fit 256 pixels.
+func (c *Client) UploadAvatar(ctx context.Context, id int, contentType string, data []byte) (*Account, error) {
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodPut, path: accountPath(id, "/avatar"), body: data, contentType: contentType, auth: authAccount}, account)
+}
+
+func (c *Client) DeleteAvatar(ctx context.Context, id int) (*Account, error) {
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, "/avatar"), auth: authAccount}, account)
+}
+
+// SendPhoneCode texts a code to the account's phone, to pass to
+// ConfirmPhone. Asking again within a minute fails with rate_limited.
+func (c *Client) SendPhoneCode(ctx context.Context, id int) (*PhoneVerification, error) {
+	res := new(PhoneVerification)
+	return res, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/phone/verification"), auth: authAccount}, res)
+}
+
+// ConfirmPhone verifies the account's phone, which SMS alerts need.
+func (c *Client) ConfirmPhone(ctx context.Context, id int, code string) (*Account, error) {
+	account := new(Account)
+	body := map[string]string{"code": code}
+	return account, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/phone/verification/confirm"), auth: authAccount, body: body}, account)
+}
+
+// Avatar fetches a PNG avatar by the path in Account.Avatar or
+// AccountLookup.Avatar.
+func (c *Client) Avatar(ctx context.Context, path string) (io.ReadCloser, error) {
+	return c.stream(ctx, request{method: http.MethodGet, path: path})
+}
+
+// DailyTotals returns credits and debits per day for the last days days, 0
+// for the server default. tz overrides the account's time zone when set.
+func (c *Client) DailyTotals(ctx context.Context, id, days int, tz string) ([]*DailyTotal, error) {
+	q := url.Values{}
+	if days > 0 {
+		q.Set("days", strconv.Itoa(days))
+	}
+	if tz != "" {
+		q.Set("tz", tz)
+	}
+	var totals []*DailyTotal
+	return totals, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/totals"), query: q, auth: authAccount}, &totals)
+}
+
+// MonthlyTotals returns credits and debits per month of the account's time
+// zone for the last months months, 0 for the server default.
+func (c *Client) MonthlyTotals(ctx context.Context, id, months int) ([]*MonthlyTotal, error) {
+	q := url.Values{}
+	if months > 0 {
+		q.Set("months", strconv.Itoa(months))
+	}
+	var totals []*MonthlyTotal
+	return totals, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/totals/monthly"), query: q, auth: authAccount}, &totals)
+}
+
+func (c *Client) Summary(ctx context.Context, id int) (*AccountSummary, error) {
+	summary := new(AccountSummary)
+	return summary, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/summary"), auth: authAccount}, summary)
+}
+
+// ListTransactions returns a page of the account's transactions, newest first.
+func (c *Client) ListTransactions(ctx context.Context, id int, cursor string, limit int) (*Page[*Transaction], error) {
+	page := new(Page[*Transaction])
+	return page, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/transactions"), query: pageQuery(cursor, limit), auth: authAccount}, page)
+}
+
+// Activity returns a page of the account's timeline, newest first. types
+// narrows it to some of the Activity kinds, none shows them all.
+func (c *Client) Activity(ctx context.Context, id int, cursor string, limit int, types ...string) (*Page[*Activity], error) {
+	page := new(Page[*Activity])
+	q := pageQuery(cursor, limit)
+	if len(types) > 0 {
+		q.Set("type", strings.Join(types, ","))
+	}
+	return page, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/activity"), query: q, auth: authAccount}, page)
+}
+
+// TransactionFeed returns the transactions after cursor, oldest first. With
+// nothing new it waits up to wait for a transaction to arrive; pass the
+// returned cursor to the next call.
+func (c *Client) TransactionFeed(ctx context.Context, id int, cursor string, wait time.Duration) (*FeedPage, error) {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if wait > 0 {
+		q.Set("wait", strconv.Itoa(int(wait/time.Second)))
+	}
+	page := new(FeedPage)
+	return page, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/transactions/feed"), query: q, auth: authAccount}, page)
+}
+
+// ImportTransactions imports a text/csv or application/x-ofx statement of
+// another bank as history, which doesn't move the balance. Nothing is imported if a row is invalid; the result lists the rows then,
+// next to an *APIError with status 422.
+func (c *Client) ImportTransactions(ctx context.Context, id int, contentType string, data []byte) (*ImportResult, error) {
+	result := new(ImportResult)
+	err := c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/transactions/import"), body: data, contentType: contentType, auth: authAccount}, result)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		json.Unmarshal(apiErr.body, result)
+	}
+	return result, err
+}
+
+// ExportOptions picks the format (csv, ofx, qif, ndjson or mt940) and the days to
+// export, To being exclusive. Zero values leave the choice to the server.
+type ExportOptions struct {
+	Format string
+	From   time.Time
+	To     time.Time
+}
+
+// ExportTransactions streams the statement, the caller closes it.
+func (c *Client) ExportTransactions(ctx context.Context, id int, opts ExportOptions) (io.ReadCloser, error) {
+	q := url.Values{}
+	if opts.Format != "" {
+		q.Set("format", opts.Format)
+	}
+	setDay(q, "from", opts.From)
+	setDay(q, "to", opts.To)
+	return c.stream(ctx, request{method: http.MethodGet, path: accountPath(id, "/transactions/export"), query: q, auth: authAccount})
+}
+
+// ExportFDX returns the account and its transactions from from to to, days
+// in the account's time zone, in the FDX format. Zero days leave the range
+// open.
+func (c *Client) ExportFDX(ctx context.Context, id int, from, to time.Time) (*FDXDocument, error) {
+	q := url.Values{}
+	setDay(q, "from", from)
+	setDay(q, "to", to)
+	doc := new(FDXDocument)
+	return doc, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/fdx"), query: q, auth: authAccount}, doc)
+}
+
+// ListStatements returns the days, oldest first, there's a camt.053 end of
+// day statement of.
+func (c *Client) ListStatements(ctx context.Context, id int) ([]string, error) {
+	var days []string
+	return days, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/statements"), auth: authAccount}, &days)
+}
+
+// Statement streams the camt.053 statement of day, the caller closes it.
+func (c *Client) Statement(ctx context.Context, id int, day time.Time) (io.ReadCloser, error) {
+	return c.stream(ctx, request{method: http.MethodGet, path: accountPath(id, "/statements/"+day.Format("2006-01-02")), auth: authAccount})
+}
+
+// TextStatement is the statement of a day as text in the account's language
+// and locale, such as de-CH, set with UpdateAccountRequest.Locale.
+func (c *Client) TextStatement(ctx context.Context, id int, day time.Time) (io.ReadCloser, error) {
+	q := url.Values{"format": {"text"}}
+	return c.stream(ctx, request{method: http.MethodGet, path: accountPath(id, "/statements/"+day.Format("2006-01-02")), query: q, auth: authAccount})
+}
+
+// Usage reports the account's API calls of the last days days, 0 for the
+// server default.
+func (c *Client) Usage(ctx context.Context, id, days int) (*UsageReport, error) {
+	q := url.Values{}
+	if days > 0 {
+		q.Set("days", strconv.Itoa(days))
+	}
+	report := new(UsageReport)
+	return report, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/usage"), query: q, auth: authAccount}, report)
+}
+
+func (c *Client) ListApiKeys(ctx context.Context, id int) ([]*ApiKey, error) {
+	var keys []*ApiKey
+	return keys, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/api-keys"), auth: authAccount}, &keys)
+}
+
+// CreateApiKey returns the new key with its secret, which is never shown again.
+func (c *Client) CreateApiKey(ctx context.Context, id int, name string) (*ApiKey, error) {
+	key := new(ApiKey)
+	body := map[string]string{"name": name}
+	return key, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/api-keys"), body: body, auth: authAccount}, key)
+}
+
+// CreateThirdPartyApiKey creates a key to hand to another provider. It only
+// reaches the account data the holder grants it a consent for.
+func (c *Client) CreateThirdPartyApiKey(ctx context.Context, id int, name string) (*ApiKey, error) {
+	key := new(ApiKey)
+	body := map[string]any{"name": name, "thirdParty": true}
+	return key, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/api-keys"), body: body, auth: authAccount}, key)
+}
+
+func (c *Client) RevokeApiKey(ctx context.Context, id int, keyID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, "/api-keys/"+url.PathEscape(keyID)), auth: authAccount}, nil)
+}
+
+func (c *Client) TermsStatus(ctx context.Context, id int) (*TermsStatus, error) {
+	status := new(TermsStatus)
+	return status, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/terms"), auth: authAccount}, status)
+}
+
+// AcceptTerms accepts the documents at the versions the holder was shown,
+// as returned by Terms. It fails with terms_outdated if one changed since.
+func (c *Client) AcceptTerms(ctx context.Context, id int, shown map[string]string) (*TermsStatus, error) {
+	status := new(TermsStatus)
+	return status, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/terms/accept"), body: shown, auth: authAccount}, status)
+}
+
+// ListImpersonations returns support's requests to read the account, newest
+// first.
+func (c *Client) ListImpersonations(ctx context.Context, id int) ([]*Impersonation, error) {
+	var imps []*Impersonation
+	return imps, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/impersonations"), auth: authAccount}, &imps)
+}
+
+func (c *Client) ApproveImpersonation(ctx context.Context, id int, impersonationID string) (*Impersonation, error) {
+	imp := new(Impersonation)
+	return imp, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/impersonations/"+url.PathEscape(impersonationID)+"/approve"), auth: authAccount}, imp)
+}
+
+func (c *Client) DenyImpersonation(ctx context.Context, id int, impersonationID string) (*Impersonation, error) {
+	imp := new(Impersonation)
+	return imp, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/impersonations/"+url.PathEscape(impersonationID)+"/deny"), auth: authAccount}, imp)
+}
+
+func (c *Client) ListConsents(ctx context.Context, id int) ([]*Consent, error) {
+	var consents []*Consent
+	return consents, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/consents"), auth: authAccount}, &consents)
+}
+
+// GrantConsent lets the third-party key req.ApiKeyID use the account within
+// req.Scopes.
+func (c *Client) GrantConsent(ctx context.Context, id int, req GrantConsentRequest) (*Consent, error) {
+	consent := new(Consent)
+	return consent, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/consents"), body: req, auth: authAccount}, consent)
+}
+
+func (c *Client) RevokeConsent(ctx context.Context, id int, consentID string) (*Consent, error) {
+	consent := new(Consent)
+	return consent, c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, "/consents/"+url.PathEscape(consentID)), auth: authAccount}, consent)
+}
+
+// Transfer sends amount from the logged in account to the account with the
+// given number. An empty currency means the sender's.
+func (c *Client) Transfer(ctx context.Context, toNumber int64, amount Money) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{"toAccount": toNumber, "amount": amount}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, auth: authAccount}, t)
+}
+
+// TransferAgain makes a transfer the server refused with duplicate_transfer,
+// as the same amount went to the same account moments ago, once the holder
+// confirmed it's meant.
+func (c *Client) TransferAgain(ctx context.Context, toNumber int64, amount Money) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{"toAccount": toNumber, "amount": amount, "allowDuplicate": true}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, auth: authAccount}, t)
+}
+
+// TransferToIBAN transfers amount to the account of an IBAN, in print or
+// electronic form.
+func (c *Client) TransferToIBAN(ctx context.Context, iban string, amount Money) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{"toIban": iban, "amount": amount}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, auth: authAccount}, t)
+}
+
+// TransferToAlias transfers amount to the account of an email address or
+// phone number.
+func (c *Client) TransferToAlias(ctx context.Context, alias string, amount Money) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{"toAlias": alias, "amount": amount}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, auth: authAccount}, t)
+}
+
+// TransferAsync hands the transfer to the server to make in the background,
+// poll GetTransferStatus for how it went.
+func (c *Client) TransferAsync(ctx context.Context, toNumber int64, amount Money) (*TransferStatus, error) {
+	status := new(TransferStatus)
+	body := map[string]any{"toAccount": toNumber, "amount": amount}
+	header := http.Header{"Prefer": {"respond-async"}}
+	return status, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, header: header, auth: authAccount}, status)
+}
+
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*TransferStatus, error) {
+	status := new(TransferStatus)
+	return status, c.do(ctx, request{method: http.MethodGet, path: "/transfer/" + url.PathEscape(id), auth: authAccount}, status)
+}
+
+// QuoteTransfer checks a transfer without making it and returns its terms.
+func (c *Client) QuoteTransfer(ctx context.Context, toNumber int64, amount Money) (*TransferQuote, error) {
+	q := new(TransferQuote)
+	body := map[string]any{"toAccount": toNumber, "amount": amount}
+	return q, c.do(ctx, request{method: http.MethodPost, path: "/transfer/quote", body: body, auth: authAccount}, q)
+}
+
+// ExecuteQuote makes the quoted transfer. It fails with quote_expired once
+// the quote has expired or was executed.
+func (c *Client) ExecuteQuote(ctx context.Context, quoteID string) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]string{"quoteId": quoteID}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/transfer", body: body, auth: authAccount}, t)
+}
+
+// TransferBatch makes the transfers, mode is "atomic" for all or nothing or
+// "best_effort" to make each that can be made. A failed atomic batch comes
+// with an *APIError with status 422; the response names the failed transfer.
+func (c *Client) TransferBatch(ctx context.Context, mode string, transfers []TransferOrder) (*BatchTransferResponse, error) {
+	resp := new(BatchTransferResponse)
+	body := map[string]any{"mode": mode, "transfers": transfers}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/transfers/batch", body: body, auth: authAccount}, resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		json.Unmarshal(apiErr.body, resp)
+	}
+	return resp, err
+}
+
+// AuthorizeTransfer reserves amount for a transfer to toNumber, to capture
+// or void later.
+func (c *Client) AuthorizeTransfer(ctx context.Context, toNumber int64, amount Money) (*TransferHold, error) {
+	hold := new(TransferHold)
+	body := map[string]any{"toAccount": toNumber, "amount": amount}
+	return hold, c.do(ctx, request{method: http.MethodPost, path: "/transfer/authorize", body: body, auth: authAccount}, hold)
+}
+
+func (c *Client) GetTransferHold(ctx context.Context, holdID string) (*TransferHold, error) {
+	hold := new(TransferHold)
+	return hold, c.do(ctx, request{method: http.MethodGet, path: holdPath(holdID, ""), auth: authAccount}, hold)
+}
+
+// CaptureTransfer makes the authorized transfer for amount, at most what was
+// authorized. A nil amount captures the whole hold.
+func (c *Client) CaptureTransfer(ctx context.Context, holdID string, amount *Money) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{}
+	if amount != nil {
+		body["amount"] = amount
+	}
+	return t, c.do(ctx, request{method: http.MethodPost, path: holdPath(holdID, "/capture"), body: body, auth: authAccount}, t)
+}
+
+func (c *Client) VoidTransfer(ctx context.Context, holdID string) (*TransferHold, error) {
+	hold := new(TransferHold)
+	return hold, c.do(ctx, request{method: http.MethodPost, path: holdPath(holdID, "/void"), auth: authAccount}, hold)
+}
+
+// SandboxTopUp credits the account out of thin air. Only sandbox servers
+// have the endpoint.
+func (c *Client) SandboxTopUp(ctx context.Context, id int, amount Money, description string) (*Transaction, error) {
+	t := new(Transaction)
+	body := map[string]any{"amount": amount, "description": description}
+	return t, c.do(ctx, request{method: http.MethodPost, path: "/sandbox" + accountPath(id, "/topup"), body: body, auth: authAccount}, t)
+}
+
+// stream sends req and hands back the body of a 2xx answer.
+func (c *Client) stream(ctx context.Context, req request) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func accountPath(id int, suffix string) string {
+	return "/account/" + strconv.Itoa(id) + suffix
+}
+
+func holdPath(holdID, suffix string) string {
+	return "/transfer/holds/" + url.PathEscape(holdID) + suffix
+}
+
+func pageQuery(cursor string, limit int) url.Values {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	return q
+}
+
+func setDay(q url.Values, name string, day time.Time) {
+	if !day.IsZero() {
+		q.Set(name, day.Format("2006-01-02"))
+	}
+}
+
+func ifMatch(version int) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
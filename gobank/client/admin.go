@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The Admin* methods need WithAdminToken. Those taking a tenant slug use the
+// default tenant when it's empty.
+
+func (c *Client) AdminListTenants(ctx context.Context) ([]*Tenant, error) {
+	var tenants []*Tenant
+	return tenants, c.do(ctx, request{method: http.MethodGet, path: "/admin/tenants", auth: authAdmin}, &tenants)
+}
+
+func (c *Client) AdminCreateTenant(ctx context.Context, slug, name string) (*Tenant, error) {
+	tenant := new(Tenant)
+	body := map[string]string{"slug": slug, "name": name}
+	return tenant, c.do(ctx, request{method: http.MethodPost, path: "/admin/tenants", body: body, auth: authAdmin}, tenant)
+}
+
+func (c *Client) AdminTenantSettings(ctx context.Context, tenantID int) (*TenantSettings, error) {
+	settings := new(TenantSettings)
+	return settings, c.do(ctx, request{method: http.MethodGet, path: tenantSettingsPath(tenantID), auth: authAdmin}, settings)
+}
+
+func (c *Client) AdminUpdateTenantSettings(ctx context.Context, tenantID int, settings TenantSettings) (*TenantSettings, error) {
+	updated := new(TenantSettings)
+	return updated, c.do(ctx, request{method: http.MethodPut, path: tenantSettingsPath(tenantID), body: settings, auth: authAdmin}, updated)
+}
+
+func (c *Client) AdminMaintenance(ctx context.Context) (*MaintenanceState, error) {
+	state := new(MaintenanceState)
+	return state, c.do(ctx, request{method: http.MethodGet, path: "/admin/maintenance", auth: authAdmin}, state)
+}
+
+func (c *Client) AdminSetMaintenance(ctx context.Context, state MaintenanceState) (*MaintenanceState, error) {
+	updated := new(MaintenanceState)
+	return updated, c.do(ctx, request{method: http.MethodPut, path: "/admin/maintenance", body: state, auth: authAdmin}, updated)
+}
+
+// AdminChaos and AdminSetChaos only exist on sandbox servers.
+func (c *Client) AdminChaos(ctx context.Context) (*ChaosState, error) {
+	state := new(ChaosState)
+	return state, c.do(ctx, request{method: http.MethodGet, path: "/admin/chaos", auth: authAdmin}, state)
+}
+
+func (c *Client) AdminSetChaos(ctx context.Context, state ChaosState) (*ChaosState, error) {
+	updated := new(ChaosState)
+	return updated, c.do(ctx, request{method: http.MethodPut, path: "/admin/chaos", body: state, auth: authAdmin}, updated)
+}
+
+// AdminClock and AdminAdvanceClock only exist on sandbox servers.
+func (c *Client) AdminClock(ctx context.Context) (*ClockState, error) {
+	state := new(ClockState)
+	return state, c.do(ctx, request{method: http.MethodGet, path: "/admin/clock", auth: authAdmin}, state)
+}
+
+// AdminAdvanceClock moves the simulated business clock forward by days plus by.
+func (c *Client) AdminAdvanceClock(ctx context.Context, days int, by time.Duration) (*ClockState, error) {
+	body := map[string]any{"days": days}
+	if by != 0 {
+		body["by"] = by.String()
+	}
+	state := new(ClockState)
+	return state, c.do(ctx, request{method: http.MethodPost, path: "/admin/clock", body: body, auth: authAdmin}, state)
+}
+
+func (c *Client) AdminReloadConfig(ctx context.Context) (*RuntimeConfig, error) {
+	cfg := new(RuntimeConfig)
+	return cfg, c.do(ctx, request{method: http.MethodPost, path: "/admin/config/reload", auth: authAdmin}, cfg)
+}
+
+// AdminListJobs lists the jobs with the given status, all of them when it's
+// empty.
+func (c *Client) AdminListJobs(ctx context.Context, status string) ([]*Job, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var jobs []*Job
+	return jobs, c.do(ctx, request{method: http.MethodGet, path: "/admin/jobs", query: q, auth: authAdmin}, &jobs)
+}
+
+func (c *Client) AdminRetryJob(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/jobs/" + strconv.Itoa(id) + "/retry", auth: authAdmin}, nil)
+}
+
+// AdminDailyReport returns the tenant's daily figures for [from, to), UTC days.
+func (c *Client) AdminDailyReport(ctx context.Context, tenant string, from, to time.Time) ([]*DailyReportRow, error) {
+	q := tenantQuery(tenant)
+	setDay(q, "from", from)
+	setDay(q, "to", to)
+	var rows []*DailyReportRow
+	return rows, c.do(ctx, request{method: http.MethodGet, path: "/admin/reports/daily", query: q, auth: authAdmin}, &rows)
+}
+
+// AdminLargeTransactionReports lists the tenant's large-transaction reports,
+// oldest first.
+func (c *Client) AdminLargeTransactionReports(ctx context.Context, tenant string) ([]*LargeTransactionReport, error) {
+	var reports []*LargeTransactionReport
+	return reports, c.do(ctx, request{method: http.MethodGet, path: "/admin/reports/large-transactions", query: tenantQuery(tenant), auth: authAdmin}, &reports)
+}
+
+// AdminLargeTransactionReport streams the report named name, the caller
+// closes it.
+func (c *Client) AdminLargeTransactionReport(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.stream(ctx, request{method: http.MethodGet, path: "/admin/reports/large-transactions/" + url.PathEscape(name), auth: authAdmin})
+}
+
+// AdminAccountingExports lists the tenant's accounting exports.
+func (c *Client) AdminAccountingExports(ctx context.Context, tenant string) ([]*AccountingExport, error) {
+	var exports []*AccountingExport
+	return exports, c.do(ctx, request{method: http.MethodGet, path: "/admin/reports/accounting", query: tenantQuery(tenant), auth: authAdmin}, &exports)
+}
+
+// AdminQueueAccountingExport has the journals of a period exported. The
+// export can be downloaded by its name once it is written.
+func (c *Client) AdminQueueAccountingExport(ctx context.Context, tenant string, req AccountingExportRequest) (*AccountingExport, error) {
+	export := new(AccountingExport)
+	return export, c.do(ctx, request{method: http.MethodPost, path: "/admin/reports/accounting", query: tenantQuery(tenant), body: req, auth: authAdmin}, export)
+}
+
+// AdminAccountingExport streams the export named name, the caller closes
+// it. One that isn't written yet is an *APIError with status 404.
+func (c *Client) AdminAccountingExport(ctx context.Context, tenant, name string) (io.ReadCloser, error) {
+	return c.stream(ctx, request{method: http.MethodGet, path: "/admin/reports/accounting/" + url.PathEscape(name), query: tenantQuery(tenant), auth: authAdmin})
+}
+
+// AdminBalanceReport returns the tenant's ledger balances per currency.
+func (c *Client) AdminBalanceReport(ctx context.Context, tenant string) ([]*BalanceTotal, error) {
+	var totals []*BalanceTotal
+	return totals, c.do(ctx, request{method: http.MethodGet, path: "/admin/reports/balances", query: tenantQuery(tenant), auth: authAdmin}, &totals)
+}
+
+func (c *Client) AdminProjections(ctx context.Context) ([]*Projection, error) {
+	var projections []*Projection
+	return projections, c.do(ctx, request{method: http.MethodGet, path: "/admin/projections", auth: authAdmin}, &projections)
+}
+
+// AdminRebuildProjection empties the projection named name, which is then
+// built again from the ledger in the background.
+func (c *Client) AdminRebuildProjection(ctx context.Context, name string) (*Projection, error) {
+	projection := new(Projection)
+	return projection, c.do(ctx, request{method: http.MethodPost, path: "/admin/projections/" + url.PathEscape(name) + "/rebuild", auth: authAdmin}, projection)
+}
+
+// AdminReconciliationIssues lists open or resolved issues, all of them when
+// status is empty.
+func (c *Client) AdminReconciliationIssues(ctx context.Context, status string) ([]*ReconciliationIssue, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var issues []*ReconciliationIssue
+	return issues, c.do(ctx, request{method: http.MethodGet, path: "/admin/reconciliation/issues", query: q, auth: authAdmin}, &issues)
+}
+
+func (c *Client) AdminResolveReconciliationIssue(ctx context.Context, id int, resolution string) error {
+	body := map[string]string{"resolution": resolution}
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/reconciliation/issues/" + strconv.Itoa(id) + "/resolve", body: body, auth: authAdmin}, nil)
+}
+
+// AdminListEvents returns a page of the audit trail, newest first.
+func (c *Client) AdminListEvents(ctx context.Context, cursor string, limit int) (*Page[*Event], error) {
+	page := new(Page[*Event])
+	return page, c.do(ctx, request{method: http.MethodGet, path: "/admin/events", query: pageQuery(cursor, limit), auth: authAdmin}, page)
+}
+
+// AdminStuckSagas lists the sagas whose compensation failed or that have been
+// running for too long.
+func (c *Client) AdminStuckSagas(ctx context.Context) ([]*Saga, error) {
+	var sagas []*Saga
+	return sagas, c.do(ctx, request{method: http.MethodGet, path: "/admin/sagas/stuck", auth: authAdmin}, &sagas)
+}
+
+// AccountFilter narrows AdminExportAccounts to accounts created in [From, To)
+// with the given currency. Zero values don't filter.
+type AccountFilter struct {
+	From     time.Time
+	To       time.Time
+	Currency string
+}
+
+// AdminListAccounts returns a page of the tenant's accounts, cursor is empty
+// for the first one.
+func (c *Client) AdminListAccounts(ctx context.Context, tenant, cursor string, limit int) (*Page[*Account], error) {
+	q := pageQuery(cursor, limit)
+	if tenant != "" {
+		q.Set("tenant", tenant)
+	}
+	page := new(Page[*Account])
+	return page, c.do(ctx, request{method: http.MethodGet, path: "/admin/accounts", query: q, auth: authAdmin}, page)
+}
+
+// AdminExportAccounts streams the tenant's accounts as CSV, the caller closes it.
+func (c *Client) AdminExportAccounts(ctx context.Context, tenant string, filter AccountFilter) (io.ReadCloser, error) {
+	q := tenantQuery(tenant)
+	setDay(q, "from", filter.From)
+	setDay(q, "to", filter.To)
+	if filter.Currency != "" {
+		q.Set("currency", filter.Currency)
+	}
+	return c.stream(ctx, request{method: http.MethodGet, path: "/admin/accounts/export", query: q, auth: authAdmin})
+}
+
+// AdminExportPortable streams the portable JSON export of one account, or
+// of all the tenant's accounts when number is 0.
+func (c *Client) AdminExportPortable(ctx context.Context, tenant string, number int64) (io.ReadCloser, error) {
+	q := tenantQuery(tenant)
+	if number != 0 {
+		q.Set("account", strconv.FormatInt(number, 10))
+	}
+	return c.stream(ctx, request{method: http.MethodGet, path: "/admin/accounts/portable", query: q, auth: authAdmin})
+}
+
+// AdminImportPortable imports a portable export into the tenant and returns
+// the number of accounts created.
+func (c *Client) AdminImportPortable(ctx context.Context, tenant string, export []byte) (int, error) {
+	var res struct {
+		Imported int `json:"imported"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/accounts/portable", query: tenantQuery(tenant), body: export, contentType: "application/json", auth: authAdmin}, &res)
+	return res.Imported, err
+}
+
+// AdminIngestPain001 hands the credit transfers of an ISO 20022 pain.001
+// file to the tenant's accounts and returns the pain.002 status report. A
+// file with nothing accepted is an *APIError with status 422 carrying the
+// report as its Message.
+func (c *Client) AdminIngestPain001(ctx context.Context, tenant string, file []byte) (io.ReadCloser, error) {
+	return c.stream(ctx, request{method: http.MethodPost, path: "/admin/payments/pain001", query: tenantQuery(tenant), body: file, contentType: "application/xml", auth: authAdmin})
+}
+
+// AdminBatchFiles lists the latest payment batch files the intake picked
+// up for the tenant, newest first.
+func (c *Client) AdminBatchFiles(ctx context.Context, tenant string) ([]*BatchFile, error) {
+	var files []*BatchFile
+	return files, c.do(ctx, request{method: http.MethodGet, path: "/admin/payments/batch-files", query: tenantQuery(tenant), auth: authAdmin}, &files)
+}
+
+// AdminTransfersInReview lists the tenant's transfers screening held,
+// oldest first.
+func (c *Client) AdminTransfersInReview(ctx context.Context, tenant string) ([]*TransferReview, error) {
+	var reviews []*TransferReview
+	return reviews, c.do(ctx, request{method: http.MethodGet, path: "/admin/transfers/review", query: tenantQuery(tenant), auth: authAdmin}, &reviews)
+}
+
+// AdminReleaseTransfer has a held transfer made in the background, it isn't
+// screened again.
+func (c *Client) AdminReleaseTransfer(ctx context.Context, tenant, id string) (*TransferStatus, error) {
+	status := new(TransferStatus)
+	return status, c.do(ctx, request{method: http.MethodPost, path: "/admin/transfers/" + id + "/release", query: tenantQuery(tenant), auth: authAdmin}, status)
+}
+
+func (c *Client) AdminRejectTransfer(ctx context.Context, tenant, id string) (*TransferStatus, error) {
+	status := new(TransferStatus)
+	return status, c.do(ctx, request{method: http.MethodPost, path: "/admin/transfers/" + id + "/reject", query: tenantQuery(tenant), auth: authAdmin}, status)
+}
+
+func (c *Client) AdminVerifyLedger(ctx context.Context, tenant string, accountID int) (*LedgerVerification, error) {
+	v := new(LedgerVerification)
+	return v, c.do(ctx, request{method: http.MethodGet, path: "/admin" + accountPath(accountID, "/ledger/verify"), query: tenantQuery(tenant), auth: authAdmin}, v)
+}
+
+// AdminAccountHistory returns up to 100 events of the account's stream
+// after version after, oldest first.
+func (c *Client) AdminAccountHistory(ctx context.Context, tenant string, accountID, after int) ([]*AccountEvent, error) {
+	q := tenantQuery(tenant)
+	if after > 0 {
+		q.Set("after", strconv.Itoa(after))
+	}
+	var events []*AccountEvent
+	return events, c.do(ctx, request{method: http.MethodGet, path: "/admin" + accountPath(accountID, "/history"), query: q, auth: authAdmin}, &events)
+}
+
+// AdminAccountState returns the account as it was at the time at, replayed
+// from its event stream, as it is now when at is zero.
+func (c *Client) AdminAccountState(ctx context.Context, tenant string, accountID int, at time.Time) (*Account, error) {
+	q := tenantQuery(tenant)
+	if !at.IsZero() {
+		q.Set("at", at.UTC().Format(time.RFC3339))
+	}
+	account := new(Account)
+	return account, c.do(ctx, request{method: http.MethodGet, path: "/admin" + accountPath(accountID, "/state"), query: q, auth: authAdmin}, account)
+}
+
+// ImpersonateRequest asks for read-only access to an account. Minutes 0 is
+// the server default.
+type ImpersonateRequest struct {
+	RequestedBy     string `json:"requestedBy"`
+	Reason          string `json:"reason"`
+	Minutes         int    `json:"minutes,omitempty"`
+	RequireApproval bool   `json:"requireApproval"`
+}
+
+// AdminImpersonate returns the impersonation with its token, unless the
+// account holder has to approve it first. Then AdminImpersonationToken gets
+// the token once they have.
+func (c *Client) AdminImpersonate(ctx context.Context, tenant string, accountID int, req ImpersonateRequest) (*Impersonation, error) {
+	imp := new(Impersonation)
+	return imp, c.do(ctx, request{method: http.MethodPost, path: "/admin" + accountPath(accountID, "/impersonations"), query: tenantQuery(tenant), body: req, auth: authAdmin}, imp)
+}
+
+func (c *Client) AdminImpersonationToken(ctx context.Context, tenant, id string) (*Impersonation, error) {
+	imp := new(Impersonation)
+	return imp, c.do(ctx, request{method: http.MethodPost, path: "/admin/impersonations/" + url.PathEscape(id) + "/token", query: tenantQuery(tenant), auth: authAdmin}, imp)
+}
+
+func (c *Client) AdminRevokeImpersonation(ctx context.Context, tenant, id string) (*Impersonation, error) {
+	imp := new(Impersonation)
+	return imp, c.do(ctx, request{method: http.MethodPost, path: "/admin/impersonations/" + url.PathEscape(id) + "/revoke", query: tenantQuery(tenant), auth: authAdmin}, imp)
+}
+
+// AdminEmailSuppressions lists the tenant's addresses no emails are sent
+// to, newest first.
+func (c *Client) AdminEmailSuppressions(ctx context.Context, tenant string) ([]*EmailSuppression, error) {
+	var suppressions []*EmailSuppression
+	return suppressions, c.do(ctx, request{method: http.MethodGet, path: "/admin/email/suppressions", query: tenantQuery(tenant), auth: authAdmin}, &suppressions)
+}
+
+func (c *Client) AdminSuppressEmail(ctx context.Context, tenant, address string) (*EmailSuppression, error) {
+	sup := new(EmailSuppression)
+	body := map[string]string{"address": address}
+	return sup, c.do(ctx, request{method: http.MethodPost, path: "/admin/email/suppressions", query: tenantQuery(tenant), body: body, auth: authAdmin}, sup)
+}
+
+func (c *Client) AdminDeleteEmailSuppression(ctx context.Context, tenant, address string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/email/suppressions/" + url.PathEscape(address), query: tenantQuery(tenant), auth: authAdmin}, nil)
+}
+
+func tenantSettingsPath(tenantID int) string {
+	return "/admin/tenants/" + strconv.Itoa(tenantID) + "/settings"
+}
+
+func tenantQuery(tenant string) url.Values {
+	q := url.Values{}
+	if tenant != "" {
+		q.Set("tenant", tenant)
+	}
+	return q
+}
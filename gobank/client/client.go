@@ -0,0 +1,319 @@
+// Package client is a typed Go client for the gobank API.
+//
+// Account endpoints authenticate with the JWT from Login or with a signed API
+// key, operator endpoints with the admin token:
+//
+//	c := client.New("https://bank.example.com", client.WithTenant("acme"))
+//	if _, err := c.Login(ctx, 1234567, "hunter888"); err != nil { ... }
+//	acc, err := c.GetAccount(ctx, 1)
+//
+// Safe requests are retried on network errors and 429/502/503/504. POSTs carry
+// an Idempotency-Key that stays the same across retries, but since the server
+// doesn't deduplicate on it yet they are only retried when the server turned
+// them away before doing anything: a refused connection, 429 or 503.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	tenant     string
+	adminToken string
+	apiKeyID   string
+	apiSecret  string
+	retries    int
+	backoff    time.Duration
+
+	mu    sync.Mutex
+	token string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) { c.http = h }
+}
+
+// WithToken authenticates account requests with a JWT from an earlier Login.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithAPIKey signs account requests with an API key instead of a JWT.
+func WithAPIKey(id, secret string) Option {
+	return func(c *Client) { c.apiKeyID, c.apiSecret = id, secret }
+}
+
+// WithAdminToken authenticates the Admin* methods.
+func WithAdminToken(token string) Option {
+	return func(c *Client) { c.adminToken = token }
+}
+
+// WithTenant sends X-Tenant with every request.
+func WithTenant(slug string) Option {
+	return func(c *Client) { c.tenant = slug }
+}
+
+// WithRetries sets how often a failed request is retried and the first
+// backoff, which doubles on every attempt. The default is 3 and 200ms, 0
+// turns retries off.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = n, backoff }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    http.DefaultClient,
+		retries: 3,
+		backoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token is the JWT the client currently sends.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// APIError is a non-2xx answer of the API.
+type APIError struct {
+	StatusCode int
+	Code       string            `json:"code"`
+	Message    string            `json:"error"`
+	Field      string            `json:"field,omitempty"`
+	Violations []SchemaViolation `json:"violations,omitempty"`
+
+	body []byte
+}
+
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Error   string `json:"error"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("gobank: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("gobank: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsStatus reports whether err is an APIError with the given status.
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+type auth int
+
+const (
+	authNone auth = iota
+	authAccount
+	authAdmin
+)
+
+// request describes one call. A []byte body is sent as is, anything else JSON
+// encoded.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	auth        auth
+	body        any
+	contentType string
+	header      http.Header
+}
+
+// do sends req and decodes a 2xx JSON answer into out, unless out is nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send returns the response of the last attempt if it's a 2xx, the caller
+// closes its body. Anything else comes back as an *APIError.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		if b, ok := req.body.([]byte); ok {
+			body = b
+		} else {
+			var err error
+			if body, err = json.Marshal(req.body); err != nil {
+				return nil, err
+			}
+		}
+	}
+	idempotencyKey := ""
+	if req.method == http.MethodPost {
+		idempotencyKey = randomHex(16)
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body, idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.http.Do(httpReq)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if attempt >= c.retries || !retryable(req.method, resp, err) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, readError(resp)
+		}
+		wait := backoff
+		if resp != nil {
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+				wait = time.Duration(secs) * time.Second
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, req request, body []byte, idempotencyKey string) (*http.Request, error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.tenant != "" {
+		httpReq.Header.Set("X-Tenant", c.tenant)
+	}
+	switch req.auth {
+	case authAdmin:
+		httpReq.Header.Set("x-admin-token", c.adminToken)
+	case authAccount:
+		if c.apiKeyID != "" {
+			c.sign(httpReq, body)
+		} else {
+			httpReq.Header.Set("x-jwt-token", c.Token())
+		}
+	}
+	return httpReq, nil
+}
+
+// sign adds the API key signature headers. Every attempt gets a fresh nonce,
+// the server burns the ones it has seen.
+func (c *Client) sign(r *http.Request, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := randomHex(16)
+	sum := sha256.Sum256(body)
+	payload := strings.Join([]string{r.Method, r.URL.RequestURI(), timestamp, nonce, hex.EncodeToString(sum[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(payload))
+	r.Header.Set("X-Api-Key", c.apiKeyID)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Nonce", nonce)
+	r.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// retryable decides whether an attempt that failed with resp or err may be
+// sent again.
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		if method != http.MethodPost {
+			return true
+		}
+		// a connection that was never made can't have reached a handler
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != http.MethodPost
+	}
+	return false
+}
+
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr.body = data
+	if err != nil || json.Unmarshal(data, apiErr) != nil {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
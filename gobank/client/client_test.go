@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetriesKeepIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":7,"amount":{"amount":"1.00","currency":"USD","minor_units":100}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithToken("jwt"), WithRetries(3, 0))
+	tx, err := c.Transfer(context.Background(), 42, Money{MinorUnits: 100})
+	assert.Nil(t, err)
+	assert.Equal(t, 7, tx.ID)
+	assert.Equal(t, int64(100), tx.Amount.MinorUnits)
+	assert.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestPostNotRetriedAfterBadGateway(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(3, 0))
+	_, err := c.Transfer(context.Background(), 42, Money{MinorUnits: 100})
+	assert.True(t, IsStatus(err, http.StatusBadGateway))
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	_, err = c.GetAccount(context.Background(), 1)
+	assert.True(t, IsStatus(err, http.StatusBadGateway))
+	assert.Equal(t, 4, calls)
+}
+
+func TestAPIKeySigning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n" + r.Header.Get("X-Timestamp") + "\n" + r.Header.Get("X-Nonce") + "\n" + hex.EncodeToString(sum[:])))
+		if r.Header.Get("X-Api-Key") != "key" || hex.EncodeToString(mac.Sum(nil)) != r.Header.Get("X-Signature") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":"permission_denied","error":"permission denied"}`))
+			return
+		}
+		w.Write([]byte(`{"name":"ci"}`))
+	}))
+	defer srv.Close()
+
+	key, err := New(srv.URL, WithAPIKey("key", "secret")).CreateApiKey(context.Background(), 1, "ci")
+	assert.Nil(t, err)
+	assert.Equal(t, "ci", key.Name)
+
+	_, err = New(srv.URL, WithAPIKey("key", "wrong")).CreateApiKey(context.Background(), 1, "ci")
+	assert.True(t, IsStatus(err, http.StatusForbidden))
+	assert.Equal(t, "permission_denied", err.(*APIError).Code)
+}
+
+func TestImportRowErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"imported":0,"errors":[{"row":2,"error":"invalid amount"}]}`))
+	}))
+	defer srv.Close()
+
+	res, err := New(srv.URL).ImportTransactions(context.Background(), 1, "text/csv", []byte("date,amount\n"))
+	assert.True(t, IsStatus(err, http.StatusUnprocessableEntity))
+	assert.Equal(t, []ImportRowError{{Row: 2, Error: "invalid amount"}}, res.Errors)
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Platforms of RegisterDevice.
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+func (c *Client) ListDevices(ctx context.Context, id int) ([]*Device, error) {
+	var devices []*Device
+	return devices, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/devices"), auth: authAccount}, &devices)
+}
+
+// RegisterDevice subscribes an app install to the account's transfer and
+// login notifications, token being its FCM registration token on Android
+// and its APNs device token on iOS. Registering the same token again is
+// fine and returns the same device.
+func (c *Client) RegisterDevice(ctx context.Context, id int, platform, token, name string) (*Device, error) {
+	device := new(Device)
+	body := map[string]string{"platform": platform, "token": token, "name": name}
+	return device, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/devices"), body: body, auth: authAccount}, device)
+}
+
+func (c *Client) DeleteDevice(ctx context.Context, id int, deviceID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, "/devices/"+url.PathEscape(deviceID)), auth: authAccount}, nil)
+}
@@ -0,0 +1,126 @@
+package client
+
+// Endpoints lists the routes this package calls, as "METHOD path template".
+// The server's tests fail when it grows a route that isn't listed here.
+var Endpoints = []string{
+	"GET /version",
+	"GET /terms",
+	"GET /reference/currencies",
+	"GET /reference/countries",
+	"GET /reference/account-types",
+	"POST /login",
+	"POST /account",
+	"GET /account/lookup",
+	"GET /account/{id}",
+	"PATCH /account/{id}",
+	"DELETE /account/{id}",
+	"PUT /account/{id}/avatar",
+	"DELETE /account/{id}/avatar",
+	"POST /account/{id}/phone/verification",
+	"POST /account/{id}/phone/verification/confirm",
+	"GET /account/{id}/devices",
+	"POST /account/{id}/devices",
+	"DELETE /account/{id}/devices/{deviceId}",
+	"GET /account/{id}/notifications",
+	"POST /account/{id}/notifications/{notificationId}/read",
+	"POST /account/{id}/notifications/read-all",
+	"GET /avatars/{name}",
+	"GET /account/{id}/totals",
+	"GET /account/{id}/totals/monthly",
+	"GET /account/{id}/summary",
+	"GET /account/{id}/pots",
+	"POST /account/{id}/pots",
+	"PATCH /account/{id}/pots/{potId}",
+	"DELETE /account/{id}/pots/{potId}",
+	"GET /account/{id}/statement-destinations",
+	"POST /account/{id}/statement-destinations",
+	"DELETE /account/{id}/statement-destinations/{destinationId}",
+	"GET /account/{id}/statement-destinations/{destinationId}/deliveries",
+	"POST /account/{id}/statement-destinations/{destinationId}/deliveries/{deliveryId}/retry",
+	"GET /account/{id}/transactions",
+	"GET /account/{id}/activity",
+	"GET /account/{id}/transactions/feed",
+	"POST /account/{id}/transactions/import",
+	"GET /account/{id}/transactions/export",
+	"GET /account/{id}/fdx",
+	"GET /account/{id}/statements",
+	"GET /account/{id}/statements/{date}",
+	"GET /account/{id}/usage",
+	"GET /account/{id}/api-keys",
+	"POST /account/{id}/api-keys",
+	"DELETE /account/{id}/api-keys/{keyId}",
+	"GET /account/{id}/webhooks",
+	"POST /account/{id}/webhooks",
+	"DELETE /account/{id}/webhooks/{webhookId}",
+	"POST /account/{id}/webhooks/{webhookId}/enable",
+	"GET /account/{id}/webhooks/{webhookId}/deliveries",
+	"POST /account/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver",
+	"GET /account/{id}/terms",
+	"POST /account/{id}/terms/accept",
+	"GET /account/{id}/impersonations",
+	"POST /account/{id}/impersonations/{impersonationId}/approve",
+	"POST /account/{id}/impersonations/{impersonationId}/deny",
+	"GET /account/{id}/consents",
+	"POST /account/{id}/consents",
+	"DELETE /account/{id}/consents/{consentId}",
+	"POST /sandbox/account/{id}/topup",
+	"POST /transfer",
+	"GET /transfer/{id}",
+	"POST /transfer/quote",
+	"POST /transfers/batch",
+	"POST /transfer/authorize",
+	"GET /transfer/holds/{holdId}",
+	"POST /transfer/holds/{holdId}/capture",
+	"POST /transfer/holds/{holdId}/void",
+	"GET /open-banking/v1/accounts",
+	"GET /open-banking/v1/accounts/{accountId}",
+	"GET /open-banking/v1/accounts/{accountId}/balances",
+	"GET /open-banking/v1/accounts/{accountId}/transactions",
+	"POST /open-banking/v1/payments/credit-transfers",
+	"GET /open-banking/v1/payments/credit-transfers/{paymentId}/status",
+	"GET /admin/tenants",
+	"POST /admin/tenants",
+	"GET /admin/tenants/{id}/settings",
+	"PUT /admin/tenants/{id}/settings",
+	"GET /admin/maintenance",
+	"PUT /admin/maintenance",
+	"GET /admin/chaos",
+	"PUT /admin/chaos",
+	"GET /admin/clock",
+	"POST /admin/clock",
+	"POST /admin/config/reload",
+	"GET /admin/jobs",
+	"POST /admin/jobs/{id}/retry",
+	"GET /admin/reports/daily",
+	"GET /admin/reports/large-transactions",
+	"GET /admin/reports/large-transactions/{name}",
+	"GET /admin/reports/accounting",
+	"POST /admin/reports/accounting",
+	"GET /admin/reports/accounting/{name}",
+	"GET /admin/reports/balances",
+	"GET /admin/projections",
+	"POST /admin/projections/{name}/rebuild",
+	"GET /admin/reconciliation/issues",
+	"POST /admin/reconciliation/issues/{id}/resolve",
+	"GET /admin/events",
+	"GET /admin/sagas/stuck",
+	"GET /admin/accounts",
+	"GET /admin/accounts/export",
+	"GET /admin/accounts/portable",
+	"POST /admin/accounts/portable",
+	"POST /admin/payments/pain001",
+	"GET /admin/payments/batch-files",
+	"GET /admin/transfers/review",
+	"POST /admin/transfers/{id}/release",
+	"POST /admin/transfers/{id}/reject",
+	"GET /admin/accounts/{id}/ledger/verify",
+	"GET /admin/accounts/{id}/history",
+	"GET /admin/accounts/{id}/state",
+	"POST /admin/accounts/{id}/impersonations",
+	"PUT /admin/accounts/{id}/minimum-balance",
+	"POST /admin/impersonations/{id}/token",
+	"POST /admin/impersonations/{id}/revoke",
+	"GET /admin/email/suppressions",
+	"POST /admin/email/suppressions",
+	"DELETE /admin/email/suppressions/{address}",
+}
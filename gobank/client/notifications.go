@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// ListNotifications returns a page of the account's in-app inbox, newest
+// first, only the unread notifications with unreadOnly.
+func (c *Client) ListNotifications(ctx context.Context, id int, cursor string, limit int, unreadOnly bool) (*NotificationsPage, error) {
+	page := new(NotificationsPage)
+	q := pageQuery(cursor, limit)
+	if unreadOnly {
+		q.Set("unread", "true")
+	}
+	return page, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/notifications"), query: q, auth: authAccount}, page)
+}
+
+func (c *Client) MarkNotificationRead(ctx context.Context, id int, notificationID int64) (*Notification, error) {
+	notification := new(Notification)
+	path := accountPath(id, "/notifications/"+strconv.FormatInt(notificationID, 10)+"/read")
+	return notification, c.do(ctx, request{method: http.MethodPost, path: path, auth: authAccount}, notification)
+}
+
+// MarkAllNotificationsRead returns how many notifications were unread.
+func (c *Client) MarkAllNotificationsRead(ctx context.Context, id int) (int64, error) {
+	var result struct {
+		Read int64 `json:"read"`
+	}
+	return result.Read, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/notifications/read-all"), auth: authAccount}, &result)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// The OpenBanking methods call the Berlin Group style /open-banking/v1
+// routes. A third-party API key needs the account holder's consent for them,
+// see GrantConsent.
+
+func (c *Client) OpenBankingAccounts(ctx context.Context) (*OBAccountList, error) {
+	list := new(OBAccountList)
+	return list, c.do(ctx, request{method: http.MethodGet, path: "/open-banking/v1/accounts", auth: authAccount}, list)
+}
+
+// OpenBankingAccount takes the account's resourceId, its UUID.
+func (c *Client) OpenBankingAccount(ctx context.Context, resourceID string) (*OBAccount, error) {
+	account := new(OBAccount)
+	return account, c.do(ctx, request{method: http.MethodGet, path: obAccountPath(resourceID, ""), auth: authAccount}, account)
+}
+
+func (c *Client) OpenBankingBalances(ctx context.Context, resourceID string) (*OBBalances, error) {
+	balances := new(OBBalances)
+	return balances, c.do(ctx, request{method: http.MethodGet, path: obAccountPath(resourceID, "/balances"), auth: authAccount}, balances)
+}
+
+// OpenBankingTransactions returns the transactions booked from dateFrom to
+// dateTo including, both "2006-01-02" days. Empty ones leave the range open.
+func (c *Client) OpenBankingTransactions(ctx context.Context, resourceID, dateFrom, dateTo string) (*OBTransactions, error) {
+	q := url.Values{}
+	if dateFrom != "" {
+		q.Set("dateFrom", dateFrom)
+	}
+	if dateTo != "" {
+		q.Set("dateTo", dateTo)
+	}
+	txs := new(OBTransactions)
+	return txs, c.do(ctx, request{method: http.MethodGet, path: obAccountPath(resourceID, "/transactions"), query: q, auth: authAccount}, txs)
+}
+
+// OpenBankingInitiatePayment starts a credit transfer, poll
+// OpenBankingPaymentStatus until it's settled (ACSC) or rejected (RJCT).
+func (c *Client) OpenBankingInitiatePayment(ctx context.Context, req OBPaymentRequest) (*OBPaymentStatus, error) {
+	status := new(OBPaymentStatus)
+	return status, c.do(ctx, request{method: http.MethodPost, path: "/open-banking/v1/payments/credit-transfers", body: req, auth: authAccount}, status)
+}
+
+func (c *Client) OpenBankingPaymentStatus(ctx context.Context, paymentID string) (*OBPaymentStatus, error) {
+	status := new(OBPaymentStatus)
+	return status, c.do(ctx, request{method: http.MethodGet, path: "/open-banking/v1/payments/credit-transfers/" + url.PathEscape(paymentID) + "/status", auth: authAccount}, status)
+}
+
+func obAccountPath(resourceID, suffix string) string {
+	return "/open-banking/v1/accounts/" + url.PathEscape(resourceID) + suffix
+}
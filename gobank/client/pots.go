@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) ListPots(ctx context.Context, id int) ([]*Pot, error) {
+	var pots []*Pot
+	return pots, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/pots"), auth: authAccount}, &pots)
+}
+
+// CreatePot puts amount aside in a new pot. It fails with funds_protected
+// when the available balance doesn't cover it.
+func (c *Client) CreatePot(ctx context.Context, id int, name string, amount Money) (*Pot, error) {
+	pot := new(Pot)
+	body := map[string]any{"name": name, "amount": amount}
+	return pot, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/pots"), body: body, auth: authAccount}, pot)
+}
+
+// UpdatePot renames the pot when name isn't empty and sets its amount when
+// amount isn't nil.
+func (c *Client) UpdatePot(ctx context.Context, id int, potID, name string, amount *Money) (*Pot, error) {
+	pot := new(Pot)
+	body := map[string]any{}
+	if name != "" {
+		body["name"] = name
+	}
+	if amount != nil {
+		body["amount"] = amount
+	}
+	return pot, c.do(ctx, request{method: http.MethodPatch, path: accountPath(id, "/pots/"+url.PathEscape(potID)), body: body, auth: authAccount}, pot)
+}
+
+// DeletePot releases the pot's amount into the available balance.
+func (c *Client) DeletePot(ctx context.Context, id int, potID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: accountPath(id, "/pots/"+url.PathEscape(potID)), auth: authAccount}, nil)
+}
+
+// AdminSetMinimumBalance sets the balance transfers can't take the account
+// below, nil leaves it to the tenant's settings.
+func (c *Client) AdminSetMinimumBalance(ctx context.Context, tenant string, accountID int, minimum *Money) (*Balances, error) {
+	balances := new(Balances)
+	body := map[string]any{"minimumBalance": minimum}
+	return balances, c.do(ctx, request{method: http.MethodPut, path: "/admin" + accountPath(accountID, "/minimum-balance"), query: tenantQuery(tenant), body: body, auth: authAdmin}, balances)
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *Client) Currencies(ctx context.Context) ([]*Currency, error) {
+	var res []*Currency
+	return res, c.do(ctx, request{method: http.MethodGet, path: "/reference/currencies"}, &res)
+}
+
+func (c *Client) Countries(ctx context.Context) ([]*Country, error) {
+	var res []*Country
+	return res, c.do(ctx, request{method: http.MethodGet, path: "/reference/countries"}, &res)
+}
+
+func (c *Client) AccountTypes(ctx context.Context) ([]*AccountType, error) {
+	var res []*AccountType
+	return res, c.do(ctx, request{method: http.MethodGet, path: "/reference/account-types"}, &res)
+}
+
+// Terms returns the current version of each document accounts have to
+// accept before moving money, keyed "terms" and "privacy".
+func (c *Client) Terms(ctx context.Context) (map[string]string, error) {
+	var res map[string]string
+	return res, c.do(ctx, request{method: http.MethodGet, path: "/terms"}, &res)
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) ListStatementDestinations(ctx context.Context, id int) ([]*StatementDestination, error) {
+	var destinations []*StatementDestination
+	return destinations, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/statement-destinations"), auth: authAccount}, &destinations)
+}
+
+// CreateStatementDestination has the account's statements delivered to an
+// email address or SFTP server from now on.
+func (c *Client) CreateStatementDestination(ctx context.Context, id int, req StatementDestinationRequest) (*StatementDestination, error) {
+	dest := new(StatementDestination)
+	return dest, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/statement-destinations"), body: req, auth: authAccount}, dest)
+}
+
+func (c *Client) DeleteStatementDestination(ctx context.Context, id int, destinationID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: statementDestinationPath(id, destinationID, ""), auth: authAccount}, nil)
+}
+
+func (c *Client) ListStatementDeliveries(ctx context.Context, id int, destinationID string) ([]*StatementDelivery, error) {
+	var deliveries []*StatementDelivery
+	return deliveries, c.do(ctx, request{method: http.MethodGet, path: statementDestinationPath(id, destinationID, "/deliveries"), auth: authAccount}, &deliveries)
+}
+
+// RetryStatementDelivery queues the statement to be delivered again.
+func (c *Client) RetryStatementDelivery(ctx context.Context, id int, destinationID, deliveryID string) error {
+	path := statementDestinationPath(id, destinationID, "/deliveries/"+url.PathEscape(deliveryID)+"/retry")
+	return c.do(ctx, request{method: http.MethodPost, path: path, auth: authAccount}, nil)
+}
+
+func statementDestinationPath(id int, destinationID, rest string) string {
+	return accountPath(id, "/statement-destinations/"+url.PathEscape(destinationID)+rest)
+}
@@ -0,0 +1,780 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Money is an amount in minor units. Requests only need MinorUnits and
+// Currency, Amount is the decimal form the server sends back.
+type Money struct {
+	Amount     string `json:"amount,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	MinorUnits int64  `json:"minor_units"`
+}
+
+type Account struct {
+	ID          int       `json:"id"`
+	UUID        string    `json:"uuid"`
+	FirstName   string    `json:"firstName"`
+	LastName    string    `json:"lastName"`
+	Email       string    `json:"email,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	Nickname    string    `json:"nickname,omitempty"`
+	Avatar      string    `json:"avatar,omitempty"`
+	Address     *Address  `json:"address,omitempty"`
+	DateOfBirth string    `json:"dateOfBirth,omitempty"`
+	Timezone    string    `json:"timezone"`
+	Language    string    `json:"language,omitempty"`
+	Locale      string    `json:"locale,omitempty"`
+	Number      int64     `json:"number"`
+	IBAN        string    `json:"iban,omitempty"`
+	Balance     Money     `json:"balance"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	Version     int       `json:"version"`
+	TenantID    int       `json:"tenantId"`
+	// PhoneVerified is set by ConfirmPhone, SMSAlerts needs it.
+	PhoneVerified bool `json:"phoneVerified"`
+	SMSAlerts     bool `json:"smsAlerts"`
+}
+
+// AccountLookup is the masked answer to LookupAccount.
+type AccountLookup struct {
+	Exists   bool   `json:"exists"`
+	Holder   string `json:"holder,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+// TransferQuote holds the terms of a transfer until ExpiresAt, pass the ID
+// to ExecuteQuote to make it.
+type TransferQuote struct {
+	ID        string    `json:"id"`
+	ToAccount int64     `json:"toAccount"`
+	Amount    Money     `json:"amount"`
+	Rate      string    `json:"rate"`
+	Fee       Money     `json:"fee"`
+	Total     Money     `json:"total"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// TransferStatus is a transfer accepted for background processing, Status
+// is pending, completed or failed, Error says why it failed.
+type TransferStatus struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	ToAccount     int64     `json:"toAccount"`
+	Amount        Money     `json:"amount"`
+	TransactionID int       `json:"transactionId,omitempty"`
+	Error         *APIError `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// TransferReview is a transfer screening held, Reason says what it matched.
+type TransferReview struct {
+	TransferStatus
+	AccountID int    `json:"accountId"`
+	Reason    string `json:"reason"`
+}
+
+type TransferOrder struct {
+	ToAccount int64 `json:"toAccount"`
+	Amount    Money `json:"amount"`
+}
+
+// BatchTransferResult holds the transaction of the transfer at Index or the
+// error it failed with.
+type BatchTransferResult struct {
+	Index       int          `json:"index"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Error       *APIError    `json:"error,omitempty"`
+}
+
+type BatchTransferResponse struct {
+	Mode      string                `json:"mode"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []BatchTransferResult `json:"results"`
+}
+
+// TransferHold is an authorized transfer, Status is authorized, captured,
+// voided or expired.
+type TransferHold struct {
+	ID            string     `json:"id"`
+	AccountID     int        `json:"accountId"`
+	ToAccount     int64      `json:"toAccount"`
+	Amount        Money      `json:"amount"`
+	Status        string     `json:"status"`
+	Captured      *Money     `json:"captured,omitempty"`
+	TransactionID int        `json:"transactionId,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	SettledAt     *time.Time `json:"settledAt,omitempty"`
+}
+
+type CreateAccountRequest struct {
+	FirstName   string   `json:"firstName"`
+	LastName    string   `json:"lastName"`
+	Email       string   `json:"email,omitempty"`
+	Phone       string   `json:"phone,omitempty"`
+	Nickname    string   `json:"nickname,omitempty"`
+	Address     *Address `json:"address,omitempty"`
+	DateOfBirth string   `json:"dateOfBirth,omitempty"`
+	Timezone    string   `json:"timezone,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	Locale      string   `json:"locale,omitempty"`
+	Password    string   `json:"password"`
+}
+
+// Address is a postal address, Country the ISO 3166 alpha-2 code.
+type Address struct {
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country"`
+}
+
+// UpdateAccountRequest is a PATCH, nil fields stay unchanged.
+type UpdateAccountRequest struct {
+	FirstName   *string  `json:"firstName,omitempty"`
+	LastName    *string  `json:"lastName,omitempty"`
+	Email       *string  `json:"email,omitempty"`
+	Phone       *string  `json:"phone,omitempty"`
+	Nickname    *string  `json:"nickname,omitempty"`
+	Address     *Address `json:"address,omitempty"`
+	DateOfBirth *string  `json:"dateOfBirth,omitempty"`
+	Timezone    *string  `json:"timezone,omitempty"`
+	Language    *string  `json:"language,omitempty"`
+	Locale      *string  `json:"locale,omitempty"`
+	SMSAlerts   *bool    `json:"smsAlerts,omitempty"`
+}
+
+// PhoneVerification tells until when the texted code is good.
+type PhoneVerification struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type LoginResponse struct {
+	ID     int    `json:"id"`
+	UUID   string `json:"uuid"`
+	Number int64  `json:"number"`
+	Token  string `json:"token"`
+}
+
+type Transaction struct {
+	ID           int       `json:"id"`
+	AccountID    int       `json:"accountId"`
+	Type         string    `json:"type"`
+	Amount       Money     `json:"amount"`
+	Counterparty int64     `json:"counterparty"`
+	Description  string    `json:"description,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Hash         string    `json:"hash,omitempty"`
+}
+
+// Page is one page of a listing, pass NextCursor back for the next one.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type FeedPage struct {
+	Transactions []*Transaction `json:"transactions"`
+	Cursor       string         `json:"cursor"`
+}
+
+type DailyTotal struct {
+	Date    string `json:"date"`
+	Credits Money  `json:"credits"`
+	Debits  Money  `json:"debits"`
+}
+
+type MonthlyTotal struct {
+	Month        string `json:"month"`
+	Credits      Money  `json:"credits"`
+	Debits       Money  `json:"debits"`
+	Transactions int    `json:"transactions"`
+}
+
+type AccountSummary struct {
+	Balance            Money          `json:"balance"`
+	AvailableBalance   Money          `json:"availableBalance"`
+	MonthToDateSpend   Money          `json:"monthToDateSpend"`
+	PendingTransfers   int            `json:"pendingTransfers"`
+	RecentTransactions []*Transaction `json:"recentTransactions"`
+	// AvailableBalance is the balance less these.
+	HeldBalance      Money `json:"heldBalance"`
+	ProtectedBalance Money `json:"protectedBalance"`
+	MinimumBalance   Money `json:"minimumBalance"`
+}
+
+// Pot is money put aside on the account, left out of its available balance.
+type Pot struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Name      string    `json:"name"`
+	Amount    Money     `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Balances struct {
+	Balance          Money `json:"balance"`
+	AvailableBalance Money `json:"availableBalance"`
+	HeldBalance      Money `json:"heldBalance"`
+	ProtectedBalance Money `json:"protectedBalance"`
+	MinimumBalance   Money `json:"minimumBalance"`
+}
+
+type DailyUsage struct {
+	Date      string `json:"date"`
+	Calls     int64  `json:"calls"`
+	Throttled int64  `json:"throttled"`
+}
+
+type UsageReport struct {
+	RateLimitPerMinute int           `json:"rateLimitPerMinute"`
+	DailyQuota         int           `json:"dailyQuota"`
+	Days               []*DailyUsage `json:"days"`
+}
+
+// ApiKey carries its Secret only in the answer to CreateApiKey.
+type ApiKey struct {
+	ID         string     `json:"id"`
+	AccountID  int        `json:"accountId"`
+	Name       string     `json:"name"`
+	Secret     string     `json:"secret,omitempty"`
+	ThirdParty bool       `json:"thirdParty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// WebhookEndpoint carries its Secret only in the answer to CreateWebhook.
+type WebhookEndpoint struct {
+	ID                  string     `json:"id"`
+	AccountID           int        `json:"accountId"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	FailingSince        *time.Time `json:"failingSince,omitempty"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+}
+
+type Device struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Platform  string    `json:"platform"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// The kinds of Activity.
+const (
+	ActivityLogin       = "login"
+	ActivityProfile     = "profile"
+	ActivityTransaction = "transaction"
+	ActivityHold        = "hold"
+	ActivityAccess      = "access"
+)
+
+// Activity is an entry of the account's timeline, an event named by Event
+// with its payload in Details or a transaction.
+type Activity struct {
+	Type        string          `json:"type"`
+	Event       string          `json:"event,omitempty"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	Transaction *Transaction    `json:"transaction,omitempty"`
+	At          time.Time       `json:"at"`
+}
+
+// Notification is an entry of the in-app inbox. Kind is welcome, transfer,
+// login or statement_ready.
+type Notification struct {
+	ID        int64             `json:"id"`
+	AccountID int               `json:"accountId"`
+	Kind      string            `json:"kind"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ReadAt    *time.Time        `json:"readAt,omitempty"`
+}
+
+type NotificationsPage struct {
+	Unread int `json:"unread"`
+	Page[*Notification]
+}
+
+type WebhookDelivery struct {
+	ID          string           `json:"id"`
+	EndpointID  string           `json:"endpointId"`
+	EventID     int64            `json:"eventId"`
+	EventType   string           `json:"eventType"`
+	Status      string           `json:"status"`
+	Attempts    []WebhookAttempt `json:"attempts"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	DeliveredAt *time.Time       `json:"deliveredAt,omitempty"`
+}
+
+// StatementDestinationRequest is an email destination with Email or an
+// SFTP one with URL, sftp://user@host/dir, and the SHA256 fingerprint of
+// the server's host key. Frequency is daily, weekly or monthly.
+type StatementDestinationRequest struct {
+	Sink      string `json:"sink"`
+	Frequency string `json:"frequency,omitempty"`
+	Email     string `json:"email,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Password  string `json:"password,omitempty"`
+	HostKey   string `json:"hostKey,omitempty"`
+}
+
+type StatementDestination struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Sink      string    `json:"sink"`
+	Frequency string    `json:"frequency"`
+	Email     string    `json:"email,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	HostKey   string    `json:"hostKey,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StatementDelivery is the statement of the days From to To to a
+// destination, its Status pending, succeeded or failed.
+type StatementDelivery struct {
+	ID            string     `json:"id"`
+	DestinationID string     `json:"destinationId"`
+	AccountID     int        `json:"accountId"`
+	From          string     `json:"from"`
+	To            string     `json:"to"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// BatchFile is a payment batch file the intake picked up. One acknowledged
+// has its acknowledgement in the ack directory.
+type BatchFile struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Format      string     `json:"format"`
+	SHA256      string     `json:"sha256"`
+	Status      string     `json:"status"`
+	Accepted    int        `json:"accepted"`
+	Rejected    int        `json:"rejected"`
+	ReceivedAt  time.Time  `json:"receivedAt"`
+	ProcessedAt *time.Time `json:"processedAt,omitempty"`
+}
+
+type WebhookAttempt struct {
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	At         time.Time `json:"at"`
+}
+
+// TermsStatus lists in Pending the documents whose Current version the
+// account hasn't accepted.
+type TermsStatus struct {
+	Current  map[string]string  `json:"current"`
+	Accepted []*TermsAcceptance `json:"accepted"`
+	Pending  []string           `json:"pending"`
+}
+
+type TermsAcceptance struct {
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
+// Impersonation is support's read-only access to an account. Token is only
+// set in the admin answers that issue one.
+// EmailSuppression is an address that gets no emails, Reason is bounce,
+// complaint or manual.
+type EmailSuppression struct {
+	Address   string    `json:"address"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Impersonation struct {
+	ID               string     `json:"id"`
+	AccountID        int        `json:"accountId"`
+	RequestedBy      string     `json:"requestedBy"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status"`
+	RequiresApproval bool       `json:"requiresApproval"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	DecidedAt        *time.Time `json:"decidedAt,omitempty"`
+	Token            string     `json:"token,omitempty"`
+}
+
+type Consent struct {
+	ID        string     `json:"id"`
+	AccountID int        `json:"accountId"`
+	ApiKeyID  string     `json:"apiKeyId"`
+	Purpose   string     `json:"purpose"`
+	Scopes    []string   `json:"scopes"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// GrantConsentRequest runs for the longest allowed, 180 days, without Days.
+type GrantConsentRequest struct {
+	ApiKeyID string   `json:"apiKeyId"`
+	Purpose  string   `json:"purpose"`
+	Scopes   []string `json:"scopes"`
+	Days     int      `json:"days,omitempty"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
+
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Mode      string `json:"mode"`
+}
+
+type Tenant struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type TenantSettings struct {
+	TenantID           int       `json:"tenantId"`
+	Currency           string    `json:"currency"`
+	MaxTransferAmount  int64     `json:"maxTransferAmount"`
+	DailyTransferLimit int64     `json:"dailyTransferLimit"`
+	BrandName          string    `json:"brandName"`
+	SupportEmail       string    `json:"supportEmail"`
+	RequireKYC         bool      `json:"requireKyc"`
+	IBANCountry        string    `json:"ibanCountry,omitempty"`
+	IBANBankCode       string    `json:"ibanBankCode,omitempty"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+	// DuplicateTransferMinutes is how far back a transfer of the same amount
+	// to the same account gets a new one refused, 0 for not at all.
+	DuplicateTransferMinutes int `json:"duplicateTransferMinutes"`
+	// MinimumBalance, in minor units, is the balance transfers can't take an
+	// account below unless it has one of its own.
+	MinimumBalance int64 `json:"minimumBalance"`
+}
+
+type MaintenanceState struct {
+	Mode       string `json:"mode"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retryAfter"`
+}
+
+type ChaosState struct {
+	Enabled   bool     `json:"enabled"`
+	Routes    []string `json:"routes,omitempty"`
+	LatencyMs int      `json:"latencyMs"`
+	JitterMs  int      `json:"jitterMs"`
+	ErrorRate float64  `json:"errorRate"`
+	DropRate  float64  `json:"dropRate"`
+}
+
+type ClockState struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+type RuntimeConfig struct {
+	LogLevel             string   `json:"logLevel"`
+	RateLimitPerMinute   int      `json:"rateLimitPerMinute"`
+	LookupsPerMinute     int      `json:"lookupsPerMinute"`
+	DailyQuota           int      `json:"dailyQuota"`
+	MaxTransferAmount    int64    `json:"maxTransferAmount"`
+	DailyTransferLimit   int64    `json:"dailyTransferLimit"`
+	CORSOrigins          []string `json:"corsOrigins"`
+	SlowQueryThresholdMs int      `json:"slowQueryThresholdMs"`
+	RecordRequests       bool     `json:"recordRequests"`
+}
+
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	RunAt       time.Time       `json:"runAt"`
+	LockedUntil *time.Time      `json:"lockedUntil,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+// LargeTransactionReport is a report of the transactions of the accounts
+// that moved at least the reporting threshold in the window ending at To.
+type LargeTransactionReport struct {
+	Name   string    `json:"name"`
+	Format string    `json:"format"`
+	To     time.Time `json:"to"`
+}
+
+// AccountingExportRequest asks for the journals of the UTC days From to To,
+// To exclusive, as "quickbooks" IIF or "xero" CSV. Accounts left empty post
+// to accounts named like the defaults.
+type AccountingExportRequest struct {
+	Format   string             `json:"format"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Currency string             `json:"currency,omitempty"`
+	Accounts AccountingAccounts `json:"accounts"`
+}
+
+type AccountingAccounts struct {
+	Deposits  string `json:"deposits,omitempty"`
+	Transfers string `json:"transfers,omitempty"`
+	Fees      string `json:"fees,omitempty"`
+	Imports   string `json:"imports,omitempty"`
+	Other     string `json:"other,omitempty"`
+}
+
+type AccountingExport struct {
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Currency string `json:"currency,omitempty"`
+}
+
+type BalanceTotal struct {
+	Accounts int   `json:"accounts"`
+	Total    Money `json:"total"`
+}
+
+type Projection struct {
+	Name              string     `json:"name"`
+	LastTransactionID int        `json:"lastTransactionId"`
+	Behind            int        `json:"behind"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+}
+
+type DailyReportRow struct {
+	Date           string `json:"date"`
+	Currency       string `json:"currency"`
+	NewAccounts    int    `json:"newAccounts"`
+	Transfers      int    `json:"transfers"`
+	TransferVolume Money  `json:"transferVolume"`
+	FeeRevenue     Money  `json:"feeRevenue"`
+}
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	AccountID int             `json:"accountId"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type Saga struct {
+	ID        string     `json:"id"`
+	TenantID  int        `json:"tenantId"`
+	AccountID int        `json:"accountId"`
+	Type      string     `json:"type"`
+	Status    string     `json:"status"`
+	Steps     []SagaStep `json:"steps"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+type SagaStep struct {
+	Name   string    `json:"name"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+type ReconciliationIssue struct {
+	ID             int        `json:"id"`
+	TenantID       int        `json:"tenantId"`
+	AccountID      int        `json:"accountId"`
+	Currency       string     `json:"currency"`
+	AccountBalance Money      `json:"accountBalance"`
+	LedgerBalance  Money      `json:"ledgerBalance"`
+	DetectedAt     time.Time  `json:"detectedAt"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	Resolution     string     `json:"resolution,omitempty"`
+}
+
+// AccountEvent is a change of an account, Version the account version it
+// resulted in.
+type AccountEvent struct {
+	AccountID int             `json:"accountId"`
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type LedgerVerification struct {
+	AccountID int    `json:"accountId"`
+	Entries   int    `json:"entries"`
+	Valid     bool   `json:"valid"`
+	BrokenAt  int    `json:"brokenAt,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type Currency struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minorUnits"`
+}
+
+type Country struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type AccountType struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OBAmount is a Berlin Group amount, a decimal string.
+type OBAmount struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+// OBAccountReference names an account by its number in BBAN or by its
+// IBAN.
+type OBAccountReference struct {
+	BBAN     string `json:"bban,omitempty"`
+	IBAN     string `json:"iban,omitempty"`
+	Currency string `json:"currency,omitempty"`
+}
+
+type OBLink struct {
+	Href string `json:"href"`
+}
+
+type OBAccountDetails struct {
+	ResourceID      string            `json:"resourceId"`
+	BBAN            string            `json:"bban"`
+	IBAN            string            `json:"iban,omitempty"`
+	Currency        string            `json:"currency"`
+	Name            string            `json:"name"`
+	OwnerName       string            `json:"ownerName"`
+	CashAccountType string            `json:"cashAccountType"`
+	Status          string            `json:"status"`
+	Links           map[string]OBLink `json:"_links"`
+}
+
+type OBAccountList struct {
+	Accounts []OBAccountDetails `json:"accounts"`
+}
+
+type OBAccount struct {
+	Account OBAccountDetails `json:"account"`
+}
+
+type OBBalance struct {
+	BalanceAmount OBAmount `json:"balanceAmount"`
+	BalanceType   string   `json:"balanceType"`
+	ReferenceDate string   `json:"referenceDate"`
+}
+
+type OBBalances struct {
+	Account  OBAccountReference `json:"account"`
+	Balances []OBBalance        `json:"balances"`
+}
+
+type OBTransaction struct {
+	TransactionID                     string              `json:"transactionId"`
+	BookingDate                       string              `json:"bookingDate"`
+	ValueDate                         string              `json:"valueDate"`
+	TransactionAmount                 OBAmount            `json:"transactionAmount"`
+	CreditorAccount                   *OBAccountReference `json:"creditorAccount,omitempty"`
+	DebtorAccount                     *OBAccountReference `json:"debtorAccount,omitempty"`
+	RemittanceInformationUnstructured string              `json:"remittanceInformationUnstructured,omitempty"`
+}
+
+type OBTransactions struct {
+	Account      OBAccountReference `json:"account"`
+	Transactions struct {
+		Booked  []OBTransaction `json:"booked"`
+		Pending []OBTransaction `json:"pending"`
+	} `json:"transactions"`
+}
+
+type OBPaymentRequest struct {
+	InstructedAmount                  OBAmount           `json:"instructedAmount"`
+	CreditorAccount                   OBAccountReference `json:"creditorAccount"`
+	CreditorName                      string             `json:"creditorName"`
+	RemittanceInformationUnstructured string             `json:"remittanceInformationUnstructured,omitempty"`
+}
+
+// OBPaymentStatus has the ISO 20022 TransactionStatus: RCVD, ACSC or RJCT.
+type OBPaymentStatus struct {
+	PaymentID         string            `json:"paymentId,omitempty"`
+	TransactionStatus string            `json:"transactionStatus"`
+	Links             map[string]OBLink `json:"_links,omitempty"`
+}
+
+// FDXDocument is an account with its transactions in the Financial Data
+// Exchange v5 format.
+type FDXDocument struct {
+	Accounts []struct {
+		DepositAccount FDXDepositAccount `json:"depositAccount"`
+	} `json:"accounts"`
+}
+
+type FDXDepositAccount struct {
+	AccountID     string `json:"accountId"`
+	AccountType   string `json:"accountType"`
+	AccountNumber string `json:"accountNumberDisplay"`
+	DisplayName   string `json:"displayName"`
+	Status        string `json:"status"`
+	Currency      struct {
+		CurrencyCode string `json:"currencyCode"`
+	} `json:"currency"`
+	CurrentBalance   float64   `json:"currentBalance"`
+	AvailableBalance float64   `json:"availableBalance"`
+	BalanceAsOf      time.Time `json:"balanceAsOf"`
+	OpeningDate      string    `json:"openingDate"`
+	Transactions     []struct {
+		DepositTransaction FDXDepositTransaction `json:"depositTransaction"`
+	} `json:"transactions"`
+}
+
+// FDXDepositTransaction has a positive Amount, DebitCreditMemo is DEBIT or
+// CREDIT.
+type FDXDepositTransaction struct {
+	AccountID            string    `json:"accountId"`
+	TransactionID        string    `json:"transactionId"`
+	PostedTimestamp      time.Time `json:"postedTimestamp"`
+	TransactionTimestamp time.Time `json:"transactionTimestamp"`
+	Description          string    `json:"description"`
+	DebitCreditMemo      string    `json:"debitCreditMemo"`
+	Status               string    `json:"status"`
+	Amount               float64   `json:"amount"`
+	TransactionType      string    `json:"transactionType"`
+}
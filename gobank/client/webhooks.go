@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) ListWebhooks(ctx context.Context, id int) ([]*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	return endpoints, c.do(ctx, request{method: http.MethodGet, path: accountPath(id, "/webhooks"), auth: authAccount}, &endpoints)
+}
+
+// CreateWebhook returns the new endpoint with its signing secret, which is
+// never shown again.
+func (c *Client) CreateWebhook(ctx context.Context, id int, endpointURL string) (*WebhookEndpoint, error) {
+	ep := new(WebhookEndpoint)
+	body := map[string]string{"url": endpointURL}
+	return ep, c.do(ctx, request{method: http.MethodPost, path: accountPath(id, "/webhooks"), body: body, auth: authAccount}, ep)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id int, webhookID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: webhookPath(id, webhookID, ""), auth: authAccount}, nil)
+}
+
+// EnableWebhook turns an endpoint that was disabled for failing back on.
+func (c *Client) EnableWebhook(ctx context.Context, id int, webhookID string) error {
+	return c.do(ctx, request{method: http.MethodPost, path: webhookPath(id, webhookID, "/enable"), auth: authAccount}, nil)
+}
+
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int, webhookID string) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	return deliveries, c.do(ctx, request{method: http.MethodGet, path: webhookPath(id, webhookID, "/deliveries"), auth: authAccount}, &deliveries)
+}
+
+// RedeliverWebhook queues the delivery to be sent again.
+func (c *Client) RedeliverWebhook(ctx context.Context, id int, webhookID, deliveryID string) error {
+	path := webhookPath(id, webhookID, "/deliveries/"+url.PathEscape(deliveryID)+"/redeliver")
+	return c.do(ctx, request{method: http.MethodPost, path: path, auth: authAccount}, nil)
+}
+
+func webhookPath(id int, webhookID, rest string) string {
+	return accountPath(id, "/webhooks/"+url.PathEscape(webhookID)+rest)
+}
+
+// VerifyWebhook checks a delivery's Webhook-Signature header against the
+// endpoint's secret and the raw body, rejecting signatures older than
+// tolerance. Receivers should also drop Webhook-Ids they have seen.
+func VerifyWebhook(secret, signature string, body []byte, tolerance time.Duration) error {
+	var timestamp, sig string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || sig == "" {
+		return errors.New("malformed webhook signature")
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("stale webhook signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(sig)) {
+		return errors.New("webhook signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"github.com/iamuditg/internal/api"
+	"github.com/iamuditg/internal/app"
+	"github.com/iamuditg/internal/storage"
+	"log"
+	"log/slog"
+	"os"
+)
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// 8498081
+func main() {
+	seed := flag.Bool("seed", false, "seed the db")
+	seedAccountCount := flag.Int("seed-accounts", 1, "number of accounts -seed creates")
+	seedTransactions := flag.Int("seed-transactions", 0, "number of ledger entries -seed spreads over the accounts")
+	seedRNG := flag.Int64("seed-rng", 1, "random seed for -seed, the same value gives the same data")
+	archiveAfter := flag.Int("archive-after", 7, "archive transactions older than this many years")
+	flag.Parse()
+
+	if flag.Arg(0) == "openapi" {
+		if err := api.RunOpenAPICommand(flag.Args(), os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := api.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	a, err := app.New(cfg, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := a.Logger
+	slog.SetDefault(logger)
+	if *seed && !cfg.Sandbox() {
+		logger.Error("seeding is disabled in production mode")
+		os.Exit(1)
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "backup", "restore":
+		if err := storage.RunBackupCommand(a.Backuper, flag.Args(), os.Stdout); err != nil {
+			fatal(logger, cmd+" failed", err)
+		}
+		return
+	case "replay":
+		if err := api.RunReplayCommand(a.Recordings, flag.Args(), os.Stdout); err != nil {
+			fatal(logger, "replay failed", err)
+		}
+		return
+	}
+
+	if err := a.Connect(); err != nil {
+		fatal(logger, "connecting to the db failed", err)
+	}
+
+	if flag.NArg() > 0 {
+		err := api.RunPortableCommand(a.Store, a.Clock, flag.Args())
+		a.Close()
+		if err != nil {
+			fatal(logger, flag.Arg(0)+" failed", err)
+		}
+		return
+	}
+
+	if *seed {
+		logger.Info("seeding the database")
+		if err := api.NewSeeder(a.Store, *seedRNG, a.Clock, logger).Seed(*seedAccountCount, *seedTransactions); err != nil {
+			fatal(logger, "seeding the database failed", err)
+		}
+	}
+
+	if err := a.Wire(app.Options{ArchiveAfterYears: *archiveAfter}); err != nil {
+		fatal(logger, "wiring the application failed", err)
+	}
+	if err := a.Run(); err != nil {
+		fatal(logger, "shutdown failed", err)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccountReplayJobType replays the event stream of every account and
+// checks that it gives the stored account. It runs when the scheduler
+// starts and daily after that, see App.schedule.
+const AccountReplayJobType = "replay_account_events"
+
+const (
+	// accountSnapshotEvery is how many events after its last snapshot an
+	// account gets a new one.
+	accountSnapshotEvery = 100
+	accountReplayPage    = 500
+)
+
+type AccountReplayer struct {
+	store    storage.Storage
+	metrics  *Metrics
+	reporter ErrorReporter
+	logger   *slog.Logger
+}
+
+func NewAccountReplayer(store storage.Storage, metrics *Metrics, reporter ErrorReporter, logger *slog.Logger) *AccountReplayer {
+	metrics.Help("account_stream_mismatches", "Accounts whose event stream didn't replay to the stored account in the last run.")
+	return &AccountReplayer{store: store, metrics: metrics, reporter: reporter, logger: logger}
+}
+
+// HandleJob replays every account. An account without a stream, one from
+// before event sourcing was turned on, gets a snapshot of its row to start
+// from. One whose row changed since the page was read is left for the
+// next run.
+func (rp *AccountReplayer) HandleJob(job *domain.Job) error {
+	replayed, snapshots, mismatches := 0, 0, 0
+	for after := 0; ; {
+		accounts, err := rp.store.AllAccountsAfter(after, accountReplayPage)
+		if err != nil {
+			return err
+		}
+		if len(accounts) == 0 {
+			break
+		}
+		after = accounts[len(accounts)-1].ID
+		for _, stored := range accounts {
+			stream, err := rp.store.ForTenant(stored.TenantID).AccountStream(stored.ID, time.Time{})
+			if errors.Is(err, domain.ErrAccountHistoryNotFound) {
+				if err := rp.store.SaveAccountSnapshot(stored); err != nil {
+					return err
+				}
+				snapshots++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			account, err := stream.Replay(time.Time{})
+			if err == nil && account != nil && account.Version > stored.Version {
+				continue
+			}
+			if err != nil || !domain.SameState(account, stored) {
+				mismatches++
+				rp.logger.Error("account stream doesn't replay to the account", "tenant_id", stored.TenantID, "account_id", stored.ID, "version", stored.Version, "error", err)
+				continue
+			}
+			replayed++
+			if len(stream.Events) >= accountSnapshotEvery {
+				if err := rp.store.SaveAccountSnapshot(account); err != nil {
+					return err
+				}
+				snapshots++
+			}
+		}
+	}
+	rp.metrics.Set("account_stream_mismatches", float64(mismatches))
+	rp.logger.Info("account streams replayed", "accounts", replayed, "snapshots", snapshots, "mismatches", mismatches)
+	if mismatches > 0 {
+		rp.reporter.Report(fmt.Errorf("%d account stream(s) don't replay to the account", mismatches), nil, nil)
+	}
+	return nil
+}
+
+// handleAccountHistory serves GET /admin/accounts/{id}/history?tenant=&after=,
+// the account's event stream from the version after on.
+func (s *APIServer) handleAccountHistory(w http.ResponseWriter, r *http.Request) error {
+	ref, err := parseAccountRef(r)
+	if err != nil {
+		return err
+	}
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	account, err := ref.lookup(store)
+	if err != nil {
+		return err
+	}
+	s.resolveAccountRef(w, r, ref, account)
+	after, err := QueryInt(r, "after", 0, 0, account.Version)
+	if err != nil {
+		return err
+	}
+	events, err := store.AccountEvents(account.ID, after, 100)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, events)
+}
+
+// handleAccountState serves GET /admin/accounts/{id}/state?tenant=&at=, the
+// account as it was at the time at, replayed from its event stream.
+func (s *APIServer) handleAccountState(w http.ResponseWriter, r *http.Request) error {
+	ref, err := parseAccountRef(r)
+	if err != nil {
+		return err
+	}
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	account, err := ref.lookup(store)
+	if err != nil {
+		return err
+	}
+	s.resolveAccountRef(w, r, ref, account)
+	at, err := QueryTime(r, "at", time.RFC3339, time.UTC, time.Time{})
+	if err != nil {
+		return err
+	}
+	stream, err := store.AccountStream(account.ID, at)
+	if err != nil {
+		return err
+	}
+	state, err := stream.Replay(at)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return domain.NotFound(domain.ErrAccountHistoryNotFound, account.ID)
+	}
+	return WriteJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// accountRef is the {id} of an account route. Clients address accounts by
+// UUID; the serial id is still accepted until the deprecation window ends and
+// such responses carry a Deprecation header.
+type accountRef struct {
+	id   int
+	uuid string
+}
+
+func parseAccountRef(r *http.Request) (accountRef, error) {
+	if v := r.PathValue("id"); domain.IsUUID(v) {
+		return accountRef{uuid: strings.ToLower(v)}, nil
+	}
+	id, err := PathInt(r, "id")
+	return accountRef{id: id}, err
+}
+
+func (ref accountRef) matches(account *domain.Account) bool {
+	if ref.uuid != "" {
+		return ref.uuid == account.UUID
+	}
+	return ref.id == account.ID
+}
+
+func (ref accountRef) lookup(store storage.Storage) (*domain.Account, error) {
+	if ref.uuid != "" {
+		return store.GetAccountByUUID(ref.uuid)
+	}
+	return store.GetAccountById(ref.id)
+}
+
+// resolveAccountRef tells a client still on serial ids to move over, and points the
+// {id} path value at the internal id the handlers work with.
+func (s *APIServer) resolveAccountRef(w http.ResponseWriter, r *http.Request, ref accountRef, account *domain.Account) {
+	if ref.uuid == "" {
+		w.Header().Set("Deprecation", "true")
+		s.metrics.Inc("deprecated_account_ids_total")
+	}
+	r.SetPathValue("id", strconv.Itoa(account.ID))
+}
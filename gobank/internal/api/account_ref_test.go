@@ -0,0 +1,39 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccountRef(t *testing.T) {
+	account := &domain.Account{ID: 7, UUID: "9b2e1c4a-3f6d-4e8b-a1c2-0d3e4f5a6b7c"}
+	s := &APIServer{metrics: NewMetrics()}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetPathValue("id", "9B2E1C4A-3F6D-4E8B-A1C2-0D3E4F5A6B7C")
+	ref, err := parseAccountRef(r)
+	assert.Nil(t, err)
+	assert.True(t, ref.matches(account))
+	w := httptest.NewRecorder()
+	s.resolveAccountRef(w, r, ref, account)
+	assert.Equal(t, "", w.Header().Get("Deprecation"))
+	assert.Equal(t, "7", r.PathValue("id"))
+
+	r.SetPathValue("id", "7")
+	ref, err = parseAccountRef(r)
+	assert.Nil(t, err)
+	assert.True(t, ref.matches(account))
+	w = httptest.NewRecorder()
+	s.resolveAccountRef(w, r, ref, account)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+
+	r.SetPathValue("id", "8")
+	ref, _ = parseAccountRef(r)
+	assert.False(t, ref.matches(account))
+
+	r.SetPathValue("id", "not-an-id")
+	_, err = parseAccountRef(r)
+	assert.Equal(t, NewError(CodeInvalidParameter, "name", "id", "value", "not-an-id"), err)
+}
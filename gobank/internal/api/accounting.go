@@ -0,0 +1,388 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccountingExportJobType is the job that writes an accounting export an
+// admin asked for.
+const AccountingExportJobType = "export_accounting"
+
+// accountingPrefix starts the blob key of every accounting export, followed
+// by the tenant id, the period, the currency and the extension of the
+// format.
+const accountingPrefix = "accounting-"
+
+// The formats of accounting exports by the extension of their files:
+// QuickBooks Desktop IIF and the manual journal CSV Xero imports.
+var accountingFormats = map[string]string{
+	"quickbooks": "iif",
+	"xero":       "csv",
+}
+
+// AccountingAccounts are the accounts of the finance team's chart of
+// accounts the journals post to, names for QuickBooks and codes for Xero.
+// Deposits is the liability of the customer balances, the others take the
+// other side of each kind of ledger entry: transfers, fees, imports and
+// anything else, such as sandbox top-ups.
+type AccountingAccounts struct {
+	Deposits  string `json:"deposits"`
+	Transfers string `json:"transfers"`
+	Fees      string `json:"fees"`
+	Imports   string `json:"imports"`
+	Other     string `json:"other"`
+}
+
+func (a AccountingAccounts) withDefaults() AccountingAccounts {
+	def := func(v *string, name string) {
+		if strings.TrimSpace(*v) == "" {
+			*v = name
+		}
+	}
+	def(&a.Deposits, "Customer Deposits")
+	def(&a.Transfers, "Transfer Clearing")
+	def(&a.Fees, "Fee Income")
+	def(&a.Imports, "Import Clearing")
+	def(&a.Other, "Suspense")
+	return a
+}
+
+// of is the account that takes the other side of entries of type typ.
+func (a AccountingAccounts) of(typ string) string {
+	switch typ {
+	case domain.TransactionTransferIn, domain.TransactionTransferOut:
+		return a.Transfers
+	case domain.TransactionFee:
+		return a.Fees
+	case domain.TransactionImport:
+		return a.Imports
+	}
+	return a.Other
+}
+
+// AccountingExportRequest asks for the journals of the UTC days From to To,
+// To exclusive, in one currency or, when Currency is empty, in each.
+type AccountingExportRequest struct {
+	Format   string             `json:"format"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Currency string             `json:"currency,omitempty"`
+	Accounts AccountingAccounts `json:"accounts"`
+}
+
+// AccountingExportJob is the payload of an AccountingExportJobType job, for
+// the tenant of slug Tenant. Name is the key the export is written to.
+type AccountingExportJob struct {
+	Tenant  string                  `json:"tenant"`
+	Name    string                  `json:"name"`
+	Request AccountingExportRequest `json:"request"`
+}
+
+// AccountingExport describes an export, one that is queued can be
+// downloaded once its job wrote it.
+type AccountingExport struct {
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// accountingExportName is the key of the export of req for the tenant. The
+// same request writes the same key, so asking again replaces the export.
+func accountingExportName(tenantID int, req *AccountingExportRequest) string {
+	currency := req.Currency
+	if currency == "" {
+		currency = "all"
+	}
+	return fmt.Sprintf("%s%d-%s-%s-%s.%s", accountingPrefix, tenantID,
+		strings.ReplaceAll(req.From, "-", ""), strings.ReplaceAll(req.To, "-", ""), currency, accountingFormats[req.Format])
+}
+
+// parseAccountingExportName reads the export back from its key, false for
+// keys of other tenants or that aren't exports.
+func parseAccountingExportName(tenantID int, name string) (AccountingExport, bool) {
+	rest, ok := strings.CutPrefix(name, accountingPrefix+strconv.Itoa(tenantID)+"-")
+	if !ok {
+		return AccountingExport{}, false
+	}
+	rest, ext, _ := strings.Cut(rest, ".")
+	parts := strings.Split(rest, "-")
+	if len(parts) != 3 {
+		return AccountingExport{}, false
+	}
+	from, err := time.Parse("20060102", parts[0])
+	if err != nil {
+		return AccountingExport{}, false
+	}
+	to, err := time.Parse("20060102", parts[1])
+	if err != nil {
+		return AccountingExport{}, false
+	}
+	export := AccountingExport{Name: name, From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	if parts[2] != "all" {
+		export.Currency = parts[2]
+	}
+	for format, e := range accountingFormats {
+		if e == ext {
+			export.Format = format
+		}
+	}
+	return export, export.Format != ""
+}
+
+// journal is the entry of one UTC day and currency. Positive amounts are
+// debits and negative ones credits, they add up to zero.
+type journal struct {
+	Date  string
+	Lines []journalLine
+}
+
+type journalLine struct {
+	Account string
+	Amount  domain.Money
+}
+
+// accountingJournals turns the ledger totals into a journal per day and
+// currency. A customer credit is a credit to the deposits, so each line of
+// the other accounts is what their entries moved, and deposits take the
+// opposite of the sum. Lines that cancel out are left out, and so are days
+// where everything does. History entries moved no money here, so they have
+// no line.
+func accountingJournals(totals []*domain.LedgerTotal, accounts AccountingAccounts) []*journal {
+	var journals []*journal
+	for i := 0; i < len(totals); {
+		day, currency := totals[i].Date, totals[i].Currency
+		j := &journal{Date: day}
+		deposits := journalLine{Account: accounts.Deposits, Amount: domain.Money{Currency: currency}}
+		var lines []journalLine
+		for ; i < len(totals) && totals[i].Date == day && totals[i].Currency == currency; i++ {
+			if totals[i].Type == domain.TransactionHistory {
+				continue
+			}
+			account := accounts.of(totals[i].Type)
+			deposits.Amount.MinorUnits -= totals[i].Amount.MinorUnits
+			k := 0
+			for k < len(lines) && lines[k].Account != account {
+				k++
+			}
+			if k == len(lines) {
+				lines = append(lines, journalLine{Account: account, Amount: domain.Money{Currency: currency}})
+			}
+			lines[k].Amount.MinorUnits += totals[i].Amount.MinorUnits
+		}
+		for _, line := range append([]journalLine{deposits}, lines...) {
+			if line.Amount.MinorUnits != 0 {
+				j.Lines = append(j.Lines, line)
+			}
+		}
+		if len(j.Lines) > 0 {
+			journals = append(journals, j)
+		}
+	}
+	return journals
+}
+
+// journalMemo names the journal in the accounting system.
+func journalMemo(tenant *domain.Tenant, j *journal) string {
+	return fmt.Sprintf("gobank %s %s ledger %s", tenant.Slug, j.Lines[0].Amount.Currency, j.Date)
+}
+
+// iifField keeps tabs and line breaks, which separate IIF fields and rows,
+// out of a field.
+func iifField(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// writeIIF writes the journals as QuickBooks general journal transactions,
+// the first line of each is the transaction and the others its splits.
+func writeIIF(w io.Writer, tenant *domain.Tenant, journals []*journal) error {
+	var buf bytes.Buffer
+	buf.WriteString("!TRNS\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\n")
+	buf.WriteString("!SPL\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\n")
+	buf.WriteString("!ENDTRNS\n")
+	for _, j := range journals {
+		day, err := time.Parse("2006-01-02", j.Date)
+		if err != nil {
+			return err
+		}
+		memo := iifField(journalMemo(tenant, j))
+		for i, line := range j.Lines {
+			kind := "SPL"
+			if i == 0 {
+				kind = "TRNS"
+			}
+			fmt.Fprintf(&buf, "%s\tGENERAL JOURNAL\t%s\t%s\t%s\t%s\n", kind, day.Format("01/02/2006"), iifField(line.Account), line.Amount.Decimal(), memo)
+		}
+		buf.WriteString("ENDTRNS\n")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeXeroCSV writes the journals as Xero manual journals, the lines of a
+// journal share its narration and date. None is taxed.
+func writeXeroCSV(w io.Writer, tenant *domain.Tenant, journals []*journal) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"})
+	for _, j := range journals {
+		memo := journalMemo(tenant, j)
+		for _, line := range j.Lines {
+			cw.Write([]string{memo, j.Date, memo, line.Account, "Tax Exempt", line.Amount.Decimal()})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AccountingExporter writes the accounting exports admins ask for to the
+// reports store.
+type AccountingExporter struct {
+	store  storage.Storage
+	blobs  storage.BlobStore
+	logger *slog.Logger
+}
+
+func NewAccountingExporter(store storage.Storage, blobs storage.BlobStore, logger *slog.Logger) *AccountingExporter {
+	return &AccountingExporter{store: store, blobs: blobs, logger: logger}
+}
+
+func (e *AccountingExporter) HandleJob(job *domain.Job) error {
+	var payload AccountingExportJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	req := payload.Request
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return err
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return err
+	}
+	tenant, err := e.store.GetTenantBySlug(payload.Tenant)
+	if err != nil {
+		return err
+	}
+	totals, err := e.store.ForTenant(tenant.ID).LedgerTotals(from, to, req.Currency)
+	if err != nil {
+		return err
+	}
+	journals := accountingJournals(totals, req.Accounts.withDefaults())
+	var buf bytes.Buffer
+	if req.Format == "xero" {
+		err = writeXeroCSV(&buf, tenant, journals)
+	} else {
+		err = writeIIF(&buf, tenant, journals)
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.blobs.Put(payload.Name, &buf); err != nil {
+		return err
+	}
+	e.logger.Info("accounting export written", "tenant_id", tenant.ID, "export", payload.Name, "journals", len(journals))
+	return nil
+}
+
+// handleAccountingExports serves /admin/reports/accounting?tenant=. GET
+// lists the tenant's exports, POST queues one, which can be downloaded by
+// its name once the job wrote it.
+func (s *APIServer) handleAccountingExports(w http.ResponseWriter, r *http.Request) error {
+	_, tenant, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	if isGet(r) {
+		keys, err := s.reports.List(accountingPrefix + strconv.Itoa(tenant.ID) + "-")
+		if err != nil {
+			return err
+		}
+		exports := make([]AccountingExport, 0, len(keys))
+		for _, key := range keys {
+			if export, ok := parseAccountingExportName(tenant.ID, key); ok {
+				exports = append(exports, export)
+			}
+		}
+		return WriteJSON(w, http.StatusOK, exports)
+	}
+
+	req := new(AccountingExportRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if _, ok := accountingFormats[req.Format]; !ok {
+		return invalidParameter("format", req.Format)
+	}
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return invalidParameter("from", req.From)
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil || !from.Before(to) || to.Sub(from) > 366*24*time.Hour {
+		return invalidParameter("to", req.To)
+	}
+	req.Currency = strings.ToUpper(req.Currency)
+	if _, ok := domain.LookupCurrency(req.Currency); req.Currency != "" && !ok {
+		return invalidParameter("currency", req.Currency)
+	}
+	req.Accounts = req.Accounts.withDefaults()
+	name := accountingExportName(tenant.ID, req)
+	job, err := domain.NewJob(AccountingExportJobType, AccountingExportJob{Tenant: tenant.Slug, Name: name, Request: *req}, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.store.EnqueueJob(job); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusAccepted, AccountingExport{Name: name, Format: req.Format, From: req.From, To: req.To, Currency: req.Currency})
+}
+
+// handleAccountingExport serves GET /admin/reports/accounting/{name}?tenant=,
+// an export as its job wrote it.
+func (s *APIServer) handleAccountingExport(w http.ResponseWriter, r *http.Request) error {
+	_, tenant, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	name := r.PathValue("name")
+	export, ok := parseAccountingExportName(tenant.ID, name)
+	if !ok {
+		return NewError(CodeNotFound, "id", name)
+	}
+	blob, err := s.reports.Get(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewError(CodeNotFound, "id", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	contentType := "text/csv"
+	if export.Format == "quickbooks" {
+		contentType = "application/x-iif"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	_, err = io.Copy(w, blob)
+	return err
+}
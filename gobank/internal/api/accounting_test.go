@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAccountingJournals(t *testing.T) {
+	eur := func(units int64) domain.Money { return domain.Money{MinorUnits: units, Currency: "EUR"} }
+	totals := []*domain.LedgerTotal{
+		{Date: "2024-05-01", Currency: "EUR", Type: domain.TransactionFee, Amount: eur(-150)},
+		{Date: "2024-05-01", Currency: "EUR", Type: domain.TransactionHistory, Amount: eur(1000000)},
+		{Date: "2024-05-01", Currency: "EUR", Type: domain.TransactionImport, Amount: eur(10000)},
+		{Date: "2024-05-01", Currency: "EUR", Type: domain.TransactionTransferIn, Amount: eur(2500)},
+		{Date: "2024-05-01", Currency: "EUR", Type: domain.TransactionTransferOut, Amount: eur(-2500)},
+		{Date: "2024-05-02", Currency: "EUR", Type: domain.TransactionTransferIn, Amount: eur(700)},
+		{Date: "2024-05-02", Currency: "EUR", Type: domain.TransactionTransferOut, Amount: eur(-700)},
+	}
+	journals := accountingJournals(totals, AccountingAccounts{Fees: "4100"}.withDefaults())
+	// the transfers of the 2nd cancel out
+	if assert.Len(t, journals, 1) {
+		assert.Equal(t, []journalLine{
+			{Account: "Customer Deposits", Amount: eur(-9850)},
+			{Account: "4100", Amount: eur(-150)},
+			{Account: "Import Clearing", Amount: eur(10000)},
+		}, journals[0].Lines)
+	}
+
+	tenant := &domain.Tenant{Slug: "acme"}
+	var buf bytes.Buffer
+	assert.NoError(t, writeIIF(&buf, tenant, journals))
+	assert.Equal(t, "!TRNS\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\n!SPL\tTRNSTYPE\tDATE\tACCNT\tAMOUNT\tMEMO\n!ENDTRNS\n"+
+		"TRNS\tGENERAL JOURNAL\t05/01/2024\tCustomer Deposits\t-98.50\tgobank acme EUR ledger 2024-05-01\n"+
+		"SPL\tGENERAL JOURNAL\t05/01/2024\t4100\t-1.50\tgobank acme EUR ledger 2024-05-01\n"+
+		"SPL\tGENERAL JOURNAL\t05/01/2024\tImport Clearing\t100.00\tgobank acme EUR ledger 2024-05-01\n"+
+		"ENDTRNS\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeXeroCSV(&buf, tenant, journals))
+	assert.Contains(t, buf.String(), "gobank acme EUR ledger 2024-05-01,2024-05-01,gobank acme EUR ledger 2024-05-01,4100,Tax Exempt,-1.50\n")
+}
+
+func TestAccountingExportName(t *testing.T) {
+	req := &AccountingExportRequest{Format: "quickbooks", From: "2024-05-01", To: "2024-06-01"}
+	name := accountingExportName(3, req)
+	assert.Equal(t, "accounting-3-20240501-20240601-all.iif", name)
+	export, ok := parseAccountingExportName(3, name)
+	assert.True(t, ok)
+	assert.Equal(t, AccountingExport{Name: name, Format: "quickbooks", From: "2024-05-01", To: "2024-06-01"}, export)
+	_, ok = parseAccountingExportName(4, name)
+	assert.False(t, ok)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+// handleActivity serves GET /account/{id}/activity, the account's logins,
+// profile changes, transactions, holds and access grants on one timeline,
+// newest first. ?type= takes a comma separated list of the kinds to show.
+func (s *APIServer) handleActivity(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	kinds, err := QueryEnums(r, "type", domain.ActivityTypes, domain.ActivityTypes...)
+	if err != nil {
+		return err
+	}
+	var before domain.ActivityCursor
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &before.At, &before.Source, &before.ID); err != nil {
+			return err
+		}
+	}
+	activity, err := s.storeFor(r).ActivityBefore(id, kinds, before, limit+1)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, NewPage(activity, limit, func(a *domain.Activity) []any { return []any{a.At, a.Source, a.ID} }))
+}
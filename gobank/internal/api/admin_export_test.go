@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAccountStore pages through a fixed set of accounts.
+type fakeAccountStore struct {
+	storage.Storage
+	accounts []*domain.Account
+	calls    int
+}
+
+func (f *fakeAccountStore) GetTenantBySlug(slug string) (*domain.Tenant, error) {
+	return &domain.Tenant{ID: 1, Slug: slug}, nil
+}
+
+func (f *fakeAccountStore) ForTenant(int) storage.Storage { return f }
+
+func (f *fakeAccountStore) AccountsAfter(afterID int, filter storage.AccountFilter, limit int) ([]*domain.Account, error) {
+	f.calls++
+	page := []*domain.Account{}
+	for _, a := range f.accounts {
+		if a.ID > afterID && len(page) < limit {
+			page = append(page, a)
+		}
+	}
+	return page, nil
+}
+
+func TestExportAccountsStreamsAllBatches(t *testing.T) {
+	store := &fakeAccountStore{}
+	for id := 1; id <= 2*accountExportBatch+1; id++ {
+		store.accounts = append(store.accounts, &domain.Account{ID: id, Balance: domain.Money{Currency: "USD"}, CreatedAt: time.Now()})
+	}
+	s := &APIServer{store: store}
+	rec := httptest.NewRecorder()
+	err := s.handleExportAccounts(rec, httptest.NewRequest("GET", "/admin/accounts/export?from=2024-01-01", nil))
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Equal(t, 2*accountExportBatch+2, len(lines))
+	assert.Equal(t, 3, store.calls)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+}
+
+func TestListAccountsNeedsAdmin(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "secret")
+	store := &fakeAccountStore{accounts: []*domain.Account{{ID: 1, FirstName: "Ada", Balance: domain.Money{Currency: "USD"}}}}
+	s := NewAPIServer(NewLiveConfig(&Config{}), store, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, NewMetrics(), nil, nil, nil, nil, nil, nil)
+	routes := s.routes()
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			r.Header.Set("x-admin-token", token)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// the listing isn't public any more
+	assert.Equal(t, http.StatusMethodNotAllowed, serve("/account", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/admin/accounts", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/admin/accounts", "wrong").Code)
+	assert.Equal(t, 0, store.calls)
+
+	rec := serve("/admin/accounts", "secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"firstName":"Ada"`)
+	assert.Equal(t, 1, store.calls)
+}
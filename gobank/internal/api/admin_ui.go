@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"embed"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"html/template"
+	"net/http"
+	"os"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var adminUITemplate = template.Must(template.ParseFS(templateFiles, "templates/admin_ui.html"))
+
+const adminUIRows = 200
+
+type adminUIPage struct {
+	Version           string
+	Maintenance       string
+	Tenant            *domain.Tenant
+	Tenants           []*domain.Tenant
+	Accounts          []*domain.Account
+	AccountsTruncated bool
+	Transfers         []*domain.Transaction
+	DeadJobs          []*domain.Job
+	StuckSagas        []*domain.Saga
+	Events            []*domain.Event
+}
+
+// withAdminUIAuth is withAdminAuth for browsers: they can't send
+// x-admin-token, so ADMIN_TOKEN is accepted as the HTTP basic auth password.
+func withAdminUIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		if serviceAccountFrom(request.Context()).HasScope(auth.ScopeAdmin) {
+			next.ServeHTTP(w, request)
+			return
+		}
+		secret := os.Getenv("ADMIN_TOKEN")
+		_, password, ok := request.BasicAuth()
+		if !ok || secret == "" || subtle.ConstantTimeCompare([]byte(password), []byte(secret)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gobank admin", charset="UTF-8"`)
+			writeError(w, request, http.StatusUnauthorized, NewError(CodePermissionDenied))
+			return
+		}
+		next.ServeHTTP(w, request)
+	})
+}
+
+// handleAdminUI renders the operator dashboard at /admin/ui?tenant=slug.
+func (s *APIServer) handleAdminUI(w http.ResponseWriter, r *http.Request) error {
+	store, tenant, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+
+	page := adminUIPage{Version: s.version.Version, Maintenance: s.maintenance.State().Mode, Tenant: tenant}
+	if page.Tenants, err = s.store.ListTenants(); err != nil {
+		return err
+	}
+	accounts, err := store.AccountsAfter(0, storage.AccountFilter{}, adminUIRows+1)
+	if err != nil {
+		return err
+	}
+	accountPage := NewPage(accounts, adminUIRows, func(a *domain.Account) []any { return []any{a.ID} })
+	page.Accounts, page.AccountsTruncated = accountPage.Items, accountPage.HasMore
+	if page.Transfers, err = store.RecentTransfers(50); err != nil {
+		return err
+	}
+	if page.DeadJobs, err = s.store.ListJobs(domain.JobDead); err != nil {
+		return err
+	}
+	if page.StuckSagas, err = s.store.StuckSagas(); err != nil {
+		return err
+	}
+	if page.Events, err = s.store.EventsBefore(0, 50); err != nil {
+		return err
+	}
+	// render first so a template error still becomes a proper error response
+	var buf bytes.Buffer
+	if err := adminUITemplate.Execute(&buf, page); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAdminUITemplate(t *testing.T) {
+	now := time.Now()
+	tenant := &domain.Tenant{ID: 1, Slug: "default", Name: "Default"}
+	page := adminUIPage{
+		Version:     "dev",
+		Maintenance: MaintenanceReadOnly,
+		Tenant:      tenant,
+		Tenants:     []*domain.Tenant{tenant},
+		Accounts:    []*domain.Account{{ID: 1, Number: 42, FirstName: "Ada", LastName: "<script>", Balance: domain.Money{MinorUnits: 1050, Currency: "USD"}, CreatedAt: now}},
+		Transfers:   []*domain.Transaction{{ID: 3, AccountID: 1, Counterparty: 7, Amount: domain.Money{MinorUnits: -500, Currency: "USD"}, CreatedAt: now}},
+		DeadJobs:    []*domain.Job{{ID: 9, Type: ArchiveJobType, Attempts: 5, MaxAttempts: 5, LastError: "boom", CreatedAt: now}},
+		StuckSagas:  []*domain.Saga{{ID: "s1", Type: "execute_quote", Status: domain.SagaStuck, Steps: []domain.SagaStep{{Name: "claim quote", Status: domain.StepCompensationFailed}}, UpdatedAt: now}},
+		Events:      []*domain.Event{{ID: 11, Type: domain.EventAccountCreated, AccountID: 1, Payload: json.RawMessage(`{"number":42}`), CreatedAt: now}},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, adminUITemplate.Execute(&buf, page))
+	html := buf.String()
+	assert.Contains(t, html, "Maintenance mode: read-only")
+	assert.Contains(t, html, "10.50 USD")
+	assert.Contains(t, html, "&lt;script&gt;")
+	assert.Contains(t, html, "boom")
+	assert.Contains(t, html, "claim quote: compensation_failed")
+	assert.Contains(t, html, "account.created")
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+// handleDailyTotals serves GET /account/{id}/totals?days=30&tz=Europe/Berlin.
+func (s *APIServer) handleDailyTotals(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	days, err := QueryInt(r, "days", 30, 1, 366)
+	if err != nil {
+		return err
+	}
+	since := domain.StartOfDay(s.clock.Now(), loc).AddDate(0, 0, -(days - 1))
+	totals, err := store.DailyTotals(account.ID, since, loc)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, totals)
+}
+
+// handleMonthlyTotals serves GET /account/{id}/totals/monthly?months=12,
+// the months of the account's time zone from the monthly projection.
+func (s *APIServer) handleMonthlyTotals(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	loc, err := loadLocation(account.Timezone)
+	if err != nil {
+		return err
+	}
+	months, err := QueryInt(r, "months", 12, 1, 120)
+	if err != nil {
+		return err
+	}
+	since := domain.StartOfMonth(s.clock.Now(), loc).AddDate(0, -(months - 1), 0)
+	totals, err := store.MonthlyTotals(account.ID, since.Format("2006-01"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, totals)
+}
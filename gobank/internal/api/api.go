@@ -0,0 +1,825 @@
+// Package api is the HTTP server: routing, middleware, handlers and the
+// background jobs they schedule.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+type apiFunc func(w http.ResponseWriter, r *http.Request) error
+
+type APIServer struct {
+	listenAddr  string
+	store       storage.Storage
+	config      *LiveConfig
+	settings    *TenantSettingsCache
+	maintenance *Maintenance
+	chaos       *Chaos
+	limiter     *RateLimiter
+	// lookupLimiter holds GET /account/lookup to LookupsPerMinute.
+	lookupLimiter *RateLimiter
+	logger        *slog.Logger
+	version       VersionInfo
+	reporter      ErrorReporter
+	metrics       *Metrics
+	notifier      *Notifier
+	clock         domain.Clock
+	schemas       SchemaSet
+	// servedPaths are the route templates, set once the router is built.
+	servedPaths map[string]bool
+	recorder    *Recorder
+	// statements hold the camt.053 end of day statements.
+	statements storage.BlobStore
+	// reports hold the large-transaction reports.
+	reports storage.BlobStore
+	// avatars hold the account pictures.
+	avatars storage.BlobStore
+	// screening screens transfers, nil when they aren't.
+	screening service.ScreeningProvider
+	// sms texts the phone verification codes, nil when no provider is set.
+	sms    SMSProvider
+	server *http.Server
+	// stop ends the background work Run started, done is closed once it has.
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewAPIServer(config *LiveConfig, store storage.Storage, clock domain.Clock, logger *slog.Logger, reporter ErrorReporter, metrics *Metrics, recorder *Recorder, statements, reports, avatars storage.BlobStore, screening service.ScreeningProvider, sms SMSProvider) *APIServer {
+	s := &APIServer{
+		listenAddr:  config.Get().ListenAddr,
+		server:      &http.Server{Addr: config.Get().ListenAddr},
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		store:       store,
+		logger:      logger,
+		reporter:    reporter,
+		metrics:     metrics,
+		recorder:    recorder,
+		statements:  statements,
+		reports:     reports,
+		avatars:     avatars,
+		screening:   screening,
+		sms:         sms,
+		version:     buildVersion(),
+		config:      config,
+		maintenance: NewMaintenance(),
+		notifier:    NewNotifier(),
+		clock:       clock,
+	}
+	schemas, err := loadSchemas()
+	if err != nil {
+		panic(err)
+	}
+	s.schemas = schemas
+	s.metrics.Help("http_request_duration_seconds", "Latency of HTTP requests by route.")
+	s.metrics.Help("deprecated_account_ids_total", "Account requests addressing the account by serial id instead of UUID.")
+	s.settings = NewTenantSettingsCache(store, time.Minute, s.defaultTenantSettings)
+	s.limiter = NewRateLimiter(func() int { return config.Get().Runtime.RateLimitPerMinute })
+	s.lookupLimiter = NewRateLimiter(func() int { return config.Get().Runtime.LookupsPerMinute })
+	s.version.Mode = config.Get().Mode
+	if config.Get().Sandbox() {
+		s.chaos = NewChaos()
+	}
+	return s
+}
+
+// Run serves the API until Shutdown is called. It returns nil after a
+// shutdown.
+func (s *APIServer) Run() error {
+	router := s.routes()
+	go func() {
+		defer close(s.done)
+		flushUsage(s.store, s.limiter, time.Minute, s.logger, s.stop)
+	}()
+	s.logger.Info("API server running", "addr", s.listenAddr, "version", s.version.Version)
+	err := s.listen(router)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests, waits for the ones in flight and
+// flushes the usage counters.
+func (s *APIServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	close(s.stop)
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return err
+}
+
+// routes builds the router. Anything added here that a client can call belongs
+// in apiOperations and the client package too, the tests keep them in step.
+func (s *APIServer) routes() *Router {
+	// Every route runs recovery, request id, logging, CORS, auth and rate
+	// limiting in that order. The groups below differ only in the auth step,
+	// routes needing something else build their own from common.
+	common := Chain{s.withRecovery, s.withRequestID, s.withRequestLogging, s.withRecording, s.withCachePolicy, s.withContentNegotiation, s.withAPIVersion, s.withLocale, s.withChaos, s.withClientCert, s.withVersionHeader, s.withCORS, s.withMaintenance, s.withTenant}
+	router := NewRouter(common)
+	public := router.Group("", common.Use(s.withRateLimit, s.withSchemaValidation))
+	account := router.Group("/account/{id}", common.Use(s.withAccountAuth, s.withRateLimit, s.withSchemaValidation))
+	admin := router.Group("/admin", common.Use(withAdminAuth, s.withRateLimit))
+
+	public.HandleFunc("GET", "/version", s.handleVersion)
+	public.HandleFunc("GET", "/schemas/", s.handleSchema)
+	public.HandleFunc("GET", "/schemas/{name}", s.handleSchema)
+	public.HandleFunc("GET", "/dev/collection", s.handleCollection)
+	public.HandleFunc("GET", "/reference/currencies", s.handleReference)
+	public.HandleFunc("GET", "/reference/countries", s.handleReference)
+	public.HandleFunc("GET", "/reference/account-types", s.handleReference)
+	public.HandleFunc("POST", "/login", s.HandleLogin)
+	public.With(s.withLookupRateLimit).HandleFunc("GET", "/account/lookup", s.handleAccountLookup)
+	public.HandleFunc("GET", "/avatars/{name}", s.handleAvatar)
+	public.HandleFunc("POST", "/account", s.handleCreateAccount)
+	public.HandleFunc("GET", "/terms", s.handleCurrentTerms)
+	// SNS can't log in, the bounce notifications carry a token of their own
+	public.HandleFunc("POST", "/email/bounces", s.handleEmailBounces)
+	// everything that moves money needs the current terms accepted
+	money := public.With(s.withTermsAccepted)
+	money.HandleFunc("POST", "/transfer", s.handleTransfer)
+	public.HandleFunc("GET", "/transfer/{id}", s.handleTransferStatus)
+	money.HandleFunc("POST", "/transfer/quote", s.handleTransferQuote)
+	money.HandleFunc("POST", "/transfers/batch", s.handleBatchTransfer)
+	money.HandleFunc("POST", "/transfer/authorize", s.handleAuthorizeTransfer)
+	public.HandleFunc("GET", "/transfer/holds/{holdId}", s.handleGetHold)
+	money.HandleFunc("POST", "/transfer/holds/{holdId}/capture", s.handleCaptureTransfer)
+	money.HandleFunc("POST", "/transfer/holds/{holdId}/void", s.handleVoidTransfer)
+	openBanking := router.Group("/open-banking/v1", common.Use(s.withRateLimit, s.withSchemaValidation))
+	openBanking.HandleFunc("GET", "/accounts", s.handleOBAccounts)
+	openBanking.HandleFunc("GET", "/accounts/{accountId}", s.handleOBAccount)
+	openBanking.HandleFunc("GET", "/accounts/{accountId}/balances", s.handleOBBalances)
+	openBanking.HandleFunc("GET", "/accounts/{accountId}/transactions", s.handleOBTransactions)
+	openBanking.With(s.withTermsAccepted).HandleFunc("POST", "/payments/"+obPaymentProduct, s.handleOBInitiatePayment)
+	openBanking.HandleFunc("GET", "/payments/"+obPaymentProduct+"/{paymentId}/status", s.handleOBPaymentStatus)
+	if s.config.Get().Sandbox() {
+		router.Group("/sandbox", account.chain).HandleFunc("POST", "/account/{id}/topup", s.handleSandboxTopUp)
+	}
+
+	account.HandleFunc("GET", "", s.handleGetAccountById)
+	account.HandleFunc("PATCH", "", s.handleUpdateAccount)
+	account.HandleFunc("DELETE", "", s.handleDeleteAccount)
+	account.HandleFunc("PUT", "/avatar", s.handleUploadAvatar)
+	account.HandleFunc("DELETE", "/avatar", s.handleDeleteAvatar)
+	account.HandleFunc("POST", "/phone/verification", s.handleSendPhoneCode)
+	account.HandleFunc("POST", "/phone/verification/confirm", s.handleConfirmPhone)
+	account.HandleFunc("GET", "/devices", s.handleDevices)
+	account.HandleFunc("POST", "/devices", s.handleDevices)
+	account.HandleFunc("DELETE", "/devices/{deviceId}", s.handleDeleteDevice)
+	account.HandleFunc("GET", "/notifications", s.handleNotifications)
+	account.HandleFunc("POST", "/notifications/{notificationId}/read", s.handleReadNotification)
+	account.HandleFunc("POST", "/notifications/read-all", s.handleReadAllNotifications)
+	account.HandleFunc("GET", "/totals", s.handleDailyTotals)
+	account.HandleFunc("GET", "/totals/monthly", s.handleMonthlyTotals)
+	account.HandleFunc("GET", "/summary", s.handleAccountSummary)
+	account.HandleFunc("GET", "/pots", s.handlePots)
+	account.HandleFunc("POST", "/pots", s.handlePots)
+	account.HandleFunc("PATCH", "/pots/{potId}", s.handleUpdatePot)
+	account.HandleFunc("DELETE", "/pots/{potId}", s.handleDeletePot)
+	account.HandleFunc("GET", "/statement-destinations", s.handleStatementDestinations)
+	account.HandleFunc("POST", "/statement-destinations", s.handleStatementDestinations)
+	account.HandleFunc("DELETE", "/statement-destinations/{destinationId}", s.handleDeleteStatementDestination)
+	account.HandleFunc("GET", "/statement-destinations/{destinationId}/deliveries", s.handleStatementDeliveries)
+	account.HandleFunc("POST", "/statement-destinations/{destinationId}/deliveries/{deliveryId}/retry", s.handleRetryStatementDelivery)
+	account.HandleFunc("GET", "/transactions", s.handleListTransactions)
+	account.HandleFunc("GET", "/activity", s.handleActivity)
+	account.HandleFunc("GET", "/transactions/feed", s.handleTransactionFeed)
+	account.HandleFunc("GET", "/events", s.handleAccountEvents)
+	account.HandleFunc("POST", "/transactions/import", s.handleImportTransactions)
+	account.HandleFunc("GET", "/transactions/export", s.handleExportTransactions)
+	account.HandleFunc("GET", "/fdx", s.handleFDXExport)
+	account.HandleFunc("GET", "/statements", s.handleStatements)
+	account.HandleFunc("GET", "/statements/{date}", s.handleStatement)
+	account.HandleFunc("GET", "/usage", s.handleUsage)
+	account.HandleFunc("GET", "/api-keys", s.handleApiKeys)
+	account.HandleFunc("POST", "/api-keys", s.handleApiKeys)
+	account.HandleFunc("DELETE", "/api-keys/{keyId}", s.handleRevokeApiKey)
+	account.HandleFunc("GET", "/webhooks", s.handleWebhooks)
+	account.HandleFunc("POST", "/webhooks", s.handleWebhooks)
+	account.HandleFunc("DELETE", "/webhooks/{webhookId}", s.handleDeleteWebhook)
+	account.HandleFunc("POST", "/webhooks/{webhookId}/enable", s.handleEnableWebhook)
+	account.HandleFunc("GET", "/webhooks/{webhookId}/deliveries", s.handleWebhookDeliveries)
+	account.HandleFunc("POST", "/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", s.handleRedeliverWebhook)
+	account.HandleFunc("GET", "/terms", s.handleTermsStatus)
+	account.HandleFunc("POST", "/terms/accept", s.handleAcceptTerms)
+	account.HandleFunc("GET", "/impersonations", s.handleImpersonations)
+	account.HandleFunc("POST", "/impersonations/{impersonationId}/approve", s.handleApproveImpersonation)
+	account.HandleFunc("POST", "/impersonations/{impersonationId}/deny", s.handleDenyImpersonation)
+	account.HandleFunc("GET", "/consents", s.handleConsents)
+	account.HandleFunc("POST", "/consents", s.handleGrantConsent)
+	account.HandleFunc("DELETE", "/consents/{consentId}", s.handleRevokeConsent)
+
+	admin.HandleFunc("GET", "/tenants", s.handleTenants)
+	admin.HandleFunc("POST", "/tenants", s.handleTenants)
+	admin.HandleFunc("GET", "/tenants/{id}/settings", s.handleTenantSettings)
+	admin.HandleFunc("PUT", "/tenants/{id}/settings", s.handleTenantSettings)
+	admin.HandleFunc("GET", "/maintenance", s.handleMaintenance)
+	admin.HandleFunc("PUT", "/maintenance", s.handleMaintenance)
+	if s.chaos != nil {
+		admin.HandleFunc("GET", "/chaos", s.handleChaos)
+		admin.HandleFunc("PUT", "/chaos", s.handleChaos)
+	}
+	if _, ok := s.clock.(*domain.SimClock); ok {
+		admin.HandleFunc("GET", "/clock", s.handleClock)
+		admin.HandleFunc("POST", "/clock", s.handleClock)
+	}
+	admin.HandleFunc("POST", "/config/reload", s.handleReloadConfig)
+	admin.HandleFunc("GET", "/jobs", s.handleListJobs)
+	admin.HandleFunc("POST", "/jobs/{id}/retry", s.handleRetryJob)
+	admin.HandleFunc("GET", "/reports/daily", s.handleDailyReport)
+	admin.HandleFunc("GET", "/reports/large-transactions", s.handleLargeTransactionReports)
+	admin.HandleFunc("GET", "/reports/large-transactions/{name}", s.handleLargeTransactionReport)
+	admin.HandleFunc("GET", "/reports/accounting", s.handleAccountingExports)
+	admin.HandleFunc("POST", "/reports/accounting", s.handleAccountingExports)
+	admin.HandleFunc("GET", "/reports/accounting/{name}", s.handleAccountingExport)
+	admin.HandleFunc("GET", "/reports/balances", s.handleBalanceReport)
+	admin.HandleFunc("GET", "/projections", s.handleProjections)
+	admin.HandleFunc("POST", "/projections/{name}/rebuild", s.handleRebuildProjection)
+	admin.HandleFunc("GET", "/reconciliation/issues", s.handleListReconciliationIssues)
+	admin.HandleFunc("POST", "/reconciliation/issues/{id}/resolve", s.handleResolveReconciliationIssue)
+	admin.HandleFunc("GET", "/events", s.handleListEvents)
+	admin.HandleFunc("GET", "/sagas/stuck", s.handleStuckSagas)
+	router.Group("/admin", common.Use(withAdminUIAuth, s.withRateLimit)).HandleFunc("GET", "/ui", s.handleAdminUI)
+	admin.HandleFunc("GET", "/accounts", s.handleListAccounts)
+	admin.HandleFunc("GET", "/accounts/export", s.handleExportAccounts)
+	admin.HandleFunc("GET", "/accounts/portable", s.handlePortableAccounts)
+	admin.HandleFunc("POST", "/accounts/portable", s.handlePortableAccounts)
+	admin.HandleFunc("POST", "/payments/pain001", s.handleIngestPain001)
+	admin.HandleFunc("GET", "/payments/batch-files", s.handleBatchFiles)
+	admin.HandleFunc("GET", "/transfers/review", s.handleTransfersInReview)
+	admin.HandleFunc("POST", "/transfers/{id}/release", s.handleReviewTransfer)
+	admin.HandleFunc("POST", "/transfers/{id}/reject", s.handleReviewTransfer)
+	admin.HandleFunc("GET", "/accounts/{id}/ledger/verify", s.handleVerifyLedger)
+	admin.HandleFunc("GET", "/accounts/{id}/history", s.handleAccountHistory)
+	admin.HandleFunc("GET", "/accounts/{id}/state", s.handleAccountState)
+	admin.HandleFunc("POST", "/accounts/{id}/impersonations", s.handleImpersonate)
+	admin.HandleFunc("PUT", "/accounts/{id}/minimum-balance", s.handleMinimumBalance)
+	admin.HandleFunc("POST", "/impersonations/{id}/token", s.handleImpersonationToken)
+	admin.HandleFunc("POST", "/impersonations/{id}/revoke", s.handleRevokeImpersonation)
+	admin.HandleFunc("GET", "/email/suppressions", s.handleEmailSuppressions)
+	admin.HandleFunc("POST", "/email/suppressions", s.handleEmailSuppressions)
+	admin.HandleFunc("DELETE", "/email/suppressions/{address}", s.handleDeleteEmailSuppression)
+	s.registerDebugRoutes(router.Group("/debug", admin.chain))
+	// scrapers poll on a fixed schedule, they don't count against the limit
+	router.Group("", common.Use(withAdminAuth)).Handle("GET", "/metrics", http.HandlerFunc(s.handleMetrics))
+	if s.config.Get().ServeFrontend {
+		// the least specific pattern, the frontend only gets what the API doesn't match
+		registerFrontend(public)
+	}
+	s.servedPaths = router.Paths()
+	return router
+}
+
+// listen serves plain HTTP, or HTTPS with optional client certificates when a
+// certificate is configured.
+func (s *APIServer) listen(handler http.Handler) error {
+	cfg := s.config.Get()
+	handler, err := configureServer(s.server, cfg, handler)
+	if err != nil {
+		return err
+	}
+	s.server.Handler = handler
+	if cfg.TLSCertFile == "" {
+		return s.server.ListenAndServe()
+	}
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = tlsConfig
+	return s.server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+}
+
+// handleListAccounts serves GET /admin/accounts?tenant=, every account of
+// the tenant a page at a time, or all of them as NDJSON.
+func (s *APIServer) handleListAccounts(writer http.ResponseWriter, request *http.Request) error {
+	store, _, err := s.adminTenantStore(request)
+	if err != nil {
+		return err
+	}
+	if wantsNDJSON(request) {
+		return streamNDJSON(writer, request, store.EachAccount)
+	}
+	cursor, limit, err := pageParams(request)
+	if err != nil {
+		return err
+	}
+	after := 0
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &after); err != nil {
+			return err
+		}
+	}
+	accounts, err := store.AccountsAfter(after, storage.AccountFilter{}, limit+1)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(writer, http.StatusOK, NewPage(accounts, limit, func(a *domain.Account) []any { return []any{a.ID} }))
+}
+
+func (s *APIServer) handleGetAccountById(writer http.ResponseWriter, request *http.Request) error {
+	id, err := PathInt(request, "id")
+	if err != nil {
+		return err
+	}
+	account, err := s.storeFor(request).GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	setValidators(writer, account)
+	if wantsProtobuf(request) {
+		return writeProtobuf(writer, http.StatusOK, accountProto(account))
+	}
+	return WriteJSON(writer, http.StatusOK, accountView(request, account))
+}
+
+func (s *APIServer) handleCreateAccount(writer http.ResponseWriter, request *http.Request) error {
+	req := new(CreateAccountRequest)
+	if err := json.NewDecoder(request.Body).Decode(req); err != nil {
+		return err
+	}
+	account, err := domain.NewAccount(req.FirstName, req.LastName, req.Password)
+	if err != nil {
+		return err
+	}
+	if err := setContact(account, &req.Email, &req.Phone); err != nil {
+		return err
+	}
+	if err := setNickname(account, &req.Nickname); err != nil {
+		return err
+	}
+	if err := s.setProfile(account, req.Address, &req.DateOfBirth); err != nil {
+		return err
+	}
+	if req.Language != "" {
+		if !isSupportedLanguage(req.Language) {
+			return NewError(CodeUnknownLanguage, "language", req.Language)
+		}
+		account.Language = req.Language
+	}
+	if err := setLocale(account, req.Locale); err != nil {
+		return err
+	}
+	if req.Timezone != "" {
+		if _, err := loadLocation(req.Timezone); err != nil {
+			return err
+		}
+		account.Timezone = req.Timezone
+	}
+	if tenant := tenantFromContext(request.Context()); tenant != nil {
+		account.TenantID = tenant.ID
+	}
+	if err := s.accountsFor(request).Open(account); err != nil {
+		return err
+	}
+
+	return WriteJSON(writer, http.StatusOK, account)
+}
+
+// handleUpdateAccount applies a PATCH to the account. The write only goes
+// through if the account is still at the version the preconditions were
+// checked against.
+func (s *APIServer) handleUpdateAccount(writer http.ResponseWriter, request *http.Request) error {
+	id, err := PathInt(request, "id")
+	if err != nil {
+		return err
+	}
+	req := new(UpdateAccountRequest)
+	if err := json.NewDecoder(request.Body).Decode(req); err != nil {
+		return err
+	}
+	store := s.storeFor(request)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(request, account); err != nil {
+		return err
+	}
+	if req.FirstName != nil {
+		account.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		account.LastName = *req.LastName
+	}
+	if err := setContact(account, req.Email, req.Phone); err != nil {
+		return err
+	}
+	if err := setNickname(account, req.Nickname); err != nil {
+		return err
+	}
+	if err := s.setProfile(account, req.Address, req.DateOfBirth); err != nil {
+		return err
+	}
+	if req.Timezone != nil {
+		if _, err := loadLocation(*req.Timezone); err != nil {
+			return err
+		}
+		account.Timezone = *req.Timezone
+	}
+	if req.Language != nil {
+		if *req.Language != "" && !isSupportedLanguage(*req.Language) {
+			return NewError(CodeUnknownLanguage, "language", *req.Language)
+		}
+		account.Language = *req.Language
+	}
+	if req.Locale != nil {
+		if err := setLocale(account, *req.Locale); err != nil {
+			return err
+		}
+	}
+	if req.SMSAlerts != nil {
+		if *req.SMSAlerts && !account.PhoneVerified {
+			return domain.ErrPhoneNotVerified
+		}
+		account.SMSAlerts = *req.SMSAlerts
+	}
+	if err := store.UpdateAccount(account); err != nil {
+		return err
+	}
+	setValidators(writer, account)
+	return WriteJSON(writer, http.StatusOK, account)
+}
+
+// setContact normalizes, validates and sets the email address and phone
+// number, leaving out the ones that are nil. An empty one clears the field.
+func setContact(account *domain.Account, email, phone *domain.PII) error {
+	if email != nil {
+		account.Email = domain.NormalizeEmail(*email)
+		if account.Email != "" {
+			if err := domain.ValidateEmail(account.Email); err != nil {
+				return err
+			}
+		}
+	}
+	if phone != nil {
+		normalized := domain.NormalizePhone(*phone)
+		if normalized != account.Phone {
+			// a new number needs verifying again before it gets texts
+			account.PhoneVerified, account.SMSAlerts = false, false
+		}
+		account.Phone = normalized
+		if account.Phone != "" {
+			if err := domain.ValidatePhone(account.Phone); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setNickname trims, validates and sets the nickname unless it's nil. An
+// empty one clears it.
+func setNickname(account *domain.Account, nickname *string) error {
+	if nickname == nil {
+		return nil
+	}
+	account.Nickname = strings.TrimSpace(*nickname)
+	if account.Nickname == "" {
+		return nil
+	}
+	return domain.ValidateNickname(account.Nickname)
+}
+
+// setLocale sets the locale amounts and dates are written in for the
+// account, by its canonical tag. An empty one falls back to the language.
+func setLocale(account *domain.Account, tag string) error {
+	if tag == "" {
+		account.Locale = ""
+		return nil
+	}
+	locale, ok := domain.LookupLocale(tag)
+	if !ok {
+		return NewError(CodeUnknownLocale, "locale", tag)
+	}
+	account.Locale = locale.Tag
+	return nil
+}
+
+// setProfile validates and sets the address and date of birth, leaving out
+// the ones that are nil or empty.
+func (s *APIServer) setProfile(account *domain.Account, address *domain.Address, dob *domain.PII) error {
+	if address != nil {
+		address.Normalize()
+		if err := address.Validate(); err != nil {
+			return err
+		}
+		account.Address = address
+	}
+	if dob != nil && *dob != "" {
+		if err := domain.ValidateDateOfBirth(*dob, s.clock.Now()); err != nil {
+			return err
+		}
+		account.DateOfBirth = *dob
+	}
+	return nil
+}
+
+func (s *APIServer) handleDeleteAccount(writer http.ResponseWriter, request *http.Request) error {
+	id, err := PathInt(request, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(request)
+	version := 0
+	if hasPreconditions(request) {
+		account, err := store.GetAccountById(id)
+		if err != nil {
+			return err
+		}
+		if err := checkPreconditions(request, account); err != nil {
+			return err
+		}
+		version = account.Version
+	}
+	if err := store.DeleteAccount(id, version); err != nil {
+		return err
+	}
+	return WriteJSON(writer, http.StatusOK, map[string]int{"deleted": id})
+}
+
+// accountByIBAN looks up the account of an IBAN in print or electronic form
+// after checking its length and check digits.
+func accountByIBAN(store storage.Storage, iban string) (*domain.Account, error) {
+	iban = domain.NormalizeIBAN(iban)
+	if err := domain.ValidateIBAN(iban); err != nil {
+		return nil, err
+	}
+	return store.GetAccountByIBAN(iban)
+}
+
+// accountByAlias looks up the account of an email address or phone number,
+// told apart by the @, after normalizing and validating it like setContact.
+func accountByAlias(store storage.Storage, alias domain.PII) (*domain.Account, error) {
+	if strings.Contains(alias.Reveal(), "@") {
+		alias = domain.NormalizeEmail(alias)
+		if err := domain.ValidateEmail(alias); err != nil {
+			return nil, err
+		}
+	} else {
+		alias = domain.NormalizePhone(alias)
+		if err := domain.ValidatePhone(alias); err != nil {
+			return nil, err
+		}
+	}
+	return store.GetAccountByAlias(alias)
+}
+
+func (s *APIServer) handleTransfer(writer http.ResponseWriter, request *http.Request) error {
+	account, err := s.authenticate(request)
+	if err != nil {
+		authFailed(writer, request, err)
+		return nil
+	}
+	setAccountLanguage(request, account.Language)
+	request = withLoggerAttrs(request, "account_id", account.ID)
+	defer request.Body.Close()
+	transferReq := new(TransferAccount)
+	if isProtobuf(request) {
+		if transferReq, err = readTransferProto(writer, request); err != nil {
+			return err
+		}
+	} else if err := json.NewDecoder(request.Body).Decode(transferReq); err != nil {
+		return err
+	}
+	if transferReq.QuoteID != "" && (transferReq.ToAccount != 0 || transferReq.ToIBAN != "" || transferReq.ToAlias != "" || transferReq.Amount.MinorUnits != 0) {
+		// the quote fixes both, a body that restates them can't be meant for it
+		return invalidParameter("quoteId", transferReq.QuoteID)
+	}
+	if transferReq.ToIBAN != "" {
+		to, err := accountByIBAN(s.storeFor(request), transferReq.ToIBAN)
+		if err != nil {
+			return err
+		}
+		transferReq.ToAccount = to.Number
+	}
+	if transferReq.ToAlias != "" {
+		to, err := accountByAlias(s.storeFor(request), transferReq.ToAlias)
+		if err != nil {
+			return err
+		}
+		transferReq.ToAccount = to.Number
+	}
+	if transferReq.QuoteID == "" && prefersAsync(request) {
+		return s.acceptTransfer(writer, request, account, transferReq)
+	}
+	var transaction *domain.Transaction
+	switch {
+	case transferReq.QuoteID != "":
+		transaction, err = s.transfersFor(request).ExecuteQuote(account, transferReq.QuoteID)
+	case transferReq.AllowDuplicate:
+		transaction, err = s.transfersFor(request).Transfer(account, transferReq.ToAccount, transferReq.Amount)
+	default:
+		transaction, err = s.transfersFor(request).TransferOnce(account, transferReq.ToAccount, transferReq.Amount)
+	}
+	if err != nil {
+		return err
+	}
+	s.notifier.Notify()
+	logger := loggerFrom(request.Context())
+	if transferReq.QuoteID != "" {
+		logger = logger.With("quote_id", transferReq.QuoteID)
+	}
+	amount := domain.Money{MinorUnits: -transaction.Amount.MinorUnits, Currency: transaction.Amount.Currency}
+	logger.Info("transfer completed", "transaction_id", transaction.ID, "amount", amount.String())
+	if wantsProtobuf(request) {
+		return writeProtobuf(writer, http.StatusOK, transactionProto(transaction))
+	}
+	return WriteJSON(writer, http.StatusOK, transaction)
+}
+
+func (s *APIServer) handleListJobs(w http.ResponseWriter, r *http.Request) error {
+	jobs, err := s.store.ListJobs(r.URL.Query().Get("status"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, jobs)
+}
+
+func (s *APIServer) handleStuckSagas(w http.ResponseWriter, r *http.Request) error {
+	sagas, err := s.store.StuckSagas()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, sagas)
+}
+
+func (s *APIServer) handleRetryJob(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if err := s.store.RetryJob(id); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]int{"retried": id})
+}
+
+func (s *APIServer) handleReloadConfig(w http.ResponseWriter, r *http.Request) error {
+	cfg, err := s.config.Reload()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, cfg.Runtime)
+}
+
+func (s *APIServer) HandleLogin(w http.ResponseWriter, r *http.Request) error {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+
+	acc, err := s.storeFor(r).GetAccountByNumber(req.Number)
+	if err != nil {
+		return err
+	}
+
+	if !acc.ValidatePassword(req.Password) {
+		return NewError(CodeInvalidCredentials)
+	}
+	// the event is for login alerts, a login doesn't fail without it
+	ev, err := domain.NewEvent(domain.EventAccountLogin, acc.ID, map[string]string{"userAgent": r.UserAgent()}, s.clock.Now())
+	if err == nil {
+		err = s.storeFor(r).RecordEvent(ev)
+	}
+	if err != nil {
+		s.logger.Error("recording login failed", "account_id", acc.ID, "error", err)
+	}
+
+	token, err := auth.CreateJWT(acc, s.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	res := LoginResponse{
+		ID:     acc.ID,
+		UUID:   acc.UUID,
+		Number: acc.Number,
+		Token:  domain.Secret(token),
+	}
+
+	return WriteJSON(w, http.StatusOK, res)
+}
+
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// withAccountAuth only lets the owner of account {id} through, authenticated
+// by JWT or a signed API key request.
+func (s *APIServer) withAccountAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		ref, err := parseAccountRef(request)
+		if err != nil {
+			writeError(w, request, http.StatusForbidden, err)
+			return
+		}
+		account, err := auth.Authenticate(request, s.storeFor(request), tenantFromContext(request.Context()), s.clock.Now())
+		if err != nil {
+			authFailed(w, request, err)
+			return
+		}
+		if !ref.matches(account) {
+			permissionDenied(w, request)
+			return
+		}
+		thirdParty, err := s.checkConsent(request, account)
+		if err != nil {
+			authFailed(w, request, err)
+			return
+		}
+		if thirdParty {
+			request = request.WithContext(context.WithValue(request.Context(), thirdPartyKey{}, true))
+		}
+		s.resolveAccountRef(w, request, ref, account)
+		setAccountLanguage(request, account.Language)
+		next.ServeHTTP(w, withLoggerAttrs(request, "account_id", account.ID))
+	})
+}
+
+// withAdminAuth guards operator endpoints with the shared ADMIN_TOKEN secret,
+// or a client certificate of a service account with the admin scope.
+func withAdminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		if serviceAccountFrom(request.Context()).HasScope(auth.ScopeAdmin) {
+			next.ServeHTTP(w, request)
+			return
+		}
+		secret := os.Getenv("ADMIN_TOKEN")
+		token := request.Header.Get("x-admin-token")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			permissionDenied(w, request)
+			return
+		}
+		next.ServeHTTP(w, request)
+	})
+}
+
+func permissionDenied(w http.ResponseWriter, request *http.Request) {
+	writeError(w, request, http.StatusForbidden, NewError(CodePermissionDenied))
+}
+
+// ApiError is the body of every error response. Code is stable, Error is
+// localized for the caller.
+type ApiError struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+	// Violations lists what's wrong with a body that failed schema validation.
+	Violations []SchemaViolation `json:"violations,omitempty"`
+}
+
+// writeError answers with the error's code and its message in the language of
+// the request. Domain errors get their code from fromDomain, errors without a
+// code are reported as bad_request as is.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	WriteJSON(w, status, toApiError(err, languageFor(r)))
+}
+
+func toApiError(err error, lang string) ApiError {
+	if mapped, _, ok := fromDomain(err); ok {
+		err = mapped
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		field, _ := apiErr.Params["field"].(string)
+		return ApiError{Code: apiErr.Code, Error: apiErr.Localize(lang), Field: field}
+	}
+	return ApiError{Code: CodeBadRequest, Error: err.Error()}
+}
+
+func makeHttpHandleFunc(f apiFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if err := f(writer, request); err != nil {
+			// handle the error
+			loggerFrom(request.Context()).Warn("request failed", "error", err)
+			if _, status, ok := fromDomain(err); ok {
+				writeError(writer, request, status, err)
+				return
+			}
+			var apiErr *Error
+			if errors.As(err, &apiErr) && apiErr.Code == CodePreconditionFailed {
+				writeError(writer, request, http.StatusPreconditionFailed, err)
+				return
+			}
+			writeError(writer, request, http.StatusBadRequest, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+type CreateApiKeyRequest struct {
+	Name string `json:"name"`
+	// ThirdParty keys are handed to a partner and limited by the account's
+	// consents.
+	ThirdParty bool `json:"thirdParty"`
+}
+
+// handleApiKeys serves /account/{id}/api-keys. The secret is only part of the
+// response that creates the key.
+func (s *APIServer) handleApiKeys(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		keys, err := store.ListApiKeys(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, keys)
+	case http.MethodPost:
+		req := new(CreateApiKeyRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+		key, err := domain.NewApiKey(id, req.Name, req.ThirdParty)
+		if err != nil {
+			return err
+		}
+		if err := store.CreateApiKey(key); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusCreated, key)
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
+
+func (s *APIServer) handleRevokeApiKey(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	keyID := r.PathValue("keyId")
+	if err := s.storeFor(r).RevokeApiKey(id, keyID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"revoked": keyID})
+}
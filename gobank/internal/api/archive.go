@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"time"
+)
+
+// Archiver periodically moves old transactions out of the hot transaction
+// table so that day to day queries stay fast.
+type Archiver struct {
+	store  storage.ArchiveStore
+	clock  domain.Clock
+	maxAge int
+	logger *slog.Logger
+}
+
+const ArchiveJobType = "archive_transactions"
+
+func NewArchiver(store storage.ArchiveStore, clock domain.Clock, maxAgeYears int, logger *slog.Logger) *Archiver {
+	return &Archiver{store: store, clock: clock, maxAge: maxAgeYears, logger: logger}
+}
+
+func (a *Archiver) HandleJob(job *domain.Job) error {
+	_, err := a.ArchiveOnce(a.clock.Now().UTC())
+	return err
+}
+
+func (a *Archiver) ArchiveOnce(now time.Time) (int64, error) {
+	cutoff := now.AddDate(-a.maxAge, 0, 0)
+	moved, err := a.store.ArchiveTransactions(cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if moved > 0 {
+		a.logger.Info("archived transactions", "count", moved, "cutoff", cutoff)
+	}
+	return moved, nil
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeArchiveStore struct {
+	cutoffs []time.Time
+}
+
+func (f *fakeArchiveStore) ArchiveTransactions(before time.Time) (int64, error) {
+	f.cutoffs = append(f.cutoffs, before)
+	return 3, nil
+}
+
+func TestArchiverCutoff(t *testing.T) {
+	store := &fakeArchiveStore{}
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	a := NewArchiver(store, fixedClock(now), 7, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	moved, err := a.ArchiveOnce(now)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), moved)
+
+	// the job archives up to the clock's time less the configured years
+	assert.Nil(t, a.HandleJob(&domain.Job{Type: ArchiveJobType}))
+	assert.Equal(t, []time.Time{time.Date(2017, 3, 15, 10, 0, 0, 0, time.UTC), time.Date(2017, 3, 15, 10, 0, 0, 0, time.UTC)}, store.cutoffs)
+}
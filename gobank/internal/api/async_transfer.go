@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TransferStatus is what GET /transfer/{id} tells about an accepted
+// transfer. Error is set once it failed.
+type TransferStatus struct {
+	ID            string               `json:"id"`
+	Status        string               `json:"status"`
+	ToAccount     domain.AccountNumber `json:"toAccount"`
+	Amount        domain.Money         `json:"amount"`
+	TransactionID int                  `json:"transactionId,omitempty"`
+	Error         *ApiError            `json:"error,omitempty"`
+	CreatedAt     time.Time            `json:"createdAt"`
+	UpdatedAt     time.Time            `json:"updatedAt"`
+}
+
+func transferStatus(req *domain.TransferRequest, lang string) TransferStatus {
+	status := TransferStatus{
+		ID:            req.ID,
+		Status:        req.Status,
+		ToAccount:     req.ToAccount,
+		Amount:        req.Amount,
+		TransactionID: req.TransactionID,
+		CreatedAt:     req.CreatedAt,
+		UpdatedAt:     req.UpdatedAt,
+	}
+	if req.FailureCode != "" {
+		apiErr := toApiError(&Error{Code: req.FailureCode, Params: req.FailureParams}, lang)
+		status.Error = &apiErr
+	} else if req.Status == domain.TransferFailed && !req.ReviewedAt.IsZero() {
+		apiErr := toApiError(NewError(CodeTransferRejected), lang)
+		status.Error = &apiErr
+	}
+	return status
+}
+
+// prefersAsync reports whether the client asked with Prefer: respond-async
+// (RFC 7240) to have the request processed in the background.
+func prefersAsync(r *http.Request) bool {
+	for _, v := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptTransfer answers POST /transfer with 202 and the transfer's status,
+// a job makes it later. The client polls the Location for the outcome.
+func (s *APIServer) acceptTransfer(w http.ResponseWriter, r *http.Request, account *domain.Account, req *TransferAccount) error {
+	accept := s.transfersFor(r).AcceptOnce
+	if req.AllowDuplicate {
+		accept = s.transfersFor(r).Accept
+	}
+	transfer, err := accept(account, req.ToAccount, req.Amount)
+	if err != nil {
+		return err
+	}
+	loggerFrom(r.Context()).Info("transfer accepted", "transfer_id", transfer.ID, "amount", transfer.Amount.String())
+	w.Header().Set("Preference-Applied", "respond-async")
+	w.Header().Set("Location", "/transfer/"+transfer.ID)
+	return WriteJSON(w, http.StatusAccepted, transferStatus(transfer, languageFor(r)))
+}
+
+func (s *APIServer) handleTransferStatus(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	transfer, err := s.storeFor(r).GetTransferRequest(account.ID, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if transfer.Status == domain.TransferPending {
+		w.Header().Set("Retry-After", "1")
+	}
+	return WriteJSON(w, http.StatusOK, transferStatus(transfer, languageFor(r)))
+}
+
+// HandleTransferJob processes a transfer accepted with Prefer:
+// respond-async. A transfer the rules refuse fails with the error the
+// synchronous call would have answered with; anything else is retried and
+// fails the transfer with internal_error on the job's last attempt.
+func (s *APIServer) HandleTransferJob(job *domain.Job) error {
+	var payload service.TransferJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	store := s.store.ForTenant(payload.TenantID)
+	fail := func(err error) error {
+		apiErr, _, refused := fromDomain(err)
+		switch {
+		case refused:
+		case job.Attempts < job.MaxAttempts:
+			return err
+		default:
+			apiErr = NewError(CodeInternal)
+		}
+		if ferr := store.FailTransferRequest(payload.TransferID, apiErr.Code, apiErr.Params); ferr != nil {
+			return errors.Join(err, ferr)
+		}
+		s.logger.Info("transfer failed", "transfer_id", payload.TransferID, "code", apiErr.Code)
+		if refused {
+			return nil
+		}
+		return err
+	}
+	account, err := store.GetAccountById(payload.AccountID)
+	if err != nil {
+		return fail(err)
+	}
+	req, err := store.GetTransferRequest(payload.AccountID, payload.TransferID)
+	if err != nil {
+		return err
+	}
+	req, err = service.NewTransferService(store, s.settings, s.clock, s.screening).Process(account, req)
+	if err != nil {
+		return fail(err)
+	}
+	if req.Status == domain.TransferCompleted {
+		s.notifier.Notify()
+	}
+	return nil
+}
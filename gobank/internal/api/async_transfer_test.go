@@ -0,0 +1,81 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeAsyncStore struct {
+	storage.Storage
+	req     *domain.TransferRequest
+	balance int64
+	failed  string
+}
+
+func (f *fakeAsyncStore) ForTenant(tenantID int) storage.Storage {
+	return f
+}
+
+func (f *fakeAsyncStore) GetTenantSettings(tenantID int) (*domain.TenantSettings, error) {
+	return nil, nil
+}
+
+func (f *fakeAsyncStore) GetAccountById(id int) (*domain.Account, error) {
+	return &domain.Account{ID: id, Timezone: "UTC", Balance: domain.Money{MinorUnits: f.balance, Currency: "EUR"}}, nil
+}
+
+func (f *fakeAsyncStore) GetTransferRequest(accountID int, id string) (*domain.TransferRequest, error) {
+	return f.req, nil
+}
+
+func (f *fakeAsyncStore) SentSince(accountID int, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeAsyncStore) ExecuteTransferRequest(from *domain.Account, id string, guard *domain.TransferGuard) (*domain.TransferRequest, error) {
+	if from.Balance.MinorUnits < f.req.Amount.MinorUnits {
+		return nil, domain.ErrInsufficientFunds
+	}
+	f.req.Status = domain.TransferCompleted
+	return f.req, nil
+}
+
+func (f *fakeAsyncStore) FailTransferRequest(id, code string, params map[string]any) error {
+	f.req.Status, f.req.FailureCode, f.req.FailureParams = domain.TransferFailed, code, params
+	f.failed = code
+	return nil
+}
+
+func TestHandleTransferJob(t *testing.T) {
+	store := &fakeAsyncStore{}
+	s := &APIServer{store: store, notifier: NewNotifier(), logger: slog.New(slog.NewTextHandler(io.Discard, nil)), clock: domain.SystemClock{}}
+	s.settings = NewTenantSettingsCache(store, time.Minute, domain.DefaultTenantSettings)
+	job := &domain.Job{Payload: []byte(`{"tenantId": 1, "accountId": 7, "transferId": "t"}`), Attempts: 1, MaxAttempts: 5}
+
+	store.req = &domain.TransferRequest{ID: "t", Status: domain.TransferPending, Amount: domain.Money{MinorUnits: 500, Currency: "EUR"}}
+	assert.Nil(t, s.HandleTransferJob(job))
+	assert.Equal(t, CodeInsufficientFunds, store.failed)
+
+	status := transferStatus(store.req, "de")
+	assert.Equal(t, domain.TransferFailed, status.Status)
+	assert.Equal(t, "unzureichende Deckung", status.Error.Error)
+
+	store.balance = 500
+	store.req = &domain.TransferRequest{ID: "t", Status: domain.TransferPending, Amount: domain.Money{MinorUnits: 500, Currency: "EUR"}}
+	assert.Nil(t, s.HandleTransferJob(job))
+	assert.Equal(t, domain.TransferCompleted, store.req.Status)
+}
+
+func TestPrefersAsync(t *testing.T) {
+	r := httptest.NewRequest("POST", "/transfer", nil)
+	assert.False(t, prefersAsync(r))
+	r.Header = http.Header{"Prefer": {"return=minimal, Respond-Async"}}
+	assert.True(t, prefersAsync(r))
+}
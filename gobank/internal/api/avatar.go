@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// avatarMaxBytes is the largest avatar upload, avatarMaxPixels the most
+	// pixels on either side of it, checked before it's decoded.
+	avatarMaxBytes  = 2 << 20
+	avatarMaxPixels = 4096
+	// avatarSize is the most pixels on either side of a stored avatar.
+	avatarSize = 256
+	// avatarPath is where avatars are served, followed by their blob key.
+	avatarPath = "/avatars/"
+)
+
+// avatarTypes are the image types an avatar may be uploaded in, by the name
+// image.Decode gives their format.
+var avatarTypes = map[string]string{"image/png": "png", "image/jpeg": "jpeg", "image/gif": "gif"}
+
+// resizeAvatar scales img down to fit avatarSize, keeping its aspect ratio,
+// by averaging the pixels each one of the result covers. Smaller images
+// are kept as they are.
+func resizeAvatar(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= avatarSize && h <= avatarSize {
+		return img
+	}
+	long := max(w, h)
+	dw, dh := max(1, w*avatarSize/long), max(1, h*avatarSize/long)
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// decodeAvatar decodes an upload of mediaType, refusing one whose content
+// is of another type or that is too large to decode, and returns it resized
+// as a PNG.
+func decodeAvatar(data []byte, mediaType string) ([]byte, error) {
+	format, ok := avatarTypes[mediaType]
+	if !ok {
+		return nil, NewError(CodeInvalidParameter, "name", "Content-Type", "value", mediaType)
+	}
+	cfg, got, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || got != format {
+		return nil, NewError(CodeInvalidImage, "reason", "not a "+format+" image")
+	}
+	if cfg.Width > avatarMaxPixels || cfg.Height > avatarMaxPixels {
+		return nil, NewError(CodeInvalidImage, "reason", fmt.Sprintf("larger than %dx%d pixels", avatarMaxPixels, avatarMaxPixels))
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, NewError(CodeInvalidImage, "reason", err.Error())
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resizeAvatar(img)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleUploadAvatar serves PUT /account/{id}/avatar with a PNG, JPEG or GIF
+// body. The picture is stored resized as a PNG under a name of the account
+// and a hash of the picture, so a new one is a new URL and caches may keep
+// each for long.
+func (s *APIServer) handleUploadAvatar(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	body := http.MaxBytesReader(w, r.Body, avatarMaxBytes)
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	picture, err := decodeAvatar(data, mediaType)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(picture)
+	key := fmt.Sprintf("%s-%x.png", account.UUID, sum[:6])
+	if err := s.avatars.Put(key, bytes.NewReader(picture)); err != nil {
+		return err
+	}
+	previous := account.Avatar
+	account.Avatar = avatarPath + key
+	if err := store.UpdateAccount(account); err != nil {
+		return err
+	}
+	s.deleteAvatar(previous, account.Avatar)
+	setValidators(w, account)
+	return WriteJSON(w, http.StatusOK, account)
+}
+
+// handleDeleteAvatar serves DELETE /account/{id}/avatar.
+func (s *APIServer) handleDeleteAvatar(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	previous := account.Avatar
+	account.Avatar = ""
+	if err := store.UpdateAccount(account); err != nil {
+		return err
+	}
+	s.deleteAvatar(previous, "")
+	setValidators(w, account)
+	return WriteJSON(w, http.StatusOK, account)
+}
+
+// deleteAvatar removes the blob of a replaced avatar. A failure only leaves
+// an unreferenced blob behind, it is logged and otherwise ignored.
+func (s *APIServer) deleteAvatar(previous, current string) {
+	if previous == "" || previous == current {
+		return
+	}
+	if err := s.avatars.Delete(strings.TrimPrefix(previous, avatarPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.logger.Warn("deleting avatar", "avatar", previous, "err", err)
+	}
+}
+
+// handleAvatar serves GET /avatars/{name}, the avatars account responses
+// and lookups link to. They need no login, like the masked holder name of
+// a lookup.
+func (s *APIServer) handleAvatar(w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	if strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".png") {
+		return NewError(CodeNotFound, "id", name)
+	}
+	blob, err := s.avatars.Get(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewError(CodeNotFound, "id", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, err = io.Copy(w, blob)
+	return err
+}
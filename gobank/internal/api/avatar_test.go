@@ -0,0 +1,43 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeAvatar(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			src.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, src))
+
+	data, err := decodeAvatar(buf.Bytes(), "image/png")
+	assert.Nil(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	if assert.Nil(t, err) {
+		assert.Equal(t, image.Rect(0, 0, 256, 128), img.Bounds())
+		r, g, _, a := img.At(100, 50).RGBA()
+		assert.Equal(t, []uint32{200, 0, 255}, []uint32{r >> 8, g >> 8, a >> 8})
+	}
+
+	var apiErr *Error
+	_, err = decodeAvatar(buf.Bytes(), "image/jpeg")
+	assert.True(t, errors.As(err, &apiErr) && apiErr.Code == CodeInvalidImage)
+	_, err = decodeAvatar(buf.Bytes(), "image/svg+xml")
+	assert.True(t, errors.As(err, &apiErr) && apiErr.Code == CodeInvalidParameter)
+
+	buf.Reset()
+	assert.Nil(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 5000, 10)), nil))
+	_, err = decodeAvatar(buf.Bytes(), "image/jpeg")
+	assert.True(t, errors.As(err, &apiErr) && apiErr.Code == CodeInvalidImage)
+}
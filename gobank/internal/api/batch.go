@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// BatchAtomic makes every transfer of a batch or none.
+	BatchAtomic = "atomic"
+	// BatchBestEffort makes each transfer that can be made.
+	BatchBestEffort = "best_effort"
+)
+
+// maxBatchTransfers bounds a batch so that an atomic one doesn't hold its
+// row locks for long.
+const maxBatchTransfers = 500
+
+type BatchTransferRequest struct {
+	Mode      string                 `json:"mode"`
+	Transfers []domain.TransferOrder `json:"transfers"`
+}
+
+// BatchTransferResult is the outcome of the transfer at Index of a batch,
+// its transaction or why it failed.
+type BatchTransferResult struct {
+	Index       int                 `json:"index"`
+	Transaction *domain.Transaction `json:"transaction,omitempty"`
+	Error       *ApiError           `json:"error,omitempty"`
+}
+
+type BatchTransferResponse struct {
+	Mode      string                `json:"mode"`
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []BatchTransferResult `json:"results"`
+}
+
+// handleBatchTransfer serves POST /transfers/batch. An atomic batch that
+// fails is a 422 naming the transfer that failed, nothing was made. A best
+// effort batch is a 200 with a result per transfer.
+func (s *APIServer) handleBatchTransfer(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	defer r.Body.Close()
+	req := new(BatchTransferRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if len(req.Transfers) == 0 || len(req.Transfers) > maxBatchTransfers {
+		return invalidParameter("transfers", strconv.Itoa(len(req.Transfers)))
+	}
+	lang := languageFor(r)
+	resp := BatchTransferResponse{Mode: req.Mode, Results: make([]BatchTransferResult, 0, len(req.Transfers))}
+	switch req.Mode {
+	case BatchAtomic:
+		txs, err := s.transfersFor(r).TransferAll(account, req.Transfers)
+		var itemErr *domain.BatchItemError
+		if errors.As(err, &itemErr) {
+			apiErr := toApiError(itemErr.Err, lang)
+			resp.Failed = 1
+			resp.Results = append(resp.Results, BatchTransferResult{Index: itemErr.Index, Error: &apiErr})
+			return WriteJSON(w, http.StatusUnprocessableEntity, resp)
+		}
+		if err != nil {
+			return err
+		}
+		for i, t := range txs {
+			resp.Results = append(resp.Results, BatchTransferResult{Index: i, Transaction: t})
+		}
+		resp.Succeeded = len(txs)
+	case BatchBestEffort:
+		txs, errs := s.transfersFor(r).TransferEach(account, req.Transfers)
+		for i := range req.Transfers {
+			result := BatchTransferResult{Index: i, Transaction: txs[i]}
+			if errs[i] != nil {
+				apiErr := toApiError(errs[i], lang)
+				result.Error = &apiErr
+				resp.Failed++
+			} else {
+				resp.Succeeded++
+			}
+			resp.Results = append(resp.Results, result)
+		}
+	default:
+		return invalidParameter("mode", req.Mode)
+	}
+	if resp.Succeeded > 0 {
+		s.notifier.Notify()
+	}
+	loggerFrom(r.Context()).Info("batch transfer", "mode", req.Mode, "succeeded", resp.Succeeded, "failed", resp.Failed)
+	return WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,423 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// batchInterrupted is the acknowledgement of a file whose ingestion was
+// interrupted. Some of its transfers may have been accepted, so it isn't
+// ingested again.
+const batchInterrupted = "the file was interrupted while being ingested, check its transfers before sending them again"
+
+// batchDuplicate is the acknowledgement of a file sent again with the name
+// and content of one already acknowledged.
+func batchDuplicate(file *domain.BatchFile) string {
+	return fmt.Sprintf("duplicate of the file received %s, rename it to have it ingested again", file.ReceivedAt.UTC().Format(time.RFC3339))
+}
+
+// batchCSVHeader is the header of a CSV batch file. from and to are account
+// numbers or IBANs, amount a decimal in currency, and reference the
+// customer's id of the transfer, unique within the file.
+var batchCSVHeader = []string{"from", "to", "amount", "currency", "reference"}
+
+// batchAckHeader is the header of the acknowledgement of a CSV batch file,
+// a line per transfer. A file rejected whole gets a single line 0.
+var batchAckHeader = []string{"line", "reference", "status", "transfer_id", "error"}
+
+// The statuses of the lines of a CSV acknowledgement.
+const (
+	batchLineAccepted = "accepted"
+	batchLineRejected = "rejected"
+)
+
+// BatchIntake picks up the payment batch files corporate customers drop into
+// a directory, pain.001 .xml or CSV .csv ones, and ingests them like POST
+// /admin/payments/pain001: the valid transfers are accepted for processing
+// in the background. Each file is replaced by its acknowledgement in the ack
+// subdirectory, a pain.002 or a CSV with a line per transfer, named after it.
+type BatchIntake struct {
+	server   *APIServer
+	tenant   string
+	inbox    storage.BlobStore
+	acks     storage.BlobStore
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+// NewBatchIntake returns the intake of the BatchIntakeDir directory, nil
+// when there is none.
+func NewBatchIntake(cfg *Config, server *APIServer) (*BatchIntake, error) {
+	if cfg.BatchIntakeDir == "" {
+		return nil, nil
+	}
+	b := &BatchIntake{server: server, tenant: cfg.BatchIntakeTenant, interval: time.Duration(cfg.BatchIntakeIntervalSeconds) * time.Second, logger: server.logger}
+	if strings.HasPrefix(cfg.BatchIntakeDir, "sftp:") {
+		var signer ssh.Signer
+		if cfg.SFTPKeyFile != "" {
+			var err error
+			if signer, err = LoadSFTPKey(cfg.SFTPKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		inbox, err := NewSFTPBlobStore(cfg.BatchIntakeDir, cfg.BatchIntakePassword, signer, cfg.BatchIntakeHostKey, importMaxBytes+1)
+		if err != nil {
+			return nil, err
+		}
+		b.inbox, b.acks = inbox, inbox.Sub("ack")
+		return b, nil
+	}
+	inbox, err := storage.NewDirBlobStore(cfg.BatchIntakeDir)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := storage.NewDirBlobStore(filepath.Join(cfg.BatchIntakeDir, "ack"))
+	if err != nil {
+		return nil, err
+	}
+	b.inbox, b.acks = inbox, acks
+	return b, nil
+}
+
+// Run polls the directory until stop is closed. Only the instance holding
+// the batch-intake lock runs it.
+func (b *BatchIntake) Run(stop <-chan struct{}) {
+	b.server.metrics.Help("batch_files_total", "Payment batch files picked up by format and result.")
+	for {
+		if err := b.poll(); err != nil {
+			b.logger.Error("batch intake failed", "error", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(b.interval):
+		}
+	}
+}
+
+// poll takes in the files of the directory, logged out of it again after.
+func (b *BatchIntake) poll() error {
+	defer func() {
+		for _, dir := range []storage.BlobStore{b.inbox, b.acks} {
+			if c, ok := dir.(io.Closer); ok {
+				c.Close()
+			}
+		}
+	}()
+	names, err := b.inbox.List("")
+	if err != nil {
+		return err
+	}
+	var store storage.Storage
+	for _, name := range names {
+		if batchFormat(name) == "" {
+			continue
+		}
+		if store == nil {
+			tenant, err := b.server.store.GetTenantBySlug(b.tenant)
+			if err != nil {
+				return err
+			}
+			store = b.server.store.ForTenant(tenant.ID)
+		}
+		if err := b.take(store, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// batchFormat is the format of a file by its extension, "" for files that
+// aren't batches.
+func batchFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xml":
+		return domain.BatchFilePain001
+	case ".csv":
+		return domain.BatchFileCSV
+	}
+	return ""
+}
+
+// batchAckName is the name of the acknowledgement of the file name.
+func batchAckName(name, format string) string {
+	if format == domain.BatchFilePain001 {
+		return name + ".ack.xml"
+	}
+	return name + ".ack.csv"
+}
+
+// take ingests a file unless one of its name and content was claimed
+// before, writes its acknowledgement and removes it. The file is claimed
+// before any transfer is accepted, so a file that is taken again, because
+// the acknowledgement or the removal failed, keeps the acknowledgement it
+// first got. One sent again after that is rejected as a duplicate.
+func (b *BatchIntake) take(store storage.Storage, name string) error {
+	r, err := b.inbox.Get(name)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(io.LimitReader(r, importMaxBytes+1))
+	r.Close()
+	if err != nil {
+		return err
+	}
+	now := b.server.clock.Now()
+	sum := sha256.Sum256(content)
+	file, claimed, err := store.ClaimBatchFile(domain.NewBatchFile(name, batchFormat(name), hex.EncodeToString(sum[:]), now))
+	if err != nil {
+		return err
+	}
+	result := "redelivered"
+	var ack []byte
+	switch {
+	case claimed:
+		if len(content) > importMaxBytes {
+			file.Ack, err = rejectBatchFile(file, content, fmt.Sprintf("files are at most %d bytes", importMaxBytes), now)
+		} else {
+			file.Ack, err = b.ingest(store, file, content)
+		}
+		if err != nil {
+			return err
+		}
+		result = "ingested"
+	case file.Status == domain.BatchFileReceived:
+		if file.Ack, err = rejectBatchFile(file, content, batchInterrupted, now); err != nil {
+			return err
+		}
+		result = "interrupted"
+	case file.Status == domain.BatchFileAcknowledged:
+		if ack, err = rejectBatchFile(file, content, batchDuplicate(file), now); err != nil {
+			return err
+		}
+		result = "duplicate"
+	}
+	if file.Status == domain.BatchFileReceived {
+		processed := now.UTC()
+		file.Status, file.ProcessedAt = domain.BatchFileProcessed, &processed
+		if err := store.CompleteBatchFile(file); err != nil {
+			return err
+		}
+	}
+	if ack == nil {
+		ack = file.Ack
+	}
+	if err := b.acks.Put(batchAckName(name, file.Format), bytes.NewReader(ack)); err != nil {
+		return err
+	}
+	if err := b.inbox.Delete(name); err != nil {
+		return err
+	}
+	if file.Status == domain.BatchFileProcessed {
+		file.Status = domain.BatchFileAcknowledged
+		if err := store.CompleteBatchFile(file); err != nil {
+			return err
+		}
+	}
+	b.server.metrics.Inc("batch_files_total", "format", file.Format, "result", result)
+	b.logger.Info("batch file taken in", "name", name, "batch_file_id", file.ID, "result", result, "accepted", file.Accepted, "rejected", file.Rejected)
+	return nil
+}
+
+// ingest accepts the valid transfers of the file and returns its
+// acknowledgement, counting the transfers into file.
+func (b *BatchIntake) ingest(store storage.Storage, file *domain.BatchFile, content []byte) ([]byte, error) {
+	transfers := service.NewTransferService(store, b.server.settings, b.server.clock, b.server.screening)
+	now := b.server.clock.Now()
+	if file.Format == domain.BatchFileCSV {
+		rows, err := parseBatchCSV(content)
+		if err != nil {
+			return rejectBatchFile(file, content, err.Error(), now)
+		}
+		acks, accepted := ingestBatchCSV(store, transfers, rows, b.logger)
+		file.Accepted, file.Rejected = accepted, len(rows)-accepted
+		return encodeBatchAck(acks)
+	}
+	doc := new(pain001Document)
+	if err := xml.Unmarshal(content, doc); err != nil {
+		return rejectBatchFile(file, content, "the file isn't a pain.001", now)
+	}
+	report, accepted := ingestPain001(store, transfers, doc, now, b.logger)
+	file.Accepted = accepted
+	if report.Report.Group.Reason == nil {
+		file.Rejected = report.Report.Group.Count - accepted
+	}
+	var buf bytes.Buffer
+	err := encodePain002(&buf, report)
+	return buf.Bytes(), err
+}
+
+// rejectBatchFile returns the acknowledgement rejecting the whole file for
+// reason.
+func rejectBatchFile(file *domain.BatchFile, content []byte, reason string, now time.Time) ([]byte, error) {
+	if file.Format == domain.BatchFileCSV {
+		return encodeBatchAck([][]string{{"0", "", batchLineRejected, "", reason}})
+	}
+	doc := new(pain001Document)
+	// what can be read of the file still names it in the report
+	xml.Unmarshal(content, doc)
+	report := newPain002(doc, now)
+	report.Report.Payments = nil
+	group := &report.Report.Group
+	group.Status, group.Reason = painRejected, painReason(painNarrativeReason, reason)
+	var buf bytes.Buffer
+	err := encodePain002(&buf, report)
+	return buf.Bytes(), err
+}
+
+// batchCSVRow is a transfer of a CSV batch file, on Line counting the
+// header as 1.
+type batchCSVRow struct {
+	Line      int
+	From      string
+	To        string
+	Amount    string
+	Currency  string
+	Reference string
+}
+
+// parseBatchCSV reads a CSV batch file. One that is malformed, has another
+// header or too many transfers is an error.
+func parseBatchCSV(content []byte) ([]batchCSVRow, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\ufeff"))))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.New("the file has no header")
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if strings.Join(header, ",") != strings.Join(batchCSVHeader, ",") {
+		return nil, fmt.Errorf("the header must be %s", strings.Join(batchCSVHeader, ","))
+	}
+	var rows []batchCSVRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, batchCSVRow{Line: line, From: record[0], To: record[1], Amount: record[2], Currency: record[3], Reference: record[4]})
+	}
+	if len(rows) == 0 || len(rows) > painMaxTransfers {
+		return nil, fmt.Errorf("a file has 1 to %d transfers", painMaxTransfers)
+	}
+	return rows, nil
+}
+
+// batchAccount looks up the account of the bank id names, by account number
+// or IBAN.
+func batchAccount(store storage.Storage, id string) (*domain.Account, error) {
+	id = strings.TrimSpace(id)
+	if _, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return painAccount(store, pain001AccountID{Other: id})
+	}
+	return painAccount(store, pain001AccountID{IBAN: id})
+}
+
+// ingestBatchCSV accepts the valid transfers of a CSV batch file, those of a
+// debtor account in one go, and returns the acknowledgement lines with how
+// many it accepted.
+func ingestBatchCSV(store storage.Storage, transfers *service.TransferService, rows []batchCSVRow, logger *slog.Logger) ([][]string, int) {
+	acks := make([][]string, len(rows))
+	type debtor struct {
+		account *domain.Account
+		orders  []domain.TransferOrder
+		lines   []int
+	}
+	var debtors []*debtor
+	byID := map[int]*debtor{}
+	seen := map[string]bool{}
+	for i, row := range rows {
+		acks[i] = []string{strconv.Itoa(row.Line), row.Reference, batchLineRejected, "", ""}
+		reject := func(reason string) { acks[i][4] = reason }
+		if row.Reference != "" && seen[row.Reference] {
+			reject("duplicate reference")
+			continue
+		}
+		seen[row.Reference] = true
+		from, err := batchAccount(store, row.From)
+		if err != nil {
+			reject("from account not found")
+			continue
+		}
+		to, err := batchAccount(store, row.To)
+		if err != nil {
+			reject("to account not found")
+			continue
+		}
+		currency := strings.ToUpper(strings.TrimSpace(row.Currency))
+		units, err := domain.ParseDecimal(strings.TrimSpace(row.Amount), currency)
+		if err != nil || units <= 0 || currency == "" {
+			reject("invalid amount")
+			continue
+		}
+		d := byID[from.ID]
+		if d == nil {
+			d = &debtor{account: from}
+			byID[from.ID] = d
+			debtors = append(debtors, d)
+		}
+		d.orders = append(d.orders, domain.TransferOrder{ToAccount: to.Number, Amount: domain.Money{MinorUnits: units, Currency: currency}})
+		d.lines = append(d.lines, i)
+	}
+	accepted := 0
+	for _, d := range debtors {
+		reqs, err := transfers.AcceptAll(d.account, d.orders)
+		if err != nil {
+			logger.Error("accepting batch file transfers failed", "account_id", d.account.ID, "error", err)
+			for _, i := range d.lines {
+				acks[i][4] = "internal error, send the transfer again"
+			}
+			continue
+		}
+		for j, req := range reqs {
+			acks[d.lines[j]][2], acks[d.lines[j]][3] = batchLineAccepted, req.ID
+		}
+		accepted += len(reqs)
+	}
+	return acks, accepted
+}
+
+func encodeBatchAck(lines [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(batchAckHeader)
+	w.WriteAll(lines)
+	return buf.Bytes(), w.Error()
+}
+
+// handleBatchFiles serves GET /admin/payments/batch-files?tenant=, the
+// latest files the intake picked up.
+func (s *APIServer) handleBatchFiles(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	files, err := store.ListBatchFiles(100)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, files)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/xml"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBatchCSV(t *testing.T) {
+	rows, err := parseBatchCSV([]byte("\ufeffFrom,To,Amount,Currency,Reference\n4711007,4711008,10.50,EUR,INV-1\n4711007, 999,20,EUR,INV-2\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, batchCSVRow{Line: 2, From: "4711007", To: "4711008", Amount: "10.50", Currency: "EUR", Reference: "INV-1"}, rows[0])
+		assert.Equal(t, 3, rows[1].Line)
+	}
+
+	_, err = parseBatchCSV([]byte("to,from,amount\n4711008,4711007,1\n"))
+	assert.ErrorContains(t, err, "header")
+	_, err = parseBatchCSV([]byte("from,to,amount,currency,reference\n"))
+	assert.Error(t, err)
+}
+
+func TestIngestBatchCSV(t *testing.T) {
+	rows, err := parseBatchCSV([]byte("from,to,amount,currency,reference\n4711007,4711008,10.50,EUR,INV-1\n4711007,999,20,EUR,INV-2\n4711007,4711008,30,EUR,INV-1\n4711007,4711008,-1,EUR,INV-3\n"))
+	assert.NoError(t, err)
+	store := &fakePainStore{}
+	acks, accepted := ingestBatchCSV(store, service.NewTransferService(store, nil, domain.NewSimClock(), nil), rows, nil)
+	assert.Equal(t, 1, accepted)
+	if assert.Len(t, store.accepted, 1) {
+		assert.Equal(t, []string{"2", "INV-1", batchLineAccepted, store.accepted[0].ID, ""}, acks[0])
+		assert.Equal(t, domain.Money{MinorUnits: 1050, Currency: "EUR"}, store.accepted[0].Amount)
+	}
+	assert.Equal(t, []string{"3", "INV-2", batchLineRejected, "", "to account not found"}, acks[1])
+	assert.Equal(t, "duplicate reference", acks[2][4])
+	assert.Equal(t, "invalid amount", acks[3][4])
+}
+
+func TestRejectBatchFile(t *testing.T) {
+	now := domain.NewSimClock().Now()
+	ack, err := rejectBatchFile(domain.NewBatchFile("pay.csv", domain.BatchFileCSV, "", now), nil, batchInterrupted, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "line,reference,status,transfer_id,error\n0,,rejected,,\""+batchInterrupted+"\"\n", string(ack))
+
+	ack, err = rejectBatchFile(domain.NewBatchFile("pay.xml", domain.BatchFilePain001, "", now), []byte(testPain001), batchInterrupted, now)
+	assert.NoError(t, err)
+	report := new(pain002Document)
+	assert.NoError(t, xml.Unmarshal(ack, report))
+	assert.Equal(t, "MSG-1", report.Report.Group.MessageID)
+	assert.Equal(t, "pain.001.001.09", report.Report.Group.MessageName)
+	assert.Equal(t, painRejected, report.Report.Group.Status)
+	assert.Equal(t, painNarrativeReason, report.Report.Group.Reason.Code)
+	assert.True(t, strings.HasSuffix(batchAckName("pay.xml", domain.BatchFilePain001), ".ack.xml"))
+}
+
+type fakeBatchFileStore struct {
+	fakePainStore
+	files []*domain.BatchFile
+}
+
+func (f *fakeBatchFileStore) ClaimBatchFile(file *domain.BatchFile) (*domain.BatchFile, bool, error) {
+	for _, existing := range f.files {
+		if existing.Name == file.Name && existing.SHA256 == file.SHA256 {
+			copied := *existing
+			return &copied, false, nil
+		}
+	}
+	copied := *file
+	f.files = append(f.files, &copied)
+	return file, true, nil
+}
+
+func (f *fakeBatchFileStore) CompleteBatchFile(file *domain.BatchFile) error {
+	for _, existing := range f.files {
+		if existing.ID == file.ID {
+			*existing = *file
+		}
+	}
+	return nil
+}
+
+func TestTakeBatchFileOnce(t *testing.T) {
+	dir := t.TempDir()
+	inbox, err := storage.NewDirBlobStore(dir)
+	assert.NoError(t, err)
+	acks, err := storage.NewDirBlobStore(dir + "/ack")
+	assert.NoError(t, err)
+	clock := domain.NewSimClock()
+	s := &APIServer{clock: clock, metrics: NewMetrics()}
+	b := &BatchIntake{server: s, inbox: inbox, acks: acks, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	store := &fakeBatchFileStore{}
+	content := "from,to,amount,currency,reference\n4711007,4711008,10.50,EUR,INV-1\n"
+	send := func(name string) string {
+		assert.NoError(t, inbox.Put(name, strings.NewReader(content)))
+		assert.NoError(t, b.take(store, name))
+		r, err := acks.Get(batchAckName(name, domain.BatchFileCSV))
+		assert.NoError(t, err)
+		ack, _ := io.ReadAll(r)
+		r.Close()
+		return string(ack)
+	}
+
+	clock.Advance(24 * time.Hour)
+	first := send("pay.csv")
+	assert.Contains(t, first, batchLineAccepted)
+	assert.True(t, store.files[0].ReceivedAt.After(time.Now().Add(23*time.Hour)), "received at the server's clock")
+	assert.Equal(t, domain.BatchFileAcknowledged, store.files[0].Status)
+
+	// taken again before it was acknowledged, it gets the same ack
+	store.files[0].Status = domain.BatchFileProcessed
+	assert.Equal(t, first, send("pay.csv"))
+	assert.Len(t, store.accepted, 1)
+
+	clock.Advance(time.Hour)
+	ack := send("pay.csv")
+	assert.Contains(t, ack, "duplicate of the file received "+store.files[0].ReceivedAt.Format(time.RFC3339))
+	assert.Len(t, store.accepted, 1)
+	assert.Equal(t, first, string(store.files[0].Ack))
+
+	// the same content under another name is another file
+	assert.Contains(t, send("pay-2.csv"), batchLineAccepted)
+	assert.Len(t, store.accepted, 2)
+}
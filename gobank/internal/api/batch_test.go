@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBatchStore posts transfers from one account to the recipients it
+// knows, TransferBatch all of them or none.
+type fakeBatchStore struct {
+	storage.Storage
+	balance    int64
+	recipients map[domain.AccountNumber]bool
+	posted     []domain.TransferOrder
+}
+
+func (f *fakeBatchStore) ForTenant(tenantID int) storage.Storage {
+	return f
+}
+
+func (f *fakeBatchStore) GetTenantSettings(tenantID int) (*domain.TenantSettings, error) {
+	settings := domain.DefaultTenantSettings(tenantID)
+	settings.Currency = "EUR"
+	settings.MaxTransferAmount = 1000
+	return settings, nil
+}
+
+func (f *fakeBatchStore) SentSince(accountID int, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeBatchStore) check(to domain.AccountNumber, amount domain.Money, balance int64) error {
+	if !f.recipients[to] {
+		return domain.NotFound(domain.ErrAccountNotFound, to)
+	}
+	if amount.MinorUnits > balance {
+		return domain.ErrInsufficientFunds
+	}
+	return nil
+}
+
+func (f *fakeBatchStore) post(o domain.TransferOrder) *domain.Transaction {
+	f.balance -= o.Amount.MinorUnits
+	f.posted = append(f.posted, o)
+	return &domain.Transaction{ID: len(f.posted), Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -o.Amount.MinorUnits, Currency: o.Amount.Currency}, Counterparty: o.ToAccount}
+}
+
+func (f *fakeBatchStore) Transfer(from *domain.Account, to domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.Transaction, error) {
+	if err := f.check(to, amount, f.balance); err != nil {
+		return nil, err
+	}
+	return f.post(domain.TransferOrder{ToAccount: to, Amount: amount}), nil
+}
+
+func (f *fakeBatchStore) TransferBatch(from *domain.Account, orders []domain.TransferOrder, guard *domain.TransferGuard) ([]*domain.Transaction, error) {
+	balance := f.balance
+	for i, o := range orders {
+		if err := f.check(o.ToAccount, o.Amount, balance); err != nil {
+			return nil, &domain.BatchItemError{Index: i, Err: err}
+		}
+		balance -= o.Amount.MinorUnits
+	}
+	txs := make([]*domain.Transaction, len(orders))
+	for i, o := range orders {
+		txs[i] = f.post(o)
+	}
+	return txs, nil
+}
+
+func TestBatchTransfer(t *testing.T) {
+	store := &fakeBatchStore{balance: 1000, recipients: map[domain.AccountNumber]bool{1111111: true, 2222222: true}}
+	s := &APIServer{store: store, notifier: NewNotifier(), clock: domain.SystemClock{}}
+	s.settings = NewTenantSettingsCache(store, time.Minute, domain.DefaultTenantSettings)
+	account := &domain.Account{ID: 7, Number: 1234567, Timezone: "UTC", Balance: domain.Money{MinorUnits: 1000, Currency: "EUR"}}
+	call := func(body string) (*httptest.ResponseRecorder, BatchTransferResponse, error) {
+		r := httptest.NewRequest("POST", "/transfers/batch", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), callerKey{}, account))
+		rec := httptest.NewRecorder()
+		var resp BatchTransferResponse
+		err := s.handleBatchTransfer(rec, r)
+		if err == nil {
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp, err
+	}
+	order := func(to, units int) string {
+		return fmt.Sprintf(`{"toAccount": %d, "amount": {"minor_units": %d, "currency": "EUR"}}`, to, units)
+	}
+
+	// an atomic batch fails as a whole, naming the transfer at fault
+	rec, resp, err := call(`{"mode": "atomic", "transfers": [` + order(1111111, 600) + `, ` + order(2222222, 500) + `]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, 1, resp.Failed)
+	if assert.Len(t, resp.Results, 1) {
+		assert.Equal(t, 1, resp.Results[0].Index)
+		assert.Equal(t, CodeInsufficientFunds, resp.Results[0].Error.Code)
+	}
+	// so does one with a transfer over the tenant's limit
+	rec, resp, err = call(`{"mode": "atomic", "transfers": [` + order(1111111, 100) + `, ` + order(1111111, 100) + `, ` + order(2222222, 1001) + `]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, 2, resp.Results[0].Index)
+	assert.Equal(t, CodeTransferLimitExceeded, resp.Results[0].Error.Code)
+	assert.Empty(t, store.posted)
+	assert.Equal(t, int64(1000), store.balance)
+
+	rec, resp, err = call(`{"mode": "atomic", "transfers": [` + order(1111111, 600) + `, ` + order(2222222, 400) + `]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Len(t, store.posted, 2)
+
+	// a best effort batch makes what it can
+	store.balance, store.posted = 1000, nil
+	rec, resp, err = call(`{"mode": "best_effort", "transfers": [` + order(1111111, 300) + `, ` + order(3333333, 100) + `, ` + order(2222222, 600) + `, ` + order(1111111, 200) + `]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, 2, resp.Failed)
+	if assert.Len(t, resp.Results, 4) {
+		assert.NotNil(t, resp.Results[0].Transaction)
+		assert.Equal(t, CodeAccountNotFound, resp.Results[1].Error.Code)
+		assert.NotNil(t, resp.Results[2].Transaction)
+		assert.Equal(t, CodeInsufficientFunds, resp.Results[3].Error.Code)
+	}
+	assert.Equal(t, int64(100), store.balance)
+
+	// empty, oversized and unknown batches are refused before anything is made
+	store.posted = nil
+	too := make([]string, maxBatchTransfers+1)
+	for i := range too {
+		too[i] = order(1111111, 1)
+	}
+	for _, body := range []string{
+		`{"mode": "atomic", "transfers": []}`,
+		`{"mode": "best_effort"}`,
+		`{"mode": "atomic", "transfers": [` + strings.Join(too, ",") + `]}`,
+		`{"mode": "all_or_some", "transfers": [` + order(1111111, 1) + `]}`,
+	} {
+		_, _, err := call(body)
+		var apiErr *Error
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, CodeInvalidParameter, apiErr.Code)
+		}
+	}
+	assert.Empty(t, store.posted)
+}
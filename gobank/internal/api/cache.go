@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// cachePolicy is what a route lets caches keep. The zero policy, for
+// everything not listed in cachePolicies, is private, no-store: account data
+// and balances must not outlive the response.
+type cachePolicy struct {
+	// public responses may be kept by shared caches, they are the same for
+	// every client.
+	public bool
+	// maxAge is how long a public response stays fresh, 0 has caches
+	// revalidate it on every use.
+	maxAge time.Duration
+}
+
+// cachePolicies maps routes, "METHOD path template", to the policy of their
+// successful responses. Errors are never cached.
+var cachePolicies = map[string]cachePolicy{
+	"GET /version":        {public: true, maxAge: time.Minute},
+	"GET /schemas/":       {public: true, maxAge: time.Hour},
+	"GET /schemas/{name}": {public: true, maxAge: time.Hour},
+	"GET /dev/collection": {public: true, maxAge: 5 * time.Minute},
+	// reference data is revalidated with its ETag once stale
+	"GET /reference/currencies":    {public: true, maxAge: 10 * time.Minute},
+	"GET /reference/countries":     {public: true, maxAge: 10 * time.Minute},
+	"GET /reference/account-types": {public: true, maxAge: 10 * time.Minute},
+	// an avatar's name changes with the picture
+	"GET /avatars/{name}": {public: true, maxAge: 24 * time.Hour},
+	// the frontend isn't fingerprinted, caches check Last-Modified instead
+	"GET /": {public: true},
+}
+
+func (p cachePolicy) cacheControl() string {
+	switch {
+	case !p.public:
+		return "private, no-store"
+	case p.maxAge == 0:
+		return "public, no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(p.maxAge.Seconds()))
+}
+
+// expires is the Expires header for HTTP/1.0 caches, a date in the past for
+// anything that isn't fresh for a while.
+func (p cachePolicy) expires(now time.Time) string {
+	if !p.public || p.maxAge == 0 {
+		return time.Unix(0, 0).UTC().Format(http.TimeFormat)
+	}
+	return now.Add(p.maxAge).UTC().Format(http.TimeFormat)
+}
+
+// withCachePolicy sets Cache-Control and Expires once the response status is
+// known. Handlers that set Cache-Control themselves, such as event streams,
+// keep theirs.
+func (s *APIServer) withCachePolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.Method
+		if isGet(r) {
+			method = http.MethodGet
+		}
+		next.ServeHTTP(&cacheWriter{ResponseWriter: w, policy: cachePolicies[method+" "+routePath(r)]}, r)
+	})
+}
+
+type cacheWriter struct {
+	http.ResponseWriter
+	policy      cachePolicy
+	wroteHeader bool
+}
+
+func (c *cacheWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		c.setHeaders(status)
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *cacheWriter) setHeaders(status int) {
+	h := c.Header()
+	if h.Get("Cache-Control") != "" {
+		return
+	}
+	policy := c.policy
+	if status >= 400 {
+		policy = cachePolicy{}
+	}
+	h.Set("Cache-Control", policy.cacheControl())
+	h.Set("Expires", policy.expires(time.Now()))
+}
+
+func (c *cacheWriter) Write(b []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *cacheWriter) Flush() {
+	c.WriteHeader(http.StatusOK)
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachePolicy(t *testing.T) {
+	s := &APIServer{}
+	router := NewRouter(nil)
+	group := router.Group("", Chain{s.withCachePolicy})
+	group.HandleFunc("GET", "/version", func(w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Query().Has("fail") {
+			return NewError(CodeInvalidParameter, "name", "fail", "value", "")
+		}
+		return WriteJSON(w, http.StatusOK, "v1")
+	})
+	group.HandleFunc("GET", "/account/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		return WriteJSON(w, http.StatusOK, "balance")
+	})
+	serve := func(method, target string) http.Header {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec.Header()
+	}
+
+	h := serve("GET", "/version")
+	assert.Equal(t, "public, max-age=60", h.Get("Cache-Control"))
+	assert.NotEqual(t, "Thu, 01 Jan 1970 00:00:00 GMT", h.Get("Expires"))
+	assert.Equal(t, "public, max-age=60", serve("HEAD", "/version").Get("Cache-Control"))
+
+	h = serve("GET", "/account/7")
+	assert.Equal(t, "private, no-store", h.Get("Cache-Control"))
+	assert.Equal(t, "Thu, 01 Jan 1970 00:00:00 GMT", h.Get("Expires"))
+
+	assert.Equal(t, "private, no-store", serve("GET", "/version?fail").Get("Cache-Control"))
+}
+
+func TestCachePoliciesAreRoutes(t *testing.T) {
+	cfg := &Config{Mode: ModeSandbox, ServeFrontend: true}
+	s := NewAPIServer(NewLiveConfig(cfg), nil, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, NewMetrics(), nil, nil, nil, nil, nil, nil)
+	served := s.routes().Routes()
+	for route := range cachePolicies {
+		assert.True(t, served[route], "cache policy for %s, which isn't served", route)
+	}
+}
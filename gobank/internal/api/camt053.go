@@ -0,0 +1,344 @@
+package api
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// camt053Document is an ISO 20022 bank to customer statement, camt.053.
+type camt053Document struct {
+	XMLName   xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+	Statement struct {
+		MessageID string           `xml:"GrpHdr>MsgId"`
+		CreatedAt string           `xml:"GrpHdr>CreDtTm"`
+		Stmt      camt053Statement `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camt053Statement struct {
+	ID        string           `xml:"Id"`
+	CreatedAt string           `xml:"CreDtTm"`
+	From      string           `xml:"FrToDt>FrDtTm"`
+	To        string           `xml:"FrToDt>ToDtTm"`
+	Account   string           `xml:"Acct>Id>Othr>Id"`
+	Currency  string           `xml:"Acct>Ccy"`
+	Owner     string           `xml:"Acct>Ownr>Nm"`
+	Balances  []camt053Balance `xml:"Bal"`
+	Entries   int              `xml:"TxsSummry>TtlNtries>NbOfNtries"`
+	Sum       string           `xml:"TxsSummry>TtlNtries>Sum"`
+	Net       string           `xml:"TxsSummry>TtlNtries>TtlNetNtryAmt"`
+	NetSign   string           `xml:"TxsSummry>TtlNtries>CdtDbtInd"`
+	Entry     []camt053Entry   `xml:"Ntry"`
+}
+
+type camt053Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camt053Balance struct {
+	Type   string        `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camt053Amount `xml:"Amt"`
+	Sign   string        `xml:"CdtDbtInd"`
+	Date   string        `xml:"Dt>Dt"`
+}
+
+type camt053Entry struct {
+	Reference    string        `xml:"NtryRef"`
+	Amount       camt053Amount `xml:"Amt"`
+	Sign         string        `xml:"CdtDbtInd"`
+	Status       string        `xml:"Sts"`
+	BookingDate  string        `xml:"BookgDt>Dt"`
+	ValueDate    string        `xml:"ValDt>Dt"`
+	ServicerRef  string        `xml:"AcctSvcrRef"`
+	Code         string        `xml:"BkTxCd>Prtry>Cd"`
+	Counterparty *camt053Party `xml:"NtryDtls>TxDtls>RltdPties,omitempty"`
+	Remittance   string        `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+// camt053Party is the other side of an entry, the creditor of a debit and
+// the debtor of a credit.
+type camt053Party struct {
+	Debtor   string `xml:"DbtrAcct>Id>Othr>Id,omitempty"`
+	Creditor string `xml:"CdtrAcct>Id>Othr>Id,omitempty"`
+}
+
+// camt053Signed splits m into its unsigned amount and the credit or debit
+// indicator.
+func camt053Signed(m domain.Money) (camt053Amount, string) {
+	sign := "CRDT"
+	if m.MinorUnits < 0 {
+		sign, m.MinorUnits = "DBIT", -m.MinorUnits
+	}
+	return camt053Amount{Currency: m.Currency, Value: m.Decimal()}, sign
+}
+
+func camt053Bal(typ string, m domain.Money, at time.Time) camt053Balance {
+	amount, sign := camt053Signed(m)
+	return camt053Balance{Type: typ, Amount: amount, Sign: sign, Date: at.Format("2006-01-02")}
+}
+
+// writeCamt053 writes the transactions from from to to as a camt.053
+// statement with its opening and closing booked balances. Dates are in the
+// account's time zone, to is exclusive.
+func writeCamt053(w io.Writer, account *domain.Account, txs []*domain.Transaction, opening, closing domain.Money, from, to, now time.Time, loc *time.Location) error {
+	number := strconv.FormatInt(account.Number.Reveal(), 10)
+	from, to = from.In(loc), to.In(loc)
+	doc := new(camt053Document)
+	doc.Statement.MessageID = domain.NewUUID()
+	doc.Statement.CreatedAt = now.UTC().Format(time.RFC3339)
+	stmt := &doc.Statement.Stmt
+	stmt.ID = number + "-" + from.Format("20060102")
+	stmt.CreatedAt = doc.Statement.CreatedAt
+	stmt.From, stmt.To = from.Format(time.RFC3339), to.Add(-time.Second).Format(time.RFC3339)
+	stmt.Account, stmt.Currency = number, account.Balance.Currency
+	stmt.Owner = account.FirstName.Reveal() + " " + account.LastName.Reveal()
+	stmt.Balances = []camt053Balance{
+		camt053Bal("OPBD", opening, from),
+		camt053Bal("CLBD", closing, to.AddDate(0, 0, -1)),
+	}
+	sum, net := domain.Money{Currency: account.Balance.Currency}, domain.Money{Currency: account.Balance.Currency}
+	for _, t := range txs {
+		at := t.CreatedAt.In(loc).Format("2006-01-02")
+		amount, sign := camt053Signed(t.Amount)
+		entry := camt053Entry{
+			Reference:   strconv.Itoa(t.ID),
+			Amount:      amount,
+			Sign:        sign,
+			Status:      "BOOK",
+			BookingDate: at,
+			ValueDate:   at,
+			ServicerRef: strconv.Itoa(t.ID),
+			Code:        t.Type,
+			Remittance:  t.Description,
+		}
+		if t.Counterparty != 0 {
+			other := strconv.FormatInt(t.Counterparty.Reveal(), 10)
+			if sign == "DBIT" {
+				entry.Counterparty = &camt053Party{Creditor: other}
+			} else {
+				entry.Counterparty = &camt053Party{Debtor: other}
+			}
+		}
+		stmt.Entry = append(stmt.Entry, entry)
+		sum.MinorUnits += max(t.Amount.MinorUnits, -t.Amount.MinorUnits)
+		net.MinorUnits += t.Amount.MinorUnits
+	}
+	stmt.Entries = len(txs)
+	stmt.Sum = sum.Decimal()
+	var netAmount camt053Amount
+	netAmount, stmt.NetSign = camt053Signed(net)
+	stmt.Net = netAmount.Value
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// StatementJobType is the job that writes each account's camt.053 statement
+// of the day before. It's scheduled hourly, so every account gets its
+// statement within the hour after midnight in its time zone.
+const StatementJobType = "generate_statements"
+
+// statementKey is the blob a statement is kept under, the day is the
+// account's.
+func statementKey(tenantID, accountID int, day string) string {
+	return fmt.Sprintf("camt053-%d-%d-%s.xml", tenantID, accountID, day)
+}
+
+// StatementGenerator writes end of day statements to a BlobStore. Each one
+// written is an EventStatementReady, which tells the account holder.
+type StatementGenerator struct {
+	store   storage.Storage
+	blobs   storage.BlobStore
+	clock   domain.Clock
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewStatementGenerator(store storage.Storage, blobs storage.BlobStore, clock domain.Clock, metrics *Metrics, logger *slog.Logger) *StatementGenerator {
+	metrics.Help("statements_generated_total", "End of day camt.053 statements written.")
+	return &StatementGenerator{store: store, blobs: blobs, clock: clock, metrics: metrics, logger: logger}
+}
+
+// HandleJob writes the statement of the last day that is over in each
+// account's time zone, unless it's there already.
+func (g *StatementGenerator) HandleJob(job *domain.Job) error {
+	tenants, err := g.store.ListTenants()
+	if err != nil {
+		return err
+	}
+	written := 0
+	for _, tenant := range tenants {
+		store := g.store.ForTenant(tenant.ID)
+		err := store.EachAccount(func(account *domain.Account) error {
+			loc, err := loadLocation(account.Timezone)
+			if err != nil {
+				loc = time.UTC
+			}
+			to := domain.StartOfDay(g.clock.Now(), loc)
+			from := to.AddDate(0, 0, -1)
+			if !account.CreatedAt.Before(to) {
+				return nil
+			}
+			key := statementKey(account.TenantID, account.ID, from.Format("2006-01-02"))
+			if blob, err := g.blobs.Get(key); err == nil {
+				blob.Close()
+				return nil
+			}
+			if err := g.generate(store, account, key, from, to, loc); err != nil {
+				return fmt.Errorf("statement of account %d: %w", account.ID, err)
+			}
+			written++
+			// the statement is written, the job won't come back for it
+			ev, err := domain.NewEvent(domain.EventStatementReady, account.ID, map[string]string{"date": from.Format("2006-01-02")}, g.clock.Now())
+			if err == nil {
+				err = store.RecordEvent(ev)
+			}
+			if err != nil {
+				g.logger.Error("recording statement event failed", "account_id", account.ID, "error", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	g.metrics.Add("statements_generated_total", float64(written))
+	g.logger.Info("statements generated", "count", written)
+	return nil
+}
+
+func (g *StatementGenerator) generate(store storage.Storage, account *domain.Account, key string, from, to time.Time, loc *time.Location) error {
+	statement, err := renderCamt053(store, account, from, to, g.clock.Now(), loc)
+	if err != nil {
+		return err
+	}
+	return g.blobs.Put(key, bytes.NewReader(statement))
+}
+
+// renderCamt053 writes the account's camt.053 statement of the period
+// from to to.
+func renderCamt053(store storage.Storage, account *domain.Account, from, to, now time.Time, loc *time.Location) ([]byte, error) {
+	txs, err := store.TransactionsBetween(account.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	txs = posted(txs)
+	opening, closing, err := periodBalances(store, account, from, to, now, txs)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeCamt053(&buf, account, txs, opening, closing, from, to, now, loc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTextStatement renders the statement of a day that is over in the
+// account's time zone from its transactions.
+func (s *APIServer) writeTextStatement(w http.ResponseWriter, store storage.Storage, account *domain.Account, day string) error {
+	loc, err := loadLocation(account.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	from, _ := time.ParseInLocation("2006-01-02", day, loc)
+	to := from.AddDate(0, 0, 1)
+	now := s.clock.Now()
+	if to.After(now) || !account.CreatedAt.Before(to) {
+		return NewError(CodeNotFound, "id", day)
+	}
+	txs, err := store.TransactionsBetween(account.ID, from, to)
+	if err != nil {
+		return err
+	}
+	txs = posted(txs)
+	opening, closing, err := periodBalances(store, account, from, to, now, txs)
+	if err != nil {
+		return err
+	}
+	text, err := renderText(account, "statement", textStatement{Account: account, Day: from, Opening: opening, Closing: closing, Transactions: txs})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Language", domain.AccountLocale(account).Tag)
+	_, err = io.WriteString(w, text)
+	return err
+}
+
+// handleStatements serves GET /account/{id}/statements, the days there's an
+// end of day statement for, oldest first.
+func (s *APIServer) handleStatements(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	account, err := s.storeFor(r).GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimSuffix(statementKey(account.TenantID, account.ID, ""), ".xml")
+	keys, err := s.statements.List(prefix)
+	if err != nil {
+		return err
+	}
+	days := make([]string, 0, len(keys))
+	for _, key := range keys {
+		days = append(days, strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".xml"))
+	}
+	return WriteJSON(w, http.StatusOK, days)
+}
+
+// handleStatement serves GET /account/{id}/statements/{date}, the camt.053
+// statement of the day, or with format=text one to read in the account's
+// language and locale.
+func (s *APIServer) handleStatement(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	day := r.PathValue("date")
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		return invalidParameter("date", day)
+	}
+	format, err := QueryEnum(r, "format", "camt053", "camt053", "text")
+	if err != nil {
+		return err
+	}
+	if format == "text" {
+		return s.writeTextStatement(w, store, account, day)
+	}
+	blob, err := s.statements.Get(statementKey(account.TenantID, account.ID, day))
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewError(CodeNotFound, "id", day)
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="camt053-%d-%s.xml"`, account.Number.Reveal(), day))
+	_, err = io.Copy(w, blob)
+	return err
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChaosState configures the faults injected into matching requests so that
+// client developers can exercise their retry and idempotency handling.
+type ChaosState struct {
+	Enabled bool `json:"enabled"`
+	// Routes are path prefixes, empty matches every non operator route.
+	Routes    []string `json:"routes"`
+	LatencyMs int      `json:"latencyMs"`
+	JitterMs  int      `json:"jitterMs"`
+	// ErrorRate and DropRate are probabilities between 0 and 1, a dropped
+	// request has its connection closed without any response.
+	ErrorRate float64 `json:"errorRate"`
+	DropRate  float64 `json:"dropRate"`
+}
+
+var chaosStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Chaos is the fault injection switch, flipped at runtime through
+// /admin/chaos. It only exists in sandbox mode.
+type Chaos struct {
+	mu    sync.RWMutex
+	state ChaosState
+	rand  func() float64
+}
+
+func NewChaos() *Chaos {
+	return &Chaos{rand: rand.Float64}
+}
+
+func (c *Chaos) State() ChaosState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+func (c *Chaos) Set(state ChaosState) error {
+	if state.LatencyMs < 0 || state.JitterMs < 0 {
+		return fmt.Errorf("latency can't be negative")
+	}
+	if state.ErrorRate < 0 || state.DropRate < 0 || state.ErrorRate+state.DropRate > 1 {
+		return fmt.Errorf("error and drop rates must be between 0 and 1 and add up to at most 1")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+	return nil
+}
+
+func (st ChaosState) matches(path string) bool {
+	if !st.Enabled || isOperatorPath(path) {
+		return false
+	}
+	if len(st.Routes) == 0 {
+		return true
+	}
+	for _, prefix := range st.Routes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withChaos injects failures inside withRecovery, which leaves them out of
+// the error reports: they're expected.
+func (s *APIServer) withChaos(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.chaos == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		state := s.chaos.State()
+		if !state.matches(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		delay := time.Duration(state.LatencyMs) * time.Millisecond
+		if state.JitterMs > 0 {
+			delay += time.Duration(s.chaos.rand()*float64(state.JitterMs)) * time.Millisecond
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		roll := s.chaos.rand()
+		switch {
+		case roll < state.DropRate:
+			loggerFrom(r.Context()).Warn("chaos: dropping connection")
+			// net/http closes the connection without logging for this panic
+			panic(http.ErrAbortHandler)
+		case roll < state.DropRate+state.ErrorRate:
+			status := chaosStatuses[int(s.chaos.rand()*float64(len(chaosStatuses)))%len(chaosStatuses)]
+			w.Header().Set("X-Chaos-Injected", "true")
+			writeError(w, r, status, NewError(CodeInternal))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) handleChaos(w http.ResponseWriter, r *http.Request) error {
+	if isGet(r) {
+		return WriteJSON(w, http.StatusOK, s.chaos.State())
+	}
+	if r.Method == http.MethodPut {
+		var state ChaosState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			return err
+		}
+		if err := s.chaos.Set(state); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, s.chaos.State())
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChaosRoutes(t *testing.T) {
+	st := ChaosState{Enabled: true, Routes: []string{"/transfer"}}
+	assert.True(t, st.matches("/transfer"))
+	assert.False(t, st.matches("/account/1"))
+	assert.False(t, ChaosState{Enabled: true}.matches("/admin/chaos"))
+	assert.False(t, ChaosState{}.matches("/transfer"))
+}
+
+func TestChaosSetValidates(t *testing.T) {
+	c := NewChaos()
+	assert.NotNil(t, c.Set(ChaosState{ErrorRate: 0.7, DropRate: 0.5}))
+	assert.NotNil(t, c.Set(ChaosState{LatencyMs: -1}))
+	assert.Nil(t, c.Set(ChaosState{Enabled: true, ErrorRate: 0.5, DropRate: 0.5}))
+}
+
+func TestWithChaos(t *testing.T) {
+	s := &APIServer{chaos: NewChaos()}
+	s.chaos.rand = func() float64 { return 0.3 }
+	handler := s.withChaos(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	s.chaos.Set(ChaosState{Enabled: true, ErrorRate: 0.5})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account/1", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("X-Chaos-Injected"))
+
+	s.chaos.Set(ChaosState{Enabled: true, DropRate: 0.5})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/account/1", nil))
+	})
+
+	s.chaos.Set(ChaosState{Enabled: true, ErrorRate: 0.2})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account/1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestChaosThroughRoutes(t *testing.T) {
+	reporter := &recordingReporter{}
+	s := NewAPIServer(NewLiveConfig(&Config{Mode: ModeSandbox}), nil, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), reporter, NewMetrics(), nil, nil, nil, nil, nil, nil)
+	srv := httptest.NewServer(s.routes())
+	defer srv.Close()
+
+	s.chaos.Set(ChaosState{Enabled: true, DropRate: 1})
+	_, err := http.Get(srv.URL + "/version")
+	assert.NotNil(t, err)
+
+	s.chaos.Set(ChaosState{Enabled: true, ErrorRate: 1})
+	res, err := http.Get(srv.URL + "/version")
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.GreaterOrEqual(t, res.StatusCode, 500)
+		assert.Equal(t, "true", res.Header.Get("X-Chaos-Injected"))
+	}
+	assert.Empty(t, reporter.errs)
+}
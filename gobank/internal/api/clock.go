@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"time"
+)
+
+type ClockState struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+// AdvanceClockRequest moves the clock by Days plus By, a Go duration such as
+// "36h".
+type AdvanceClockRequest struct {
+	Days int    `json:"days"`
+	By   string `json:"by"`
+}
+
+// handleClock serves /admin/clock, registered in sandbox mode only.
+func (s *APIServer) handleClock(w http.ResponseWriter, r *http.Request) error {
+	clock, ok := s.clock.(*domain.SimClock)
+	if !ok {
+		return NewError(CodeMethodNotAllowed, "method", r.Method)
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		req := new(AdvanceClockRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+		d := time.Duration(req.Days) * 24 * time.Hour
+		if req.By != "" {
+			by, err := time.ParseDuration(req.By)
+			if err != nil {
+				return NewError(CodeInvalidParameter, "name", "by", "value", req.By)
+			}
+			d += by
+		}
+		if err := clock.Advance(d); err != nil {
+			return err
+		}
+		loggerFrom(r.Context()).Warn("simulated clock advanced", "by", d.String(), "offset", clock.Offset().String())
+	default:
+		return NewError(CodeMethodNotAllowed, "method", r.Method)
+	}
+	return WriteJSON(w, http.StatusOK, ClockState{Now: clock.Now().UTC(), Offset: clock.Offset().String()})
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleClock(t *testing.T) {
+	clock := domain.NewSimClock()
+	s := &APIServer{clock: clock}
+	rec := httptest.NewRecorder()
+	err := s.handleClock(rec, httptest.NewRequest("POST", "/admin/clock", strings.NewReader(`{"days": 30, "by": "12h"}`)))
+	assert.Nil(t, err)
+	assert.Equal(t, 30*24*time.Hour+12*time.Hour, clock.Offset())
+	assert.Contains(t, rec.Body.String(), `"offset":"732h0m0s"`)
+
+	err = s.handleClock(httptest.NewRecorder(), httptest.NewRequest("POST", "/admin/clock", strings.NewReader(`{"by": "soon"}`)))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Codec converts bodies between JSON and another wire format. Handlers and
+// middleware only ever deal in JSON: request bodies in a registered format
+// are converted on the way in, JSON responses on the way out, so every format
+// carries exactly what the JSON would.
+type Codec interface {
+	// Encode writes v, a value as decoded from JSON with UseNumber.
+	Encode(w io.Writer, v any) error
+	// Decode reads a body into the same kind of value.
+	Decode(r io.Reader) (any, error)
+}
+
+// codecs maps media types to the formats served besides JSON, picked by the
+// Accept and Content-Type headers.
+var codecs = map[string]Codec{
+	"application/xml":       xmlCodec{},
+	"application/msgpack":   msgpackCodec{},
+	"application/x-msgpack": msgpackCodec{},
+}
+
+// rawBodyRoutes read a body in a registered format themselves. An ISO 20022
+// file isn't the XML form of a JSON body.
+var rawBodyRoutes = map[string]bool{
+	"POST /admin/payments/pain001": true,
+}
+
+// codecMaxDepth bounds the nesting of decoded bodies, the request bodies the
+// API takes are a few levels deep at most.
+const codecMaxDepth = 32
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// negotiate picks the response format from Accept. It returns a nil Codec,
+// leaving the response as it is, when JSON is preferred or nothing registered
+// is acceptable; wildcards count as JSON.
+func negotiate(accept string) (string, Codec) {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if _, ok := codecs[mediaType]; !ok && mediaType != "application/json" {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	if codec, ok := codecs[best]; ok {
+		return best, codec
+	}
+	return "", nil
+}
+
+// withContentNegotiation serves JSON responses in the format the client
+// accepts and turns request bodies in a registered format into JSON before
+// schema validation sees them.
+func (s *APIServer) withContentNegotiation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		var tc *transcoder
+		if mediaType, codec := negotiate(r.Header.Get("Accept")); codec != nil {
+			tc = &transcoder{ResponseWriter: w, mediaType: mediaType, codec: codec}
+			w = tc
+		}
+		if err := decodeBody(w, r); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+		} else {
+			next.ServeHTTP(w, r)
+		}
+		if tc != nil {
+			tc.finish(r)
+		}
+	})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	codec, ok := codecs[mediaType]
+	if !ok || r.Body == nil || r.Body == http.NoBody || rawBodyRoutes[r.Method+" "+routePath(r)] {
+		return nil
+	}
+	v, err := codec.Decode(http.MaxBytesReader(w, r.Body, schemaMaxBytes))
+	var body []byte
+	if err == nil {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		loggerFrom(r.Context()).Info("unreadable request body", "content_type", mediaType, "error", err)
+		return NewError(CodeUnreadableBody, "format", mediaType)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// transcoder holds back JSON responses and re-encodes them once the handler
+// is done. Anything else, CSV exports or event streams, passes through.
+type transcoder struct {
+	http.ResponseWriter
+	mediaType   string
+	codec       Codec
+	status      int
+	wroteHeader bool
+	holding     bool
+	buf         bytes.Buffer
+}
+
+func (t *transcoder) WriteHeader(status int) {
+	if t.wroteHeader {
+		return
+	}
+	t.wroteHeader = true
+	if mediaType, _, _ := mime.ParseMediaType(t.Header().Get("Content-Type")); mediaType == "application/json" {
+		t.holding = true
+		t.status = status
+		return
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *transcoder) Write(b []byte) (int, error) {
+	t.WriteHeader(http.StatusOK)
+	if t.holding {
+		return t.buf.Write(b)
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+// Flush only flushes responses passed through, a held one goes out whole.
+func (t *transcoder) Flush() {
+	if t.holding {
+		return
+	}
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (t *transcoder) finish(r *http.Request) {
+	if !t.holding {
+		return
+	}
+	raw := t.buf.Bytes()
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		// empty, as on 204, or not JSON after all
+		t.ResponseWriter.WriteHeader(t.status)
+		t.ResponseWriter.Write(raw)
+		return
+	}
+	t.Header().Set("Content-Type", t.mediaType)
+	t.Header().Del("Content-Length")
+	t.ResponseWriter.WriteHeader(t.status)
+	if err := t.codec.Encode(t.ResponseWriter, v); err != nil {
+		loggerFrom(r.Context()).Error("encoding response failed", "content_type", t.mediaType, "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func genericJSON(t *testing.T, s string) any {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	assert.Nil(t, dec.Decode(&v))
+	return v
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	v := genericJSON(t, `{"id": 7, "name": "Ada ", "email": null, "active": true, "rate": 1.25,
+		"big": 18446744073709551615, "neg": -40000, "tags": [], "items": [{"a": 1}, "x"],
+		"empty": {}, "totals": {"2024-01-31": 12}, "long": "`+strings.Repeat("x", 300)+`"}`)
+	for name, codec := range map[string]Codec{"xml": xmlCodec{}, "msgpack": msgpackCodec{}} {
+		var buf bytes.Buffer
+		assert.Nil(t, codec.Encode(&buf, v), name)
+		got, err := codec.Decode(&buf)
+		assert.Nil(t, err, name)
+		assert.Equal(t, v, got, name)
+	}
+}
+
+func TestXMLCodec(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, xmlCodec{}.Encode(&buf, genericJSON(t, `{"id": 7, "tags": ["a"], "2024": null}`)))
+	assert.Equal(t, xml.Header+`<response><entry key="2024" nil="true"></entry><id type="number">7</id><tags type="array"><item>a</item></tags></response>`, buf.String())
+
+	v, err := xmlCodec{}.Decode(strings.NewReader(`<transfer>
+		<toAccount type="number">42</toAccount>
+		<amount>12.50</amount>
+	</transfer>`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"toAccount": json.Number("42"), "amount": "12.50"}, v)
+
+	_, err = xmlCodec{}.Decode(strings.NewReader(`<a><n type="number">0x10</n></a>`))
+	assert.NotNil(t, err)
+}
+
+func TestMsgpackDecodeRejects(t *testing.T) {
+	for name, b := range map[string][]byte{
+		"truncated":    {0xa5, 'a'},
+		"trailing":     {0xc0, 0xc0},
+		"int key":      {0x81, 0x01, 0x01},
+		"ext":          {0xd4, 0x01, 0x00},
+		"bogus length": {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"nan":          {0xcb, 0x7f, 0xf8, 0, 0, 0, 0, 0, 0},
+	} {
+		_, err := msgpackCodec{}.Decode(bytes.NewReader(b))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                 "",
+		"*/*":              "",
+		"application/json": "",
+		"application/xml":  "application/xml",
+		"application/json;q=0.5, application/msgpack": "application/msgpack",
+		"application/xml, application/json":           "application/xml",
+		"text/csv":                                    "",
+	} {
+		mediaType, _ := negotiate(accept)
+		assert.Equal(t, want, mediaType, accept)
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	s := &APIServer{}
+	h := s.withContentNegotiation(makeHttpHandleFunc(func(w http.ResponseWriter, r *http.Request) error {
+		body, _ := io.ReadAll(r.Body)
+		return WriteJSON(w, http.StatusCreated, map[string]string{"got": string(body), "type": r.Header.Get("Content-Type")})
+	}))
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`<req><n type="number">5</n></req>`))
+	r.Header.Set("Content-Type", "application/xml")
+	r.Header.Set("Accept", "application/msgpack")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "application/msgpack", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	v, err := msgpackCodec{}.Decode(rec.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"got": `{"n":5}`, "type": "application/json"}, v)
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`<req>`))
+	r.Header.Set("Content-Type", "application/xml")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"unreadable_body"`)
+}
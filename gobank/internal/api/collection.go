@@ -0,0 +1,283 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// requestExamples are the bodies the collection fills in. {{name}} is a
+// collection variable, substituted by Postman before sending.
+var requestExamples = map[string]string{
+	"POST /login":         `{"number": {{number}}, "password": "{{password}}"}`,
+	"POST /account":       `{"firstName": "Anthony", "lastName": "GG", "email": "anthony@example.com", "address": {"line1": "Torstraße 1", "city": "Berlin", "postalCode": "10119", "country": "DE"}, "dateOfBirth": "1990-05-17", "timezone": "Europe/Berlin", "password": "hunter888"}`,
+	"PATCH /account/{id}": `{"timezone": "America/New_York"}`,
+	"POST /account/{id}/phone/verification/confirm":   `{"code": "123456"}`,
+	"POST /account/{id}/pots":                         `{"name": "Rent", "amount": "950.00"}`,
+	"PATCH /account/{id}/pots/{potId}":                `{"amount": "1000.00"}`,
+	"POST /account/{id}/statement-destinations":       `{"sink": "sftp", "frequency": "monthly", "url": "sftp://gobank@sftp.example.com/statements", "hostKey": "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"}`,
+	"POST /account/{id}/devices":                      `{"platform": "ios", "token": "8c97a1b3f7e4d2a6c5b8e9f0a1d2c3b4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0", "name": "Jana's iPhone"}`,
+	"POST /account/{id}/api-keys":                     `{"name": "ci"}`,
+	"POST /account/{id}/webhooks":                     `{"url": "https://example.com/hooks/gobank"}`,
+	"POST /account/{id}/consents":                     `{"apiKeyId": "gbk_3f9a", "purpose": "budgeting app", "scopes": ["balances", "transactions"], "days": 90}`,
+	"POST /open-banking/v1/payments/credit-transfers": `{"instructedAmount": {"currency": "EUR", "amount": "25.00"}, "creditorAccount": {"bban": "4711007"}, "creditorName": "Jane Doe", "remittanceInformationUnstructured": "rent"}`,
+	"POST /account/{id}/terms/accept":                 `{"terms": "2024-06-01", "privacy": "2024-06-01"}`,
+	"POST /account/{id}/transactions/import":          "date,amount,description\n2024-05-01,-12.50,Coffee\n2024-05-02,2500.00,Salary\n",
+	"POST /transfer":                                  `{"toAccount": 1234567, "amount": {"amount": "25.00", "currency": "USD"}}`,
+	"POST /transfer/quote":                            `{"toAccount": 1234567, "amount": {"amount": "25.00", "currency": "USD"}}`,
+	"POST /transfers/batch":                           `{"mode": "atomic", "transfers": [{"toAccount": 1234567, "amount": "1500.00"}, {"toAccount": 7654321, "amount": "1750.00"}]}`,
+	"POST /transfer/authorize":                        `{"toAccount": 1234567, "amount": {"amount": "25.00", "currency": "USD"}}`,
+	"POST /transfer/holds/{holdId}/capture":           `{"amount": {"amount": "19.80", "currency": "USD"}}`,
+	"POST /sandbox/account/{id}/topup":                `{"amount": {"amount": "100.00", "currency": "USD"}, "description": "test money"}`,
+	"POST /admin/tenants":                             `{"slug": "acme", "name": "Acme Inc"}`,
+	"PUT /admin/tenants/{id}/settings":                `{"currency": "EUR", "maxTransferAmount": 100000, "dailyTransferLimit": 500000, "brandName": "Acme Bank", "supportEmail": "support@acme.example"}`,
+	"PUT /admin/maintenance":                          `{"mode": "read-only", "message": "Upgrading the database", "retryAfter": 300}`,
+	"PUT /admin/chaos":                                `{"enabled": true, "routes": ["/transfer"], "latencyMs": 200, "jitterMs": 100, "errorRate": 0.1, "dropRate": 0}`,
+	"POST /admin/clock":                               `{"days": 30}`,
+	"POST /admin/reconciliation/issues/{id}/resolve":  `{"resolution": "corrected by hand"}`,
+	"PUT /admin/accounts/{id}/minimum-balance":        `{"minimumBalance": "100.00"}`,
+	"POST /admin/reports/accounting":                  `{"format": "xero", "from": "2024-05-01", "to": "2024-06-01", "currency": "EUR", "accounts": {"deposits": "2100", "transfers": "2190", "fees": "4100", "imports": "2191", "other": "9990"}}`,
+	"POST /admin/accounts/{id}/impersonations":        `{"requestedBy": "jane@support", "reason": "ticket 4711, balance looks wrong", "minutes": 30, "requireApproval": true}`,
+	"POST /admin/email/suppressions":                  `{"address": "jana.novak@example.com"}`,
+	"POST /admin/accounts/portable":                   `{"version": 1, "exportedAt": "2024-06-01T00:00:00Z", "accounts": []}`,
+}
+
+// collectionPreRequest runs before every request of the collection. It logs
+// in when an account request has no token yet, and signs account requests
+// with the API key instead when apiKeyId is set.
+var collectionPreRequest = []string{
+	`(() => {`,
+	`const auth = pm.request.headers.get("x-jwt-token");`,
+	`if (auth === undefined) return;`,
+	`const keyId = pm.collectionVariables.get("apiKeyId");`,
+	`if (keyId) {`,
+	`  pm.request.headers.remove("x-jwt-token");`,
+	`  const ts = Math.floor(Date.now() / 1000).toString();`,
+	`  const nonce = CryptoJS.lib.WordArray.random(16).toString();`,
+	`  const body = pm.request.body && pm.request.body.raw ? pm.variables.replaceIn(pm.request.body.raw) : "";`,
+	`  const uri = pm.variables.replaceIn(pm.request.url.getPathWithQuery());`,
+	`  const payload = [pm.request.method, uri, ts, nonce, CryptoJS.SHA256(body).toString()].join("\n");`,
+	`  pm.request.headers.upsert({ key: "X-Api-Key", value: keyId });`,
+	`  pm.request.headers.upsert({ key: "X-Timestamp", value: ts });`,
+	`  pm.request.headers.upsert({ key: "X-Nonce", value: nonce });`,
+	`  pm.request.headers.upsert({ key: "X-Signature", value: CryptoJS.HmacSHA256(payload, pm.collectionVariables.get("apiKeySecret")).toString() });`,
+	`  return;`,
+	`}`,
+	`if (pm.collectionVariables.get("token") || !pm.collectionVariables.get("number")) return;`,
+	`pm.sendRequest({`,
+	`  url: pm.collectionVariables.get("baseUrl") + "/login",`,
+	`  method: "POST",`,
+	`  header: { "Content-Type": "application/json", "X-Tenant": pm.collectionVariables.get("tenant") },`,
+	`  body: { mode: "raw", raw: JSON.stringify({ number: Number(pm.collectionVariables.get("number")), password: pm.collectionVariables.get("password") }) },`,
+	`}, (err, res) => {`,
+	`  if (err || res.code !== 200) return console.log("login failed", err || res.text());`,
+	`  pm.collectionVariables.set("token", res.json().token);`,
+	`  pm.collectionVariables.set("accountId", res.json().id);`,
+	`  pm.request.headers.upsert({ key: "x-jwt-token", value: res.json().token });`,
+	`});`,
+	`})();`,
+}
+
+// loginTest keeps the token of a manual login for the requests after it.
+var loginTest = []string{
+	`if (pm.response.code === 200) {`,
+	`  pm.collectionVariables.set("token", pm.response.json().token);`,
+	`  pm.collectionVariables.set("accountId", pm.response.json().id);`,
+	`}`,
+}
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Variable []postmanVariable `json:"variable"`
+	Event    []postmanEvent    `json:"event,omitempty"`
+	Item     []postmanItem     `json:"item"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type postmanVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type postmanEvent struct {
+	Listen string        `json:"listen"`
+	Script postmanScript `json:"script"`
+}
+
+type postmanScript struct {
+	Type string   `json:"type"`
+	Exec []string `json:"exec"`
+}
+
+// postmanItem is a folder when Item is set, a request otherwise.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item,omitempty"`
+	Request *postmanRequest `json:"request,omitempty"`
+	Event   []postmanEvent  `json:"event,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string          `json:"method"`
+	Header      []postmanHeader `json:"header"`
+	URL         postmanURL      `json:"url"`
+	Body        *postmanBody    `json:"body,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+type postmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []postmanQuery    `json:"query,omitempty"`
+	Variable []postmanVariable `json:"variable,omitempty"`
+}
+
+type postmanQuery struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanBody struct {
+	Mode    string              `json:"mode"`
+	Raw     string              `json:"raw"`
+	Options *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+func postmanJS(lines []string) postmanScript {
+	return postmanScript{Type: "text/javascript", Exec: lines}
+}
+
+// buildCollection turns apiOperations into a Postman v2.1 collection, which
+// Insomnia imports too. Operations on routes the router doesn't have, the
+// sandbox ones in production, are left out.
+func buildCollection(baseURL string, served map[string]bool) postmanCollection {
+	folders := map[string][]postmanItem{}
+	for _, op := range apiOperations {
+		if !served[op.Path] {
+			continue
+		}
+		folder := "Account"
+		if strings.HasPrefix(op.Path, "/admin/") {
+			folder = "Admin"
+		} else if strings.HasPrefix(op.Path, "/sandbox/") {
+			folder = "Sandbox"
+		}
+		folders[folder] = append(folders[folder], collectionItem(op))
+	}
+	names := make([]string, 0, len(folders))
+	for name := range folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := postmanCollection{
+		Info: postmanInfo{
+			Name:        "gobank",
+			Description: "Set number and password to log in automatically, or apiKeyId and apiKeySecret to sign requests with an API key. Admin requests send adminToken.",
+			Schema:      postmanSchema,
+		},
+		Variable: []postmanVariable{
+			{Key: "baseUrl", Value: baseURL},
+			{Key: "tenant", Value: domain.DefaultTenantSlug},
+			{Key: "number", Value: ""},
+			{Key: "password", Value: ""},
+			{Key: "token", Value: ""},
+			{Key: "accountId", Value: "1"},
+			{Key: "apiKeyId", Value: ""},
+			{Key: "apiKeySecret", Value: ""},
+			{Key: "adminToken", Value: ""},
+		},
+		Event: []postmanEvent{{Listen: "prerequest", Script: postmanJS(collectionPreRequest)}},
+	}
+	for _, name := range names {
+		c.Item = append(c.Item, postmanItem{Name: name, Item: folders[name]})
+	}
+	return c
+}
+
+func collectionItem(op apiOperation) postmanItem {
+	key := op.Method + " " + op.Path
+	url := postmanURL{Host: []string{"{{baseUrl}}"}}
+	for _, seg := range strings.Split(strings.TrimPrefix(op.Path, "/"), "/") {
+		if m := pathParam.FindStringSubmatch(seg); m != nil {
+			// the logged in account's id where it's the account, a path
+			// variable to fill in anywhere else
+			if m[1] == "id" && !strings.HasPrefix(op.Path, "/admin/") {
+				seg = "{{accountId}}"
+			} else {
+				seg = ":" + m[1]
+				url.Variable = append(url.Variable, postmanVariable{Key: m[1], Value: "1"})
+			}
+		}
+		url.Path = append(url.Path, seg)
+	}
+	url.Raw = "{{baseUrl}}/" + strings.Join(url.Path, "/")
+	for _, q := range op.Query {
+		url.Query = append(url.Query, postmanQuery{Key: q, Disabled: true})
+	}
+
+	req := &postmanRequest{
+		Method:      op.Method,
+		Header:      []postmanHeader{{Key: "X-Tenant", Value: "{{tenant}}"}},
+		URL:         url,
+		Description: op.Summary,
+	}
+	switch op.Auth {
+	case authAccount:
+		req.Header = append(req.Header, postmanHeader{Key: "x-jwt-token", Value: "{{token}}"})
+	case authAdmin:
+		req.Header = append(req.Header, postmanHeader{Key: "x-admin-token", Value: "{{adminToken}}"})
+	}
+	if example, ok := requestExamples[key]; ok {
+		body := &postmanBody{Mode: "raw", Raw: example}
+		if len(op.Consumes) > 0 {
+			req.Header = append(req.Header, postmanHeader{Key: "Content-Type", Value: op.Consumes[0]})
+		} else {
+			req.Header = append(req.Header, postmanHeader{Key: "Content-Type", Value: "application/json"})
+			body.Options = new(postmanBodyOptions)
+			body.Options.Raw.Language = "json"
+		}
+		req.Body = body
+	}
+
+	item := postmanItem{Name: op.Summary, Request: req}
+	if key == "POST /login" {
+		item.Event = []postmanEvent{{Listen: "test", Script: postmanJS(loginTest)}}
+	}
+	return item
+}
+
+// handleCollection serves GET /dev/collection, a Postman collection of the
+// routes this server has, pointed at the host it was fetched from.
+func (s *APIServer) handleCollection(w http.ResponseWriter, r *http.Request) error {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="gobank.postman_collection.json"`)
+	return WriteJSON(w, http.StatusOK, buildCollection(scheme+"://"+r.Host, s.servedPaths))
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCollectionExamplesValidate(t *testing.T) {
+	set, err := loadSchemas()
+	assert.Nil(t, err)
+	for route, file := range requestSchemas {
+		example, ok := requestExamples[route]
+		if !assert.True(t, ok, "no example for %s", route) {
+			continue
+		}
+		example = strings.NewReplacer("{{number}}", "1234567", "{{password}}", "hunter888").Replace(example)
+		assert.Empty(t, set.Validate(file, []byte(example)), route)
+	}
+}
+
+func TestBuildCollection(t *testing.T) {
+	served := map[string]bool{"/login": true, "/account/{id}": true, "/admin/jobs/{id}/retry": true}
+	c := buildCollection("https://bank.example.com", served)
+
+	assert.Equal(t, postmanSchema, c.Info.Schema)
+	assert.Equal(t, postmanVariable{Key: "baseUrl", Value: "https://bank.example.com"}, c.Variable[0])
+	assert.Len(t, c.Item, 2)
+
+	account, admin := c.Item[0], c.Item[1]
+	assert.Equal(t, "Account", account.Name)
+	assert.Len(t, account.Item, 4) // login, and get, patch and delete account
+	getAccount := account.Item[1].Request
+	assert.Equal(t, "{{baseUrl}}/account/{{accountId}}", getAccount.URL.Raw)
+	assert.Contains(t, getAccount.Header, postmanHeader{Key: "x-jwt-token", Value: "{{token}}"})
+
+	retry := admin.Item[0].Request
+	assert.Equal(t, "{{baseUrl}}/admin/jobs/:id/retry", retry.URL.Raw)
+	assert.Equal(t, []postmanVariable{{Key: "id", Value: "1"}}, retry.URL.Variable)
+	assert.Contains(t, retry.Header, postmanHeader{Key: "x-admin-token", Value: "{{adminToken}}"})
+}
@@ -0,0 +1,554 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"github.com/joho/godotenv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	ModeSandbox    = "sandbox"
+	ModeProduction = "production"
+)
+
+// Config holds the process wide settings, read from the environment (and
+// .env when present).
+type Config struct {
+	ListenAddr string
+
+	// Mode is "sandbox" or "production". Sandbox enables the conveniences for
+	// client developers, such as seeding and fault injection. Production
+	// disables them and requires TLS.
+	Mode string
+
+	// TenantDomain is the base domain tenants are served from as subdomains,
+	// e.g. acme.gobank.example resolves the "acme" tenant.
+	TenantDomain string
+
+	// EventTransport is "memory" for a single instance or "nats" to share
+	// events between instances.
+	EventTransport string
+	NatsURL        string
+	NatsSubject    string
+
+	KafkaBrokers []string
+	KafkaTopic   string
+	KafkaFormat  string
+
+	// ServeFrontend serves the embedded demo frontend at /.
+	ServeFrontend bool
+
+	// LogFormat is "text" or "json".
+	LogFormat string
+
+	// SentryDSN enables error reporting to Sentry when set.
+	SentryDSN   string
+	Environment string
+
+	// TLSCertFile and TLSKeyFile make the server speak HTTPS.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientAuth is "off", "optional" or "require". Client certificates are
+	// verified against ClientCAFile and mapped to ServiceAccounts, see mtls.go.
+	ClientAuth    string
+	ClientCAFile  string
+	ClientCRLFile string
+	// ClientCertPins, when set, only admits client certificates with one of
+	// these hex SHA-256 fingerprints.
+	ClientCertPins []string
+	// ServiceAccounts maps certificate subjects (common name or full DN) to
+	// scopes, from MTLS_SERVICE_ACCOUNTS="billing=admin,read;reports=read".
+	ServiceAccounts map[string][]string
+
+	// ReadHeaderTimeoutSeconds bounds how long a client may take to send the
+	// request headers. IdleTimeoutSeconds is how long a kept-alive connection
+	// waits for the next request, KeepAlive false closes every connection
+	// after one request.
+	ReadHeaderTimeoutSeconds int
+	IdleTimeoutSeconds       int
+	KeepAlive                bool
+	// HTTP2MaxStreams is the number of requests an HTTP/2 client may have in
+	// flight on one connection. H2C serves HTTP/2 without TLS, for internal
+	// traffic only.
+	HTTP2MaxStreams int
+	H2C             bool
+
+	// BackupDir is where `gobank backup` and the scheduled backup job put
+	// their dumps. BackupIntervalHours 0 turns the scheduled backups off,
+	// BackupKeep 0 never deletes old ones.
+	BackupDir           string
+	BackupIntervalHours int
+	BackupKeep          int
+
+	// RecordingDir is where request recordings go while
+	// Runtime.RecordRequests is on, see record.go.
+	RecordingDir string
+
+	// StatementDir is where the statement job puts the camt.053 end of day
+	// statements, see camt053.go.
+	StatementDir string
+	// ReportDir is where the large-transaction reports go, see
+	// large_transactions.go.
+	ReportDir string
+	// AvatarDir is where the account avatars go, see avatar.go.
+	AvatarDir string
+	// AccountEventSourcing appends every account change to the account's
+	// event stream, see storage/account_events.go, which is replayed and
+	// snapshotted at startup and daily.
+	AccountEventSourcing bool
+	// WarehouseDir is where the hourly Parquet export for the data
+	// warehouse goes, see warehouse.go. Empty turns the export off.
+	WarehouseDir string
+	// ScreeningDenylistFile is the local denylist transfers are screened
+	// against, an account number or name per line. Empty screens nothing.
+	ScreeningDenylistFile string
+
+	// EmailProvider is "smtp" or "ses", empty sends no emails. They go out
+	// from EmailFrom, see email.go.
+	EmailProvider string
+	EmailFrom     string
+	// SMTPAddr is the host:port of the SMTP relay, which is logged into when
+	// SMTPUsername is set.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	// SESRegion is the AWS region of SES, the credentials are the usual
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	SESRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+	// EmailBounceToken is the token SES bounce notifications must carry in
+	// their URL, empty refuses them all.
+	EmailBounceToken string
+
+	// SMSProvider is "twilio" or "log", which only logs the messages for
+	// development. Empty sends no text messages, see sms.go.
+	SMSProvider string
+	// TwilioFrom is the sender's number, or the SID of a messaging service.
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFrom       string
+
+	// FCMCredentialsFile is the JSON key of a Firebase service account,
+	// which push notifications to Android devices need.
+	FCMCredentialsFile string
+	// APNsKeyFile is the .p8 key push notifications to iOS devices are
+	// signed with, APNsTopic the app's bundle id. APNsSandbox sends to
+	// development builds.
+	APNsKeyFile string
+	APNsKeyID   string
+	APNsTeamID  string
+	APNsTopic   string
+	APNsSandbox bool
+
+	// SFTPKeyFile is the private key statements are uploaded to SFTP
+	// servers with when their destination has no password. Any account can
+	// add a destination, so the key only logs in to the hosts, or
+	// host:ports, of SFTPKeyHosts: the partners that trust it.
+	SFTPKeyFile  string
+	SFTPKeyHosts []string
+	// SecretsKey is the 32-byte AES key the passwords of statement
+	// destinations are encrypted with at rest, from the base64 in
+	// SECRETS_KEY. Without it destinations can't have a password.
+	SecretsKey []byte
+
+	// BatchIntakeDir is the local directory or sftp:// URL payment batch
+	// files are picked up from, for the tenant BatchIntakeTenant, every
+	// BatchIntakeIntervalSeconds. Their acknowledgements go to its ack
+	// subdirectory. An SFTP server is logged in to with BatchIntakePassword
+	// or else SFTPKeyFile, if its host key has the SHA256 fingerprint
+	// BatchIntakeHostKey. Without a directory there is no intake.
+	BatchIntakeDir             string
+	BatchIntakeTenant          string
+	BatchIntakePassword        string
+	BatchIntakeHostKey         string
+	BatchIntakeIntervalSeconds int
+
+	Runtime RuntimeConfig
+}
+
+// RuntimeConfig is the part of Config that can change while the process is
+// running, see LiveConfig.Reload.
+type RuntimeConfig struct {
+	LogLevel string `json:"logLevel"`
+	// RateLimitPerMinute is the number of requests a client may make per
+	// minute, 0 disables rate limiting.
+	RateLimitPerMinute int `json:"rateLimitPerMinute"`
+	// LookupsPerMinute is how many account lookups a client IP may make per
+	// minute on top of that, 0 disables the extra limit.
+	LookupsPerMinute int `json:"lookupsPerMinute"`
+	// DailyQuota is the number of calls an account's plan includes per day,
+	// reported by /account/{id}/usage. 0 means unmetered.
+	DailyQuota int `json:"dailyQuota"`
+	// MaxTransferAmount and DailyTransferLimit are the transfer limits of
+	// tenants without their own settings.
+	MaxTransferAmount  int64    `json:"maxTransferAmount"`
+	DailyTransferLimit int64    `json:"dailyTransferLimit"`
+	CORSOrigins        []string `json:"corsOrigins"`
+	// SlowQueryThresholdMs logs statements slower than this, 0 disables it.
+	SlowQueryThresholdMs int `json:"slowQueryThresholdMs"`
+	// RecordRequests saves redacted request/response pairs for debugging,
+	// `gobank replay` sends them again.
+	RecordRequests bool `json:"recordRequests"`
+	// TermsVersion and PrivacyVersion are the current versions of the terms
+	// of service and the privacy policy. Accounts can't move money until they
+	// accepted them, empty doesn't require the document.
+	TermsVersion   string `json:"termsVersion"`
+	PrivacyVersion string `json:"privacyVersion"`
+	// LargeTransactionThreshold is, in minor units of the account's
+	// currency, what an account must move in and out within
+	// LargeTransactionWindowHours to have its transactions of the window
+	// reported to compliance, 0 turns the reports off. The report is csv or
+	// xml as LargeTransactionReportFormat says.
+	LargeTransactionThreshold    int64  `json:"largeTransactionThreshold"`
+	LargeTransactionWindowHours  int    `json:"largeTransactionWindowHours"`
+	LargeTransactionReportFormat string `json:"largeTransactionReportFormat"`
+}
+
+func LoadConfig() (*Config, error) {
+	// a missing .env is fine, the environment may be set by the process manager
+	_ = godotenv.Load(".env")
+	return configFromEnv()
+}
+
+func configFromEnv() (*Config, error) {
+	cfg := &Config{
+		ListenAddr:            getenv("LISTEN_ADDR", ":3000"),
+		Mode:                  getenv("MODE", ModeSandbox),
+		TenantDomain:          os.Getenv("TENANT_DOMAIN"),
+		EventTransport:        getenv("EVENT_TRANSPORT", "memory"),
+		NatsURL:               getenv("NATS_URL", "nats://127.0.0.1:4222"),
+		NatsSubject:           getenv("NATS_SUBJECT", "gobank.events"),
+		KafkaTopic:            getenv("KAFKA_TOPIC", "gobank.events"),
+		KafkaFormat:           getenv("KAFKA_FORMAT", "json"),
+		LogFormat:             getenv("LOG_FORMAT", "text"),
+		SentryDSN:             os.Getenv("SENTRY_DSN"),
+		Environment:           getenv("ENVIRONMENT", "development"),
+		TLSCertFile:           os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:            os.Getenv("TLS_KEY_FILE"),
+		ClientAuth:            getenv("MTLS_CLIENT_AUTH", auth.ClientAuthOff),
+		ClientCAFile:          os.Getenv("MTLS_CLIENT_CA_FILE"),
+		ClientCRLFile:         os.Getenv("MTLS_CRL_FILE"),
+		ClientCertPins:        splitList(os.Getenv("MTLS_PINNED_FINGERPRINTS")),
+		BackupDir:             getenv("BACKUP_DIR", "backups"),
+		RecordingDir:          getenv("RECORDING_DIR", "recordings"),
+		StatementDir:          getenv("STATEMENT_DIR", "statements"),
+		ReportDir:             getenv("REPORT_DIR", "reports"),
+		WarehouseDir:          os.Getenv("WAREHOUSE_DIR"),
+		AvatarDir:             getenv("AVATAR_DIR", "avatars"),
+		ScreeningDenylistFile: os.Getenv("SCREENING_DENYLIST_FILE"),
+		EmailProvider:         os.Getenv("EMAIL_PROVIDER"),
+		EmailFrom:             os.Getenv("EMAIL_FROM"),
+		SMTPAddr:              getenv("SMTP_ADDR", "localhost:25"),
+		SMTPUsername:          os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+		SESRegion:             getenv("SES_REGION", "us-east-1"),
+		AWSAccessKeyID:        os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken:       os.Getenv("AWS_SESSION_TOKEN"),
+		EmailBounceToken:      os.Getenv("EMAIL_BOUNCE_TOKEN"),
+		SMSProvider:           os.Getenv("SMS_PROVIDER"),
+		TwilioAccountSID:      os.Getenv("TWILIO_ACCOUNT_SID"),
+		TwilioAuthToken:       os.Getenv("TWILIO_AUTH_TOKEN"),
+		TwilioFrom:            os.Getenv("TWILIO_FROM"),
+		FCMCredentialsFile:    os.Getenv("FCM_CREDENTIALS_FILE"),
+		APNsKeyFile:           os.Getenv("APNS_KEY_FILE"),
+		APNsKeyID:             os.Getenv("APNS_KEY_ID"),
+		APNsTeamID:            os.Getenv("APNS_TEAM_ID"),
+		APNsTopic:             os.Getenv("APNS_TOPIC"),
+		SFTPKeyFile:           os.Getenv("SFTP_KEY_FILE"),
+		BatchIntakeDir:        os.Getenv("BATCH_INTAKE_DIR"),
+		BatchIntakeTenant:     getenv("BATCH_INTAKE_TENANT", domain.DefaultTenantSlug),
+		BatchIntakePassword:   os.Getenv("BATCH_INTAKE_PASSWORD"),
+		BatchIntakeHostKey:    os.Getenv("BATCH_INTAKE_HOST_KEY"),
+		Runtime: RuntimeConfig{
+			LogLevel:                     getenv("LOG_LEVEL", "info"),
+			CORSOrigins:                  splitList(os.Getenv("CORS_ORIGINS")),
+			TermsVersion:                 os.Getenv("TERMS_VERSION"),
+			PrivacyVersion:               os.Getenv("PRIVACY_VERSION"),
+			LargeTransactionReportFormat: getenv("LARGE_TRANSACTION_REPORT_FORMAT", "csv"),
+		},
+	}
+	cfg.KafkaBrokers = splitList(os.Getenv("KAFKA_BROKERS"))
+	cfg.SFTPKeyHosts = splitList(os.Getenv("SFTP_KEY_HOSTS"))
+	cfg.ServiceAccounts = parseServiceAccounts(os.Getenv("MTLS_SERVICE_ACCOUNTS"))
+
+	var err error
+	if cfg.ServeFrontend, err = getenvBool("SERVE_FRONTEND", false); err != nil {
+		return nil, err
+	}
+	if cfg.ReadHeaderTimeoutSeconds, err = getenvInt("READ_HEADER_TIMEOUT_SECONDS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.IdleTimeoutSeconds, err = getenvInt("IDLE_TIMEOUT_SECONDS", 120); err != nil {
+		return nil, err
+	}
+	if cfg.KeepAlive, err = getenvBool("KEEP_ALIVE", true); err != nil {
+		return nil, err
+	}
+	if cfg.HTTP2MaxStreams, err = getenvInt("HTTP2_MAX_STREAMS", 250); err != nil {
+		return nil, err
+	}
+	if cfg.H2C, err = getenvBool("H2C", false); err != nil {
+		return nil, err
+	}
+	if cfg.AccountEventSourcing, err = getenvBool("ACCOUNT_EVENT_SOURCING", false); err != nil {
+		return nil, err
+	}
+	if v := os.Getenv("SECRETS_KEY"); v != "" {
+		if cfg.SecretsKey, err = base64.StdEncoding.DecodeString(v); err != nil || len(cfg.SecretsKey) != 32 {
+			return nil, fmt.Errorf("SECRETS_KEY must be the base64 of 32 bytes")
+		}
+	}
+	if cfg.BackupIntervalHours, err = getenvInt("BACKUP_INTERVAL_HOURS", 24); err != nil {
+		return nil, err
+	}
+	if cfg.BackupKeep, err = getenvInt("BACKUP_KEEP", 7); err != nil {
+		return nil, err
+	}
+	if cfg.APNsSandbox, err = getenvBool("APNS_SANDBOX", false); err != nil {
+		return nil, err
+	}
+	if cfg.BatchIntakeIntervalSeconds, err = getenvInt("BATCH_INTAKE_INTERVAL_SECONDS", 60); err != nil {
+		return nil, err
+	}
+	if cfg.Runtime.RateLimitPerMinute, err = getenvInt("RATE_LIMIT_PER_MINUTE", 600); err != nil {
+		return nil, err
+	}
+	if cfg.Runtime.LookupsPerMinute, err = getenvInt("LOOKUPS_PER_MINUTE", 10); err != nil {
+		return nil, err
+	}
+	if cfg.Runtime.DailyQuota, err = getenvInt("API_DAILY_QUOTA", 0); err != nil {
+		return nil, err
+	}
+	limit, err := getenvInt("MAX_TRANSFER_AMOUNT", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Runtime.MaxTransferAmount = int64(limit)
+	if limit, err = getenvInt("DAILY_TRANSFER_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	cfg.Runtime.DailyTransferLimit = int64(limit)
+	if cfg.Runtime.SlowQueryThresholdMs, err = getenvInt("SLOW_QUERY_THRESHOLD_MS", 200); err != nil {
+		return nil, err
+	}
+	if cfg.Runtime.RecordRequests, err = getenvBool("RECORD_REQUESTS", false); err != nil {
+		return nil, err
+	}
+	if limit, err = getenvInt("LARGE_TRANSACTION_THRESHOLD", 1000000); err != nil {
+		return nil, err
+	}
+	cfg.Runtime.LargeTransactionThreshold = int64(limit)
+	if cfg.Runtime.LargeTransactionWindowHours, err = getenvInt("LARGE_TRANSACTION_WINDOW_HOURS", 24); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) Validate() error {
+	if c.Mode != ModeSandbox && c.Mode != ModeProduction {
+		return fmt.Errorf("unknown MODE %s", c.Mode)
+	}
+	if c.EventTransport != "memory" && c.EventTransport != "nats" {
+		return fmt.Errorf("unknown EVENT_TRANSPORT %s", c.EventTransport)
+	}
+	if c.KafkaFormat != "json" && c.KafkaFormat != "avro" {
+		return fmt.Errorf("unknown KAFKA_FORMAT %s", c.KafkaFormat)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("unknown LOG_FORMAT %s", c.LogFormat)
+	}
+	if _, err := ParseLogLevel(c.Runtime.LogLevel); err != nil {
+		return fmt.Errorf("unknown LOG_LEVEL %s", c.Runtime.LogLevel)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	switch c.ClientAuth {
+	case auth.ClientAuthOff:
+	case auth.ClientAuthOptional, auth.ClientAuthRequire:
+		if c.TLSCertFile == "" || c.ClientCAFile == "" {
+			return fmt.Errorf("MTLS_CLIENT_AUTH %s needs TLS_CERT_FILE, TLS_KEY_FILE and MTLS_CLIENT_CA_FILE", c.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("unknown MTLS_CLIENT_AUTH %s", c.ClientAuth)
+	}
+	if c.Mode == ModeProduction {
+		if c.TLSCertFile == "" {
+			return fmt.Errorf("production mode requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		if originAllowed("*", c.Runtime.CORSOrigins) {
+			return fmt.Errorf("production mode doesn't allow the CORS origin *")
+		}
+	}
+	if c.H2C && c.TLSCertFile != "" {
+		return fmt.Errorf("H2C is for plain HTTP, HTTPS negotiates h2 by itself")
+	}
+	if c.ReadHeaderTimeoutSeconds < 0 || c.IdleTimeoutSeconds < 0 || c.HTTP2MaxStreams < 0 {
+		return fmt.Errorf("READ_HEADER_TIMEOUT_SECONDS, IDLE_TIMEOUT_SECONDS and HTTP2_MAX_STREAMS can't be negative")
+	}
+	if c.BackupIntervalHours < 0 || c.BackupKeep < 0 {
+		return fmt.Errorf("BACKUP_INTERVAL_HOURS and BACKUP_KEEP can't be negative")
+	}
+	if c.BatchIntakeDir != "" {
+		if c.BatchIntakeIntervalSeconds < 1 {
+			return fmt.Errorf("BATCH_INTAKE_INTERVAL_SECONDS must be at least 1")
+		}
+		if strings.HasPrefix(c.BatchIntakeDir, "sftp:") {
+			if _, err := parseSFTPURL(c.BatchIntakeDir); err != nil {
+				return fmt.Errorf("BATCH_INTAKE_DIR: %w", err)
+			}
+			if !strings.HasPrefix(c.BatchIntakeHostKey, "SHA256:") {
+				return fmt.Errorf("an sftp BATCH_INTAKE_DIR needs the SHA256 fingerprint of the server's key in BATCH_INTAKE_HOST_KEY")
+			}
+			if c.BatchIntakePassword == "" && c.SFTPKeyFile == "" {
+				return fmt.Errorf("an sftp BATCH_INTAKE_DIR needs BATCH_INTAKE_PASSWORD or SFTP_KEY_FILE")
+			}
+		}
+	}
+	if c.Runtime.RateLimitPerMinute < 0 || c.Runtime.LookupsPerMinute < 0 {
+		return fmt.Errorf("RATE_LIMIT_PER_MINUTE and LOOKUPS_PER_MINUTE can't be negative")
+	}
+	if c.Runtime.DailyQuota < 0 {
+		return fmt.Errorf("API_DAILY_QUOTA can't be negative")
+	}
+	if c.Runtime.SlowQueryThresholdMs < 0 {
+		return fmt.Errorf("SLOW_QUERY_THRESHOLD_MS can't be negative")
+	}
+	if c.Runtime.MaxTransferAmount < 0 || c.Runtime.DailyTransferLimit < 0 {
+		return fmt.Errorf("transfer limits can't be negative")
+	}
+	if c.Runtime.LargeTransactionThreshold < 0 || c.Runtime.LargeTransactionWindowHours < 1 {
+		return fmt.Errorf("LARGE_TRANSACTION_THRESHOLD can't be negative and LARGE_TRANSACTION_WINDOW_HOURS must be at least 1")
+	}
+	switch c.EmailProvider {
+	case "":
+	case "smtp", "ses":
+		if c.EmailFrom == "" {
+			return fmt.Errorf("EMAIL_PROVIDER %s needs EMAIL_FROM", c.EmailProvider)
+		}
+		if c.EmailProvider == "ses" && (c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+			return fmt.Errorf("EMAIL_PROVIDER ses needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+	default:
+		return fmt.Errorf("unknown EMAIL_PROVIDER %s", c.EmailProvider)
+	}
+	switch c.SMSProvider {
+	case "", "log":
+	case "twilio":
+		if c.TwilioAccountSID == "" || c.TwilioAuthToken == "" || c.TwilioFrom == "" {
+			return fmt.Errorf("SMS_PROVIDER twilio needs TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM")
+		}
+	default:
+		return fmt.Errorf("unknown SMS_PROVIDER %s", c.SMSProvider)
+	}
+	if c.APNsKeyFile != "" && (c.APNsKeyID == "" || c.APNsTeamID == "" || c.APNsTopic == "") {
+		return fmt.Errorf("APNS_KEY_FILE needs APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC")
+	}
+	if c.Runtime.LargeTransactionReportFormat != "csv" && c.Runtime.LargeTransactionReportFormat != "xml" {
+		return fmt.Errorf("unknown LARGE_TRANSACTION_REPORT_FORMAT %s", c.Runtime.LargeTransactionReportFormat)
+	}
+	return nil
+}
+
+func (c *Config) Sandbox() bool {
+	return c.Mode == ModeSandbox
+}
+
+// LiveConfig holds the current Config snapshot. Readers always see a complete
+// snapshot, a reload swaps it atomically.
+type LiveConfig struct {
+	current  atomic.Pointer[Config]
+	mu       sync.Mutex
+	onReload []func(cfg *Config)
+}
+
+func NewLiveConfig(cfg *Config) *LiveConfig {
+	l := &LiveConfig{}
+	l.current.Store(cfg)
+	return l
+}
+
+func (l *LiveConfig) Get() *Config {
+	return l.current.Load()
+}
+
+// OnReload registers fn to be called with every successfully reloaded config.
+func (l *LiveConfig) OnReload(fn func(cfg *Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onReload = append(l.onReload, fn)
+}
+
+// Reload re-reads the environment and .env and applies the RuntimeConfig
+// part of it. Structural settings such as the listen address keep their
+// startup values. An invalid config leaves the current one in place.
+func (l *LiveConfig) Reload() (*Config, error) {
+	// values from .env take precedence on reload, otherwise edits to it would
+	// never be picked up once the variables are set
+	_ = godotenv.Overload(".env")
+	fresh, err := configFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next := *l.Get()
+	next.Runtime = fresh.Runtime
+	l.current.Store(&next)
+	for _, fn := range l.onReload {
+		fn(&next)
+	}
+	return &next, nil
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getenvBool(key string, def bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %s", key, v)
+	}
+	return b, nil
+}
+
+func getenvInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s", key, v)
+	}
+	return n, nil
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	items := strings.Split(v, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
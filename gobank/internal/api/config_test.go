@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLiveConfigReload(t *testing.T) {
+	t.Setenv("LISTEN_ADDR", ":3000")
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "10")
+	cfg, err := configFromEnv()
+	assert.Nil(t, err)
+	live := NewLiveConfig(cfg)
+
+	t.Setenv("LISTEN_ADDR", ":4000")
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "20")
+	reloaded, err := live.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, 20, reloaded.Runtime.RateLimitPerMinute)
+	assert.Equal(t, ":3000", live.Get().ListenAddr)
+
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "lots")
+	_, err = live.Reload()
+	assert.NotNil(t, err)
+	assert.Equal(t, 20, live.Get().Runtime.RateLimitPerMinute)
+}
+
+func TestProductionModeRequiresTLS(t *testing.T) {
+	t.Setenv("MODE", ModeProduction)
+	_, err := configFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "key.pem")
+	cfg, err := configFromEnv()
+	assert.Nil(t, err)
+	assert.False(t, cfg.Sandbox())
+
+	t.Setenv("CORS_ORIGINS", "*")
+	_, err = configFromEnv()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// consentScopes is the scope a third-party key needs a consent for, by route.
+// Third-party keys can't reach routes missing here at all.
+var consentScopes = map[string]string{
+	"GET /account/{id}":                                                 domain.ScopeAccounts,
+	"GET /account/{id}/summary":                                         domain.ScopeBalances,
+	"GET /account/{id}/totals":                                          domain.ScopeBalances,
+	"GET /account/{id}/transactions":                                    domain.ScopeTransactions,
+	"GET /account/{id}/transactions/feed":                               domain.ScopeTransactions,
+	"GET /account/{id}/transactions/export":                             domain.ScopeTransactions,
+	"GET /account/{id}/fdx":                                             domain.ScopeTransactions,
+	"GET /account/{id}/statements":                                      domain.ScopeTransactions,
+	"GET /account/{id}/statements/{date}":                               domain.ScopeTransactions,
+	"POST /transfer":                                                    domain.ScopePayments,
+	"GET /transfer/{id}":                                                domain.ScopePayments,
+	"POST /transfer/quote":                                              domain.ScopePayments,
+	"POST /transfers/batch":                                             domain.ScopePayments,
+	"POST /transfer/authorize":                                          domain.ScopePayments,
+	"GET /transfer/holds/{holdId}":                                      domain.ScopePayments,
+	"POST /transfer/holds/{holdId}/capture":                             domain.ScopePayments,
+	"POST /transfer/holds/{holdId}/void":                                domain.ScopePayments,
+	"GET /open-banking/v1/accounts":                                     domain.ScopeAccounts,
+	"GET /open-banking/v1/accounts/{accountId}":                         domain.ScopeAccounts,
+	"GET /open-banking/v1/accounts/{accountId}/balances":                domain.ScopeBalances,
+	"GET /open-banking/v1/accounts/{accountId}/transactions":            domain.ScopeTransactions,
+	"POST /open-banking/v1/payments/credit-transfers":                   domain.ScopePayments,
+	"GET /open-banking/v1/payments/credit-transfers/{paymentId}/status": domain.ScopePayments,
+}
+
+// checkConsent lets a request made with a third-party API key through only
+// while the account holder consents to the scope of its route. thirdParty
+// tells whether the request was made with such a key.
+func (s *APIServer) checkConsent(r *http.Request, account *domain.Account) (thirdParty bool, err error) {
+	keyID := r.Header.Get("X-Api-Key")
+	if keyID == "" {
+		return false, nil
+	}
+	store := s.storeFor(r)
+	key, err := store.GetApiKey(keyID)
+	if err != nil {
+		return false, err
+	}
+	if !key.ThirdParty {
+		return false, nil
+	}
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	scope, ok := consentScopes[method+" "+routePath(r)]
+	if !ok {
+		return true, &domain.ConsentError{Scope: routePath(r)}
+	}
+	consents, err := store.ActiveConsents(key.ID)
+	if err != nil {
+		return true, err
+	}
+	now := s.clock.Now()
+	for _, c := range consents {
+		if c.AccountID == account.ID && c.Covers(scope, now) {
+			return true, nil
+		}
+	}
+	return true, &domain.ConsentError{Scope: scope}
+}
+
+type thirdPartyKey struct{}
+
+// isThirdParty tells whether withAccountAuth let the request in on a
+// third-party key the holder consented to.
+func isThirdParty(r *http.Request) bool {
+	thirdParty, _ := r.Context().Value(thirdPartyKey{}).(bool)
+	return thirdParty
+}
+
+// accountView is the account as the caller may see it. The address and date
+// of birth are for the holder and operators only, a third party with the
+// holder's consent gets the account without them.
+func accountView(r *http.Request, account *domain.Account) *domain.Account {
+	if !isThirdParty(r) {
+		return account
+	}
+	view := *account
+	view.Address = nil
+	view.DateOfBirth = ""
+	return &view
+}
+
+// authFailed answers a request that failed authentication, telling a third
+// party which consent it lacks.
+func authFailed(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrConsentRequired) {
+		writeError(w, r, http.StatusForbidden, err)
+		return
+	}
+	loggerFrom(r.Context()).Warn("authentication failed", "error", err)
+	permissionDenied(w, r)
+}
+
+type GrantConsentRequest struct {
+	ApiKeyID string   `json:"apiKeyId"`
+	Purpose  string   `json:"purpose"`
+	Scopes   []string `json:"scopes"`
+	// Days defaults to the longest a consent may run.
+	Days int `json:"days"`
+}
+
+func (s *APIServer) handleConsents(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	consents, err := s.storeFor(r).ListConsents(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, consents)
+}
+
+func (s *APIServer) handleGrantConsent(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req := new(GrantConsentRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if req.Purpose == "" {
+		return invalidParameter("purpose", req.Purpose)
+	}
+	if len(req.Scopes) == 0 {
+		return invalidParameter("scopes", "")
+	}
+	for _, scope := range req.Scopes {
+		if !domain.IsConsentScope(scope) {
+			return invalidParameter("scopes", scope)
+		}
+	}
+	validity := domain.MaxConsentValidity
+	if req.Days != 0 {
+		validity = time.Duration(req.Days) * 24 * time.Hour
+	}
+	if validity <= 0 || validity > domain.MaxConsentValidity {
+		return invalidParameter("days", strconv.Itoa(req.Days))
+	}
+	now := s.clock.Now().UTC()
+	consent := &domain.Consent{
+		ID:        domain.NewUUID(),
+		AccountID: id,
+		ApiKeyID:  req.ApiKeyID,
+		Purpose:   req.Purpose,
+		Scopes:    req.Scopes,
+		Status:    domain.ConsentActive,
+		CreatedAt: now,
+		ExpiresAt: now.Add(validity),
+	}
+	if err := s.storeFor(r).CreateConsent(consent); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, consent)
+}
+
+func (s *APIServer) handleRevokeConsent(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	consent, err := s.storeFor(r).RevokeConsent(id, r.PathValue("consentId"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, consent)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeConsentStore struct {
+	storage.Storage
+	key      *domain.ApiKey
+	consents []*domain.Consent
+}
+
+func (f *fakeConsentStore) GetApiKey(id string) (*domain.ApiKey, error) {
+	return f.key, nil
+}
+
+func (f *fakeConsentStore) ActiveConsents(apiKeyID string) ([]*domain.Consent, error) {
+	return f.consents, nil
+}
+
+func TestCheckConsent(t *testing.T) {
+	clock := domain.NewSimClock()
+	store := &fakeConsentStore{key: &domain.ApiKey{ID: "gbk_1", AccountID: 7, ThirdParty: true}}
+	s := &APIServer{store: store, clock: clock}
+	account := &domain.Account{ID: 7}
+	check := func(method, pattern string) error {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("X-Api-Key", "gbk_1")
+		r.Pattern = method + " " + pattern
+		_, err := s.checkConsent(r, account)
+		return err
+	}
+
+	var consentErr *domain.ConsentError
+	assert.True(t, errors.As(check("GET", "/account/{id}/summary"), &consentErr))
+	assert.Equal(t, domain.ScopeBalances, consentErr.Scope)
+
+	store.consents = []*domain.Consent{{AccountID: 7, Scopes: []string{domain.ScopeBalances}, Status: domain.ConsentActive, ExpiresAt: clock.Now().Add(time.Hour)}}
+	assert.NoError(t, check("GET", "/account/{id}/summary"))
+	assert.NoError(t, check("HEAD", "/account/{id}/summary"))
+	assert.ErrorIs(t, check("GET", "/account/{id}/transactions"), domain.ErrConsentRequired)
+	// routes without a scope, like managing keys, are closed to third parties
+	assert.ErrorIs(t, check("POST", "/account/{id}/api-keys"), domain.ErrConsentRequired)
+
+	clock.Advance(2 * time.Hour)
+	assert.ErrorIs(t, check("GET", "/account/{id}/summary"), domain.ErrConsentRequired)
+
+	store.key.ThirdParty = false
+	assert.NoError(t, check("POST", "/account/{id}/api-keys"))
+}
+
+func TestAccountViewHidesTheProfileFromThirdParties(t *testing.T) {
+	account := &domain.Account{ID: 7, FirstName: "Ada", Address: &domain.Address{Line1: "1 Main St", City: "Springfield", Country: "US"}, DateOfBirth: "1990-01-01"}
+	r := httptest.NewRequest("GET", "/account/7", nil)
+	assert.Same(t, account, accountView(r, account))
+
+	r = r.WithContext(context.WithValue(r.Context(), thirdPartyKey{}, true))
+	data, err := json.Marshal(accountView(r, account))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"firstName":"Ada"`)
+	assert.NotContains(t, string(data), "address")
+	assert.NotContains(t, string(data), "dateOfBirth")
+	// the holder's account itself is left alone
+	assert.NotNil(t, account.Address)
+	assert.Equal(t, domain.PII("1990-01-01"), account.DateOfBirth)
+
+	store := &fakeConsentStore{key: &domain.ApiKey{ID: "gbk_1", AccountID: 7}}
+	s := &APIServer{store: store, clock: domain.NewSimClock()}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Api-Key", "gbk_1")
+	r.Pattern = "GET /account/{id}"
+	thirdParty, err := s.checkConsent(r, account)
+	assert.Nil(t, err)
+	assert.False(t, thirdParty)
+	store.key.ThirdParty = true
+	store.consents = []*domain.Consent{{AccountID: 7, Scopes: []string{domain.ScopeAccounts}, Status: domain.ConsentActive, ExpiresAt: s.clock.Now().Add(time.Hour)}}
+	thirdParty, err = s.checkConsent(r, account)
+	assert.Nil(t, err)
+	assert.True(t, thirdParty)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+)
+
+func originAllowed(origin string, allowed []string) bool {
+	for _, o := range allowed {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS sets the CORS headers for the origins listed in CORS_ORIGINS.
+// Preflights go on to the router, which knows the methods of the path.
+func (s *APIServer) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !originAllowed(origin, s.config.Get().Runtime.CORSOrigins) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-Chaos-Injected, X-Api-Version, Location, Preference-Applied, Retry-After")
+		if isPreflight(r) {
+			// the router's OPTIONS handler answers with the path's methods
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept-Language, x-jwt-token, X-Tenant, If-Match, If-Unmodified-Since, X-Api-Key, X-Timestamp, X-Nonce, X-Signature, X-Api-Version, Prefer")
+			w.Header().Set("Access-Control-Max-Age", "600")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPreflight reports whether r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// isOperatorPath reports whether the path belongs to the operator surface
+// (admin API and debug endpoints), which is not tenant scoped and stays up
+// during maintenance.
+func isOperatorPath(path string) bool {
+	return strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/debug/") || path == "/metrics"
+}
+
+// registerDebugRoutes mounts net/http/pprof and /debug/vars on the admin
+// guarded debug group.
+func (s *APIServer) registerDebugRoutes(debug *Group) {
+	debug.Handle("GET", "/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	debug.Handle("GET", "/pprof/profile", http.HandlerFunc(pprof.Profile))
+	debug.Handle("GET", "/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	debug.Handle("POST", "/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	debug.Handle("GET", "/pprof/trace", http.HandlerFunc(pprof.Trace))
+	debug.HandlePrefix("GET", "/pprof/", http.HandlerFunc(pprof.Index))
+	debug.HandleFunc("GET", "/vars", s.handleDebugVars)
+}
+
+type DebugVars struct {
+	Goroutines   int       `json:"goroutines"`
+	NumCPU       int       `json:"numCpu"`
+	GoVersion    string    `json:"goVersion"`
+	HeapAlloc    uint64    `json:"heapAlloc"`
+	HeapInuse    uint64    `json:"heapInuse"`
+	Sys          uint64    `json:"sys"`
+	NumGC        uint32    `json:"numGc"`
+	PauseTotalNs uint64    `json:"pauseTotalNs"`
+	LastGC       time.Time `json:"lastGc"`
+	Module       string    `json:"module,omitempty"`
+	Settings     []string  `json:"buildSettings,omitempty"`
+}
+
+func (s *APIServer) handleDebugVars(w http.ResponseWriter, r *http.Request) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	vars := DebugVars{
+		Goroutines:   runtime.NumGoroutine(),
+		NumCPU:       runtime.NumCPU(),
+		GoVersion:    runtime.Version(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+		LastGC:       time.Unix(0, int64(mem.LastGC)).UTC(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		vars.Module = info.Main.Path + "@" + info.Main.Version
+		for _, setting := range info.Settings {
+			vars.Settings = append(vars.Settings, setting.Key+"="+setting.Value)
+		}
+	}
+	return WriteJSON(w, http.StatusOK, vars)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDebugRoutesNeedAdmin(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "secret")
+	s := NewAPIServer(NewLiveConfig(&Config{}), &fakeAccountStore{}, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, NewMetrics(), nil, nil, nil, nil, nil, nil)
+	routes := s.routes()
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			r.Header.Set("x-admin-token", token)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, path := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/heap"} {
+		assert.Equal(t, http.StatusForbidden, serve(path, "").Code, path)
+		assert.Equal(t, http.StatusOK, serve(path, "secret").Code, path)
+	}
+
+	var vars DebugVars
+	assert.Nil(t, json.Unmarshal(serve("/debug/vars", "secret").Body.Bytes(), &vars))
+	assert.Greater(t, vars.Goroutines, 0)
+	assert.NotEmpty(t, vars.GoVersion)
+
+	assert.True(t, isOperatorPath("/debug/pprof/"))
+	assert.False(t, isOperatorPath("/debugger"))
+}
@@ -0,0 +1,525 @@
+package api
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// emailTemplate lays the text of an email out as HTML, a paragraph per
+// block of lines.
+var emailTemplate = template.Must(template.ParseFS(templateFiles, "templates/email/email.html"))
+
+type emailPage struct {
+	Lang       string
+	Subject    string
+	Brand      string
+	Paragraphs [][]string
+	Footer     []string
+}
+
+// renderEmail finishes the subject and body rendered for account into the
+// text and HTML of an email, both ending in a footer that names the tenant
+// and its support address.
+func renderEmail(account *domain.Account, settings *domain.TenantSettings, subject, body string) (text, html string, err error) {
+	lang := domain.AccountLocale(account).Language
+	footer := []string{lookupMessage(textCatalog, lang, "email.footer", map[string]any{"brand": settings.BrandName})}
+	if settings.SupportEmail != "" {
+		footer = append(footer, lookupMessage(textCatalog, lang, "email.support", map[string]any{"email": settings.SupportEmail}))
+	}
+	page := emailPage{Lang: lang, Subject: subject, Brand: settings.BrandName, Footer: footer}
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		page.Paragraphs = append(page.Paragraphs, strings.Split(block, "\n"))
+	}
+	var b strings.Builder
+	if err := emailTemplate.Execute(&b, page); err != nil {
+		return "", "", err
+	}
+	return strings.TrimRight(body, "\n") + "\n\n-- \n" + strings.Join(footer, "\n") + "\n", b.String(), nil
+}
+
+// emailAttachment is a file sent along with an email.
+type emailAttachment struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// buildEmail writes the email as a multipart/alternative MIME message of
+// its text and HTML, inside a multipart/mixed one with the attachments if
+// there are any.
+func buildEmail(from mail.Address, to, subject, text, html, messageID string, date time.Time, attachments ...emailAttachment) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", (&mail.Address{Address: to}).String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s>\r\n", messageID)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ mediaType, content string }{{"text/plain", text}, {"text/html", html}} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.mediaType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qp, part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	alternative := mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()})
+	if len(attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", alternative)
+		buf.Write(body.Bytes())
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()}))
+	w, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {alternative}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Content)
+		for len(encoded) > 0 {
+			n := min(len(encoded), 76)
+			if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+				return nil, err
+			}
+			encoded = encoded[n:]
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// welcomeNotice is the data of the welcome templates, statementNotice that
+// of the statement_ready ones.
+type welcomeNotice struct {
+	Account *domain.Account
+	Brand   string
+}
+
+type statementNotice struct {
+	Account *domain.Account
+	Day     time.Time
+}
+
+// EmailNotifier renders the emails accounts get and queues them. Every
+// email has a dedupe key, so the events relayed more than once and the
+// statements of a job that ran again send theirs once.
+type EmailNotifier struct {
+	store    storage.Storage
+	settings *TenantSettingsCache
+	logger   *slog.Logger
+}
+
+func NewEmailNotifier(store storage.Storage, logger *slog.Logger) *EmailNotifier {
+	return &EmailNotifier{store: store, settings: NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings), logger: logger}
+}
+
+// Notify is subscribed to the bus, it welcomes new accounts, sends both
+// sides of a transfer their receipt and tells holders about statements.
+func (n *EmailNotifier) Notify(ev *domain.Event) {
+	var err error
+	switch ev.Type {
+	case domain.EventAccountCreated:
+		err = n.welcome(ev.AccountID)
+	case domain.EventTransferCompleted:
+		err = n.transferReceipts(ev)
+	case domain.EventStatementReady:
+		err = n.statementReady(ev)
+	}
+	if err != nil {
+		n.logger.Error("queueing email failed", "event_id", ev.ID, "event_type", ev.Type, "error", err)
+	}
+}
+
+func (n *EmailNotifier) welcome(accountID int) error {
+	account, err := n.store.FindAccount(accountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings, err := n.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	data := welcomeNotice{Account: account, Brand: settings.BrandName}
+	subject, err := renderText(account, "welcome.subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "welcome.body", data)
+	if err != nil {
+		return err
+	}
+	return n.queue(account, settings, domain.EmailWelcome, fmt.Sprintf("welcome:%d", account.ID), subject, body)
+}
+
+func (n *EmailNotifier) transferReceipts(ev *domain.Event) error {
+	var payload struct {
+		TransactionID int `json:"transactionId"`
+	}
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		return err
+	}
+	out, in, err := n.store.TransferLegs(payload.TransactionID)
+	if errors.Is(err, domain.ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, tx := range []*domain.Transaction{out, in} {
+		if tx == nil {
+			continue
+		}
+		account, err := n.store.FindAccount(tx.AccountID)
+		if errors.Is(err, domain.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		subject, body, _, err := renderTransferNotice(account, tx)
+		if err != nil {
+			return err
+		}
+		settings, err := n.settings.Get(account.TenantID)
+		if err != nil {
+			return err
+		}
+		if err := n.queue(account, settings, domain.EmailTransferReceipt, fmt.Sprintf("transfer_receipt:%d", tx.ID), subject, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementReady tells the holder that the statement of the event's day is
+// there to download.
+func (n *EmailNotifier) statementReady(ev *domain.Event) error {
+	account, day, err := statementOf(n.store, ev)
+	if account == nil || err != nil {
+		return err
+	}
+	settings, err := n.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	data := statementNotice{Account: account, Day: day}
+	subject, err := renderText(account, "statement_ready.subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "statement_ready.body", data)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("statement_ready:%d:%s", account.ID, day.Format("2006-01-02"))
+	return n.queue(account, settings, domain.EmailStatementReady, key, subject, body)
+}
+
+// statementOf returns the account of an EventStatementReady and the start
+// of its day in the account's time zone, no account if it was deleted.
+func statementOf(store storage.Storage, ev *domain.Event) (*domain.Account, time.Time, error) {
+	var payload struct {
+		Date string `json:"date"`
+	}
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		return nil, time.Time{}, err
+	}
+	account, err := store.FindAccount(ev.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	loc, err := loadLocation(account.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	day, err := time.ParseInLocation("2006-01-02", payload.Date, loc)
+	return account, day, err
+}
+
+// queue saves the email for the send job, accounts without an address get
+// none.
+func (n *EmailNotifier) queue(account *domain.Account, settings *domain.TenantSettings, kind, key, subject, body string) error {
+	if account.Email == "" {
+		return nil
+	}
+	text, html, err := renderEmail(account, settings, subject, body)
+	if err != nil {
+		return err
+	}
+	_, err = n.store.QueueEmail(domain.NewEmail(account, kind, key, subject, text, html))
+	return err
+}
+
+// EmailDeliverer sends the queued emails from the configured address, under
+// the tenant's brand name.
+type EmailDeliverer struct {
+	store    storage.Storage
+	sender   EmailSender
+	from     string
+	settings *TenantSettingsCache
+	metrics  *Metrics
+	logger   *slog.Logger
+}
+
+func NewEmailDeliverer(store storage.Storage, sender EmailSender, from string, metrics *Metrics, logger *slog.Logger) *EmailDeliverer {
+	metrics.Help("emails_total", "Email send attempts by kind and result.")
+	return &EmailDeliverer{
+		store:    store,
+		sender:   sender,
+		from:     from,
+		settings: NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings),
+		metrics:  metrics,
+		logger:   logger,
+	}
+}
+
+// HandleJob makes one attempt to send an email. One the provider may accept
+// later fails the job, so the pool retries it with backoff. An email the
+// provider refused isn't retried, and if it refused the recipient the
+// address is suppressed.
+func (d *EmailDeliverer) HandleJob(job *domain.Job) error {
+	var payload storage.EmailJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	email, err := d.store.GetEmail(payload.EmailID)
+	if errors.Is(err, domain.ErrEmailNotFound) {
+		// the account was deleted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if email.Status != domain.EmailPending {
+		return nil
+	}
+	store := d.store.ForTenant(email.TenantID)
+	suppressed, err := store.EmailSuppressed(email.To)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		email.Status = domain.EmailSuppressed
+		d.metrics.Inc("emails_total", "kind", email.Kind, "result", email.Status)
+		return store.RecordEmailAttempt(email)
+	}
+	settings, err := d.settings.Get(email.TenantID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	msg := &OutgoingEmail{From: d.from, To: email.To.Reveal(), MessageID: email.ID + "@" + d.from[strings.LastIndexByte(d.from, '@')+1:]}
+	msg.Raw, err = buildEmail(mail.Address{Name: settings.BrandName, Address: d.from}, msg.To, email.Subject, email.Text, email.HTML, msg.MessageID, now)
+	if err != nil {
+		return err
+	}
+
+	email.Attempts++
+	providerID, sendErr := d.sender.Send(msg)
+	var refused *EmailRefusedError
+	switch {
+	case sendErr == nil:
+		email.Status, email.ProviderID, email.SentAt, email.LastError = domain.EmailSent, providerID, &now, ""
+	case errors.As(sendErr, &refused):
+		email.Status, email.LastError = domain.EmailFailed, sendErr.Error()
+		if refused.Bounce {
+			if err := store.SuppressEmail(&domain.EmailSuppression{Address: email.To, Reason: domain.SuppressionBounce, CreatedAt: now}); err != nil {
+				return err
+			}
+		}
+	default:
+		email.LastError = sendErr.Error()
+	}
+	if err := store.RecordEmailAttempt(email); err != nil {
+		return err
+	}
+	result := email.Status
+	if result == domain.EmailPending {
+		result = "retry"
+	}
+	d.metrics.Inc("emails_total", "kind", email.Kind, "result", result)
+	if email.Status == domain.EmailPending {
+		return fmt.Errorf("email %s: %w", email.ID, sendErr)
+	}
+	if email.Status == domain.EmailFailed {
+		d.logger.Warn("email refused", "email_id", email.ID, "tenant_id", email.TenantID, "to", email.To, "error", sendErr)
+	}
+	return nil
+}
+
+type SuppressEmailRequest struct {
+	Address domain.PII `json:"address"`
+}
+
+// handleEmailSuppressions serves /admin/email/suppressions, the tenant's
+// addresses that get no emails. Admins add the ones whose owners asked not
+// to get any.
+func (s *APIServer) handleEmailSuppressions(w http.ResponseWriter, r *http.Request) error {
+	store := s.storeFor(r)
+	if r.Method != http.MethodPost {
+		suppressions, err := store.ListEmailSuppressions()
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, suppressions)
+	}
+	req := new(SuppressEmailRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	sup := &domain.EmailSuppression{Address: domain.NormalizeEmail(req.Address), Reason: domain.SuppressionManual, CreatedAt: s.clock.Now().UTC()}
+	if err := domain.ValidateEmail(sup.Address); err != nil {
+		return err
+	}
+	if err := store.SuppressEmail(sup); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, sup)
+}
+
+func (s *APIServer) handleDeleteEmailSuppression(w http.ResponseWriter, r *http.Request) error {
+	address := r.PathValue("address")
+	if err := s.storeFor(r).DeleteEmailSuppression(domain.PII(address)); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": address})
+}
+
+// snsMessage is the envelope SNS posts SES notifications in.
+type snsMessage struct {
+	Type         string
+	Message      string
+	SubscribeURL string
+}
+
+// sesNotification is the part of an SES bounce or complaint notification
+// the suppression list needs. Notifications of a configuration set's event
+// destination name their type eventType.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType        string         `json:"bounceType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplainedRecipients []sesRecipient `json:"complainedRecipients"`
+	} `json:"complaint"`
+}
+
+type sesRecipient struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+// sesSuppressions reads an SES notification and returns the addresses it
+// says to suppress, with the reason. Transient bounces suppress nothing.
+func sesSuppressions(data []byte) (messageID, reason string, addresses []domain.PII, err error) {
+	var n sesNotification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", "", nil, err
+	}
+	var recipients []sesRecipient
+	switch cmp.Or(n.NotificationType, n.EventType) {
+	case "Bounce":
+		if n.Bounce.BounceType != "Permanent" {
+			return n.Mail.MessageID, "", nil, nil
+		}
+		reason, recipients = domain.SuppressionBounce, n.Bounce.BouncedRecipients
+	case "Complaint":
+		reason, recipients = domain.SuppressionComplaint, n.Complaint.ComplainedRecipients
+	}
+	for _, r := range recipients {
+		// bounce notifications may name the recipient as "Name <address>"
+		if addr, err := mail.ParseAddress(r.EmailAddress); err == nil {
+			addresses = append(addresses, domain.PII(addr.Address))
+		}
+	}
+	return n.Mail.MessageID, reason, addresses, nil
+}
+
+// handleEmailBounces serves POST /email/bounces?token=..., where the SNS
+// topic of the SES bounce and complaint notifications posts to. The
+// subscription has to be confirmed by visiting the URL it logs.
+func (s *APIServer) handleEmailBounces(w http.ResponseWriter, r *http.Request) error {
+	token := s.config.Get().EmailBounceToken
+	if token == "" || subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+		permissionDenied(w, r)
+		return nil
+	}
+	var msg snsMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, schemaMaxBytes)).Decode(&msg); err != nil {
+		return err
+	}
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		s.logger.Warn("confirm the SNS subscription for email bounces", "url", msg.SubscribeURL)
+	case "Notification":
+		messageID, reason, addresses, err := sesSuppressions([]byte(msg.Message))
+		if err != nil {
+			return invalidParameter("Message", err.Error())
+		}
+		for _, address := range addresses {
+			if err := s.store.SuppressBouncedEmail(messageID, address, reason, s.clock.Now().UTC()); err != nil {
+				return err
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OutgoingEmail is an email as it goes to the provider, Raw the whole MIME
+// message with MessageID in its Message-ID header.
+type OutgoingEmail struct {
+	From      string
+	To        string
+	MessageID string
+	Raw       []byte
+}
+
+// EmailSender hands emails to a provider and returns the provider's id of
+// the message, which its bounce notifications refer to. A refusal the
+// provider won't change its mind about is an *EmailRefusedError, every
+// other error is worth retrying.
+type EmailSender interface {
+	Send(msg *OutgoingEmail) (string, error)
+}
+
+// EmailRefusedError is an email the provider refused for good. Bounce means
+// it refused the recipient, whose address goes on the suppression list.
+type EmailRefusedError struct {
+	Err    error
+	Bounce bool
+}
+
+func (e *EmailRefusedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EmailRefusedError) Unwrap() error {
+	return e.Err
+}
+
+func NewEmailSender(cfg *Config) (EmailSender, error) {
+	switch cfg.EmailProvider {
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	case "ses":
+		return NewSESSender(cfg.SESRegion, awsCredentials{cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken}), nil
+	}
+	return nil, fmt.Errorf("unknown EMAIL_PROVIDER %s", cfg.EmailProvider)
+}
+
+// smtpTimeout bounds a whole SMTP conversation.
+const smtpTimeout = 30 * time.Second
+
+// SMTPSender relays emails through an SMTP server, upgrading to TLS when
+// the server offers it. It only logs in over TLS or to localhost.
+type SMTPSender struct {
+	addr     string
+	username string
+	password string
+}
+
+func NewSMTPSender(addr, username, password string) *SMTPSender {
+	return &SMTPSender{addr: addr, username: username, password: password}
+}
+
+func (s *SMTPSender) Send(msg *OutgoingEmail) (string, error) {
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.DialTimeout("tcp", s.addr, smtpTimeout)
+	if err != nil {
+		return "", err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return "", err
+		}
+	}
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return "", err
+		}
+	}
+	if err := c.Mail(msg.From); err != nil {
+		return "", err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return "", smtpRefused(err, true)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(msg.Raw); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", smtpRefused(err, false)
+	}
+	c.Quit()
+	return msg.MessageID, nil
+}
+
+// smtpRefused makes a 5xx reply to RCPT TO or the message an
+// *EmailRefusedError. 5xx replies to the other commands mean something is
+// wrong with the relay's settings, those are retried until someone fixes
+// them.
+func smtpRefused(err error, bounce bool) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &EmailRefusedError{Err: err, Bounce: bounce}
+	}
+	return err
+}
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// SESSender sends emails with the SES v2 API, as raw MIME so they are the
+// same as those sent over SMTP. Bounces come back later as notifications,
+// see handleEmailBounces.
+type SESSender struct {
+	endpoint string
+	region   string
+	creds    awsCredentials
+	client   *http.Client
+}
+
+func NewSESSender(region string, creds awsCredentials) *SESSender {
+	return &SESSender{
+		endpoint: "https://email." + region + ".amazonaws.com/v2/email/outbound-emails",
+		region:   region,
+		creds:    creds,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// sesPermanentErrors are the SES error types retrying doesn't help with.
+var sesPermanentErrors = map[string]bool{"MessageRejected": true, "BadRequestException": true}
+
+func (s *SESSender) Send(msg *OutgoingEmail) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"FromEmailAddress": msg.From,
+		"Destination":      map[string][]string{"ToAddresses": {msg.To}},
+		"Content":          map[string]any{"Raw": map[string][]byte{"Data": msg.Raw}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWS(req, body, s.creds, s.region, "ses", time.Now())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var out struct {
+		MessageID string `json:"MessageId"`
+		Message   string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&out)
+	if res.StatusCode == http.StatusOK {
+		return out.MessageID, nil
+	}
+	errorType, _, _ := strings.Cut(res.Header.Get("X-Amzn-Errortype"), ":")
+	err = fmt.Errorf("ses: %s %s: %s", res.Status, errorType, out.Message)
+	if sesPermanentErrors[errorType] {
+		return "", &EmailRefusedError{Err: err}
+	}
+	return "", err
+}
+
+// signAWS signs req with AWS Signature Version 4 for the service in region,
+// payload being its body. Every header set on req so far is signed.
+func signAWS(req *http.Request, payload []byte, creds awsCredentials, region, service string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	scope := stamp[:8] + "/" + region + "/" + service + "/aws4_request"
+	req.Header.Set("X-Amz-Date", stamp)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		values[strings.ToLower(name)] = strings.TrimSpace(strings.Join(req.Header.Values(name), ","))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + values[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	// Encode sorts by key, AWS wants spaces as %20
+	query := strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20")
+	canonical := strings.Join([]string{req.Method, path, query, headers.String(), signed, sha256Hex(payload)}, "\n")
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{stamp[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
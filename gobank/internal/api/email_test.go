@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWS(t *testing.T) {
+	// the example of the AWS Signature Version 4 documentation
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWS(req, nil, creds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"))
+}
+
+func TestBuildEmail(t *testing.T) {
+	account := &domain.Account{FirstName: "Jana", Language: "de", Email: "jana@example.com"}
+	settings := &domain.TenantSettings{BrandName: "Bänk", SupportEmail: "hilfe@example.com"}
+	text, html, err := renderEmail(account, settings, "Willkommen", "Hallo Jana,\n\n<b>Ihr Konto</b> ist eröffnet.\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "Hallo Jana,\n\n<b>Ihr Konto</b> ist eröffnet.\n\n-- \n"+
+		"Sie erhalten diese E-Mail, weil Sie ein Konto bei Bänk haben.\nFragen? Schreiben Sie an hilfe@example.com.\n", text)
+	assert.Contains(t, html, `<html lang="de">`)
+	assert.Contains(t, html, "&lt;b&gt;Ihr Konto&lt;/b&gt; ist eröffnet.")
+
+	raw, err := buildEmail(mail.Address{Name: "Bänk", Address: "noreply@bank.example"}, "jana@example.com", "Überweisung erhalten", text, html, "abc@bank.example", time.Unix(0, 0))
+	assert.Nil(t, err)
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if !assert.Nil(t, err) {
+		return
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal(t, "Überweisung erhalten", subject)
+	from, _ := msg.Header.AddressList("From")
+	assert.Equal(t, []*mail.Address{{Name: "Bänk", Address: "noreply@bank.example"}}, from)
+	assert.Equal(t, "<abc@bank.example>", msg.Header.Get("Message-ID"))
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		// NextPart undoes the quoted-printable, which wrote CRLF line breaks
+		data, _ := io.ReadAll(part)
+		bodies = append(bodies, strings.ReplaceAll(string(data), "\r\n", "\n"))
+	}
+	assert.Equal(t, []string{text, html}, bodies)
+}
+
+func TestSESSuppressions(t *testing.T) {
+	messageID, reason, addresses, err := sesSuppressions([]byte(`{"notificationType": "Bounce",
+		"bounce": {"bounceType": "Permanent", "bouncedRecipients": [{"emailAddress": "Jana <jana@example.com>"}, {"emailAddress": "x@example.com"}]},
+		"mail": {"messageId": "0100018f"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "0100018f", messageID)
+	assert.Equal(t, domain.SuppressionBounce, reason)
+	assert.Equal(t, []domain.PII{"jana@example.com", "x@example.com"}, addresses)
+
+	_, _, addresses, err = sesSuppressions([]byte(`{"notificationType": "Bounce", "bounce": {"bounceType": "Transient", "bouncedRecipients": [{"emailAddress": "x@example.com"}]}}`))
+	assert.Nil(t, err)
+	assert.Empty(t, addresses)
+
+	_, reason, addresses, _ = sesSuppressions([]byte(`{"eventType": "Complaint", "complaint": {"complainedRecipients": [{"emailAddress": "x@example.com"}]}}`))
+	assert.Equal(t, domain.SuppressionComplaint, reason)
+	assert.Equal(t, []domain.PII{"x@example.com"}, addresses)
+}
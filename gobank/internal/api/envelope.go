@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// apiVersions are the response shapes a client can pin with X-Api-Version.
+// Version 1, the default, returns bodies bare. Version 2 wraps every success
+// as {"data": ..., "meta": {...}}; listings put their items in data and the
+// cursor in meta.pagination. Errors keep their shape in every version.
+var apiVersions = map[string]struct{ envelope bool }{
+	"1": {envelope: false},
+	"2": {envelope: true},
+}
+
+const defaultAPIVersion = "1"
+
+// withAPIVersion reads X-Api-Version and wraps responses for the versions
+// that use the envelope. It runs inside content negotiation, so XML and
+// MessagePack clients get the envelope too.
+func (s *APIServer) withAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "X-Api-Version")
+		v := r.Header.Get("X-Api-Version")
+		if v == "" {
+			v = defaultAPIVersion
+		}
+		version, ok := apiVersions[v]
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, NewError(CodeInvalidParameter, "name", "X-Api-Version", "value", v))
+			return
+		}
+		w.Header().Set("X-Api-Version", v)
+		if !version.envelope {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ew := &envelopeWriter{ResponseWriter: w, version: v}
+		next.ServeHTTP(ew, r)
+		ew.finish(r)
+	})
+}
+
+// envelopeWriter holds back successful JSON responses and wraps them once the
+// handler is done. Errors, empty responses and anything not JSON pass
+// through.
+type envelopeWriter struct {
+	http.ResponseWriter
+	version     string
+	status      int
+	wroteHeader bool
+	holding     bool
+	buf         bytes.Buffer
+}
+
+func (e *envelopeWriter) WriteHeader(status int) {
+	if e.wroteHeader {
+		return
+	}
+	e.wroteHeader = true
+	mediaType, _, _ := mime.ParseMediaType(e.Header().Get("Content-Type"))
+	if mediaType == "application/json" && status >= 200 && status < 300 && status != http.StatusNoContent {
+		e.holding = true
+		e.status = status
+		return
+	}
+	e.ResponseWriter.WriteHeader(status)
+}
+
+func (e *envelopeWriter) Write(b []byte) (int, error) {
+	e.WriteHeader(http.StatusOK)
+	if e.holding {
+		return e.buf.Write(b)
+	}
+	return e.ResponseWriter.Write(b)
+}
+
+// Flush only flushes responses passed through, a held one goes out whole.
+func (e *envelopeWriter) Flush() {
+	if e.holding {
+		return
+	}
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (e *envelopeWriter) finish(r *http.Request) {
+	if !e.holding {
+		return
+	}
+	raw := e.buf.Bytes()
+	var data json.RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		// empty, as on HEAD, or not JSON after all
+		e.ResponseWriter.WriteHeader(e.status)
+		e.ResponseWriter.Write(raw)
+		return
+	}
+	meta := map[string]any{"apiVersion": e.version, "requestId": requestIDFrom(r)}
+	if items, pagination, ok := splitPage(data); ok {
+		data = items
+		meta["pagination"] = pagination
+	}
+	e.Header().Del("Content-Length")
+	e.ResponseWriter.WriteHeader(e.status)
+	if err := json.NewEncoder(e.ResponseWriter).Encode(map[string]any{"data": data, "meta": meta}); err != nil {
+		loggerFrom(r.Context()).Error("writing envelope failed", "error", err)
+	}
+}
+
+// splitPage takes a Page body apart into its items and the rest. Pages are
+// told apart by their keys: items and has_more, and next_cursor when there
+// is one.
+func splitPage(data json.RawMessage) (json.RawMessage, map[string]json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return nil, nil, false
+	}
+	for k := range fields {
+		if k != "items" && k != "has_more" && k != "next_cursor" {
+			return nil, nil, false
+		}
+	}
+	items, hasItems := fields["items"]
+	if _, hasMore := fields["has_more"]; !hasItems || !hasMore {
+		return nil, nil, false
+	}
+	delete(fields, "items")
+	return items, fields, true
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIVersionEnvelope(t *testing.T) {
+	s := &APIServer{}
+	body := any(map[string]int{"id": 7})
+	h := s.withAPIVersion(makeHttpHandleFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return WriteJSON(w, http.StatusOK, body)
+	}))
+	serve := func(version string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		if version != "" {
+			r.Header.Set("X-Api-Version", version)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serve("")
+	assert.Equal(t, "1", rec.Header().Get("X-Api-Version"))
+	assert.JSONEq(t, `{"id": 7}`, rec.Body.String())
+
+	rec = serve("2")
+	assert.JSONEq(t, `{"data": {"id": 7}, "meta": {"apiVersion": "2", "requestId": ""}}`, rec.Body.String())
+
+	body = Page[int]{Items: []int{1, 2}, NextCursor: "abc", HasMore: true}
+	rec = serve("2")
+	assert.JSONEq(t, `{"data": [1, 2], "meta": {"apiVersion": "2", "requestId": "", "pagination": {"next_cursor": "abc", "has_more": true}}}`, rec.Body.String())
+
+	// errors keep their shape
+	body = nil
+	h = s.withAPIVersion(makeHttpHandleFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return NewError(CodeInvalidParameter, "name", "limit", "value", "x")
+	}))
+	rec = serve("2")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"data"`)
+
+	rec = serve("3")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strings"
+)
+
+// domainErrors maps the errors of the domain package to the API's codes and
+// statuses.
+var domainErrors = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{domain.ErrAccountNotFound, CodeAccountNotFound, http.StatusNotFound},
+	{domain.ErrApiKeyNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrTenantNotFound, CodeUnknownTenant, http.StatusNotFound},
+	{domain.ErrJobNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrIssueNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrInvalidAmount, CodeInvalidAmount, http.StatusBadRequest},
+	{domain.ErrInsufficientFunds, CodeInsufficientFunds, http.StatusUnprocessableEntity},
+	{domain.ErrFundsProtected, CodeFundsProtected, http.StatusUnprocessableEntity},
+	{domain.ErrCurrencyMismatch, CodeCurrencyMismatch, http.StatusUnprocessableEntity},
+	{domain.ErrSameAccount, CodeSameAccount, http.StatusUnprocessableEntity},
+	{domain.ErrVersionConflict, CodePreconditionFailed, http.StatusPreconditionFailed},
+	{domain.ErrNonceReplayed, CodePermissionDenied, http.StatusForbidden},
+	{domain.ErrTransferLimitExceeded, CodeTransferLimitExceeded, http.StatusBadRequest},
+	{domain.ErrDailyLimitExceeded, CodeDailyLimitExceeded, http.StatusBadRequest},
+	{domain.ErrUnknownCountry, CodeUnknownCountry, http.StatusBadRequest},
+	{domain.ErrInvalidPostalCode, CodeInvalidPostalCode, http.StatusBadRequest},
+	{domain.ErrInvalidDateOfBirth, CodeInvalidDateOfBirth, http.StatusBadRequest},
+	{domain.ErrInvalidIBAN, CodeInvalidIBAN, http.StatusBadRequest},
+	{domain.ErrInvalidEmail, CodeInvalidEmail, http.StatusBadRequest},
+	{domain.ErrInvalidPhone, CodeInvalidPhone, http.StatusBadRequest},
+	{domain.ErrInvalidNickname, CodeInvalidNickname, http.StatusBadRequest},
+	{domain.ErrIBANCountry, CodeIBANCountry, http.StatusBadRequest},
+	{domain.ErrUnderage, CodeUnderage, http.StatusUnprocessableEntity},
+	{domain.ErrKYCIncomplete, CodeKYCIncomplete, http.StatusForbidden},
+	{domain.ErrQuoteNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrQuoteExpired, CodeQuoteExpired, http.StatusGone},
+	{domain.ErrHoldNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrHoldClosed, CodeHoldClosed, http.StatusConflict},
+	{domain.ErrCaptureExceedsHold, CodeCaptureExceedsHold, http.StatusUnprocessableEntity},
+	{domain.ErrWebhookNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrDeliveryNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrImpersonationNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrEmailNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrSuppressionNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrSMSNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrDeviceNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrPushNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrNotificationNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrPotNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrStatementDestinationNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrStatementDeliveryNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrProjectionNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrAccountHistoryNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrPhoneNotVerified, CodePhoneNotVerified, http.StatusConflict},
+	{domain.ErrVerificationFailed, CodeVerificationFailed, http.StatusUnprocessableEntity},
+	{domain.ErrImpersonationInactive, CodeImpersonationInactive, http.StatusConflict},
+	{domain.ErrTermsNotAccepted, CodeTermsNotAccepted, http.StatusForbidden},
+	{domain.ErrConsentNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrConsentRequired, CodeConsentRequired, http.StatusForbidden},
+	{domain.ErrTransferRequestNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrTransactionNotFound, CodeNotFound, http.StatusNotFound},
+	{domain.ErrTransferHeld, CodeTransferHeld, http.StatusForbidden},
+	{domain.ErrScreeningFlagged, CodeScreeningFlagged, http.StatusForbidden},
+	{domain.ErrTransferNotInReview, CodeTransferNotInReview, http.StatusConflict},
+	{domain.ErrDuplicateTransfer, CodeDuplicateTransfer, http.StatusConflict},
+}
+
+// fromDomain translates a domain error into a coded Error and the status to
+// answer it with. It reports false for any other error.
+func fromDomain(err error) (*Error, int, bool) {
+	var dup *domain.DuplicateError
+	if errors.As(err, &dup) {
+		return NewError(CodeDuplicate, "field", dup.Field), http.StatusConflict, true
+	}
+	for _, m := range domainErrors {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+		apiErr := NewError(m.code)
+		var nf *domain.NotFoundError
+		if errors.As(err, &nf) {
+			apiErr.Params["id"] = nf.ID
+		}
+		var limit *domain.LimitError
+		if errors.As(err, &limit) {
+			apiErr.Params["limit"] = limit.Limit
+		}
+		var profile *domain.ProfileError
+		if errors.As(err, &profile) {
+			apiErr.Params["value"] = profile.Value
+		}
+		var kyc *domain.KYCError
+		if errors.As(err, &kyc) {
+			apiErr.Params["missing"] = strings.Join(kyc.Missing, ", ")
+		}
+		var terms *domain.TermsError
+		if errors.As(err, &terms) {
+			apiErr.Params["documents"] = strings.Join(terms.Pending, ", ")
+		}
+		var consent *domain.ConsentError
+		if errors.As(err, &consent) {
+			apiErr.Params["scope"] = consent.Scope
+		}
+		var held *domain.HeldError
+		if errors.As(err, &held) {
+			apiErr.Params["id"] = held.TransferID
+		}
+		var duplicate *domain.DuplicateTransferError
+		if errors.As(err, &duplicate) {
+			apiErr.Params["minutes"] = duplicate.Minutes
+		}
+		return apiErr, m.status, true
+	}
+	return nil, 0, false
+}
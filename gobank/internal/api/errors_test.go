@@ -0,0 +1,32 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDomainErrorStatuses(t *testing.T) {
+	for err, want := range map[error]int{
+		domain.NotFound(domain.ErrAccountNotFound, 7):           http.StatusNotFound,
+		fmt.Errorf("transfer: %w", domain.ErrInsufficientFunds): http.StatusUnprocessableEntity,
+		domain.ErrVersionConflict:                               http.StatusPreconditionFailed,
+		&domain.DuplicateError{Field: "email"}:                  http.StatusConflict,
+		errors.New("something else"):                            http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		makeHttpHandleFunc(func(http.ResponseWriter, *http.Request) error { return err })(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, want, rec.Code, err.Error())
+	}
+
+	rec := httptest.NewRecorder()
+	writeError(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusNotFound, domain.NotFound(domain.ErrAccountNotFound, domain.AccountNumber(1234567)))
+	assert.JSONEq(t, `{"code":"account_not_found","error":"account ****4567 not found"}`, rec.Body.String())
+
+	assert.True(t, errors.Is(&domain.DuplicateError{Field: "number"}, domain.ErrDuplicateNumber))
+	assert.False(t, errors.Is(&domain.DuplicateError{Field: "slug"}, domain.ErrDuplicateNumber))
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ErrorReporter ships unexpected server errors to an external error tracker.
+type ErrorReporter interface {
+	Report(err error, r *http.Request, stack []byte)
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Report(err error, r *http.Request, stack []byte) {}
+
+// NewErrorReporter returns a Sentry reporter when dsn is set and a no-op
+// reporter otherwise.
+func NewErrorReporter(dsn, environment string, logger *slog.Logger) (ErrorReporter, error) {
+	if dsn == "" {
+		return nopReporter{}, nil
+	}
+	return NewSentryReporter(dsn, environment, logger)
+}
+
+// SentryReporter sends events to Sentry's envelope endpoint.
+type SentryReporter struct {
+	endpoint    string
+	auth        string
+	dsn         string
+	environment string
+	client      *http.Client
+	logger      *slog.Logger
+}
+
+func NewSentryReporter(dsn, environment string, logger *slog.Logger) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	projectID := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid sentry dsn")
+	}
+	return &SentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/envelope/", u.Scheme, u.Host, projectID),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=gobank/%s, sentry_key=%s", version, u.User.Username()),
+		dsn:         dsn,
+		environment: environment,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		logger:      logger,
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryRequest struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+}
+
+// Report sends the event in the background so the request isn't held up by
+// the error tracker.
+func (s *SentryReporter) Report(err error, r *http.Request, stack []byte) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	ev := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Release:     version,
+		Environment: s.environment,
+		Message:     err.Error(),
+		Extra:       map[string]string{},
+	}
+	if len(stack) > 0 {
+		ev.Extra["stack"] = string(stack)
+	}
+	if r != nil {
+		ev.Request = &sentryRequest{
+			URL:    r.URL.String(),
+			Method: r.Method,
+			// only forward headers that can't carry credentials
+			Headers: map[string]string{
+				"User-Agent": r.Header.Get("User-Agent"),
+				"X-Tenant":   r.Header.Get("X-Tenant"),
+			},
+		}
+		ev.Tags = map[string]string{"request_id": requestIDFrom(r)}
+	}
+	go s.send(ev)
+}
+
+func (s *SentryReporter) send(ev sentryEvent) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		s.logger.Error("encoding sentry event failed", "error", err)
+		return
+	}
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{"event_id": ev.EventID, "dsn": s.dsn})
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(item)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		s.logger.Error("building sentry request failed", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	res, err := s.client.Do(req)
+	if err != nil {
+		s.logger.Error("sending sentry event failed", "error", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		s.logger.Error("sentry rejected event", "status", res.StatusCode)
+	}
+}
+
+// withRecovery turns panics into 500 responses and reports them, along with
+// any other 5xx response, to the error reporter. 503s are intentional
+// (maintenance) and not reported, and neither are the failures withChaos
+// injects. http.ErrAbortHandler is passed on, so net/http drops the
+// connection as asked.
+func (s *APIServer) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// recovery runs outermost, the request id is only on the response
+			requestID := w.Header().Get("X-Request-ID")
+			r := r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID))
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				stack := debug.Stack()
+				err := fmt.Errorf("panic: %v", p)
+				s.logger.Error("panic serving request", "request_id", requestID, "error", err, "stack", string(stack))
+				s.reporter.Report(err, r, stack)
+				writeError(w, r, http.StatusInternalServerError, NewError(CodeInternal))
+				return
+			}
+			if rec.status >= 500 && rec.status != http.StatusServiceUnavailable && w.Header().Get("X-Chaos-Injected") == "" {
+				s.reporter.Report(fmt.Errorf("%s %s returned %d", r.Method, r.URL.Path, rec.status), r, nil)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRecoveryReports(t *testing.T) {
+	reporter := &recordingReporter{}
+	s := &APIServer{reporter: reporter, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	serve := func(h http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		s.withRecovery(h).ServeHTTP(rec, httptest.NewRequest("GET", "/account/1", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, serve(func(w http.ResponseWriter, r *http.Request) { panic("boom") }))
+	assert.Equal(t, http.StatusBadGateway, serve(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) }))
+	if assert.Len(t, reporter.errs, 2) {
+		assert.EqualError(t, reporter.errs[0], "panic: boom")
+		assert.EqualError(t, reporter.errs[1], "GET /account/1 returned 502")
+	}
+
+	// maintenance, injected failures and client errors aren't bugs
+	serve(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) })
+	serve(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Chaos-Injected", "error")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	serve(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	assert.Len(t, reporter.errs, 2)
+
+	assert.Panics(t, func() { serve(func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }) })
+	assert.Len(t, reporter.errs, 2)
+}
+
+func TestSentryReporter(t *testing.T) {
+	reporter, err := NewErrorReporter("", "test", nil)
+	assert.Nil(t, err)
+	assert.IsType(t, nopReporter{}, reporter)
+	_, err = NewErrorReporter("https://sentry.example.com/42", "test", nil)
+	assert.NotNil(t, err)
+
+	type received struct {
+		path, auth string
+		event      sentryEvent
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines := bufio.NewScanner(r.Body)
+		var ev sentryEvent
+		for i := 0; lines.Scan(); i++ {
+			if i == 2 {
+				json.Unmarshal(lines.Bytes(), &ev)
+			}
+		}
+		got <- received{r.URL.Path, r.Header.Get("X-Sentry-Auth"), ev}
+	}))
+	defer srv.Close()
+
+	reporter, err = NewErrorReporter(strings.Replace(srv.URL, "://", "://key@", 1)+"/42", "test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Nil(t, err)
+	r := httptest.NewRequest("POST", "/transfer", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	reporter.Report(errors.New("POST /transfer returned 500"), r, []byte("goroutine 1"))
+
+	select {
+	case rec := <-got:
+		assert.Equal(t, "/api/42/envelope/", rec.path)
+		assert.Contains(t, rec.auth, "sentry_key=key")
+		assert.Equal(t, "POST /transfer returned 500", rec.event.Message)
+		assert.Equal(t, "test", rec.event.Environment)
+		assert.Equal(t, "goroutine 1", rec.event.Extra["stack"])
+		assert.NotContains(t, rec.event.Request.Headers, "Authorization")
+	case <-time.After(time.Second):
+		t.Fatal("no event reached sentry")
+	}
+}
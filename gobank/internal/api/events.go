@@ -0,0 +1,84 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type EventPublisher interface {
+	Publish(ev *domain.Event) error
+}
+
+// Bus is the transport events are published on and subscribed to.
+type Bus interface {
+	EventPublisher
+	Subscribe(fn func(ev *domain.Event)) error
+}
+
+func NewBus(cfg *Config, logger *slog.Logger) (Bus, error) {
+	if cfg.EventTransport == "nats" {
+		return NewNatsBus(cfg.NatsURL, cfg.NatsSubject, logger)
+	}
+	return NewEventBus(), nil
+}
+
+// EventBus fans events out to in-process subscribers such as notifiers and
+// webhook dispatchers.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []func(ev *domain.Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (b *EventBus) Subscribe(fn func(ev *domain.Event)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+	return nil
+}
+
+func (b *EventBus) Publish(ev *domain.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subscribers {
+		fn(ev)
+	}
+	return nil
+}
+
+// OutboxRelay moves events written to the outbox table, in the same db
+// transaction as the state change that produced them, onto the publisher.
+// Delivery is at least once: consumers should dedupe on Event.ID.
+type OutboxRelay struct {
+	store     storage.OutboxStore
+	publisher EventPublisher
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+func NewOutboxRelay(store storage.OutboxStore, publisher EventPublisher, logger *slog.Logger) *OutboxRelay {
+	return &OutboxRelay{store: store, publisher: publisher, interval: time.Second, logger: logger}
+}
+
+func (r *OutboxRelay) Run(stop <-chan struct{}) {
+	for {
+		n, err := r.store.RelayOutbox(100, r.publisher.Publish)
+		if err != nil {
+			r.logger.Error("outbox relay failed", "error", err)
+		}
+		if n == 100 {
+			continue
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeOutboxStore struct {
+	mu      sync.Mutex
+	pending []*domain.Event
+}
+
+func (f *fakeOutboxStore) RecordEvent(ev *domain.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ev.ID = int64(len(f.pending) + 1)
+	f.pending = append(f.pending, ev)
+	return nil
+}
+
+func (f *fakeOutboxStore) RelayOutbox(limit int, publish func(ev *domain.Event) error) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	published := 0
+	for published < limit && published < len(f.pending) {
+		if err := publish(f.pending[published]); err != nil {
+			break
+		}
+		published++
+	}
+	f.pending = f.pending[published:]
+	return published, nil
+}
+
+func (f *fakeOutboxStore) left() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending)
+}
+
+type flakyPublisher struct {
+	mu       sync.Mutex
+	failures int
+	got      []int64
+}
+
+func (p *flakyPublisher) Publish(ev *domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker down")
+	}
+	p.got = append(p.got, ev.ID)
+	return nil
+}
+
+func (p *flakyPublisher) published() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int64(nil), p.got...)
+}
+
+func TestOutboxRelay(t *testing.T) {
+	store := &fakeOutboxStore{}
+	for i := 0; i < 150; i++ {
+		assert.Nil(t, store.RecordEvent(&domain.Event{Type: domain.EventTransferCompleted}))
+	}
+	publisher := &flakyPublisher{failures: 1}
+	relay := NewOutboxRelay(store, publisher, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	relay.interval = time.Millisecond
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		relay.Run(stop)
+		close(done)
+	}()
+	// the failed publish is retried on the next pass and nothing overtakes it
+	assert.Eventually(t, func() bool { return store.left() == 0 }, time.Second, time.Millisecond)
+	close(stop)
+	<-done
+
+	got := publisher.published()
+	assert.Len(t, got, 150)
+	for i, id := range got {
+		assert.Equal(t, int64(i+1), id)
+	}
+}
+
+func TestNewBusFromConfig(t *testing.T) {
+	cfg, err := configFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "memory", cfg.EventTransport)
+	bus, err := NewBus(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Nil(t, err)
+	assert.IsType(t, &EventBus{}, bus)
+
+	t.Setenv("EVENT_TRANSPORT", "kafka")
+	_, err = configFromEnv()
+	assert.NotNil(t, err)
+
+	// a NATS bus needs the server up when the instance starts
+	t.Setenv("EVENT_TRANSPORT", "nats")
+	t.Setenv("NATS_URL", "nats://127.0.0.1:1")
+	cfg, err = configFromEnv()
+	assert.Nil(t, err)
+	_, err = NewBus(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NotNil(t, err)
+}
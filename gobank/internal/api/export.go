@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFormats maps the format parameter to its content type and file extension.
+var exportFormats = map[string][2]string{
+	"csv":    {"text/csv", "csv"},
+	"ofx":    {"application/x-ofx", "ofx"},
+	"qif":    {"application/qif", "qif"},
+	"ndjson": {ndjsonContentType, "ndjson"},
+	"mt940":  {"text/plain", "sta"},
+}
+
+// handleExportTransactions serves GET /account/{id}/transactions/export
+// ?format=csv|ofx|qif|ndjson|mt940&from=2024-01-01&to=2024-02-01. from and to are
+// days in the account's time zone, to is exclusive. Accept: application/x-ndjson
+// picks ndjson too.
+func (s *APIServer) handleExportTransactions(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" && wantsNDJSON(r) {
+		format = "ndjson"
+	}
+	if format == "" {
+		format = "csv"
+	}
+	ft, ok := exportFormats[format]
+	if !ok {
+		return NewError(CodeInvalidParameter, "name", "format", "value", format)
+	}
+	from, err := QueryTime(r, "from", "2006-01-02", loc, domain.StartOfDay(account.CreatedAt, loc))
+	if err != nil {
+		return err
+	}
+	to, err := QueryTime(r, "to", "2006-01-02", loc, domain.StartOfDay(s.clock.Now(), loc).AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	if format == "ndjson" {
+		return streamNDJSON(w, r, func(fn func(*domain.Transaction) error) error {
+			return store.EachTransactionBetween(account.ID, from, to, fn)
+		})
+	}
+	txs, err := store.TransactionsBetween(account.ID, from, to)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ft[0])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions-%d.%s"`, account.Number.Reveal(), ft[1]))
+	switch format {
+	case "ofx":
+		return writeOFX(w, account, txs, from, to, s.clock.Now())
+	case "qif":
+		return writeQIF(w, txs, loc)
+	case "mt940":
+		txs = posted(txs)
+		opening, closing, err := periodBalances(store, account, from, to, s.clock.Now(), txs)
+		if err != nil {
+			return err
+		}
+		return writeMT940(w, account, txs, opening, closing, from, to, loc)
+	}
+	return writeTransactionsCSV(w, txs, loc)
+}
+
+func transactionPayee(t *domain.Transaction) string {
+	if t.Description != "" {
+		return t.Description
+	}
+	if t.Counterparty != 0 {
+		return strconv.FormatInt(t.Counterparty.Reveal(), 10)
+	}
+	return t.Type
+}
+
+// writeTransactionsCSV uses the columns the importer reads, so an export can
+// be imported again.
+func writeTransactionsCSV(w io.Writer, txs []*domain.Transaction, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "date", "type", "amount", "currency", "counterparty", "description"})
+	for _, t := range txs {
+		cw.Write([]string{
+			strconv.Itoa(t.ID),
+			t.CreatedAt.In(loc).Format(time.RFC3339),
+			t.Type,
+			t.Amount.Decimal(),
+			t.Amount.Currency,
+			strconv.FormatInt(t.Counterparty.Reveal(), 10),
+			t.Description,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + ".000[0:GMT]"
+}
+
+// ofxEscape escapes the characters SGML OFX reserves.
+var ofxEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// writeOFX writes an OFX 1.02 bank statement, the flavour GnuCash and Quicken
+// both read, as generated at now.
+func writeOFX(w io.Writer, account *domain.Account, txs []*domain.Transaction, from, to, now time.Time) error {
+	var b strings.Builder
+	b.WriteString("OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\nSECURITY:NONE\r\nENCODING:USASCII\r\nCHARSET:1252\r\nCOMPRESSION:NONE\r\nOLDFILEUID:NONE\r\nNEWFILEUID:NONE\r\n\r\n")
+	fmt.Fprintf(&b, "<OFX>\r\n<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>%s<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>\r\n", ofxTime(now))
+	b.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0<STATUS><CODE>0<SEVERITY>INFO</STATUS>\r\n")
+	fmt.Fprintf(&b, "<STMTRS><CURDEF>%s\r\n", account.Balance.Currency)
+	fmt.Fprintf(&b, "<BANKACCTFROM><BANKID>GOBANK<ACCTID>%d<ACCTTYPE>CHECKING</BANKACCTFROM>\r\n", account.Number.Reveal())
+	fmt.Fprintf(&b, "<BANKTRANLIST><DTSTART>%s<DTEND>%s\r\n", ofxTime(from), ofxTime(to))
+	for _, t := range txs {
+		trnType := "CREDIT"
+		if t.Amount.MinorUnits < 0 {
+			trnType = "DEBIT"
+		}
+		fmt.Fprintf(&b, "<STMTTRN><TRNTYPE>%s<DTPOSTED>%s<TRNAMT>%s<FITID>%d<NAME>%s</STMTTRN>\r\n",
+			trnType, ofxTime(t.CreatedAt), t.Amount.Decimal(), t.ID, ofxEscape.Replace(truncate(transactionPayee(t), 32)))
+	}
+	b.WriteString("</BANKTRANLIST>\r\n")
+	fmt.Fprintf(&b, "<LEDGERBAL><BALAMT>%s<DTASOF>%s</LEDGERBAL>\r\n", account.Balance.Decimal(), ofxTime(now))
+	b.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\r\n</OFX>\r\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeQIF writes a QIF bank register with US style dates in the account's
+// time zone.
+func writeQIF(w io.Writer, txs []*domain.Transaction, loc *time.Location) error {
+	var b strings.Builder
+	b.WriteString("!Type:Bank\n")
+	for _, t := range txs {
+		fmt.Fprintf(&b, "D%s\nT%s\nN%d\nP%s\n^\n", t.CreatedAt.In(loc).Format("01/02/2006"), t.Amount.Decimal(), t.ID, transactionPayee(t))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
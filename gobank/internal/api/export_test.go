@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportFixture() (*domain.Account, []*domain.Transaction) {
+	account := &domain.Account{ID: 1, Number: 4242, Balance: domain.Money{MinorUnits: 98750, Currency: "USD"}}
+	txs := []*domain.Transaction{
+		{ID: 7, Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -1250, Currency: "USD"}, Counterparty: 99, CreatedAt: time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{ID: 8, Type: domain.TransactionImport, Amount: domain.Money{MinorUnits: 100000, Currency: "USD"}, Description: "Salary & bonus", CreatedAt: time.Date(2024, 1, 6, 0, 30, 0, 0, time.UTC)},
+	}
+	return account, txs
+}
+
+func TestWriteQIF(t *testing.T) {
+	_, txs := exportFixture()
+	var buf bytes.Buffer
+	ny, _ := loadLocation("America/New_York")
+	assert.Nil(t, writeQIF(&buf, txs, ny))
+	assert.Equal(t, "!Type:Bank\nD01/05/2024\nT-12.50\nN7\nP99\n^\nD01/05/2024\nT1000.00\nN8\nPSalary & bonus\n^\n", buf.String())
+}
+
+func TestOFXExportCanBeImported(t *testing.T) {
+	account, txs := exportFixture()
+	var buf bytes.Buffer
+	assert.Nil(t, writeOFX(&buf, account, txs, txs[0].CreatedAt, txs[1].CreatedAt, time.Now()))
+	assert.Contains(t, buf.String(), "<LEDGERBAL><BALAMT>987.50")
+
+	rows, err := parseImportOFX(strings.NewReader(buf.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, importRow{row: 2, date: "20240106003000", amount: "1000.00", currency: "USD", description: "Salary & bonus"}, rows[1])
+}
+
+func TestWriteMT940(t *testing.T) {
+	account, txs := exportFixture()
+	var buf bytes.Buffer
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	opening := domain.Money{MinorUnits: 0, Currency: "USD"}
+	assert.Nil(t, writeMT940(&buf, account, txs, opening, account.Balance, from, to, time.UTC))
+	assert.Equal(t, ":20:2401014242\r\n"+
+		":25:GOBANK/4242\r\n"+
+		":28C:00001/001\r\n"+
+		":60F:C240101USD0,00\r\n"+
+		":61:2401050105D12,50NTRFNONREF//7\r\n"+
+		":86:99\r\n"+
+		":61:2401060106C1000,00NMSCNONREF//8\r\n"+
+		":86:Salary . bonus\r\n"+
+		":62F:C240131USD987,50\r\n"+
+		":64:C240131USD987,50\r\n"+
+		"-\r\n", buf.String())
+}
+
+func TestWriteCamt053(t *testing.T) {
+	account, txs := exportFixture()
+	var buf bytes.Buffer
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	opening := domain.Money{MinorUnits: 0, Currency: "USD"}
+	assert.Nil(t, writeCamt053(&buf, account, txs, opening, account.Balance, from, to, to, time.UTC))
+
+	doc := new(camt053Document)
+	assert.Nil(t, xml.Unmarshal(buf.Bytes(), doc))
+	stmt := doc.Statement.Stmt
+	assert.Equal(t, "4242-20240101", stmt.ID)
+	assert.Equal(t, "2024-01-31T23:59:59Z", stmt.To)
+	assert.Equal(t, []camt053Balance{
+		{Type: "OPBD", Amount: camt053Amount{Currency: "USD", Value: "0.00"}, Sign: "CRDT", Date: "2024-01-01"},
+		{Type: "CLBD", Amount: camt053Amount{Currency: "USD", Value: "987.50"}, Sign: "CRDT", Date: "2024-01-31"},
+	}, stmt.Balances)
+	assert.Equal(t, 2, stmt.Entries)
+	assert.Equal(t, "1012.50", stmt.Sum)
+	assert.Equal(t, "987.50", stmt.Net)
+	if assert.Len(t, stmt.Entry, 2) {
+		assert.Equal(t, "DBIT", stmt.Entry[0].Sign)
+		assert.Equal(t, "12.50", stmt.Entry[0].Amount.Value)
+		assert.Equal(t, &camt053Party{Creditor: "99"}, stmt.Entry[0].Counterparty)
+		assert.Equal(t, "2024-01-06", stmt.Entry[1].BookingDate)
+	}
+}
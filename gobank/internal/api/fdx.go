@@ -0,0 +1,145 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// FDXDocument presents an account and its transactions in the Financial Data
+// Exchange (FDX) v5 JSON format, which US aggregators read without a mapping
+// of their own.
+type FDXDocument struct {
+	Accounts []FDXAccount `json:"accounts"`
+}
+
+type FDXAccount struct {
+	DepositAccount FDXDepositAccount `json:"depositAccount"`
+}
+
+type FDXCurrency struct {
+	CurrencyCode string `json:"currencyCode"`
+}
+
+// FDXDepositAccount amounts are decimals in the account's currency.
+type FDXDepositAccount struct {
+	AccountID        string           `json:"accountId"`
+	AccountType      string           `json:"accountType"`
+	AccountNumber    string           `json:"accountNumberDisplay"`
+	DisplayName      string           `json:"displayName"`
+	Status           string           `json:"status"`
+	Currency         FDXCurrency      `json:"currency"`
+	CurrentBalance   float64          `json:"currentBalance"`
+	AvailableBalance float64          `json:"availableBalance"`
+	BalanceAsOf      time.Time        `json:"balanceAsOf"`
+	OpeningDate      string           `json:"openingDate"`
+	Transactions     []FDXTransaction `json:"transactions"`
+}
+
+type FDXTransaction struct {
+	DepositTransaction FDXDepositTransaction `json:"depositTransaction"`
+}
+
+// FDXDepositTransaction has a positive Amount, DebitCreditMemo tells its
+// direction.
+type FDXDepositTransaction struct {
+	AccountID            string    `json:"accountId"`
+	TransactionID        string    `json:"transactionId"`
+	PostedTimestamp      time.Time `json:"postedTimestamp"`
+	TransactionTimestamp time.Time `json:"transactionTimestamp"`
+	Description          string    `json:"description"`
+	DebitCreditMemo      string    `json:"debitCreditMemo"`
+	Status               string    `json:"status"`
+	Amount               float64   `json:"amount"`
+	TransactionType      string    `json:"transactionType"`
+}
+
+// fdxTransactionTypes maps transaction types to FDX's, anything else is an
+// ADJUSTMENT.
+var fdxTransactionTypes = map[string]string{
+	domain.TransactionTransferIn:  "TRANSFER",
+	domain.TransactionTransferOut: "TRANSFER",
+	domain.TransactionFee:         "FEE",
+	domain.TransactionSandbox:     "DEPOSIT",
+}
+
+// fdxAmount is m as a decimal number. FDX has no way to write minor units.
+func fdxAmount(m domain.Money) float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+func fdxTransaction(accountID string, t *domain.Transaction) FDXTransaction {
+	memo, amount := "CREDIT", t.Amount
+	if amount.MinorUnits < 0 {
+		memo, amount.MinorUnits = "DEBIT", -amount.MinorUnits
+	}
+	txType, ok := fdxTransactionTypes[t.Type]
+	if !ok {
+		txType = "ADJUSTMENT"
+	}
+	return FDXTransaction{DepositTransaction: FDXDepositTransaction{
+		AccountID:            accountID,
+		TransactionID:        strconv.Itoa(t.ID),
+		PostedTimestamp:      t.CreatedAt.UTC(),
+		TransactionTimestamp: t.CreatedAt.UTC(),
+		Description:          transactionPayee(t),
+		DebitCreditMemo:      memo,
+		Status:               "POSTED",
+		Amount:               fdxAmount(amount),
+		TransactionType:      txType,
+	}}
+}
+
+// handleFDXExport serves GET /account/{id}/fdx?from=2024-01-01&to=2024-02-01,
+// days in the account's time zone with to exclusive, like the transaction
+// export.
+func (s *APIServer) handleFDXExport(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	from, err := QueryTime(r, "from", "2006-01-02", loc, domain.StartOfDay(account.CreatedAt, loc))
+	if err != nil {
+		return err
+	}
+	to, err := QueryTime(r, "to", "2006-01-02", loc, domain.StartOfDay(s.clock.Now(), loc).AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	txs, err := store.TransactionsBetween(account.ID, from, to)
+	if err != nil {
+		return err
+	}
+	availability, err := store.Availability(account.ID)
+	if err != nil {
+		return err
+	}
+	deposit := FDXDepositAccount{
+		AccountID:        account.UUID,
+		AccountType:      "CHECKING",
+		AccountNumber:    account.Number.String(),
+		DisplayName:      "Checking " + account.Number.String(),
+		Status:           "OPEN",
+		Currency:         FDXCurrency{CurrencyCode: account.Balance.Currency},
+		CurrentBalance:   fdxAmount(account.Balance),
+		AvailableBalance: fdxAmount(availability.Available()),
+		BalanceAsOf:      s.clock.Now().UTC(),
+		OpeningDate:      account.CreatedAt.In(loc).Format("2006-01-02"),
+		Transactions:     make([]FDXTransaction, 0, len(txs)),
+	}
+	for _, t := range txs {
+		deposit.Transactions = append(deposit.Transactions, fdxTransaction(account.UUID, t))
+	}
+	return WriteJSON(w, http.StatusOK, FDXDocument{Accounts: []FDXAccount{{DepositAccount: deposit}}})
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFDXTransaction(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tx := fdxTransaction("acc", &domain.Transaction{ID: 3, Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -1999, Currency: "USD"}, Counterparty: 4711007, CreatedAt: at}).DepositTransaction
+	assert.Equal(t, "DEBIT", tx.DebitCreditMemo)
+	assert.Equal(t, 19.99, tx.Amount)
+	assert.Equal(t, "TRANSFER", tx.TransactionType)
+	assert.Equal(t, "4711007", tx.Description)
+
+	tx = fdxTransaction("acc", &domain.Transaction{ID: 4, Type: domain.TransactionImport, Amount: domain.Money{MinorUnits: 500, Currency: "USD"}, Description: "refund", CreatedAt: at}).DepositTransaction
+	assert.Equal(t, "CREDIT", tx.DebitCreditMemo)
+	assert.Equal(t, "ADJUSTMENT", tx.TransactionType)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	feedPageSize     = 100
+	feedDefaultWait  = 25 * time.Second
+	feedMaxWait      = 60 * time.Second
+	feedPollInterval = 2 * time.Second
+)
+
+type FeedPage struct {
+	Transactions []*domain.Transaction `json:"transactions"`
+	Cursor       string                `json:"cursor"`
+}
+
+// Notifier wakes the long-polling requests of this instance when a transfer
+// commits. Transfers made on other instances are picked up by polling.
+type Notifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{ch: make(chan struct{})}
+}
+
+// Wait returns a channel that's closed on the next Notify.
+func (n *Notifier) Wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+func (n *Notifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// handleTransactionFeed serves GET /account/{id}/transactions/feed?cursor=&wait=.
+// It answers right away when there are transactions after the cursor and
+// otherwise holds the request for up to wait seconds until one shows up.
+func (s *APIServer) handleTransactionFeed(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	cursor, err := QueryInt(r, "cursor", 0, 0, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	secs, err := QueryInt(r, "wait", int(feedDefaultWait/time.Second), 0, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	wait := min(time.Duration(secs)*time.Second, feedMaxWait)
+
+	store := s.storeFor(r)
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	for {
+		// take the channel before querying so a transfer committed in between
+		// isn't missed
+		wake := s.notifier.Wait()
+		txs, err := store.TransactionsAfter(id, cursor, feedPageSize)
+		if err != nil {
+			return err
+		}
+		if len(txs) > 0 {
+			cursor = txs[len(txs)-1].ID
+			return WriteJSON(w, http.StatusOK, FeedPage{Transactions: txs, Cursor: strconv.Itoa(cursor)})
+		}
+		select {
+		case <-wake:
+		case <-time.After(feedPollInterval):
+		case <-deadline.C:
+			return WriteJSON(w, http.StatusOK, FeedPage{Transactions: txs, Cursor: strconv.Itoa(cursor)})
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNotifierWakesWaiters(t *testing.T) {
+	n := NewNotifier()
+	first, second := n.Wait(), n.Wait()
+	n.Notify()
+	for _, ch := range []<-chan struct{}{first, second} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("waiter not woken")
+		}
+	}
+	// waiters that arrive after a notify wait for the next one
+	select {
+	case <-n.Wait():
+		t.Fatal("woken without a notify")
+	default:
+	}
+	assert.NotEqual(t, first, n.Wait())
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles is the demo single page frontend, served at / when
+// SERVE_FRONTEND=true.
+//
+//go:embed web
+var webFiles embed.FS
+
+func registerFrontend(group *Group) {
+	sub, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServer(http.FS(sub))
+	group.HandlePrefix("GET", "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		files.ServeHTTP(w, r)
+	}))
+}
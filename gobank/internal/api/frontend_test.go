@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFrontendIsServed(t *testing.T) {
+	router := NewRouter(nil)
+	registerFrontend(router.Group("", nil))
+
+	for path, contentType := range map[string]string{"/": "text/html; charset=utf-8", "/app.js": "text/javascript; charset=utf-8"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, 200, rec.Code, path)
+		assert.Equal(t, contentType, rec.Header().Get("Content-Type"), path)
+	}
+}
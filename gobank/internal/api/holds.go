@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+// CaptureTransferRequest is the final amount of an authorized transfer, the
+// zero Money captures all of it.
+type CaptureTransferRequest struct {
+	Amount domain.Money `json:"amount"`
+}
+
+// handleAuthorizeTransfer puts a hold on the funds of a transfer. The sender
+// captures it once the final amount is known, or voids it.
+func (s *APIServer) handleAuthorizeTransfer(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	defer r.Body.Close()
+	req := new(TransferAccount)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	hold, err := s.transfersFor(r).Authorize(account, req.ToAccount, req.Amount)
+	if err != nil {
+		return err
+	}
+	loggerFrom(r.Context()).Info("transfer authorized", "hold_id", hold.ID, "amount", hold.Amount.String())
+	return WriteJSON(w, http.StatusCreated, hold)
+}
+
+func (s *APIServer) handleGetHold(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	hold, err := s.storeFor(r).GetHold(account.ID, r.PathValue("holdId"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, hold)
+}
+
+func (s *APIServer) handleCaptureTransfer(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	defer r.Body.Close()
+	req := new(CaptureTransferRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	holdID := r.PathValue("holdId")
+	transaction, err := s.transfersFor(r).Capture(account, holdID, req.Amount)
+	if err != nil {
+		return err
+	}
+	s.notifier.Notify()
+	loggerFrom(r.Context()).Info("transfer captured", "hold_id", holdID, "transaction_id", transaction.ID)
+	return WriteJSON(w, http.StatusOK, transaction)
+}
+
+func (s *APIServer) handleVoidTransfer(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	hold, err := s.storeFor(r).VoidTransfer(account.ID, r.PathValue("holdId"))
+	if err != nil {
+		return err
+	}
+	loggerFrom(r.Context()).Info("transfer voided", "hold_id", hold.ID)
+	return WriteJSON(w, http.StatusOK, hold)
+}
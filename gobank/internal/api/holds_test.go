@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHoldStore keeps the holds of one account and reserves their amounts
+// the way the store does: an authorized hold that hasn't expired counts
+// against the available balance, except for the capture of the hold itself.
+type fakeHoldStore struct {
+	storage.Storage
+	clock   domain.Clock
+	balance int64
+	holds   map[string]*domain.TransferHold
+}
+
+func (f *fakeHoldStore) ForTenant(tenantID int) storage.Storage {
+	return f
+}
+
+func (f *fakeHoldStore) GetTenantSettings(tenantID int) (*domain.TenantSettings, error) {
+	return nil, nil
+}
+
+func (f *fakeHoldStore) SentSince(accountID int, since time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeHoldStore) availability(exceptHold string) domain.Availability {
+	a := domain.Availability{Balance: domain.Money{MinorUnits: f.balance, Currency: "EUR"}}
+	for _, h := range f.holds {
+		if h.Status == domain.HoldAuthorized && f.clock.Now().Before(h.ExpiresAt) && h.ID != exceptHold {
+			a.Held += h.Amount.MinorUnits
+		}
+	}
+	return a
+}
+
+func (f *fakeHoldStore) Availability(accountID int) (domain.Availability, error) {
+	return f.availability(""), nil
+}
+
+func (f *fakeHoldStore) AuthorizeTransfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.TransferHold, error) {
+	if err := f.availability("").Check(amount.MinorUnits); err != nil {
+		return nil, err
+	}
+	now := f.clock.Now()
+	hold := &domain.TransferHold{ID: domain.NewUUID(), AccountID: from.ID, ToAccount: toNumber, Amount: amount, Status: domain.HoldAuthorized, CreatedAt: now, ExpiresAt: now.Add(domain.HoldTTL)}
+	f.holds[hold.ID] = hold
+	return hold, nil
+}
+
+func (f *fakeHoldStore) lockHold(holdID string) (*domain.TransferHold, error) {
+	hold, ok := f.holds[holdID]
+	if !ok {
+		return nil, domain.NotFound(domain.ErrHoldNotFound, holdID)
+	}
+	if hold.Status != domain.HoldAuthorized || !f.clock.Now().Before(hold.ExpiresAt) {
+		return nil, domain.ErrHoldClosed
+	}
+	return hold, nil
+}
+
+func (f *fakeHoldStore) CaptureTransfer(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error) {
+	hold, err := f.lockHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	if amount.MinorUnits == 0 {
+		amount = hold.Amount
+	}
+	if amount.MinorUnits > hold.Amount.MinorUnits {
+		return nil, domain.ErrCaptureExceedsHold
+	}
+	if err := f.availability(hold.ID).Check(amount.MinorUnits); err != nil {
+		return nil, err
+	}
+	f.balance -= amount.MinorUnits
+	hold.Status = domain.HoldCaptured
+	return &domain.Transaction{ID: 1, AccountID: from.ID, Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -amount.MinorUnits, Currency: "EUR"}, Counterparty: hold.ToAccount}, nil
+}
+
+func (f *fakeHoldStore) VoidTransfer(accountID int, holdID string) (*domain.TransferHold, error) {
+	hold, err := f.lockHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	hold.Status = domain.HoldVoided
+	return hold, nil
+}
+
+func TestTransferHolds(t *testing.T) {
+	clock := domain.NewSimClock()
+	store := &fakeHoldStore{clock: clock, balance: 1000, holds: map[string]*domain.TransferHold{}}
+	s := &APIServer{store: store, notifier: NewNotifier(), clock: clock}
+	s.settings = NewTenantSettingsCache(store, time.Minute, domain.DefaultTenantSettings)
+	account := &domain.Account{ID: 7, Number: 1234567, Timezone: "UTC", Balance: domain.Money{MinorUnits: 1000, Currency: "EUR"}}
+	call := func(h apiFunc, body, holdID string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest("POST", "/transfer/authorize", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), callerKey{}, account))
+		r.SetPathValue("holdId", holdID)
+		rec := httptest.NewRecorder()
+		return rec, h(rec, r)
+	}
+	authorize := func(units int) (*domain.TransferHold, error) {
+		rec, err := call(s.handleAuthorizeTransfer, `{"toAccount": 7654321, "amount": {"minor_units": `+strconv.Itoa(units)+`, "currency": "EUR"}}`, "")
+		if err != nil {
+			return nil, err
+		}
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		hold := new(domain.TransferHold)
+		return hold, json.Unmarshal(rec.Body.Bytes(), hold)
+	}
+
+	// an authorized hold reserves its amount for other transfers
+	hold, err := authorize(600)
+	assert.Nil(t, err)
+	a, _ := store.Availability(account.ID)
+	assert.Equal(t, int64(400), a.Available().MinorUnits)
+	_, err = authorize(500)
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+
+	// capturing more than was authorized fails, the hold stays authorized
+	_, err = call(s.handleCaptureTransfer, `{"amount": {"minor_units": 601, "currency": "EUR"}}`, hold.ID)
+	assert.ErrorIs(t, err, domain.ErrCaptureExceedsHold)
+	_, status, _ := fromDomain(err)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	// a capture isn't held back by its own hold
+	other, err := authorize(400)
+	assert.Nil(t, err)
+	_, err = call(s.handleCaptureTransfer, `{}`, hold.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(400), store.balance)
+	assert.Equal(t, domain.HoldCaptured, store.holds[hold.ID].Status)
+	_, err = call(s.handleCaptureTransfer, `{}`, hold.ID)
+	assert.ErrorIs(t, err, domain.ErrHoldClosed)
+
+	// voiding releases the funds
+	_, err = call(s.handleVoidTransfer, ``, other.ID)
+	assert.Nil(t, err)
+	a, _ = store.Availability(account.ID)
+	assert.Equal(t, int64(400), a.Available().MinorUnits)
+
+	// an expired hold can't be captured and reserves nothing
+	late, err := authorize(300)
+	assert.Nil(t, err)
+	clock.Advance(domain.HoldTTL + time.Minute)
+	_, err = call(s.handleCaptureTransfer, `{}`, late.ID)
+	assert.ErrorIs(t, err, domain.ErrHoldClosed)
+	_, status, _ = fromDomain(err)
+	assert.Equal(t, http.StatusConflict, status)
+	a, _ = store.Availability(account.ID)
+	assert.Equal(t, int64(400), a.Available().MinorUnits)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net/http"
+	"time"
+)
+
+// configureServer applies the connection settings of cfg to srv and returns
+// the handler to serve. HTTPS always offers h2 next to HTTP/1.1; plain HTTP
+// speaks h2c as well when cfg.H2C is set, for clients inside the cluster
+// that skip TLS but still want one multiplexed connection.
+func configureServer(srv *http.Server, cfg *Config, handler http.Handler) (http.Handler, error) {
+	srv.ReadHeaderTimeout = time.Duration(cfg.ReadHeaderTimeoutSeconds) * time.Second
+	srv.IdleTimeout = time.Duration(cfg.IdleTimeoutSeconds) * time.Second
+	srv.SetKeepAlivesEnabled(cfg.KeepAlive)
+	h2 := &http2.Server{
+		MaxConcurrentStreams: uint32(cfg.HTTP2MaxStreams),
+		IdleTimeout:          srv.IdleTimeout,
+	}
+	if cfg.TLSCertFile == "" {
+		if cfg.H2C {
+			// h2c connections are hijacked, Shutdown doesn't wait for their
+			// requests the way it does for HTTP/1.1
+			return h2c.NewHandler(handler, h2), nil
+		}
+		return handler, nil
+	}
+	return handler, http2.ConfigureServer(srv, h2)
+}
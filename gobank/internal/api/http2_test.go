@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newBenchServer(t testing.TB, cfg *Config) *httptest.Server {
+	srv := httptest.NewUnstartedServer(nil)
+	handler, err := configureServer(srv.Config, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"proto": r.Proto})
+	}))
+	assert.Nil(t, err)
+	srv.Config.Handler = handler
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// h2cClient speaks HTTP/2 over plain TCP, with prior knowledge.
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+func TestH2C(t *testing.T) {
+	srv := newBenchServer(t, &Config{KeepAlive: true, H2C: true, HTTP2MaxStreams: 250})
+	res, err := h2cClient().Get(srv.URL)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 2, res.ProtoMajor)
+
+	// HTTP/1.1 clients are still served
+	res, err = srv.Client().Get(srv.URL)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 1, res.ProtoMajor)
+}
+
+func TestH2CRequiresPlainHTTP(t *testing.T) {
+	t.Setenv("H2C", "true")
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "key.pem")
+	_, err := configFromEnv()
+	assert.NotNil(t, err)
+}
+
+// BenchmarkSmallRequests compares many small requests from concurrent
+// clients: a new connection per request, kept-alive HTTP/1.1 connections and
+// one multiplexed h2c connection.
+func BenchmarkSmallRequests(b *testing.B) {
+	run := func(b *testing.B, cfg *Config, client *http.Client) {
+		srv := newBenchServer(b, cfg)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				res, err := client.Get(srv.URL)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+		})
+	}
+	b.Run("http1-close", func(b *testing.B) {
+		run(b, &Config{}, &http.Client{Transport: &http.Transport{DisableKeepAlives: true}})
+	})
+	b.Run("http1-keepalive", func(b *testing.B) {
+		run(b, &Config{KeepAlive: true, IdleTimeoutSeconds: 120}, &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 64}})
+	})
+	b.Run("h2c", func(b *testing.B) {
+		run(b, &Config{KeepAlive: true, IdleTimeoutSeconds: 120, H2C: true, HTTP2MaxStreams: 250}, h2cClient())
+	})
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+// Error codes are part of the API contract, clients match on them instead of
+// the message, which changes with the language.
+const (
+	CodeBadRequest            = "bad_request"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeInvalidParameter      = "invalid_parameter"
+	CodeInvalidImport         = "invalid_import"
+	CodeInvalidImage          = "invalid_image"
+	CodeInvalidNickname       = "invalid_nickname"
+	CodeInvalidBody           = "invalid_body"
+	CodeUnreadableBody        = "unreadable_body"
+	CodePermissionDenied      = "permission_denied"
+	CodeInvalidCredentials    = "invalid_credentials"
+	CodeAccountNotFound       = "account_not_found"
+	CodeNotFound              = "not_found"
+	CodeDuplicate             = "duplicate"
+	CodePreconditionFailed    = "precondition_failed"
+	CodeInvalidAmount         = "invalid_amount"
+	CodeSameAccount           = "same_account"
+	CodeCurrencyMismatch      = "currency_mismatch"
+	CodeInsufficientFunds     = "insufficient_funds"
+	CodeTransferLimitExceeded = "transfer_limit_exceeded"
+	CodeDailyLimitExceeded    = "daily_limit_exceeded"
+	CodeUnknownCountry        = "unknown_country"
+	CodeInvalidPostalCode     = "invalid_postal_code"
+	CodeInvalidDateOfBirth    = "invalid_date_of_birth"
+	CodeUnderage              = "underage"
+	CodeKYCIncomplete         = "kyc_incomplete"
+	CodeQuoteExpired          = "quote_expired"
+	CodeHoldClosed            = "hold_closed"
+	CodeCaptureExceedsHold    = "capture_exceeds_hold"
+	CodeImpersonationInactive = "impersonation_inactive"
+	CodeTermsNotAccepted      = "terms_not_accepted"
+	CodeConsentRequired       = "consent_required"
+	CodeTransferHeld          = "transfer_held"
+	CodeScreeningFlagged      = "screening_flagged"
+	CodeTransferNotInReview   = "transfer_not_in_review"
+	CodeTransferRejected      = "transfer_rejected"
+	CodeInvalidIBAN           = "invalid_iban"
+	CodeIBANCountry           = "iban_country_not_supported"
+	CodeInvalidEmail          = "invalid_email"
+	CodeInvalidPhone          = "invalid_phone"
+	CodePhoneRequired         = "phone_required"
+	CodePhoneNotVerified      = "phone_not_verified"
+	CodeVerificationFailed    = "verification_failed"
+	CodeSMSUnavailable        = "sms_unavailable"
+	CodeInvalidDeviceToken    = "invalid_device_token"
+	CodeDuplicateTransfer     = "duplicate_transfer"
+	CodeFundsProtected        = "funds_protected"
+	CodeTermsOutdated         = "terms_outdated"
+	CodeUnknownTimeZone       = "unknown_time_zone"
+	CodeUnknownLanguage       = "unknown_language"
+	CodeUnknownLocale         = "unknown_locale"
+	CodeUnknownTenant         = "unknown_tenant"
+	CodeRateLimited           = "rate_limited"
+	CodeMaintenance           = "maintenance"
+	CodeInternal              = "internal_error"
+)
+
+// catalog holds the message templates per language. {name} placeholders are
+// filled from the error's params.
+var catalog = map[string]map[string]string{
+	"en": {
+		CodeMethodNotAllowed:      "method not allowed {method}",
+		CodeInvalidParameter:      "invalid value {value} for {name}",
+		CodeInvalidImport:         "the import file can't be read: {reason}",
+		CodeInvalidImage:          "the image can't be used: {reason}",
+		CodeInvalidNickname:       "invalid nickname {value}, use 1 to 30 characters",
+		CodeInvalidBody:           "the request body doesn't match the schema {schema}",
+		CodeUnreadableBody:        "the request body can't be read as {format}",
+		CodePermissionDenied:      "permission denied",
+		CodeInvalidCredentials:    "not authenticated",
+		CodeAccountNotFound:       "account {id} not found",
+		CodeNotFound:              "{id} not found",
+		CodeDuplicate:             "{field} already exists",
+		CodePreconditionFailed:    "the account was modified in the meantime",
+		CodeInvalidAmount:         "amount must be positive",
+		CodeSameAccount:           "cannot transfer to the same account",
+		CodeCurrencyMismatch:      "currency mismatch",
+		CodeInsufficientFunds:     "insufficient funds",
+		CodeTransferLimitExceeded: "amount exceeds the transfer limit of {limit}",
+		CodeDailyLimitExceeded:    "amount exceeds the daily transfer limit of {limit}",
+		CodeUnknownCountry:        "unknown country {value}",
+		CodeInvalidPostalCode:     "invalid postal code {value}",
+		CodeInvalidIBAN:           "invalid IBAN {value}",
+		CodeInvalidEmail:          "invalid email address {value}",
+		CodeInvalidPhone:          "invalid phone number {value}, expected international format such as +4915123456789",
+		CodePhoneRequired:         "the account has no phone number",
+		CodePhoneNotVerified:      "verify the phone number before turning on SMS alerts",
+		CodeVerificationFailed:    "the verification code is wrong or expired, request a new one if needed",
+		CodeSMSUnavailable:        "text messages are not available",
+		CodeInvalidDeviceToken:    "the token is not a valid {platform} push token",
+		CodeDuplicateTransfer:     "the same amount went to this account {minutes} minutes ago or less, send again with allowDuplicate to transfer it once more",
+		CodeFundsProtected:        "the amount would dip into the minimum balance or money put aside in pots",
+		CodeIBANCountry:           "IBANs of country {value} are not supported",
+		CodeInvalidDateOfBirth:    "invalid date of birth {value}",
+		CodeUnderage:              "account holders must be at least 18 years old",
+		CodeKYCIncomplete:         "the account profile is incomplete, missing {missing}",
+		CodeQuoteExpired:          "the quote has expired or was already executed",
+		CodeHoldClosed:            "the transfer is no longer authorized",
+		CodeCaptureExceedsHold:    "the capture exceeds the authorized amount",
+		CodeImpersonationInactive: "the impersonation is not approved or has ended",
+		CodeTermsNotAccepted:      "the current version of {documents} must be accepted first",
+		CodeConsentRequired:       "the account holder has not consented to sharing {scope}",
+		CodeTransferHeld:          "the transfer is held for review, follow it at /transfer/{id}",
+		CodeScreeningFlagged:      "the transfer was flagged by screening",
+		CodeTransferNotInReview:   "the transfer is not held for review",
+		CodeTransferRejected:      "the transfer was rejected in review",
+		CodeTermsOutdated:         "{document} version {version} is not the current one",
+		CodeUnknownTimeZone:       "unknown time zone {zone}",
+		CodeUnknownLanguage:       "unsupported language {language}",
+		CodeUnknownLocale:         "unsupported locale {locale}",
+		CodeUnknownTenant:         "unknown tenant",
+		CodeRateLimited:           "rate limit exceeded",
+		CodeMaintenance:           "service is under maintenance",
+		CodeInternal:              "internal server error",
+	},
+	"de": {
+		CodeMethodNotAllowed:      "Methode {method} nicht erlaubt",
+		CodeInvalidParameter:      "ungültiger Wert {value} für {name}",
+		CodeInvalidImport:         "die Importdatei kann nicht gelesen werden: {reason}",
+		CodeInvalidImage:          "das Bild kann nicht verwendet werden: {reason}",
+		CodeInvalidNickname:       "ungültiger Spitzname {value}, erlaubt sind 1 bis 30 Zeichen",
+		CodeInvalidBody:           "der Request-Body entspricht nicht dem Schema {schema}",
+		CodeUnreadableBody:        "der Request-Body lässt sich nicht als {format} lesen",
+		CodePermissionDenied:      "Zugriff verweigert",
+		CodeInvalidCredentials:    "nicht angemeldet",
+		CodeAccountNotFound:       "Konto {id} nicht gefunden",
+		CodeNotFound:              "{id} nicht gefunden",
+		CodeDuplicate:             "{field} existiert bereits",
+		CodePreconditionFailed:    "das Konto wurde zwischenzeitlich geändert",
+		CodeInvalidAmount:         "der Betrag muss positiv sein",
+		CodeSameAccount:           "Überweisung auf dasselbe Konto nicht möglich",
+		CodeCurrencyMismatch:      "Währungen stimmen nicht überein",
+		CodeInsufficientFunds:     "unzureichende Deckung",
+		CodeTransferLimitExceeded: "der Betrag überschreitet das Überweisungslimit von {limit}",
+		CodeDailyLimitExceeded:    "der Betrag überschreitet das Tageslimit von {limit}",
+		CodeUnknownCountry:        "unbekanntes Land {value}",
+		CodeInvalidPostalCode:     "ungültige Postleitzahl {value}",
+		CodeInvalidIBAN:           "ungültige IBAN {value}",
+		CodeInvalidEmail:          "ungültige E-Mail-Adresse {value}",
+		CodeInvalidPhone:          "ungültige Telefonnummer {value}, erwartet wird das internationale Format wie +4915123456789",
+		CodePhoneRequired:         "das Konto hat keine Telefonnummer",
+		CodePhoneNotVerified:      "bestätigen Sie die Telefonnummer, bevor Sie SMS-Benachrichtigungen einschalten",
+		CodeVerificationFailed:    "der Bestätigungscode ist falsch oder abgelaufen, fordern Sie bei Bedarf einen neuen an",
+		CodeSMSUnavailable:        "SMS sind nicht verfügbar",
+		CodeInvalidDeviceToken:    "das Token ist kein gültiges Push-Token für {platform}",
+		CodeDuplicateTransfer:     "derselbe Betrag ging vor höchstens {minutes} Minuten an dieses Konto, senden Sie erneut mit allowDuplicate, um ihn nochmals zu überweisen",
+		CodeFundsProtected:        "der Betrag würde den Mindestsaldo oder in Töpfen zurückgelegtes Geld angreifen",
+		CodeIBANCountry:           "IBANs des Landes {value} werden nicht unterstützt",
+		CodeInvalidDateOfBirth:    "ungültiges Geburtsdatum {value}",
+		CodeUnderage:              "Kontoinhaber müssen mindestens 18 Jahre alt sein",
+		CodeKYCIncomplete:         "das Kontoprofil ist unvollständig, es fehlt {missing}",
+		CodeQuoteExpired:          "das Angebot ist abgelaufen oder wurde bereits ausgeführt",
+		CodeHoldClosed:            "die Überweisung ist nicht mehr autorisiert",
+		CodeCaptureExceedsHold:    "der Betrag übersteigt den autorisierten Betrag",
+		CodeImpersonationInactive: "der Zugriff ist nicht genehmigt oder beendet",
+		CodeTermsNotAccepted:      "die aktuelle Version von {documents} muss zuerst akzeptiert werden",
+		CodeConsentRequired:       "der Kontoinhaber hat der Freigabe von {scope} nicht zugestimmt",
+		CodeTransferHeld:          "die Überweisung wird geprüft, verfolgen Sie sie unter /transfer/{id}",
+		CodeScreeningFlagged:      "die Überweisung wurde bei der Prüfung markiert",
+		CodeTransferNotInReview:   "die Überweisung wird nicht geprüft",
+		CodeTransferRejected:      "die Überweisung wurde bei der Prüfung abgelehnt",
+		CodeTermsOutdated:         "{document} Version {version} ist nicht die aktuelle",
+		CodeUnknownTimeZone:       "unbekannte Zeitzone {zone}",
+		CodeUnknownLanguage:       "nicht unterstützte Sprache {language}",
+		CodeUnknownLocale:         "nicht unterstütztes Gebietsschema {locale}",
+		CodeUnknownTenant:         "unbekannter Mandant",
+		CodeRateLimited:           "zu viele Anfragen",
+		CodeMaintenance:           "der Dienst wird gerade gewartet",
+		CodeInternal:              "interner Serverfehler",
+	},
+	"es": {
+		CodeMethodNotAllowed:      "método {method} no permitido",
+		CodeInvalidParameter:      "valor no válido {value} para {name}",
+		CodeInvalidImport:         "no se puede leer el archivo de importación: {reason}",
+		CodeInvalidImage:          "la imagen no se puede usar: {reason}",
+		CodeInvalidNickname:       "apodo no válido {value}, use de 1 a 30 caracteres",
+		CodeInvalidBody:           "el cuerpo de la solicitud no cumple el esquema {schema}",
+		CodeUnreadableBody:        "el cuerpo de la solicitud no se puede leer como {format}",
+		CodePermissionDenied:      "permiso denegado",
+		CodeInvalidCredentials:    "no autenticado",
+		CodeAccountNotFound:       "cuenta {id} no encontrada",
+		CodeNotFound:              "{id} no encontrado",
+		CodeDuplicate:             "{field} ya existe",
+		CodePreconditionFailed:    "la cuenta se ha modificado entretanto",
+		CodeInvalidAmount:         "el importe debe ser positivo",
+		CodeSameAccount:           "no se puede transferir a la misma cuenta",
+		CodeCurrencyMismatch:      "las divisas no coinciden",
+		CodeInsufficientFunds:     "fondos insuficientes",
+		CodeTransferLimitExceeded: "el importe supera el límite por transferencia de {limit}",
+		CodeDailyLimitExceeded:    "el importe supera el límite diario de {limit}",
+		CodeUnknownCountry:        "país desconocido {value}",
+		CodeInvalidPostalCode:     "código postal no válido {value}",
+		CodeInvalidIBAN:           "IBAN no válido {value}",
+		CodeInvalidEmail:          "dirección de correo electrónico no válida {value}",
+		CodeInvalidPhone:          "número de teléfono no válido {value}, se espera el formato internacional como +4915123456789",
+		CodePhoneRequired:         "la cuenta no tiene número de teléfono",
+		CodePhoneNotVerified:      "verifique el número de teléfono antes de activar las alertas por SMS",
+		CodeVerificationFailed:    "el código de verificación es incorrecto o ha caducado, solicite uno nuevo si es necesario",
+		CodeSMSUnavailable:        "los mensajes de texto no están disponibles",
+		CodeInvalidDeviceToken:    "el token no es un token push válido de {platform}",
+		CodeDuplicateTransfer:     "el mismo importe se envió a esta cuenta hace {minutes} minutos o menos, envíe de nuevo con allowDuplicate para transferirlo otra vez",
+		CodeFundsProtected:        "el importe tocaría el saldo mínimo o el dinero apartado en huchas",
+		CodeIBANCountry:           "no se admiten IBAN del país {value}",
+		CodeInvalidDateOfBirth:    "fecha de nacimiento no válida {value}",
+		CodeUnderage:              "los titulares deben tener al menos 18 años",
+		CodeKYCIncomplete:         "el perfil de la cuenta está incompleto, falta {missing}",
+		CodeQuoteExpired:          "la cotización ha caducado o ya se ejecutó",
+		CodeHoldClosed:            "la transferencia ya no está autorizada",
+		CodeCaptureExceedsHold:    "el importe supera el importe autorizado",
+		CodeImpersonationInactive: "el acceso no está aprobado o ha terminado",
+		CodeTermsNotAccepted:      "primero debe aceptarse la versión actual de {documents}",
+		CodeConsentRequired:       "el titular de la cuenta no ha consentido compartir {scope}",
+		CodeTransferHeld:          "la transferencia está retenida para revisión, sígala en /transfer/{id}",
+		CodeScreeningFlagged:      "la transferencia fue marcada en la verificación",
+		CodeTransferNotInReview:   "la transferencia no está retenida para revisión",
+		CodeTransferRejected:      "la transferencia fue rechazada en la revisión",
+		CodeTermsOutdated:         "la versión {version} de {document} no es la actual",
+		CodeUnknownTimeZone:       "zona horaria desconocida {zone}",
+		CodeUnknownLanguage:       "idioma no soportado {language}",
+		CodeUnknownLocale:         "configuración regional no admitida {locale}",
+		CodeUnknownTenant:         "inquilino desconocido",
+		CodeRateLimited:           "límite de solicitudes superado",
+		CodeMaintenance:           "el servicio está en mantenimiento",
+		CodeInternal:              "error interno del servidor",
+	},
+	"fr": {
+		CodeMethodNotAllowed:      "méthode {method} non autorisée",
+		CodeInvalidParameter:      "valeur invalide {value} pour {name}",
+		CodeInvalidImport:         "le fichier d'import est illisible : {reason}",
+		CodeInvalidImage:          "l'image est inutilisable : {reason}",
+		CodeInvalidNickname:       "pseudonyme invalide {value}, utilisez 1 à 30 caractères",
+		CodeInvalidBody:           "le corps de la requête ne respecte pas le schéma {schema}",
+		CodeUnreadableBody:        "le corps de la requête ne peut pas être lu comme {format}",
+		CodePermissionDenied:      "accès refusé",
+		CodeInvalidCredentials:    "non authentifié",
+		CodeAccountNotFound:       "compte {id} introuvable",
+		CodeNotFound:              "{id} introuvable",
+		CodeDuplicate:             "{field} existe déjà",
+		CodePreconditionFailed:    "le compte a été modifié entre-temps",
+		CodeInvalidAmount:         "le montant doit être positif",
+		CodeSameAccount:           "impossible de virer sur le même compte",
+		CodeCurrencyMismatch:      "les devises ne correspondent pas",
+		CodeInsufficientFunds:     "fonds insuffisants",
+		CodeTransferLimitExceeded: "le montant dépasse la limite par virement de {limit}",
+		CodeDailyLimitExceeded:    "le montant dépasse la limite journalière de {limit}",
+		CodeUnknownCountry:        "pays inconnu {value}",
+		CodeInvalidPostalCode:     "code postal invalide {value}",
+		CodeInvalidIBAN:           "IBAN invalide {value}",
+		CodeInvalidEmail:          "adresse e-mail invalide {value}",
+		CodeInvalidPhone:          "numéro de téléphone invalide {value}, format international attendu comme +4915123456789",
+		CodePhoneRequired:         "le compte n'a pas de numéro de téléphone",
+		CodePhoneNotVerified:      "vérifiez le numéro de téléphone avant d'activer les alertes SMS",
+		CodeVerificationFailed:    "le code de vérification est erroné ou expiré, demandez-en un nouveau si nécessaire",
+		CodeSMSUnavailable:        "les SMS ne sont pas disponibles",
+		CodeInvalidDeviceToken:    "le jeton n'est pas un jeton push {platform} valide",
+		CodeDuplicateTransfer:     "le même montant a été envoyé à ce compte il y a {minutes} minutes ou moins, renvoyez avec allowDuplicate pour le virer à nouveau",
+		CodeFundsProtected:        "le montant entamerait le solde minimum ou l'argent mis de côté dans des cagnottes",
+		CodeIBANCountry:           "les IBAN du pays {value} ne sont pas pris en charge",
+		CodeInvalidDateOfBirth:    "date de naissance invalide {value}",
+		CodeUnderage:              "les titulaires doivent avoir au moins 18 ans",
+		CodeKYCIncomplete:         "le profil du compte est incomplet, il manque {missing}",
+		CodeQuoteExpired:          "le devis a expiré ou a déjà été exécuté",
+		CodeHoldClosed:            "le virement n'est plus autorisé",
+		CodeCaptureExceedsHold:    "le montant dépasse le montant autorisé",
+		CodeImpersonationInactive: "l'accès n'est pas approuvé ou a pris fin",
+		CodeTermsNotAccepted:      "la version actuelle de {documents} doit d'abord être acceptée",
+		CodeConsentRequired:       "le titulaire du compte n'a pas consenti au partage de {scope}",
+		CodeTransferHeld:          "le virement est retenu pour examen, suivez-le sur /transfer/{id}",
+		CodeScreeningFlagged:      "le virement a été signalé lors du contrôle",
+		CodeTransferNotInReview:   "le virement n'est pas retenu pour examen",
+		CodeTransferRejected:      "le virement a été refusé lors de l'examen",
+		CodeTermsOutdated:         "la version {version} de {document} n'est pas la version actuelle",
+		CodeUnknownTimeZone:       "fuseau horaire inconnu {zone}",
+		CodeUnknownLanguage:       "langue non prise en charge {language}",
+		CodeUnknownLocale:         "paramètres régionaux non pris en charge {locale}",
+		CodeUnknownTenant:         "locataire inconnu",
+		CodeRateLimited:           "limite de requêtes dépassée",
+		CodeMaintenance:           "le service est en maintenance",
+		CodeInternal:              "erreur interne du serveur",
+	},
+}
+
+// Error is an error the API reports with a stable code and a message looked
+// up in the catalog. Error() renders it in the default language for logs.
+type Error struct {
+	Code   string
+	Params map[string]any
+}
+
+// NewError builds an Error from a code and alternating param names and values.
+func NewError(code string, params ...any) *Error {
+	e := &Error{Code: code, Params: map[string]any{}}
+	for i := 0; i+1 < len(params); i += 2 {
+		e.Params[fmt.Sprint(params[i])] = params[i+1]
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Localize(DefaultLanguage)
+}
+
+func (e *Error) Localize(lang string) string {
+	return localize(lang, e.Code, e.Params)
+}
+
+func localize(lang, code string, params map[string]any) string {
+	return lookupMessage(catalog, lang, code, params)
+}
+
+// lookupMessage fills in the template of code in lang, or in
+// DefaultLanguage if lang lacks it. An unknown code comes back as it is.
+func lookupMessage(catalog map[string]map[string]string, lang, code string, params map[string]any) string {
+	msg, ok := catalog[lang][code]
+	if !ok {
+		msg, ok = catalog[DefaultLanguage][code]
+	}
+	if !ok {
+		return code
+	}
+	for name, v := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", fmt.Sprint(v))
+	}
+	return msg
+}
+
+func isSupportedLanguage(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// parseAcceptLanguage returns the supported language the client prefers most,
+// or "" when none of the listed languages is supported.
+func parseAcceptLanguage(header string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// only the primary subtag matters, "de-AT" is served in "de"
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && isSupportedLanguage(lang) {
+			choices = append(choices, choice{lang, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	if len(choices) == 0 {
+		return ""
+	}
+	return choices[0].lang
+}
+
+type localeKey struct{}
+
+// locale is shared by the handlers of a request so that an account resolved
+// deep inside a handler can still pick the language of its error response.
+type locale struct {
+	accepted string
+	account  string
+}
+
+func (s *APIServer) withLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := &locale{accepted: parseAcceptLanguage(r.Header.Get("Accept-Language"))}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, l)))
+	})
+}
+
+// setAccountLanguage records the language preference of the authenticated account.
+func setAccountLanguage(r *http.Request, lang string) {
+	if l, ok := r.Context().Value(localeKey{}).(*locale); ok {
+		l.account = lang
+	}
+}
+
+// languageFor picks the account's preference, then Accept-Language, then the default.
+func languageFor(r *http.Request) string {
+	l, ok := r.Context().Value(localeKey{}).(*locale)
+	if !ok {
+		l = &locale{accepted: parseAcceptLanguage(r.Header.Get("Accept-Language"))}
+	}
+	if l.account != "" {
+		return l.account
+	}
+	if l.accepted != "" {
+		return l.accepted
+	}
+	return DefaultLanguage
+}
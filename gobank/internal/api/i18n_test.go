@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, "de", parseAcceptLanguage("de-AT,de;q=0.9,en;q=0.8"))
+	assert.Equal(t, "fr", parseAcceptLanguage("en;q=0.5, fr"))
+	assert.Equal(t, "es", parseAcceptLanguage("ja, es;q=0.3"))
+	assert.Equal(t, "", parseAcceptLanguage("ja, zh-CN"))
+	assert.Equal(t, "", parseAcceptLanguage(""))
+}
+
+func TestErrorLocalize(t *testing.T) {
+	err := NewError(CodeAccountNotFound, "id", 42)
+	assert.Equal(t, "account 42 not found", err.Error())
+	assert.Equal(t, "Konto 42 nicht gefunden", err.Localize("de"))
+	// unknown languages fall back to English
+	assert.Equal(t, "account 42 not found", err.Localize("ja"))
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ImpersonateRequest struct {
+	RequestedBy string `json:"requestedBy"`
+	Reason      string `json:"reason"`
+	// Minutes defaults to domain.DefaultImpersonationTTL.
+	Minutes         int  `json:"minutes"`
+	RequireApproval bool `json:"requireApproval"`
+}
+
+// ImpersonationResponse carries the token once the impersonation is
+// approved, it isn't stored anywhere.
+type ImpersonationResponse struct {
+	*domain.Impersonation
+	Token domain.Secret `json:"token,omitempty"`
+}
+
+// handleImpersonate serves POST /admin/accounts/{id}/impersonations?tenant=.
+// Without RequireApproval the answer has the token right away, otherwise
+// it's fetched from /admin/impersonations/{id}/token once the holder
+// approved.
+func (s *APIServer) handleImpersonate(w http.ResponseWriter, r *http.Request) error {
+	ref, err := parseAccountRef(r)
+	if err != nil {
+		return err
+	}
+	req := new(ImpersonateRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if req.RequestedBy == "" {
+		return invalidParameter("requestedBy", req.RequestedBy)
+	}
+	if req.Reason == "" {
+		return invalidParameter("reason", req.Reason)
+	}
+	ttl := domain.DefaultImpersonationTTL
+	if req.Minutes != 0 {
+		ttl = time.Duration(req.Minutes) * time.Minute
+	}
+	if ttl <= 0 || ttl > domain.MaxImpersonationTTL {
+		return invalidParameter("minutes", strconv.Itoa(req.Minutes))
+	}
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	account, err := ref.lookup(store)
+	if err != nil {
+		return err
+	}
+	imp := domain.NewImpersonation(account.ID, req.RequestedBy, req.Reason, ttl, req.RequireApproval, s.clock.Now().UTC())
+	if err := store.CreateImpersonation(imp); err != nil {
+		return err
+	}
+	s.logger.Info("impersonation requested", "impersonation_id", imp.ID, "account_id", account.ID, "requested_by", imp.RequestedBy)
+	res := &ImpersonationResponse{Impersonation: imp}
+	if imp.Status == domain.ImpersonationApproved {
+		if res.Token, err = s.issueImpersonationToken(store, account, imp); err != nil {
+			return err
+		}
+	}
+	return WriteJSON(w, http.StatusCreated, res)
+}
+
+func (s *APIServer) handleImpersonationToken(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	imp, err := store.GetImpersonation(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if !imp.Active(s.clock.Now()) {
+		return domain.ErrImpersonationInactive
+	}
+	account, err := store.GetAccountById(imp.AccountID)
+	if err != nil {
+		return err
+	}
+	token, err := s.issueImpersonationToken(store, account, imp)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, &ImpersonationResponse{Impersonation: imp, Token: token})
+}
+
+func (s *APIServer) issueImpersonationToken(store storage.ImpersonationStore, account *domain.Account, imp *domain.Impersonation) (domain.Secret, error) {
+	token, err := auth.CreateImpersonationJWT(account, imp)
+	if err != nil {
+		return "", err
+	}
+	if err := store.RecordImpersonationEvent(imp, domain.EventImpersonationIssued, nil); err != nil {
+		return "", err
+	}
+	return domain.Secret(token), nil
+}
+
+func (s *APIServer) handleRevokeImpersonation(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	imp, err := store.RevokeImpersonation(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, imp)
+}
+
+// handleImpersonations lets the holder see who asked for access to the
+// account and why.
+func (s *APIServer) handleImpersonations(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	imps, err := s.storeFor(r).ListImpersonations(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, imps)
+}
+
+func (s *APIServer) handleApproveImpersonation(w http.ResponseWriter, r *http.Request) error {
+	return s.decideImpersonation(w, r, true)
+}
+
+func (s *APIServer) handleDenyImpersonation(w http.ResponseWriter, r *http.Request) error {
+	return s.decideImpersonation(w, r, false)
+}
+
+func (s *APIServer) decideImpersonation(w http.ResponseWriter, r *http.Request, approve bool) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	imp, err := s.storeFor(r).DecideImpersonation(id, r.PathValue("impersonationId"), approve)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, imp)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const importMaxBytes = 10 << 20
+
+// ImportRowError points at a row of the uploaded file, counting from 1.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
+
+// importRow is a parsed but unvalidated line of an import file.
+type importRow struct {
+	row         int
+	date        string
+	amount      string
+	currency    string
+	description string
+}
+
+// handleImportTransactions serves POST /account/{id}/transactions/import with
+// a text/csv or application/x-ofx body. Nothing is imported unless every row
+// is valid; the errors are reported per row with 422. The rows are history
+// from another bank, so they're recorded without moving the balance.
+func (s *APIServer) handleImportTransactions(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+
+	body := http.MaxBytesReader(w, r.Body, importMaxBytes)
+	defer body.Close()
+	var rows []importRow
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		rows, err = parseImportCSV(body)
+	case "application/x-ofx", "application/ofx":
+		rows, err = parseImportOFX(body)
+	default:
+		return NewError(CodeInvalidParameter, "name", "Content-Type", "value", mediaType)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := ImportResult{}
+	txs := make([]*domain.Transaction, 0, len(rows))
+	for _, row := range rows {
+		t, err := row.transaction(account, s.clock.Now())
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Row: row.row, Error: err.Error()})
+			continue
+		}
+		txs = append(txs, t)
+	}
+	if len(result.Errors) > 0 {
+		return WriteJSON(w, http.StatusUnprocessableEntity, result)
+	}
+	if err := store.ImportTransactions(account.ID, txs); err != nil {
+		return err
+	}
+	result.Imported = len(txs)
+	loggerFrom(r.Context()).Info("transactions imported", "count", result.Imported)
+	s.notifier.Notify()
+	return WriteJSON(w, http.StatusOK, result)
+}
+
+func (row importRow) transaction(account *domain.Account, now time.Time) (*domain.Transaction, error) {
+	currency := strings.ToUpper(row.currency)
+	if currency == "" {
+		currency = account.Balance.Currency
+	}
+	if currency != account.Balance.Currency {
+		return nil, fmt.Errorf("currency %s doesn't match the account currency %s", currency, account.Balance.Currency)
+	}
+	units, err := domain.ParseDecimal(strings.TrimPrefix(row.amount, "+"), currency)
+	if err != nil {
+		return nil, err
+	}
+	if units == 0 {
+		return nil, fmt.Errorf("amount can't be zero")
+	}
+	date, err := parseImportDate(row.date)
+	if err != nil {
+		return nil, err
+	}
+	if date.After(now) {
+		return nil, fmt.Errorf("date %s is in the future", row.date)
+	}
+	if len(row.description) > 255 {
+		return nil, fmt.Errorf("description is longer than 255 characters")
+	}
+	return &domain.Transaction{
+		AccountID:   account.ID,
+		TenantID:    account.TenantID,
+		Type:        domain.TransactionHistory,
+		Amount:      domain.Money{MinorUnits: units, Currency: currency},
+		Description: row.description,
+		CreatedAt:   date,
+	}, nil
+}
+
+var importDateLayouts = []string{time.RFC3339, "2006-01-02", "20060102150405", "20060102"}
+
+func parseImportDate(v string) (time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %s", v)
+}
+
+// parseImportCSV reads a CSV file with a header row. date and amount columns
+// are required, description and currency are optional, others are ignored.
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, NewError(CodeInvalidImport, "reason", "missing header row")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, NewError(CodeInvalidImport, "reason", "missing column "+required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, NewError(CodeInvalidImport, "reason", err.Error())
+		}
+		rows = append(rows, importRow{
+			row:         line,
+			date:        field(record, "date"),
+			amount:      field(record, "amount"),
+			currency:    field(record, "currency"),
+			description: field(record, "description"),
+		})
+	}
+}
+
+var (
+	ofxTransactionRe = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxFieldRe       = regexp.MustCompile(`(?i)<(\w+)>([^<\r\n]*)`)
+	ofxCurrencyRe    = regexp.MustCompile(`(?i)<CURDEF>\s*(\w{3})`)
+)
+
+// parseImportOFX pulls the STMTTRN entries out of an OFX statement. It reads
+// both the SGML (1.x) and the XML (2.x) flavour, where closing tags of leaf
+// elements are optional.
+func parseImportOFX(r io.Reader) ([]importRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	currency := ""
+	if m := ofxCurrencyRe.FindSubmatch(data); m != nil {
+		currency = string(m[1])
+	}
+	var rows []importRow
+	for i, m := range ofxTransactionRe.FindAllSubmatch(data, -1) {
+		fields := map[string]string{}
+		for _, f := range ofxFieldRe.FindAllSubmatch(m[1], -1) {
+			fields[strings.ToUpper(string(f[1]))] = html.UnescapeString(strings.TrimSpace(string(f[2])))
+		}
+		description := fields["NAME"]
+		if memo := fields["MEMO"]; memo != "" {
+			description = strings.TrimSpace(description + " " + memo)
+		}
+		rows = append(rows, importRow{
+			row:         i + 1,
+			date:        ofxDate(fields["DTPOSTED"]),
+			amount:      fields["TRNAMT"],
+			currency:    currency,
+			description: description,
+		})
+	}
+	if len(rows) == 0 {
+		return nil, NewError(CodeInvalidImport, "reason", "no transactions found")
+	}
+	return rows, nil
+}
+
+// ofxDate drops the fractional seconds and [offset:TZ] suffix OFX allows,
+// treating the timestamp as UTC.
+func ofxDate(v string) string {
+	if i := strings.IndexAny(v, ".["); i >= 0 {
+		v = v[:i]
+	}
+	return v
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	rows, err := parseImportCSV(strings.NewReader("Date,Amount,Description\n2024-01-05,-12.50,Coffee\n2024-01-06,1000,Salary\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []importRow{
+		{row: 2, date: "2024-01-05", amount: "-12.50", description: "Coffee"},
+		{row: 3, date: "2024-01-06", amount: "1000", description: "Salary"},
+	}, rows)
+
+	_, err = parseImportCSV(strings.NewReader("when,amount\n"))
+	assert.NotNil(t, err)
+}
+
+func TestParseImportOFX(t *testing.T) {
+	ofx := `OFXHEADER:100
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240105120000.000[-5:EST]<TRNAMT>-12.50<FITID>1<NAME>Coffee</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240106<TRNAMT>+1000.00<FITID>2<NAME>ACME<MEMO>Salary</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	rows, err := parseImportOFX(strings.NewReader(ofx))
+	assert.Nil(t, err)
+	assert.Equal(t, []importRow{
+		{row: 1, date: "20240105120000", amount: "-12.50", currency: "EUR", description: "Coffee"},
+		{row: 2, date: "20240106", amount: "+1000.00", currency: "EUR", description: "ACME Salary"},
+	}, rows)
+}
+
+func TestImportRowValidation(t *testing.T) {
+	account := &domain.Account{ID: 1, Balance: domain.Money{Currency: "EUR"}}
+	tx, err := importRow{date: "20240106", amount: "+1000.00", currency: "EUR"}.transaction(account, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, domain.Money{MinorUnits: 100000, Currency: "EUR"}, tx.Amount)
+	// imported history doesn't post
+	assert.False(t, tx.Posted())
+	assert.Equal(t, time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), tx.CreatedAt)
+
+	for _, row := range []importRow{
+		{date: "2024-01-06", amount: "1", currency: "USD"},
+		{date: "2024-01-06", amount: "1.234"},
+		{date: "2024-01-06", amount: "0"},
+		{date: "06/01/2024", amount: "1"},
+		{date: time.Now().AddDate(1, 0, 0).Format("2006-01-02"), amount: "1"},
+	} {
+		_, err := row.transaction(account, time.Now())
+		assert.NotNil(t, err, row)
+	}
+}
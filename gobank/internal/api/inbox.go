@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// InboxNotifier adds the notifications of the in-app inbox, from the same
+// events and in the same words as the emails and push notifications. Their
+// dedupe keys make events relayed more than once add theirs once.
+type InboxNotifier struct {
+	store    storage.Storage
+	settings *TenantSettingsCache
+	logger   *slog.Logger
+}
+
+func NewInboxNotifier(store storage.Storage, logger *slog.Logger) *InboxNotifier {
+	return &InboxNotifier{store: store, settings: NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings), logger: logger}
+}
+
+// Notify is subscribed to the bus.
+func (n *InboxNotifier) Notify(ev *domain.Event) {
+	var err error
+	switch ev.Type {
+	case domain.EventAccountCreated:
+		err = n.welcome(ev)
+	case domain.EventTransferCompleted:
+		err = n.transfers(ev)
+	case domain.EventAccountLogin:
+		err = n.login(ev)
+	case domain.EventStatementReady:
+		err = n.statementReady(ev)
+	}
+	if err != nil {
+		n.logger.Error("adding notification failed", "event_id", ev.ID, "event_type", ev.Type, "error", err)
+	}
+}
+
+func (n *InboxNotifier) welcome(ev *domain.Event) error {
+	account, err := n.store.FindAccount(ev.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings, err := n.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	data := welcomeNotice{Account: account, Brand: settings.BrandName}
+	title, err := renderText(account, "welcome.subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "inbox.welcome.body", data)
+	if err != nil {
+		return err
+	}
+	return n.add(account, domain.NotificationWelcome, fmt.Sprintf("welcome:%d", account.ID), title, body, nil)
+}
+
+func (n *InboxNotifier) transfers(ev *domain.Event) error {
+	var payload struct {
+		TransactionID int `json:"transactionId"`
+	}
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		return err
+	}
+	out, in, err := n.store.TransferLegs(payload.TransactionID)
+	if errors.Is(err, domain.ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, tx := range []*domain.Transaction{out, in} {
+		if tx == nil {
+			continue
+		}
+		account, err := n.store.FindAccount(tx.AccountID)
+		if errors.Is(err, domain.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		title, _, ok, err := renderTransferNotice(account, tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		name := "push.transfer_received"
+		if tx.Type == domain.TransactionTransferOut {
+			name = "push.transfer_sent"
+		}
+		body, err := renderText(account, name, transferNotice{Account: account, Transaction: tx})
+		if err != nil {
+			return err
+		}
+		data := map[string]string{"transactionId": strconv.Itoa(tx.ID)}
+		if err := n.add(account, domain.NotificationTransfer, fmt.Sprintf("transfer:%d", tx.ID), title, body, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *InboxNotifier) login(ev *domain.Event) error {
+	account, err := n.store.FindAccount(ev.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings, err := n.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	data := loginNotice{Brand: settings.BrandName, Time: ev.CreatedAt}
+	title, err := renderText(account, "push.login.title", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "push.login.body", data)
+	if err != nil {
+		return err
+	}
+	return n.add(account, domain.NotificationLogin, fmt.Sprintf("login:%d", ev.ID), title, body, nil)
+}
+
+func (n *InboxNotifier) statementReady(ev *domain.Event) error {
+	account, day, err := statementOf(n.store, ev)
+	if account == nil || err != nil {
+		return err
+	}
+	data := statementNotice{Account: account, Day: day}
+	title, err := renderText(account, "statement_ready.subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "inbox.statement_ready.body", data)
+	if err != nil {
+		return err
+	}
+	date := day.Format("2006-01-02")
+	key := fmt.Sprintf("statement_ready:%d:%s", account.ID, date)
+	return n.add(account, domain.NotificationStatementReady, key, title, body, map[string]string{"date": date})
+}
+
+func (n *InboxNotifier) add(account *domain.Account, kind, key, title, body string, data map[string]string) error {
+	return n.store.ForTenant(account.TenantID).AddNotification(domain.NewNotification(account.ID, kind, key, title, body, data))
+}
+
+// NotificationsPage is a page of the inbox with the number of notifications
+// that are still unread, of the whole inbox.
+type NotificationsPage struct {
+	Unread int `json:"unread"`
+	Page[*domain.Notification]
+}
+
+// handleNotifications serves GET /account/{id}/notifications, newest first.
+// ?unread=true leaves out the ones that were read.
+func (s *APIServer) handleNotifications(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	unread, err := QueryEnum(r, "unread", "false", "true", "false")
+	if err != nil {
+		return err
+	}
+	var before int64
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &before); err != nil {
+			return err
+		}
+	}
+	store := s.storeFor(r)
+	notifications, err := store.NotificationsBefore(id, before, unread == "true", limit+1)
+	if err != nil {
+		return err
+	}
+	count, err := store.UnreadNotifications(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, NotificationsPage{
+		Unread: count,
+		Page:   NewPage(notifications, limit, func(n *domain.Notification) []any { return []any{n.ID} }),
+	})
+}
+
+func (s *APIServer) handleReadNotification(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	notificationID, err := PathInt(r, "notificationId")
+	if err != nil {
+		return err
+	}
+	notification, err := s.storeFor(r).MarkNotificationRead(id, int64(notificationID))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, notification)
+}
+
+func (s *APIServer) handleReadAllNotifications(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	n, err := s.storeFor(r).MarkAllNotificationsRead(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]int64{"read": n})
+}
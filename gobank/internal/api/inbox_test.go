@@ -0,0 +1,20 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInboxTemplates(t *testing.T) {
+	account := &domain.Account{FirstName: "Jana", Number: 4711007, Timezone: "Europe/Berlin", Language: "de"}
+
+	body, err := renderText(account, "inbox.statement_ready.body", statementNotice{Account: account, Day: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)})
+	assert.Nil(t, err)
+	assert.Equal(t, "Der Kontoauszug Ihres Kontos vom 02.03.2024 steht in der App und über die API zum Download bereit.", body)
+
+	body, err = renderText(account, "inbox.welcome.body", welcomeNotice{Account: account, Brand: "GoBank"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Ihr Konto ****1007 ist eröffnet. Sie können jetzt Geld empfangen und an andere Konten überweisen.", body)
+}
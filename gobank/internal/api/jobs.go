@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type JobHandler func(job *domain.Job) error
+
+// WorkerPool runs persisted jobs with a fixed number of workers. Jobs are
+// leased so a crashed worker's job becomes runnable again once its lease
+// expires, failed jobs are retried with backoff and end up dead after
+// MaxAttempts.
+type WorkerPool struct {
+	store    storage.JobStore
+	clock    domain.Clock
+	workers  int
+	lease    time.Duration
+	poll     time.Duration
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	logger   *slog.Logger
+}
+
+func NewWorkerPool(store storage.JobStore, clock domain.Clock, workers int, logger *slog.Logger) *WorkerPool {
+	return &WorkerPool{
+		store:    store,
+		clock:    clock,
+		logger:   logger,
+		workers:  workers,
+		lease:    5 * time.Minute,
+		poll:     time.Second,
+		handlers: map[string]JobHandler{},
+	}
+}
+
+func (p *WorkerPool) Register(jobType string, h JobHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[jobType] = h
+}
+
+// Every enqueues a job of the given type on a fixed interval.
+func (p *WorkerPool) Every(interval time.Duration, jobType string, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := domain.NewJob(jobType, struct{}{}, p.clock.Now())
+		if err == nil {
+			err = p.store.EnqueueJob(job)
+		}
+		if err != nil {
+			p.logger.Error("scheduling job failed", "job_type", jobType, "error", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *WorkerPool) Run(stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(stop)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *WorkerPool) work(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		job, err := p.store.LeaseJob(p.lease)
+		if err != nil {
+			p.logger.Error("leasing job failed", "error", err)
+		}
+		if job == nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(p.poll):
+			}
+			continue
+		}
+		p.execute(job)
+	}
+}
+
+func (p *WorkerPool) execute(job *domain.Job) {
+	p.mu.RLock()
+	h, ok := p.handlers[job.Type]
+	p.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type %s", job.Type)
+	} else {
+		err = runJob(h, job)
+	}
+	logger := p.logger.With("job_id", job.ID, "job_type", job.Type, "attempt", job.Attempts)
+	if err == nil {
+		if err := p.store.CompleteJob(job.ID, job.Attempts); err != nil {
+			finishFailed(logger, "completing job failed", err)
+		}
+		return
+	}
+
+	dead := job.Attempts >= job.MaxAttempts
+	logger.Warn("job failed", "error", err, "dead", dead)
+	retryAt := p.clock.Now().UTC().Add(backoff(job.Attempts))
+	if err := p.store.FailJob(job.ID, job.Attempts, err.Error(), retryAt, dead); err != nil {
+		finishFailed(logger, "failing job failed", err)
+	}
+}
+
+// finishFailed logs a job that couldn't be marked done or failed. A lost
+// lease happens when a job outruns it, the worker that leased it since
+// finishes it.
+func finishFailed(logger *slog.Logger, msg string, err error) {
+	if errors.Is(err, domain.ErrJobLeaseLost) {
+		logger.Warn(msg, "error", err)
+		return
+	}
+	logger.Error(msg, "error", err)
+}
+
+func runJob(h JobHandler, job *domain.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(job)
+}
+
+func backoff(attempts int) time.Duration {
+	return time.Duration(attempts*attempts) * 10 * time.Second
+}
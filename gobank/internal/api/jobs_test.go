@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeJobStore struct {
+	storage.JobStore
+	completed []int
+	failed    map[int]bool
+	// leased is the attempt a job was leased again with, finishing an
+	// earlier one fails
+	leased map[int]int
+}
+
+func (f *fakeJobStore) CompleteJob(id, attempt int) error {
+	if f.leased[id] > attempt {
+		return domain.ErrJobLeaseLost
+	}
+	f.completed = append(f.completed, id)
+	return nil
+}
+
+func (f *fakeJobStore) FailJob(id, attempt int, reason string, retryAt time.Time, dead bool) error {
+	if f.leased[id] > attempt {
+		return domain.ErrJobLeaseLost
+	}
+	f.failed[id] = dead
+	return nil
+}
+
+func TestWorkerPoolExecute(t *testing.T) {
+	store := &fakeJobStore{failed: map[int]bool{}}
+	pool := NewWorkerPool(store, domain.SystemClock{}, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.Register("ok", func(job *domain.Job) error { return nil })
+	pool.Register("boom", func(job *domain.Job) error { panic("boom") })
+	pool.Register("fail", func(job *domain.Job) error { return fmt.Errorf("failed") })
+
+	pool.execute(&domain.Job{ID: 1, Type: "ok", Attempts: 1, MaxAttempts: 5})
+	pool.execute(&domain.Job{ID: 2, Type: "boom", Attempts: 1, MaxAttempts: 5})
+	pool.execute(&domain.Job{ID: 3, Type: "fail", Attempts: 5, MaxAttempts: 5})
+	pool.execute(&domain.Job{ID: 4, Type: "unknown", Attempts: 1, MaxAttempts: 5})
+
+	assert.Equal(t, []int{1}, store.completed)
+	assert.Equal(t, map[int]bool{2: false, 3: true, 4: false}, store.failed)
+}
+
+func TestWorkerPoolLostLease(t *testing.T) {
+	// jobs 1 and 2 outran their lease and were leased again with attempt 2
+	store := &fakeJobStore{failed: map[int]bool{}, leased: map[int]int{1: 2, 2: 2}}
+	pool := NewWorkerPool(store, domain.SystemClock{}, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.Register("ok", func(job *domain.Job) error { return nil })
+	pool.Register("fail", func(job *domain.Job) error { return fmt.Errorf("failed") })
+
+	pool.execute(&domain.Job{ID: 1, Type: "ok", Attempts: 1, MaxAttempts: 5})
+	pool.execute(&domain.Job{ID: 2, Type: "fail", Attempts: 1, MaxAttempts: 5})
+	assert.Empty(t, store.completed)
+	assert.Empty(t, store.failed)
+
+	// the worker holding the lease now finishes them
+	pool.execute(&domain.Job{ID: 1, Type: "ok", Attempts: 2, MaxAttempts: 5})
+	pool.execute(&domain.Job{ID: 2, Type: "fail", Attempts: 2, MaxAttempts: 5})
+	assert.Equal(t, []int{1}, store.completed)
+	assert.Equal(t, map[int]bool{2: false}, store.failed)
+}
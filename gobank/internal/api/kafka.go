@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"time"
+)
+
+// EventAvroSchema is the Avro schema used when KAFKA_FORMAT=avro.
+const EventAvroSchema = `{"type":"record","name":"Event","namespace":"gobank","fields":[` +
+	`{"name":"id","type":"long"},` +
+	`{"name":"type","type":"string"},` +
+	`{"name":"accountId","type":"int"},` +
+	`{"name":"payload","type":"string"},` +
+	`{"name":"createdAt","type":{"type":"long","logicalType":"timestamp-millis"}}]}`
+
+// KafkaPublisher streams events to a Kafka topic keyed by account id so that
+// every account's events stay ordered within a partition.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+	format string
+}
+
+func NewKafkaPublisher(brokers []string, topic, format string) (*KafkaPublisher, error) {
+	if format != "json" && format != "avro" {
+		return nil, fmt.Errorf("unsupported kafka format %s", format)
+	}
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+		format: format,
+	}, nil
+}
+
+func (p *KafkaPublisher) Publish(ev *domain.Event) error {
+	value, err := p.encode(ev)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(strconv.Itoa(ev.AccountID)),
+		Value: value,
+		Time:  ev.CreatedAt,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(strconv.FormatInt(ev.ID, 10))},
+			{Key: "event-type", Value: []byte(ev.Type)},
+			{Key: "content-type", Value: []byte(p.contentType())},
+		},
+	})
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+func (p *KafkaPublisher) contentType() string {
+	if p.format == "avro" {
+		return "avro/binary"
+	}
+	return "application/json"
+}
+
+func (p *KafkaPublisher) encode(ev *domain.Event) ([]byte, error) {
+	if p.format == "avro" {
+		return encodeEventAvro(ev), nil
+	}
+	return json.Marshal(ev)
+}
+
+// encodeEventAvro writes ev in Avro binary encoding for EventAvroSchema.
+// Avro longs and ints are zig-zag varints, which is what binary.AppendVarint
+// produces, and strings are length prefixed.
+func encodeEventAvro(ev *domain.Event) []byte {
+	buf := make([]byte, 0, 64+len(ev.Payload))
+	buf = binary.AppendVarint(buf, ev.ID)
+	buf = appendAvroString(buf, ev.Type)
+	buf = binary.AppendVarint(buf, int64(ev.AccountID))
+	buf = appendAvroString(buf, string(ev.Payload))
+	buf = binary.AppendVarint(buf, ev.CreatedAt.UnixMilli())
+	return buf
+}
+
+func appendAvroString(buf []byte, s string) []byte {
+	buf = binary.AppendVarint(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+// MultiPublisher publishes every event to all of its publishers, failing if
+// any of them fails so the outbox relay retries the event.
+type MultiPublisher []EventPublisher
+
+func (m MultiPublisher) Publish(ev *domain.Event) error {
+	for _, p := range m {
+		if err := p.Publish(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEncodeEventAvro(t *testing.T) {
+	ev := &domain.Event{
+		ID:        1,
+		Type:      "a",
+		AccountID: -1,
+		Payload:   []byte("{}"),
+		CreatedAt: time.UnixMilli(2),
+	}
+	// zig-zag: 1 -> 2, -1 -> 1, 2 -> 4; strings are length prefixed
+	assert.Equal(t, []byte{2, 2, 'a', 1, 4, '{', '}', 4}, encodeEventAvro(ev))
+}
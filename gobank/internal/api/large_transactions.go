@@ -0,0 +1,258 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LargeTransactionJobType is the job that writes the large-transaction
+// report of each tenant for the window that just ended.
+const LargeTransactionJobType = "report_large_transactions"
+
+// largeTransactionPrefix starts the blob key of every large-transaction
+// report, followed by the tenant id and the end of its window.
+const largeTransactionPrefix = "large-transactions-"
+
+// largeTransaction is a transaction of an account that moved at least the
+// reporting threshold within the window. WindowTotal is what the account
+// moved in and out in the window, the sum of the unsigned amounts.
+type largeTransaction struct {
+	AccountNumber domain.AccountNumber
+	TransactionID int
+	Type          string
+	Amount        domain.Money
+	Counterparty  domain.AccountNumber
+	CreatedAt     time.Time
+	WindowTotal   domain.Money
+}
+
+// LargeTransactionReport describes a report the job wrote, To is the end of
+// its window.
+type LargeTransactionReport struct {
+	Name   string    `json:"name"`
+	Format string    `json:"format"`
+	To     time.Time `json:"to"`
+}
+
+// largeTransactionXML is the XML report, amounts are decimals.
+type largeTransactionXML struct {
+	XMLName      xml.Name                 `xml:"LargeTransactionReport"`
+	Tenant       string                   `xml:"tenant,attr"`
+	From         string                   `xml:"from,attr"`
+	To           string                   `xml:"to,attr"`
+	Threshold    int64                    `xml:"thresholdMinorUnits,attr"`
+	Transactions []largeTransactionXMLRow `xml:"Transaction"`
+}
+
+type largeTransactionXMLRow struct {
+	Account      string `xml:"Account"`
+	ID           int    `xml:"Id"`
+	Type         string `xml:"Type"`
+	Amount       string `xml:"Amount"`
+	Currency     string `xml:"Currency"`
+	Counterparty string `xml:"Counterparty,omitempty"`
+	CreatedAt    string `xml:"CreatedAt"`
+	WindowTotal  string `xml:"WindowTotal"`
+}
+
+// LargeTransactionReporter flags the accounts that moved at least
+// Runtime.LargeTransactionThreshold within the last
+// Runtime.LargeTransactionWindowHours and writes their transactions of the
+// window to a report per tenant for compliance.
+type LargeTransactionReporter struct {
+	store   storage.Storage
+	blobs   storage.BlobStore
+	config  *LiveConfig
+	clock   domain.Clock
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewLargeTransactionReporter(store storage.Storage, blobs storage.BlobStore, config *LiveConfig, clock domain.Clock, metrics *Metrics, logger *slog.Logger) *LargeTransactionReporter {
+	metrics.Help("large_transactions_flagged_total", "Transactions written to large-transaction reports.")
+	return &LargeTransactionReporter{store: store, blobs: blobs, config: config, clock: clock, metrics: metrics, logger: logger}
+}
+
+// HandleJob reports the window that ended at the last full hour. A
+// threshold of 0 turns the reports off.
+func (lr *LargeTransactionReporter) HandleJob(job *domain.Job) error {
+	cfg := lr.config.Get().Runtime
+	if cfg.LargeTransactionThreshold <= 0 {
+		return nil
+	}
+	to := lr.clock.Now().UTC().Truncate(time.Hour)
+	from := to.Add(-time.Duration(cfg.LargeTransactionWindowHours) * time.Hour)
+	tenants, err := lr.store.ListTenants()
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		rows, err := largeTransactions(lr.store.ForTenant(tenant.ID), from, to, cfg.LargeTransactionThreshold)
+		if err != nil {
+			return fmt.Errorf("large transactions of tenant %d: %w", tenant.ID, err)
+		}
+		var buf bytes.Buffer
+		format := cfg.LargeTransactionReportFormat
+		if format == "xml" {
+			err = writeLargeTransactionsXML(&buf, tenant, rows, from, to, cfg.LargeTransactionThreshold)
+		} else {
+			err = writeLargeTransactionsCSV(&buf, rows)
+		}
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s%d-%s.%s", largeTransactionPrefix, tenant.ID, to.Format("20060102T1504Z"), format)
+		if err := lr.blobs.Put(key, &buf); err != nil {
+			return err
+		}
+		lr.metrics.Add("large_transactions_flagged_total", float64(len(rows)))
+		if len(rows) > 0 {
+			lr.logger.Warn("large transactions reported", "tenant_id", tenant.ID, "count", len(rows), "report", key)
+		}
+	}
+	return nil
+}
+
+// largeTransactions returns the transactions from from to to of the
+// accounts whose window total reaches threshold, in minor units of the
+// account's currency.
+func largeTransactions(store storage.Storage, from, to time.Time, threshold int64) ([]largeTransaction, error) {
+	var rows []largeTransaction
+	err := store.EachAccount(func(account *domain.Account) error {
+		txs, err := store.TransactionsBetween(account.ID, from, to)
+		if err != nil {
+			return err
+		}
+		total := domain.Money{Currency: account.Balance.Currency}
+		for _, t := range txs {
+			total.MinorUnits += max(t.Amount.MinorUnits, -t.Amount.MinorUnits)
+		}
+		if total.MinorUnits < threshold {
+			return nil
+		}
+		for _, t := range txs {
+			rows = append(rows, largeTransaction{
+				AccountNumber: account.Number,
+				TransactionID: t.ID,
+				Type:          t.Type,
+				Amount:        t.Amount,
+				Counterparty:  t.Counterparty,
+				CreatedAt:     t.CreatedAt.UTC(),
+				WindowTotal:   total,
+			})
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// counterpartyNumber is n in full, empty for none.
+func counterpartyNumber(n domain.AccountNumber) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n.Reveal(), 10)
+}
+
+func writeLargeTransactionsCSV(w io.Writer, rows []largeTransaction) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"account_number", "transaction_id", "type", "amount", "currency", "counterparty", "created_at", "window_total"})
+	for _, row := range rows {
+		cw.Write([]string{
+			strconv.FormatInt(row.AccountNumber.Reveal(), 10),
+			strconv.Itoa(row.TransactionID),
+			row.Type,
+			row.Amount.Decimal(),
+			row.Amount.Currency,
+			counterpartyNumber(row.Counterparty),
+			row.CreatedAt.Format(time.RFC3339),
+			row.WindowTotal.Decimal(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeLargeTransactionsXML(w io.Writer, tenant *domain.Tenant, rows []largeTransaction, from, to time.Time, threshold int64) error {
+	doc := largeTransactionXML{Tenant: tenant.Slug, From: from.Format(time.RFC3339), To: to.Format(time.RFC3339), Threshold: threshold}
+	for _, row := range rows {
+		doc.Transactions = append(doc.Transactions, largeTransactionXMLRow{
+			Account:      strconv.FormatInt(row.AccountNumber.Reveal(), 10),
+			ID:           row.TransactionID,
+			Type:         row.Type,
+			Amount:       row.Amount.Decimal(),
+			Currency:     row.Amount.Currency,
+			Counterparty: counterpartyNumber(row.Counterparty),
+			CreatedAt:    row.CreatedAt.Format(time.RFC3339),
+			WindowTotal:  row.WindowTotal.Decimal(),
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// handleLargeTransactionReports serves GET
+// /admin/reports/large-transactions?tenant=, the tenant's reports, oldest
+// first.
+func (s *APIServer) handleLargeTransactionReports(w http.ResponseWriter, r *http.Request) error {
+	_, tenant, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	prefix := largeTransactionPrefix + strconv.Itoa(tenant.ID) + "-"
+	keys, err := s.reports.List(prefix)
+	if err != nil {
+		return err
+	}
+	reports := make([]LargeTransactionReport, 0, len(keys))
+	for _, key := range keys {
+		stamp, format, _ := strings.Cut(strings.TrimPrefix(key, prefix), ".")
+		to, err := time.Parse("20060102T1504Z", stamp)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, LargeTransactionReport{Name: key, Format: format, To: to})
+	}
+	return WriteJSON(w, http.StatusOK, reports)
+}
+
+// handleLargeTransactionReport serves GET
+// /admin/reports/large-transactions/{name}, a report as the job wrote it.
+func (s *APIServer) handleLargeTransactionReport(w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	if !strings.HasPrefix(name, largeTransactionPrefix) {
+		return NewError(CodeNotFound, "id", name)
+	}
+	blob, err := s.reports.Get(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewError(CodeNotFound, "id", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	contentType := "text/csv"
+	if strings.HasSuffix(name, ".xml") {
+		contentType = "application/xml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	_, err = io.Copy(w, blob)
+	return err
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeLargeTransactionStore struct {
+	storage.Storage
+	accounts []*domain.Account
+	txs      map[int][]*domain.Transaction
+}
+
+func (f *fakeLargeTransactionStore) EachAccount(fn func(*domain.Account) error) error {
+	for _, a := range f.accounts {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeLargeTransactionStore) TransactionsBetween(accountID int, from, to time.Time) ([]*domain.Transaction, error) {
+	return f.txs[accountID], nil
+}
+
+func TestLargeTransactions(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	usd := func(units int64) domain.Money { return domain.Money{MinorUnits: units, Currency: "USD"} }
+	store := &fakeLargeTransactionStore{
+		accounts: []*domain.Account{
+			{ID: 1, Number: 11, Balance: usd(0)},
+			{ID: 2, Number: 22, Balance: usd(0)},
+		},
+		txs: map[int][]*domain.Transaction{
+			// two transfers out that only add up to the threshold together
+			1: {
+				{ID: 1, Type: domain.TransactionTransferOut, Amount: usd(-600000), Counterparty: 22, CreatedAt: at},
+				{ID: 2, Type: domain.TransactionTransferOut, Amount: usd(-400000), Counterparty: 33, CreatedAt: at},
+			},
+			2: {{ID: 3, Type: domain.TransactionTransferIn, Amount: usd(600000), Counterparty: 11, CreatedAt: at}},
+		},
+	}
+	rows, err := largeTransactions(store, at.Add(-24*time.Hour), at.Add(time.Hour), 1000000)
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, usd(1000000), rows[0].WindowTotal)
+		assert.Equal(t, 2, rows[1].TransactionID)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeLargeTransactionsCSV(&buf, rows[:1]))
+	assert.Equal(t, "account_number,transaction_id,type,amount,currency,counterparty,created_at,window_total\n"+
+		"11,1,transfer_out,-6000.00,USD,22,2024-03-01T09:00:00Z,10000.00\n", buf.String())
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeLedgerStore []*domain.Transaction
+
+func (f fakeLedgerStore) EachLedgerEntry(accountID int, fn func(*domain.Transaction) error) error {
+	for _, t := range f {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chainedLedger() fakeLedgerStore {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ledger := fakeLedgerStore{
+		{ID: 1, AccountID: 7, Type: domain.TransactionImport, Amount: domain.Money{MinorUnits: 1000, Currency: "USD"}, CreatedAt: created},
+		{ID: 2, AccountID: 7, Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -250, Currency: "USD"}, Counterparty: 42, CreatedAt: created.Add(time.Hour)},
+		{ID: 3, AccountID: 7, Type: domain.TransactionTransferIn, Amount: domain.Money{MinorUnits: 50, Currency: "USD"}, Counterparty: 42, CreatedAt: created.Add(2 * time.Hour)},
+	}
+	prev := ""
+	for _, t := range ledger {
+		t.Hash = t.ComputeHash(prev)
+		prev = t.Hash
+	}
+	return ledger
+}
+
+func TestVerifyLedger(t *testing.T) {
+	v, err := VerifyLedger(chainedLedger(), 7)
+	assert.Nil(t, err)
+	assert.True(t, v.Valid)
+	assert.Equal(t, 3, v.Entries)
+
+	tampered := chainedLedger()
+	tampered[1].Amount.MinorUnits = -25
+	v, err = VerifyLedger(tampered, 7)
+	assert.Nil(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, 2, v.BrokenAt)
+	assert.Equal(t, "hash mismatch", v.Reason)
+
+	deleted := chainedLedger()
+	deleted = append(deleted[:1], deleted[2:]...)
+	v, _ = VerifyLedger(deleted, 7)
+	assert.False(t, v.Valid)
+	assert.Equal(t, 3, v.BrokenAt)
+}
+
+func TestVerifyLedgerSkipsEntriesBeforeTheChain(t *testing.T) {
+	legacy := &domain.Transaction{ID: 0, AccountID: 7, Type: domain.TransactionTransferIn, Amount: domain.Money{MinorUnits: 5, Currency: "USD"}}
+	v, err := VerifyLedger(append(fakeLedgerStore{legacy}, chainedLedger()...), 7)
+	assert.Nil(t, err)
+	assert.True(t, v.Valid)
+
+	stripped := chainedLedger()
+	stripped[2].Hash = ""
+	v, _ = VerifyLedger(stripped, 7)
+	assert.False(t, v.Valid)
+	assert.Equal(t, "hash missing", v.Reason)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewLogger builds the shared logger. format is "text" or "json", level can be
+// changed at runtime through the LevelVar.
+func NewLogger(w io.Writer, format string, level *slog.LevelVar) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %s", format)
+}
+
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.ToUpper(s)))
+	return level, err
+}
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+func requestIDFrom(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// withLogger returns a context carrying logger, see loggerFrom.
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the request scoped logger, which already carries the
+// request id and, once authenticated, the account id.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// withLoggerAttrs adds attrs to the request scoped logger.
+func withLoggerAttrs(r *http.Request, args ...any) *http.Request {
+	return r.WithContext(withLogger(r.Context(), loggerFrom(r.Context()).With(args...)))
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withRequestID tags each request with an id, reusing a valid incoming
+// X-Request-ID, and puts a logger carrying it on the context.
+func (s *APIServer) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		next.ServeHTTP(w, r.WithContext(withLogger(ctx, s.logger.With("request_id", requestID))))
+	})
+}
+
+// withRequestLogging logs the outcome of the request and records its
+// latency. A panicking request is logged as a 500 before the panic moves on
+// to withRecovery.
+func (s *APIServer) withRequestLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p != nil {
+				rec.status = http.StatusInternalServerError
+			}
+			route := routePath(r)
+			if route == "" {
+				route = r.URL.Path
+			}
+			s.metrics.Observe("http_request_duration_seconds", time.Since(start),
+				"method", r.Method, "route", route, "status", strconv.Itoa(rec.status))
+			loggerFrom(r.Context()).Info("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rec.status,
+				"duration", time.Since(start))
+			if p != nil {
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
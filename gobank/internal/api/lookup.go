@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AccountLookup is what GET /account/lookup tells about an account number:
+// whether it exists, the holder's masked name and the nickname and avatar
+// they chose to show, nothing to identify or size up the account by.
+type AccountLookup struct {
+	Exists   bool   `json:"exists"`
+	Holder   string `json:"holder,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+// handleAccountLookup lets a sender confirm the recipient of a transfer
+// before making it. Unknown numbers are a 200 with exists false, the same
+// shape and status as a hit.
+func (s *APIServer) handleAccountLookup(w http.ResponseWriter, r *http.Request) error {
+	number, err := QueryInt(r, "number", 0, 1, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	if number == 0 {
+		return invalidParameter("number", "")
+	}
+	account, err := s.storeFor(r).GetAccountByNumber(domain.AccountNumber(number))
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return WriteJSON(w, http.StatusOK, AccountLookup{})
+	}
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, AccountLookup{
+		Exists:   true,
+		Holder:   account.FirstName.String() + " " + account.LastName.String(),
+		Nickname: account.Nickname,
+		Avatar:   account.Avatar,
+	})
+}
+
+// withLookupRateLimit holds lookups to their own, much lower limit per client
+// IP, logged in or not, so account numbers can't be enumerated through them.
+func (s *APIServer) withLookupRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		ok, quota := s.lookupLimiter.Allow("ip:"+clientIP(r), now)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(quota.Reset.Sub(now).Seconds())+1))
+			writeError(w, r, http.StatusTooManyRequests, NewError(CodeRateLimited))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
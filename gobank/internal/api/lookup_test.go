@@ -0,0 +1,43 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeLookupStore struct {
+	storage.Storage
+}
+
+func (f *fakeLookupStore) GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error) {
+	if number != 1234567 {
+		return nil, domain.NotFound(domain.ErrAccountNotFound, number)
+	}
+	return &domain.Account{ID: 7, FirstName: "Ada", LastName: "Lovelace", Number: number, Balance: domain.Money{MinorUnits: 100, Currency: "EUR"}}, nil
+}
+
+func TestAccountLookup(t *testing.T) {
+	s := &APIServer{store: &fakeLookupStore{}, lookupLimiter: NewRateLimiter(func() int { return 2 })}
+	h := s.withLookupRateLimit(makeHttpHandleFunc(s.handleAccountLookup))
+	lookup := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/account/lookup"+query, nil))
+		return rec
+	}
+
+	rec := lookup("?number=1234567")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"exists": true, "holder": "A*** L***"}`, rec.Body.String())
+
+	rec = lookup("?number=7654321")
+	assert.JSONEq(t, `{"exists": false}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusTooManyRequests, lookup("?number=1234567").Code)
+
+	s.lookupLimiter = NewRateLimiter(func() int { return 0 })
+	assert.Equal(t, http.StatusBadRequest, lookup("").Code)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	MaintenanceOff      = "off"
+	MaintenanceReadOnly = "read-only"
+	MaintenanceFull     = "full"
+)
+
+type MaintenanceState struct {
+	Mode       string `json:"mode"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retryAfter"`
+}
+
+// Maintenance is the process wide maintenance switch, flipped at runtime
+// through /admin/maintenance.
+type Maintenance struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+}
+
+func NewMaintenance() *Maintenance {
+	return &Maintenance{state: MaintenanceState{Mode: MaintenanceOff}}
+}
+
+func (m *Maintenance) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *Maintenance) Set(state MaintenanceState) error {
+	if state.Mode != MaintenanceOff && state.Mode != MaintenanceReadOnly && state.Mode != MaintenanceFull {
+		return fmt.Errorf("unknown maintenance mode %s", state.Mode)
+	}
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = 300
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	return nil
+}
+
+// blocks reports whether the request must be refused in the current mode.
+func (st MaintenanceState) blocks(r *http.Request) bool {
+	switch st.Mode {
+	case MaintenanceFull:
+		return true
+	case MaintenanceReadOnly:
+		// logging in doesn't change any state
+		if r.URL.Path == "/login" {
+			return false
+		}
+		return r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+	}
+	return false
+}
+
+func (s *APIServer) withMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// operators must still be able to switch maintenance off
+		if isOperatorPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		state := s.maintenance.State()
+		if state.blocks(r) {
+			w.Header().Set("Retry-After", strconv.Itoa(state.RetryAfter))
+			if state.Message != "" {
+				WriteJSON(w, http.StatusServiceUnavailable, ApiError{Code: CodeMaintenance, Error: state.Message})
+				return
+			}
+			writeError(w, r, http.StatusServiceUnavailable, NewError(CodeMaintenance))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *APIServer) handleMaintenance(w http.ResponseWriter, r *http.Request) error {
+	if isGet(r) {
+		return WriteJSON(w, http.StatusOK, s.maintenance.State())
+	}
+	if r.Method == http.MethodPut {
+		var state MaintenanceState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			return err
+		}
+		if err := s.maintenance.Set(state); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, s.maintenance.State())
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithMaintenance(t *testing.T) {
+	s := &APIServer{maintenance: NewMaintenance()}
+	h := s.withMaintenance(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin/maintenance" {
+			if err := s.handleMaintenance(w, r); err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve("POST", "/transfer", "").Code)
+
+	assert.Nil(t, s.maintenance.Set(MaintenanceState{Mode: MaintenanceReadOnly, RetryAfter: 60}))
+	rec := serve("POST", "/transfer", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	var apiErr ApiError
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &apiErr))
+	assert.Equal(t, CodeMaintenance, apiErr.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("DELETE", "/account/1", "").Code)
+	assert.Equal(t, http.StatusOK, serve("GET", "/account/1", "").Code)
+	assert.Equal(t, http.StatusOK, serve("HEAD", "/account/1", "").Code)
+	assert.Equal(t, http.StatusOK, serve("POST", "/login", "").Code)
+
+	assert.Nil(t, s.maintenance.Set(MaintenanceState{Mode: MaintenanceFull, Message: "back at noon"}))
+	for _, req := range [][2]string{{"GET", "/account/1"}, {"POST", "/login"}, {"POST", "/transfer"}} {
+		rec := serve(req[0], req[1], "")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, req[1])
+		assert.Equal(t, "300", rec.Header().Get("Retry-After"), req[1])
+		assert.JSONEq(t, `{"code": "maintenance", "error": "back at noon"}`, rec.Body.String(), req[1])
+	}
+
+	// the admin toggle still gets through and turns it off
+	rec = serve("PUT", "/admin/maintenance", `{"mode": "off"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MaintenanceOff, s.maintenance.State().Mode)
+	assert.Equal(t, http.StatusOK, serve("POST", "/transfer", "").Code)
+
+	assert.NotNil(t, s.maintenance.Set(MaintenanceState{Mode: "partial"}))
+	assert.Equal(t, MaintenanceOff, s.maintenance.State().Mode)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics is a small in-process registry of counters, gauges and latency
+// histograms rendered in the Prometheus text exposition format at /metrics.
+type Metrics struct {
+	mu         sync.Mutex
+	help       map[string]string
+	counters   map[string]map[string]float64
+	gauges     map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+var latencyBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		help:       map[string]string{},
+		counters:   map[string]map[string]float64{},
+		gauges:     map[string]map[string]float64{},
+		histograms: map[string]map[string]*histogram{},
+	}
+}
+
+// labels renders label pairs, given as alternating names and values, in
+// Prometheus syntax.
+func labels(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], v))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func (m *Metrics) Help(name, help string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.help[name] = help
+}
+
+func (m *Metrics) Inc(name string, labelPairs ...string) {
+	m.Add(name, 1, labelPairs...)
+}
+
+func (m *Metrics) Add(name string, v float64, labelPairs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters[name] == nil {
+		m.counters[name] = map[string]float64{}
+	}
+	m.counters[name][labels(labelPairs...)] += v
+}
+
+func (m *Metrics) Set(name string, v float64, labelPairs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.gauges[name] == nil {
+		m.gauges[name] = map[string]float64{}
+	}
+	m.gauges[name][labels(labelPairs...)] = v
+}
+
+func (m *Metrics) Observe(name string, d time.Duration, labelPairs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.histograms[name] == nil {
+		m.histograms[name] = map[string]*histogram{}
+	}
+	key := labels(labelPairs...)
+	h := m.histograms[name][key]
+	if h == nil {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.histograms[name][key] = h
+	}
+	secs := d.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += secs
+}
+
+func (m *Metrics) Render(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range sortedKeys(m.counters) {
+		m.writeHeader(w, name, "counter")
+		for _, key := range sortedKeys(m.counters[name]) {
+			fmt.Fprintf(w, "%s%s %g\n", name, key, m.counters[name][key])
+		}
+	}
+	for _, name := range sortedKeys(m.gauges) {
+		m.writeHeader(w, name, "gauge")
+		for _, key := range sortedKeys(m.gauges[name]) {
+			fmt.Fprintf(w, "%s%s %g\n", name, key, m.gauges[name][key])
+		}
+	}
+	for _, name := range sortedKeys(m.histograms) {
+		m.writeHeader(w, name, "histogram")
+		for _, key := range sortedKeys(m.histograms[name]) {
+			h := m.histograms[name][key]
+			inner := strings.TrimSuffix(strings.TrimPrefix(key, "{"), "}")
+			if inner != "" {
+				inner += ","
+			}
+			for i, le := range latencyBuckets {
+				fmt.Fprintf(w, "%s_bucket{%sle=\"%g\"} %d\n", name, inner, le, h.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, inner, h.count)
+			fmt.Fprintf(w, "%s_sum%s %g\n", name, key, h.sum)
+			fmt.Fprintf(w, "%s_count%s %d\n", name, key, h.count)
+		}
+	}
+}
+
+func (m *Metrics) writeHeader(w io.Writer, name, kind string) {
+	if help, ok := m.help[name]; ok {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.Render(w)
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMetricsRender(t *testing.T) {
+	m := NewMetrics()
+	m.Help("requests_total", "All requests.")
+	m.Inc("requests_total", "route", `/a"b`)
+	m.Observe("latency_seconds", 30*time.Millisecond, "route", "/a")
+
+	var buf bytes.Buffer
+	m.Render(&buf)
+	out := buf.String()
+	assert.Contains(t, out, "# HELP requests_total All requests.\n# TYPE requests_total counter\n")
+	assert.Contains(t, out, `requests_total{route="/a\"b"} 1`)
+	assert.Contains(t, out, `latency_seconds_bucket{route="/a",le="0.025"} 0`)
+	assert.Contains(t, out, `latency_seconds_bucket{route="/a",le="0.05"} 1`)
+	assert.Contains(t, out, `latency_seconds_count{route="/a"} 1`)
+}
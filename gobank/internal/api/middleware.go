@@ -0,0 +1,28 @@
+package api
+
+import "net/http"
+
+// Middleware wraps a handler with behaviour shared by many routes.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is a middleware stack, outermost first.
+type Chain []Middleware
+
+// Use returns the chain with m appended. The receiver is left alone, so
+// route groups and single routes can extend a shared stack.
+func (c Chain) Use(m ...Middleware) Chain {
+	return append(c[:len(c):len(c)], m...)
+}
+
+// Then wraps h in the chain.
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
+
+// ThenFunc wraps an API handler in the chain.
+func (c Chain) ThenFunc(f apiFunc) http.Handler {
+	return c.Then(makeHttpHandleFunc(f))
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	base := Chain{tag("recovery"), tag("request id")}
+	withAuth := base.Use(tag("auth"))
+	withOverride := base.Use(tag("override"))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	withAuth.Then(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, []string{"recovery", "request id", "auth", "handler"}, calls)
+
+	calls = nil
+	withOverride.Then(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, []string{"recovery", "request id", "override", "handler"}, calls)
+	assert.Len(t, base, 2)
+}
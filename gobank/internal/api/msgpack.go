@@ -0,0 +1,305 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// msgpackCodec speaks the MessagePack subset JSON maps onto: nil, booleans,
+// integers, floats, strings, arrays and maps with string keys. Numbers go
+// out as integers when they are whole and fit, floats otherwise. Binary
+// values are read as base64 strings, the way encoding/json carries []byte;
+// extension types are refused.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	b, err := appendMsgpack(nil, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func appendMsgpack(b []byte, v any) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if val {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return appendMsgpackInt(b, n), nil
+		}
+		if n, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), n), nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+	case string:
+		n := len(val)
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, val...), nil
+	case []any:
+		b = appendMsgpackLen(b, len(val), 0x90, 0xdc)
+		for _, item := range val {
+			var err error
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendMsgpackLen(b, len(val), 0x80, 0xde)
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var err error
+			if b, err = appendMsgpack(b, k); err != nil {
+				return nil, err
+			}
+			if b, err = appendMsgpack(b, val[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: can't encode %T", v)
+}
+
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
+
+// appendMsgpackLen writes an array or map header, fix is the fixarray or
+// fixmap prefix and wide the 16 bit form, the 32 bit one following it.
+func appendMsgpackLen(b []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, wide), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, wide+1), uint32(n))
+}
+
+func (msgpackCodec) Decode(r io.Reader) (any, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &msgpackDecoder{b: b}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(b) {
+		return nil, errors.New("msgpack: trailing data")
+	}
+	return v, nil
+}
+
+type msgpackDecoder struct {
+	b   []byte
+	off int
+}
+
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.b)-d.off < n {
+		return nil, errMsgpackShort
+	}
+	p := d.b[d.off : d.off+n]
+	d.off += n
+	return p, nil
+}
+
+// uint reads a big endian unsigned integer of size bytes.
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	p, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range p {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (any, error) {
+	if depth > codecMaxDepth {
+		return nil, errors.New("msgpack: nested too deeply")
+	}
+	p, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := p[0]
+	switch {
+	case c <= 0x7f:
+		return json.Number(strconv.Itoa(int(c))), nil
+	case c >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(c)))), nil
+	case c&0xf0 == 0x80:
+		return d.object(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.array(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(p), nil
+	case 0xca:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return msgpackFloat(float64(math.Float32frombits(uint32(n))), 32)
+	case 0xcb:
+		n, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return msgpackFloat(math.Float64frombits(n), 64)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend from the encoded width
+		shift := 64 - 8*size
+		return json.Number(strconv.FormatInt(int64(n<<shift)>>shift, 10)), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.object(int(n), depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", c)
+}
+
+func msgpackFloat(f float64, bits int) (any, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("msgpack: NaN and infinity have no JSON form")
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bits)), nil
+}
+
+func (d *msgpackDecoder) str(n int) (any, error) {
+	p, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(p), nil
+}
+
+func (d *msgpackDecoder) array(n, depth int) (any, error) {
+	// every element takes at least a byte, a bogus length can't allocate more
+	if n > len(d.b)-d.off {
+		return nil, errMsgpackShort
+	}
+	items := make([]any, n)
+	for i := range items {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) object(n, depth int) (any, error) {
+	if n > len(d.b)-d.off {
+		return nil, errMsgpackShort
+	}
+	m := make(map[string]any, n)
+	for range n {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key must be a string, got %T", k)
+		}
+		if m[key], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
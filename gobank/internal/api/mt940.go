@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// periodBalances returns the account's balance at from and at to, working
+// back from its current balance over the transactions since. txs are the
+// posted ones from from to to.
+func periodBalances(store storage.Storage, account *domain.Account, from, to, now time.Time, txs []*domain.Transaction) (opening, closing domain.Money, err error) {
+	closing = account.Balance
+	if to.Before(now) {
+		later, err := store.TransactionsBetween(account.ID, to, now.Add(time.Second))
+		if err != nil {
+			return opening, closing, err
+		}
+		for _, t := range posted(later) {
+			closing.MinorUnits -= t.Amount.MinorUnits
+		}
+	}
+	opening = closing
+	for _, t := range txs {
+		opening.MinorUnits -= t.Amount.MinorUnits
+	}
+	return opening, closing, nil
+}
+
+// posted leaves out the history entries, which aren't part of a statement
+// as they didn't move the balance.
+func posted(txs []*domain.Transaction) []*domain.Transaction {
+	kept := txs[:0:0]
+	for _, t := range txs {
+		if t.Posted() {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// mt940Amount writes m the SWIFT way, unsigned with a decimal comma and the
+// mark C for credit or D for debit in front.
+func mt940Amount(m domain.Money) string {
+	mark := "C"
+	if m.MinorUnits < 0 {
+		mark, m.MinorUnits = "D", -m.MinorUnits
+	}
+	amount := strings.Replace(m.Decimal(), ".", ",", 1)
+	if !strings.Contains(amount, ",") {
+		amount += ","
+	}
+	return mark + amount
+}
+
+// swiftText keeps what the SWIFT x character set allows, anything else
+// becomes a dot.
+func swiftText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-?:().,'+ ", r):
+			return r
+		}
+		return '.'
+	}, s)
+}
+
+// mt940TransactionCodes maps transaction types to the SWIFT codes of field
+// 61, anything else is miscellaneous.
+var mt940TransactionCodes = map[string]string{
+	domain.TransactionTransferIn:  "TRF",
+	domain.TransactionTransferOut: "TRF",
+	domain.TransactionFee:         "CHG",
+}
+
+// writeMT940 writes the transactions from from to to as one SWIFT MT940
+// customer statement message, the text block without the FIN envelope.
+// Dates are in the account's time zone, to is exclusive.
+func writeMT940(w io.Writer, account *domain.Account, txs []*domain.Transaction, opening, closing domain.Money, from, to time.Time, loc *time.Location) error {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+	number := strconv.FormatInt(account.Number.Reveal(), 10)
+	from, last := from.In(loc), to.In(loc).AddDate(0, 0, -1)
+	line(":20:%s", truncate(from.Format("060102")+number, 16))
+	line(":25:GOBANK/%s", number)
+	line(":28C:%05d/001", from.YearDay())
+	line(":60F:%s", balanceField(opening, from))
+	for _, t := range txs {
+		at := t.CreatedAt.In(loc)
+		code, ok := mt940TransactionCodes[t.Type]
+		if !ok {
+			code = "MSC"
+		}
+		line(":61:%s%s%sN%sNONREF//%d", at.Format("060102"), at.Format("0102"), mt940Amount(t.Amount), code, t.ID)
+		if info := swiftText(transactionPayee(t)); info != "" {
+			for i, part := range mt940Lines(info, 65, 6) {
+				if i == 0 {
+					line(":86:%s", part)
+				} else {
+					line("%s", part)
+				}
+			}
+		}
+	}
+	line(":62F:%s", balanceField(closing, last))
+	line(":64:%s", balanceField(closing, last))
+	line("-")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// balanceField is a balance of fields 60, 62 and 64: mark, date, currency
+// and amount.
+func balanceField(m domain.Money, at time.Time) string {
+	amount := mt940Amount(m)
+	return amount[:1] + at.Format("060102") + m.Currency + amount[1:]
+}
+
+// mt940Lines cuts s into at most max lines of n characters.
+func mt940Lines(s string, n, max int) []string {
+	var lines []string
+	r := []rune(s)
+	for len(r) > 0 && len(lines) < max {
+		end := min(n, len(r))
+		lines = append(lines, string(r[:end]))
+		r = r[end:]
+	}
+	return lines
+}
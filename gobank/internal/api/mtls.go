@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/iamuditg/internal/auth"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type serviceAccountKey struct{}
+
+func serviceAccountFrom(ctx context.Context) *auth.ServiceAccount {
+	account, _ := ctx.Value(serviceAccountKey{}).(*auth.ServiceAccount)
+	return account
+}
+
+// parseServiceAccounts reads "name=scope,scope;name=scope". Names may be full
+// DNs, so the scopes start after the last "=".
+func parseServiceAccounts(v string) map[string][]string {
+	accounts := map[string][]string{}
+	for _, entry := range strings.Split(v, ";") {
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			continue
+		}
+		if name := strings.TrimSpace(entry[:i]); name != "" {
+			accounts[name] = splitList(entry[i+1:])
+		}
+	}
+	return accounts
+}
+
+// newTLSConfig builds the server TLS config for the client auth mode. The CRL
+// and pins are checked on top of the usual chain verification.
+func newTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientAuth == auth.ClientAuthOff {
+		return tlsConfig, nil
+	}
+	caPEM, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	cas, err := auth.ParseCertificates(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	if len(cas) == 0 {
+		return nil, fmt.Errorf("no certificates in %s", cfg.ClientCAFile)
+	}
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.ClientAuth == auth.ClientAuthRequire {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	revoked := map[string]bool{}
+	if cfg.ClientCRLFile != "" {
+		if revoked, err = auth.LoadCRL(cfg.ClientCRLFile, cas); err != nil {
+			return nil, err
+		}
+	}
+	pins := map[string]bool{}
+	for _, pin := range cfg.ClientCertPins {
+		pins[strings.ToLower(strings.ReplaceAll(pin, ":", ""))] = true
+	}
+	tlsConfig.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+		for _, chain := range chains {
+			leaf := chain[0]
+			if revoked[leaf.SerialNumber.String()] {
+				return fmt.Errorf("client certificate %s is revoked", leaf.SerialNumber)
+			}
+			if len(pins) > 0 && !pins[auth.Fingerprint(leaf)] {
+				return fmt.Errorf("client certificate %s is not pinned", auth.Fingerprint(leaf))
+			}
+		}
+		return nil
+	}
+	return tlsConfig, nil
+}
+
+// withClientCert puts the service account of a verified client certificate on
+// the request context. In require mode certificates that don't map to a
+// service account are refused.
+func (s *APIServer) withClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.config.Get()
+		if cfg.ClientAuth == auth.ClientAuthOff || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		account := auth.ServiceAccountFor(r.TLS.VerifiedChains[0][0], cfg.ServiceAccounts)
+		if account == nil {
+			if cfg.ClientAuth == auth.ClientAuthRequire {
+				permissionDenied(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = withLoggerAttrs(r, "service_account", account.Name)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), serviceAccountKey{}, account)))
+	})
+}
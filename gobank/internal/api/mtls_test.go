@@ -0,0 +1,36 @@
+package api
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/iamuditg/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServiceAccountFor(t *testing.T) {
+	accounts := parseServiceAccounts("billing=admin,read; CN=reports,O=Gobank=read")
+	assert.Equal(t, map[string][]string{"billing": {"admin", "read"}, "CN=reports,O=Gobank": {"read"}}, accounts)
+
+	billing := &x509.Certificate{Subject: pkix.Name{CommonName: "billing", Organization: []string{"Gobank"}}}
+	account := auth.ServiceAccountFor(billing, accounts)
+	assert.Equal(t, "billing", account.Name)
+	assert.True(t, account.HasScope(auth.ScopeAdmin))
+
+	reports := &x509.Certificate{Subject: pkix.Name{CommonName: "reports", Organization: []string{"Gobank"}}}
+	assert.False(t, auth.ServiceAccountFor(reports, accounts).HasScope(auth.ScopeAdmin))
+
+	assert.Nil(t, auth.ServiceAccountFor(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}}, accounts))
+}
+
+func TestClientAuthNeedsCertificates(t *testing.T) {
+	t.Setenv("MTLS_CLIENT_AUTH", auth.ClientAuthRequire)
+	_, err := configFromEnv()
+	assert.NotNil(t, err)
+
+	t.Setenv("TLS_CERT_FILE", "server.crt")
+	t.Setenv("TLS_KEY_FILE", "server.key")
+	t.Setenv("MTLS_CLIENT_CA_FILE", "clients.crt")
+	_, err = configFromEnv()
+	assert.Nil(t, err)
+}
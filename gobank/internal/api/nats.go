@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/nats-io/nats.go"
+	"log/slog"
+	"time"
+)
+
+// NatsBus is an event bus backed by NATS subjects, so subscribers on every
+// instance see events published by any instance.
+type NatsBus struct {
+	conn    *nats.Conn
+	subject string
+	logger  *slog.Logger
+}
+
+func NewNatsBus(url, subject string, logger *slog.Logger) (*NatsBus, error) {
+	conn, err := nats.Connect(url, nats.Name("gobank"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &NatsBus{conn: conn, subject: subject, logger: logger}, nil
+}
+
+func (b *NatsBus) Publish(ev *domain.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := b.conn.Publish(b.subject+"."+ev.Type, data); err != nil {
+		return err
+	}
+	// make sure the server has the event before the outbox marks it published
+	return b.conn.FlushTimeout(5 * time.Second)
+}
+
+func (b *NatsBus) Subscribe(fn func(ev *domain.Event)) error {
+	_, err := b.conn.Subscribe(b.subject+".>", func(msg *nats.Msg) {
+		ev := new(domain.Event)
+		if err := json.Unmarshal(msg.Data, ev); err != nil {
+			b.logger.Warn("dropping malformed event", "subject", msg.Subject, "error", err)
+			return
+		}
+		fn(ev)
+	})
+	return err
+}
+
+func (b *NatsBus) Close() {
+	b.conn.Close()
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery is how many lines are buffered before flushing them out.
+const ndjsonFlushEvery = 100
+
+func wantsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjsonWriter writes one JSON value per line. The status goes out with the
+// first line, so errors hit before that can still get a JSON error response.
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	started bool
+	lines   int
+}
+
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+	return &ndjsonWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonWriter) start() {
+	if !n.started {
+		n.w.Header().Set("Content-Type", ndjsonContentType)
+		n.w.WriteHeader(http.StatusOK)
+		n.started = true
+	}
+}
+
+func (n *ndjsonWriter) Write(v any) error {
+	n.start()
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+	n.lines++
+	if n.lines%ndjsonFlushEvery == 0 {
+		n.flush()
+	}
+	return nil
+}
+
+// Close sends the status if nothing was written yet and flushes the rest.
+func (n *ndjsonWriter) Close() {
+	n.start()
+	n.flush()
+}
+
+func (n *ndjsonWriter) flush() {
+	if f, ok := n.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// streamNDJSON runs each, writing every value it yields. Once streaming has
+// started an error can only cut the response short, so it's logged instead.
+func streamNDJSON[T any](w http.ResponseWriter, r *http.Request, each func(func(T) error) error) error {
+	nw := newNDJSONWriter(w)
+	err := each(func(v T) error { return nw.Write(v) })
+	if err != nil && nw.started {
+		loggerFrom(r.Context()).Error("streaming response failed", "error", err, "lines", nw.lines)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	nw.Close()
+	return nil
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWantsNDJSON(t *testing.T) {
+	r := httptest.NewRequest("GET", "/account", nil)
+	assert.False(t, wantsNDJSON(r))
+	r.Header.Set("Accept", "application/json, application/x-ndjson;q=0.9")
+	assert.True(t, wantsNDJSON(r))
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	r := httptest.NewRequest("GET", "/account", nil)
+
+	rec := httptest.NewRecorder()
+	err := streamNDJSON(rec, r, func(fn func(int) error) error {
+		for i := 1; i <= 3; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "1\n2\n3\n", rec.Body.String())
+	assert.Equal(t, ndjsonContentType, rec.Header().Get("Content-Type"))
+
+	// failing before the first line is still a normal error response
+	rec = httptest.NewRecorder()
+	err = streamNDJSON(rec, r, func(fn func(int) error) error { return fmt.Errorf("db down") })
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, rec.Body.Len())
+}
@@ -0,0 +1,470 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiOperation describes an endpoint for the OpenAPI document. Response is a
+// value of the JSON answer's type; endpoints answering with something else
+// set Produces instead.
+type apiOperation struct {
+	ID      string
+	Method  string
+	Path    string
+	Summary string
+	Auth    string
+	Status  int
+	Query   []string
+	// Request is a value of the JSON body's type, for bodies without a
+	// request schema.
+	Request  any
+	Response any
+	Produces string
+	// Consumes lists the body content types of endpoints that don't take JSON.
+	Consumes []string
+}
+
+const (
+	authAccount = "account"
+	authAdmin   = "admin"
+)
+
+// apiOperations is every route a client can call. TestOpenAPICoversRoutes
+// fails when a route is added to the router without an entry here.
+var apiOperations = []apiOperation{
+	{ID: "version", Method: "GET", Path: "/version", Summary: "Build and mode of the server", Response: VersionInfo{}},
+	{ID: "currentTerms", Method: "GET", Path: "/terms", Summary: "Current versions of the terms of service and privacy policy", Response: domain.TermsVersions{}},
+	{ID: "referenceCurrencies", Method: "GET", Path: "/reference/currencies", Summary: "Currencies accounts can hold", Response: []domain.Currency{}},
+	{ID: "referenceCountries", Method: "GET", Path: "/reference/countries", Summary: "ISO 3166 countries", Response: []domain.Country{}},
+	{ID: "referenceAccountTypes", Method: "GET", Path: "/reference/account-types", Summary: "Account types on offer", Response: []domain.AccountType{}},
+	{ID: "login", Method: "POST", Path: "/login", Summary: "Exchange account number and password for a JWT", Response: LoginResponse{}},
+	{ID: "lookupAccount", Method: "GET", Path: "/account/lookup", Summary: "Confirm a transfer recipient by account number, masked", Query: []string{"number"}, Response: AccountLookup{}},
+	{ID: "createAccount", Method: "POST", Path: "/account", Summary: "Open an account", Response: domain.Account{}},
+	{ID: "getAccount", Method: "GET", Path: "/account/{id}", Summary: "Get an account", Auth: authAccount, Response: domain.Account{}},
+	{ID: "updateAccount", Method: "PATCH", Path: "/account/{id}", Summary: "Update an account, If-Match guards against lost updates", Auth: authAccount, Response: domain.Account{}},
+	{ID: "deleteAccount", Method: "DELETE", Path: "/account/{id}", Summary: "Delete an account", Auth: authAccount, Response: map[string]int{}},
+	{ID: "uploadAvatar", Method: "PUT", Path: "/account/{id}/avatar", Summary: "Set the account's picture, scaled to fit 256 pixels", Auth: authAccount, Consumes: []string{"image/png", "image/jpeg", "image/gif"}, Response: domain.Account{}},
+	{ID: "deleteAvatar", Method: "DELETE", Path: "/account/{id}/avatar", Summary: "Remove the account's picture", Auth: authAccount, Response: domain.Account{}},
+	{ID: "sendPhoneCode", Method: "POST", Path: "/account/{id}/phone/verification", Summary: "Text a code to the account's phone, at most once a minute", Auth: authAccount, Status: http.StatusAccepted, Response: PhoneVerificationResponse{}},
+	{ID: "confirmPhone", Method: "POST", Path: "/account/{id}/phone/verification/confirm", Summary: "Verify the account's phone with the texted code", Auth: authAccount, Response: domain.Account{}},
+	{ID: "listDevices", Method: "GET", Path: "/account/{id}/devices", Summary: "List the devices that get push notifications", Auth: authAccount, Response: []*domain.Device{}},
+	{ID: "registerDevice", Method: "POST", Path: "/account/{id}/devices", Summary: "Register a device for transfer and login push notifications", Auth: authAccount, Status: http.StatusCreated, Response: domain.Device{}},
+	{ID: "deleteDevice", Method: "DELETE", Path: "/account/{id}/devices/{deviceId}", Summary: "Stop push notifications to a device", Auth: authAccount, Response: map[string]string{}},
+	{ID: "listNotifications", Method: "GET", Path: "/account/{id}/notifications", Summary: "List the in-app inbox, newest first, with the unread count", Auth: authAccount, Query: []string{"cursor", "limit", "unread"}, Response: NotificationsPage{}},
+	{ID: "readNotification", Method: "POST", Path: "/account/{id}/notifications/{notificationId}/read", Summary: "Mark a notification as read", Auth: authAccount, Response: domain.Notification{}},
+	{ID: "readAllNotifications", Method: "POST", Path: "/account/{id}/notifications/read-all", Summary: "Mark every notification as read", Auth: authAccount, Response: map[string]int64{}},
+	{ID: "getAvatar", Method: "GET", Path: "/avatars/{name}", Summary: "An account picture, as linked from accounts and lookups", Produces: "image/png"},
+	{ID: "dailyTotals", Method: "GET", Path: "/account/{id}/totals", Summary: "Credits and debits per day", Auth: authAccount, Query: []string{"days", "tz"}, Response: []*domain.DailyTotal{}},
+	{ID: "monthlyTotals", Method: "GET", Path: "/account/{id}/totals/monthly", Summary: "Credits and debits per month of the account's time zone", Auth: authAccount, Query: []string{"months"}, Response: []*domain.MonthlyTotal{}},
+	{ID: "summary", Method: "GET", Path: "/account/{id}/summary", Summary: "Balance, spend and recent transactions", Auth: authAccount, Response: domain.AccountSummary{}},
+	{ID: "listPots", Method: "GET", Path: "/account/{id}/pots", Summary: "List the pots money is put aside in", Auth: authAccount, Response: []*domain.Pot{}},
+	{ID: "createPot", Method: "POST", Path: "/account/{id}/pots", Summary: "Put money aside in a pot, out of the available balance", Auth: authAccount, Status: http.StatusCreated, Response: domain.Pot{}},
+	{ID: "updatePot", Method: "PATCH", Path: "/account/{id}/pots/{potId}", Summary: "Rename a pot or change how much is in it", Auth: authAccount, Response: domain.Pot{}},
+	{ID: "deletePot", Method: "DELETE", Path: "/account/{id}/pots/{potId}", Summary: "Release a pot into the available balance", Auth: authAccount, Response: map[string]string{}},
+	{ID: "listStatementDestinations", Method: "GET", Path: "/account/{id}/statement-destinations", Summary: "List where statements are delivered to", Auth: authAccount, Response: []*domain.StatementDestination{}},
+	{ID: "createStatementDestination", Method: "POST", Path: "/account/{id}/statement-destinations", Summary: "Deliver statements to an email address or SFTP server", Auth: authAccount, Status: http.StatusCreated, Response: domain.StatementDestination{}},
+	{ID: "deleteStatementDestination", Method: "DELETE", Path: "/account/{id}/statement-destinations/{destinationId}", Summary: "Stop delivering statements to a destination", Auth: authAccount, Response: map[string]string{}},
+	{ID: "listStatementDeliveries", Method: "GET", Path: "/account/{id}/statement-destinations/{destinationId}/deliveries", Summary: "Latest statement deliveries of a destination", Auth: authAccount, Response: []*domain.StatementDelivery{}},
+	{ID: "retryStatementDelivery", Method: "POST", Path: "/account/{id}/statement-destinations/{destinationId}/deliveries/{deliveryId}/retry", Summary: "Deliver a statement again", Auth: authAccount, Status: http.StatusAccepted, Response: map[string]string{}},
+	{ID: "listTransactions", Method: "GET", Path: "/account/{id}/transactions", Summary: "List transactions, newest first", Auth: authAccount, Query: []string{"cursor", "limit"}, Response: Page[*domain.Transaction]{}},
+	{ID: "listActivity", Method: "GET", Path: "/account/{id}/activity", Summary: "Logins, profile changes, transactions, holds and access grants on one timeline, newest first", Auth: authAccount, Query: []string{"type", "cursor", "limit"}, Response: Page[*domain.Activity]{}},
+	{ID: "transactionFeed", Method: "GET", Path: "/account/{id}/transactions/feed", Summary: "Long poll for new transactions", Auth: authAccount, Query: []string{"cursor", "wait"}, Response: FeedPage{}},
+	{ID: "importTransactions", Method: "POST", Path: "/account/{id}/transactions/import", Summary: "Import a CSV or OFX statement", Auth: authAccount, Consumes: []string{"text/csv", "application/x-ofx"}, Response: ImportResult{}},
+	{ID: "exportTransactions", Method: "GET", Path: "/account/{id}/transactions/export", Summary: "Export transactions as CSV, OFX, QIF, NDJSON or MT940", Auth: authAccount, Query: []string{"format", "from", "to"}, Produces: "text/csv"},
+	{ID: "exportFDX", Method: "GET", Path: "/account/{id}/fdx", Summary: "The account and its transactions in FDX JSON", Auth: authAccount, Query: []string{"from", "to"}, Response: FDXDocument{}},
+	{ID: "listStatements", Method: "GET", Path: "/account/{id}/statements", Summary: "Days with a camt.053 end of day statement", Auth: authAccount, Response: []string{}},
+	{ID: "getStatement", Method: "GET", Path: "/account/{id}/statements/{date}", Summary: "The camt.053 end of day statement of a day, or a localized text one", Auth: authAccount, Query: []string{"format"}, Produces: "application/xml"},
+	{ID: "usage", Method: "GET", Path: "/account/{id}/usage", Summary: "API calls per day", Auth: authAccount, Query: []string{"days"}, Response: UsageReport{}},
+	{ID: "listApiKeys", Method: "GET", Path: "/account/{id}/api-keys", Summary: "List API keys", Auth: authAccount, Response: []*domain.ApiKey{}},
+	{ID: "createApiKey", Method: "POST", Path: "/account/{id}/api-keys", Summary: "Create an API key, the secret is only shown once", Auth: authAccount, Status: http.StatusCreated, Response: domain.ApiKey{}},
+	{ID: "revokeApiKey", Method: "DELETE", Path: "/account/{id}/api-keys/{keyId}", Summary: "Revoke an API key", Auth: authAccount, Response: map[string]string{}},
+	{ID: "listWebhooks", Method: "GET", Path: "/account/{id}/webhooks", Summary: "List webhook endpoints", Auth: authAccount, Response: []*domain.WebhookEndpoint{}},
+	{ID: "createWebhook", Method: "POST", Path: "/account/{id}/webhooks", Summary: "Add a webhook endpoint, the signing secret is only shown once", Auth: authAccount, Status: http.StatusCreated, Response: domain.WebhookEndpoint{}},
+	{ID: "deleteWebhook", Method: "DELETE", Path: "/account/{id}/webhooks/{webhookId}", Summary: "Delete a webhook endpoint", Auth: authAccount, Response: map[string]string{}},
+	{ID: "enableWebhook", Method: "POST", Path: "/account/{id}/webhooks/{webhookId}/enable", Summary: "Turn a disabled webhook endpoint back on", Auth: authAccount, Response: map[string]string{}},
+	{ID: "listWebhookDeliveries", Method: "GET", Path: "/account/{id}/webhooks/{webhookId}/deliveries", Summary: "Latest deliveries of a webhook endpoint with their attempts", Auth: authAccount, Response: []*domain.WebhookDelivery{}},
+	{ID: "redeliverWebhook", Method: "POST", Path: "/account/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", Summary: "Send a webhook delivery again", Auth: authAccount, Status: http.StatusAccepted, Response: map[string]string{}},
+	{ID: "termsStatus", Method: "GET", Path: "/account/{id}/terms", Summary: "Terms the account accepted and the ones pending", Auth: authAccount, Response: domain.TermsStatus{}},
+	{ID: "acceptTerms", Method: "POST", Path: "/account/{id}/terms/accept", Summary: "Accept the current terms, needed before moving money", Auth: authAccount, Response: domain.TermsStatus{}},
+	{ID: "listImpersonations", Method: "GET", Path: "/account/{id}/impersonations", Summary: "Support's requests to read the account", Auth: authAccount, Response: []*domain.Impersonation{}},
+	{ID: "approveImpersonation", Method: "POST", Path: "/account/{id}/impersonations/{impersonationId}/approve", Summary: "Let support read the account", Auth: authAccount, Response: domain.Impersonation{}},
+	{ID: "denyImpersonation", Method: "POST", Path: "/account/{id}/impersonations/{impersonationId}/deny", Summary: "Refuse support access to the account", Auth: authAccount, Response: domain.Impersonation{}},
+	{ID: "listConsents", Method: "GET", Path: "/account/{id}/consents", Summary: "Consents given to third-party API keys", Auth: authAccount, Response: []*domain.Consent{}},
+	{ID: "grantConsent", Method: "POST", Path: "/account/{id}/consents", Summary: "Let a third-party API key use the account", Auth: authAccount, Status: http.StatusCreated, Response: domain.Consent{}},
+	{ID: "revokeConsent", Method: "DELETE", Path: "/account/{id}/consents/{consentId}", Summary: "Withdraw a consent", Auth: authAccount, Response: domain.Consent{}},
+	{ID: "sandboxTopUp", Method: "POST", Path: "/sandbox/account/{id}/topup", Summary: "Credit test money, sandbox only", Auth: authAccount, Response: domain.Transaction{}},
+	{ID: "transfer", Method: "POST", Path: "/transfer", Summary: "Transfer money to another account, Prefer: respond-async makes it in the background", Auth: authAccount, Response: domain.Transaction{}},
+	{ID: "getTransferStatus", Method: "GET", Path: "/transfer/{id}", Summary: "Poll a transfer accepted with Prefer: respond-async", Auth: authAccount, Response: TransferStatus{}},
+	{ID: "quoteTransfer", Method: "POST", Path: "/transfer/quote", Summary: "Check a transfer and quote its terms without making it", Auth: authAccount, Status: http.StatusCreated, Response: domain.TransferQuote{}},
+	{ID: "batchTransfer", Method: "POST", Path: "/transfers/batch", Summary: "Make up to 500 transfers, all or nothing or each on its own", Auth: authAccount, Response: BatchTransferResponse{}},
+	{ID: "authorizeTransfer", Method: "POST", Path: "/transfer/authorize", Summary: "Reserve the funds of a transfer to capture or void later", Auth: authAccount, Status: http.StatusCreated, Response: domain.TransferHold{}},
+	{ID: "getTransferHold", Method: "GET", Path: "/transfer/holds/{holdId}", Summary: "Get an authorized transfer", Auth: authAccount, Response: domain.TransferHold{}},
+	{ID: "captureTransfer", Method: "POST", Path: "/transfer/holds/{holdId}/capture", Summary: "Make an authorized transfer for at most the authorized amount", Auth: authAccount, Response: domain.Transaction{}},
+	{ID: "voidTransfer", Method: "POST", Path: "/transfer/holds/{holdId}/void", Summary: "Release an authorized transfer's funds", Auth: authAccount, Response: domain.TransferHold{}},
+	{ID: "obListAccounts", Method: "GET", Path: "/open-banking/v1/accounts", Summary: "Accounts the consent covers, Berlin Group format", Auth: authAccount, Response: OBAccountList{}},
+	{ID: "obGetAccount", Method: "GET", Path: "/open-banking/v1/accounts/{accountId}", Summary: "Account details, Berlin Group format", Auth: authAccount, Response: OBAccount{}},
+	{ID: "obBalances", Method: "GET", Path: "/open-banking/v1/accounts/{accountId}/balances", Summary: "Account balances, Berlin Group format", Auth: authAccount, Response: OBBalances{}},
+	{ID: "obTransactions", Method: "GET", Path: "/open-banking/v1/accounts/{accountId}/transactions", Summary: "Booked transactions, Berlin Group format", Auth: authAccount, Query: []string{"dateFrom", "dateTo", "bookingStatus"}, Response: OBTransactions{}},
+	{ID: "obInitiatePayment", Method: "POST", Path: "/open-banking/v1/payments/credit-transfers", Summary: "Initiate a credit transfer, Berlin Group format", Auth: authAccount, Status: http.StatusCreated, Response: OBPaymentStatus{}},
+	{ID: "obPaymentStatus", Method: "GET", Path: "/open-banking/v1/payments/credit-transfers/{paymentId}/status", Summary: "Status of an initiated payment", Auth: authAccount, Response: OBPaymentStatus{}},
+	{ID: "adminListTenants", Method: "GET", Path: "/admin/tenants", Summary: "List tenants", Auth: authAdmin, Response: []*domain.Tenant{}},
+	{ID: "adminCreateTenant", Method: "POST", Path: "/admin/tenants", Summary: "Create a tenant", Auth: authAdmin, Status: http.StatusCreated, Response: domain.Tenant{}},
+	{ID: "adminTenantSettings", Method: "GET", Path: "/admin/tenants/{id}/settings", Summary: "Get a tenant's settings", Auth: authAdmin, Response: domain.TenantSettings{}},
+	{ID: "adminUpdateTenantSettings", Method: "PUT", Path: "/admin/tenants/{id}/settings", Summary: "Replace a tenant's settings", Auth: authAdmin, Response: domain.TenantSettings{}},
+	{ID: "adminMaintenance", Method: "GET", Path: "/admin/maintenance", Summary: "Get the maintenance mode", Auth: authAdmin, Response: MaintenanceState{}},
+	{ID: "adminSetMaintenance", Method: "PUT", Path: "/admin/maintenance", Summary: "Set the maintenance mode", Auth: authAdmin, Response: MaintenanceState{}},
+	{ID: "adminChaos", Method: "GET", Path: "/admin/chaos", Summary: "Get the fault injection settings, sandbox only", Auth: authAdmin, Response: ChaosState{}},
+	{ID: "adminSetChaos", Method: "PUT", Path: "/admin/chaos", Summary: "Set the fault injection settings, sandbox only", Auth: authAdmin, Response: ChaosState{}},
+	{ID: "adminClock", Method: "GET", Path: "/admin/clock", Summary: "Get the simulated clock, sandbox only", Auth: authAdmin, Response: ClockState{}},
+	{ID: "adminAdvanceClock", Method: "POST", Path: "/admin/clock", Summary: "Advance the simulated clock, sandbox only", Auth: authAdmin, Response: ClockState{}},
+	{ID: "adminReloadConfig", Method: "POST", Path: "/admin/config/reload", Summary: "Reload the runtime config", Auth: authAdmin, Response: RuntimeConfig{}},
+	{ID: "adminListJobs", Method: "GET", Path: "/admin/jobs", Summary: "List background jobs", Auth: authAdmin, Query: []string{"status"}, Response: []*domain.Job{}},
+	{ID: "adminRetryJob", Method: "POST", Path: "/admin/jobs/{id}/retry", Summary: "Retry a failed job", Auth: authAdmin, Response: map[string]int{}},
+	{ID: "adminDailyReport", Method: "GET", Path: "/admin/reports/daily", Summary: "Daily figures of a tenant", Auth: authAdmin, Query: []string{"tenant", "from", "to", "format"}, Response: []*domain.DailyReportRow{}},
+	{ID: "adminLargeTransactionReports", Method: "GET", Path: "/admin/reports/large-transactions", Summary: "Large-transaction reports of a tenant, oldest first", Auth: authAdmin, Query: []string{"tenant"}, Response: []LargeTransactionReport{}},
+	{ID: "adminLargeTransactionReport", Method: "GET", Path: "/admin/reports/large-transactions/{name}", Summary: "A large-transaction report, CSV or XML", Auth: authAdmin, Produces: "text/csv"},
+	{ID: "adminAccountingExports", Method: "GET", Path: "/admin/reports/accounting", Summary: "Accounting exports of a tenant", Auth: authAdmin, Query: []string{"tenant"}, Response: []AccountingExport{}},
+	{ID: "adminQueueAccountingExport", Method: "POST", Path: "/admin/reports/accounting", Summary: "Queue the export of a period's ledger as QuickBooks IIF or Xero CSV journals", Auth: authAdmin, Query: []string{"tenant"}, Request: AccountingExportRequest{}, Status: http.StatusAccepted, Response: AccountingExport{}},
+	{ID: "adminAccountingExport", Method: "GET", Path: "/admin/reports/accounting/{name}", Summary: "An accounting export, IIF or CSV", Auth: authAdmin, Query: []string{"tenant"}, Produces: "text/csv"},
+	{ID: "adminBalanceReport", Method: "GET", Path: "/admin/reports/balances", Summary: "Ledger balances of a tenant per currency", Auth: authAdmin, Query: []string{"tenant"}, Response: []*domain.BalanceTotal{}},
+	{ID: "adminProjections", Method: "GET", Path: "/admin/projections", Summary: "How far the read model projections got through the ledger", Auth: authAdmin, Response: []*domain.Projection{}},
+	{ID: "adminRebuildProjection", Method: "POST", Path: "/admin/projections/{name}/rebuild", Summary: "Empty a projection and build it again from the ledger", Auth: authAdmin, Status: http.StatusAccepted, Response: domain.Projection{}},
+	{ID: "adminReconciliationIssues", Method: "GET", Path: "/admin/reconciliation/issues", Summary: "List balance discrepancies", Auth: authAdmin, Query: []string{"status"}, Response: []*domain.ReconciliationIssue{}},
+	{ID: "adminResolveReconciliationIssue", Method: "POST", Path: "/admin/reconciliation/issues/{id}/resolve", Summary: "Mark a discrepancy resolved", Auth: authAdmin, Response: map[string]int{}},
+	{ID: "adminListEvents", Method: "GET", Path: "/admin/events", Summary: "The audit trail, newest first", Auth: authAdmin, Query: []string{"cursor", "limit"}, Response: Page[*domain.Event]{}},
+	{ID: "adminStuckSagas", Method: "GET", Path: "/admin/sagas/stuck", Summary: "Sagas left stuck or running too long", Auth: authAdmin, Response: []*domain.Saga{}},
+	{ID: "adminListAccounts", Method: "GET", Path: "/admin/accounts", Summary: "List a tenant's accounts", Auth: authAdmin, Query: []string{"tenant", "cursor", "limit"}, Response: Page[*domain.Account]{}},
+	{ID: "adminExportAccounts", Method: "GET", Path: "/admin/accounts/export", Summary: "Export a tenant's accounts as CSV", Auth: authAdmin, Query: []string{"tenant", "from", "to", "currency"}, Produces: "text/csv"},
+	{ID: "adminExportPortable", Method: "GET", Path: "/admin/accounts/portable", Summary: "Export accounts with their history", Auth: authAdmin, Query: []string{"tenant", "account"}, Response: PortableExport{}},
+	{ID: "adminImportPortable", Method: "POST", Path: "/admin/accounts/portable", Summary: "Import a portable export", Auth: authAdmin, Query: []string{"tenant"}, Request: PortableExport{}, Response: map[string]int{}},
+	{ID: "adminIngestPain001", Method: "POST", Path: "/admin/payments/pain001", Summary: "Accept the credit transfers of an ISO 20022 pain.001 file, answers with a pain.002 status report", Auth: authAdmin, Query: []string{"tenant"}, Consumes: []string{"application/xml"}, Produces: "application/xml"},
+	{ID: "adminListBatchFiles", Method: "GET", Path: "/admin/payments/batch-files", Summary: "Latest payment batch files the intake picked up, newest first", Auth: authAdmin, Query: []string{"tenant"}, Response: []*domain.BatchFile{}},
+	{ID: "adminTransfersInReview", Method: "GET", Path: "/admin/transfers/review", Summary: "Transfers screening held for review, oldest first", Auth: authAdmin, Query: []string{"tenant"}, Response: []TransferReview{}},
+	{ID: "adminReleaseTransfer", Method: "POST", Path: "/admin/transfers/{id}/release", Summary: "Release a held transfer to be made without screening it again", Auth: authAdmin, Query: []string{"tenant"}, Response: TransferStatus{}},
+	{ID: "adminRejectTransfer", Method: "POST", Path: "/admin/transfers/{id}/reject", Summary: "Reject a held transfer", Auth: authAdmin, Query: []string{"tenant"}, Response: TransferStatus{}},
+	{ID: "adminVerifyLedger", Method: "GET", Path: "/admin/accounts/{id}/ledger/verify", Summary: "Verify an account's hash chain", Auth: authAdmin, Query: []string{"tenant"}, Response: domain.LedgerVerification{}},
+	{ID: "adminAccountHistory", Method: "GET", Path: "/admin/accounts/{id}/history", Summary: "An account's event stream, with account event sourcing on", Auth: authAdmin, Query: []string{"tenant", "after"}, Response: []*domain.AccountEvent{}},
+	{ID: "adminAccountState", Method: "GET", Path: "/admin/accounts/{id}/state", Summary: "An account as it was at a time, replayed from its event stream", Auth: authAdmin, Query: []string{"tenant", "at"}, Response: domain.Account{}},
+	{ID: "adminImpersonate", Method: "POST", Path: "/admin/accounts/{id}/impersonations", Summary: "Request read-only access to an account", Auth: authAdmin, Query: []string{"tenant"}, Status: http.StatusCreated, Response: ImpersonationResponse{}},
+	{ID: "adminSetMinimumBalance", Method: "PUT", Path: "/admin/accounts/{id}/minimum-balance", Summary: "Set the balance transfers can't take an account below", Auth: authAdmin, Query: []string{"tenant"}, Response: Balances{}},
+	{ID: "adminImpersonationToken", Method: "POST", Path: "/admin/impersonations/{id}/token", Summary: "Issue a token for an approved impersonation", Auth: authAdmin, Query: []string{"tenant"}, Response: ImpersonationResponse{}},
+	{ID: "adminRevokeImpersonation", Method: "POST", Path: "/admin/impersonations/{id}/revoke", Summary: "End an impersonation", Auth: authAdmin, Query: []string{"tenant"}, Response: domain.Impersonation{}},
+	{ID: "adminListEmailSuppressions", Method: "GET", Path: "/admin/email/suppressions", Summary: "Addresses no emails are sent to, newest first", Auth: authAdmin, Query: []string{"tenant"}, Response: []*domain.EmailSuppression{}},
+	{ID: "adminSuppressEmail", Method: "POST", Path: "/admin/email/suppressions", Summary: "Stop sending emails to an address", Auth: authAdmin, Query: []string{"tenant"}, Status: http.StatusCreated, Response: domain.EmailSuppression{}},
+	{ID: "adminDeleteEmailSuppression", Method: "DELETE", Path: "/admin/email/suppressions/{address}", Summary: "Send emails to an address again", Auth: authAdmin, Query: []string{"tenant"}, Response: map[string]string{}},
+}
+
+// integerQueryParams are the query parameters that take a number, the rest
+// are strings.
+var integerQueryParams = map[string]bool{"limit": true, "days": true, "wait": true, "account": true, "number": true}
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// openAPIBuilder collects the component schemas while the paths are built.
+type openAPIBuilder struct {
+	components map[string]any
+	// requestRefs maps a request schema file to its component name.
+	requestRefs map[string]string
+}
+
+// buildOpenAPI describes apiOperations as an OpenAPI 3.1 document. Request
+// bodies are the schemas the server validates against, responses are
+// derived from the Go types the handlers encode.
+func buildOpenAPI(info VersionInfo) (map[string]any, error) {
+	b := &openAPIBuilder{components: map[string]any{}, requestRefs: map[string]string{}}
+	b.typeSchema(reflect.TypeOf(ApiError{}))
+
+	paths := map[string]map[string]any{}
+	for _, op := range apiOperations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = b.operation(op)
+	}
+	if err := b.addRequestSchemas(); err != nil {
+		return nil, err
+	}
+	b.rewriteOperationRefs(paths)
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "gobank",
+			"version": info.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"jwt":    map[string]any{"type": "apiKey", "in": "header", "name": "x-jwt-token", "description": "The token from /login."},
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-Api-Key", "description": "Signed with X-Timestamp, X-Nonce and X-Signature, an HMAC-SHA256 over method, path with query, timestamp, nonce and the hex SHA-256 of the body, one per line."},
+				"admin":  map[string]any{"type": "apiKey", "in": "header", "name": "x-admin-token"},
+			},
+		},
+	}, nil
+}
+
+func (b *openAPIBuilder) operation(op apiOperation) map[string]any {
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		schema := map[string]any{"type": "string"}
+		switch {
+		case m[1] == "id" && strings.Contains(op.Path, "/account"):
+			// serial ids are still accepted but no longer advertised
+			schema["format"] = "uuid"
+		case m[1] == "id":
+			schema["type"] = "integer"
+		}
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": schema})
+	}
+	for _, q := range op.Query {
+		typ := "string"
+		if integerQueryParams[q] {
+			typ = "integer"
+		}
+		params = append(params, map[string]any{"name": q, "in": "query", "schema": map[string]any{"type": typ}})
+	}
+	params = append(params, map[string]any{"name": "X-Tenant", "in": "header", "schema": map[string]any{"type": "string"}})
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": b.typeSchema(reflect.TypeOf(op.Response))}}
+	} else if op.Produces != "" {
+		ok["content"] = map[string]any{op.Produces: map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+	o := map[string]any{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"parameters":  params,
+		"responses": map[string]any{
+			fmt.Sprint(status): ok,
+			"default": map[string]any{
+				"description": "Error",
+				"content":     map[string]any{"application/json": map[string]any{"schema": ref("ApiError")}},
+			},
+		},
+	}
+	switch op.Auth {
+	case authAccount:
+		o["security"] = []any{map[string]any{"jwt": []string{}}, map[string]any{"apiKey": []string{}}}
+	case authAdmin:
+		o["security"] = []any{map[string]any{"admin": []string{}}}
+	}
+	if file, ok := requestSchemas[op.Method+" "+op.Path]; ok {
+		o["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": file}}},
+		}
+	} else if op.Request != nil {
+		o["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": b.typeSchema(reflect.TypeOf(op.Request))}},
+		}
+	} else if len(op.Consumes) > 0 {
+		content := map[string]any{}
+		for _, ct := range op.Consumes {
+			content[ct] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+		o["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+	return o
+}
+
+// addRequestSchemas adds the embedded request schemas as components named
+// after their titles, with an Input suffix where a response type has the
+// name already, and points the $refs at them.
+func (b *openAPIBuilder) addRequestSchemas() error {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return err
+	}
+	docs := map[string]map[string]any{}
+	for _, e := range entries {
+		data, err := schemaFiles.ReadFile(path.Join("schemas", e.Name()))
+		if err != nil {
+			return err
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		name, _ := doc["title"].(string)
+		if _, taken := b.components[name]; taken {
+			name += "Input"
+		}
+		delete(doc, "$schema")
+		delete(doc, "$id")
+		docs[name] = doc
+		b.requestRefs[e.Name()] = name
+	}
+	for name, doc := range docs {
+		b.components[name] = b.rewriteRefs(doc)
+	}
+	return nil
+}
+
+// rewriteRefs points file $refs at the components, in place.
+func (b *openAPIBuilder) rewriteRefs(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if r, ok := v["$ref"].(string); ok && strings.HasSuffix(r, ".json") {
+			v["$ref"] = "#/components/schemas/" + b.requestRefs[r]
+		}
+		for _, child := range v {
+			b.rewriteRefs(child)
+		}
+	case []any:
+		for _, child := range v {
+			b.rewriteRefs(child)
+		}
+	}
+	return v
+}
+
+// rewriteOperationRefs does for the paths what rewriteRefs does for the
+// components, the request bodies reference the schema files too.
+func (b *openAPIBuilder) rewriteOperationRefs(paths map[string]map[string]any) {
+	for _, ops := range paths {
+		for _, op := range ops {
+			if body, ok := op.(map[string]any)["requestBody"]; ok {
+				b.rewriteRefs(body)
+			}
+		}
+	}
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	moneyType = reflect.TypeOf(domain.Money{})
+	rawType   = reflect.TypeOf(json.RawMessage{})
+)
+
+// typeSchema derives the schema of a Go type from its json tags. Named
+// structs become components.
+func (b *openAPIBuilder) typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]any{}
+	case moneyType:
+		if _, ok := b.components["Money"]; !ok {
+			b.components["Money"] = map[string]any{
+				"type":     "object",
+				"required": []string{"amount", "currency", "minor_units"},
+				"properties": map[string]any{
+					"amount":      map[string]any{"type": "string", "description": "Decimal amount, e.g. 125.50."},
+					"currency":    map[string]any{"type": "string"},
+					"minor_units": map[string]any{"type": "integer"},
+				},
+			}
+		}
+		return ref("Money")
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			// claimed before recursing, so self references terminate
+			b.components[name] = nil
+			b.components[name] = b.structSchema(t)
+		}
+		return ref(name)
+	}
+	return map[string]any{}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.typeSchema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// componentName names generic instances after their type argument, so
+// Page[*Account] becomes PageAccount.
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	i := strings.IndexByte(name, '[')
+	if i < 0 {
+		return name
+	}
+	arg := strings.TrimSuffix(name[i+1:], "]")
+	return name[:i] + arg[strings.LastIndexAny(arg, ".*")+1:]
+}
+
+// RunOpenAPICommand implements `gobank openapi [file]`, writing the document
+// to the file or stdout.
+func RunOpenAPICommand(args []string, stdout io.Writer) error {
+	doc, err := buildOpenAPI(buildVersion())
+	if err != nil {
+		return err
+	}
+	w := stdout
+	if len(args) > 1 {
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/client"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var ops []string
+	for _, op := range apiOperations {
+		ops = append(ops, op.Method+" "+op.Path)
+	}
+	assert.ElementsMatch(t, client.Endpoints, ops)
+	for route := range requestSchemas {
+		assert.Contains(t, ops, route)
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	doc, err := buildOpenAPI(VersionInfo{Version: "test"})
+	assert.Nil(t, err)
+	data, err := json.Marshal(doc)
+	assert.Nil(t, err)
+
+	var parsed struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.Nil(t, json.Unmarshal(data, &parsed))
+	assert.Contains(t, parsed.Components.Schemas, "PageAccount")
+	assert.Contains(t, parsed.Components.Schemas, "TransferRequest")
+	assert.Contains(t, parsed.Components.Schemas, "MoneyInput")
+
+	for _, part := range strings.Split(string(data), `"$ref":"`)[1:] {
+		target := part[:strings.IndexByte(part, '"')]
+		name, ok := strings.CutPrefix(target, "#/components/schemas/")
+		assert.True(t, ok, target)
+		assert.Contains(t, parsed.Components.Schemas, name)
+	}
+}
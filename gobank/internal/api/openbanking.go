@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The /open-banking/v1 routes present accounts and payments the way the
+// Berlin Group NextGenPSD2 API does, so partners can reuse their PSD2
+// integration. A third-party key reaches them through the account holder's
+// consent, see checkConsent.
+
+// obPaymentProduct is the one payment product offered, a transfer between
+// accounts of the bank.
+const obPaymentProduct = "credit-transfers"
+
+type OBAmount struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+func obAmount(m domain.Money) OBAmount {
+	return OBAmount{Currency: m.Currency, Amount: m.Decimal()}
+}
+
+// OBAccountReference identifies an account by its number, and by its IBAN
+// when the tenant issues them.
+type OBAccountReference struct {
+	BBAN     string `json:"bban,omitempty"`
+	IBAN     string `json:"iban,omitempty"`
+	Currency string `json:"currency,omitempty"`
+}
+
+func obAccountReference(account *domain.Account) OBAccountReference {
+	return OBAccountReference{BBAN: strconv.FormatInt(account.Number.Reveal(), 10), IBAN: account.IBAN, Currency: account.Balance.Currency}
+}
+
+type OBLink struct {
+	Href string `json:"href"`
+}
+
+type OBAccountDetails struct {
+	ResourceID      string            `json:"resourceId"`
+	BBAN            string            `json:"bban"`
+	IBAN            string            `json:"iban,omitempty"`
+	Currency        string            `json:"currency"`
+	Name            string            `json:"name"`
+	OwnerName       string            `json:"ownerName"`
+	CashAccountType string            `json:"cashAccountType"`
+	Status          string            `json:"status"`
+	Links           map[string]OBLink `json:"_links"`
+}
+
+func obAccountDetails(account *domain.Account) OBAccountDetails {
+	self := "/open-banking/v1/accounts/" + account.UUID
+	return OBAccountDetails{
+		ResourceID:      account.UUID,
+		BBAN:            strconv.FormatInt(account.Number.Reveal(), 10),
+		IBAN:            account.IBAN,
+		Currency:        account.Balance.Currency,
+		Name:            "Current account",
+		OwnerName:       account.FirstName.Reveal() + " " + account.LastName.Reveal(),
+		CashAccountType: "CACC",
+		Status:          "enabled",
+		Links:           map[string]OBLink{"balances": {self + "/balances"}, "transactions": {self + "/transactions"}},
+	}
+}
+
+type OBAccountList struct {
+	Accounts []OBAccountDetails `json:"accounts"`
+}
+
+type OBAccount struct {
+	Account OBAccountDetails `json:"account"`
+}
+
+type OBBalance struct {
+	BalanceAmount OBAmount `json:"balanceAmount"`
+	BalanceType   string   `json:"balanceType"`
+	ReferenceDate string   `json:"referenceDate"`
+}
+
+type OBBalances struct {
+	Account  OBAccountReference `json:"account"`
+	Balances []OBBalance        `json:"balances"`
+}
+
+type OBTransaction struct {
+	TransactionID                     string              `json:"transactionId"`
+	BookingDate                       string              `json:"bookingDate"`
+	ValueDate                         string              `json:"valueDate"`
+	TransactionAmount                 OBAmount            `json:"transactionAmount"`
+	CreditorAccount                   *OBAccountReference `json:"creditorAccount,omitempty"`
+	DebtorAccount                     *OBAccountReference `json:"debtorAccount,omitempty"`
+	RemittanceInformationUnstructured string              `json:"remittanceInformationUnstructured,omitempty"`
+}
+
+// OBTransactions lists the booked transactions, every transaction is booked
+// right away so Pending stays empty.
+type OBTransactions struct {
+	Account      OBAccountReference `json:"account"`
+	Transactions struct {
+		Booked  []OBTransaction `json:"booked"`
+		Pending []OBTransaction `json:"pending"`
+	} `json:"transactions"`
+}
+
+// obTransaction names the counterparty as creditor of money going out and as
+// debtor of money coming in.
+func obTransaction(t *domain.Transaction, loc *time.Location) OBTransaction {
+	day := t.CreatedAt.In(loc).Format("2006-01-02")
+	tx := OBTransaction{
+		TransactionID:                     strconv.Itoa(t.ID),
+		BookingDate:                       day,
+		ValueDate:                         day,
+		TransactionAmount:                 obAmount(t.Amount),
+		RemittanceInformationUnstructured: t.Description,
+	}
+	if t.Counterparty != 0 {
+		ref := &OBAccountReference{BBAN: strconv.FormatInt(t.Counterparty.Reveal(), 10)}
+		if t.Amount.MinorUnits < 0 {
+			tx.CreditorAccount = ref
+		} else {
+			tx.DebtorAccount = ref
+		}
+	}
+	return tx
+}
+
+// OBPaymentRequest initiates a credit transfer. Amounts are decimal strings.
+type OBPaymentRequest struct {
+	InstructedAmount                  OBAmount           `json:"instructedAmount"`
+	CreditorAccount                   OBAccountReference `json:"creditorAccount"`
+	CreditorName                      string             `json:"creditorName"`
+	RemittanceInformationUnstructured string             `json:"remittanceInformationUnstructured,omitempty"`
+}
+
+type OBPaymentStatus struct {
+	PaymentID         string            `json:"paymentId,omitempty"`
+	TransactionStatus string            `json:"transactionStatus"`
+	Links             map[string]OBLink `json:"_links,omitempty"`
+}
+
+// obTransactionStatus maps a transfer's status to the ISO 20022 codes PSD2
+// uses: received, settled or rejected.
+func obTransactionStatus(status string) string {
+	switch status {
+	case domain.TransferCompleted:
+		return "ACSC"
+	case domain.TransferFailed:
+		return "RJCT"
+	}
+	return "RCVD"
+}
+
+// obAccount authenticates the request and returns the caller's account,
+// which has to be the one the path names.
+func (s *APIServer) obAccount(w http.ResponseWriter, r *http.Request) (*domain.Account, bool) {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil, false
+	}
+	setAccountLanguage(r, account.Language)
+	if id := r.PathValue("accountId"); id != "" && id != account.UUID {
+		writeError(w, r, http.StatusNotFound, domain.NotFound(domain.ErrAccountNotFound, id))
+		return nil, false
+	}
+	return account, true
+}
+
+// handleOBAccounts lists the accounts the consent covers, which is the one
+// of the caller.
+func (s *APIServer) handleOBAccounts(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	return WriteJSON(w, http.StatusOK, OBAccountList{Accounts: []OBAccountDetails{obAccountDetails(account)}})
+}
+
+func (s *APIServer) handleOBAccount(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	return WriteJSON(w, http.StatusOK, OBAccount{Account: obAccountDetails(account)})
+}
+
+func (s *APIServer) handleOBBalances(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	availability, err := s.storeFor(r).Availability(account.ID)
+	if err != nil {
+		return err
+	}
+	day := s.clock.Now().In(loc).Format("2006-01-02")
+	return WriteJSON(w, http.StatusOK, OBBalances{
+		Account: obAccountReference(account),
+		Balances: []OBBalance{
+			{BalanceAmount: obAmount(account.Balance), BalanceType: "interimBooked", ReferenceDate: day},
+			{BalanceAmount: obAmount(availability.Available()), BalanceType: "interimAvailable", ReferenceDate: day},
+		},
+	})
+}
+
+// handleOBTransactions serves ?dateFrom=2024-01-01&dateTo=2024-01-31, days in
+// the account's time zone with dateTo included.
+func (s *APIServer) handleOBTransactions(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	if status := r.URL.Query().Get("bookingStatus"); status != "" && status != "booked" && status != "both" {
+		return invalidParameter("bookingStatus", status)
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	from, err := QueryTime(r, "dateFrom", "2006-01-02", loc, domain.StartOfDay(account.CreatedAt, loc))
+	if err != nil {
+		return err
+	}
+	to, err := QueryTime(r, "dateTo", "2006-01-02", loc, domain.StartOfDay(s.clock.Now(), loc))
+	if err != nil {
+		return err
+	}
+	txs, err := s.storeFor(r).TransactionsBetween(account.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	res := OBTransactions{Account: obAccountReference(account)}
+	res.Transactions.Booked = make([]OBTransaction, 0, len(txs))
+	res.Transactions.Pending = []OBTransaction{}
+	for _, t := range txs {
+		res.Transactions.Booked = append(res.Transactions.Booked, obTransaction(t, loc))
+	}
+	return WriteJSON(w, http.StatusOK, res)
+}
+
+// handleOBInitiatePayment accepts the payment for processing in the
+// background, like POST /transfer with Prefer: respond-async. Its status
+// link tells when it settled.
+func (s *APIServer) handleOBInitiatePayment(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	req := new(OBPaymentRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	currency := req.InstructedAmount.Currency
+	if currency == "" {
+		currency = account.Balance.Currency
+	}
+	units, err := domain.ParseDecimal(req.InstructedAmount.Amount, currency)
+	if err != nil {
+		return invalidParameter("instructedAmount", req.InstructedAmount.Amount)
+	}
+	var to domain.AccountNumber
+	if req.CreditorAccount.IBAN != "" {
+		creditor, err := accountByIBAN(s.storeFor(r), req.CreditorAccount.IBAN)
+		if err != nil {
+			return err
+		}
+		to = creditor.Number
+	} else {
+		n, err := strconv.ParseInt(req.CreditorAccount.BBAN, 10, 64)
+		if err != nil {
+			return invalidParameter("creditorAccount", req.CreditorAccount.BBAN)
+		}
+		to = domain.AccountNumber(n)
+	}
+	transfer, err := s.transfersFor(r).Accept(account, to, domain.Money{MinorUnits: units, Currency: currency})
+	if err != nil {
+		return err
+	}
+	loggerFrom(r.Context()).Info("payment initiated", "transfer_id", transfer.ID, "amount", transfer.Amount.String())
+	status := "/open-banking/v1/payments/" + obPaymentProduct + "/" + transfer.ID + "/status"
+	w.Header().Set("Location", status)
+	return WriteJSON(w, http.StatusCreated, OBPaymentStatus{
+		PaymentID:         transfer.ID,
+		TransactionStatus: obTransactionStatus(transfer.Status),
+		Links:             map[string]OBLink{"status": {status}},
+	})
+}
+
+func (s *APIServer) handleOBPaymentStatus(w http.ResponseWriter, r *http.Request) error {
+	account, ok := s.obAccount(w, r)
+	if !ok {
+		return nil
+	}
+	transfer, err := s.storeFor(r).GetTransferRequest(account.ID, r.PathValue("paymentId"))
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, OBPaymentStatus{TransactionStatus: obTransactionStatus(transfer.Status)})
+}
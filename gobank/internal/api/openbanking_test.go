@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOBTransaction(t *testing.T) {
+	at := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	out := obTransaction(&domain.Transaction{ID: 9, Amount: domain.Money{MinorUnits: -1250, Currency: "EUR"}, Counterparty: 4711007, CreatedAt: at}, time.UTC)
+	assert.Equal(t, OBAmount{Currency: "EUR", Amount: "-12.50"}, out.TransactionAmount)
+	assert.Equal(t, "4711007", out.CreditorAccount.BBAN)
+	assert.Nil(t, out.DebtorAccount)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	in := obTransaction(&domain.Transaction{ID: 10, Amount: domain.Money{MinorUnits: 500, Currency: "EUR"}, Counterparty: 4711007, CreatedAt: at}, berlin)
+	assert.Equal(t, "2024-03-02", in.BookingDate)
+	assert.Equal(t, "4711007", in.DebtorAccount.BBAN)
+}
+
+func TestOBAccountOfOtherHolder(t *testing.T) {
+	s := &APIServer{}
+	account := &domain.Account{ID: 7, UUID: "3f0e6c52-4a47-4b8e-9d59-0b3a54f1c7aa"}
+	call := func(resourceID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/open-banking/v1/accounts/"+resourceID, nil)
+		r.SetPathValue("accountId", resourceID)
+		r = r.WithContext(context.WithValue(r.Context(), callerKey{}, account))
+		w := httptest.NewRecorder()
+		assert.NoError(t, s.handleOBAccount(w, r))
+		return w
+	}
+	assert.Equal(t, http.StatusNotFound, call("8d8ac610-566d-4ef0-9c22-186b2a5ed793").Code)
+	w := call(account.UUID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"resourceId":"`+account.UUID)
+}
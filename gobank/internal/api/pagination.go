@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Page is the envelope of every paginated listing. Pass NextCursor as
+// ?cursor= to get the following page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// EncodeCursor packs the sort keys of the last item of a page into an opaque
+// cursor: base64url of a JSON array.
+func EncodeCursor(keys ...any) string {
+	data, _ := json.Marshal(keys)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor unpacks a cursor into pointers to the sort keys, in the order
+// they were encoded.
+func DecodeCursor(cursor string, keys ...any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return NewError(CodeInvalidParameter, "name", "cursor", "value", cursor)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw) != len(keys) {
+		return NewError(CodeInvalidParameter, "name", "cursor", "value", cursor)
+	}
+	for i, key := range keys {
+		if err := json.Unmarshal(raw[i], key); err != nil {
+			return NewError(CodeInvalidParameter, "name", "cursor", "value", cursor)
+		}
+	}
+	return nil
+}
+
+// pageParams reads ?cursor= and ?limit=.
+func pageParams(r *http.Request) (string, int, error) {
+	limit, err := QueryInt(r, "limit", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		return "", 0, err
+	}
+	return r.URL.Query().Get("cursor"), limit, nil
+}
+
+// NewPage builds a page from up to limit+1 rows: storage helpers fetch one
+// row more than asked for so has_more doesn't need a count query.
+func NewPage[T any](rows []T, limit int, keys func(T) []any) Page[T] {
+	page := Page[T]{Items: rows}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		page.HasMore = true
+		page.NextCursor = EncodeCursor(keys(page.Items[limit-1])...)
+	}
+	return page
+}
+
+// handleListTransactions serves GET /account/{id}/transactions, newest first.
+func (s *APIServer) handleListTransactions(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	var before domain.TransactionCursor
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &before.CreatedAt, &before.ID); err != nil {
+			return err
+		}
+	}
+	txs, err := s.storeFor(r).TransactionsBefore(id, before, limit+1)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, NewPage(txs, limit, func(t *domain.Transaction) []any { return []any{t.CreatedAt, t.ID} }))
+}
+
+// handleListEvents serves GET /admin/events, the audit trail, newest first.
+func (s *APIServer) handleListEvents(w http.ResponseWriter, r *http.Request) error {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	var before int64
+	if cursor != "" {
+		if err := DecodeCursor(cursor, &before); err != nil {
+			return err
+		}
+	}
+	events, err := s.store.EventsBefore(before, limit+1)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, NewPage(events, limit, func(ev *domain.Event) []any { return []any{ev.ID} }))
+}
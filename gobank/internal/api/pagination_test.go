@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 5, 12, 0, 0, 123, time.UTC)
+	cursor := EncodeCursor(at, 42)
+
+	var gotAt time.Time
+	var gotID int
+	assert.Nil(t, DecodeCursor(cursor, &gotAt, &gotID))
+	assert.True(t, at.Equal(gotAt))
+	assert.Equal(t, 42, gotID)
+
+	assert.NotNil(t, DecodeCursor(cursor, &gotID), "wrong number of keys")
+	assert.NotNil(t, DecodeCursor("not a cursor!", &gotID))
+}
+
+func TestNewPage(t *testing.T) {
+	key := func(n int) []any { return []any{n} }
+
+	page := NewPage([]int{1, 2, 3}, 2, key)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.True(t, page.HasMore)
+	var next int
+	assert.Nil(t, DecodeCursor(page.NextCursor, &next))
+	assert.Equal(t, 2, next)
+
+	page = NewPage([]int{1, 2}, 2, key)
+	assert.False(t, page.HasMore)
+	assert.Equal(t, "", page.NextCursor)
+}
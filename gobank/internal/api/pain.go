@@ -0,0 +1,336 @@
+package api
+
+import (
+	"encoding/xml"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// painMaxTransfers bounds the transfers of a pain.001 or CSV batch file.
+// Those of a debtor account are saved in one db transaction.
+const painMaxTransfers = 5000
+
+// The ISO 20022 statuses of a pain.002 report: accepted, partially accepted
+// and rejected.
+const (
+	painAccepted = "ACCP"
+	painPartial  = "PART"
+	painRejected = "RJCT"
+)
+
+const (
+	// painMessageName is the pain.001 version assumed when the document
+	// doesn't name its schema.
+	painMessageName = "pain.001.001.03"
+	// painTransfer is the only payment method taken, credit transfers.
+	painTransfer = "TRF"
+)
+
+// The ISO 20022 external status reason codes the ingestion reports.
+const (
+	painInvalidFile     = "FF01"
+	painInvalidAccount  = "AC01"
+	painInvalidAmount   = "AM12"
+	painDuplicate       = "AM05"
+	painWrongCount      = "AM18"
+	painWrongControlSum = "AM10"
+	painNarrativeReason = "NARR"
+)
+
+// pain001Document is a customer credit transfer initiation, pain.001. Only
+// the elements the bank acts on are read, in any version of the schema.
+type pain001Document struct {
+	XMLName    xml.Name `xml:"Document"`
+	Initiation struct {
+		MessageID string               `xml:"GrpHdr>MsgId"`
+		Count     string               `xml:"GrpHdr>NbOfTxs"`
+		CtrlSum   string               `xml:"GrpHdr>CtrlSum"`
+		Payments  []pain001PaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+// pain001PaymentInfo is a set of transfers from one debtor account.
+type pain001PaymentInfo struct {
+	ID           string               `xml:"PmtInfId"`
+	Method       string               `xml:"PmtMtd"`
+	Count        string               `xml:"NbOfTxs"`
+	CtrlSum      string               `xml:"CtrlSum"`
+	DebtorAcct   pain001AccountID     `xml:"DbtrAcct>Id"`
+	Transactions []pain001Transaction `xml:"CdtTrfTxInf"`
+}
+
+// pain001AccountID is an account's IBAN or, for accounts of the bank, its
+// number as the other identification.
+type pain001AccountID struct {
+	IBAN  string `xml:"IBAN"`
+	Other string `xml:"Othr>Id"`
+}
+
+type pain001Transaction struct {
+	EndToEndID string `xml:"PmtId>EndToEndId"`
+	Amount     struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt>InstdAmt"`
+	CreditorAcct pain001AccountID `xml:"CdtrAcct>Id"`
+}
+
+// pain002Document is the customer payment status report answering a
+// pain.001.
+type pain002Document struct {
+	XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.002.001.03 Document"`
+	Report  struct {
+		MessageID string `xml:"GrpHdr>MsgId"`
+		CreatedAt string `xml:"GrpHdr>CreDtTm"`
+		Group     struct {
+			MessageID   string         `xml:"OrgnlMsgId"`
+			MessageName string         `xml:"OrgnlMsgNmId"`
+			Count       int            `xml:"OrgnlNbOfTxs"`
+			Status      string         `xml:"GrpSts"`
+			Reason      *pain002Reason `xml:"StsRsnInf,omitempty"`
+		} `xml:"OrgnlGrpInfAndSts"`
+		Payments []*pain002PaymentStatus `xml:"OrgnlPmtInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+type pain002Reason struct {
+	Code       string `xml:"Rsn>Cd"`
+	Additional string `xml:"AddtlInf,omitempty"`
+}
+
+type pain002PaymentStatus struct {
+	ID           string                      `xml:"OrgnlPmtInfId"`
+	Status       string                      `xml:"PmtInfSts"`
+	Reason       *pain002Reason              `xml:"StsRsnInf,omitempty"`
+	Transactions []*pain002TransactionStatus `xml:"TxInfAndSts"`
+}
+
+// pain002TransactionStatus of an accepted transfer has the id to poll GET
+// /transfer/{id} with as StatusID.
+type pain002TransactionStatus struct {
+	StatusID   string         `xml:"StsId,omitempty"`
+	EndToEndID string         `xml:"OrgnlEndToEndId"`
+	Status     string         `xml:"TxSts"`
+	Reason     *pain002Reason `xml:"StsRsnInf,omitempty"`
+}
+
+func painReason(code, info string) *pain002Reason {
+	return &pain002Reason{Code: code, Additional: info}
+}
+
+// painStatus is the status of a group of which accepted out of total
+// transfers were accepted.
+func painStatus(accepted, total int) string {
+	switch {
+	case accepted == 0:
+		return painRejected
+	case accepted < total:
+		return painPartial
+	}
+	return painAccepted
+}
+
+// checkControlSum compares the control sum a pain.001 states, if it does, to
+// the amounts. It's the plain sum of the amounts whatever their currency.
+func checkControlSum(ctrlSum string, txs []pain001Transaction) bool {
+	if ctrlSum == "" {
+		return true
+	}
+	want, ok := new(big.Rat).SetString(strings.TrimSpace(ctrlSum))
+	if !ok {
+		return false
+	}
+	sum := new(big.Rat)
+	for _, t := range txs {
+		amount, ok := new(big.Rat).SetString(strings.TrimSpace(t.Amount.Value))
+		if !ok {
+			return false
+		}
+		sum.Add(sum, amount)
+	}
+	return sum.Cmp(want) == 0
+}
+
+// painAccount looks up the account of the bank id names, by IBAN or by
+// account number.
+func painAccount(store storage.Storage, id pain001AccountID) (*domain.Account, error) {
+	if id.IBAN != "" {
+		return accountByIBAN(store, id.IBAN)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(id.Other), 10, 64)
+	if err != nil {
+		return nil, domain.ErrAccountNotFound
+	}
+	return store.GetAccountByNumber(domain.AccountNumber(n))
+}
+
+// handleIngestPain001 serves POST /admin/payments/pain001?tenant= with a
+// pain.001 XML file. A file whose header doesn't add up is rejected whole.
+// Otherwise the transfers of each debtor account that pass validation are
+// accepted for processing in the background, like POST /transfer with
+// Prefer: respond-async, and the pain.002 answer reports the status of each.
+// A file with nothing accepted is a 422.
+func (s *APIServer) handleIngestPain001(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	body := http.MaxBytesReader(w, r.Body, importMaxBytes)
+	defer body.Close()
+	doc := new(pain001Document)
+	if err := xml.NewDecoder(body).Decode(doc); err != nil {
+		return NewError(CodeUnreadableBody, "format", "pain.001")
+	}
+
+	report, accepted := ingestPain001(store, service.NewTransferService(store, s.settings, s.clock, s.screening), doc, s.clock.Now(), s.logger)
+	group := report.Report.Group
+	if group.Reason == nil {
+		loggerFrom(r.Context()).Info("pain.001 ingested", "message_id", group.MessageID, "accepted", accepted, "rejected", group.Count-accepted)
+	}
+	if accepted == 0 {
+		return writePain002(w, http.StatusUnprocessableEntity, report)
+	}
+	return writePain002(w, http.StatusOK, report)
+}
+
+// newPain002 starts the report answering doc.
+func newPain002(doc *pain001Document, now time.Time) *pain002Document {
+	report := new(pain002Document)
+	report.Report.MessageID = domain.NewUUID()
+	report.Report.CreatedAt = now.UTC().Format(time.RFC3339)
+	group := &report.Report.Group
+	group.MessageID = doc.Initiation.MessageID
+	group.MessageName = painMessageName
+	if _, version, ok := strings.Cut(doc.XMLName.Space, "xsd:"); ok {
+		group.MessageName = version
+	}
+	for _, p := range doc.Initiation.Payments {
+		group.Count += len(p.Transactions)
+	}
+	return report
+}
+
+// ingestPain001 accepts the valid transfers of doc and returns the pain.002
+// reporting on them, with how many it accepted. A file whose header doesn't
+// add up is rejected whole, with the reason in the group status.
+func ingestPain001(store storage.Storage, transfers *service.TransferService, doc *pain001Document, now time.Time, logger *slog.Logger) (*pain002Document, int) {
+	report := newPain002(doc, now)
+	group := &report.Report.Group
+	var all []pain001Transaction
+	for _, p := range doc.Initiation.Payments {
+		all = append(all, p.Transactions...)
+	}
+	switch {
+	case doc.Initiation.MessageID == "":
+		group.Reason = painReason(painInvalidFile, "MsgId is missing")
+	case len(all) == 0 || len(all) > painMaxTransfers:
+		group.Reason = painReason(painWrongCount, "a file has 1 to "+strconv.Itoa(painMaxTransfers)+" transactions")
+	case doc.Initiation.Count != strconv.Itoa(len(all)):
+		group.Reason = painReason(painWrongCount, "")
+	case !checkControlSum(doc.Initiation.CtrlSum, all):
+		group.Reason = painReason(painWrongControlSum, "")
+	}
+	if group.Reason != nil {
+		group.Status = painRejected
+		return report, 0
+	}
+
+	seen := map[string]bool{}
+	accepted := 0
+	for _, p := range doc.Initiation.Payments {
+		status := ingestPayment(store, transfers, p, seen, logger)
+		for _, t := range status.Transactions {
+			if t.Status == painAccepted {
+				accepted++
+			}
+		}
+		report.Report.Payments = append(report.Report.Payments, status)
+	}
+	group.Status = painStatus(accepted, len(all))
+	return report, accepted
+}
+
+// ingestPayment accepts the valid transfers of a payment information block
+// and reports the status of each. seen holds the end to end ids of the
+// file, a repeated one is a duplicate.
+func ingestPayment(store storage.Storage, transfers *service.TransferService, p pain001PaymentInfo, seen map[string]bool, logger *slog.Logger) *pain002PaymentStatus {
+	status := &pain002PaymentStatus{ID: p.ID}
+	for _, t := range p.Transactions {
+		status.Transactions = append(status.Transactions, &pain002TransactionStatus{EndToEndID: t.EndToEndID, Status: painRejected})
+	}
+	reject := func(reason *pain002Reason) *pain002PaymentStatus {
+		status.Status, status.Reason = painRejected, reason
+		return status
+	}
+	if p.Method != painTransfer {
+		return reject(painReason(painNarrativeReason, "PmtMtd must be TRF"))
+	}
+	if p.Count != "" && p.Count != strconv.Itoa(len(p.Transactions)) {
+		return reject(painReason(painWrongCount, ""))
+	}
+	if !checkControlSum(p.CtrlSum, p.Transactions) {
+		return reject(painReason(painWrongControlSum, ""))
+	}
+	debtor, err := painAccount(store, p.DebtorAcct)
+	if err != nil {
+		return reject(painReason(painInvalidAccount, "debtor account"))
+	}
+
+	var orders []domain.TransferOrder
+	var pending []*pain002TransactionStatus
+	for i, t := range p.Transactions {
+		txStatus := status.Transactions[i]
+		if t.EndToEndID == "" || seen[t.EndToEndID] {
+			txStatus.Reason = painReason(painDuplicate, "EndToEndId must be unique")
+			continue
+		}
+		seen[t.EndToEndID] = true
+		units, err := domain.ParseDecimal(t.Amount.Value, t.Amount.Currency)
+		if err != nil || units <= 0 || t.Amount.Currency == "" {
+			txStatus.Reason = painReason(painInvalidAmount, "")
+			continue
+		}
+		creditor, err := painAccount(store, t.CreditorAcct)
+		if err != nil {
+			txStatus.Reason = painReason(painInvalidAccount, "creditor account")
+			continue
+		}
+		orders = append(orders, domain.TransferOrder{ToAccount: creditor.Number, Amount: domain.Money{MinorUnits: units, Currency: t.Amount.Currency}})
+		pending = append(pending, txStatus)
+	}
+	if len(orders) > 0 {
+		reqs, err := transfers.AcceptAll(debtor, orders)
+		if err != nil {
+			logger.Error("accepting pain.001 transfers failed", "payment_info_id", p.ID, "error", err)
+			return reject(painReason(painNarrativeReason, "internal error, send the payment again"))
+		}
+		for i, req := range reqs {
+			pending[i].Status, pending[i].StatusID = painAccepted, req.ID
+		}
+	}
+	status.Status = painStatus(len(orders), len(p.Transactions))
+	return status
+}
+
+func writePain002(w http.ResponseWriter, status int, doc *pain002Document) error {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	return encodePain002(w, doc)
+}
+
+func encodePain002(w io.Writer, doc *pain002Document) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
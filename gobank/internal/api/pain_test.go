@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/xml"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fakePainStore struct {
+	storage.Storage
+	accepted []*domain.TransferRequest
+}
+
+func (f *fakePainStore) GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error) {
+	if number == 4711007 || number == 4711008 {
+		return &domain.Account{ID: int(number), Number: number, Balance: domain.Money{Currency: "EUR"}}, nil
+	}
+	return nil, domain.ErrAccountNotFound
+}
+
+func (f *fakePainStore) CreateTransferRequests(reqs []*domain.TransferRequest, jobs []*domain.Job, guard *domain.TransferGuard) error {
+	f.accepted = append(f.accepted, reqs...)
+	return nil
+}
+
+const testPain001 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>3</NbOfTxs><CtrlSum>60.50</CtrlSum></GrpHdr>
+    <PmtInf>
+      <PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd><NbOfTxs>3</NbOfTxs>
+      <DbtrAcct><Id><Othr><Id>4711007</Id></Othr></Id></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="EUR">10.50</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>4711008</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="EUR">20</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>999</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="EUR">30</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>4711008</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+func TestIngestPain001Payment(t *testing.T) {
+	doc := new(pain001Document)
+	assert.NoError(t, xml.NewDecoder(strings.NewReader(testPain001)).Decode(doc))
+	assert.Equal(t, "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09", doc.XMLName.Space)
+	p := doc.Initiation.Payments[0]
+	assert.True(t, checkControlSum(doc.Initiation.CtrlSum, p.Transactions))
+	assert.False(t, checkControlSum("60.49", p.Transactions))
+
+	store := &fakePainStore{}
+	status := ingestPayment(store, service.NewTransferService(store, nil, domain.NewSimClock(), nil), p, map[string]bool{}, nil)
+	assert.Equal(t, painPartial, status.Status)
+	assert.Equal(t, painAccepted, status.Transactions[0].Status)
+	assert.Equal(t, painInvalidAccount, status.Transactions[1].Reason.Code)
+	assert.Equal(t, painDuplicate, status.Transactions[2].Reason.Code)
+	if assert.Len(t, store.accepted, 1) {
+		assert.Equal(t, store.accepted[0].ID, status.Transactions[0].StatusID)
+		assert.Equal(t, domain.Money{MinorUnits: 1050, Currency: "EUR"}, store.accepted[0].Amount)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The helpers below read typed path and query parameters. A value that
+// doesn't parse is answered with invalid_parameter naming the parameter, the
+// same on every endpoint.
+
+func invalidParameter(name, value string) error {
+	return NewError(CodeInvalidParameter, "name", name, "value", value)
+}
+
+// PathInt parses the path parameter name as a positive integer.
+func PathInt(r *http.Request, name string) (int, error) {
+	v := r.PathValue(name)
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, invalidParameter(name, v)
+	}
+	return n, nil
+}
+
+// PathUUID returns the path parameter name, lowercased, when it is a UUID in
+// its canonical 8-4-4-4-12 hex form.
+func PathUUID(r *http.Request, name string) (string, error) {
+	v := r.PathValue(name)
+	if !domain.IsUUID(v) {
+		return "", invalidParameter(name, v)
+	}
+	return strings.ToLower(v), nil
+}
+
+// QueryInt parses the query parameter name as an integer between min and
+// max. It returns def when the parameter is absent.
+func QueryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, invalidParameter(name, v)
+	}
+	return n, nil
+}
+
+// QueryTime parses the query parameter name with layout in loc. It returns
+// def when the parameter is absent.
+func QueryTime(r *http.Request, name, layout string, loc *time.Location, def time.Time) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	t, err := time.ParseInLocation(layout, v, loc)
+	if err != nil {
+		return time.Time{}, invalidParameter(name, v)
+	}
+	return t, nil
+}
+
+// QueryEnum returns the query parameter name when it is one of allowed. It
+// returns def when the parameter is absent.
+func QueryEnum(r *http.Request, name, def string, allowed ...string) (string, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	if !slices.Contains(allowed, v) {
+		return "", invalidParameter(name, v)
+	}
+	return v, nil
+}
+
+// QueryEnums parses the query parameter name as a comma separated list of
+// values of allowed. It returns def when the parameter is absent.
+func QueryEnums(r *http.Request, name string, def []string, allowed ...string) ([]string, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	values := strings.Split(v, ",")
+	for _, value := range values {
+		if !slices.Contains(allowed, value) {
+			return nil, invalidParameter(name, v)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPathParams(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetPathValue("id", "42")
+	id, err := PathInt(r, "id")
+	assert.Nil(t, err)
+	assert.Equal(t, 42, id)
+
+	for _, v := range []string{"", "0", "-1", "abc"} {
+		r.SetPathValue("id", v)
+		_, err := PathInt(r, "id")
+		assert.Equal(t, NewError(CodeInvalidParameter, "name", "id", "value", v), err, v)
+	}
+
+	r.SetPathValue("key", "9B2E1C4A-3F6D-4E8B-A1C2-0D3E4F5A6B7C")
+	key, err := PathUUID(r, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "9b2e1c4a-3f6d-4e8b-a1c2-0d3e4f5a6b7c", key)
+	for _, v := range []string{"9b2e1c4a3f6d4e8ba1c20d3e4f5a6b7c", "9b2e1c4a-3f6d-4e8b-a1c2-0d3e4f5a6b7g"} {
+		r.SetPathValue("key", v)
+		_, err := PathUUID(r, "key")
+		assert.NotNil(t, err, v)
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?days=7&limit=0&status=open&from=2024-03-01&to=tomorrow&type=login,hold", nil)
+
+	days, err := QueryInt(r, "days", 30, 1, 366)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, days)
+	_, err = QueryInt(r, "limit", 20, 1, 100)
+	assert.Equal(t, NewError(CodeInvalidParameter, "name", "limit", "value", "0"), err)
+	n, err := QueryInt(r, "missing", 20, 1, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 20, n)
+
+	status, err := QueryEnum(r, "status", "", "open", "resolved")
+	assert.Nil(t, err)
+	assert.Equal(t, "open", status)
+	_, err = QueryEnum(r, "status", "", "resolved")
+	assert.NotNil(t, err)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	from, err := QueryTime(r, "from", "2006-01-02", berlin, time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, berlin), from)
+	_, err = QueryTime(r, "to", "2006-01-02", berlin, time.Time{})
+	assert.Equal(t, NewError(CodeInvalidParameter, "name", "to", "value", "tomorrow"), err)
+
+	types, err := QueryEnums(r, "type", nil, "login", "hold", "profile")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"login", "hold"}, types)
+	_, err = QueryEnums(r, "type", nil, "login")
+	assert.Equal(t, NewError(CodeInvalidParameter, "name", "type", "value", "login,hold"), err)
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// The Parquet physical types, converted types, encodings and codec the
+// writer uses, see parquet.thrift.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetGzip = 2
+)
+
+// parquetFile is a table of required columns, written as a Parquet file
+// with a single row group where each column is one gzip compressed page of
+// plain encoded values. That is all Athena or BigQuery need to read it.
+type parquetFile struct {
+	columns []*parquetColumn
+}
+
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32
+	values    int
+	data      bytes.Buffer
+}
+
+func (f *parquetFile) column(name string, kind, converted int32) *parquetColumn {
+	c := &parquetColumn{name: name, kind: kind, converted: converted}
+	f.columns = append(f.columns, c)
+	return c
+}
+
+// Int64 adds a column of integers.
+func (f *parquetFile) Int64(name string) *parquetColumn {
+	return f.column(name, parquetInt64, -1)
+}
+
+// String adds a column of UTF-8 strings.
+func (f *parquetFile) String(name string) *parquetColumn {
+	return f.column(name, parquetByteArray, parquetUTF8)
+}
+
+// Timestamp adds a column of UTC timestamps in microseconds.
+func (f *parquetFile) Timestamp(name string) *parquetColumn {
+	return f.column(name, parquetInt64, parquetTimestampMicros)
+}
+
+func (c *parquetColumn) AppendInt64(v int64) {
+	c.data.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+	c.values++
+}
+
+func (c *parquetColumn) AppendString(s string) {
+	c.data.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	c.data.WriteString(s)
+	c.values++
+}
+
+// Rows is the number of rows, that of the first column. Every column has
+// to have as many values.
+func (f *parquetFile) Rows() int {
+	if len(f.columns) == 0 {
+		return 0
+	}
+	return f.columns[0].values
+}
+
+// chunk is where a column was written and how big it is.
+type parquetChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+func (f *parquetFile) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	out.WriteString("PAR1")
+	chunks := make([]parquetChunk, len(f.columns))
+	for i, c := range f.columns {
+		var page bytes.Buffer
+		zw := gzip.NewWriter(&page)
+		zw.Write(c.data.Bytes())
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		t := new(thriftWriter)
+		t.i32(1, 0) // data page
+		t.i32(2, int32(c.data.Len()))
+		t.i32(3, int32(page.Len()))
+		t.beginStruct(5)
+		t.i32(1, int32(c.values))
+		t.i32(2, parquetPlain)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.endStruct()
+		t.stop()
+		chunks[i] = parquetChunk{
+			offset:       int64(out.Len()),
+			uncompressed: int64(len(t.buf) + c.data.Len()),
+			compressed:   int64(len(t.buf) + page.Len()),
+		}
+		out.Write(t.buf)
+		out.Write(page.Bytes())
+	}
+	footer := f.metadata(chunks)
+	out.Write(footer)
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	out.WriteString("PAR1")
+	return out.WriteTo(w)
+}
+
+// metadata is the FileMetaData of the file.
+func (f *parquetFile) metadata(chunks []parquetChunk) []byte {
+	t := new(thriftWriter)
+	t.i32(1, 1)
+	t.beginList(2, thriftStruct, len(f.columns)+1)
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(f.columns)))
+	t.endStruct()
+	for _, c := range f.columns {
+		t.beginElement()
+		t.i32(1, c.kind)
+		t.i32(3, 0) // required
+		t.binary(4, c.name)
+		if c.converted >= 0 {
+			t.i32(6, c.converted)
+		}
+		t.endStruct()
+	}
+	t.i64(3, int64(f.Rows()))
+
+	t.beginList(4, thriftStruct, 1)
+	t.beginElement()
+	t.beginList(1, thriftStruct, len(f.columns))
+	var total int64
+	for i, c := range f.columns {
+		chunk := chunks[i]
+		total += chunk.uncompressed
+		t.beginElement()
+		t.i64(2, chunk.offset)
+		t.beginStruct(3)
+		t.i32(1, c.kind)
+		t.beginList(2, thriftI32, 2)
+		t.appendVarint(parquetPlain)
+		t.appendVarint(parquetRLE)
+		t.beginList(3, thriftBinary, 1)
+		t.appendString(c.name)
+		t.i32(4, parquetGzip)
+		t.i64(5, int64(c.values))
+		t.i64(6, chunk.uncompressed)
+		t.i64(7, chunk.compressed)
+		t.i64(9, chunk.offset)
+		t.endStruct()
+		t.endStruct()
+	}
+	t.i64(2, total)
+	t.i64(3, int64(f.Rows()))
+	t.endStruct()
+
+	t.binary(6, "gobank")
+	t.stop()
+	return t.buf
+}
+
+// The types of the Thrift compact protocol the Parquet metadata needs.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes structs in the Thrift compact protocol, where a field
+// header holds the difference of its id to that of the previous field of
+// the struct.
+type thriftWriter struct {
+	buf    []byte
+	last   int16
+	parent []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.appendVarint(int64(id))
+	}
+	t.last = id
+}
+
+// appendVarint appends v zigzag encoded, as i16, i32 and i64 values are.
+func (t *thriftWriter) appendVarint(v int64) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(v<<1^v>>63))
+}
+
+func (t *thriftWriter) appendString(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.appendVarint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.appendVarint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.appendString(s)
+}
+
+// beginList starts a list field of n elements of typ. Elements that are
+// structs are written between beginElement and endStruct.
+func (t *thriftWriter) beginList(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|typ)
+	t.buf = binary.AppendUvarint(t.buf, uint64(n))
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) beginElement() {
+	t.parent = append(t.parent, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.parent[len(t.parent)-1]
+	t.parent = t.parent[:len(t.parent)-1]
+}
+
+// stop ends the struct being written.
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestThriftWriter(t *testing.T) {
+	w := new(thriftWriter)
+	w.i32(1, -1)
+	w.beginStruct(3)
+	w.binary(1, "ab")
+	w.endStruct()
+	w.i64(20, 300)
+	w.beginList(21, thriftI32, 2)
+	w.appendVarint(1)
+	w.appendVarint(2)
+	w.stop()
+	assert.Equal(t, []byte{
+		0x15, 0x01, // field 1, i32 -1 zigzagged
+		0x2c, 0x18, 0x02, 'a', 'b', 0x00, // field 3, struct with field 1 "ab"
+		0x06, 0x28, 0xd8, 0x04, // field 20 is 17 after 3, so written in full
+		0x19, 0x25, 0x02, 0x04,
+		0x00,
+	}, w.buf)
+}
+
+func TestParquetFile(t *testing.T) {
+	f := new(parquetFile)
+	id, name := f.Int64("id"), f.String("name")
+	id.AppendInt64(7)
+	name.AppendString("seven")
+	id.AppendInt64(8)
+	name.AppendString("eight")
+	assert.Equal(t, 2, f.Rows())
+
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	assert.NoError(t, err)
+	file := buf.Bytes()
+	assert.Equal(t, "PAR1", string(file[:4]))
+	assert.Equal(t, "PAR1", string(file[len(file)-4:]))
+	footer := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	metadata := file[len(file)-8-footer : len(file)-8]
+	assert.Contains(t, string(metadata), "gobank")
+	assert.Contains(t, string(metadata), "name")
+
+	// the first page follows the magic and its header, which ends with the
+	// stops of the data page header and page header
+	page := file[4:]
+	start := bytes.Index(page, []byte{0x00, 0x00, 0x1f, 0x8b})
+	if assert.True(t, start > 0) {
+		zr, err := gzip.NewReader(bytes.NewReader(page[start+2:]))
+		assert.NoError(t, err)
+		zr.Multistream(false)
+		values, _ := io.ReadAll(zr)
+		assert.Equal(t, []byte{7, 0, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0}, values)
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+func TestPIIMasking(t *testing.T) {
+	account := &domain.Account{FirstName: "Anthony", Email: "anthony@example.com", Number: 1234567, EncryptedPassword: "$2a$hash"}
+	assert.Equal(t, "****4567 A*** a*** [REDACTED]", fmt.Sprintf("%d %s %v %v", account.Number, account.FirstName, account.Email, account.EncryptedPassword))
+	assert.Equal(t, "****", domain.AccountNumber(42).String())
+	assert.Equal(t, "account ****4567 not found", NewError(CodeAccountNotFound, "id", account.Number).Error())
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("login", "number", account.Number, "name", account.FirstName)
+	assert.Contains(t, buf.String(), "number=****4567 name=A***")
+
+	// the API still sends the real values
+	data, _ := json.Marshal(account)
+	assert.Contains(t, string(data), `"firstName":"Anthony"`)
+	assert.Contains(t, string(data), `"number":1234567`)
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"time"
+)
+
+// PortableVersion is the version of the portable account format. Bump it on
+// any incompatible change and keep importing the older versions.
+const PortableVersion = 1
+
+// PortableExport moves accounts with their complete ledger between
+// environments and storage backends. It carries no database ids, accounts keep
+// their number and UUID and ledger hashes are recomputed on import.
+type PortableExport struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	Accounts   []*PortableAccount `json:"accounts"`
+}
+
+type PortableAccount struct {
+	UUID         string                 `json:"uuid,omitempty"`
+	Number       domain.AccountNumber   `json:"number"`
+	FirstName    domain.PII             `json:"firstName"`
+	LastName     domain.PII             `json:"lastName"`
+	Email        domain.PII             `json:"email,omitempty"`
+	Phone        domain.PII             `json:"phone,omitempty"`
+	Address      *domain.Address        `json:"address,omitempty"`
+	DateOfBirth  domain.PII             `json:"dateOfBirth,omitempty"`
+	Timezone     string                 `json:"timezone"`
+	Language     string                 `json:"language,omitempty"`
+	PasswordHash domain.Secret          `json:"passwordHash"`
+	Balance      domain.Money           `json:"balance"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	Transactions []*PortableTransaction `json:"transactions"`
+}
+
+type PortableTransaction struct {
+	Type         string               `json:"type"`
+	Amount       domain.Money         `json:"amount"`
+	Counterparty domain.AccountNumber `json:"counterparty,omitempty"`
+	Description  string               `json:"description,omitempty"`
+	CreatedAt    time.Time            `json:"createdAt"`
+}
+
+func portableAccount(store storage.Storage, account *domain.Account) (*PortableAccount, error) {
+	pa := &PortableAccount{
+		UUID:         account.UUID,
+		Number:       account.Number,
+		FirstName:    account.FirstName,
+		LastName:     account.LastName,
+		Email:        account.Email,
+		Phone:        account.Phone,
+		Address:      account.Address,
+		DateOfBirth:  account.DateOfBirth,
+		Timezone:     account.Timezone,
+		Language:     account.Language,
+		PasswordHash: account.EncryptedPassword,
+		Balance:      account.Balance,
+		CreatedAt:    account.CreatedAt,
+		Transactions: []*PortableTransaction{},
+	}
+	err := store.EachLedgerEntry(account.ID, func(t *domain.Transaction) error {
+		pa.Transactions = append(pa.Transactions, &PortableTransaction{
+			Type:         t.Type,
+			Amount:       t.Amount,
+			Counterparty: t.Counterparty,
+			Description:  t.Description,
+			CreatedAt:    t.CreatedAt,
+		})
+		return nil
+	})
+	return pa, err
+}
+
+// writePortable writes the account with the given number, or every account
+// of the store's tenant when number is 0. Accounts are encoded one at a time
+// so that only one ledger is held in memory.
+func writePortable(w io.Writer, store storage.Storage, number int64, now time.Time) error {
+	header, err := json.Marshal(now.UTC())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, `{"version":%d,"exportedAt":%s,"accounts":[`, PortableVersion, header)
+	first := true
+	write := func(account *domain.Account) error {
+		pa, err := portableAccount(store, account)
+		if err != nil {
+			return err
+		}
+		if !first {
+			io.WriteString(w, ",")
+		}
+		first = false
+		return json.NewEncoder(w).Encode(pa)
+	}
+	if number != 0 {
+		account, err := store.GetAccountByNumber(domain.AccountNumber(number))
+		if err != nil {
+			return err
+		}
+		err = write(account)
+	} else {
+		err = store.EachAccount(write)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func (pa *PortableAccount) validate() error {
+	if pa.UUID != "" && !domain.IsUUID(pa.UUID) {
+		return fmt.Errorf("account %s: invalid uuid %q", pa.Number, pa.UUID)
+	}
+	contact := new(domain.Account)
+	if err := setContact(contact, &pa.Email, &pa.Phone); err != nil {
+		return fmt.Errorf("account %s: %w", pa.Number, err)
+	}
+	pa.Email, pa.Phone = contact.Email, contact.Phone
+	if pa.Address != nil {
+		pa.Address.Normalize()
+		if err := pa.Address.Validate(); err != nil {
+			return fmt.Errorf("account %s: %w", pa.Number, err)
+		}
+	}
+	// the holder had to be of age when the account was opened
+	if pa.DateOfBirth != "" {
+		if err := domain.ValidateDateOfBirth(pa.DateOfBirth, pa.CreatedAt); err != nil {
+			return fmt.Errorf("account %s: %w", pa.Number, err)
+		}
+	}
+	var sum int64
+	for i, t := range pa.Transactions {
+		if t.Type == "" {
+			return fmt.Errorf("account %s: transaction %d has no type", pa.Number, i+1)
+		}
+		if t.Amount.Currency != pa.Balance.Currency {
+			return fmt.Errorf("account %s: transaction %d is in %s, the account in %s", pa.Number, i+1, t.Amount.Currency, pa.Balance.Currency)
+		}
+		if len(t.Description) > 255 {
+			return fmt.Errorf("account %s: transaction %d has a description longer than 255 characters", pa.Number, i+1)
+		}
+		if t.Type != domain.TransactionHistory {
+			sum += t.Amount.MinorUnits
+		}
+	}
+	if sum != pa.Balance.MinorUnits {
+		return fmt.Errorf("account %s: the transactions add up to %d, not the balance %d", pa.Number, sum, pa.Balance.MinorUnits)
+	}
+	return nil
+}
+
+// importPortable validates the whole export before writing anything. Each
+// account is then created in its own db transaction, a failure part way
+// leaves the accounts before it imported.
+func importPortable(store storage.Storage, r io.Reader) (int, error) {
+	export := new(PortableExport)
+	if err := json.NewDecoder(r).Decode(export); err != nil {
+		return 0, NewError(CodeInvalidImport, "reason", err.Error())
+	}
+	if export.Version != PortableVersion {
+		return 0, NewError(CodeInvalidImport, "reason", fmt.Sprintf("unsupported version %d", export.Version))
+	}
+	for _, pa := range export.Accounts {
+		if err := pa.validate(); err != nil {
+			return 0, NewError(CodeInvalidImport, "reason", err.Error())
+		}
+	}
+	for i, pa := range export.Accounts {
+		account := &domain.Account{
+			UUID:              pa.UUID,
+			FirstName:         pa.FirstName,
+			LastName:          pa.LastName,
+			Email:             pa.Email,
+			Phone:             pa.Phone,
+			Address:           pa.Address,
+			DateOfBirth:       pa.DateOfBirth,
+			Timezone:          pa.Timezone,
+			Language:          pa.Language,
+			Number:            pa.Number,
+			EncryptedPassword: pa.PasswordHash,
+			Balance:           domain.Money{Currency: pa.Balance.Currency},
+			CreatedAt:         pa.CreatedAt,
+			UpdatedAt:         pa.CreatedAt,
+			Version:           1,
+		}
+		if err := store.CreateAccount(account); err != nil {
+			return i, err
+		}
+		if len(pa.Transactions) == 0 {
+			continue
+		}
+		txs := make([]*domain.Transaction, len(pa.Transactions))
+		for j, t := range pa.Transactions {
+			txs[j] = &domain.Transaction{Type: t.Type, Amount: t.Amount, Counterparty: t.Counterparty, Description: t.Description, CreatedAt: t.CreatedAt}
+		}
+		if err := store.ImportTransactions(account.ID, txs); err != nil {
+			return i, err
+		}
+	}
+	return len(export.Accounts), nil
+}
+
+// handlePortableAccounts serves /admin/accounts/portable?tenant=. GET exports
+// the tenant's accounts, or just ?account=NUMBER, POST imports an export.
+func (s *APIServer) handlePortableAccounts(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		number, err := QueryInt(r, "account", 0, 0, math.MaxInt)
+		if err != nil {
+			return err
+		}
+		if number != 0 {
+			if _, err := store.GetAccountByNumber(domain.AccountNumber(number)); err != nil {
+				return err
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := writePortable(w, store, int64(number), s.clock.Now()); err != nil {
+			// the status line is out already, all that's left is to cut the body short
+			loggerFrom(r.Context()).Error("portable export failed", "error", err)
+		}
+		return nil
+	case http.MethodPost:
+		defer r.Body.Close()
+		n, err := importPortable(store, r.Body)
+		if err != nil {
+			return err
+		}
+		loggerFrom(r.Context()).Info("portable accounts imported", "count", n)
+		return WriteJSON(w, http.StatusOK, map[string]int{"imported": n})
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
+
+// RunPortableCommand implements `gobank export [-tenant slug] [-account number] file`
+// and `gobank import [-tenant slug] file`.
+func RunPortableCommand(store storage.Storage, clock domain.Clock, args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	tenantSlug := fs.String("tenant", domain.DefaultTenantSlug, "tenant slug")
+	number := fs.Int64("account", 0, "account number, all accounts when left out")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gobank %s [-tenant slug] [-account number] file", args[0])
+	}
+	tenant, err := store.GetTenantBySlug(*tenantSlug)
+	if err != nil {
+		return err
+	}
+	store = store.ForTenant(tenant.ID)
+	switch args[0] {
+	case "export":
+		f, err := os.Create(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := writePortable(f, store, *number, clock.Now()); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case "import":
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := importPortable(store, f)
+		fmt.Printf("imported %d account(s)\n", n)
+		return err
+	}
+	return fmt.Errorf("unknown command %s", args[0])
+}
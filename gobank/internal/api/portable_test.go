@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakePortableStore struct {
+	storage.Storage
+	accounts []*domain.Account
+	ledgers  map[int][]*domain.Transaction
+}
+
+func (f *fakePortableStore) EachAccount(fn func(*domain.Account) error) error {
+	for _, a := range f.accounts {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakePortableStore) EachLedgerEntry(accountID int, fn func(*domain.Transaction) error) error {
+	for _, t := range f.ledgers[accountID] {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakePortableStore) CreateAccount(account *domain.Account) error {
+	account.ID = len(f.accounts) + 1
+	f.accounts = append(f.accounts, account)
+	return nil
+}
+
+func (f *fakePortableStore) ImportTransactions(accountID int, txs []*domain.Transaction) error {
+	if f.ledgers == nil {
+		f.ledgers = map[int][]*domain.Transaction{}
+	}
+	f.ledgers[accountID] = append(f.ledgers[accountID], txs...)
+	return nil
+}
+
+func TestPortableRoundTrip(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := &fakePortableStore{
+		accounts: []*domain.Account{{ID: 9, Number: 1234, FirstName: "Ada", LastName: "Costa", Timezone: "UTC", EncryptedPassword: "$2a$hash", Balance: domain.Money{MinorUnits: 750, Currency: "EUR"}, CreatedAt: created}},
+		ledgers: map[int][]*domain.Transaction{9: {
+			{ID: 1, AccountID: 9, Type: domain.TransactionImport, Amount: domain.Money{MinorUnits: 1000, Currency: "EUR"}, CreatedAt: created},
+			{ID: 2, AccountID: 9, Type: domain.TransactionTransferOut, Amount: domain.Money{MinorUnits: -250, Currency: "EUR"}, Counterparty: 42, CreatedAt: created.Add(time.Hour)},
+		}},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, writePortable(&buf, src, 0, created))
+	var export PortableExport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &export))
+	assert.Equal(t, PortableVersion, export.Version)
+
+	dst := &fakePortableStore{}
+	n, err := importPortable(dst, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, domain.AccountNumber(1234), dst.accounts[0].Number)
+	assert.Equal(t, domain.Secret("$2a$hash"), dst.accounts[0].EncryptedPassword)
+	assert.Len(t, dst.ledgers[1], 2)
+	assert.Equal(t, domain.AccountNumber(42), dst.ledgers[1][1].Counterparty)
+}
+
+func TestPortableImportRejectsInconsistentBalances(t *testing.T) {
+	body := `{"version":1,"accounts":[{"number":1,"balance":{"minor_units":500,"currency":"EUR"},
+		"transactions":[{"type":"import","amount":{"minor_units":400,"currency":"EUR"}}]}]}`
+	dst := &fakePortableStore{}
+	_, err := importPortable(dst, strings.NewReader(body))
+	assert.Equal(t, CodeInvalidImport, err.(*Error).Code)
+	assert.Empty(t, dst.accounts)
+
+	_, err = importPortable(dst, strings.NewReader(`{"version":2,"accounts":[]}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strings"
+)
+
+// PotRequest creates a pot, or changes one where fields left out stay as
+// they are.
+type PotRequest struct {
+	Name   *string       `json:"name"`
+	Amount *domain.Money `json:"amount"`
+}
+
+// MinimumBalanceRequest sets an account's minimum balance, null leaves it to
+// the tenant's settings.
+type MinimumBalanceRequest struct {
+	MinimumBalance *domain.Money `json:"minimumBalance"`
+}
+
+// Balances is an account's balance broken down like the summary does.
+type Balances struct {
+	Balance          domain.Money `json:"balance"`
+	AvailableBalance domain.Money `json:"availableBalance"`
+	HeldBalance      domain.Money `json:"heldBalance"`
+	ProtectedBalance domain.Money `json:"protectedBalance"`
+	MinimumBalance   domain.Money `json:"minimumBalance"`
+}
+
+func newBalances(a domain.Availability) Balances {
+	money := func(units int64) domain.Money { return domain.Money{MinorUnits: units, Currency: a.Balance.Currency} }
+	return Balances{
+		Balance:          a.Balance,
+		AvailableBalance: a.Available(),
+		HeldBalance:      money(a.Held),
+		ProtectedBalance: money(a.Protected),
+		MinimumBalance:   money(a.Minimum),
+	}
+}
+
+// accountAmount checks an amount to set aside on the account, one without a
+// currency is in the account's.
+func accountAmount(account *domain.Account, amount domain.Money) (int64, error) {
+	if amount.MinorUnits < 0 {
+		return 0, domain.ErrInvalidAmount
+	}
+	if amount.Currency != "" && amount.Currency != account.Balance.Currency {
+		return 0, domain.ErrCurrencyMismatch
+	}
+	return amount.MinorUnits, nil
+}
+
+// handlePots serves /account/{id}/pots, money the holder puts aside so it
+// can't be transferred by mistake. It stays in the balance and is left out
+// of the available balance.
+func (s *APIServer) handlePots(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	if isGet(r) {
+		pots, err := store.ListPots(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, pots)
+	}
+	req := new(PotRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		return invalidParameter("name", "")
+	}
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	var amount int64
+	if req.Amount != nil {
+		if amount, err = accountAmount(account, *req.Amount); err != nil {
+			return err
+		}
+	}
+	pot := domain.NewPot(account, strings.TrimSpace(*req.Name), amount, s.clock.Now())
+	if err := store.CreatePot(pot); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, pot)
+}
+
+// handleUpdatePot serves PATCH /account/{id}/pots/{potId}, which renames the
+// pot or sets how much is in it.
+func (s *APIServer) handleUpdatePot(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req := new(PotRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	pot, err := store.GetPot(id, r.PathValue("potId"))
+	if err != nil {
+		return err
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return invalidParameter("name", *req.Name)
+		}
+		pot.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Amount != nil {
+		account, err := store.GetAccountById(id)
+		if err != nil {
+			return err
+		}
+		if pot.Amount.MinorUnits, err = accountAmount(account, *req.Amount); err != nil {
+			return err
+		}
+	}
+	pot.UpdatedAt = s.clock.Now().UTC()
+	if err := store.UpdatePot(pot); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, pot)
+}
+
+func (s *APIServer) handleDeletePot(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	potID := r.PathValue("potId")
+	if err := s.storeFor(r).DeletePot(id, potID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": potID})
+}
+
+// handleMinimumBalance serves PUT /admin/accounts/{id}/minimum-balance. A
+// minimum above the balance doesn't move any money, it only stops transfers
+// until the balance is back above it.
+func (s *APIServer) handleMinimumBalance(w http.ResponseWriter, r *http.Request) error {
+	ref, err := parseAccountRef(r)
+	if err != nil {
+		return err
+	}
+	req := new(MinimumBalanceRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	account, err := ref.lookup(store)
+	if err != nil {
+		return err
+	}
+	s.resolveAccountRef(w, r, ref, account)
+	var minimum *int64
+	if req.MinimumBalance != nil {
+		units, err := accountAmount(account, *req.MinimumBalance)
+		if err != nil {
+			return err
+		}
+		minimum = &units
+	}
+	if err := store.SetMinimumBalance(account.ID, minimum); err != nil {
+		return err
+	}
+	a, err := store.Availability(account.ID)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, newBalances(a))
+}
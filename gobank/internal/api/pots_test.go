@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakePotStore keeps the pots of one account and checks them against its
+// available balance the way the store does.
+type fakePotStore struct {
+	storage.Storage
+	balance int64
+	pots    map[string]*domain.Pot
+}
+
+func (f *fakePotStore) GetAccountById(id int) (*domain.Account, error) {
+	return &domain.Account{ID: id, Balance: domain.Money{MinorUnits: f.balance, Currency: "EUR"}}, nil
+}
+
+func (f *fakePotStore) Availability(accountID int) (domain.Availability, error) {
+	a := domain.Availability{Balance: domain.Money{MinorUnits: f.balance, Currency: "EUR"}}
+	for _, p := range f.pots {
+		a.Protected += p.Amount.MinorUnits
+	}
+	return a, nil
+}
+
+func (f *fakePotStore) CreatePot(p *domain.Pot) error {
+	a, _ := f.Availability(p.AccountID)
+	if err := a.Check(p.Amount.MinorUnits); err != nil {
+		return err
+	}
+	f.pots[p.ID] = p
+	return nil
+}
+
+func (f *fakePotStore) GetPot(accountID int, id string) (*domain.Pot, error) {
+	p, ok := f.pots[id]
+	if !ok {
+		return nil, domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (f *fakePotStore) UpdatePot(p *domain.Pot) error {
+	if more := p.Amount.MinorUnits - f.pots[p.ID].Amount.MinorUnits; more > 0 {
+		a, _ := f.Availability(p.AccountID)
+		if err := a.Check(more); err != nil {
+			return err
+		}
+	}
+	f.pots[p.ID] = p
+	return nil
+}
+
+func (f *fakePotStore) DeletePot(accountID int, id string) error {
+	if _, ok := f.pots[id]; !ok {
+		return domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	delete(f.pots, id)
+	return nil
+}
+
+func TestPotsAgainstTheAvailableBalance(t *testing.T) {
+	store := &fakePotStore{balance: 1000, pots: map[string]*domain.Pot{}}
+	s := &APIServer{store: store, clock: domain.NewSimClock()}
+	call := func(h apiFunc, method, body, potID string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(method, "/account/7/pots", strings.NewReader(body))
+		r.SetPathValue("id", "7")
+		r.SetPathValue("potId", potID)
+		rec := httptest.NewRecorder()
+		return rec, h(rec, r)
+	}
+	available := func() int64 {
+		a, _ := store.Availability(7)
+		return a.Available().MinorUnits
+	}
+
+	rec, err := call(s.handlePots, "POST", `{"name": "rent", "amount": {"minor_units": 600}}`, "")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var pot domain.Pot
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &pot))
+	assert.Equal(t, int64(400), available())
+
+	// more than is available can't be put aside, in a new pot or an old one
+	_, err = call(s.handlePots, "POST", `{"name": "holiday", "amount": {"minor_units": 500}}`, "")
+	assert.ErrorIs(t, err, domain.ErrFundsProtected)
+	_, err = call(s.handleUpdatePot, "PATCH", `{"amount": {"minor_units": 1001}}`, pot.ID)
+	assert.ErrorIs(t, err, domain.ErrFundsProtected)
+	_, err = call(s.handleUpdatePot, "PATCH", `{"amount": {"minor_units": 1601}}`, pot.ID)
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+	assert.Equal(t, int64(400), available())
+
+	// growing it needs the difference only, shrinking it gives the rest back
+	_, err = call(s.handleUpdatePot, "PATCH", `{"amount": {"minor_units": 1000}}`, pot.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), available())
+	_, err = call(s.handleUpdatePot, "PATCH", `{"amount": {"minor_units": 200}}`, pot.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(800), available())
+
+	_, err = call(s.handleDeletePot, "DELETE", "", pot.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), available())
+	_, err = call(s.handleDeletePot, "DELETE", "", pot.ID)
+	assert.ErrorIs(t, err, domain.ErrPotNotFound)
+}
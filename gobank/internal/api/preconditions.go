@@ -0,0 +1,46 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountETag is the strong validator of an account representation. The
+// version changes on every write, balance changes included.
+func accountETag(account *domain.Account) string {
+	return `"` + strconv.Itoa(account.Version) + `"`
+}
+
+func setValidators(w http.ResponseWriter, account *domain.Account) {
+	w.Header().Set("ETag", accountETag(account))
+	w.Header().Set("Last-Modified", account.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+func hasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != ""
+}
+
+// checkPreconditions evaluates If-Match and If-Unmodified-Since against the
+// current account. As in RFC 9110, If-Unmodified-Since is ignored when
+// If-Match is present, and so is a date that doesn't parse.
+func checkPreconditions(r *http.Request, account *domain.Account) error {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		etag := accountETag(account)
+		for _, candidate := range strings.Split(ifMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == etag {
+				return nil
+			}
+		}
+		return NewError(CodePreconditionFailed)
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil {
+		if account.UpdatedAt.Truncate(time.Second).After(since) {
+			return NewError(CodePreconditionFailed)
+		}
+	}
+	return nil
+}
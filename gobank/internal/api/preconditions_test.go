@@ -0,0 +1,28 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+	account := &domain.Account{Version: 3, UpdatedAt: updated}
+
+	check := func(header, value string) error {
+		r := httptest.NewRequest("PATCH", "/account/1", nil)
+		r.Header.Set(header, value)
+		return checkPreconditions(r, account)
+	}
+	assert.Nil(t, check("If-Match", `"3"`))
+	assert.Nil(t, check("If-Match", `"2", "3"`))
+	assert.Nil(t, check("If-Match", "*"))
+	assert.NotNil(t, check("If-Match", `"2"`))
+
+	assert.Nil(t, check("If-Unmodified-Since", updated.Format("Mon, 02 Jan 2006 15:04:05 GMT")))
+	assert.NotNil(t, check("If-Unmodified-Since", updated.Add(-time.Minute).Format("Mon, 02 Jan 2006 15:04:05 GMT")))
+	assert.Nil(t, check("If-Unmodified-Since", "yesterday"))
+}
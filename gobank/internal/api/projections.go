@@ -0,0 +1,96 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// projectionBatch is how many entries a pass applies at most.
+const projectionBatch = 1000
+
+// Projector keeps the read tables the summary and analytics endpoints are
+// served from up to date with the ledger, see storage/projections.go. One
+// instance runs it.
+type Projector struct {
+	store    storage.ProjectionStore
+	metrics  *Metrics
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewProjector(store storage.ProjectionStore, metrics *Metrics, logger *slog.Logger) *Projector {
+	metrics.Help("projection_entries_total", "Ledger entries applied by projection.")
+	return &Projector{store: store, metrics: metrics, interval: time.Second, logger: logger}
+}
+
+func (p *Projector) Run(stop <-chan struct{}) {
+	for {
+		behind := false
+		for _, name := range domain.Projections {
+			n, err := p.store.Project(name, projectionBatch)
+			if err != nil {
+				p.logger.Error("projection failed", "projection", name, "error", err)
+				continue
+			}
+			if n > 0 {
+				p.metrics.Add("projection_entries_total", float64(n), "projection", name)
+			}
+			behind = behind || n == projectionBatch
+		}
+		if behind {
+			continue
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// handleProjections serves GET /admin/projections, how far each one got.
+func (s *APIServer) handleProjections(w http.ResponseWriter, r *http.Request) error {
+	projections, err := s.store.Projections()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, projections)
+}
+
+// handleRebuildProjection serves POST /admin/projections/{name}/rebuild.
+// The projection is emptied and the projector builds it again in the
+// background, reads are served from the ledger in the meantime.
+func (s *APIServer) handleRebuildProjection(w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	if err := s.store.ResetProjection(name); err != nil {
+		return err
+	}
+	s.logger.Info("projection reset for rebuild", "projection", name)
+	projections, err := s.store.Projections()
+	if err != nil {
+		return err
+	}
+	for _, p := range projections {
+		if p.Name == name {
+			return WriteJSON(w, http.StatusAccepted, p)
+		}
+	}
+	return domain.NotFound(domain.ErrProjectionNotFound, name)
+}
+
+// handleBalanceReport serves GET /admin/reports/balances?tenant=, the
+// tenant's ledger balances per currency from the balance projection.
+func (s *APIServer) handleBalanceReport(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	totals, err := store.BalanceTotals()
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, totals)
+}
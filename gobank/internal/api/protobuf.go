@@ -0,0 +1,89 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/api/pb"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// protobufContentType is offered by the endpoints the mobile apps poll, see
+// pb/gobank.proto. Handlers encode the messages themselves, without the JSON
+// detour the codecs take, which would defeat the point.
+const protobufContentType = "application/x-protobuf"
+
+func wantsProtobuf(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == protobufContentType {
+			return true
+		}
+	}
+	return false
+}
+
+func isProtobuf(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == protobufContentType
+}
+
+func writeProtobuf(w http.ResponseWriter, status int, m interface{ Marshal() []byte }) error {
+	w.Header().Set("Content-Type", protobufContentType)
+	w.WriteHeader(status)
+	_, err := w.Write(m.Marshal())
+	return err
+}
+
+// readTransferProto reads a pb.TransferRequest body.
+func readTransferProto(w http.ResponseWriter, r *http.Request) (*TransferAccount, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, schemaMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	req := new(pb.TransferRequest)
+	if err := req.Unmarshal(body); err != nil {
+		loggerFrom(r.Context()).Info("unreadable request body", "content_type", protobufContentType, "error", err)
+		return nil, NewError(CodeUnreadableBody, "format", protobufContentType)
+	}
+	transfer := &TransferAccount{ToAccount: domain.AccountNumber(req.ToAccount), AllowDuplicate: req.AllowDuplicate}
+	if req.Amount != nil {
+		transfer.Amount = domain.Money{MinorUnits: req.Amount.MinorUnits, Currency: strings.ToUpper(req.Amount.Currency)}
+	}
+	return transfer, nil
+}
+
+func moneyProto(m domain.Money) *pb.Money {
+	return &pb.Money{MinorUnits: m.MinorUnits, Currency: m.Currency}
+}
+
+func accountProto(a *domain.Account) *pb.Account {
+	return &pb.Account{
+		ID:        int64(a.ID),
+		UUID:      a.UUID,
+		FirstName: a.FirstName.Reveal(),
+		LastName:  a.LastName.Reveal(),
+		Email:     a.Email.Reveal(),
+		Timezone:  a.Timezone,
+		Language:  a.Language,
+		Number:    a.Number.Reveal(),
+		Balance:   moneyProto(a.Balance),
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+		Version:   int32(a.Version),
+		TenantID:  int64(a.TenantID),
+	}
+}
+
+func transactionProto(t *domain.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		ID:           int64(t.ID),
+		AccountID:    int64(t.AccountID),
+		Type:         t.Type,
+		Amount:       moneyProto(t.Amount),
+		Counterparty: t.Counterparty.Reveal(),
+		Description:  t.Description,
+		CreatedAt:    t.CreatedAt,
+		Hash:         t.Hash,
+	}
+}
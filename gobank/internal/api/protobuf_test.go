@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"github.com/iamuditg/internal/api/pb"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProtobufRoundTrip(t *testing.T) {
+	// field 1 varint 150, field 2 "EUR", as in the protobuf encoding guide
+	m := &pb.Money{MinorUnits: 150, Currency: "EUR"}
+	assert.Equal(t, []byte{0x08, 0x96, 0x01, 0x12, 0x03, 'E', 'U', 'R'}, m.Marshal())
+
+	created := time.Date(2024, 1, 31, 12, 0, 0, 500, time.UTC)
+	tx := &pb.Transaction{ID: 7, AccountID: -1, Type: "transfer", Amount: m, CreatedAt: created, Hash: "ab"}
+	got := new(pb.Transaction)
+	assert.Nil(t, got.Unmarshal(tx.Marshal()))
+	assert.Equal(t, tx, got)
+
+	// unknown fields are skipped, truncated messages refused
+	assert.Nil(t, new(pb.Money).Unmarshal(append(m.Marshal(), 0x78, 0x01)))
+	assert.NotNil(t, new(pb.Money).Unmarshal(m.Marshal()[:5]))
+}
+
+func TestReadTransferProto(t *testing.T) {
+	body := (&pb.TransferRequest{ToAccount: 4711, Amount: &pb.Money{MinorUnits: 250, Currency: "eur"}}).Marshal()
+	r := httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(body))
+	r.Header.Set("Content-Type", protobufContentType)
+	assert.True(t, isProtobuf(r))
+	transfer, err := readTransferProto(httptest.NewRecorder(), r)
+	assert.Nil(t, err)
+	assert.Equal(t, &TransferAccount{ToAccount: 4711, Amount: domain.Money{MinorUnits: 250, Currency: "EUR"}}, transfer)
+
+	r = httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewReader([]byte{0x0a}))
+	_, err = readTransferProto(httptest.NewRecorder(), r)
+	assert.Equal(t, CodeUnreadableBody, err.(*Error).Code)
+}
+
+func TestWantsProtobuf(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/account/1", nil)
+	assert.False(t, wantsProtobuf(r))
+	r.Header.Set("Accept", "application/json;q=0.5, application/x-protobuf")
+	assert.True(t, wantsProtobuf(r))
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loginNotice is the data of the push.login templates.
+type loginNotice struct {
+	Brand string
+	Time  time.Time
+}
+
+// PushNotifier queues push notifications of transfers and logins for every
+// device the holder registered. Registering a device is what turns them on.
+type PushNotifier struct {
+	store    storage.Storage
+	settings *TenantSettingsCache
+	logger   *slog.Logger
+}
+
+func NewPushNotifier(store storage.Storage, logger *slog.Logger) *PushNotifier {
+	return &PushNotifier{store: store, settings: NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings), logger: logger}
+}
+
+// Notify is subscribed to the bus.
+func (n *PushNotifier) Notify(ev *domain.Event) {
+	var err error
+	switch ev.Type {
+	case domain.EventTransferCompleted:
+		err = n.transfers(ev)
+	case domain.EventAccountLogin:
+		err = n.login(ev)
+	}
+	if err != nil {
+		n.logger.Error("queueing push notification failed", "event_id", ev.ID, "event_type", ev.Type, "error", err)
+	}
+}
+
+func (n *PushNotifier) transfers(ev *domain.Event) error {
+	var payload struct {
+		TransactionID int `json:"transactionId"`
+	}
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		return err
+	}
+	out, in, err := n.store.TransferLegs(payload.TransactionID)
+	if errors.Is(err, domain.ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, tx := range []*domain.Transaction{out, in} {
+		if tx == nil {
+			continue
+		}
+		account, err := n.store.FindAccount(tx.AccountID)
+		if errors.Is(err, domain.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		title, _, ok, err := renderTransferNotice(account, tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		name := "push.transfer_received"
+		if tx.Type == domain.TransactionTransferOut {
+			name = "push.transfer_sent"
+		}
+		body, err := renderText(account, name, transferNotice{Account: account, Transaction: tx})
+		if err != nil {
+			return err
+		}
+		data := map[string]string{"type": domain.PushTransfer, "transactionId": strconv.Itoa(tx.ID)}
+		if err := n.queue(account, domain.PushTransfer, fmt.Sprintf("transfer:%d", tx.ID), title, body, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *PushNotifier) login(ev *domain.Event) error {
+	account, err := n.store.FindAccount(ev.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings, err := n.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	data := loginNotice{Brand: settings.BrandName, Time: ev.CreatedAt}
+	title, err := renderText(account, "push.login.title", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "push.login.body", data)
+	if err != nil {
+		return err
+	}
+	return n.queue(account, domain.PushLogin, fmt.Sprintf("login:%d", ev.ID), title, body, map[string]string{"type": domain.PushLogin})
+}
+
+// queue saves a notification for each of the account's devices.
+func (n *PushNotifier) queue(account *domain.Account, kind, key, title, body string, data map[string]string) error {
+	store := n.store.ForTenant(account.TenantID)
+	devices, err := store.ListDevices(account.ID)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if _, err := store.QueuePush(domain.NewPush(account, device, kind, key, title, body, data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushDeliverer sends the queued notifications with the sender of their
+// device's platform.
+type PushDeliverer struct {
+	store   storage.Storage
+	senders map[string]PushSender
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewPushDeliverer(store storage.Storage, senders map[string]PushSender, metrics *Metrics, logger *slog.Logger) *PushDeliverer {
+	metrics.Help("push_notifications_total", "Push notification send attempts by kind, platform and result.")
+	return &PushDeliverer{store: store, senders: senders, metrics: metrics, logger: logger}
+}
+
+// HandleJob makes one attempt to send a notification, like
+// EmailDeliverer.HandleJob. A device whose token the push service no longer
+// knows is forgotten.
+func (d *PushDeliverer) HandleJob(job *domain.Job) error {
+	var payload storage.PushJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	push, err := d.store.GetPush(payload.PushID)
+	if errors.Is(err, domain.ErrPushNotFound) {
+		// the device or the account was deleted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if push.Status != domain.EmailPending {
+		return nil
+	}
+	device, err := d.store.GetDevice(push.DeviceID)
+	if errors.Is(err, domain.ErrDeviceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	push.Attempts++
+	var sendErr error
+	if sender := d.senders[device.Platform]; sender == nil {
+		push.Status, push.LastError = domain.EmailFailed, "no push service for "+device.Platform
+	} else {
+		var providerID string
+		providerID, sendErr = sender.Push(device.Token, &PushMessage{Title: push.Title, Body: push.Body, Data: push.Data})
+		now := time.Now().UTC()
+		var refused *PushRefusedError
+		switch {
+		case sendErr == nil:
+			push.Status, push.ProviderID, push.SentAt, push.LastError = domain.EmailSent, providerID, &now, ""
+		case errors.As(sendErr, &refused):
+			push.Status, push.LastError = domain.EmailFailed, sendErr.Error()
+		default:
+			push.LastError = sendErr.Error()
+		}
+	}
+	if err := d.store.RecordPushAttempt(push); err != nil {
+		return err
+	}
+	result := push.Status
+	if result == domain.EmailPending {
+		result = "retry"
+	}
+	d.metrics.Inc("push_notifications_total", "kind", push.Kind, "platform", device.Platform, "result", result)
+	if push.Status == domain.EmailPending {
+		return fmt.Errorf("push %s: %w", push.ID, sendErr)
+	}
+	if push.Status == domain.EmailFailed {
+		d.logger.Warn("push notification not sent", "push_id", push.ID, "device_id", device.ID, "error", push.LastError)
+	}
+	var refused *PushRefusedError
+	if errors.As(sendErr, &refused) && refused.TokenGone {
+		return d.store.ForgetDevice(device.ID)
+	}
+	return nil
+}
+
+type RegisterDeviceRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+}
+
+// handleDevices serves /account/{id}/devices, the app installs that get
+// push notifications. Apps register their token on every start, a known
+// token answers 200 instead of 201.
+func (s *APIServer) handleDevices(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	if isGet(r) {
+		devices, err := store.ListDevices(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, devices)
+	}
+	req := new(RegisterDeviceRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if !validPushToken(req.Platform, req.Token) {
+		return NewError(CodeInvalidDeviceToken, "platform", req.Platform)
+	}
+	device := domain.NewDevice(id, req.Platform, req.Token, strings.TrimSpace(req.Name))
+	created := device.ID
+	if err := store.RegisterDevice(device); err != nil {
+		return err
+	}
+	if device.ID != created {
+		return WriteJSON(w, http.StatusOK, device)
+	}
+	return WriteJSON(w, http.StatusCreated, device)
+}
+
+func (s *APIServer) handleDeleteDevice(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deviceID := r.PathValue("deviceId")
+	if err := s.storeFor(r).DeleteDevice(id, deviceID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": deviceID})
+}
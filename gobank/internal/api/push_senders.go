@@ -0,0 +1,367 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PushMessage is a notification as it goes to a push service.
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushSender delivers notifications to the devices of one platform and
+// returns the push service's id of the message. A notification the service
+// won't take however often it is asked is a *PushRefusedError, every other
+// error is worth retrying.
+type PushSender interface {
+	Push(token string, msg *PushMessage) (string, error)
+}
+
+// PushRefusedError is a notification the push service refused for good.
+// TokenGone means it doesn't know the device token (anymore), the app was
+// uninstalled or the token rotated, and the device is forgotten.
+type PushRefusedError struct {
+	Err       error
+	TokenGone bool
+}
+
+func (e *PushRefusedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PushRefusedError) Unwrap() error {
+	return e.Err
+}
+
+// NewPushSenders returns the senders cfg configures by platform, FCM for
+// Android and APNs for iOS. Devices of a platform without one get no
+// notifications.
+func NewPushSenders(cfg *Config) (map[string]PushSender, error) {
+	senders := map[string]PushSender{}
+	if cfg.FCMCredentialsFile != "" {
+		fcm, err := LoadFCMSender(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		senders[domain.PlatformAndroid] = fcm
+	}
+	if cfg.APNsKeyFile != "" {
+		apns, err := LoadAPNsSender(cfg.APNsKeyFile, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsSandbox)
+		if err != nil {
+			return nil, err
+		}
+		senders[domain.PlatformIOS] = apns
+	}
+	return senders, nil
+}
+
+// pushTimeout bounds a request to a push service or its token endpoint.
+const pushTimeout = 15 * time.Second
+
+// FCMSender sends notifications with the Firebase Cloud Messaging HTTP v1
+// API, authorized by OAuth2 access tokens it gets for a service account.
+type FCMSender struct {
+	endpoint    string
+	tokenURL    string
+	clientEmail string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// LoadFCMSender reads the JSON key of a service account of the Firebase
+// project, as downloaded from the Google Cloud console.
+func LoadFCMSender(file string) (*FCMSender, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var creds struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("FCM credentials %s: %w", file, err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(creds.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("FCM credentials %s: %w", file, err)
+	}
+	if creds.TokenURI == "" {
+		creds.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &FCMSender{
+		endpoint:    "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(creds.ProjectID) + "/messages:send",
+		tokenURL:    creds.TokenURI,
+		clientEmail: creds.ClientEmail,
+		key:         key,
+		client:      &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+// token returns the cached access token, or exchanges a JWT signed with the
+// service account's key for a new one when it is about to expire.
+func (s *FCMSender) token(now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && now.Before(s.expires.Add(-time.Minute)) {
+		return s.accessToken, nil
+	}
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": "https://www.googleapis.com/auth/firebase.messaging",
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	res, err := s.client.PostForm(s.tokenURL, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error_description"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&out)
+	if res.StatusCode != http.StatusOK || out.AccessToken == "" {
+		return "", fmt.Errorf("fcm token: %s: %s", res.Status, out.Error)
+	}
+	s.accessToken, s.expires = out.AccessToken, now.Add(time.Duration(out.ExpiresIn)*time.Second)
+	return s.accessToken, nil
+}
+
+func (s *FCMSender) Push(token string, msg *PushMessage) (string, error) {
+	accessToken, err := s.token(time.Now())
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]any{"message": map[string]any{
+		"token":        token,
+		"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+		"data":         msg.Data,
+		"android":      map[string]string{"priority": "high"},
+	}})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode == http.StatusUnauthorized {
+		// the token was revoked early, the retry gets a new one
+		s.mu.Lock()
+		s.accessToken = ""
+		s.mu.Unlock()
+	}
+	return fcmResult(res.StatusCode, data)
+}
+
+// fcmResult interprets an answer of the FCM v1 API. UNREGISTERED is a token
+// of an app that was uninstalled, 400s are messages FCM won't take, the
+// rest is about authorization or FCM itself.
+func fcmResult(status int, data []byte) (string, error) {
+	var out struct {
+		Name  string `json:"name"`
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(data, &out)
+	if status == http.StatusOK {
+		return out.Name, nil
+	}
+	code := out.Error.Status
+	for _, d := range out.Error.Details {
+		if d.ErrorCode != "" {
+			code = d.ErrorCode
+		}
+	}
+	err := fmt.Errorf("fcm: %d %s: %s", status, code, out.Error.Message)
+	switch {
+	case code == "UNREGISTERED":
+		return "", &PushRefusedError{Err: err, TokenGone: true}
+	case status == http.StatusBadRequest:
+		return "", &PushRefusedError{Err: err}
+	}
+	return "", err
+}
+
+// apnsTokenAge is how long an APNs provider token is used. Apple refuses
+// ones older than an hour and ones renewed more often than every 20
+// minutes.
+const apnsTokenAge = 50 * time.Minute
+
+// APNsSender sends notifications to Apple's push service over HTTP/2,
+// authorized by provider tokens signed with a .p8 key of the team.
+type APNsSender struct {
+	endpoint string
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+	client   *http.Client
+
+	mu     sync.Mutex
+	token  string
+	issued time.Time
+}
+
+// LoadAPNsSender reads the .p8 key file. topic is the app's bundle id,
+// sandbox sends to the development environment of apps installed from
+// Xcode.
+func LoadAPNsSender(keyFile, keyID, teamID, topic string, sandbox bool) (*APNsSender, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("APNs key %s: %w", keyFile, err)
+	}
+	endpoint := "https://api.push.apple.com"
+	if sandbox {
+		endpoint = "https://api.sandbox.push.apple.com"
+	}
+	return &APNsSender{
+		endpoint: endpoint,
+		keyID:    keyID,
+		teamID:   teamID,
+		topic:    topic,
+		key:      key,
+		// the default transport speaks HTTP/2, which APNs requires
+		client: &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+func (s *APNsSender) providerToken(now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && now.Sub(s.issued) < apnsTokenAge {
+		return s.token, nil
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"iss": s.teamID, "iat": now.Unix()})
+	t.Header["kid"] = s.keyID
+	signed, err := t.SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.issued = signed, now
+	return signed, nil
+}
+
+func (s *APNsSender) Push(token string, msg *PushMessage) (string, error) {
+	providerToken, err := s.providerToken(time.Now())
+	if err != nil {
+		return "", err
+	}
+	payload := map[string]any{"aps": map[string]any{
+		"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+		"sound": "default",
+	}}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var out struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&out)
+	if out.Reason == "ExpiredProviderToken" {
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+	}
+	return apnsResult(res.StatusCode, res.Header.Get("apns-id"), out.Reason)
+}
+
+// apnsTokenReasons are the APNs errors about the device token itself.
+var apnsTokenReasons = map[string]bool{"BadDeviceToken": true, "Unregistered": true, "DeviceTokenNotForTopic": true}
+
+// apnsResult interprets an answer of APNs. 410 is a token of an app that was
+// uninstalled, 400s are notifications APNs won't take, 403s are about the
+// provider token and the rest about APNs itself.
+func apnsResult(status int, apnsID, reason string) (string, error) {
+	if status == http.StatusOK {
+		return apnsID, nil
+	}
+	err := fmt.Errorf("apns: %d %s", status, reason)
+	switch {
+	case status == http.StatusGone || apnsTokenReasons[reason]:
+		return "", &PushRefusedError{Err: err, TokenGone: true}
+	case status == http.StatusBadRequest || status == http.StatusRequestEntityTooLarge:
+		return "", &PushRefusedError{Err: err}
+	}
+	return "", err
+}
+
+// validPushToken reports whether token looks like a token of the platform,
+// hex for APNs and the longer printable FCM tokens.
+func validPushToken(platform, token string) bool {
+	switch platform {
+	case domain.PlatformIOS:
+		if len(token) < 64 || len(token) > 200 {
+			return false
+		}
+		return strings.Trim(strings.ToLower(token), "0123456789abcdef") == ""
+	case domain.PlatformAndroid:
+		return len(token) >= 32 && len(token) <= 4096 && !strings.ContainsAny(token, " \t\r\n")
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPushResults(t *testing.T) {
+	id, err := fcmResult(200, []byte(`{"name": "projects/gobank/messages/0:1500"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "projects/gobank/messages/0:1500", id)
+
+	var refused *PushRefusedError
+	_, err = fcmResult(404, []byte(`{"error": {"code": 404, "status": "NOT_FOUND", "message": "Requested entity was not found.",
+		"details": [{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": "UNREGISTERED"}]}}`))
+	assert.True(t, errors.As(err, &refused) && refused.TokenGone)
+	_, err = fcmResult(400, []byte(`{"error": {"status": "INVALID_ARGUMENT", "message": "bad data"}}`))
+	assert.True(t, errors.As(err, &refused) && !refused.TokenGone)
+	_, err = fcmResult(503, nil)
+	assert.False(t, errors.As(err, &refused))
+
+	_, err = apnsResult(400, "", "BadDeviceToken")
+	assert.True(t, errors.As(err, &refused) && refused.TokenGone)
+	_, err = apnsResult(410, "", "Unregistered")
+	assert.True(t, errors.As(err, &refused) && refused.TokenGone)
+	_, err = apnsResult(403, "", "ExpiredProviderToken")
+	assert.False(t, errors.As(err, &refused))
+}
+
+func TestValidPushToken(t *testing.T) {
+	assert.True(t, validPushToken(domain.PlatformIOS, strings.Repeat("a1", 32)))
+	assert.False(t, validPushToken(domain.PlatformIOS, strings.Repeat("z", 64)))
+	assert.True(t, validPushToken(domain.PlatformAndroid, "dGVzdA:APA91bH"+strings.Repeat("x", 140)))
+	assert.False(t, validPushToken(domain.PlatformAndroid, "short"))
+	assert.False(t, validPushToken("windows", strings.Repeat("a1", 32)))
+}
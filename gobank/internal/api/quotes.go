@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// handleTransferQuote checks a transfer the way POST /transfer would and
+// answers with a quote of its terms. Passing the quote's id to /transfer
+// within domain.QuoteTTL executes it at those terms.
+func (s *APIServer) handleTransferQuote(w http.ResponseWriter, r *http.Request) error {
+	account, err := s.authenticate(r)
+	if err != nil {
+		authFailed(w, r, err)
+		return nil
+	}
+	setAccountLanguage(r, account.Language)
+	defer r.Body.Close()
+	req := new(TransferAccount)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	quote, err := s.transfersFor(r).Quote(account, req.ToAccount, req.Amount)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, quote)
+}
+
+const PurgeQuotesJobType = "purge_quotes"
+
+// QuotePurger deletes transfer quotes that can't be executed anymore.
+type QuotePurger struct {
+	store  storage.QuoteStore
+	clock  domain.Clock
+	logger *slog.Logger
+}
+
+func NewQuotePurger(store storage.QuoteStore, clock domain.Clock, logger *slog.Logger) *QuotePurger {
+	return &QuotePurger{store: store, clock: clock, logger: logger}
+}
+
+func (p *QuotePurger) HandleJob(job *domain.Job) error {
+	// keep a day of executed and expired quotes around for support queries
+	purged, err := p.store.PurgeQuotes(p.clock.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		p.logger.Info("purged transfer quotes", "count", purged)
+	}
+	return nil
+}
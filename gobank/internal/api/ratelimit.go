@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter counts requests per client in fixed one minute windows. It also
+// accumulates the daily call counts of authenticated accounts until they are
+// drained into the usage table.
+type RateLimiter struct {
+	mu      sync.Mutex
+	window  time.Time
+	counts  map[string]int
+	usage   map[domain.UsageKey]domain.Usage
+	limitFn func() int
+}
+
+// Quota is the state of a client's current window, sent as X-RateLimit-* headers.
+type Quota struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func NewRateLimiter(limitFn func() int) *RateLimiter {
+	return &RateLimiter{counts: map[string]int{}, usage: map[domain.UsageKey]domain.Usage{}, limitFn: limitFn}
+}
+
+// Allow records a request for key and reports whether it is within the limit
+// together with the quota left in the window.
+func (l *RateLimiter) Allow(key string, now time.Time) (bool, Quota) {
+	limit := l.limitFn()
+	if limit == 0 {
+		return true, Quota{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if window := now.Truncate(time.Minute); !window.Equal(l.window) {
+		l.window = window
+		l.counts = map[string]int{}
+	}
+	l.counts[key]++
+	quota := Quota{Limit: limit, Remaining: max(limit-l.counts[key], 0), Reset: l.window.Add(time.Minute)}
+	return l.counts[key] <= limit, quota
+}
+
+// Record counts a call of an authenticated account.
+func (l *RateLimiter) Record(key domain.UsageKey, allowed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.usage[key]
+	u.Calls++
+	if !allowed {
+		u.Throttled++
+	}
+	l.usage[key] = u
+}
+
+// DrainUsage returns the counts recorded since the last drain and resets them.
+func (l *RateLimiter) DrainUsage() map[domain.UsageKey]domain.Usage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	usage := l.usage
+	l.usage = map[domain.UsageKey]domain.Usage{}
+	return usage
+}
+
+// restoreUsage puts back counts that couldn't be saved.
+func (l *RateLimiter) restoreUsage(usage map[domain.UsageKey]domain.Usage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, u := range usage {
+		cur := l.usage[k]
+		cur.Calls += u.Calls
+		cur.Throttled += u.Throttled
+		l.usage[k] = cur
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tokenAccount returns the tenant and account number of a JWT on the request
+// that is valid at now.
+func tokenAccount(r *http.Request, now time.Time) (int, int64, bool) {
+	tokenString := r.Header.Get("x-jwt-token")
+	if tokenString == "" {
+		return 0, 0, false
+	}
+	token, err := auth.ValidateJWT(tokenString, now)
+	if err != nil || !token.Valid {
+		return 0, 0, false
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	number, ok := claims["accountNumber"].(float64)
+	if !ok {
+		return 0, 0, false
+	}
+	tenantID, _ := claims["tenantId"].(float64)
+	return int(tenantID), int64(number), true
+}
+
+// withRateLimit limits authenticated requests per account and anonymous ones
+// per client IP.
+func (s *APIServer) withRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := s.clock.Now()
+		key := "ip:" + clientIP(r)
+		tenantID, number, authenticated := tokenAccount(r, now)
+		if authenticated {
+			key = fmt.Sprintf("account:%d:%d", tenantID, number)
+		}
+		ok, quota := s.limiter.Allow(key, now)
+		if authenticated {
+			s.limiter.Record(domain.UsageKey{TenantID: tenantID, AccountNumber: number, Day: now.UTC().Truncate(24 * time.Hour)}, ok)
+		}
+		if quota.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(quota.Reset.Unix(), 10))
+		}
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(quota.Reset.Sub(now).Seconds())+1))
+			writeError(w, r, http.StatusTooManyRequests, NewError(CodeRateLimited))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterQuota(t *testing.T) {
+	l := NewRateLimiter(func() int { return 2 })
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	reset := time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)
+
+	ok, q := l.Allow("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, Quota{Limit: 2, Remaining: 1, Reset: reset}, q)
+	l.Allow("a", now)
+	ok, q = l.Allow("a", now)
+	assert.False(t, ok)
+	assert.Equal(t, 0, q.Remaining)
+
+	// a new window starts over
+	ok, _ = l.Allow("a", reset)
+	assert.True(t, ok)
+}
+
+func TestRateLimiterUsage(t *testing.T) {
+	l := NewRateLimiter(func() int { return 0 })
+	key := domain.UsageKey{TenantID: 1, AccountNumber: 42, Day: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l.Record(key, true)
+	l.Record(key, false)
+	assert.Equal(t, map[domain.UsageKey]domain.Usage{key: {Calls: 2, Throttled: 1}}, l.DrainUsage())
+	assert.Empty(t, l.DrainUsage())
+
+	l.Record(key, true)
+	l.restoreUsage(map[domain.UsageKey]domain.Usage{key: {Calls: 2, Throttled: 1}})
+	assert.Equal(t, domain.Usage{Calls: 3, Throttled: 1}, l.DrainUsage()[key])
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ResolveReconciliationRequest struct {
+	Resolution string `json:"resolution"`
+}
+
+const ReconcileJobType = "reconcile_balances"
+
+// Reconciler recomputes every balance from the ledger. Discrepancies should
+// never happen, so each run that finds one is reported as an error.
+type Reconciler struct {
+	store    storage.ReconciliationStore
+	clock    domain.Clock
+	metrics  *Metrics
+	reporter ErrorReporter
+	logger   *slog.Logger
+}
+
+func NewReconciler(store storage.ReconciliationStore, clock domain.Clock, metrics *Metrics, reporter ErrorReporter, logger *slog.Logger) *Reconciler {
+	metrics.Help("reconciliation_runs_total", "Completed balance reconciliation runs.")
+	metrics.Help("reconciliation_discrepancies", "Accounts whose balance didn't match the ledger in the last run.")
+	return &Reconciler{store: store, clock: clock, metrics: metrics, reporter: reporter, logger: logger}
+}
+
+func (rc *Reconciler) HandleJob(job *domain.Job) error {
+	_, err := rc.ReconcileOnce(rc.clock.Now().UTC())
+	return err
+}
+
+func (rc *Reconciler) ReconcileOnce(now time.Time) ([]*domain.ReconciliationIssue, error) {
+	issues, err := rc.store.Reconcile(now)
+	if err != nil {
+		return nil, err
+	}
+	rc.metrics.Inc("reconciliation_runs_total")
+	rc.metrics.Set("reconciliation_discrepancies", float64(len(issues)))
+	for _, issue := range issues {
+		rc.logger.Error("balance doesn't match the ledger",
+			"issue_id", issue.ID,
+			"tenant_id", issue.TenantID,
+			"account_id", issue.AccountID,
+			"balance", issue.AccountBalance.String(),
+			"ledger", issue.LedgerBalance.String())
+	}
+	if len(issues) > 0 {
+		rc.reporter.Report(fmt.Errorf("reconciliation found %d account(s) out of balance", len(issues)), nil, nil)
+	}
+	return issues, nil
+}
+
+func (s *APIServer) handleListReconciliationIssues(w http.ResponseWriter, r *http.Request) error {
+	status, err := QueryEnum(r, "status", "", "open", "resolved")
+	if err != nil {
+		return err
+	}
+	issues, err := s.store.ListReconciliationIssues(status)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, issues)
+}
+
+func (s *APIServer) handleResolveReconciliationIssue(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req := new(ResolveReconciliationRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if req.Resolution == "" {
+		return NewError(CodeInvalidParameter, "name", "resolution", "value", `""`)
+	}
+	if err := s.store.ResolveReconciliationIssue(id, truncate(req.Resolution, 255), s.clock.Now().UTC()); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]int{"resolved": id})
+}
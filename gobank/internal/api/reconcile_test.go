@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeReconciliationStore struct {
+	storage.ReconciliationStore
+	issues []*domain.ReconciliationIssue
+}
+
+func (f *fakeReconciliationStore) Reconcile(now time.Time) ([]*domain.ReconciliationIssue, error) {
+	return f.issues, nil
+}
+
+type recordingReporter struct {
+	errs []error
+}
+
+func (r *recordingReporter) Report(err error, req *http.Request, stack []byte) {
+	r.errs = append(r.errs, err)
+}
+
+func TestReconcileOnce(t *testing.T) {
+	store := &fakeReconciliationStore{}
+	metrics := NewMetrics()
+	reporter := &recordingReporter{}
+	rc := NewReconciler(store, domain.SystemClock{}, metrics, reporter, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := rc.ReconcileOnce(time.Now())
+	assert.Nil(t, err)
+	assert.Empty(t, reporter.errs)
+
+	store.issues = []*domain.ReconciliationIssue{{
+		ID:             1,
+		AccountID:      7,
+		AccountBalance: domain.Money{MinorUnits: 500, Currency: "USD"},
+		LedgerBalance:  domain.Money{MinorUnits: 400, Currency: "USD"},
+	}}
+	issues, err := rc.ReconcileOnce(time.Now())
+	assert.Nil(t, err)
+	assert.Len(t, issues, 1)
+	assert.Len(t, reporter.errs, 1)
+
+	var buf bytes.Buffer
+	metrics.Render(&buf)
+	assert.Contains(t, buf.String(), "# TYPE reconciliation_discrepancies gauge\nreconciliation_discrepancies 1\n")
+	assert.Contains(t, buf.String(), "reconciliation_runs_total 2\n")
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// recordBodyLimit caps the bytes of a request or response body a recording
+// keeps, anything past it is cut off.
+const recordBodyLimit = 64 << 10
+
+// recordingPrefix starts the blob key of every recording, the timestamp after
+// it keeps them in order.
+const recordingPrefix = "recording-"
+
+// Recording is one request and the response to it, with the credentials and
+// personal data taken out, see redactRecording.
+type Recording struct {
+	RequestID      string      `json:"requestId"`
+	RecordedAt     time.Time   `json:"recordedAt"`
+	DurationMs     int64       `json:"durationMs"`
+	Method         string      `json:"method"`
+	URI            string      `json:"uri"`
+	Header         http.Header `json:"header"`
+	Body           string      `json:"body,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"responseHeader"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+	Truncated      bool        `json:"truncated,omitempty"`
+	// BodyDropped is set when the request body wasn't JSON and had to go
+	// whole, a replay then sends none.
+	BodyDropped bool `json:"bodyDropped,omitempty"`
+}
+
+// Recorder saves recordings to a BlobStore from a single goroutine, so a slow
+// store can't hold up requests. Recordings that don't fit the queue are
+// dropped and counted.
+type Recorder struct {
+	blobs   storage.BlobStore
+	queue   chan *Recording
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewRecorder(blobs storage.BlobStore, metrics *Metrics, logger *slog.Logger) *Recorder {
+	metrics.Help("recordings_dropped_total", "Request recordings dropped because the queue was full.")
+	rec := &Recorder{blobs: blobs, queue: make(chan *Recording, 256), metrics: metrics, logger: logger}
+	go rec.run()
+	return rec
+}
+
+func (rec *Recorder) run() {
+	for r := range rec.queue {
+		data, err := json.Marshal(r)
+		if err == nil {
+			err = rec.blobs.Put(recordingKey(r), bytes.NewReader(data))
+		}
+		if err != nil {
+			rec.logger.Error("saving recording failed", "request_id", r.RequestID, "error", err)
+		}
+	}
+}
+
+func (rec *Recorder) Record(r *Recording) {
+	select {
+	case rec.queue <- r:
+	default:
+		rec.metrics.Inc("recordings_dropped_total")
+	}
+}
+
+func recordingKey(r *Recording) string {
+	return recordingPrefix + r.RecordedAt.UTC().Format("20060102T150405.000000Z") + "-" + r.RequestID + ".json"
+}
+
+// bodyCapture keeps the first recordBodyLimit bytes written through it.
+type bodyCapture struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (c *bodyCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *bodyCapture) Write(p []byte) (int, error) {
+	if room := recordBodyLimit - c.body.Len(); room > 0 {
+		c.body.Write(p[:min(len(p), room)])
+	}
+	if c.body.Len()+len(p) > recordBodyLimit {
+		c.truncated = true
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *bodyCapture) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withRecording records requests while RecordRequests is on. It runs after
+// withRequestID so recordings carry the request id, and sees what the client
+// saw, injected faults included. Event streams are never recorded.
+func (s *APIServer) withRecording(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.recorder == nil || !s.config.Get().Runtime.RecordRequests || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var body []byte
+		truncated := false
+		if r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, recordBodyLimit+1))
+			if len(body) > recordBodyLimit {
+				body, truncated = body[:recordBodyLimit], true
+			}
+			// the handler still gets the whole body, recorded or not
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		}
+		start := time.Now()
+		capture := &bodyCapture{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(capture, r)
+
+		s.recorder.Record(redactRecording(&Recording{
+			RequestID:      requestIDFrom(r),
+			RecordedAt:     start.UTC(),
+			DurationMs:     time.Since(start).Milliseconds(),
+			Method:         r.Method,
+			URI:            r.URL.RequestURI(),
+			Header:         r.Header.Clone(),
+			Body:           string(body),
+			Status:         capture.status,
+			ResponseHeader: w.Header().Clone(),
+			ResponseBody:   capture.body.String(),
+			Truncated:      truncated || capture.truncated,
+		}))
+	})
+}
+
+// redactedHeaders carry credentials.
+var redactedHeaders = []string{"x-jwt-token", "x-admin-token", "X-Signature", "Authorization", "Cookie", "Set-Cookie"}
+
+// redactedFields are the JSON properties holding credentials or personal
+// data, wherever they appear in a body.
+var redactedFields = map[string]bool{
+	"password": true, "passwordHash": true, "token": true, "secret": true,
+	"firstName": true, "lastName": true, "email": true, "supportEmail": true,
+}
+
+// redactRecording takes credentials and personal data out of a recording.
+// JSON bodies keep their shape with the sensitive values replaced, other
+// bodies (CSV, OFX and the like) are dropped whole since there's no telling
+// what's in them.
+func redactRecording(r *Recording) *Recording {
+	for _, h := range redactedHeaders {
+		if r.Header.Get(h) != "" {
+			r.Header.Set(h, domain.Redacted)
+		}
+		if r.ResponseHeader.Get(h) != "" {
+			r.ResponseHeader.Set(h, domain.Redacted)
+		}
+	}
+	r.Body, r.BodyDropped = redactBody(r.Body, r.Header.Get("Content-Type"))
+	r.ResponseBody, _ = redactBody(r.ResponseBody, r.ResponseHeader.Get("Content-Type"))
+	return r
+}
+
+// redactBody returns the redacted body, or a note in its place and true when
+// the whole body had to go.
+func redactBody(body, contentType string) (string, bool) {
+	if body == "" {
+		return "", false
+	}
+	if !strings.HasPrefix(contentType, "application/json") {
+		return fmt.Sprintf("%d bytes of %s redacted", len(body), contentType), true
+	}
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return fmt.Sprintf("%d bytes of invalid or truncated JSON redacted", len(body)), true
+	}
+	data, _ := json.Marshal(redactJSON(v))
+	return string(data), false
+}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if redactedFields[k] {
+				v[k] = domain.Redacted
+			} else {
+				v[k] = redactJSON(child)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redactJSON(child)
+		}
+	}
+	return v
+}
+
+// runReplayCommand implements
+//
+//	gobank replay -target https://staging.example.com [-token jwt] [-admin-token t] [-request-id id] [-since 2024-06-01T00:00:00Z]
+//
+// re-sending the recorded requests in order and reporting where the status
+// differs from the recorded one. The recorded credentials are redacted, so
+// the ones for the target come from the flags; API key signatures can't be
+// reproduced and are replaced by the token.
+func RunReplayCommand(blobs storage.BlobStore, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	target := fs.String("target", "", "base URL of the instance to replay against")
+	token := fs.String("token", "", "JWT sent in place of the recorded account credentials")
+	adminToken := fs.String("admin-token", "", "admin token sent in place of the recorded one")
+	requestID := fs.String("request-id", "", "replay only this request")
+	since := fs.String("since", "", "replay only requests recorded at or after this RFC 3339 time")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *target == "" {
+		return fmt.Errorf("usage: gobank replay -target url [-token jwt] [-admin-token token] [-request-id id] [-since time]")
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, *since); err != nil {
+			return fmt.Errorf("invalid -since %s", *since)
+		}
+	}
+
+	keys, err := blobs.List(recordingPrefix)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	replayed, mismatched := 0, 0
+	for _, key := range keys {
+		rec, err := loadRecording(blobs, key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if (*requestID != "" && rec.RequestID != *requestID) || rec.RecordedAt.Before(from) {
+			continue
+		}
+		status, err := replay(client, strings.TrimRight(*target, "/"), rec, *token, *adminToken)
+		if err != nil {
+			return fmt.Errorf("replaying %s: %w", rec.RequestID, err)
+		}
+		replayed++
+		mark := "ok"
+		if status != rec.Status {
+			mark = "MISMATCH"
+			mismatched++
+		}
+		fmt.Fprintf(stdout, "%s %s %s recorded %d replayed %d %s\n", rec.RequestID, rec.Method, rec.URI, rec.Status, status, mark)
+	}
+	fmt.Fprintf(stdout, "replayed %d requests, %d mismatched\n", replayed, mismatched)
+	return nil
+}
+
+func loadRecording(blobs storage.BlobStore, key string) (*Recording, error) {
+	rc, err := blobs.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	rec := new(Recording)
+	return rec, json.NewDecoder(rc).Decode(rec)
+}
+
+func replay(client *http.Client, target string, rec *Recording, token, adminToken string) (int, error) {
+	var body io.Reader
+	if rec.Body != "" && !rec.BodyDropped {
+		body = strings.NewReader(rec.Body)
+	}
+	req, err := http.NewRequest(rec.Method, target+rec.URI, body)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range rec.Header {
+		req.Header[k] = v
+	}
+	for _, h := range []string{"X-Api-Key", "X-Timestamp", "X-Nonce", "X-Signature", "Content-Length"} {
+		req.Header.Del(h)
+	}
+	req.Header.Set("X-Request-ID", rec.RequestID)
+	if req.Header.Get("x-jwt-token") != "" || rec.Header.Get("X-Api-Key") != "" {
+		req.Header.Set("x-jwt-token", token)
+	}
+	if req.Header.Get("x-admin-token") != "" {
+		req.Header.Set("x-admin-token", adminToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactRecording(t *testing.T) {
+	rec := redactRecording(&Recording{
+		Header:         http.Header{"X-Jwt-Token": {"secret-jwt"}, "Content-Type": {"application/json"}},
+		Body:           `{"firstName":"Anthony","password":"hunter888","amount":{"amount":"1.00"}}`,
+		ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+		ResponseBody:   `{"items":[{"id":1,"email":"a@example.com"}]}`,
+	})
+	assert.Equal(t, domain.Redacted, rec.Header.Get("x-jwt-token"))
+	assert.JSONEq(t, `{"firstName":"[REDACTED]","password":"[REDACTED]","amount":{"amount":"1.00"}}`, rec.Body)
+	assert.JSONEq(t, `{"items":[{"id":1,"email":"[REDACTED]"}]}`, rec.ResponseBody)
+	assert.False(t, rec.BodyDropped)
+
+	rec = redactRecording(&Recording{
+		Header:         http.Header{"Content-Type": {"text/csv"}},
+		Body:           "date,amount\n2024-05-01,-12.50\n",
+		ResponseHeader: http.Header{},
+	})
+	assert.True(t, rec.BodyDropped)
+	assert.NotContains(t, rec.Body, "12.50")
+}
+
+func TestReplay(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		var b bytes.Buffer
+		b.ReadFrom(r.Body)
+		gotBody = b.String()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer target.Close()
+
+	blobs, _ := storage.NewDirBlobStore(t.TempDir())
+	rec := &Recording{
+		RequestID:  "abc",
+		RecordedAt: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Method:     http.MethodPost,
+		URI:        "/transfer",
+		Header:     http.Header{"X-Jwt-Token": {domain.Redacted}, "Content-Type": {"application/json"}},
+		Body:       `{"toAccount":1}`,
+		Status:     http.StatusOK,
+	}
+	data, _ := json.Marshal(rec)
+	blobs.Put(recordingKey(rec), bytes.NewReader(data))
+
+	var out bytes.Buffer
+	assert.Nil(t, RunReplayCommand(blobs, []string{"replay", "-target", target.URL, "-token", "staging-jwt"}, &out))
+	assert.Equal(t, "staging-jwt", got.Header.Get("x-jwt-token"))
+	assert.Equal(t, "abc", got.Header.Get("X-Request-ID"))
+	assert.Equal(t, `{"toAccount":1}`, gotBody)
+	assert.True(t, strings.Contains(out.String(), "recorded 200 replayed 404 MISMATCH"))
+	assert.True(t, strings.Contains(out.String(), "replayed 1 requests, 1 mismatched"))
+
+	out.Reset()
+	assert.Nil(t, RunReplayCommand(blobs, []string{"replay", "-target", target.URL, "-since", "2024-06-02T00:00:00Z"}, &out))
+	assert.Equal(t, "replayed 0 requests, 0 mismatched\n", out.String())
+}
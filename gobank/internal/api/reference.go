@@ -0,0 +1,57 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"strings"
+)
+
+// referenceList is a reference data listing with its ETag, which only
+// changes with a new build.
+type referenceList struct {
+	items any
+	etag  string
+}
+
+func newReferenceList(items any) referenceList {
+	data, err := json.Marshal(items)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	// weak: the XML or enveloped forms of the list are the same list
+	return referenceList{items: items, etag: `W/"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+var referenceLists = map[string]referenceList{
+	"/reference/currencies":    newReferenceList(domain.Currencies()),
+	"/reference/countries":     newReferenceList(domain.Countries()),
+	"/reference/account-types": newReferenceList(domain.AccountTypes()),
+}
+
+// handleReference serves the reference data routes. A client revalidating
+// with If-None-Match gets a 304 as long as the list hasn't changed.
+func (s *APIServer) handleReference(w http.ResponseWriter, r *http.Request) error {
+	list := referenceLists[routePath(r)]
+	w.Header().Set("ETag", list.etag)
+	if etagMatches(r.Header.Get("If-None-Match"), list.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return WriteJSON(w, http.StatusOK, list.items)
+}
+
+// etagMatches compares If-None-Match against etag the weak way RFC 9110
+// prescribes for it, ignoring W/ prefixes.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
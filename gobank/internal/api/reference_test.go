@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReference(t *testing.T) {
+	s := &APIServer{}
+	router := NewRouter(nil)
+	router.Group("", Chain{s.withCachePolicy}).HandleFunc("GET", "/reference/currencies", s.handleReference)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/reference/currencies", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `{"code":"JPY","name":"Yen","minorUnits":0}`)
+	assert.Equal(t, "public, max-age=600", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	r := httptest.NewRequest("GET", "/reference/currencies", nil)
+	r.Header.Set("If-None-Match", `"other", `+etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// textTemplates render the statements and notifications people read, in
+// templates/text. The funcs are placeholders for parsing, renderText binds
+// them to the reader's locale and time zone.
+var textTemplates = template.Must(template.New("").Funcs(textFuncs(domain.Locales["en-US"], time.UTC)).ParseFS(templateFiles, "templates/text/*.tmpl"))
+
+// textCatalog holds the phrases of textTemplates per language, {name}
+// placeholders are filled like those of the error catalog.
+var textCatalog = map[string]map[string]string{
+	"en": {
+		"greeting":                   "Hello {name},",
+		"balance":                    "Your balance is now {amount}.",
+		"transfer_received.subject":  "You received {amount}",
+		"transfer_received.body":     "You received {amount} from account {from} on {time}.",
+		"transfer_sent.subject":      "You sent {amount}",
+		"transfer_sent.body":         "You sent {amount} to account {to} on {time}.",
+		"statement.title":            "Statement of {date}",
+		"statement.account":          "Account {number}, {holder}",
+		"statement.opening":          "Opening balance: {amount}",
+		"statement.closing":          "Closing balance: {amount}",
+		"statement.counterparty":     "account {number}",
+		"statement.none":             "No transactions on this day.",
+		"transaction.transfer_in":    "Incoming transfer",
+		"transaction.transfer_out":   "Outgoing transfer",
+		"transaction.import":         "Imported",
+		"transaction.fee":            "Fee",
+		"transaction.sandbox":        "Sandbox top-up",
+		"welcome.subject":            "Welcome to {brand}",
+		"welcome.body":               "Your account {number} is open. You can now receive money and send it to other accounts.",
+		"statement_ready.subject":    "Your statement of {date} is ready",
+		"statement_ready.body":       "The statement of your account for {date} is ready to download in the app or through the API.",
+		"statement_delivery.subject": "Statement of {from} to {to}",
+		"statement_delivery.body":    "Attached is the camt.053 statement of your account {number}, which you asked to have delivered to this address.",
+		"email.footer":               "You receive this email because you have an account with {brand}.",
+		"email.support":              "Questions? Write to {email}.",
+		"sms.verification_code":      "{code} is your {brand} verification code. It expires in {minutes} minutes.",
+		"login.subject":              "New login to {brand}",
+		"login.body":                 "Someone logged in to your account on {time}. If it wasn't you, contact support right away.",
+	},
+	"de": {
+		"greeting":                   "Hallo {name},",
+		"balance":                    "Ihr Kontostand beträgt jetzt {amount}.",
+		"transfer_received.subject":  "Sie haben {amount} erhalten",
+		"transfer_received.body":     "Sie haben am {time} {amount} von Konto {from} erhalten.",
+		"transfer_sent.subject":      "Sie haben {amount} überwiesen",
+		"transfer_sent.body":         "Sie haben am {time} {amount} an Konto {to} überwiesen.",
+		"statement.title":            "Kontoauszug vom {date}",
+		"statement.account":          "Konto {number}, {holder}",
+		"statement.opening":          "Anfangssaldo: {amount}",
+		"statement.closing":          "Endsaldo: {amount}",
+		"statement.counterparty":     "Konto {number}",
+		"statement.none":             "Keine Umsätze an diesem Tag.",
+		"transaction.transfer_in":    "Eingehende Überweisung",
+		"transaction.transfer_out":   "Ausgehende Überweisung",
+		"transaction.import":         "Importiert",
+		"transaction.fee":            "Gebühr",
+		"transaction.sandbox":        "Sandbox-Aufladung",
+		"welcome.subject":            "Willkommen bei {brand}",
+		"welcome.body":               "Ihr Konto {number} ist eröffnet. Sie können jetzt Geld empfangen und an andere Konten überweisen.",
+		"statement_ready.subject":    "Ihr Kontoauszug vom {date} ist bereit",
+		"statement_ready.body":       "Der Kontoauszug Ihres Kontos vom {date} steht in der App und über die API zum Download bereit.",
+		"statement_delivery.subject": "Kontoauszug vom {from} bis {to}",
+		"statement_delivery.body":    "Anbei der camt.053-Kontoauszug Ihres Kontos {number}, den Sie an diese Adresse bestellt haben.",
+		"email.footer":               "Sie erhalten diese E-Mail, weil Sie ein Konto bei {brand} haben.",
+		"email.support":              "Fragen? Schreiben Sie an {email}.",
+		"sms.verification_code":      "{code} ist Ihr Bestätigungscode für {brand}. Er läuft in {minutes} Minuten ab.",
+		"login.subject":              "Neue Anmeldung bei {brand}",
+		"login.body":                 "Am {time} hat sich jemand bei Ihrem Konto angemeldet. Wenn Sie das nicht waren, wenden Sie sich sofort an den Support.",
+	},
+	"es": {
+		"greeting":                   "Hola {name}:",
+		"balance":                    "Su saldo es ahora de {amount}.",
+		"transfer_received.subject":  "Ha recibido {amount}",
+		"transfer_received.body":     "Ha recibido {amount} de la cuenta {from} el {time}.",
+		"transfer_sent.subject":      "Ha enviado {amount}",
+		"transfer_sent.body":         "Ha enviado {amount} a la cuenta {to} el {time}.",
+		"statement.title":            "Extracto del {date}",
+		"statement.account":          "Cuenta {number}, {holder}",
+		"statement.opening":          "Saldo inicial: {amount}",
+		"statement.closing":          "Saldo final: {amount}",
+		"statement.counterparty":     "cuenta {number}",
+		"statement.none":             "No hay movimientos este día.",
+		"transaction.transfer_in":    "Transferencia recibida",
+		"transaction.transfer_out":   "Transferencia enviada",
+		"transaction.import":         "Importado",
+		"transaction.fee":            "Comisión",
+		"transaction.sandbox":        "Recarga de sandbox",
+		"welcome.subject":            "Bienvenido a {brand}",
+		"welcome.body":               "Su cuenta {number} está abierta. Ya puede recibir dinero y enviarlo a otras cuentas.",
+		"statement_ready.subject":    "Su extracto del {date} está listo",
+		"statement_ready.body":       "El extracto de su cuenta del {date} está listo para descargar en la aplicación o a través de la API.",
+		"statement_delivery.subject": "Extracto del {from} al {to}",
+		"statement_delivery.body":    "Adjuntamos el extracto camt.053 de su cuenta {number}, que pidió recibir en esta dirección.",
+		"email.footer":               "Recibe este correo porque tiene una cuenta en {brand}.",
+		"email.support":              "¿Preguntas? Escriba a {email}.",
+		"sms.verification_code":      "{code} es su código de verificación de {brand}. Caduca en {minutes} minutos.",
+		"login.subject":              "Nuevo inicio de sesión en {brand}",
+		"login.body":                 "Alguien inició sesión en su cuenta el {time}. Si no fue usted, contacte con soporte de inmediato.",
+	},
+	"fr": {
+		"greeting":                   "Bonjour {name},",
+		"balance":                    "Votre solde est maintenant de {amount}.",
+		"transfer_received.subject":  "Vous avez reçu {amount}",
+		"transfer_received.body":     "Vous avez reçu {amount} du compte {from} le {time}.",
+		"transfer_sent.subject":      "Vous avez envoyé {amount}",
+		"transfer_sent.body":         "Vous avez envoyé {amount} au compte {to} le {time}.",
+		"statement.title":            "Relevé du {date}",
+		"statement.account":          "Compte {number}, {holder}",
+		"statement.opening":          "Solde d'ouverture : {amount}",
+		"statement.closing":          "Solde de clôture : {amount}",
+		"statement.counterparty":     "compte {number}",
+		"statement.none":             "Aucune opération ce jour-là.",
+		"transaction.transfer_in":    "Virement reçu",
+		"transaction.transfer_out":   "Virement émis",
+		"transaction.import":         "Importé",
+		"transaction.fee":            "Frais",
+		"transaction.sandbox":        "Recharge sandbox",
+		"welcome.subject":            "Bienvenue chez {brand}",
+		"welcome.body":               "Votre compte {number} est ouvert. Vous pouvez maintenant recevoir de l'argent et en envoyer vers d'autres comptes.",
+		"statement_ready.subject":    "Votre relevé du {date} est prêt",
+		"statement_ready.body":       "Le relevé de votre compte du {date} est prêt à être téléchargé dans l'application ou via l'API.",
+		"statement_delivery.subject": "Relevé du {from} au {to}",
+		"statement_delivery.body":    "Vous trouverez ci-joint le relevé camt.053 de votre compte {number}, que vous avez demandé à recevoir à cette adresse.",
+		"email.footer":               "Vous recevez cet e-mail car vous avez un compte chez {brand}.",
+		"email.support":              "Des questions ? Écrivez à {email}.",
+		"sms.verification_code":      "{code} est votre code de vérification {brand}. Il expire dans {minutes} minutes.",
+		"login.subject":              "Nouvelle connexion à {brand}",
+		"login.body":                 "Quelqu'un s'est connecté à votre compte le {time}. Si ce n'était pas vous, contactez immédiatement le support.",
+	},
+}
+
+// textFuncs are the template funcs for a reader: t translates a phrase
+// given alternating param names and values, money and the date funcs write
+// in the locale, the times in loc.
+func textFuncs(locale domain.Locale, loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, params ...any) string {
+			values := map[string]any{}
+			for i := 0; i+1 < len(params); i += 2 {
+				values[fmt.Sprint(params[i])] = params[i+1]
+			}
+			return lookupMessage(textCatalog, locale.Language, key, values)
+		},
+		"money": locale.FormatMoney,
+		"abs": func(m domain.Money) domain.Money {
+			m.MinorUnits = max(m.MinorUnits, -m.MinorUnits)
+			return m
+		},
+		"date":     func(t time.Time) string { return locale.FormatDate(t.In(loc)) },
+		"datetime": func(t time.Time) string { return locale.FormatDateTime(t.In(loc)) },
+		"time":     func(t time.Time) string { return t.In(loc).Format(locale.TimeLayout) },
+	}
+}
+
+// renderText executes the text template name for account, in its locale
+// and time zone.
+func renderText(account *domain.Account, name string, data any) (string, error) {
+	loc, err := loadLocation(account.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t, err := textTemplates.Clone()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Funcs(textFuncs(domain.AccountLocale(account), loc)).ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// transferNotice is the data of the transfer_received and transfer_sent
+// templates, Account as it was after the transfer.
+type transferNotice struct {
+	Account     *domain.Account
+	Transaction *domain.Transaction
+}
+
+// renderTransferNotice renders the subject and body telling the holder of
+// account about a transfer in or out of it, for transactions of other
+// types ok is false.
+func renderTransferNotice(account *domain.Account, tx *domain.Transaction) (subject, body string, ok bool, err error) {
+	var name string
+	switch tx.Type {
+	case domain.TransactionTransferIn:
+		name = "transfer_received"
+	case domain.TransactionTransferOut:
+		name = "transfer_sent"
+	default:
+		return "", "", false, nil
+	}
+	data := transferNotice{Account: account, Transaction: tx}
+	if subject, err = renderText(account, name+".subject", data); err != nil {
+		return "", "", false, err
+	}
+	if body, err = renderText(account, name+".body", data); err != nil {
+		return "", "", false, err
+	}
+	return subject, body, true, nil
+}
+
+// textStatement is the data of the statement template.
+type textStatement struct {
+	Account          *domain.Account
+	Day              time.Time
+	Opening, Closing domain.Money
+	Transactions     []*domain.Transaction
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRenderText(t *testing.T) {
+	eur := func(units int64) domain.Money { return domain.Money{MinorUnits: units, Currency: "EUR"} }
+	account := &domain.Account{FirstName: "Jana", LastName: "Novak", Number: 4711007, Timezone: "Europe/Berlin", Language: "de", Balance: eur(250000)}
+	at := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	tx := &domain.Transaction{Type: domain.TransactionTransferOut, Amount: eur(-123450), Counterparty: 1234567, CreatedAt: at}
+
+	subject, body, ok, err := renderTransferNotice(account, tx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Sie haben 1.234,50 EUR überwiesen", subject)
+	assert.Equal(t, "Hallo Jana,\n\nSie haben am 02.03.2024 00:30 1.234,50 EUR an Konto ****4567 überwiesen.\nIhr Kontostand beträgt jetzt 2.500,00 EUR.\n", body)
+
+	account.Locale, account.Language = "en-GB", "en"
+	text, err := renderText(account, "statement", textStatement{Account: account, Day: at, Opening: eur(373450), Closing: eur(250000), Transactions: []*domain.Transaction{tx}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Statement of 02/03/2024\nAccount 4711007, Jana Novak\n\nOpening balance: EUR 3,734.50\n\n"+
+		"00:30  Outgoing transfer  EUR -1,234.50  account 1234567\n\nClosing balance: EUR 2,500.00\n", text)
+
+	_, _, ok, _ = renderTransferNotice(account, &domain.Transaction{Type: domain.TransactionFee})
+	assert.False(t, ok)
+}
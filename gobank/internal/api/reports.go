@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleDailyReport serves GET /admin/reports/daily?tenant=&from=&to=&format=csv.
+// from and to are UTC days, to is exclusive, and default to the last 30 days.
+func (s *APIServer) handleDailyReport(w http.ResponseWriter, r *http.Request) error {
+	store, tenant, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	today := s.clock.Now().UTC().Truncate(24 * time.Hour)
+	from, err := QueryTime(r, "from", "2006-01-02", time.UTC, today.AddDate(0, 0, -29))
+	if err != nil {
+		return err
+	}
+	to, err := QueryTime(r, "to", "2006-01-02", time.UTC, today.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	if !from.Before(to) || to.Sub(from) > 366*24*time.Hour {
+		return NewError(CodeInvalidParameter, "name", "to", "value", r.URL.Query().Get("to"))
+	}
+	rows, err := store.DailyReport(from, to)
+	if err != nil {
+		return err
+	}
+	format, err := QueryEnum(r, "format", "json", "json", "csv")
+	if err != nil {
+		return err
+	}
+	if format != "csv" {
+		return WriteJSON(w, http.StatusOK, rows)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="daily-`+tenant.Slug+`-`+from.Format("2006-01-02")+`.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "currency", "new_accounts", "transfers", "transfer_volume", "fee_revenue"})
+	for _, row := range rows {
+		cw.Write([]string{
+			row.Date,
+			row.Currency,
+			strconv.Itoa(row.NewAccounts),
+			strconv.Itoa(row.Transfers),
+			row.TransferVolume.Decimal(),
+			row.FeeRevenue.Decimal(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
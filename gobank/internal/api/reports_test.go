@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeReportStore struct {
+	fakeAccountStore
+	from, to time.Time
+}
+
+func (f *fakeReportStore) ForTenant(int) storage.Storage { return f }
+
+func (f *fakeReportStore) DailyReport(from, to time.Time) ([]*domain.DailyReportRow, error) {
+	f.from, f.to = from, to
+	return []*domain.DailyReportRow{{
+		Date: "2024-01-05", Currency: "EUR", NewAccounts: 2, Transfers: 3,
+		TransferVolume: domain.Money{MinorUnits: 12550, Currency: "EUR"},
+		FeeRevenue:     domain.Money{MinorUnits: 75, Currency: "EUR"},
+	}}, nil
+}
+
+func TestDailyReportCSV(t *testing.T) {
+	store := &fakeReportStore{}
+	s := &APIServer{store: store, clock: domain.SystemClock{}}
+	rec := httptest.NewRecorder()
+	err := s.handleDailyReport(rec, httptest.NewRequest("GET", "/admin/reports/daily?from=2024-01-01&to=2024-02-01&format=csv", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "date,currency,new_accounts,transfers,transfer_volume,fee_revenue\n2024-01-05,EUR,2,3,125.50,0.75\n", rec.Body.String())
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), store.from)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), store.to)
+
+	err = s.handleDailyReport(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin/reports/daily?from=2024-02-01&to=2024-01-01", nil))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Router serves method-aware routes with the stdlib ServeMux and remembers
+// the path templates, which ServeMux can't list. Requests to a known path
+// with a method it doesn't serve get a coded 405, and those to an unknown
+// path a coded 404, instead of ServeMux's plain text ones. Every GET route
+// answers HEAD and every path answers OPTIONS.
+type Router struct {
+	mux *http.ServeMux
+	// preflight answers OPTIONS for every path, so CORS preflights reach
+	// withCORS although no route is registered for OPTIONS.
+	preflight Chain
+	paths     map[string]bool
+	routes    map[string]bool
+	methods   map[string][]string
+}
+
+func NewRouter(preflight Chain) *Router {
+	return &Router{mux: http.NewServeMux(), preflight: preflight, paths: map[string]bool{}, routes: map[string]bool{}, methods: map[string][]string{}}
+}
+
+// Group returns a group of routes under prefix served through chain.
+func (rt *Router) Group(prefix string, chain Chain) *Group {
+	return &Group{router: rt, prefix: prefix, chain: chain}
+}
+
+// Paths returns the path templates served, such as "/account/{id}".
+func (rt *Router) Paths() map[string]bool {
+	return rt.paths
+}
+
+// Routes returns the routes served as "METHOD path template", the form of
+// client.Endpoints.
+func (rt *Router) Routes() map[string]bool {
+	return rt.routes
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, pattern := rt.mux.Handler(r)
+	if pattern == "" {
+		probe := &headerProbe{header: http.Header{}}
+		h.ServeHTTP(probe, r)
+		switch probe.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", probe.header.Get("Allow"))
+			writeError(w, r, http.StatusMethodNotAllowed, NewError(CodeMethodNotAllowed, "method", r.Method))
+			return
+		case http.StatusNotFound:
+			writeError(w, r, http.StatusNotFound, NewError(CodeNotFound, "id", r.URL.Path))
+			return
+		}
+	}
+	if r.Method == http.MethodHead {
+		hw := &headWriter{ResponseWriter: w}
+		rt.mux.ServeHTTP(hw, r)
+		hw.send()
+		return
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// allow lists the methods path answers to for Allow and
+// Access-Control-Allow-Methods, sorted like ServeMux's 405 answers.
+func (rt *Router) allow(path string) string {
+	methods := []string{http.MethodOptions}
+	for _, m := range rt.methods[path] {
+		methods = append(methods, m)
+		if m == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
+}
+
+func (rt *Router) handle(method, path string, prefix bool, h http.Handler) {
+	pattern := path
+	if strings.HasSuffix(path, "/") && !prefix {
+		pattern += "{$}"
+	}
+	if !rt.paths[path] {
+		rt.paths[path] = true
+		rt.mux.Handle(http.MethodOptions+" "+pattern, rt.preflight.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allow := rt.allow(path)
+			w.Header().Set("Allow", allow)
+			if isPreflight(r) && w.Header().Get("Access-Control-Allow-Origin") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", allow)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	}
+	rt.routes[method+" "+path] = true
+	rt.methods[path] = append(rt.methods[path], method)
+	rt.mux.Handle(method+" "+pattern, h)
+}
+
+// headerProbe records what ServeMux's not found and method not allowed
+// handlers would answer.
+type headerProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *headerProbe) Header() http.Header {
+	return p.header
+}
+
+func (p *headerProbe) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (p *headerProbe) WriteHeader(status int) {
+	p.status = status
+}
+
+// headWriter answers HEAD with the headers the GET route sends, Content-Length
+// included, and drops the body. The header goes out once the handler is done,
+// or on the first Flush of a streaming handler, which can't know its length.
+type headWriter struct {
+	http.ResponseWriter
+	status  int
+	written int
+	sent    bool
+}
+
+func (w *headWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.written += len(b)
+	return len(b), nil
+}
+
+func (w *headWriter) Flush() {
+	w.send()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headWriter) send() {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.written > 0 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.written))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// isGet reports whether r reads the resource, the GET route serving HEAD too.
+func isGet(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// Group is a set of routes sharing a path prefix and a middleware chain.
+type Group struct {
+	router *Router
+	prefix string
+	chain  Chain
+}
+
+// With returns the group with m added to its chain, for routes that need
+// more than the rest of the group.
+func (g *Group) With(m ...Middleware) *Group {
+	return &Group{router: g.router, prefix: g.prefix, chain: g.chain.Use(m...)}
+}
+
+// Handle serves method requests to the path exactly. A GET route answers
+// HEAD too.
+func (g *Group) Handle(method, path string, h http.Handler) {
+	g.router.handle(method, g.prefix+path, false, g.chain.Then(h))
+}
+
+func (g *Group) HandleFunc(method, path string, f apiFunc) {
+	g.router.handle(method, g.prefix+path, false, g.chain.ThenFunc(f))
+}
+
+// HandlePrefix serves method requests to every path below prefix, which
+// ends in a slash.
+func (g *Group) HandlePrefix(method, prefix string, h http.Handler) {
+	g.router.handle(method, g.prefix+prefix, true, g.chain.Then(h))
+}
+
+// routePath is the path template of the route serving r, "" when no route
+// matched.
+func routePath(r *http.Request) string {
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	return strings.TrimSuffix(path, "{$}")
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterMethods(t *testing.T) {
+	s := &APIServer{config: NewLiveConfig(&Config{Runtime: RuntimeConfig{CORSOrigins: []string{"https://app.example.com"}}})}
+	router := NewRouter(Chain{s.withCORS})
+	group := router.Group("/account/{id}", nil)
+	group.HandleFunc("GET", "", func(w http.ResponseWriter, r *http.Request) error {
+		return WriteJSON(w, http.StatusOK, r.PathValue("id"))
+	})
+	group.HandleFunc("DELETE", "", func(w http.ResponseWriter, r *http.Request) error {
+		return WriteJSON(w, http.StatusOK, "deleted")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/account/7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "\"7\"\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/account/7", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+	assert.Contains(t, rec.Body.String(), `"code":"method_not_allowed"`)
+
+	preflight := httptest.NewRequest("OPTIONS", "/account/7", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "DELETE")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, preflight)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("OPTIONS", "/account/7", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "DELETE, GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+	assert.Equal(t, "", rec.Header().Get("Access-Control-Allow-Methods"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("HEAD", "/account/7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "4", rec.Header().Get("Content-Length"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/nowhere", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"not_found","error":"/nowhere not found"}`, rec.Body.String())
+	assert.Equal(t, map[string]bool{"GET /account/{id}": true, "DELETE /account/{id}": true}, router.Routes())
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/iamuditg/client"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// routesWithoutClient are served but deliberately not in the client: the
+// schemas, the Postman collection, the SSE stream, the HTML admin UI, metrics and profiling,
+// and the email bounces SNS posts.
+var routesWithoutClient = map[string]bool{
+	"/schemas/":            true,
+	"/schemas/{name}":      true,
+	"/dev/collection":      true,
+	"/account/{id}/events": true,
+	"/admin/ui":            true,
+	"/metrics":             true,
+	"/debug/pprof/cmdline": true,
+	"/debug/pprof/profile": true,
+	"/debug/pprof/symbol":  true,
+	"/debug/pprof/trace":   true,
+	"/debug/pprof/":        true,
+	"/debug/vars":          true,
+	"/email/bounces":       true,
+}
+
+func TestClientCoversRoutes(t *testing.T) {
+	// sandbox mode registers every route there is
+	cfg := &Config{Mode: ModeSandbox}
+	s := NewAPIServer(NewLiveConfig(cfg), nil, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, NewMetrics(), nil, nil, nil, nil, nil, nil)
+
+	served := s.routes().Routes()
+
+	called := map[string]bool{}
+	for _, e := range client.Endpoints {
+		called[e] = true
+		assert.True(t, served[e], "client calls %s, which the server doesn't serve", e)
+	}
+	for route := range served {
+		if !routesWithoutClient[route[strings.Index(route, " ")+1:]] {
+			assert.True(t, called[route], "route %s is missing from the client", route)
+		}
+	}
+}
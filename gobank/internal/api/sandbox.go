@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+)
+
+type TopUpRequest struct {
+	Amount      domain.Money `json:"amount"`
+	Description string       `json:"description"`
+}
+
+// handleSandboxTopUp serves POST /sandbox/account/{id}/topup. The route is
+// only registered in sandbox mode.
+func (s *APIServer) handleSandboxTopUp(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req := new(TopUpRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if req.Amount.MinorUnits <= 0 {
+		return NewError(CodeInvalidAmount)
+	}
+	store := s.storeFor(r)
+	if req.Amount.Currency == "" {
+		account, err := store.GetAccountById(id)
+		if err != nil {
+			return err
+		}
+		req.Amount.Currency = account.Balance.Currency
+	}
+	transaction, err := store.TopUp(id, req.Amount, truncate(req.Description, 255))
+	if err != nil {
+		return err
+	}
+	s.notifier.Notify()
+	loggerFrom(r.Context()).Info("sandbox top-up", "account_id", id, "amount", req.Amount.String())
+	return WriteJSON(w, http.StatusOK, transaction)
+}
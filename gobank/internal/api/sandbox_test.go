@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeSandboxStore struct {
+	storage.Storage
+	credited domain.Money
+}
+
+func (f *fakeSandboxStore) GetAccountById(id int) (*domain.Account, error) {
+	return &domain.Account{ID: id, Balance: domain.Money{Currency: "EUR"}}, nil
+}
+
+func (f *fakeSandboxStore) TopUp(accountID int, amount domain.Money, description string) (*domain.Transaction, error) {
+	f.credited = amount
+	return &domain.Transaction{ID: 1, AccountID: accountID, Type: domain.TransactionSandbox, Amount: amount, Description: description}, nil
+}
+
+func TestSandboxTopUp(t *testing.T) {
+	store := &fakeSandboxStore{}
+	s := &APIServer{store: store, notifier: NewNotifier()}
+	topUp := func(body string) error {
+		r := httptest.NewRequest("POST", "/sandbox/account/7/topup", strings.NewReader(body))
+		r.SetPathValue("id", "7")
+		return s.handleSandboxTopUp(httptest.NewRecorder(), r)
+	}
+
+	assert.Nil(t, topUp(`{"amount": "250.00"}`))
+	assert.Equal(t, domain.Money{MinorUnits: 25000, Currency: "EUR"}, store.credited)
+
+	err := topUp(`{"amount": 0}`)
+	assert.Equal(t, CodeInvalidAmount, err.(*Error).Code)
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaFiles are the JSON Schemas of the request bodies, served at
+// /schemas/{name} so clients can validate before sending.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// requestSchemas maps "METHOD route template" to the schema its body must
+// match.
+var requestSchemas = map[string]string{
+	"POST /login":         "login.json",
+	"POST /account":       "create-account.json",
+	"PATCH /account/{id}": "update-account.json",
+	"POST /account/{id}/phone/verification/confirm":   "confirm-phone.json",
+	"POST /account/{id}/devices":                      "register-device.json",
+	"POST /account/{id}/pots":                         "create-pot.json",
+	"PATCH /account/{id}/pots/{potId}":                "update-pot.json",
+	"POST /account/{id}/statement-destinations":       "statement-destination.json",
+	"POST /account/{id}/api-keys":                     "create-api-key.json",
+	"POST /account/{id}/webhooks":                     "create-webhook.json",
+	"POST /account/{id}/consents":                     "create-consent.json",
+	"POST /open-banking/v1/payments/credit-transfers": "ob-payment.json",
+	"POST /account/{id}/terms/accept":                 "accept-terms.json",
+	"POST /transfer":                                  "transfer.json",
+	"POST /transfer/quote":                            "transfer-terms.json",
+	"POST /transfers/batch":                           "batch-transfer.json",
+	"POST /transfer/authorize":                        "transfer-terms.json",
+	"POST /transfer/holds/{holdId}/capture":           "capture-transfer.json",
+	"POST /sandbox/account/{id}/topup":                "sandbox-topup.json",
+	"POST /admin/tenants":                             "create-tenant.json",
+	"PUT /admin/tenants/{id}/settings":                "tenant-settings.json",
+	"PUT /admin/maintenance":                          "maintenance.json",
+	"PUT /admin/chaos":                                "chaos.json",
+	"POST /admin/clock":                               "advance-clock.json",
+	"POST /admin/reconciliation/issues/{id}/resolve":  "resolve-reconciliation.json",
+	"POST /admin/accounts/{id}/impersonations":        "impersonate.json",
+	"PUT /admin/accounts/{id}/minimum-balance":        "minimum-balance.json",
+	"POST /admin/reports/accounting":                  "accounting-export.json",
+	"POST /admin/email/suppressions":                  "email-suppression.json",
+}
+
+// schemaMaxBytes bounds the bodies validated in memory, none of the schema
+// validated requests comes close.
+const schemaMaxBytes = 1 << 20
+
+// Schema is the subset of JSON Schema 2020-12 the request schemas use.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaTypes        `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	If                   *Schema            `json:"if"`
+	Then                 *Schema            `json:"then"`
+	Else                 *Schema            `json:"else"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaTypes accepts both "type": "string" and "type": ["string", "number"].
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// SchemaViolation points at the part of the body that broke the schema with
+// a JSON Pointer (RFC 6901), "" being the whole body.
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Error   string `json:"error"`
+}
+
+// SchemaSet holds the parsed schemas by file name, $ref resolves against it.
+type SchemaSet map[string]*Schema
+
+func loadSchemas() (SchemaSet, error) {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+	set := SchemaSet{}
+	for _, e := range entries {
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		schema := new(Schema)
+		if err := json.Unmarshal(raw, schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", e.Name(), err)
+		}
+		if err := schema.compile(); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", e.Name(), err)
+		}
+		set[e.Name()] = schema
+	}
+	return set, nil
+}
+
+func (s *Schema) compile() (err error) {
+	if s.Pattern != "" {
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	children := append(append([]*Schema{s.Items, s.If, s.Then, s.Else}, s.OneOf...), s.AnyOf...)
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	for _, c := range children {
+		if c == nil {
+			continue
+		}
+		if err := c.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks a body against the named schema.
+func (set SchemaSet) Validate(name string, body []byte) []SchemaViolation {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []SchemaViolation{{Pointer: "", Error: "invalid JSON: " + err.Error()}}
+	}
+	return set.validate(set[name], v, "")
+}
+
+func (set SchemaSet) validate(s *Schema, v any, ptr string) []SchemaViolation {
+	if s.Ref != "" {
+		ref, ok := set[s.Ref]
+		if !ok {
+			return []SchemaViolation{{ptr, "unknown schema " + s.Ref}}
+		}
+		return set.validate(ref, v, ptr)
+	}
+	fail := func(format string, args ...any) []SchemaViolation {
+		return []SchemaViolation{{ptr, fmt.Sprintf(format, args...)}}
+	}
+	if len(s.Type) > 0 && !matchesType(s.Type, v) {
+		return fail("must be of type %s, got %s", strings.Join(s.Type, " or "), jsonType(v))
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fail("must be one of %v", s.Enum)
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, alt := range s.OneOf {
+			if len(set.validate(alt, v, ptr)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fail("must match exactly one of %d alternatives, matches %d", len(s.OneOf), matched)
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, alt := range s.AnyOf {
+			if len(set.validate(alt, v, ptr)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fail("must match at least one of %d alternatives", len(s.AnyOf))
+		}
+	}
+
+	var violations []SchemaViolation
+	if s.If != nil {
+		branch := s.Else
+		if len(set.validate(s.If, v, ptr)) == 0 {
+			branch = s.Then
+		}
+		if branch != nil {
+			violations = set.validate(branch, v, ptr)
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			return fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			return fail("must match %s", s.Pattern)
+		}
+	case json.Number:
+		f, _ := val.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			return fail("must be at least %g", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fail("must be at most %g", *s.Maximum)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range val {
+				violations = append(violations, set.validate(s.Items, item, ptr+"/"+strconv.Itoa(i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				violations = append(violations, SchemaViolation{ptr + "/" + escapePointer(name), "is required"})
+			}
+		}
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := ptr + "/" + escapePointer(name)
+			if prop, ok := s.Properties[name]; ok {
+				violations = append(violations, set.validate(prop, val[name], child)...)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violations = append(violations, SchemaViolation{child, "is not allowed"})
+			}
+		}
+	}
+	return violations
+}
+
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+func jsonType(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(val.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+func matchesType(types []string, v any) bool {
+	actual := jsonType(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) && jsonType(v) != "object" {
+			return true
+		}
+	}
+	return false
+}
+
+// withSchemaValidation rejects request bodies that don't match the route's
+// schema before they reach the handler. Protocol Buffers bodies have their
+// schema in the message type and are left to the handler.
+func (s *APIServer) withSchemaValidation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := requestSchemas[r.Method+" "+routePath(r)]
+		if !ok || isProtobuf(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, schemaMaxBytes))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if violations := s.schemas.Validate(name, body); len(violations) > 0 {
+			err := NewError(CodeInvalidBody, "schema", "/schemas/"+name)
+			WriteJSON(w, http.StatusBadRequest, ApiError{Code: CodeInvalidBody, Error: err.Localize(languageFor(r)), Violations: violations})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleSchema serves /schemas/ (the list of schema names) and
+// /schemas/{name}.
+func (s *APIServer) handleSchema(w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	if name == "" {
+		names := make([]string, 0, len(s.schemas))
+		for n := range s.schemas {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return WriteJSON(w, http.StatusOK, names)
+	}
+	raw, err := schemaFiles.ReadFile(path.Join("schemas", path.Base(name)))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	_, err = w.Write(raw)
+	return err
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSchemasValidate(t *testing.T) {
+	set, err := loadSchemas()
+	assert.Nil(t, err)
+	for _, name := range requestSchemas {
+		assert.Contains(t, set, name)
+	}
+
+	assert.Empty(t, set.Validate("transfer.json", []byte(`{"toAccount": 42, "amount": "12.50"}`)))
+	assert.Empty(t, set.Validate("transfer.json", []byte(`{"toAccount": 42, "amount": {"amount": "12.50", "currency": "EUR"}}`)))
+	assert.Equal(t, []SchemaViolation{
+		{Pointer: "/toAccount", Error: "is required"},
+		{Pointer: "/amount", Error: "must match exactly one of 3 alternatives, matches 0"},
+		{Pointer: "/note", Error: "is not allowed"},
+	}, set.Validate("transfer.json", []byte(`{"amount": {"currency": "EUR"}, "note": "hi"}`)))
+	assert.Empty(t, set.Validate("transfer.json", []byte(`{"quoteId": "0b8f6c3e-6f0a-4a53-9d55-2f7c1d4e8a10"}`)))
+	assert.Equal(t, []SchemaViolation{
+		{Pointer: "/quoteId", Error: "must match ^[0-9a-fA-F-]{36}$"},
+	}, set.Validate("transfer.json", []byte(`{"quoteId": "42"}`)))
+	assert.Equal(t, []SchemaViolation{
+		{Pointer: "/number", Error: "must be of type integer, got number"},
+	}, set.Validate("login.json", []byte(`{"number": 1.5, "password": "x"}`)))
+	assert.Equal(t, "", set.Validate("login.json", []byte(`{"number":`))[0].Pointer)
+}
+
+func TestWithSchemaValidation(t *testing.T) {
+	set, _ := loadSchemas()
+	s := &APIServer{schemas: set}
+	router := NewRouter(nil)
+	router.Group("", Chain{s.withSchemaValidation}).Handle("POST", "/transfer", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/transfer", strings.NewReader(`{"toAccount": "42", "amount": 5}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":"invalid_body"`)
+	assert.Contains(t, rec.Body.String(), `"pointer":"/toAccount"`)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "/transfer", strings.NewReader(`{"toAccount": 42, "amount": 5}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"toAccount": 42, "amount": 5}`, rec.Body.String())
+}
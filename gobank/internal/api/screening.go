@@ -0,0 +1,50 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"net/http"
+	"strings"
+)
+
+// TransferReview is a transfer held for review as the admin sees it.
+type TransferReview struct {
+	TransferStatus
+	AccountID int    `json:"accountId"`
+	Reason    string `json:"reason"`
+}
+
+// handleTransfersInReview serves GET /admin/transfers/review?tenant=, the
+// transfers screening held, oldest first.
+func (s *APIServer) handleTransfersInReview(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	reqs, err := store.ListTransferRequests(domain.TransferInReview)
+	if err != nil {
+		return err
+	}
+	reviews := make([]TransferReview, 0, len(reqs))
+	for _, req := range reqs {
+		reviews = append(reviews, TransferReview{TransferStatus: transferStatus(req, languageFor(r)), AccountID: req.AccountID, Reason: req.ReviewReason})
+	}
+	return WriteJSON(w, http.StatusOK, reviews)
+}
+
+// handleReviewTransfer serves POST /admin/transfers/{id}/release?tenant=,
+// which has the held transfer processed in the background without
+// screening it again, and POST /admin/transfers/{id}/reject?tenant=.
+func (s *APIServer) handleReviewTransfer(w http.ResponseWriter, r *http.Request) error {
+	store, _, err := s.adminTenantStore(r)
+	if err != nil {
+		return err
+	}
+	release := strings.HasSuffix(routePath(r), "/release")
+	req, err := service.NewTransferService(store, s.settings, s.clock, s.screening).Review(r.PathValue("id"), release)
+	if err != nil {
+		return err
+	}
+	loggerFrom(r.Context()).Info("transfer reviewed", "transfer_id", req.ID, "released", release)
+	return WriteJSON(w, http.StatusOK, transferStatus(req, languageFor(r)))
+}
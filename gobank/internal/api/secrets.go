@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks a secret sealed by sealSecret.
+const sealedPrefix = "gcm:"
+
+// sealSecret encrypts a secret that's kept in the database, like the
+// password of a statement destination, with AES-256-GCM under key. It
+// returns the base64 of the nonce and the ciphertext after sealedPrefix.
+func sealSecret(key []byte, secret string) (string, error) {
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// openSecret decrypts a secret sealSecret sealed under key.
+func openSecret(key []byte, sealed string) (string, error) {
+	raw, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", errors.New("secret isn't encrypted")
+	}
+	data, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := secretsCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed secret is too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("secret can't be decrypted with SECRETS_KEY")
+	}
+	return string(plain), nil
+}
+
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("SECRETS_KEY isn't set")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
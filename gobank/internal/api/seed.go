@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	seedFirstNames = []string{"Ada", "Ben", "Chloe", "Daniel", "Elena", "Farah", "Gustav", "Hana", "Ivan", "Julia", "Kofi", "Lena", "Mateo", "Nora", "Omar", "Priya", "Quentin", "Rosa", "Sven", "Tara", "Umar", "Vera", "Wei", "Yara", "Zoe"}
+	seedLastNames  = []string{"Andersen", "Baptiste", "Costa", "Dubois", "Eriksen", "Fischer", "Garcia", "Hoffmann", "Ito", "Jansen", "Kowalski", "Lopez", "Müller", "Nakamura", "Okafor", "Petrov", "Rossi", "Schmidt", "Tanaka", "Urban", "Varga", "Wagner", "Yilmaz", "Zimmermann"}
+	seedTimezones  = []string{"UTC", "Europe/Berlin", "Europe/Madrid", "Europe/Paris", "America/New_York", "America/Los_Angeles", "Asia/Tokyo"}
+	seedMerchants  = []string{"Corner Coffee", "FreshMart Groceries", "City Transit", "Streamly Subscription", "Book Nook", "Fuel Stop", "Pharmacy Plus", "Pizza Palace", "Hardware Depot", "Electric Utility", "Mobile Carrier", "Gym Membership"}
+	seedEmployers  = []string{"ACME Corp", "Globex", "Initech", "Umbrella Ltd", "Stark Industries"}
+)
+
+// seedPassword is shared by all seeded accounts, hashing one bcrypt password
+// per account would dominate the seeding time.
+const seedPassword = "hunter888"
+
+// Seeder fills a sandbox database with believable accounts and histories.
+// The same rng seed produces the same data, dates are relative to the clock.
+type Seeder struct {
+	store  storage.Storage
+	rng    *rand.Rand
+	now    time.Time
+	logger *slog.Logger
+}
+
+func NewSeeder(store storage.Storage, rngSeed int64, clock domain.Clock, logger *slog.Logger) *Seeder {
+	return &Seeder{store: store, rng: rand.New(rand.NewSource(rngSeed)), now: clock.Now().UTC(), logger: logger}
+}
+
+// Seed creates accounts accounts, the first one always being the well known
+// anthony/GG demo account, and spreads transactions ledger entries over them.
+func (sd *Seeder) Seed(accounts, transactions int) error {
+	demo, err := domain.NewAccount("anthony", "GG", seedPassword)
+	if err != nil {
+		return err
+	}
+	demo.Number = domain.AccountNumber(sd.rng.Int63n(10000000))
+	demo.CreatedAt = sd.now.AddDate(-1, 0, 0).Truncate(time.Second)
+	demo.UpdatedAt = demo.CreatedAt
+	perAccount := make([]int, accounts)
+	for i := 0; i < transactions && accounts > 0; i++ {
+		perAccount[sd.rng.Intn(accounts)]++
+	}
+	for i := 0; i < accounts; i++ {
+		account := demo
+		if i > 0 {
+			account = sd.account(i, demo.EncryptedPassword)
+		}
+		if err := sd.create(account); err != nil {
+			return err
+		}
+		if perAccount[i] > 0 {
+			if err := sd.store.ImportTransactions(account.ID, sd.history(account, perAccount[i])); err != nil {
+				return err
+			}
+		}
+		sd.logger.Info("seeded account", "number", account.Number, "transactions", perAccount[i])
+	}
+	return nil
+}
+
+func (sd *Seeder) create(account *domain.Account) error {
+	err := sd.store.CreateAccount(account)
+	for attempt := 0; attempt < 3 && errors.Is(err, domain.ErrDuplicateNumber); attempt++ {
+		account.Number = domain.AccountNumber(sd.rng.Int63n(10000000))
+		err = sd.store.CreateAccount(account)
+	}
+	return err
+}
+
+func (sd *Seeder) account(i int, encryptedPassword domain.Secret) *domain.Account {
+	first := seedFirstNames[sd.rng.Intn(len(seedFirstNames))]
+	last := seedLastNames[sd.rng.Intn(len(seedLastNames))]
+	languages := []string{"", "en", "de", "es", "fr"}
+	created := sd.now.AddDate(0, 0, -30-sd.rng.Intn(700)).Truncate(time.Second)
+	return &domain.Account{
+		FirstName:         domain.PII(first),
+		LastName:          domain.PII(last),
+		Email:             domain.PII(fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i)),
+		Timezone:          seedTimezones[sd.rng.Intn(len(seedTimezones))],
+		Language:          languages[sd.rng.Intn(len(languages))],
+		Number:            domain.AccountNumber(sd.rng.Int63n(10000000)),
+		EncryptedPassword: encryptedPassword,
+		Balance:           domain.Money{Currency: "USD"},
+		CreatedAt:         created,
+		UpdatedAt:         created,
+		Version:           1,
+	}
+}
+
+// history returns n entries between the account's creation and now, oldest
+// first: an opening deposit, then card spending with a salary every few
+// weeks. Spending never takes the balance below zero.
+func (sd *Seeder) history(account *domain.Account, n int) []*domain.Transaction {
+	span := max(sd.now.Sub(account.CreatedAt), time.Second)
+	dates := make([]time.Time, n)
+	for i := range dates {
+		dates[i] = account.CreatedAt.Add(time.Duration(sd.rng.Int63n(int64(span)))).Truncate(time.Second)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	currency := account.Balance.Currency
+	employer := seedEmployers[sd.rng.Intn(len(seedEmployers))]
+	salary := int64(200000 + sd.rng.Intn(400000))
+	var balance int64
+	txs := make([]*domain.Transaction, 0, n)
+	for i, date := range dates {
+		t := &domain.Transaction{AccountID: account.ID, Type: domain.TransactionImport, CreatedAt: date}
+		spend := int64(sd.rng.ExpFloat64()*4000) + 150
+		switch {
+		case i == 0:
+			t.Amount.MinorUnits = int64(50000 + sd.rng.Intn(500000))
+			t.Description = "Opening deposit"
+		case sd.rng.Intn(12) == 0 || spend > balance:
+			t.Amount.MinorUnits = salary
+			t.Description = "Salary " + employer
+		default:
+			t.Amount.MinorUnits = -spend
+			t.Description = seedMerchants[sd.rng.Intn(len(seedMerchants))]
+		}
+		t.Amount.Currency = currency
+		balance += t.Amount.MinorUnits
+		txs = append(txs, t)
+	}
+	return txs
+}
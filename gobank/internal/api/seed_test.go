@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestSeederIsDeterministic(t *testing.T) {
+	clock := fixedClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := NewSeeder(nil, 42, clock, logger)
+	b := NewSeeder(nil, 42, clock, logger)
+
+	accA, accB := a.account(1, "x"), b.account(1, "x")
+	assert.Equal(t, accA, accB)
+	assert.Equal(t, a.history(accA, 200), b.history(accB, 200))
+}
+
+func TestSeederHistoryStaysInTheBlack(t *testing.T) {
+	sd := NewSeeder(nil, 7, fixedClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	account := sd.account(1, "x")
+	var balance int64
+	var last time.Time
+	for _, tx := range sd.history(account, 500) {
+		balance += tx.Amount.MinorUnits
+		assert.GreaterOrEqual(t, balance, int64(0))
+		assert.False(t, tx.CreatedAt.Before(last))
+		assert.False(t, tx.CreatedAt.Before(account.CreatedAt))
+		last = tx.CreatedAt
+	}
+}
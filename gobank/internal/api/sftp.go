@@ -0,0 +1,626 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sftpTimeout bounds connecting to an SFTP server and logging in.
+const sftpTimeout = 30 * time.Second
+
+// The packets of version 3 of the SFTP protocol, which every server
+// speaks, that the client needs.
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpWrite   = 6
+	sftpOpendir = 11
+	sftpReaddir = 12
+	sftpRemove  = 13
+	sftpRename  = 18
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+	sftpName    = 104
+)
+
+const (
+	sftpFlagRead  = 0x01
+	sftpFlagWrite = 0x02
+	sftpFlagCreat = 0x08
+	sftpFlagTrunc = 0x10
+)
+
+const (
+	sftpOK               = 0
+	sftpEOF              = 1
+	sftpNoSuchFile       = 2
+	sftpPermissionDenied = 3
+)
+
+// sftpMaxPacket is the largest packet read from a server, and sftpChunk the
+// most data written in one.
+const (
+	sftpMaxPacket = 256 << 10
+	sftpChunk     = 32 << 10
+)
+
+// SFTPStatusError is an SFTP request the server answered with an error
+// status.
+type SFTPStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *SFTPStatusError) Error() string {
+	return fmt.Sprintf("sftp: %s (status %d)", e.Message, e.Code)
+}
+
+// sftpTarget is what an sftp://user@host[:port]/dir URL points at. Its
+// password, if any, is kept apart from the URL.
+type sftpTarget struct {
+	Addr string
+	User string
+	Dir  string
+}
+
+func parseSFTPURL(raw string) (*sftpTarget, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "sftp" || u.Hostname() == "" || u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sftp url %q", raw)
+	}
+	if _, ok := u.User.Password(); ok {
+		return nil, fmt.Errorf("sftp url %q has a password", raw)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	dir := strings.TrimSuffix(u.Path, "/")
+	if dir == "" {
+		dir = "."
+	}
+	return &sftpTarget{Addr: addr, User: u.User.Username(), Dir: dir}, nil
+}
+
+// sftpKeyHost reports whether hosts, host names or host:ports, has the
+// server at addr.
+func sftpKeyHost(hosts []string, addr string) bool {
+	host, _, _ := net.SplitHostPort(addr)
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) || strings.EqualFold(h, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSFTPKey reads the private key the server logs in to SFTP servers
+// with when it has no password for them.
+func LoadSFTPKey(file string) (ssh.Signer, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(pem)
+}
+
+// SFTPHostKeyError is a server whose host key isn't the one it should have.
+type SFTPHostKeyError struct {
+	Want, Got string
+}
+
+func (e *SFTPHostKeyError) Error() string {
+	return fmt.Sprintf("sftp host key is %s, not %s", e.Got, e.Want)
+}
+
+// sftpClient makes one SFTP request at a time over an SSH session.
+type sftpClient struct {
+	conn    *ssh.Client
+	session *ssh.Session
+	in      io.WriteCloser
+	out     io.Reader
+	id      uint32
+}
+
+// dialSFTP logs in to the target's server, with password or else signer,
+// if its host key has the SHA256 fingerprint hostKey.
+func dialSFTP(target *sftpTarget, password string, signer ssh.Signer, hostKey string) (*sftpClient, error) {
+	var auth []ssh.AuthMethod
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	if signer != nil {
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("no password or key to log in to the sftp server with")
+	}
+	conn, err := ssh.Dial("tcp", target.Addr, &ssh.ClientConfig{
+		User: target.User,
+		Auth: auth,
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if got := ssh.FingerprintSHA256(key); got != hostKey {
+				return &SFTPHostKeyError{Want: hostKey, Got: got}
+			}
+			return nil
+		},
+		Timeout: sftpTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := &sftpClient{conn: conn}
+	if err := c.start(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sftpClient) start() error {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return err
+	}
+	c.session = session
+	if c.in, err = session.StdinPipe(); err != nil {
+		return err
+	}
+	if c.out, err = session.StdoutPipe(); err != nil {
+		return err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return err
+	}
+	if err := c.writePacket(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, _, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion {
+		return fmt.Errorf("sftp: unexpected packet %d instead of the version", typ)
+	}
+	return nil
+}
+
+func (c *sftpClient) Close() error {
+	c.session.Close()
+	return c.conn.Close()
+}
+
+// Upload writes content to the file at name in dir. It goes to a temporary
+// file first and is renamed once complete, so whoever picks files up never
+// sees half of one. A file that is there already is replaced.
+func (c *sftpClient) Upload(dir, name string, content []byte) error {
+	final := path.Join(dir, name)
+	tmp := path.Join(dir, "."+name+".part")
+	handle, err := c.open(tmp, sftpFlagWrite|sftpFlagCreat|sftpFlagTrunc)
+	if err != nil {
+		return err
+	}
+	for offset := 0; offset < len(content); offset += sftpChunk {
+		chunk := content[offset:min(offset+sftpChunk, len(content))]
+		payload := sftpString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(offset))
+		payload = sftpString(payload, string(chunk))
+		if err := c.status(c.request(sftpWrite, payload)); err != nil {
+			c.closeHandle(handle)
+			return err
+		}
+	}
+	if err := c.closeHandle(handle); err != nil {
+		return err
+	}
+	// version 3 renames don't overwrite
+	if err := c.Remove(final); err != nil && !sftpNotFound(err) {
+		return err
+	}
+	return c.status(c.request(sftpRename, sftpString(sftpString(nil, tmp), final)))
+}
+
+// ReadFile returns the content of the file at name, the first limit bytes
+// of a longer one.
+func (c *sftpClient) ReadFile(name string, limit int) ([]byte, error) {
+	handle, err := c.open(name, sftpFlagRead)
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+	var content []byte
+	for {
+		payload := sftpString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(content)))
+		payload = binary.BigEndian.AppendUint32(payload, sftpChunk)
+		typ, data, err := c.request(sftpRead, payload)
+		if err != nil {
+			return nil, err
+		}
+		if typ != sftpData {
+			if err := c.status(typ, data, nil); err != nil && !sftpAtEOF(err) {
+				return nil, err
+			}
+			return content, nil
+		}
+		chunk, _, err := sftpReadString(data)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, chunk...)
+		if len(content) >= limit {
+			return content[:limit], nil
+		}
+	}
+}
+
+// ReadDir returns the names of the regular files in dir, in no particular
+// order.
+func (c *sftpClient) ReadDir(dir string) ([]string, error) {
+	typ, data, err := c.request(sftpOpendir, sftpString(nil, dir))
+	if err != nil {
+		return nil, err
+	}
+	if typ != sftpHandle {
+		return nil, c.status(typ, data, nil)
+	}
+	handle, _, err := sftpReadString(data)
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+	var names []string
+	for {
+		typ, data, err := c.request(sftpReaddir, sftpString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if typ != sftpName {
+			if err := c.status(typ, data, nil); err != nil && !sftpAtEOF(err) {
+				return nil, err
+			}
+			return names, nil
+		}
+		if names, err = sftpReadNames(names, data); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *sftpClient) Remove(name string) error {
+	return c.status(c.request(sftpRemove, sftpString(nil, name)))
+}
+
+func (c *sftpClient) open(name string, flags uint32) (string, error) {
+	payload := sftpString(nil, name)
+	payload = binary.BigEndian.AppendUint32(payload, flags)
+	// no attributes
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+	typ, data, err := c.request(sftpOpen, payload)
+	if err != nil {
+		return "", err
+	}
+	if typ != sftpHandle {
+		return "", c.status(typ, data, nil)
+	}
+	handle, _, err := sftpReadString(data)
+	return handle, err
+}
+
+func (c *sftpClient) closeHandle(handle string) error {
+	return c.status(c.request(sftpClose, sftpString(nil, handle)))
+}
+
+// request sends a packet with the next request id and returns the type and
+// the rest of the answer.
+func (c *sftpClient) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.id++
+	if err := c.writePacket(typ, append(binary.BigEndian.AppendUint32(nil, c.id), payload...)); err != nil {
+		return 0, nil, err
+	}
+	rtyp, data, err := c.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != c.id {
+		return 0, nil, errors.New("sftp: answer to another request")
+	}
+	return rtyp, data[4:], nil
+}
+
+// status turns an answer that should be a status into an error unless it
+// is OK.
+func (c *sftpClient) status(typ byte, data []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if typ != sftpStatus || len(data) < 4 {
+		return fmt.Errorf("sftp: unexpected packet %d instead of a status", typ)
+	}
+	code := binary.BigEndian.Uint32(data)
+	if code == sftpOK {
+		return nil
+	}
+	message, _, _ := sftpReadString(data[4:])
+	return &SFTPStatusError{Code: code, Message: message}
+}
+
+func (c *sftpClient) writePacket(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	_, err := c.in.Write(append(packet, payload...))
+	return err
+}
+
+func (c *sftpClient) readPacket() (byte, []byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(c.out, length[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n == 0 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: packet of %d bytes", n)
+	}
+	packet := make([]byte, n)
+	if _, err := io.ReadFull(c.out, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+func sftpString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func sftpReadString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, errors.New("sftp: short packet")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", nil, errors.New("sftp: short packet")
+	}
+	return string(b[4 : 4+n]), b[4+n:], nil
+}
+
+// sftpReadNames appends the regular files of a name packet to names.
+// Entries without permissions are taken to be files.
+func sftpReadNames(names []string, data []byte) ([]string, error) {
+	if len(data) < 4 {
+		return nil, errors.New("sftp: short packet")
+	}
+	count := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	for range count {
+		name, rest, err := sftpReadString(data)
+		if err != nil {
+			return nil, err
+		}
+		// the long name, like ls -l prints it
+		if _, rest, err = sftpReadString(rest); err != nil {
+			return nil, err
+		}
+		mode, rest, err := sftpReadAttrs(rest)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		if mode == 0 || mode&sftpModeType == sftpModeRegular {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// The file type bits of the permissions attribute.
+const (
+	sftpModeType    = 0o170000
+	sftpModeRegular = 0o100000
+)
+
+// sftpReadAttrs skips over file attributes and returns their permissions, 0
+// if they have none.
+func sftpReadAttrs(b []byte) (uint32, []byte, error) {
+	short := errors.New("sftp: short packet")
+	if len(b) < 4 {
+		return 0, nil, short
+	}
+	flags := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	var mode uint32
+	// size, uid and gid, permissions, access and modification time
+	for _, field := range []struct {
+		flag uint32
+		size int
+	}{{0x1, 8}, {0x2, 8}, {0x4, 4}, {0x8, 8}} {
+		if flags&field.flag == 0 {
+			continue
+		}
+		if len(b) < field.size {
+			return 0, nil, short
+		}
+		if field.flag == 0x4 {
+			mode = binary.BigEndian.Uint32(b)
+		}
+		b = b[field.size:]
+	}
+	if flags&0x80000000 != 0 {
+		if len(b) < 4 {
+			return 0, nil, short
+		}
+		n := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		for range 2 * n {
+			var err error
+			if _, b, err = sftpReadString(b); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+	return mode, b, nil
+}
+
+func sftpNotFound(err error) bool {
+	var status *SFTPStatusError
+	return errors.As(err, &status) && status.Code == sftpNoSuchFile
+}
+
+func sftpAtEOF(err error) bool {
+	var status *SFTPStatusError
+	return errors.As(err, &status) && status.Code == sftpEOF
+}
+
+// sftpRefused reports whether an SFTP error won't go away by trying again:
+// the server isn't the one it should be, doesn't let the user in or
+// doesn't let them write there.
+func sftpRefused(err error) bool {
+	var hostKey *SFTPHostKeyError
+	var status *SFTPStatusError
+	if errors.As(err, &hostKey) || strings.Contains(err.Error(), "unable to authenticate") {
+		return true
+	}
+	return errors.As(err, &status) && (status.Code == sftpNoSuchFile || status.Code == sftpPermissionDenied)
+}
+
+// SFTPBlobStore is a directory of an SFTP server as a storage.BlobStore,
+// for the batch intake. It logs in when first used and stays logged in until
+// Close or an error that isn't the server's answer.
+type SFTPBlobStore struct {
+	target   *sftpTarget
+	password string
+	signer   ssh.Signer
+	hostKey  string
+	// limit bounds how much of an object Get reads.
+	limit  int
+	client *sftpClient
+}
+
+func NewSFTPBlobStore(rawURL, password string, signer ssh.Signer, hostKey string, limit int) (*SFTPBlobStore, error) {
+	target, err := parseSFTPURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &SFTPBlobStore{target: target, password: password, signer: signer, hostKey: hostKey, limit: limit}, nil
+}
+
+// Sub returns the store of the subdirectory dir, which logs in on its own.
+func (b *SFTPBlobStore) Sub(dir string) *SFTPBlobStore {
+	target := *b.target
+	target.Dir = path.Join(target.Dir, dir)
+	return &SFTPBlobStore{target: &target, password: b.password, signer: b.signer, hostKey: b.hostKey, limit: b.limit}
+}
+
+func (b *SFTPBlobStore) conn() (*sftpClient, error) {
+	if b.client == nil {
+		c, err := dialSFTP(b.target, b.password, b.signer, b.hostKey)
+		if err != nil {
+			return nil, err
+		}
+		b.client = c
+	}
+	return b.client, nil
+}
+
+// done logs out after an error the connection may not survive, so the next
+// call logs in again.
+func (b *SFTPBlobStore) done(err error) error {
+	var status *SFTPStatusError
+	if err != nil && !errors.As(err, &status) {
+		b.Close()
+	}
+	return err
+}
+
+func (b *SFTPBlobStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return path.Join(b.target.Dir, key), nil
+}
+
+func (b *SFTPBlobStore) Put(key string, r io.Reader) error {
+	if _, err := b.path(key); err != nil {
+		return err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c, err := b.conn()
+	if err != nil {
+		return err
+	}
+	return b.done(c.Upload(b.target.Dir, key, content))
+}
+
+func (b *SFTPBlobStore) Get(key string) (io.ReadCloser, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.conn()
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.ReadFile(name, b.limit)
+	if err != nil {
+		return nil, b.done(err)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (b *SFTPBlobStore) Delete(key string) error {
+	name, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	c, err := b.conn()
+	if err != nil {
+		return err
+	}
+	return b.done(c.Remove(name))
+}
+
+func (b *SFTPBlobStore) List(prefix string) ([]string, error) {
+	c, err := b.conn()
+	if err != nil {
+		return nil, err
+	}
+	names, err := c.ReadDir(b.target.Dir)
+	if err != nil {
+		return nil, b.done(err)
+	}
+	keys := []string{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, ".") && strings.HasPrefix(name, prefix) {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (b *SFTPBlobStore) Close() error {
+	if b.client == nil {
+		return nil
+	}
+	err := b.client.Close()
+	b.client = nil
+	return err
+}
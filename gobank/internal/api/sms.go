@@ -0,0 +1,388 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMSProvider sends text messages to E.164 numbers and returns the
+// provider's id of the message. A message the provider won't take however
+// often it is asked is an *SMSRefusedError, every other error is worth
+// retrying.
+type SMSProvider interface {
+	SendSMS(to, body string) (string, error)
+}
+
+// SMSRefusedError is a text message the provider refused for good, mostly
+// because the number can't get texts or opted out of them.
+type SMSRefusedError struct {
+	Err error
+}
+
+func (e *SMSRefusedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SMSRefusedError) Unwrap() error {
+	return e.Err
+}
+
+// NewSMSProvider returns the provider cfg asks for, nil when it asks for
+// none.
+func NewSMSProvider(cfg *Config, logger *slog.Logger) (SMSProvider, error) {
+	switch cfg.SMSProvider {
+	case "":
+		return nil, nil
+	case "log":
+		return NewLogSMSProvider(logger), nil
+	case "twilio":
+		return NewTwilioProvider(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFrom), nil
+	}
+	return nil, fmt.Errorf("unknown SMS_PROVIDER %s", cfg.SMSProvider)
+}
+
+// TwilioProvider sends text messages with Twilio's Messages API.
+type TwilioProvider struct {
+	endpoint  string
+	accountID string
+	authToken string
+	from      string
+	client    *http.Client
+}
+
+func NewTwilioProvider(accountSID, authToken, from string) *TwilioProvider {
+	return &TwilioProvider{
+		endpoint:  "https://api.twilio.com/2010-04-01/Accounts/" + url.PathEscape(accountSID) + "/Messages.json",
+		accountID: accountSID,
+		authToken: authToken,
+		from:      from,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *TwilioProvider) SendSMS(to, body string) (string, error) {
+	form := url.Values{"To": {to}, "Body": {body}}
+	// a messaging service picks the sending number itself
+	if strings.HasPrefix(p.from, "MG") {
+		form.Set("MessagingServiceSid", p.from)
+	} else {
+		form.Set("From", p.from)
+	}
+	req, err := http.NewRequest(http.MethodPost, p.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.accountID, p.authToken)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var out struct {
+		SID     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&out)
+	return twilioResult(res.StatusCode, out.SID, out.Code, out.Message)
+}
+
+// twilioResult interprets Twilio's answer. It answers 400 to messages it
+// won't send, to invalid or landline numbers and to ones that replied STOP,
+// the other errors are about the account or Twilio itself.
+func twilioResult(status int, sid string, code int, message string) (string, error) {
+	if status == http.StatusCreated || status == http.StatusOK {
+		return sid, nil
+	}
+	err := fmt.Errorf("twilio: %d %d: %s", status, code, message)
+	if status == http.StatusBadRequest {
+		return "", &SMSRefusedError{Err: err}
+	}
+	return "", err
+}
+
+// LogSMSProvider only logs the messages, codes included, for development
+// without a Twilio account.
+type LogSMSProvider struct {
+	logger *slog.Logger
+}
+
+func NewLogSMSProvider(logger *slog.Logger) *LogSMSProvider {
+	return &LogSMSProvider{logger: logger}
+}
+
+func (p *LogSMSProvider) SendSMS(to, body string) (string, error) {
+	id := domain.NewUUID()
+	p.logger.Info("sms", "id", id, "to", domain.PII(to), "body", body)
+	return id, nil
+}
+
+// verificationText is the data of the sms.verification_code template.
+type verificationText struct {
+	Code    string
+	Brand   string
+	Minutes int
+}
+
+// transferAlertText is the data of the sms.transfer_alert template, Notice
+// the subject of the transfer's notice.
+type transferAlertText struct {
+	Brand  string
+	Notice string
+}
+
+// SMSAlerter texts the holders who turned SMS alerts on about their
+// transfers.
+type SMSAlerter struct {
+	store    storage.Storage
+	settings *TenantSettingsCache
+	logger   *slog.Logger
+}
+
+func NewSMSAlerter(store storage.Storage, logger *slog.Logger) *SMSAlerter {
+	return &SMSAlerter{store: store, settings: NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings), logger: logger}
+}
+
+// Notify is subscribed to the bus, it queues an alert for each side of a
+// completed transfer that wants one.
+func (a *SMSAlerter) Notify(ev *domain.Event) {
+	if ev.Type != domain.EventTransferCompleted {
+		return
+	}
+	if err := a.transferAlerts(ev); err != nil {
+		a.logger.Error("queueing sms failed", "event_id", ev.ID, "event_type", ev.Type, "error", err)
+	}
+}
+
+func (a *SMSAlerter) transferAlerts(ev *domain.Event) error {
+	var payload struct {
+		TransactionID int `json:"transactionId"`
+	}
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		return err
+	}
+	out, in, err := a.store.TransferLegs(payload.TransactionID)
+	if errors.Is(err, domain.ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, tx := range []*domain.Transaction{out, in} {
+		if tx == nil {
+			continue
+		}
+		account, err := a.store.FindAccount(tx.AccountID)
+		if errors.Is(err, domain.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !account.SMSAlerts || !account.PhoneVerified {
+			continue
+		}
+		notice, _, ok, err := renderTransferNotice(account, tx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		settings, err := a.settings.Get(account.TenantID)
+		if err != nil {
+			return err
+		}
+		body, err := renderText(account, "sms.transfer_alert", transferAlertText{Brand: settings.BrandName, Notice: notice})
+		if err != nil {
+			return err
+		}
+		if _, err := a.store.QueueSMS(domain.NewSMS(account, domain.SMSTransferAlert, fmt.Sprintf("transfer_alert:%d", tx.ID), body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SMSDeliverer sends the queued text messages.
+type SMSDeliverer struct {
+	store    storage.Storage
+	provider SMSProvider
+	metrics  *Metrics
+	logger   *slog.Logger
+}
+
+func NewSMSDeliverer(store storage.Storage, provider SMSProvider, metrics *Metrics, logger *slog.Logger) *SMSDeliverer {
+	metrics.Help("sms_total", "Text message send attempts by kind and result.")
+	return &SMSDeliverer{store: store, provider: provider, metrics: metrics, logger: logger}
+}
+
+// HandleJob makes one attempt to send a message, like
+// EmailDeliverer.HandleJob. Messages to a number that was changed since
+// they were queued are dropped.
+func (d *SMSDeliverer) HandleJob(job *domain.Job) error {
+	var payload storage.SMSJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	sms, err := d.store.GetSMS(payload.SMSID)
+	if errors.Is(err, domain.ErrSMSNotFound) {
+		// the account was deleted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sms.Status != domain.EmailPending {
+		return nil
+	}
+	account, err := d.store.FindAccount(sms.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sms.Attempts++
+	var sendErr error
+	if account.Phone != sms.To || !account.PhoneVerified {
+		sms.Status, sms.LastError = domain.EmailFailed, "phone number changed"
+	} else {
+		var providerID string
+		providerID, sendErr = d.provider.SendSMS(sms.To.Reveal(), sms.Body)
+		now := time.Now().UTC()
+		var refused *SMSRefusedError
+		switch {
+		case sendErr == nil:
+			sms.Status, sms.ProviderID, sms.SentAt, sms.LastError = domain.EmailSent, providerID, &now, ""
+		case errors.As(sendErr, &refused):
+			sms.Status, sms.LastError = domain.EmailFailed, sendErr.Error()
+		default:
+			sms.LastError = sendErr.Error()
+		}
+	}
+	if err := d.store.RecordSMSAttempt(sms); err != nil {
+		return err
+	}
+	result := sms.Status
+	if result == domain.EmailPending {
+		result = "retry"
+	}
+	d.metrics.Inc("sms_total", "kind", sms.Kind, "result", result)
+	if sms.Status == domain.EmailPending {
+		return fmt.Errorf("sms %s: %w", sms.ID, sendErr)
+	}
+	if sms.Status == domain.EmailFailed {
+		d.logger.Warn("sms not sent", "sms_id", sms.ID, "tenant_id", sms.TenantID, "to", sms.To, "error", sms.LastError)
+	}
+	return nil
+}
+
+type PhoneVerificationResponse struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type ConfirmPhoneRequest struct {
+	Code string `json:"code"`
+}
+
+// handleSendPhoneCode serves POST /account/{id}/phone/verification. It texts
+// a one-time code to the account's phone right away, the holder is waiting
+// for it, and at most one a minute.
+func (s *APIServer) handleSendPhoneCode(w http.ResponseWriter, r *http.Request) error {
+	if s.sms == nil {
+		writeError(w, r, http.StatusServiceUnavailable, NewError(CodeSMSUnavailable))
+		return nil
+	}
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	if account.Phone == "" {
+		return NewError(CodePhoneRequired)
+	}
+	now := s.clock.Now().UTC()
+	previous, err := store.GetPhoneVerification(account.ID)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if wait := previous.SentAt.Add(domain.VerificationResendAfter).Sub(now); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			writeError(w, r, http.StatusTooManyRequests, NewError(CodeRateLimited))
+			return nil
+		}
+	}
+	verification, code, err := domain.NewPhoneVerification(account, now)
+	if err != nil {
+		return err
+	}
+	settings, err := s.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "sms.verification_code", verificationText{Code: code, Brand: settings.BrandName, Minutes: int(domain.VerificationCodeTTL.Minutes())})
+	if err != nil {
+		return err
+	}
+	if err := store.SavePhoneVerification(verification); err != nil {
+		return err
+	}
+	if _, err := s.sms.SendSMS(account.Phone.Reveal(), body); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusAccepted, PhoneVerificationResponse{ExpiresAt: verification.ExpiresAt})
+}
+
+// handleConfirmPhone serves POST /account/{id}/phone/verification/confirm
+// with the code that was texted. Each wrong guess counts, after
+// domain.MaxVerificationAttempts the holder needs a new code.
+func (s *APIServer) handleConfirmPhone(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req := new(ConfirmPhoneRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	verification, err := store.GetPhoneVerification(account.ID)
+	if err != nil {
+		return err
+	}
+	if verification == nil || verification.Phone != account.Phone {
+		return domain.ErrVerificationFailed
+	}
+	if !verification.Check(req.Code, s.clock.Now()) {
+		if err := store.CountVerificationAttempt(account.ID); err != nil {
+			return err
+		}
+		return domain.ErrVerificationFailed
+	}
+	if err := store.ConfirmPhone(account, verification.Phone); err != nil {
+		return err
+	}
+	setValidators(w, account)
+	return WriteJSON(w, http.StatusOK, account)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTwilioResult(t *testing.T) {
+	sid, err := twilioResult(201, "SM123", 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "SM123", sid)
+
+	var refused *SMSRefusedError
+	_, err = twilioResult(400, "", 21610, "Attempt to send to unsubscribed recipient")
+	assert.True(t, errors.As(err, &refused))
+	_, err = twilioResult(429, "", 20429, "Too Many Requests")
+	assert.False(t, errors.As(err, &refused))
+}
+
+func TestVerificationText(t *testing.T) {
+	account := &domain.Account{Language: "de"}
+	body, err := renderText(account, "sms.verification_code", verificationText{Code: "042917", Brand: "Bänk", Minutes: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, "042917 ist Ihr Bestätigungscode für Bänk. Er läuft in 10 Minuten ab.", body)
+}
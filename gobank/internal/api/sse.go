@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// writeSSE writes one server-sent event. id is left out when empty so the
+// client keeps the last one it saw.
+func writeSSE(w io.Writer, id, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// handleAccountEvents serves GET /account/{id}/events as a text/event-stream.
+// Every transaction is sent as a "transaction" event with its id as the event
+// id, followed by a "balance" event. Reconnecting clients send Last-Event-ID
+// and get the transactions they missed.
+func (s *APIServer) handleAccountEvents(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming not supported")
+	}
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	cursor := 0
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if cursor, err = strconv.Atoi(v); err != nil || cursor < 0 {
+			return NewError(CodeInvalidParameter, "name", "Last-Event-ID", "value", v)
+		}
+	} else if cursor, err = store.LastTransactionID(id); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// tell the client how long to wait before reconnecting
+	fmt.Fprintf(w, "retry: %d\n\n", feedPollInterval.Milliseconds())
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		wake := s.notifier.Wait()
+		txs, err := store.TransactionsAfter(id, cursor, feedPageSize)
+		if err != nil {
+			loggerFrom(r.Context()).Error("reading account events failed", "error", err)
+			return nil
+		}
+		if len(txs) > 0 {
+			for _, t := range txs {
+				if err := writeSSE(w, strconv.Itoa(t.ID), "transaction", t); err != nil {
+					return nil
+				}
+			}
+			cursor = txs[len(txs)-1].ID
+			account, err := store.GetAccountById(id)
+			if err != nil {
+				loggerFrom(r.Context()).Error("reading account events failed", "error", err)
+				return nil
+			}
+			if err := writeSSE(w, "", "balance", map[string]domain.Money{"balance": account.Balance}); err != nil {
+				return nil
+			}
+			flusher.Flush()
+			if len(txs) == feedPageSize {
+				continue
+			}
+		}
+		select {
+		case <-wake:
+		case <-time.After(feedPollInterval):
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriteSSE(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeSSE(&buf, "7", "transaction", map[string]int{"id": 7}))
+	assert.Nil(t, writeSSE(&buf, "", "balance", map[string]int{"balance": 1}))
+	assert.Equal(t, "id: 7\nevent: transaction\ndata: {\"id\":7}\n\nevent: balance\ndata: {\"balance\":1}\n\n", buf.String())
+}
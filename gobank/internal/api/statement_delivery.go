@@ -0,0 +1,424 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"golang.org/x/crypto/ssh"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+)
+
+// StatementFile is a statement as it goes to a destination, the camt.053
+// of the account's days From to To.
+type StatementFile struct {
+	Name     string
+	Account  *domain.Account
+	From, To time.Time
+	Content  []byte
+}
+
+// StatementSink delivers statements to destinations of one kind. A delivery
+// the destination won't take however often it is asked is a
+// *StatementRefusedError, every other error is worth retrying.
+type StatementSink interface {
+	Deliver(dest *domain.StatementDestination, file *StatementFile) error
+}
+
+type StatementRefusedError struct {
+	Err error
+}
+
+func (e *StatementRefusedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatementRefusedError) Unwrap() error {
+	return e.Err
+}
+
+// NewStatementSinks returns the sinks by kind. Email needs an email
+// provider, so there is no email sink without emails.
+func NewStatementSinks(cfg *Config, store storage.Storage, emails EmailSender) (map[string]StatementSink, error) {
+	sinks := map[string]StatementSink{}
+	if emails != nil {
+		settings := NewTenantSettingsCache(store, 5*time.Minute, domain.DefaultTenantSettings)
+		sinks[domain.StatementSinkEmail] = &EmailStatementSink{sender: emails, from: cfg.EmailFrom, settings: settings}
+	}
+	sftp := &SFTPStatementSink{keyHosts: cfg.SFTPKeyHosts, secretsKey: cfg.SecretsKey}
+	if cfg.SFTPKeyFile != "" {
+		signer, err := LoadSFTPKey(cfg.SFTPKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		sftp.signer = signer
+	}
+	sinks[domain.StatementSinkSFTP] = sftp
+	return sinks, nil
+}
+
+// statementDeliveryNotice is the data of the statement_delivery templates.
+type statementDeliveryNotice struct {
+	Account  *domain.Account
+	From, To time.Time
+}
+
+// EmailStatementSink sends statements attached to an email, from the
+// address the other emails go out from. It sends them right away rather
+// than through the email queue, the delivery is what is retried.
+type EmailStatementSink struct {
+	sender   EmailSender
+	from     string
+	settings *TenantSettingsCache
+}
+
+func (s *EmailStatementSink) Deliver(dest *domain.StatementDestination, file *StatementFile) error {
+	account := file.Account
+	data := statementDeliveryNotice{Account: account, From: file.From, To: file.To}
+	subject, err := renderText(account, "statement_delivery.subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := renderText(account, "statement_delivery.body", data)
+	if err != nil {
+		return err
+	}
+	settings, err := s.settings.Get(dest.TenantID)
+	if err != nil {
+		return err
+	}
+	text, html, err := renderEmail(account, settings, subject, body)
+	if err != nil {
+		return err
+	}
+	msg := &OutgoingEmail{From: s.from, To: dest.Email.Reveal(), MessageID: domain.NewUUID() + "@" + s.from[strings.LastIndexByte(s.from, '@')+1:]}
+	msg.Raw, err = buildEmail(mail.Address{Name: settings.BrandName, Address: s.from}, msg.To, subject, text, html, msg.MessageID, time.Now().UTC(),
+		emailAttachment{Name: file.Name, ContentType: "application/xml", Content: file.Content})
+	if err != nil {
+		return err
+	}
+	_, err = s.sender.Send(msg)
+	var refused *EmailRefusedError
+	if errors.As(err, &refused) {
+		return &StatementRefusedError{Err: err}
+	}
+	return err
+}
+
+// SFTPStatementSink uploads statements into the directory of the
+// destination's URL. It logs in with the destination's password, or with
+// the server's key to the keyHosts only.
+type SFTPStatementSink struct {
+	signer     ssh.Signer
+	keyHosts   []string
+	secretsKey []byte
+}
+
+func (s *SFTPStatementSink) Deliver(dest *domain.StatementDestination, file *StatementFile) error {
+	target, err := parseSFTPURL(dest.URL)
+	if err != nil {
+		return &StatementRefusedError{Err: err}
+	}
+	var password string
+	var signer ssh.Signer
+	if dest.Password != "" {
+		if password, err = openSecret(s.secretsKey, dest.Password.Reveal()); err != nil {
+			return &StatementRefusedError{Err: fmt.Errorf("destination password: %w", err)}
+		}
+	} else if sftpKeyHost(s.keyHosts, target.Addr) {
+		signer = s.signer
+	} else {
+		return &StatementRefusedError{Err: fmt.Errorf("the server's key doesn't log in to %s", target.Addr)}
+	}
+	c, err := dialSFTP(target, password, signer, dest.HostKey)
+	if err == nil {
+		err = c.Upload(target.Dir, file.Name, file.Content)
+		c.Close()
+	}
+	if err != nil && sftpRefused(err) {
+		return &StatementRefusedError{Err: err}
+	}
+	return err
+}
+
+// statementFileName is the name a statement goes by at its destination,
+// the day of daily ones and the first and last day of the others.
+func statementFileName(d *domain.StatementDelivery) string {
+	if d.From == d.To {
+		return fmt.Sprintf("camt053-%d-%s.xml", d.AccountID, d.From)
+	}
+	return fmt.Sprintf("camt053-%d-%s-%s.xml", d.AccountID, d.From, d.To)
+}
+
+// StatementDispatcher turns the end of day statements into deliveries for
+// the destinations of their accounts whose period the day ends.
+type StatementDispatcher struct {
+	store  storage.Storage
+	logger *slog.Logger
+}
+
+func NewStatementDispatcher(store storage.Storage, logger *slog.Logger) *StatementDispatcher {
+	return &StatementDispatcher{store: store, logger: logger}
+}
+
+// Dispatch is subscribed to the bus. The store keeps a single delivery per
+// destination and period, however often the event is relayed.
+func (d *StatementDispatcher) Dispatch(ev *domain.Event) {
+	if ev.Type != domain.EventStatementReady {
+		return
+	}
+	if err := d.dispatch(ev); err != nil {
+		d.logger.Error("queueing statement deliveries failed", "event_id", ev.ID, "error", err)
+	}
+}
+
+func (d *StatementDispatcher) dispatch(ev *domain.Event) error {
+	account, day, err := statementOf(d.store, ev)
+	if account == nil || err != nil {
+		return err
+	}
+	store := d.store.ForTenant(account.TenantID)
+	destinations, err := store.ListStatementDestinations(account.ID)
+	if err != nil {
+		return err
+	}
+	for _, dest := range destinations {
+		from, ok := domain.StatementPeriod(dest.Frequency, day)
+		if !ok {
+			continue
+		}
+		if _, err := store.QueueStatementDelivery(domain.NewStatementDelivery(dest, from, day)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StatementDeliverer writes the statements of deliveries and hands them to
+// the sink of their destination.
+type StatementDeliverer struct {
+	store   storage.Storage
+	sinks   map[string]StatementSink
+	clock   domain.Clock
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewStatementDeliverer(store storage.Storage, sinks map[string]StatementSink, clock domain.Clock, metrics *Metrics, logger *slog.Logger) *StatementDeliverer {
+	metrics.Help("statement_deliveries_total", "Statement delivery attempts by sink and result.")
+	return &StatementDeliverer{store: store, sinks: sinks, clock: clock, metrics: metrics, logger: logger}
+}
+
+// HandleJob makes one attempt of a delivery. One the destination may take
+// later fails the job, so the pool retries it with backoff, and the
+// delivery fails with the job's last attempt. One the destination refused
+// fails right away.
+func (d *StatementDeliverer) HandleJob(job *domain.Job) error {
+	var payload storage.StatementDeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	delivery, dest, err := d.store.GetStatementDelivery(payload.DeliveryID)
+	if errors.Is(err, domain.ErrStatementDeliveryNotFound) {
+		// the destination was deleted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != domain.DeliveryPending {
+		return nil
+	}
+	account, err := d.store.FindAccount(delivery.AccountID)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	file, err := d.statement(account, delivery)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	var deliverErr error
+	if sink := d.sinks[dest.Sink]; sink == nil {
+		deliverErr = &StatementRefusedError{Err: fmt.Errorf("no %s sink is configured", dest.Sink)}
+	} else {
+		deliverErr = sink.Deliver(dest, file)
+	}
+	var refused *StatementRefusedError
+	switch {
+	case deliverErr == nil:
+		now := time.Now().UTC()
+		delivery.Status, delivery.DeliveredAt, delivery.LastError = domain.DeliverySucceeded, &now, ""
+	case errors.As(deliverErr, &refused) || job.Attempts >= job.MaxAttempts:
+		delivery.Status, delivery.LastError = domain.DeliveryFailed, deliverErr.Error()
+	default:
+		delivery.LastError = deliverErr.Error()
+	}
+	if err := d.store.RecordStatementAttempt(delivery); err != nil {
+		return err
+	}
+	result := delivery.Status
+	if result == domain.DeliveryPending {
+		result = "retry"
+	}
+	d.metrics.Inc("statement_deliveries_total", "sink", dest.Sink, "result", result)
+	switch delivery.Status {
+	case domain.DeliveryPending:
+		return fmt.Errorf("statement delivery %s: %w", delivery.ID, deliverErr)
+	case domain.DeliveryFailed:
+		d.logger.Warn("statement not delivered", "delivery_id", delivery.ID, "destination_id", dest.ID, "tenant_id", dest.TenantID, "error", deliverErr)
+	}
+	return nil
+}
+
+// statement writes the statement of the delivery's days in the account's
+// time zone.
+func (d *StatementDeliverer) statement(account *domain.Account, delivery *domain.StatementDelivery) (*StatementFile, error) {
+	loc, err := loadLocation(account.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	from, err := time.ParseInLocation("2006-01-02", delivery.From, loc)
+	if err != nil {
+		return nil, err
+	}
+	to, err := time.ParseInLocation("2006-01-02", delivery.To, loc)
+	if err != nil {
+		return nil, err
+	}
+	content, err := renderCamt053(d.store.ForTenant(account.TenantID), account, from, to.AddDate(0, 0, 1), d.clock.Now(), loc)
+	if err != nil {
+		return nil, err
+	}
+	return &StatementFile{Name: statementFileName(delivery), Account: account, From: from, To: to, Content: content}, nil
+}
+
+// StatementDestinationRequest adds a destination. Email ones need Email,
+// SFTP ones a URL like sftp://user@host/dir and the SHA256 fingerprint of
+// the server's host key. Without a password they log in with the server's
+// key, which only hosts of SFTP_KEY_HOSTS take.
+type StatementDestinationRequest struct {
+	Sink      string        `json:"sink"`
+	Frequency string        `json:"frequency"`
+	Email     domain.PII    `json:"email"`
+	URL       string        `json:"url"`
+	Password  domain.Secret `json:"password"`
+	HostKey   string        `json:"hostKey"`
+}
+
+// handleStatementDestinations serves /account/{id}/statement-destinations,
+// where the account's statements are delivered to. Each one gets a
+// statement at the end of its period, from the day after the last one.
+func (s *APIServer) handleStatementDestinations(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	if isGet(r) {
+		destinations, err := store.ListStatementDestinations(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, destinations)
+	}
+	req := new(StatementDestinationRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return err
+	}
+	if req.Frequency == "" {
+		req.Frequency = domain.StatementDaily
+	}
+	if !slices.Contains(domain.StatementFrequencies, req.Frequency) {
+		return invalidParameter("frequency", req.Frequency)
+	}
+	dest := domain.NewStatementDestination(id, req.Sink, req.Frequency)
+	switch req.Sink {
+	case domain.StatementSinkEmail:
+		if s.config.Get().EmailProvider == "" {
+			return invalidParameter("sink", req.Sink)
+		}
+		dest.Email = domain.NormalizeEmail(req.Email)
+		if err := domain.ValidateEmail(dest.Email); err != nil {
+			return err
+		}
+	case domain.StatementSinkSFTP:
+		target, err := parseSFTPURL(req.URL)
+		if err != nil {
+			return invalidParameter("url", req.URL)
+		}
+		if !strings.HasPrefix(req.HostKey, "SHA256:") {
+			return invalidParameter("hostKey", req.HostKey)
+		}
+		dest.URL, dest.HostKey = req.URL, req.HostKey
+		cfg := s.config.Get()
+		if req.Password == "" {
+			if !sftpKeyHost(cfg.SFTPKeyHosts, target.Addr) {
+				return invalidParameter("url", req.URL)
+			}
+		} else {
+			if len(cfg.SecretsKey) == 0 {
+				return invalidParameter("password", domain.Redacted)
+			}
+			sealed, err := sealSecret(cfg.SecretsKey, req.Password.Reveal())
+			if err != nil {
+				return err
+			}
+			dest.Password = domain.Secret(sealed)
+		}
+	default:
+		return invalidParameter("sink", req.Sink)
+	}
+	if err := store.CreateStatementDestination(dest); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusCreated, dest)
+}
+
+func (s *APIServer) handleDeleteStatementDestination(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	destinationID := r.PathValue("destinationId")
+	if err := s.storeFor(r).DeleteStatementDestination(id, destinationID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": destinationID})
+}
+
+func (s *APIServer) handleStatementDeliveries(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deliveries, err := s.storeFor(r).ListStatementDeliveries(id, r.PathValue("destinationId"), 100)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// handleRetryStatementDelivery delivers a statement again, whether or not
+// it was delivered before. It answers once the delivery is queued.
+func (s *APIServer) handleRetryStatementDelivery(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deliveryID := r.PathValue("deliveryId")
+	if _, err := s.storeFor(r).RetryStatementDelivery(id, r.PathValue("destinationId"), deliveryID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusAccepted, map[string]string{"queued": deliveryID})
+}
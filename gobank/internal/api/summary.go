@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"time"
+)
+
+// handleAccountSummary serves GET /account/{id}/summary, everything a home
+// screen needs in one call. The month starts in the account's time zone.
+func (s *APIServer) handleAccountSummary(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	loc, err := locationFor(r, account)
+	if err != nil {
+		return err
+	}
+	summary, err := store.AccountSummary(account.ID)
+	if err != nil {
+		return err
+	}
+	if summary.MonthToDateSpend, err = s.monthToDateSpend(store, account, loc); err != nil {
+		return err
+	}
+	if summary.RecentTransactions, err = store.TransactionsBefore(account.ID, domain.TransactionCursor{}, 5); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, summary)
+}
+
+// monthToDateSpend comes from the monthly projection when the month is that
+// of the account's own time zone, the projection's months, and is summed
+// from the daily totals of the ledger for any other.
+func (s *APIServer) monthToDateSpend(store storage.Storage, account *domain.Account, loc *time.Location) (domain.Money, error) {
+	spend := domain.Money{Currency: account.Balance.Currency}
+	now := s.clock.Now()
+	if own, err := loadLocation(account.Timezone); err == nil && own.String() == loc.String() {
+		month := now.In(loc).Format("2006-01")
+		totals, err := store.MonthlyTotals(account.ID, month)
+		if err != nil {
+			return spend, err
+		}
+		for _, t := range totals {
+			if t.Month == month && t.Debits.Currency == spend.Currency {
+				spend.MinorUnits += t.Debits.MinorUnits
+			}
+		}
+		return spend, nil
+	}
+	totals, err := store.DailyTotals(account.ID, domain.StartOfMonth(now, loc), loc)
+	if err != nil {
+		return spend, err
+	}
+	for _, t := range totals {
+		if t.Debits.Currency == spend.Currency {
+			spend.MinorUnits += t.Debits.MinorUnits
+		}
+	}
+	return spend, nil
+}
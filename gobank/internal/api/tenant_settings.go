@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type cachedSettings struct {
+	settings *domain.TenantSettings
+	expires  time.Time
+}
+
+// TenantSettingsCache keeps tenant settings in memory for ttl so the hot
+// request path doesn't hit the database.
+// Tenants without their own settings get the ones built by defaults.
+type TenantSettingsCache struct {
+	store    storage.TenantSettingsStore
+	ttl      time.Duration
+	defaults func(tenantID int) *domain.TenantSettings
+	mu       sync.RWMutex
+	entries  map[int]cachedSettings
+}
+
+func NewTenantSettingsCache(store storage.TenantSettingsStore, ttl time.Duration, defaults func(tenantID int) *domain.TenantSettings) *TenantSettingsCache {
+	return &TenantSettingsCache{store: store, ttl: ttl, defaults: defaults, entries: map[int]cachedSettings{}}
+}
+
+func (c *TenantSettingsCache) Get(tenantID int) (*domain.TenantSettings, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[tenantID]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.settings, nil
+	}
+
+	settings, err := c.store.GetTenantSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		// not cached so a config reload applies straight away
+		return c.defaults(tenantID), nil
+	}
+	c.mu.Lock()
+	c.entries[tenantID] = cachedSettings{settings: settings, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return settings, nil
+}
+
+func (c *TenantSettingsCache) Save(settings *domain.TenantSettings) error {
+	if err := c.store.SaveTenantSettings(settings); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.entries, settings.TenantID)
+	c.mu.Unlock()
+	return nil
+}
+
+func (s *APIServer) defaultTenantSettings(tenantID int) *domain.TenantSettings {
+	runtime := s.config.Get().Runtime
+	settings := domain.DefaultTenantSettings(tenantID)
+	settings.MaxTransferAmount = runtime.MaxTransferAmount
+	settings.DailyTransferLimit = runtime.DailyTransferLimit
+	return settings
+}
+
+func (s *APIServer) handleTenantSettings(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if isGet(r) {
+		settings, err := s.settings.Get(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, settings)
+	}
+	if r.Method == http.MethodPut {
+		settings := s.defaultTenantSettings(id)
+		if err := json.NewDecoder(r.Body).Decode(settings); err != nil {
+			return err
+		}
+		settings.TenantID = id
+		settings.UpdatedAt = time.Now().UTC()
+		if err := settings.Validate(); err != nil {
+			return err
+		}
+		if err := s.settings.Save(settings); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, settings)
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSettingsStore struct {
+	settings map[int]*domain.TenantSettings
+	reads    int
+}
+
+func (f *fakeSettingsStore) GetTenantSettings(tenantID int) (*domain.TenantSettings, error) {
+	f.reads++
+	if s, ok := f.settings[tenantID]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeSettingsStore) SaveTenantSettings(settings *domain.TenantSettings) error {
+	f.settings[settings.TenantID] = settings
+	return nil
+}
+
+func TestTenantSettingsCache(t *testing.T) {
+	store := &fakeSettingsStore{settings: map[int]*domain.TenantSettings{1: {TenantID: 1, Currency: "EUR", MaxTransferAmount: 100}}}
+	limit := int64(500)
+	cache := NewTenantSettingsCache(store, time.Hour, func(tenantID int) *domain.TenantSettings {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.MaxTransferAmount = limit
+		return s
+	})
+
+	s, err := cache.Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), s.MaxTransferAmount)
+	s, _ = cache.Get(1)
+	assert.Equal(t, int64(100), s.MaxTransferAmount)
+	assert.Equal(t, 1, store.reads)
+
+	// saving drops the cached settings
+	assert.Nil(t, cache.Save(&domain.TenantSettings{TenantID: 1, Currency: "EUR", MaxTransferAmount: 200}))
+	s, _ = cache.Get(1)
+	assert.Equal(t, int64(200), s.MaxTransferAmount)
+	assert.Equal(t, 2, store.reads)
+
+	// defaults aren't cached, so a config change applies straight away
+	s, _ = cache.Get(2)
+	assert.Equal(t, int64(500), s.MaxTransferAmount)
+	limit = 600
+	s, _ = cache.Get(2)
+	assert.Equal(t, int64(600), s.MaxTransferAmount)
+	assert.Equal(t, 4, store.reads)
+}
+
+func TestTenantSettingsCacheExpires(t *testing.T) {
+	store := &fakeSettingsStore{settings: map[int]*domain.TenantSettings{1: {TenantID: 1, Currency: "EUR"}}}
+	cache := NewTenantSettingsCache(store, time.Nanosecond, domain.DefaultTenantSettings)
+
+	_, err := cache.Get(1)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = cache.Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, store.reads)
+}
+
+func TestHandleTenantSettings(t *testing.T) {
+	store := &fakeSettingsStore{settings: map[int]*domain.TenantSettings{}}
+	s := &APIServer{config: NewLiveConfig(&Config{Runtime: RuntimeConfig{MaxTransferAmount: 5000}})}
+	s.settings = NewTenantSettingsCache(store, time.Minute, s.defaultTenantSettings)
+	call := func(method, body string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(method, "/admin/tenants/2/settings", strings.NewReader(body))
+		r.SetPathValue("id", "2")
+		rec := httptest.NewRecorder()
+		return rec, s.handleTenantSettings(rec, r)
+	}
+
+	// a tenant without its own settings gets the configured limits
+	rec, err := call("GET", "")
+	assert.Nil(t, err)
+	var got domain.TenantSettings
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, int64(5000), got.MaxTransferAmount)
+	assert.Equal(t, "USD", got.Currency)
+
+	_, err = call("PUT", `{"currency": "EURO"}`)
+	assert.NotNil(t, err)
+	assert.Empty(t, store.settings)
+
+	// what the body leaves out keeps its default
+	_, err = call("PUT", `{"currency": "EUR", "brandName": "acme"}`)
+	assert.Nil(t, err)
+	rec, err = call("GET", "")
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, 2, got.TenantID)
+	assert.Equal(t, "EUR", got.Currency)
+	assert.Equal(t, "acme", got.BrandName)
+	assert.Equal(t, int64(5000), got.MaxTransferAmount)
+}
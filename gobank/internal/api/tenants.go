@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"strings"
+)
+
+type CreateTenantRequest struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+type tenantKey struct{}
+
+func tenantFromContext(ctx context.Context) *domain.Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(*domain.Tenant)
+	return tenant
+}
+
+// resolveTenantSlug picks the tenant from the X-Tenant header, falling back to
+// the first label of the host when it is a subdomain of baseDomain.
+func resolveTenantSlug(r *http.Request, baseDomain string) string {
+	if slug := r.Header.Get("X-Tenant"); slug != "" {
+		return strings.ToLower(slug)
+	}
+	host := strings.ToLower(r.Host)
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	if baseDomain != "" && strings.HasSuffix(host, "."+baseDomain) {
+		return strings.TrimSuffix(host, "."+baseDomain)
+	}
+	return domain.DefaultTenantSlug
+}
+
+// withTenant resolves the request's tenant and stores it on the request
+// context. Handlers reach tenant scoped storage through storeFor.
+func (s *APIServer) withTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isOperatorPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant, err := s.store.GetTenantBySlug(resolveTenantSlug(r, s.config.Get().TenantDomain))
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			writeError(w, r, http.StatusNotFound, NewError(CodeUnknownTenant))
+			return
+		}
+		if err != nil {
+			loggerFrom(r.Context()).Error("resolving the tenant failed", "error", err)
+			writeError(w, r, http.StatusInternalServerError, NewError(CodeInternal))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+	})
+}
+
+func (s *APIServer) storeFor(r *http.Request) storage.Storage {
+	if tenant := tenantFromContext(r.Context()); tenant != nil {
+		return s.store.ForTenant(tenant.ID)
+	}
+	return s.store
+}
+
+func (s *APIServer) accountsFor(r *http.Request) *service.AccountService {
+	return service.NewAccountService(s.storeFor(r), s.settings, s.clock)
+}
+
+func (s *APIServer) transfersFor(r *http.Request) *service.TransferService {
+	return service.NewTransferService(s.storeFor(r), s.settings, s.clock, s.screening)
+}
+
+func (s *APIServer) handleTenants(w http.ResponseWriter, r *http.Request) error {
+	if isGet(r) {
+		tenants, err := s.store.ListTenants()
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, tenants)
+	}
+	if r.Method == http.MethodPost {
+		req := new(CreateTenantRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+		tenant, err := domain.NewTenant(req.Slug, req.Name)
+		if err != nil {
+			return err
+		}
+		if err := s.store.CreateTenant(tenant); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusCreated, tenant)
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveTenantSlug(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://acme.gobank.test:3000/account", nil)
+	assert.Equal(t, "acme", resolveTenantSlug(r, "gobank.test"))
+	assert.Equal(t, domain.DefaultTenantSlug, resolveTenantSlug(r, ""))
+
+	r.Header.Set("X-Tenant", "Other")
+	assert.Equal(t, "other", resolveTenantSlug(r, "gobank.test"))
+}
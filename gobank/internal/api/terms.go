@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"sort"
+)
+
+// currentTerms are the document versions accounts have to accept.
+func (s *APIServer) currentTerms() domain.TermsVersions {
+	cfg := s.config.Get().Runtime
+	current := domain.TermsVersions{}
+	if cfg.TermsVersion != "" {
+		current[domain.TermsOfService] = cfg.TermsVersion
+	}
+	if cfg.PrivacyVersion != "" {
+		current[domain.PrivacyPolicy] = cfg.PrivacyVersion
+	}
+	return current
+}
+
+func (s *APIServer) handleCurrentTerms(w http.ResponseWriter, r *http.Request) error {
+	return WriteJSON(w, http.StatusOK, s.currentTerms())
+}
+
+func (s *APIServer) handleTermsStatus(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	accepted, err := s.storeFor(r).AcceptedTerms(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, domain.NewTermsStatus(s.currentTerms(), accepted))
+}
+
+// handleAcceptTerms accepts the current version of every document. The body
+// names the versions the holder was shown, so one that changed in the
+// meantime isn't accepted unseen.
+func (s *APIServer) handleAcceptTerms(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	shown := domain.TermsVersions{}
+	if err := json.NewDecoder(r.Body).Decode(&shown); err != nil {
+		return err
+	}
+	current := s.currentTerms()
+	docs := make([]string, 0, len(current))
+	for doc := range current {
+		docs = append(docs, doc)
+	}
+	sort.Strings(docs)
+	now := s.clock.Now().UTC()
+	accepted := make([]*domain.TermsAcceptance, 0, len(docs))
+	for _, doc := range docs {
+		if shown[doc] != current[doc] {
+			return NewError(CodeTermsOutdated, "document", doc, "version", shown[doc])
+		}
+		accepted = append(accepted, &domain.TermsAcceptance{Document: doc, Version: current[doc], AcceptedAt: now})
+	}
+	store := s.storeFor(r)
+	if err := store.AcceptTerms(id, accepted); err != nil {
+		return err
+	}
+	all, err := store.AcceptedTerms(id)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, domain.NewTermsStatus(current, all))
+}
+
+type callerKey struct{}
+
+// authenticate returns the account behind the request, reusing the one a
+// middleware authenticated already. A signed request can only be verified
+// once, its nonce is used up.
+func (s *APIServer) authenticate(r *http.Request) (*domain.Account, error) {
+	if account, ok := r.Context().Value(callerKey{}).(*domain.Account); ok {
+		return account, nil
+	}
+	account, err := auth.Authenticate(r, s.storeFor(r), tenantFromContext(r.Context()), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.checkConsent(r, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// withTermsAccepted keeps accounts that haven't accepted the current terms
+// from moving money.
+func (s *APIServer) withTermsAccepted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		account, err := s.authenticate(r)
+		if err != nil {
+			authFailed(w, r, err)
+			return
+		}
+		setAccountLanguage(r, account.Language)
+		if current := s.currentTerms(); len(current) > 0 {
+			accepted, err := s.storeFor(r).AcceptedTerms(account.ID)
+			if err == nil {
+				err = domain.NewTermsStatus(current, accepted).Check()
+			}
+			if errors.Is(err, domain.ErrTermsNotAccepted) {
+				writeError(w, r, http.StatusForbidden, err)
+				return
+			}
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, NewError(CodeInternal))
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, account)))
+	})
+}
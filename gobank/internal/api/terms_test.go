@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeTermsStore struct {
+	storage.Storage
+	accepted []*domain.TermsAcceptance
+}
+
+func (f *fakeTermsStore) AcceptedTerms(accountID int) ([]*domain.TermsAcceptance, error) {
+	return f.accepted, nil
+}
+
+func TestWithTermsAccepted(t *testing.T) {
+	store := &fakeTermsStore{}
+	cfg := &Config{Runtime: RuntimeConfig{TermsVersion: "2", PrivacyVersion: "1"}}
+	s := &APIServer{store: store, config: NewLiveConfig(cfg)}
+	account := &domain.Account{ID: 7}
+	var caller *domain.Account
+	h := s.withTermsAccepted(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ = s.authenticate(r)
+	}))
+	call := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/transfer", nil)
+		r = r.WithContext(context.WithValue(r.Context(), callerKey{}, account))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	store.accepted = []*domain.TermsAcceptance{{Document: domain.TermsOfService, Version: "1"}, {Document: domain.PrivacyPolicy, Version: "1"}}
+	w := call()
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), CodeTermsNotAccepted)
+	assert.Contains(t, w.Body.String(), "terms must be accepted")
+
+	store.accepted = append(store.accepted, &domain.TermsAcceptance{Document: domain.TermsOfService, Version: "2"})
+	assert.Equal(t, http.StatusOK, call().Code)
+	assert.Equal(t, account, caller)
+}
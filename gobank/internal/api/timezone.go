@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"net/http"
+	"time"
+)
+
+// loadLocation validates an IANA time zone name. The tz database is embedded
+// so this works in minimal containers too.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, NewError(CodeUnknownTimeZone, "zone", name)
+	}
+	return loc, nil
+}
+
+// locationFor picks the time zone used for day and month boundaries: the tz
+// query parameter, then the account's preferred zone, then UTC.
+func locationFor(r *http.Request, account *domain.Account) (*time.Location, error) {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		return loadLocation(tz)
+	}
+	return loadLocation(account.Timezone)
+}
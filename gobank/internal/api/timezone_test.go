@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStartOfDay(t *testing.T) {
+	loc, err := loadLocation("America/New_York")
+	assert.Nil(t, err)
+	// 02:30 UTC is still the previous evening in New York
+	day := domain.StartOfDay(time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC), loc)
+	assert.Equal(t, time.Date(2024, 3, 9, 5, 0, 0, 0, time.UTC), day.UTC())
+
+	// and still February in Los Angeles
+	la, _ := loadLocation("America/Los_Angeles")
+	month := domain.StartOfMonth(time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC), la)
+	assert.Equal(t, time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC), month.UTC())
+
+	_, err = loadLocation("Mars/Olympus")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+)
+
+type LoginResponse struct {
+	ID     int                  `json:"id"`
+	UUID   string               `json:"uuid"`
+	Number domain.AccountNumber `json:"number"`
+	Token  domain.Secret        `json:"token"`
+}
+
+type LoginRequest struct {
+	Number   domain.AccountNumber `json:"number"`
+	Password domain.Secret        `json:"password"`
+}
+
+// TransferAccount is a transfer to make, either spelled out or as the id of
+// a quote from POST /transfer/quote. The recipient is ToAccount, ToIBAN or
+// ToAlias, the email address or phone number of an account of the tenant.
+type TransferAccount struct {
+	ToAccount domain.AccountNumber `json:"toAccount"`
+	ToIBAN    string               `json:"toIban,omitempty"`
+	ToAlias   domain.PII           `json:"toAlias,omitempty"`
+	Amount    domain.Money         `json:"amount"`
+	QuoteID   string               `json:"quoteId,omitempty"`
+	// AllowDuplicate confirms a transfer refused with duplicate_transfer.
+	AllowDuplicate bool `json:"allowDuplicate,omitempty"`
+}
+
+// UpdateAccountRequest is a PATCH body, fields left out stay unchanged.
+type UpdateAccountRequest struct {
+	FirstName   *domain.PII     `json:"firstName"`
+	LastName    *domain.PII     `json:"lastName"`
+	Email       *domain.PII     `json:"email"`
+	Phone       *domain.PII     `json:"phone"`
+	Nickname    *string         `json:"nickname"`
+	Address     *domain.Address `json:"address"`
+	DateOfBirth *domain.PII     `json:"dateOfBirth"`
+	Timezone    *string         `json:"timezone"`
+	Language    *string         `json:"language"`
+	Locale      *string         `json:"locale"`
+	SMSAlerts   *bool           `json:"smsAlerts"`
+}
+
+type CreateAccountRequest struct {
+	FirstName   domain.PII      `json:"firstName"`
+	LastName    domain.PII      `json:"lastName"`
+	Email       domain.PII      `json:"email"`
+	Phone       domain.PII      `json:"phone"`
+	Nickname    string          `json:"nickname"`
+	Address     *domain.Address `json:"address"`
+	DateOfBirth domain.PII      `json:"dateOfBirth"`
+	Timezone    string          `json:"timezone"`
+	Language    string          `json:"language"`
+	Locale      string          `json:"locale"`
+	Password    domain.Secret   `json:"password"`
+}
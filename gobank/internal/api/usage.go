@@ -0,0 +1,72 @@
+package api
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type UsageReport struct {
+	RateLimitPerMinute int                  `json:"rateLimitPerMinute"`
+	DailyQuota         int                  `json:"dailyQuota"`
+	Days               []*domain.DailyUsage `json:"days"`
+}
+
+// flushUsage saves the rate limiter's account counts every interval, and once
+// more when stop is closed. Every instance adds its own share, so the totals
+// stay right behind a load balancer.
+func flushUsage(store storage.UsageStore, limiter *RateLimiter, interval time.Duration, logger *slog.Logger, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			saveUsage(store, limiter, logger)
+			return
+		case <-ticker.C:
+			saveUsage(store, limiter, logger)
+		}
+	}
+}
+
+func saveUsage(store storage.UsageStore, limiter *RateLimiter, logger *slog.Logger) {
+	usage := limiter.DrainUsage()
+	if len(usage) == 0 {
+		return
+	}
+	if err := store.RecordUsage(usage); err != nil {
+		logger.Error("saving api usage failed", "error", err)
+		limiter.restoreUsage(usage)
+	}
+}
+
+// handleUsage serves GET /account/{id}/usage?days=30 with the account's calls
+// per UTC day. Counts are flushed every minute, so today's lags behind a bit.
+func (s *APIServer) handleUsage(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	account, err := store.GetAccountById(id)
+	if err != nil {
+		return err
+	}
+	days, err := QueryInt(r, "days", 30, 1, 366)
+	if err != nil {
+		return err
+	}
+	since := s.clock.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	usage, err := store.GetUsage(account.Number, since)
+	if err != nil {
+		return err
+	}
+	runtime := s.config.Get().Runtime
+	return WriteJSON(w, http.StatusOK, UsageReport{
+		RateLimitPerMinute: runtime.RateLimitPerMinute,
+		DailyQuota:         runtime.DailyQuota,
+		Days:               usage,
+	})
+}
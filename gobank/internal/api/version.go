@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, see MakeFile:
+//
+//	go build -ldflags "-X github.com/iamuditg/internal/api.version=v1.2.3 -X github.com/iamuditg/internal/api.commit=abc123" ./cmd/gobank
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Mode      string `json:"mode"`
+}
+
+// buildVersion falls back to the VCS stamp go build embeds when the binary
+// was built without ldflags.
+func buildVersion() VersionInfo {
+	info := VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+func (s *APIServer) handleVersion(w http.ResponseWriter, r *http.Request) error {
+	return WriteJSON(w, http.StatusOK, s.version)
+}
+
+func (s *APIServer) withVersionHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Gobank-Version", s.version.Version)
+		next.ServeHTTP(w, r)
+	})
+}
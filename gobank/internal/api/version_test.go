@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	defer func(v, c, d string) { version, commit, buildDate = v, c, d }(version, commit, buildDate)
+	version, commit, buildDate = "v1.2.3", "abc123", "2024-06-01T00:00:00Z"
+
+	s := NewAPIServer(NewLiveConfig(&Config{Mode: ModeSandbox}), &fakeAccountStore{}, domain.NewSimClock(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, NewMetrics(), nil, nil, nil, nil, nil, nil)
+	routes := s.routes()
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := serve("/version")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var info VersionInfo
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, VersionInfo{Version: "v1.2.3", Commit: "abc123", BuildDate: "2024-06-01T00:00:00Z", GoVersion: runtime.Version(), Mode: ModeSandbox}, info)
+	assert.Equal(t, "v1.2.3", rec.Header().Get("X-Gobank-Version"))
+
+	// every response says what answered it, errors included
+	rec = serve("/account/1")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "v1.2.3", rec.Header().Get("X-Gobank-Version"))
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// WarehouseExportJobType is the hourly job that writes the accounts and
+// transactions changed since its last run as Parquet files, partitioned by
+// date the way Athena and BigQuery external tables expect:
+//
+//	transactions/date=2024-05-01/part-<first id>.parquet
+//	accounts/snapshot_date=2024-05-01/part-<first update>-<first id>.parquet
+//
+// An account file holds the state of the accounts that changed, so the
+// latest row of an id across the snapshots is its current state. None of
+// the columns hold personal data.
+const WarehouseExportJobType = "export_warehouse"
+
+const (
+	// warehouseLag keeps rows younger than it for the next run, so a
+	// transaction committing late with a lower id isn't skipped.
+	warehouseLag = 5 * time.Minute
+	// warehousePage is how many rows go in a file at most.
+	warehousePage = 50_000
+)
+
+type WarehouseExporter struct {
+	store   storage.Storage
+	blobs   storage.PartitionedBlobStore
+	clock   domain.Clock
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+func NewWarehouseExporter(store storage.Storage, blobs storage.PartitionedBlobStore, clock domain.Clock, metrics *Metrics, logger *slog.Logger) *WarehouseExporter {
+	metrics.Help("warehouse_rows_exported_total", "Rows written to the warehouse export by dataset.")
+	return &WarehouseExporter{store: store, blobs: blobs, clock: clock, metrics: metrics, logger: logger}
+}
+
+// HandleJob exports what changed up to warehouseLag ago. The watermark of a
+// dataset moves after each file, so a failed run picks up where it stopped
+// and rewrites at most the file it was writing.
+func (e *WarehouseExporter) HandleJob(job *domain.Job) error {
+	cutoff := e.clock.Now().UTC().Add(-warehouseLag)
+	if err := e.exportTransactions(cutoff); err != nil {
+		return err
+	}
+	return e.exportAccounts(cutoff)
+}
+
+func (e *WarehouseExporter) exportTransactions(cutoff time.Time) error {
+	w, err := e.store.WarehouseWatermark(domain.WarehouseTransactions)
+	if err != nil {
+		return err
+	}
+	for {
+		txs, err := e.store.TransactionsAfterID(w.ID, warehousePage)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for n < len(txs) && txs[n].CreatedAt.Before(cutoff) {
+			n++
+		}
+		if n == 0 {
+			return nil
+		}
+		byDate := map[string][]*domain.Transaction{}
+		for _, t := range txs[:n] {
+			date := t.CreatedAt.UTC().Format("2006-01-02")
+			byDate[date] = append(byDate[date], t)
+		}
+		dates := make([]string, 0, len(byDate))
+		for date := range byDate {
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+		for _, date := range dates {
+			rows := byDate[date]
+			if err := e.write("transactions/date="+date, fmt.Sprintf("part-%d.parquet", rows[0].ID), transactionsParquet(rows)); err != nil {
+				return err
+			}
+		}
+		w.ID, w.UpdatedAt = txs[n-1].ID, e.clock.Now()
+		if err := e.store.SaveWarehouseWatermark(w); err != nil {
+			return err
+		}
+		e.metrics.Add("warehouse_rows_exported_total", float64(n), "dataset", domain.WarehouseTransactions)
+		e.logger.Info("warehouse transactions exported", "rows", n, "files", len(byDate), "last_id", w.ID)
+		if n < len(txs) || len(txs) < warehousePage {
+			return nil
+		}
+	}
+}
+
+func (e *WarehouseExporter) exportAccounts(cutoff time.Time) error {
+	w, err := e.store.WarehouseWatermark(domain.WarehouseAccounts)
+	if err != nil {
+		return err
+	}
+	partition := "accounts/snapshot_date=" + cutoff.Format("2006-01-02")
+	for {
+		accounts, err := e.store.AccountsChangedAfter(w, cutoff, warehousePage)
+		if err != nil {
+			return err
+		}
+		if len(accounts) == 0 {
+			return nil
+		}
+		first := accounts[0]
+		if err := e.write(partition, fmt.Sprintf("part-%d-%d.parquet", first.UpdatedAt.UnixMicro(), first.ID), accountsParquet(accounts)); err != nil {
+			return err
+		}
+		last := accounts[len(accounts)-1]
+		w.Time, w.ID, w.UpdatedAt = last.UpdatedAt, last.ID, e.clock.Now()
+		if err := e.store.SaveWarehouseWatermark(w); err != nil {
+			return err
+		}
+		e.metrics.Add("warehouse_rows_exported_total", float64(len(accounts)), "dataset", domain.WarehouseAccounts)
+		e.logger.Info("warehouse accounts exported", "rows", len(accounts), "partition", partition)
+		if len(accounts) < warehousePage {
+			return nil
+		}
+	}
+}
+
+func (e *WarehouseExporter) write(partition, name string, f *parquetFile) error {
+	blobs, err := e.blobs.Partition(partition)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return err
+	}
+	return blobs.Put(name, &buf)
+}
+
+func transactionsParquet(txs []*domain.Transaction) *parquetFile {
+	f := new(parquetFile)
+	id, account, tenant := f.Int64("id"), f.Int64("account_id"), f.Int64("tenant_id")
+	typ, amount, currency, created := f.String("type"), f.Int64("amount"), f.String("currency"), f.Timestamp("created_at")
+	for _, t := range txs {
+		id.AppendInt64(int64(t.ID))
+		account.AppendInt64(int64(t.AccountID))
+		tenant.AppendInt64(int64(t.TenantID))
+		typ.AppendString(t.Type)
+		amount.AppendInt64(t.Amount.MinorUnits)
+		currency.AppendString(t.Amount.Currency)
+		created.AppendInt64(t.CreatedAt.UnixMicro())
+	}
+	return f
+}
+
+func accountsParquet(accounts []*domain.Account) *parquetFile {
+	f := new(parquetFile)
+	id, uuid, tenant := f.Int64("id"), f.String("uuid"), f.Int64("tenant_id")
+	balance, currency := f.Int64("balance"), f.String("currency")
+	timezone, language, version := f.String("timezone"), f.String("language"), f.Int64("version")
+	created, updated := f.Timestamp("created_at"), f.Timestamp("updated_at")
+	for _, a := range accounts {
+		id.AppendInt64(int64(a.ID))
+		uuid.AppendString(a.UUID)
+		tenant.AppendInt64(int64(a.TenantID))
+		balance.AppendInt64(a.Balance.MinorUnits)
+		currency.AppendString(a.Balance.Currency)
+		timezone.AppendString(a.Timezone)
+		language.AppendString(a.Language)
+		version.AppendInt64(int64(a.Version))
+		created.AppendInt64(a.CreatedAt.UnixMicro())
+		updated.AppendInt64(a.UpdatedAt.UnixMicro())
+	}
+	return f
+}
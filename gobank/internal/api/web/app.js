@@ -0,0 +1,100 @@
+// A small demo client for the gobank API. The session lives in
+// sessionStorage so a reload keeps you logged in.
+const $ = (id) => document.getElementById(id);
+let session = JSON.parse(sessionStorage.getItem("gobank") || "null");
+
+async function api(path, options = {}) {
+  const headers = { "Content-Type": "application/json", ...(options.headers || {}) };
+  if (session) headers["x-jwt-token"] = session.token;
+  const res = await fetch(path, { ...options, headers });
+  const body = await res.json().catch(() => ({}));
+  if (!res.ok) throw Object.assign(new Error(body.error || res.statusText), { code: body.code });
+  return body;
+}
+
+function show(message, isError = false) {
+  $("message").textContent = message;
+  $("message").className = isError ? "error" : "";
+}
+
+function formatMoney(money) {
+  return new Intl.NumberFormat(undefined, { style: "currency", currency: money.currency }).format(money.amount);
+}
+
+// sessions from before accounts had UUIDs only carry the serial id
+const accountPath = () => `/account/${session.uuid || session.id}`;
+
+async function loadAccount() {
+  const account = await api(accountPath());
+  $("account-name").textContent = `${account.firstName} ${account.lastName}`;
+  $("account-number").textContent = account.number;
+  $("account-balance").textContent = formatMoney(account.balance);
+
+  const page = await api(`${accountPath()}/transactions?limit=20`);
+  $("transactions").replaceChildren(...page.items.map((t) => {
+    const row = document.createElement("tr");
+    for (const [text, cls] of [
+      [new Date(t.createdAt).toLocaleString(), ""],
+      [t.description || (t.type === "transfer_out" ? `To ${t.counterparty}` : `From ${t.counterparty}`), ""],
+      [formatMoney(t.amount), "num"],
+    ]) {
+      const cell = document.createElement("td");
+      cell.textContent = text;
+      cell.className = cls;
+      row.append(cell);
+    }
+    return row;
+  }));
+}
+
+function render() {
+  $("login-screen").hidden = !!session;
+  $("account-screen").hidden = !session;
+  $("logout").hidden = !session;
+  if (session) loadAccount().catch((err) => show(err.message, true));
+}
+
+$("login-form").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  try {
+    session = await api("/login", {
+      method: "POST",
+      body: JSON.stringify({ number: Number(form.get("number")), password: form.get("password") }),
+    });
+    sessionStorage.setItem("gobank", JSON.stringify(session));
+    show("");
+    render();
+  } catch (err) {
+    show(err.message, true);
+  }
+});
+
+$("transfer-form").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  const transfer = { toAccount: Number(form.get("toAccount")), amount: form.get("amount") };
+  try {
+    try {
+      await api("/transfer", { method: "POST", body: JSON.stringify(transfer) });
+    } catch (err) {
+      // the same transfer went out moments ago, only send it again if meant
+      if (err.code !== "duplicate_transfer" || !confirm(`${err.message}\n\nSend it again?`)) throw err;
+      await api("/transfer", { method: "POST", body: JSON.stringify({ ...transfer, allowDuplicate: true }) });
+    }
+    e.target.reset();
+    show("Transfer sent.");
+    await loadAccount();
+  } catch (err) {
+    show(err.message, true);
+  }
+});
+
+$("logout").addEventListener("click", () => {
+  session = null;
+  sessionStorage.removeItem("gobank");
+  show("");
+  render();
+});
+
+render();
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url"`
+}
+
+// handleWebhooks serves /account/{id}/webhooks. Like an API key's, the
+// signing secret is only part of the response that creates the endpoint.
+// Endpoints are https URLs, outside sandbox mode, of public hosts.
+func (s *APIServer) handleWebhooks(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	store := s.storeFor(r)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		endpoints, err := store.ListWebhooks(id)
+		if err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusOK, endpoints)
+	case http.MethodPost:
+		req := new(CreateWebhookRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return err
+		}
+		if !webhookURLAllowed(req.URL, s.config.Get().Sandbox()) {
+			return invalidParameter("url", req.URL)
+		}
+		ep, err := domain.NewWebhookEndpoint(id, req.URL)
+		if err != nil {
+			return err
+		}
+		if err := store.CreateWebhook(ep); err != nil {
+			return err
+		}
+		return WriteJSON(w, http.StatusCreated, ep)
+	}
+	return NewError(CodeMethodNotAllowed, "method", r.Method)
+}
+
+// webhookURLAllowed reports whether raw can be an endpoint. The deliverer
+// checks the address it dials as well, this only turns away what's bound to
+// fail there.
+func webhookURLAllowed(raw string, sandbox bool) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if sandbox {
+		return u.Scheme == "https" || u.Scheme == "http"
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !publicIP(ip) || strings.EqualFold(u.Hostname(), "localhost") {
+		return false
+	}
+	return u.Scheme == "https"
+}
+
+func (s *APIServer) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	webhookID := r.PathValue("webhookId")
+	if err := s.storeFor(r).DeleteWebhook(id, webhookID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"deleted": webhookID})
+}
+
+// handleEnableWebhook turns an endpoint disabled after failing for too long
+// back on. Deliveries it missed meanwhile can be sent again with redeliver.
+func (s *APIServer) handleEnableWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	webhookID := r.PathValue("webhookId")
+	if err := s.storeFor(r).EnableWebhook(id, webhookID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, map[string]string{"enabled": webhookID})
+}
+
+func (s *APIServer) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deliveries, err := s.storeFor(r).ListDeliveries(id, r.PathValue("webhookId"), 100)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// handleRedeliverWebhook sends a delivery again, whether or not it
+// succeeded before. It answers once the delivery is queued.
+func (s *APIServer) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := PathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deliveryID := r.PathValue("deliveryId")
+	if _, err := s.storeFor(r).RedeliverWebhook(id, r.PathValue("webhookId"), deliveryID); err != nil {
+		return err
+	}
+	return WriteJSON(w, http.StatusAccepted, map[string]string{"queued": deliveryID})
+}
+
+// WebhookDispatcher turns the events on the bus into deliveries for the
+// endpoints of their accounts.
+type WebhookDispatcher struct {
+	store  storage.WebhookStore
+	logger *slog.Logger
+}
+
+func NewWebhookDispatcher(store storage.WebhookStore, logger *slog.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{store: store, logger: logger}
+}
+
+// Dispatch is subscribed to the bus. The outbox relays at least once, the
+// store keeps a single delivery per endpoint and event.
+func (d *WebhookDispatcher) Dispatch(ev *domain.Event) {
+	payload, err := json.Marshal(ev)
+	if err == nil {
+		_, err = d.store.QueueWebhookDeliveries(ev, payload)
+	}
+	if err != nil {
+		d.logger.Error("queueing webhook deliveries failed", "event_id", ev.ID, "error", err)
+	}
+}
+
+// webhookTimeout bounds a delivery request, receivers should queue the
+// event and answer right away.
+const webhookTimeout = 10 * time.Second
+
+// WebhookDeliverer posts deliveries to their endpoints. Each request carries
+// the delivery id in Webhook-Id, which stays the same across retries, and a
+// Webhook-Signature of "t=<unix time>,v1=<hex HMAC-SHA256 of the time, a dot
+// and the body>" under the endpoint's secret. A redirect fails the attempt.
+type WebhookDeliverer struct {
+	store   storage.WebhookStore
+	client  *http.Client
+	metrics *Metrics
+	logger  *slog.Logger
+}
+
+// NewWebhookDeliverer returns a deliverer that only connects to public
+// addresses, unless private is set, as it is in sandbox mode.
+func NewWebhookDeliverer(store storage.WebhookStore, private bool, metrics *Metrics, logger *slog.Logger) *WebhookDeliverer {
+	metrics.Help("webhook_deliveries_total", "Webhook delivery attempts by result.")
+	metrics.Help("webhook_delivery_duration_seconds", "Latency of webhook delivery attempts.")
+	metrics.Help("webhook_endpoints_disabled_total", "Webhook endpoints disabled after failing for too long.")
+	return &WebhookDeliverer{store: store, client: webhookClient(private), metrics: metrics, logger: logger}
+}
+
+// webhookClient doesn't follow redirects. Any account can add an endpoint,
+// so unless private is set the address it dials, whatever the host name
+// resolved to, must be public, and it doesn't go through a proxy that would
+// dial for it.
+func webhookClient(private bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !private {
+		dialer := &net.Dialer{
+			Timeout: webhookTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+					return fmt.Errorf("webhook address %s isn't public", host)
+				}
+				return nil
+			},
+		}
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private too.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is reachable on the internet rather than a
+// loopback, private, link-local or otherwise special address.
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// HandleJob makes one attempt of a delivery. A failed attempt fails the job,
+// so the pool retries it with backoff, unless it disabled the endpoint.
+func (wd *WebhookDeliverer) HandleJob(job *domain.Job) error {
+	var payload storage.WebhookJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	delivery, ep, err := wd.store.GetDelivery(payload.DeliveryID)
+	if errors.Is(err, domain.ErrDeliveryNotFound) {
+		// the endpoint was deleted
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status == domain.DeliverySucceeded || ep.Status != domain.WebhookActive {
+		return nil
+	}
+	attempt := wd.post(ep, delivery, time.Now().UTC())
+	disabled, err := wd.store.RecordWebhookAttempt(delivery.ID, attempt)
+	if err != nil {
+		return err
+	}
+	result := domain.DeliverySucceeded
+	if !attempt.Succeeded() {
+		result = domain.DeliveryFailed
+	}
+	wd.metrics.Inc("webhook_deliveries_total", "result", result)
+	wd.metrics.Observe("webhook_delivery_duration_seconds", time.Duration(attempt.DurationMs)*time.Millisecond)
+	if disabled {
+		wd.metrics.Inc("webhook_endpoints_disabled_total")
+		wd.logger.Warn("webhook endpoint disabled", "endpoint_id", ep.ID, "tenant_id", ep.TenantID, "account_id", ep.AccountID)
+		return nil
+	}
+	if !attempt.Succeeded() {
+		return fmt.Errorf("webhook delivery %s failed: %s", delivery.ID, attempt.Error)
+	}
+	return nil
+}
+
+func (wd *WebhookDeliverer) post(ep *domain.WebhookEndpoint, delivery *domain.WebhookDelivery, now time.Time) domain.WebhookAttempt {
+	attempt := domain.WebhookAttempt{At: now}
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Webhook-Id", delivery.ID)
+	req.Header.Set("Webhook-Event", delivery.EventType)
+	req.Header.Set("Webhook-Signature", "t="+timestamp+",v1="+signWebhook(ep.Secret.Reveal(), timestamp, delivery.Payload))
+	res, err := wd.client.Do(req)
+	attempt.DurationMs = time.Since(now).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	res.Body.Close()
+	attempt.StatusCode = res.StatusCode
+	if !attempt.Succeeded() {
+		attempt.Error = res.Status
+	}
+	return attempt
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
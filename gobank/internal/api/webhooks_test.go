@@ -0,0 +1,107 @@
+package api
+
+import (
+	"github.com/iamuditg/client"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeWebhookStore struct {
+	storage.WebhookStore
+	delivery *domain.WebhookDelivery
+	endpoint *domain.WebhookEndpoint
+	attempts []domain.WebhookAttempt
+	disable  bool
+}
+
+func (f *fakeWebhookStore) GetDelivery(id string) (*domain.WebhookDelivery, *domain.WebhookEndpoint, error) {
+	return f.delivery, f.endpoint, nil
+}
+
+func (f *fakeWebhookStore) RecordWebhookAttempt(deliveryID string, a domain.WebhookAttempt) (bool, error) {
+	f.attempts = append(f.attempts, a)
+	return f.disable && !a.Succeeded(), nil
+}
+
+func TestWebhookDeliverer(t *testing.T) {
+	status := http.StatusNoContent
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	ep, err := domain.NewWebhookEndpoint(1, srv.URL)
+	assert.NoError(t, err)
+	store := &fakeWebhookStore{
+		endpoint: ep,
+		delivery: &domain.WebhookDelivery{ID: "d1", EventType: domain.EventTransferCompleted, Payload: []byte(`{"id":7}`), Status: domain.DeliveryPending},
+	}
+	metrics := NewMetrics()
+	wd := NewWebhookDeliverer(store, true, metrics, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	job, _ := domain.NewJob(storage.DeliverWebhookJobType, storage.WebhookJob{DeliveryID: "d1"}, time.Now())
+
+	assert.NoError(t, wd.HandleJob(job))
+	assert.Equal(t, `{"id":7}`, string(body))
+	assert.Equal(t, "d1", got.Header.Get("Webhook-Id"))
+	assert.NoError(t, client.VerifyWebhook(ep.Secret.Reveal(), got.Header.Get("Webhook-Signature"), body, time.Minute))
+	assert.Error(t, client.VerifyWebhook("wrong", got.Header.Get("Webhook-Signature"), body, time.Minute))
+
+	// a failure is retried until it disables the endpoint
+	status = http.StatusBadGateway
+	assert.Error(t, wd.HandleJob(job))
+	store.disable = true
+	assert.NoError(t, wd.HandleJob(job))
+	assert.Len(t, store.attempts, 3)
+	assert.Equal(t, http.StatusBadGateway, store.attempts[2].StatusCode)
+
+	// a delivery that went through isn't sent twice
+	store.delivery.Status = domain.DeliverySucceeded
+	assert.NoError(t, wd.HandleJob(job))
+	assert.Len(t, store.attempts, 3)
+}
+
+func TestWebhookURLAllowed(t *testing.T) {
+	assert.True(t, webhookURLAllowed("https://hooks.example.com/gobank", false))
+	for _, raw := range []string{
+		"http://hooks.example.com/gobank",
+		"https://169.254.169.254/latest/meta-data",
+		"https://localhost:8080/",
+		"https://10.0.0.7/",
+		"https://[::1]/",
+		"ftp://hooks.example.com/",
+	} {
+		assert.False(t, webhookURLAllowed(raw, false), raw)
+	}
+	assert.True(t, webhookURLAllowed("http://localhost:8080/", true))
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer srv.Close()
+
+	_, err := webhookClient(false).Post(srv.URL, "application/json", nil)
+	assert.ErrorContains(t, err, "isn't public")
+
+	res, err := webhookClient(true).Post(srv.URL, "application/json", nil)
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+	}
+	assert.False(t, redirected)
+}
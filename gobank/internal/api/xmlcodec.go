@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlCodec writes the JSON value as elements named after the keys, under a
+// <response> root. Anything that isn't a string says so in a type attribute,
+// the way legacy XML clients know it, so bodies survive the round trip:
+//
+//	<response>
+//	  <id type="number">7</id>
+//	  <firstName>Ada</firstName>
+//	  <email nil="true"/>
+//	  <items type="array"><item>a</item></items>
+//	  <totals><entry key="2024-01-31" type="number">12</entry></totals>
+//	</response>
+//
+// Keys that aren't XML names become <entry key="...">. Request bodies may use
+// any root element name.
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := encodeXML(enc, "response", v); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func encodeXML(enc *xml.Encoder, key string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: key}}
+	if !isXMLName(key) {
+		start = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}}
+	}
+	typed := func(typ string) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: typ})
+	}
+	var text string
+	var children func() error
+	switch val := v.(type) {
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	case bool:
+		typed("boolean")
+		text = strconv.FormatBool(val)
+	case json.Number:
+		typed("number")
+		text = val.String()
+	case string:
+		text = val
+	case []any:
+		typed("array")
+		children = func() error {
+			for _, item := range val {
+				if err := encodeXML(enc, "item", item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case map[string]any:
+		if len(val) == 0 {
+			typed("object")
+		}
+		children = func() error {
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if err := encodeXML(enc, k, val[k]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	default:
+		return fmt.Errorf("xml: can't encode %T", v)
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	if children != nil {
+		if err := children(); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// isXMLName reports whether key can be used as an element name as it is.
+func isXMLName(key string) bool {
+	if key == "" || strings.HasPrefix(strings.ToLower(key), "xml") {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && (c == '-' || c == '.' || '0' <= c && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (xmlCodec) Decode(r io.Reader) (any, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return decodeXML(dec, start, 0)
+		}
+	}
+}
+
+func decodeXML(dec *xml.Decoder, start xml.StartElement, depth int) (any, error) {
+	if depth > codecMaxDepth {
+		return nil, errors.New("xml: nested too deeply")
+	}
+	var text strings.Builder
+	var keys []string
+	var values []any
+	for done := false; !done; {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			key := t.Name.Local
+			if k := attrValue(t, "key"); key == "entry" && k != "" {
+				key = k
+			}
+			v, err := decodeXML(dec, t, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			values = append(values, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			done = true
+		}
+	}
+	s := strings.TrimSpace(text.String())
+	typ := attrValue(start, "type")
+	switch {
+	case attrValue(start, "nil") == "true":
+		return nil, nil
+	case typ == "array":
+		if values == nil {
+			values = []any{}
+		}
+		return values, nil
+	case typ == "object" || len(keys) > 0:
+		m := make(map[string]any, len(keys))
+		for i, k := range keys {
+			m[k] = values[i]
+		}
+		return m, nil
+	case typ == "boolean":
+		return strconv.ParseBool(s)
+	case typ == "number":
+		if !jsonNumber.MatchString(s) {
+			return nil, fmt.Errorf("xml: invalid number %q in <%s>", s, start.Name.Local)
+		}
+		return json.Number(s), nil
+	}
+	// a string keeps its whitespace, only nested elements drop it
+	return text.String(), nil
+}
+
+func attrValue(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
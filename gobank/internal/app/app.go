@@ -0,0 +1,361 @@
+// Package app wires the service together: it builds every subsystem once
+// from the config and starts and stops them in dependency order.
+package app
+
+import (
+	"context"
+	"github.com/iamuditg/internal/api"
+	"github.com/iamuditg/internal/auth"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/service"
+	"github.com/iamuditg/internal/storage"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long Run waits for the subsystems to stop.
+const shutdownTimeout = 30 * time.Second
+
+// App holds the wired subsystems. New builds the ones that don't need the
+// database, so CLI commands such as backup run without it, Connect opens the
+// store and Wire builds the rest.
+type App struct {
+	Config     *api.LiveConfig
+	Logger     *slog.Logger
+	Clock      domain.Clock
+	Metrics    *api.Metrics
+	Backups    storage.BlobStore
+	Backuper   *storage.Backuper
+	Recordings storage.BlobStore
+	Statements storage.BlobStore
+	Reports    storage.BlobStore
+	Avatars    storage.BlobStore
+	// Warehouse is nil unless the warehouse export is on.
+	Warehouse storage.PartitionedBlobStore
+	Store     *storage.PostgresStore
+	Reporter  api.ErrorReporter
+	Pool      *api.WorkerPool
+	Server    *api.APIServer
+
+	level     *slog.LevelVar
+	lifecycle *Lifecycle
+}
+
+func New(cfg *api.Config, out io.Writer) (*App, error) {
+	level := new(slog.LevelVar)
+	lvl, _ := api.ParseLogLevel(cfg.Runtime.LogLevel)
+	level.Set(lvl)
+	logger, err := api.NewLogger(out, cfg.LogFormat, level)
+	if err != nil {
+		return nil, err
+	}
+	a := &App{
+		Config:    api.NewLiveConfig(cfg),
+		Logger:    logger,
+		Clock:     domain.SystemClock{},
+		Metrics:   api.NewMetrics(),
+		level:     level,
+		lifecycle: NewLifecycle(logger),
+	}
+	if cfg.Sandbox() {
+		a.Clock = domain.NewSimClock()
+	}
+	a.Config.OnReload(func(cfg *api.Config) {
+		lvl, _ := api.ParseLogLevel(cfg.Runtime.LogLevel)
+		a.level.Set(lvl)
+	})
+	if a.Backups, err = storage.NewDirBlobStore(cfg.BackupDir); err != nil {
+		return nil, err
+	}
+	a.Backuper = storage.NewBackuper(os.Getenv("POSTGRES_URL"), a.Backups, cfg.BackupKeep, logger)
+	if a.Recordings, err = storage.NewDirBlobStore(cfg.RecordingDir); err != nil {
+		return nil, err
+	}
+	if a.Statements, err = storage.NewDirBlobStore(cfg.StatementDir); err != nil {
+		return nil, err
+	}
+	if a.Reports, err = storage.NewDirBlobStore(cfg.ReportDir); err != nil {
+		return nil, err
+	}
+	if a.Avatars, err = storage.NewDirBlobStore(cfg.AvatarDir); err != nil {
+		return nil, err
+	}
+	if cfg.WarehouseDir != "" {
+		if a.Warehouse, err = storage.NewDirBlobStore(cfg.WarehouseDir); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Connect opens the store and brings its schema up to date.
+func (a *App) Connect() error {
+	store, err := storage.NewPostgresStore(a.Logger, a.Metrics, a.Clock, func() time.Duration {
+		return time.Duration(a.Config.Get().Runtime.SlowQueryThresholdMs) * time.Millisecond
+	})
+	if err != nil {
+		return err
+	}
+	if err := store.Init(); err != nil {
+		store.Close()
+		return err
+	}
+	if a.Config.Get().AccountEventSourcing {
+		store.EnableAccountEvents()
+	}
+	a.Store = store
+	a.lifecycle.Append(Hook{Name: "store", Stop: func(context.Context) error {
+		return store.Close()
+	}})
+	return a.lifecycle.Start(context.Background())
+}
+
+// Options are the settings of the background work that come from flags
+// rather than the config.
+type Options struct {
+	ArchiveAfterYears int
+}
+
+// Wire builds the workers, publishers and the API server on top of the
+// connected store and registers their lifecycle hooks.
+func (a *App) Wire(opts Options) error {
+	cfg := a.Config.Get()
+	reporter, err := api.NewErrorReporter(cfg.SentryDSN, cfg.Environment, a.Logger)
+	if err != nil {
+		return err
+	}
+	a.Reporter = reporter
+
+	bus, err := api.NewBus(cfg, a.Logger)
+	if err != nil {
+		return err
+	}
+	publishers := api.MultiPublisher{bus}
+	if nats, ok := bus.(*api.NatsBus); ok {
+		a.lifecycle.Append(Hook{Name: "nats", Stop: func(context.Context) error {
+			nats.Close()
+			return nil
+		}})
+	}
+	if len(cfg.KafkaBrokers) > 0 {
+		kafka, err := api.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaFormat)
+		if err != nil {
+			return err
+		}
+		publishers = append(publishers, kafka)
+		a.lifecycle.Append(Hook{Name: "kafka", Stop: func(context.Context) error {
+			return kafka.Close()
+		}})
+	}
+	relay := api.NewOutboxRelay(a.Store, publishers, a.Logger)
+	a.lifecycle.Append(background("outbox relay", func(stop <-chan struct{}) {
+		storage.RunExclusive(a.Store, "outbox-relay", a.Logger, stop, relay.Run)
+	}))
+
+	projector := api.NewProjector(a.Store, a.Metrics, a.Logger)
+	a.lifecycle.Append(background("projector", func(stop <-chan struct{}) {
+		storage.RunExclusive(a.Store, "projector", a.Logger, stop, projector.Run)
+	}))
+
+	a.Pool = api.NewWorkerPool(a.Store, a.Clock, 4, a.Logger)
+	a.Pool.Register(api.ArchiveJobType, api.NewArchiver(a.Store, a.Clock, opts.ArchiveAfterYears, a.Logger).HandleJob)
+	a.Pool.Register(auth.PurgeNoncesJobType, auth.NewNoncePurger(a.Store, a.Logger).HandleJob)
+	a.Pool.Register(api.PurgeQuotesJobType, api.NewQuotePurger(a.Store, a.Clock, a.Logger).HandleJob)
+	a.Pool.Register(api.ReconcileJobType, api.NewReconciler(a.Store, a.Clock, a.Metrics, reporter, a.Logger).HandleJob)
+	a.Pool.Register(api.VerifyLedgerJobType, api.NewLedgerVerifier(a.Store, a.Metrics, reporter, a.Logger).HandleJob)
+	var emails api.EmailSender
+	if cfg.EmailProvider != "" {
+		sender, err := api.NewEmailSender(cfg)
+		if err != nil {
+			return err
+		}
+		emails = sender
+		if err := bus.Subscribe(api.NewEmailNotifier(a.Store, a.Logger).Notify); err != nil {
+			return err
+		}
+		a.Pool.Register(storage.SendEmailJobType, api.NewEmailDeliverer(a.Store, sender, cfg.EmailFrom, a.Metrics, a.Logger).HandleJob)
+	}
+	sms, err := api.NewSMSProvider(cfg, a.Logger)
+	if err != nil {
+		return err
+	}
+	if sms != nil {
+		if err := bus.Subscribe(api.NewSMSAlerter(a.Store, a.Logger).Notify); err != nil {
+			return err
+		}
+		a.Pool.Register(storage.SendSMSJobType, api.NewSMSDeliverer(a.Store, sms, a.Metrics, a.Logger).HandleJob)
+	}
+	if err := bus.Subscribe(api.NewInboxNotifier(a.Store, a.Logger).Notify); err != nil {
+		return err
+	}
+	pushSenders, err := api.NewPushSenders(cfg)
+	if err != nil {
+		return err
+	}
+	if len(pushSenders) > 0 {
+		if err := bus.Subscribe(api.NewPushNotifier(a.Store, a.Logger).Notify); err != nil {
+			return err
+		}
+		a.Pool.Register(storage.SendPushJobType, api.NewPushDeliverer(a.Store, pushSenders, a.Metrics, a.Logger).HandleJob)
+	}
+	a.Pool.Register(api.StatementJobType, api.NewStatementGenerator(a.Store, a.Statements, a.Clock, a.Metrics, a.Logger).HandleJob)
+	statementSinks, err := api.NewStatementSinks(cfg, a.Store, emails)
+	if err != nil {
+		return err
+	}
+	a.Pool.Register(storage.DeliverStatementJobType, api.NewStatementDeliverer(a.Store, statementSinks, a.Clock, a.Metrics, a.Logger).HandleJob)
+	if err := bus.Subscribe(api.NewStatementDispatcher(a.Store, a.Logger).Dispatch); err != nil {
+		return err
+	}
+	a.Pool.Register(api.LargeTransactionJobType, api.NewLargeTransactionReporter(a.Store, a.Reports, a.Config, a.Clock, a.Metrics, a.Logger).HandleJob)
+	a.Pool.Register(storage.BackupJobType, a.Backuper.HandleJob)
+	a.Pool.Register(api.AccountingExportJobType, api.NewAccountingExporter(a.Store, a.Reports, a.Logger).HandleJob)
+	if cfg.AccountEventSourcing {
+		a.Pool.Register(api.AccountReplayJobType, api.NewAccountReplayer(a.Store, a.Metrics, reporter, a.Logger).HandleJob)
+	}
+	if a.Warehouse != nil {
+		a.Pool.Register(api.WarehouseExportJobType, api.NewWarehouseExporter(a.Store, a.Warehouse, a.Clock, a.Metrics, a.Logger).HandleJob)
+	}
+	a.Pool.Register(storage.DeliverWebhookJobType, api.NewWebhookDeliverer(a.Store, cfg.Sandbox(), a.Metrics, a.Logger).HandleJob)
+	if err := bus.Subscribe(api.NewWebhookDispatcher(a.Store, a.Logger).Dispatch); err != nil {
+		return err
+	}
+	a.lifecycle.Append(background("workers", a.Pool.Run))
+	a.lifecycle.Append(background("scheduler", func(stop <-chan struct{}) {
+		storage.RunExclusive(a.Store, "scheduler", a.Logger, stop, a.schedule)
+	}))
+
+	var screening service.ScreeningProvider
+	if cfg.ScreeningDenylistFile != "" {
+		denylist, err := service.LoadDenylist(cfg.ScreeningDenylistFile)
+		if err != nil {
+			return err
+		}
+		screening = denylist
+	}
+	a.Server = api.NewAPIServer(a.Config, a.Store, a.Clock, a.Logger, reporter, a.Metrics, api.NewRecorder(a.Recordings, a.Metrics, a.Logger), a.Statements, a.Reports, a.Avatars, screening, sms)
+	a.Pool.Register(service.ProcessTransferJobType, a.Server.HandleTransferJob)
+	intake, err := api.NewBatchIntake(cfg, a.Server)
+	if err != nil {
+		return err
+	}
+	if intake != nil {
+		a.lifecycle.Append(background("batch intake", func(stop <-chan struct{}) {
+			storage.RunExclusive(a.Store, "batch-intake", a.Logger, stop, intake.Run)
+		}))
+	}
+	a.lifecycle.Append(a.serverHook())
+	a.lifecycle.Append(a.reloadHook())
+	return nil
+}
+
+// schedule enqueues the periodic jobs. Only the instance holding the
+// scheduler lock runs it.
+func (a *App) schedule(stop <-chan struct{}) {
+	go a.Pool.Every(time.Hour, auth.PurgeNoncesJobType, stop)
+	go a.Pool.Every(time.Hour, api.PurgeQuotesJobType, stop)
+	go a.Pool.Every(24*time.Hour, api.ReconcileJobType, stop)
+	go a.Pool.Every(24*time.Hour, api.VerifyLedgerJobType, stop)
+	go a.Pool.Every(time.Hour, api.StatementJobType, stop)
+	go a.Pool.Every(time.Duration(a.Config.Get().Runtime.LargeTransactionWindowHours)*time.Hour, api.LargeTransactionJobType, stop)
+	if hours := a.Config.Get().BackupIntervalHours; hours > 0 {
+		go a.Pool.Every(time.Duration(hours)*time.Hour, storage.BackupJobType, stop)
+	}
+	if a.Warehouse != nil {
+		go a.Pool.Every(time.Hour, api.WarehouseExportJobType, stop)
+	}
+	if a.Config.Get().AccountEventSourcing {
+		go a.Pool.Every(24*time.Hour, api.AccountReplayJobType, stop)
+	}
+	a.Pool.Every(24*time.Hour, api.ArchiveJobType, stop)
+}
+
+// serverHook runs the API server. A server that fails on its own, for
+// example because the port is taken, takes the process down.
+func (a *App) serverHook() Hook {
+	return Hook{
+		Name: "api server",
+		Start: func(context.Context) error {
+			go func() {
+				if err := a.Server.Run(); err != nil {
+					a.Logger.Error("error while running server", "error", err)
+					os.Exit(1)
+				}
+			}()
+			return nil
+		},
+		Stop: a.Server.Shutdown,
+	}
+}
+
+// reloadHook reloads the config on SIGHUP, keeping the current one when the
+// new one doesn't load.
+func (a *App) reloadHook() Hook {
+	hup := make(chan os.Signal, 1)
+	return background("config reload", func(stop <-chan struct{}) {
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-hup:
+			}
+			if _, err := a.Config.Reload(); err != nil {
+				a.Logger.Error("config reload failed, keeping the current config", "error", err)
+				continue
+			}
+			a.Logger.Info("config reloaded")
+		}
+	})
+}
+
+// background is the hook of a subsystem that runs until its stop channel is
+// closed.
+func background(name string, run func(stop <-chan struct{})) Hook {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			go func() {
+				defer close(done)
+				run(stop)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(stop)
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Run starts every subsystem and blocks until SIGINT or SIGTERM, then stops
+// them in reverse order.
+func (a *App) Run() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	a.Logger.Info("shutting down")
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelStop()
+	return a.lifecycle.Stop(stopCtx)
+}
+
+// Close stops whatever was started, for commands that exit without Run.
+func (a *App) Close() error {
+	return a.lifecycle.Stop(context.Background())
+}
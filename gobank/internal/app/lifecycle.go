@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Hook is a subsystem's startup and shutdown. Start must not block, long
+// running work belongs in a goroutine that Stop ends. Either may be nil.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse, so a subsystem is up before anything depending on it starts and
+// still up until those have stopped.
+type Lifecycle struct {
+	hooks   []Hook
+	started int
+	logger  *slog.Logger
+}
+
+func NewLifecycle(logger *slog.Logger) *Lifecycle {
+	return &Lifecycle{logger: logger}
+}
+
+func (l *Lifecycle) Append(h Hook) {
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs the start hooks in order. When one fails the hooks already
+// started are stopped again and its error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for _, h := range l.hooks[l.started:] {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				return errors.Join(fmt.Errorf("starting %s: %w", h.Name, err), l.Stop(ctx))
+			}
+		}
+		l.logger.Debug("started", "subsystem", h.Name)
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started subsystems in reverse order. It
+// keeps going past failures and returns them all.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.Stop == nil {
+			continue
+		}
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", h.Name, err))
+			continue
+		}
+		l.logger.Debug("stopped", "subsystem", h.Name)
+	}
+	return errors.Join(errs...)
+}
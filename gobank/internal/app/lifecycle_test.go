@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestLifecycleOrder(t *testing.T) {
+	var calls []string
+	hook := func(name string, failStart bool) Hook {
+		return Hook{
+			Name: name,
+			Start: func(context.Context) error {
+				calls = append(calls, "start "+name)
+				if failStart {
+					return fmt.Errorf("boom")
+				}
+				return nil
+			},
+			Stop: func(context.Context) error {
+				calls = append(calls, "stop "+name)
+				return nil
+			},
+		}
+	}
+	l := NewLifecycle(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	l.Append(hook("store", false))
+	assert.Nil(t, l.Start(context.Background()))
+	l.Append(hook("workers", false))
+	l.Append(hook("server", false))
+	assert.Nil(t, l.Start(context.Background()))
+	assert.Nil(t, l.Stop(context.Background()))
+	assert.Equal(t, []string{"start store", "start workers", "start server", "stop server", "stop workers", "stop store"}, calls)
+
+	calls = nil
+	l = NewLifecycle(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	l.Append(hook("store", false))
+	l.Append(hook("server", true))
+	l.Append(hook("never", false))
+	err := l.Start(context.Background())
+	assert.EqualError(t, err, "starting server: boom")
+	assert.Equal(t, []string{"start store", "start server", "stop store"}, calls)
+}
@@ -0,0 +1,121 @@
+// Package auth authenticates callers by JWT, signed API key or client
+// certificate.
+package auth
+
+import (
+	"fmt"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"net/http"
+	"os"
+	"time"
+)
+
+// TokenLifetime is how long a login token is good for.
+const TokenLifetime = time.Hour
+
+// CreateJWT issues a login token for the account, good for TokenLifetime
+// from now.
+func CreateJWT(account *domain.Account, now time.Time) (string, error) {
+	claims := &jwt.MapClaims{"exp": now.Add(TokenLifetime).Unix(), "accountNumber": account.Number, "tenantId": account.TenantID}
+
+	secret := os.Getenv("JWT_SECRET")
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(secret))
+}
+
+// CreateImpersonationJWT issues a token for support to read the account of
+// imp with. It ends when the impersonation does, see checkImpersonation.
+func CreateImpersonationJWT(account *domain.Account, imp *domain.Impersonation) (string, error) {
+	claims := &jwt.MapClaims{
+		"accountNumber": account.Number,
+		"tenantId":      account.TenantID,
+		"impersonation": imp.ID,
+		"exp":           imp.ExpiresAt.Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// Authenticate resolves the account behind the request's signed API key
+// headers or, without them, its JWT. tenant is the tenant the request was
+// routed to, if any, tokens and signatures are checked against now.
+func Authenticate(request *http.Request, s storage.Storage, tenant *domain.Tenant, now time.Time) (*domain.Account, error) {
+	if request.Header.Get("X-Api-Key") != "" {
+		return verifySignedRequest(request, s, now)
+	}
+	token, err := ValidateJWT(request.Header.Get("x-jwt-token"), now)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || !tokenMatchesTenant(token, tenant) {
+		return nil, fmt.Errorf("invalid token")
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	number, ok := claims["accountNumber"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid token")
+	}
+	account, err := s.GetAccountByNumber(domain.AccountNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := claims["impersonation"].(string); ok {
+		if err := checkImpersonation(request, s, id, account, now); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+// checkImpersonation only lets safe requests of an active impersonation of
+// the account through, and adds every one of them to the audit trail.
+func checkImpersonation(request *http.Request, s storage.Storage, id string, account *domain.Account, now time.Time) error {
+	imp, err := s.GetImpersonation(id)
+	if err != nil {
+		return err
+	}
+	if !imp.Active(now) || imp.AccountID != account.ID {
+		return fmt.Errorf("impersonation %s is %s", id, imp.Status)
+	}
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return fmt.Errorf("impersonation %s is read-only", id)
+	}
+	return s.RecordImpersonationEvent(imp, domain.EventImpersonationAccess, map[string]any{
+		"method": request.Method,
+		"path":   request.URL.Path,
+	})
+}
+
+// tokenMatchesTenant makes sure a token issued for one tenant can't be used
+// against another tenant's accounts.
+func tokenMatchesTenant(token *jwt.Token, tenant *domain.Tenant) bool {
+	if tenant == nil {
+		return true
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	tenantID, ok := claims["tenantId"].(float64)
+	return ok && int(tenantID) == tenant.ID
+}
+
+// ValidateJWT parses the token and checks its signature, and that it
+// hasn't expired at now. Tokens without an expiry aren't valid.
+func ValidateJWT(tokenString string, now time.Time) (*jwt.Token, error) {
+	secret := os.Getenv("JWT_SECRET")
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := token.Claims.(jwt.MapClaims); !ok || !claims.VerifyExpiresAt(now.Unix(), true) {
+		return nil, fmt.Errorf("token expired")
+	}
+	return token, nil
+}
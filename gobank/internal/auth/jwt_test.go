@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeImpersonationStore struct {
+	storage.Storage
+	account *domain.Account
+	imp     *domain.Impersonation
+	audited []string
+}
+
+func (f *fakeImpersonationStore) GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error) {
+	return f.account, nil
+}
+
+func (f *fakeImpersonationStore) GetImpersonation(id string) (*domain.Impersonation, error) {
+	return f.imp, nil
+}
+
+func (f *fakeImpersonationStore) RecordImpersonationEvent(imp *domain.Impersonation, eventType string, details map[string]any) error {
+	f.audited = append(f.audited, details["method"].(string)+" "+details["path"].(string))
+	return nil
+}
+
+func TestImpersonationTokenIsReadOnly(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test")
+	account := &domain.Account{ID: 7, Number: 123456}
+	clock := domain.NewSimClock()
+	imp := domain.NewImpersonation(7, "support", "ticket", time.Hour, false, clock.Now())
+	store := &fakeImpersonationStore{account: account, imp: imp}
+	token, err := CreateImpersonationJWT(account, imp)
+	assert.NoError(t, err)
+
+	call := func(method, path string) error {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("x-jwt-token", token)
+		_, err := Authenticate(r, store, nil, clock.Now())
+		return err
+	}
+	assert.NoError(t, call("GET", "/account/7/transactions"))
+	assert.Error(t, call("POST", "/transfer"))
+	assert.Equal(t, []string{"GET /account/7/transactions"}, store.audited)
+
+	imp.Status = domain.ImpersonationRevoked
+	assert.Error(t, call("GET", "/account/7"))
+
+	imp.Status = domain.ImpersonationApproved
+	clock.Advance(2 * time.Hour)
+	assert.Error(t, call("GET", "/account/7"))
+}
+
+func TestLoginTokenExpires(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test")
+	clock := domain.NewSimClock()
+	account := &domain.Account{ID: 7, Number: 123456}
+	store := &fakeImpersonationStore{account: account}
+	token, err := CreateJWT(account, clock.Now())
+	assert.NoError(t, err)
+
+	call := func() error {
+		r := httptest.NewRequest("GET", "/account/7", nil)
+		r.Header.Set("x-jwt-token", token)
+		_, err := Authenticate(r, store, nil, clock.Now())
+		return err
+	}
+	assert.NoError(t, call())
+	clock.Advance(TokenLifetime - time.Minute)
+	assert.NoError(t, call())
+	clock.Advance(2 * time.Minute)
+	assert.Error(t, call())
+}
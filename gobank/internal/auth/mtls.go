@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"slices"
+)
+
+const (
+	ClientAuthOff      = "off"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+
+	ScopeAdmin = "admin"
+)
+
+// ServiceAccount is the identity of an internal caller authenticated by its
+// client certificate.
+type ServiceAccount struct {
+	Name   string
+	Scopes []string
+}
+
+func (a *ServiceAccount) HasScope(scope string) bool {
+	return a != nil && slices.Contains(a.Scopes, scope)
+}
+
+// ServiceAccountFor maps a verified client certificate to its service account
+// by full subject DN first, then by common name.
+func ServiceAccountFor(cert *x509.Certificate, accounts map[string][]string) *ServiceAccount {
+	for _, name := range []string{cert.Subject.String(), cert.Subject.CommonName} {
+		if scopes, ok := accounts[name]; ok {
+			return &ServiceAccount{Name: name, Scopes: scopes}
+		}
+	}
+	return nil
+}
+
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// LoadCRL returns the serial numbers revoked by a PEM or DER CRL signed by
+// one of the client CAs.
+func LoadCRL(path string, cas []*x509.Certificate) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	signed := slices.ContainsFunc(cas, func(ca *x509.Certificate) bool { return crl.CheckSignatureFrom(ca) == nil })
+	if !signed {
+		return nil, fmt.Errorf("%s isn't signed by a client CA", path)
+	}
+	revoked := map[string]bool{}
+	for _, entry := range crl.RevokedCertificateEntries {
+		revoked[entry.SerialNumber.String()] = true
+	}
+	return revoked, nil
+}
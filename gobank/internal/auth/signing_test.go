@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/iamuditg/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSigningStore implements the few Storage methods request signing uses.
+type fakeSigningStore struct {
+	storage.Storage
+	key    *domain.ApiKey
+	nonces map[string]bool
+}
+
+func (f *fakeSigningStore) GetApiKey(id string) (*domain.ApiKey, error) {
+	if id != f.key.ID {
+		return nil, fmt.Errorf("api key %s not found", id)
+	}
+	return f.key, nil
+}
+
+func (f *fakeSigningStore) UseNonce(keyID, nonce string, at time.Time) error {
+	if f.nonces[nonce] {
+		return fmt.Errorf("replayed nonce %s", nonce)
+	}
+	f.nonces[nonce] = true
+	return nil
+}
+
+func (f *fakeSigningStore) GetAccountById(id int) (*domain.Account, error) {
+	return &domain.Account{ID: id}, nil
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	store := &fakeSigningStore{key: &domain.ApiKey{ID: "gbk_1", AccountID: 7, Secret: "s3cret"}, nonces: map[string]bool{}}
+	now := time.Unix(1700000000, 0)
+	body := `{"toAccount":1,"amount":"5.00"}`
+
+	signed := func(ts time.Time, nonce, secret string) error {
+		r := httptest.NewRequest("POST", "/transfer?x=1", strings.NewReader(body))
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		r.Header.Set("X-Api-Key", "gbk_1")
+		r.Header.Set("X-Timestamp", timestamp)
+		r.Header.Set("X-Nonce", nonce)
+		r.Header.Set("X-Signature", sign(secret, signaturePayload("POST", "/transfer?x=1", timestamp, nonce, []byte(body))))
+		account, err := verifySignedRequest(r, store, now)
+		if err == nil {
+			assert.Equal(t, 7, account.ID)
+		}
+		return err
+	}
+	assert.Nil(t, signed(now, "n1", "s3cret"))
+	assert.NotNil(t, signed(now, "n1", "s3cret"), "replay")
+	assert.NotNil(t, signed(now, "n2", "wrong"), "bad signature")
+	assert.NotNil(t, signed(now.Add(-10*time.Minute), "n3", "s3cret"), "stale")
+	// a rejected signature doesn't burn the nonce
+	assert.Nil(t, signed(now, "n2", "s3cret"))
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"math/rand"
+	"time"
+)
+
+type Account struct {
+	ID          int           `json:"id"`
+	UUID        string        `json:"uuid"`
+	FirstName   PII           `json:"firstName"`
+	LastName    PII           `json:"lastName"`
+	Email       PII           `json:"email,omitempty"`
+	Phone       PII           `json:"phone,omitempty"`
+	Address     *Address      `json:"address,omitempty"`
+	DateOfBirth PII           `json:"dateOfBirth,omitempty"`
+	Timezone    string        `json:"timezone"`
+	Language    string        `json:"language,omitempty"`
+	Locale      string        `json:"locale,omitempty"`
+	Number      AccountNumber `json:"number"`
+	// IBAN is set for accounts of tenants with IBAN settings.
+	IBAN string `json:"iban,omitempty"`
+	// Nickname is the name the holder chose to be shown to senders, Avatar
+	// the path their picture is served at.
+	Nickname          string    `json:"nickname,omitempty"`
+	Avatar            string    `json:"avatar,omitempty"`
+	EncryptedPassword Secret    `json:"-"`
+	Balance           Money     `json:"balance"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	Version           int       `json:"version"`
+	TenantID          int       `json:"tenantId"`
+	// PhoneVerified is whether the holder confirmed the phone with a code
+	// sent to it, SMSAlerts whether they want their transfers texted there.
+	PhoneVerified bool `json:"phoneVerified"`
+	SMSAlerts     bool `json:"smsAlerts"`
+}
+
+func (a *Account) ValidatePassword(pw Secret) bool {
+	return bcrypt.CompareHashAndPassword([]byte(a.EncryptedPassword.Reveal()), []byte(pw.Reveal())) == nil
+}
+
+func NewAccountNumber() AccountNumber {
+	return AccountNumber(rand.Intn(10000000))
+}
+
+func NewAccount(firstName, lastName PII, password Secret) (*Account, error) {
+	encpw, err := bcrypt.GenerateFromPassword([]byte(password.Reveal()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &Account{
+		FirstName:         firstName,
+		LastName:          lastName,
+		EncryptedPassword: Secret(encpw),
+		Number:            NewAccountNumber(),
+		Balance:           Money{Currency: "USD"},
+		Timezone:          "UTC",
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Version:           1,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The events of an event-sourced account, see storage/account_events.go.
+// Each takes the account to the next version.
+const (
+	// AccountOpened carries the whole account.
+	AccountOpened = "opened"
+	// AccountProfileChanged carries an AccountProfile.
+	AccountProfileChanged = "profile_changed"
+	AccountPhoneVerified  = "phone_verified"
+	// AccountBalanceChanged carries a BalanceChange.
+	AccountBalanceChanged = "balance_changed"
+	// AccountClosed is the last event of a deleted account.
+	AccountClosed = "closed"
+)
+
+// ErrAccountHistoryNotFound means the account has no event stream, because
+// event sourcing is off or the account predates it and hasn't been
+// snapshotted yet, or none that goes back to the time asked for.
+var ErrAccountHistoryNotFound = errors.New("account history not found")
+
+type AccountEvent struct {
+	AccountID int             `json:"accountId"`
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// AccountProfile is what the holder can change of an account.
+type AccountProfile struct {
+	FirstName     PII      `json:"firstName"`
+	LastName      PII      `json:"lastName"`
+	Email         PII      `json:"email,omitempty"`
+	Phone         PII      `json:"phone,omitempty"`
+	Address       *Address `json:"address,omitempty"`
+	DateOfBirth   PII      `json:"dateOfBirth,omitempty"`
+	Timezone      string   `json:"timezone"`
+	Language      string   `json:"language,omitempty"`
+	Locale        string   `json:"locale,omitempty"`
+	Nickname      string   `json:"nickname,omitempty"`
+	Avatar        string   `json:"avatar,omitempty"`
+	PhoneVerified bool     `json:"phoneVerified"`
+	SMSAlerts     bool     `json:"smsAlerts"`
+}
+
+func ProfileOf(a *Account) AccountProfile {
+	return AccountProfile{
+		FirstName:     a.FirstName,
+		LastName:      a.LastName,
+		Email:         a.Email,
+		Phone:         a.Phone,
+		Address:       a.Address,
+		DateOfBirth:   a.DateOfBirth,
+		Timezone:      a.Timezone,
+		Language:      a.Language,
+		Locale:        a.Locale,
+		Nickname:      a.Nickname,
+		Avatar:        a.Avatar,
+		PhoneVerified: a.PhoneVerified,
+		SMSAlerts:     a.SMSAlerts,
+	}
+}
+
+// BalanceChange is the sum of the ledger entries of a change, the one
+// entry's id when there is one.
+type BalanceChange struct {
+	Amount        int64 `json:"amount"`
+	TransactionID int   `json:"transactionId,omitempty"`
+	Entries       int   `json:"entries"`
+}
+
+// AccountStream is an account's latest snapshot, nil if it has none, and
+// the events after it, oldest first.
+type AccountStream struct {
+	Snapshot *Account
+	Events   []*AccountEvent
+}
+
+// Replay folds the events up to and including those created at until, all
+// of them when until is zero, into the snapshot. It returns nil if the
+// account didn't exist by then, or was closed.
+func (s *AccountStream) Replay(until time.Time) (*Account, error) {
+	var account *Account
+	if s.Snapshot != nil {
+		snapshot := *s.Snapshot
+		account = &snapshot
+	}
+	for _, ev := range s.Events {
+		if !until.IsZero() && ev.CreatedAt.After(until) {
+			break
+		}
+		var err error
+		if account, err = account.apply(ev); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+// apply returns the account after ev, nil once it's closed.
+func (a *Account) apply(ev *AccountEvent) (*Account, error) {
+	if ev.Type == AccountOpened {
+		opened := new(Account)
+		if err := json.Unmarshal(ev.Data, opened); err != nil {
+			return nil, err
+		}
+		opened.Version = ev.Version
+		return opened, nil
+	}
+	if a == nil || a.Version+1 != ev.Version {
+		return nil, fmt.Errorf("account %d: %s event of version %d doesn't follow the stream", ev.AccountID, ev.Type, ev.Version)
+	}
+	switch ev.Type {
+	case AccountProfileChanged:
+		var p AccountProfile
+		if err := json.Unmarshal(ev.Data, &p); err != nil {
+			return nil, err
+		}
+		a.FirstName, a.LastName, a.Email, a.Phone, a.Address, a.DateOfBirth = p.FirstName, p.LastName, p.Email, p.Phone, p.Address, p.DateOfBirth
+		a.Timezone, a.Language, a.Locale, a.Nickname, a.Avatar = p.Timezone, p.Language, p.Locale, p.Nickname, p.Avatar
+		a.PhoneVerified, a.SMSAlerts = p.PhoneVerified, p.SMSAlerts
+	case AccountPhoneVerified:
+		a.PhoneVerified = true
+	case AccountBalanceChanged:
+		var c BalanceChange
+		if err := json.Unmarshal(ev.Data, &c); err != nil {
+			return nil, err
+		}
+		a.Balance.MinorUnits += c.Amount
+	case AccountClosed:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("account %d: unknown event %q", ev.AccountID, ev.Type)
+	}
+	a.Version = ev.Version
+	a.UpdatedAt = ev.CreatedAt
+	return a, nil
+}
+
+// SameState reports whether replaying an account's events gave its stored
+// state, all but the password and the update time, which not every change
+// sets from the same clock.
+func SameState(replayed, stored *Account) bool {
+	if replayed == nil || stored == nil {
+		return replayed == stored
+	}
+	a, b := *replayed, *stored
+	a.EncryptedPassword, b.EncryptedPassword = "", ""
+	a.UpdatedAt, b.UpdatedAt = time.Time{}, time.Time{}
+	a.CreatedAt, b.CreatedAt = a.CreatedAt.UTC(), b.CreatedAt.UTC()
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
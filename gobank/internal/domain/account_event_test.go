@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAccountStreamReplay(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	opened := &Account{ID: 7, FirstName: "Ada", Balance: Money{Currency: "EUR"}, Timezone: "UTC", Version: 1, CreatedAt: start, UpdatedAt: start}
+	event := func(version int, typ string, data any) *AccountEvent {
+		raw, _ := json.Marshal(data)
+		return &AccountEvent{AccountID: 7, Version: version, Type: typ, Data: raw, CreatedAt: start.Add(time.Duration(version) * time.Hour)}
+	}
+	profile := ProfileOf(opened)
+	profile.Nickname = "ada"
+	stream := &AccountStream{Events: []*AccountEvent{
+		event(1, AccountOpened, opened),
+		event(2, AccountBalanceChanged, BalanceChange{Amount: 1500, TransactionID: 10, Entries: 1}),
+		event(3, AccountProfileChanged, profile),
+		event(4, AccountBalanceChanged, BalanceChange{Amount: -500, TransactionID: 11, Entries: 1}),
+	}}
+
+	account, err := stream.Replay(time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, Money{MinorUnits: 1000, Currency: "EUR"}, account.Balance)
+	assert.Equal(t, "ada", account.Nickname)
+	assert.Equal(t, 4, account.Version)
+	stored := *opened
+	stored.Balance.MinorUnits, stored.Nickname, stored.Version = 1000, "ada", 4
+	assert.True(t, SameState(account, &stored))
+	stored.Balance.MinorUnits = 999
+	assert.False(t, SameState(account, &stored))
+
+	// as it was before the profile change
+	account, err = stream.Replay(start.Add(150 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), account.Balance.MinorUnits)
+	assert.Equal(t, 2, account.Version)
+	assert.Empty(t, account.Nickname)
+
+	// from a snapshot, without the events before it
+	snapshot, _ := stream.Replay(start.Add(2 * time.Hour))
+	account, err = (&AccountStream{Snapshot: snapshot, Events: stream.Events[2:]}).Replay(time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), account.Balance.MinorUnits)
+
+	stream.Events = append(stream.Events, event(5, AccountClosed, struct{}{}))
+	account, err = stream.Replay(time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, account)
+
+	_, err = (&AccountStream{Snapshot: snapshot, Events: stream.Events[3:]}).Replay(time.Time{})
+	assert.ErrorContains(t, err, "doesn't follow")
+}
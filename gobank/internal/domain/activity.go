@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// The kinds of entries of the account's activity timeline, which its type
+// filter takes.
+const (
+	ActivityLogin       = "login"
+	ActivityProfile     = "profile"
+	ActivityTransaction = "transaction"
+	ActivityHold        = "hold"
+	ActivityAccess      = "access"
+)
+
+var ActivityTypes = []string{ActivityLogin, ActivityProfile, ActivityTransaction, ActivityHold, ActivityAccess}
+
+// activityEvents are the outbox events of each kind of activity. Money
+// movements come from the transactions instead of their events, so incoming
+// transfers, fees and interest are on the timeline too.
+var activityEvents = map[string][]string{
+	ActivityLogin:   {EventAccountLogin},
+	ActivityProfile: {EventAccountCreated, EventAccountUpdated, EventTermsAccepted},
+	ActivityHold:    {EventTransferAuthorized, EventTransferVoided},
+	ActivityAccess: {
+		EventConsentGranted, EventConsentRevoked,
+		EventImpersonationRequested, EventImpersonationApproved, EventImpersonationDenied,
+		EventImpersonationRevoked, EventImpersonationIssued, EventImpersonationAccess,
+	},
+}
+
+// ActivityEventTypes returns the event types of the kinds of activity.
+func ActivityEventTypes(kinds []string) []string {
+	var types []string
+	for _, kind := range kinds {
+		types = append(types, activityEvents[kind]...)
+	}
+	return types
+}
+
+// ActivityOf returns the kind of activity of an event type.
+func ActivityOf(eventType string) string {
+	for kind, types := range activityEvents {
+		if slices.Contains(types, eventType) {
+			return kind
+		}
+	}
+	return ""
+}
+
+// Activity is an entry of the account's timeline: an event, whose type and
+// payload are Event and Details, or a transaction.
+type Activity struct {
+	Type        string          `json:"type"`
+	Event       string          `json:"event,omitempty"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	Transaction *Transaction    `json:"transaction,omitempty"`
+	At          time.Time       `json:"at"`
+	// Source and ID order entries of the same time, transactions before
+	// events.
+	Source int   `json:"-"`
+	ID     int64 `json:"-"`
+}
+
+// ActivityCursor is the sort key of the timeline, the zero value starts at
+// the newest entry.
+type ActivityCursor struct {
+	At     time.Time
+	Source int
+	ID     int64
+}
@@ -0,0 +1,23 @@
+package domain
+
+type DailyTotal struct {
+	Date    string `json:"date"`
+	Credits Money  `json:"credits"`
+	Debits  Money  `json:"debits"`
+}
+
+// MonthlyTotal is what went in and out of an account in a calendar month,
+// YYYY-MM, of its time zone.
+type MonthlyTotal struct {
+	Month        string `json:"month"`
+	Credits      Money  `json:"credits"`
+	Debits       Money  `json:"debits"`
+	Transactions int    `json:"transactions"`
+}
+
+// BalanceTotal sums the ledger balances of a tenant's accounts in a
+// currency.
+type BalanceTotal struct {
+	Accounts int   `json:"accounts"`
+	Total    Money `json:"total"`
+}
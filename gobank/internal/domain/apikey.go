@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// ApiKey lets an integration call the API for an account without a JWT. Its
+// requests must be signed with the secret, see verifySignedRequest. A
+// ThirdParty key only gets at what the holder consented to, see Consent.
+type ApiKey struct {
+	ID         string     `json:"id"`
+	AccountID  int        `json:"accountId"`
+	Name       string     `json:"name"`
+	ThirdParty bool       `json:"thirdParty"`
+	Secret     Secret     `json:"secret,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	TenantID   int        `json:"-"`
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func NewApiKey(accountID int, name string, thirdParty bool) (*ApiKey, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	return &ApiKey{
+		ID:         "gbk_" + id,
+		AccountID:  accountID,
+		Name:       name,
+		ThirdParty: thirdParty,
+		Secret:     Secret(secret),
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
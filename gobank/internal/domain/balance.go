@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPotNotFound = errors.New("pot not found")
+	// ErrFundsProtected is an amount the balance covers, but only by dipping
+	// into the account's minimum balance or its pots.
+	ErrFundsProtected = errors.New("amount exceeds the available balance")
+)
+
+// Availability breaks an account's balance down into what is spoken for and
+// what can be spent. Every check of funds, for transfers, holds and pots,
+// goes through it.
+type Availability struct {
+	Balance Money
+	// Held is reserved by authorized holds, Protected put aside in pots and
+	// Minimum the balance the account has to keep, all in minor units.
+	Held      int64
+	Protected int64
+	Minimum   int64
+}
+
+// Available is the balance less the holds, the pots and the minimum balance.
+func (a Availability) Available() Money {
+	return Money{MinorUnits: a.Balance.MinorUnits - a.Held - a.Protected - a.Minimum, Currency: a.Balance.Currency}
+}
+
+// Check fails with ErrInsufficientFunds when the balance less the holds
+// doesn't cover amount and with ErrFundsProtected when it only does with the
+// minimum balance or the pots.
+func (a Availability) Check(amount int64) error {
+	if a.Balance.MinorUnits-a.Held < amount {
+		return ErrInsufficientFunds
+	}
+	if a.Available().MinorUnits < amount {
+		return ErrFundsProtected
+	}
+	return nil
+}
+
+// Pot is money the holder put aside on the account: it stays in the
+// balance but can't be transferred until it's taken out of the pot.
+type Pot struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Name      string    `json:"name"`
+	Amount    Money     `json:"amount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func NewPot(account *Account, name string, amount int64, now time.Time) *Pot {
+	now = now.UTC()
+	return &Pot{
+		ID:        NewUUID(),
+		AccountID: account.ID,
+		Name:      name,
+		Amount:    Money{MinorUnits: amount, Currency: account.Balance.Currency},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAvailabilityCheck(t *testing.T) {
+	a := Availability{Balance: Money{MinorUnits: 10000, Currency: "EUR"}, Held: 1000, Protected: 2000, Minimum: 3000}
+	assert.Equal(t, int64(4000), a.Available().MinorUnits)
+	assert.Nil(t, a.Check(4000))
+	assert.ErrorIs(t, a.Check(4001), ErrFundsProtected)
+	assert.ErrorIs(t, a.Check(9000), ErrFundsProtected)
+	assert.ErrorIs(t, a.Check(9001), ErrInsufficientFunds)
+}
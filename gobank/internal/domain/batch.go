@@ -0,0 +1,23 @@
+package domain
+
+import "fmt"
+
+// TransferOrder is one transfer of a batch.
+type TransferOrder struct {
+	ToAccount AccountNumber `json:"toAccount"`
+	Amount    Money         `json:"amount"`
+}
+
+// BatchItemError is the transfer at Index of a batch failing with Err.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("transfer %d: %s", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
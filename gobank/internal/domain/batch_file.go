@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// The formats of the payment batch files the intake picks up, by the
+// extension of their names.
+const (
+	BatchFilePain001 = "pain.001"
+	BatchFileCSV     = "csv"
+)
+
+// A batch file is received once it is claimed for ingestion, processed once
+// its acknowledgement is saved and acknowledged once that's written and the
+// file removed. One left received was interrupted.
+const (
+	BatchFileReceived     = "received"
+	BatchFileProcessed    = "processed"
+	BatchFileAcknowledged = "acknowledged"
+)
+
+// BatchFile is a payment batch file the intake picked up from a directory.
+// A file of the same name and content is ingested once per tenant: taken
+// again before it was acknowledged it gets the acknowledgement it was given,
+// sent again after it's rejected as a duplicate.
+type BatchFile struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Format      string     `json:"format"`
+	SHA256      string     `json:"sha256"`
+	Status      string     `json:"status"`
+	Accepted    int        `json:"accepted"`
+	Rejected    int        `json:"rejected"`
+	Ack         []byte     `json:"-"`
+	ReceivedAt  time.Time  `json:"receivedAt"`
+	ProcessedAt *time.Time `json:"processedAt,omitempty"`
+	TenantID    int        `json:"-"`
+}
+
+func NewBatchFile(name, format, sha256 string, now time.Time) *BatchFile {
+	return &BatchFile{
+		ID:         NewUUID(),
+		Name:       name,
+		Format:     format,
+		SHA256:     sha256,
+		Status:     BatchFileReceived,
+		ReceivedAt: now.UTC(),
+	}
+}
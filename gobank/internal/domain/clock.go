@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of business time: ledger timestamps, daily limits,
+// statement ranges and the jobs acting on them. Infrastructure timing such as
+// job leases, rate limits, caches and request signatures stays on the wall
+// clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimClock is the sandbox clock. It runs at wall clock speed shifted by an
+// offset that only ever grows, so ledger timestamps never go backwards. The
+// offset is process local, time travel needs a single instance.
+type SimClock struct {
+	offset atomic.Int64
+}
+
+func NewSimClock() *SimClock {
+	return &SimClock{}
+}
+
+func (c *SimClock) Now() time.Time {
+	return time.Now().Add(c.Offset())
+}
+
+func (c *SimClock) Offset() time.Duration {
+	return time.Duration(c.offset.Load())
+}
+
+func (c *SimClock) Advance(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("the clock can only be advanced")
+	}
+	c.offset.Add(int64(d))
+	return nil
+}
+
+// StartOfDay is midnight of t's day in loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// StartOfMonth is midnight of the first of t's month in loc.
+func StartOfMonth(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSimClockOnlyMovesForward(t *testing.T) {
+	clock := NewSimClock()
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+
+	assert.Nil(t, clock.Advance(48*time.Hour))
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), clock.Now(), time.Second)
+	assert.NotNil(t, clock.Advance(-time.Hour))
+	assert.Equal(t, 48*time.Hour, clock.Offset())
+}
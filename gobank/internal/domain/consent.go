@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The scopes a consent grants a third party.
+const (
+	ScopeAccounts     = "accounts"
+	ScopeBalances     = "balances"
+	ScopeTransactions = "transactions"
+	ScopePayments     = "payments"
+)
+
+var ConsentScopes = []string{ScopeAccounts, ScopeBalances, ScopeTransactions, ScopePayments}
+
+const (
+	ConsentActive  = "active"
+	ConsentRevoked = "revoked"
+	// ConsentExpired is an active consent past its ExpiresAt.
+	ConsentExpired = "expired"
+)
+
+const (
+	EventConsentGranted = "consent.granted"
+	EventConsentRevoked = "consent.revoked"
+)
+
+// MaxConsentValidity is the longest a consent may run, after which the
+// holder has to grant it again.
+const MaxConsentValidity = 180 * 24 * time.Hour
+
+var (
+	ErrConsentNotFound = errors.New("consent not found")
+	ErrConsentRequired = errors.New("no consent for this data")
+)
+
+// Consent lets the third-party API key ApiKeyID use the account for Purpose,
+// limited to Scopes, until it expires or is revoked.
+type Consent struct {
+	ID        string     `json:"id"`
+	AccountID int        `json:"accountId"`
+	ApiKeyID  string     `json:"apiKeyId"`
+	Purpose   string     `json:"purpose"`
+	Scopes    []string   `json:"scopes"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	TenantID  int        `json:"-"`
+}
+
+func IsConsentScope(scope string) bool {
+	for _, s := range ConsentScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether the consent grants scope at now.
+func (c *Consent) Covers(scope string, now time.Time) bool {
+	if c.Status != ConsentActive || !now.Before(c.ExpiresAt) {
+		return false
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsentError is a third-party request without a consent for its scope. It
+// unwraps to ErrConsentRequired.
+type ConsentError struct {
+	Scope string
+}
+
+func (e *ConsentError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrConsentRequired, e.Scope)
+}
+
+func (e *ConsentError) Unwrap() error {
+	return ErrConsentRequired
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email address")
+	ErrInvalidPhone = errors.New("invalid phone number")
+)
+
+// NormalizeEmail trims the address and lower-cases its domain, the local
+// part is case-sensitive in principle and kept as given.
+func NormalizeEmail(email PII) PII {
+	s := strings.TrimSpace(email.Reveal())
+	if at := strings.LastIndexByte(s, '@'); at >= 0 {
+		s = s[:at] + strings.ToLower(s[at:])
+	}
+	return PII(s)
+}
+
+// ValidateEmail checks a bare RFC 5322 addr-spec with a dot-atom local part,
+// no display name, comments or quoting, of at most 254 characters with a
+// local part of at most 64 and a domain of dot-separated labels.
+func ValidateEmail(email PII) error {
+	s := email.Reveal()
+	invalid := &ProfileError{Err: ErrInvalidEmail, Value: s}
+	if len(s) > 254 {
+		return invalid
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return invalid
+	}
+	at := strings.LastIndexByte(s, '@')
+	if at > 64 || !isHostname(s[at+1:]) {
+		return invalid
+	}
+	return nil
+}
+
+// isHostname reports whether host is at least two labels of letters, digits
+// and inner hyphens.
+func isHostname(host string) bool {
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NormalizePhone takes a phone number in international format, a + or 00
+// followed by the country code, to E.164 by dropping the spaces, dots,
+// hyphens and parentheses people write between the digits. A number it
+// can't make sense of comes back trimmed for ValidatePhone to reject.
+func NormalizePhone(phone PII) PII {
+	s := strings.TrimSpace(phone.Reveal())
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '.' || r == '-' || r == '(' || r == ')':
+		default:
+			return PII(s)
+		}
+	}
+	n := b.String()
+	if strings.HasPrefix(n, "00") {
+		n = "+" + n[2:]
+	}
+	return PII(n)
+}
+
+// ValidatePhone checks a number in E.164: a + and at most 15 digits, the
+// first of which starts the country code and isn't 0.
+func ValidatePhone(phone PII) error {
+	s := phone.Reveal()
+	if len(s) < 8 || len(s) > 16 || s[0] != '+' || s[1] == '0' {
+		return &ProfileError{Err: ErrInvalidPhone, Value: s}
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return &ProfileError{Err: ErrInvalidPhone, Value: s}
+		}
+	}
+	return nil
+}
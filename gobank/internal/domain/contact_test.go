@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	email := NormalizeEmail(" Jane.Doe+bank@Example.COM ")
+	assert.Equal(t, PII("Jane.Doe+bank@example.com"), email)
+	assert.Nil(t, ValidateEmail(email))
+	assert.Nil(t, ValidateEmail("o'brien@mail.example.org"))
+
+	for _, bad := range []PII{"jane", "jane@localhost", "Jane <jane@example.com>", "jane@exa_mple.com", "jane@-example.com", "jane@@example.com", `"jane doe"@example.com`} {
+		assert.True(t, errors.Is(ValidateEmail(bad), ErrInvalidEmail), bad)
+	}
+}
+
+func TestValidatePhone(t *testing.T) {
+	assert.Equal(t, PII("+4915123456789"), NormalizePhone(" +49 (151) 234-567.89 "))
+	assert.Equal(t, PII("+4915123456789"), NormalizePhone("0049 151 23456789"))
+	assert.Nil(t, ValidatePhone(NormalizePhone("+1 415 555 0100")))
+
+	for _, bad := range []PII{"015123456789", "+0151234567", "+49", "+4915123456789012", NormalizePhone("+49 151 CALL ME")} {
+		assert.True(t, errors.Is(ValidatePhone(bad), ErrInvalidPhone), bad)
+	}
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// The emails accounts get.
+const (
+	EmailWelcome         = "welcome"
+	EmailTransferReceipt = "transfer_receipt"
+	EmailStatementReady  = "statement_ready"
+)
+
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	// EmailFailed is an email the provider refused for good, EmailSuppressed
+	// one that wasn't sent because its address is on the suppression list.
+	EmailFailed     = "failed"
+	EmailSuppressed = "suppressed"
+)
+
+// Why an address is on the suppression list.
+const (
+	SuppressionBounce    = "bounce"
+	SuppressionComplaint = "complaint"
+	SuppressionManual    = "manual"
+)
+
+// Email is a rendered email waiting in the send queue or sent from it.
+// DedupeKey is unique, so an event relayed twice queues its email once.
+// ProviderID is the id the provider gave the sent message, bounce
+// notifications refer to it.
+type Email struct {
+	ID         string     `json:"id"`
+	TenantID   int        `json:"tenantId"`
+	AccountID  int        `json:"accountId"`
+	Kind       string     `json:"kind"`
+	DedupeKey  string     `json:"-"`
+	To         PII        `json:"to"`
+	Subject    string     `json:"subject"`
+	Text       string     `json:"-"`
+	HTML       string     `json:"-"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"lastError,omitempty"`
+	ProviderID string     `json:"providerId,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	SentAt     *time.Time `json:"sentAt,omitempty"`
+}
+
+func NewEmail(account *Account, kind, dedupeKey, subject, text, html string) *Email {
+	return &Email{
+		ID:        NewUUID(),
+		TenantID:  account.TenantID,
+		AccountID: account.ID,
+		Kind:      kind,
+		DedupeKey: dedupeKey,
+		To:        account.Email,
+		Subject:   subject,
+		Text:      text,
+		HTML:      html,
+		Status:    EmailPending,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// EmailSuppression is an address of a tenant nothing is sent to anymore,
+// because mail to it bounced, its owner complained or an admin put it there.
+type EmailSuppression struct {
+	Address   PII       `json:"address"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,127 @@
+// Package domain holds the bank's entities and errors, independent of HTTP
+// and Postgres. The storage layer returns the errors and the API maps them
+// to codes and statuses with errors.Is and errors.As.
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrApiKeyNotFound  = errors.New("api key not found")
+	ErrTenantNotFound  = errors.New("tenant not found")
+	ErrJobNotFound     = errors.New("dead job not found")
+	ErrIssueNotFound   = errors.New("open reconciliation issue not found")
+	// ErrJobLeaseLost is a worker finishing a job whose lease ran out and
+	// that another worker leased since, or that was finished already.
+	ErrJobLeaseLost = errors.New("job lease lost")
+
+	ErrDuplicateNumber = errors.New("account number already exists")
+	ErrDuplicateEmail  = errors.New("email already exists")
+
+	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+	ErrSameAccount       = errors.New("cannot transfer to the same account")
+	// ErrVersionConflict means the row changed since the caller read it.
+	ErrVersionConflict = errors.New("modified in the meantime")
+	ErrNonceReplayed   = errors.New("replayed nonce")
+
+	ErrTransferLimitExceeded = errors.New("amount exceeds the transfer limit")
+	ErrDailyLimitExceeded    = errors.New("amount exceeds the daily transfer limit")
+	// ErrDuplicateTransfer is a transfer like one the account made moments
+	// ago, most likely sent twice by mistake.
+	ErrDuplicateTransfer = errors.New("possible duplicate transfer")
+
+	ErrQuoteNotFound           = errors.New("transfer quote not found")
+	ErrHoldNotFound            = errors.New("transfer hold not found")
+	ErrTransferRequestNotFound = errors.New("transfer not found")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrWebhookNotFound         = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound        = errors.New("webhook delivery not found")
+	ErrImpersonationNotFound   = errors.New("impersonation not found")
+	ErrEmailNotFound           = errors.New("email not found")
+	ErrSuppressionNotFound     = errors.New("email suppression not found")
+	// ErrQuoteExpired is a quote past its expiry or already executed.
+	ErrQuoteExpired = errors.New("transfer quote expired")
+	// ErrHoldClosed is a hold that was captured, voided or expired.
+	ErrHoldClosed         = errors.New("transfer hold is no longer authorized")
+	ErrCaptureExceedsHold = errors.New("capture exceeds the authorized amount")
+	// ErrImpersonationInactive is an impersonation that is pending, was
+	// denied or revoked, or expired.
+	ErrImpersonationInactive = errors.New("impersonation is not active")
+)
+
+// NotFoundError is a lookup that matched nothing. It unwraps to the
+// ErrXNotFound of what was looked up, ID is what it was looked up by.
+type NotFoundError struct {
+	Err error
+	ID  any
+}
+
+func NotFound(err error, id any) *NotFoundError {
+	return &NotFoundError{Err: err, ID: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Err, e.ID)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// LimitError is a transfer over one of the tenant's limits. It unwraps to
+// ErrTransferLimitExceeded or ErrDailyLimitExceeded.
+type LimitError struct {
+	Err   error
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s of %d", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateTransferError is a transfer of the amount the account sent to the
+// same recipient within the last Minutes. It unwraps to ErrDuplicateTransfer.
+type DuplicateTransferError struct {
+	Minutes int
+}
+
+func (e *DuplicateTransferError) Error() string {
+	return fmt.Sprintf("%s within %d minutes", ErrDuplicateTransfer, e.Minutes)
+}
+
+func (e *DuplicateTransferError) Unwrap() error {
+	return ErrDuplicateTransfer
+}
+
+// duplicateFields are the unique fields with a sentinel of their own. An
+// IBAN is made of the number, so a taken one is a taken number too.
+var duplicateFields = map[string]error{
+	"number": ErrDuplicateNumber,
+	"iban":   ErrDuplicateNumber,
+	"email":  ErrDuplicateEmail,
+}
+
+// DuplicateError reports a write that conflicts with an existing row on a
+// unique field. It matches ErrDuplicateNumber and ErrDuplicateEmail for
+// those fields.
+type DuplicateError struct {
+	Field string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s already exists", e.Field)
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	sentinel, ok := duplicateFields[e.Field]
+	return ok && sentinel == target
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const (
+	EventAccountCreated     = "account.created"
+	EventAccountUpdated     = "account.updated"
+	EventAccountDeleted     = "account.deleted"
+	EventTransferCompleted  = "transfer.completed"
+	EventTransferAuthorized = "transfer.authorized"
+	EventTransferVoided     = "transfer.voided"
+	EventSandboxTopUp       = "sandbox.topup"
+	// EventStatementReady is an end of day statement that was written, its
+	// payload has the day as "date".
+	EventStatementReady = "statement.ready"
+)
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	AccountID int             `json:"accountId"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func NewEvent(eventType string, accountID int, payload any, now time.Time) (*Event, error) {
+	raw, err := json.Marshal(masked(reflect.ValueOf(payload)))
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		Type:      eventType,
+		AccountID: accountID,
+		Payload:   raw,
+		CreatedAt: now.UTC(),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	HoldAuthorized = "authorized"
+	HoldCaptured   = "captured"
+	HoldVoided     = "voided"
+	// HoldExpired is an authorized hold past its ExpiresAt, it no longer
+	// reserves anything.
+	HoldExpired = "expired"
+)
+
+// HoldTTL is how long an authorized transfer reserves the funds for.
+const HoldTTL = 7 * 24 * time.Hour
+
+// TransferHold is a transfer authorized but not made yet. Its Amount is
+// reserved on the sender's account until the hold is captured, voided or
+// expires. Capturing transfers at most Amount, the final amount can be less.
+type TransferHold struct {
+	ID            string        `json:"id"`
+	AccountID     int           `json:"accountId"`
+	ToAccount     AccountNumber `json:"toAccount"`
+	Amount        Money         `json:"amount"`
+	Status        string        `json:"status"`
+	Captured      *Money        `json:"captured,omitempty"`
+	TransactionID int           `json:"transactionId,omitempty"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	ExpiresAt     time.Time     `json:"expiresAt"`
+	SettledAt     *time.Time    `json:"settledAt,omitempty"`
+}
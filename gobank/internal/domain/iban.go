@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidIBAN = errors.New("invalid IBAN")
+	// ErrIBANCountry is a country the bank doesn't know the IBAN length of.
+	ErrIBANCountry = errors.New("IBAN country not supported")
+)
+
+// ibanLengths are the IBAN lengths of the countries the bank supports,
+// from the SWIFT IBAN registry.
+var ibanLengths = map[string]int{
+	"AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GR": 27,
+	"HR": 21, "HU": 28, "IE": 22, "IS": 26, "IT": 27, "LI": 21, "LT": 20,
+	"LU": 20, "LV": 21, "MC": 27, "MT": 31, "NL": 18, "NO": 15, "PL": 28,
+	"PT": 25, "RO": 24, "SE": 24, "SI": 19, "SK": 24, "SM": 27,
+}
+
+// accountNumberDigits is how many digits NewAccountNumber draws.
+const accountNumberDigits = 7
+
+// NormalizeIBAN takes an IBAN in print form, with spaces and in any case,
+// to the electronic form the bank stores.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// ValidateIBAN checks an IBAN in electronic form: a supported country, the
+// length of that country and the ISO 7064 mod 97 check digits.
+func ValidateIBAN(iban string) error {
+	if len(iban) < 5 {
+		return &ProfileError{Err: ErrInvalidIBAN, Value: iban}
+	}
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return &ProfileError{Err: ErrIBANCountry, Value: iban[:2]}
+	}
+	if len(iban) != length || ibanRemainder(iban[4:]+iban[:4]) != 1 {
+		return &ProfileError{Err: ErrInvalidIBAN, Value: iban}
+	}
+	return nil
+}
+
+// CheckIBANBank checks that an IBAN country and bank code leave enough of
+// the BBAN for an account number.
+func CheckIBANBank(country, bankCode string) error {
+	length, ok := ibanLengths[country]
+	if !ok {
+		return &ProfileError{Err: ErrIBANCountry, Value: country}
+	}
+	if len(bankCode) > length-4-accountNumberDigits || !isAlphanumeric(bankCode) {
+		return &ProfileError{Err: ErrInvalidIBAN, Value: bankCode}
+	}
+	return nil
+}
+
+// NewIBAN makes the IBAN of an account of the bank: the bank code followed
+// by the account number padded with zeros to the country's length.
+func NewIBAN(country, bankCode string, number AccountNumber) (string, error) {
+	if err := CheckIBANBank(country, bankCode); err != nil {
+		return "", err
+	}
+	width := ibanLengths[country] - 4 - len(bankCode)
+	bban := strings.ToUpper(bankCode) + fmt.Sprintf("%0*d", width, number.Reveal())
+	check := 98 - ibanRemainder(bban+country+"00")
+	return fmt.Sprintf("%s%02d%s", country, check, bban), nil
+}
+
+// ibanRemainder is s mod 97 with letters counting A as 10 to Z as 35.
+func ibanRemainder(s string) int64 {
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		default:
+			return -1
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return -1
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64()
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range strings.ToUpper(s) {
+		if !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	assert.Nil(t, ValidateIBAN(NormalizeIBAN("de89 3704 0044 0532 0130 00")))
+	assert.Nil(t, ValidateIBAN("GB82WEST12345698765432"))
+	assert.True(t, errors.Is(ValidateIBAN("DE88370400440532013000"), ErrInvalidIBAN))
+	assert.True(t, errors.Is(ValidateIBAN("DE8937040044053201300"), ErrInvalidIBAN))
+	assert.True(t, errors.Is(ValidateIBAN("XX89370400440532013000"), ErrIBANCountry))
+}
+
+func TestNewIBAN(t *testing.T) {
+	iban, err := NewIBAN("DE", "37040044", 4711)
+	assert.Nil(t, err)
+	assert.Equal(t, "DE", iban[:2])
+	assert.Equal(t, "370400440000004711", iban[4:])
+	assert.Nil(t, ValidateIBAN(iban))
+
+	iban, err = NewIBAN("GB", "GOBK000000", 9999999)
+	assert.Nil(t, err)
+	assert.Nil(t, ValidateIBAN(iban))
+
+	_, err = NewIBAN("NO", "12345", 1)
+	assert.True(t, errors.Is(err, ErrInvalidIBAN))
+}
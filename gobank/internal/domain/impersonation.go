@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+const (
+	// ImpersonationPending waits for the account holder to approve it.
+	ImpersonationPending  = "pending"
+	ImpersonationApproved = "approved"
+	ImpersonationDenied   = "denied"
+	ImpersonationRevoked  = "revoked"
+	// ImpersonationExpired is an approved impersonation past its ExpiresAt.
+	ImpersonationExpired = "expired"
+)
+
+const (
+	EventImpersonationRequested = "impersonation.requested"
+	EventImpersonationApproved  = "impersonation.approved"
+	EventImpersonationDenied    = "impersonation.denied"
+	EventImpersonationRevoked   = "impersonation.revoked"
+	EventImpersonationIssued    = "impersonation.token_issued"
+	// EventImpersonationAccess is written for every request made with an
+	// impersonation token.
+	EventImpersonationAccess = "impersonation.access"
+)
+
+const (
+	DefaultImpersonationTTL = 30 * time.Minute
+	MaxImpersonationTTL     = 4 * time.Hour
+)
+
+// Impersonation lets support read an account as its holder would, for a
+// limited time. Its tokens can't move money or change anything.
+// RequestedBy names the operator, who has no account of their own.
+type Impersonation struct {
+	ID               string     `json:"id"`
+	AccountID        int        `json:"accountId"`
+	RequestedBy      string     `json:"requestedBy"`
+	Reason           string     `json:"reason"`
+	Status           string     `json:"status"`
+	RequiresApproval bool       `json:"requiresApproval"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	DecidedAt        *time.Time `json:"decidedAt,omitempty"`
+	TenantID         int        `json:"-"`
+}
+
+// NewImpersonation starts out approved unless the holder has to approve it.
+// Its time runs from when it's created either way.
+func NewImpersonation(accountID int, requestedBy, reason string, ttl time.Duration, requiresApproval bool, now time.Time) *Impersonation {
+	status := ImpersonationApproved
+	if requiresApproval {
+		status = ImpersonationPending
+	}
+	return &Impersonation{
+		ID:               NewUUID(),
+		AccountID:        accountID,
+		RequestedBy:      requestedBy,
+		Reason:           reason,
+		Status:           status,
+		RequiresApproval: requiresApproval,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(ttl),
+	}
+}
+
+// Active reports whether tokens of the impersonation are good at now.
+func (i *Impersonation) Active(now time.Time) bool {
+	return i.Status == ImpersonationApproved && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
+
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	RunAt       time.Time       `json:"runAt"`
+	LockedUntil *time.Time      `json:"lockedUntil,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+func NewJob(jobType string, payload any, now time.Time) (*Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now = now.UTC()
+	return &Job{
+		Type:        jobType,
+		Payload:     raw,
+		Status:      JobPending,
+		MaxAttempts: 5,
+		RunAt:       now,
+		CreatedAt:   now,
+	}, nil
+}
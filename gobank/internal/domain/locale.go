@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Locale is how amounts and dates are written for a language and region.
+// Tag is the BCP 47 tag, de-CH, Language its language subtag.
+type Locale struct {
+	Tag      string
+	Language string
+	// Decimal and Group are the decimal and thousands separators.
+	Decimal string
+	Group   string
+	// CurrencyFirst puts the currency code before the amount.
+	CurrencyFirst bool
+	// DateLayout and TimeLayout are time.Format layouts.
+	DateLayout string
+	TimeLayout string
+}
+
+// Locales are the locales accounts can pick, by tag.
+var Locales = map[string]Locale{
+	"en-US": {Tag: "en-US", Language: "en", Decimal: ".", Group: ",", CurrencyFirst: true, DateLayout: "01/02/2006", TimeLayout: "3:04 PM"},
+	"en-GB": {Tag: "en-GB", Language: "en", Decimal: ".", Group: ",", CurrencyFirst: true, DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"de-DE": {Tag: "de-DE", Language: "de", Decimal: ",", Group: ".", DateLayout: "02.01.2006", TimeLayout: "15:04"},
+	"de-AT": {Tag: "de-AT", Language: "de", Decimal: ",", Group: " ", DateLayout: "02.01.2006", TimeLayout: "15:04"},
+	"de-CH": {Tag: "de-CH", Language: "de", Decimal: ".", Group: "’", CurrencyFirst: true, DateLayout: "02.01.2006", TimeLayout: "15:04"},
+	"es-ES": {Tag: "es-ES", Language: "es", Decimal: ",", Group: ".", DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"es-MX": {Tag: "es-MX", Language: "es", Decimal: ".", Group: ",", CurrencyFirst: true, DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"fr-FR": {Tag: "fr-FR", Language: "fr", Decimal: ",", Group: "\u202f", DateLayout: "02/01/2006", TimeLayout: "15:04"},
+	"fr-CH": {Tag: "fr-CH", Language: "fr", Decimal: ",", Group: "\u202f", DateLayout: "02.01.2006", TimeLayout: "15:04"},
+}
+
+// languageLocales are the locales of accounts that chose a language only.
+var languageLocales = map[string]string{"en": "en-US", "de": "de-DE", "es": "es-ES", "fr": "fr-FR"}
+
+// LookupLocale finds a locale by its tag, whatever the case and separator,
+// de_ch is de-CH.
+func LookupLocale(tag string) (Locale, bool) {
+	lang, region, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	l, ok := Locales[strings.ToLower(lang)+"-"+strings.ToUpper(region)]
+	return l, ok
+}
+
+// AccountLocale is the account's locale, else the default one of its
+// language, else en-US.
+func AccountLocale(a *Account) Locale {
+	if l, ok := LookupLocale(a.Locale); ok {
+		return l
+	}
+	if tag, ok := languageLocales[a.Language]; ok {
+		return Locales[tag]
+	}
+	return Locales["en-US"]
+}
+
+// FormatMoney writes m with the locale's separators and its currency code,
+// USD 1,234.50 or 1.234,50 EUR.
+func (l Locale) FormatMoney(m Money) string {
+	s := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(l.Decimal + frac)
+	}
+	if l.CurrencyFirst {
+		return m.Currency + " " + b.String()
+	}
+	return b.String() + " " + m.Currency
+}
+
+func (l Locale) FormatDate(t time.Time) string {
+	return t.Format(l.DateLayout)
+}
+
+func (l Locale) FormatDateTime(t time.Time) string {
+	return t.Format(l.DateLayout + " " + l.TimeLayout)
+}
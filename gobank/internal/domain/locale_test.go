@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	eur := Money{MinorUnits: -123456789, Currency: "EUR"}
+	de, ok := LookupLocale("de_de")
+	assert.True(t, ok)
+	assert.Equal(t, "-1.234.567,89 EUR", de.FormatMoney(eur))
+	assert.Equal(t, "USD 999.00", Locales["en-US"].FormatMoney(Money{MinorUnits: 99900, Currency: "USD"}))
+	assert.Equal(t, "CHF 1’000.50", Locales["de-CH"].FormatMoney(Money{MinorUnits: 100050, Currency: "CHF"}))
+	assert.Equal(t, "1\u202f500 JPY", Locales["fr-FR"].FormatMoney(Money{MinorUnits: 1500, Currency: "JPY"}))
+
+	at := time.Date(2024, 3, 1, 14, 5, 0, 0, time.UTC)
+	assert.Equal(t, "03/01/2024 2:05 PM", Locales["en-US"].FormatDateTime(at))
+	assert.Equal(t, "01.03.2024", de.FormatDate(at))
+
+	assert.Equal(t, "fr-FR", AccountLocale(&Account{Language: "fr"}).Tag)
+	assert.Equal(t, "fr-CH", AccountLocale(&Account{Language: "fr", Locale: "fr-CH"}).Tag)
+	assert.Equal(t, "en-US", AccountLocale(&Account{}).Tag)
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currencyExponents lists the ISO 4217 currencies whose minor unit isn't a
+// hundredth.
+var currencyExponents = map[string]int{
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "JOD": 3, "TND": 3,
+}
+
+func currencyExponent(currency string) int {
+	if exp, ok := currencyExponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Money is an amount in the minor units of its currency. It serializes as
+// {"amount": "125.50", "currency": "USD", "minor_units": 12550} and accepts
+// that object, a bare number of minor units or a decimal string.
+type Money struct {
+	MinorUnits int64
+	Currency   string
+}
+
+type moneyJSON struct {
+	Amount     json.RawMessage `json:"amount,omitempty"`
+	Currency   string          `json:"currency,omitempty"`
+	MinorUnits *int64          `json:"minor_units,omitempty"`
+}
+
+// Decimal formats the amount with the currency's number of decimals.
+func (m Money) Decimal() string {
+	exp := currencyExponent(m.Currency)
+	if exp == 0 {
+		return strconv.FormatInt(m.MinorUnits, 10)
+	}
+	sign := ""
+	units := m.MinorUnits
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	div := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d", sign, units/div, exp, units%div)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount     string `json:"amount"`
+		Currency   string `json:"currency"`
+		MinorUnits int64  `json:"minor_units"`
+	}{m.Decimal(), m.Currency, m.MinorUnits})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var v moneyJSON
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		m.Currency = strings.ToUpper(v.Currency)
+		if v.MinorUnits != nil {
+			m.MinorUnits = *v.MinorUnits
+			return nil
+		}
+		if len(v.Amount) == 0 {
+			return fmt.Errorf("money needs amount or minor_units")
+		}
+		var amount string
+		if err := json.Unmarshal(v.Amount, &amount); err != nil {
+			// a JSON number like 125.50
+			amount = string(v.Amount)
+		}
+		units, err := ParseDecimal(amount, m.Currency)
+		m.MinorUnits = units
+		return err
+	}
+	var amount string
+	if err := json.Unmarshal(data, &amount); err == nil {
+		units, err := ParseDecimal(amount, m.Currency)
+		m.MinorUnits = units
+		return err
+	}
+	// legacy requests send a bare integer of minor units
+	return json.Unmarshal(data, &m.MinorUnits)
+}
+
+// ParseDecimal converts a decimal amount such as "125.5" into minor units of
+// currency, rejecting more decimals than the currency has.
+func ParseDecimal(amount, currency string) (int64, error) {
+	exp := currencyExponent(currency)
+	amount = strings.TrimSpace(amount)
+	neg := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+	whole, frac, _ := strings.Cut(amount, ".")
+	if whole == "" || len(frac) > exp || strings.ContainsAny(whole+frac, "+-eE") {
+		return 0, fmt.Errorf("invalid amount %s", amount)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %s", amount)
+	}
+	if neg {
+		units = -units
+	}
+	return units, nil
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	out, err := json.Marshal(Money{MinorUnits: 12550, Currency: "USD"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount":"125.50","currency":"USD","minor_units":12550}`, string(out))
+
+	out, err = json.Marshal(Money{MinorUnits: -5, Currency: "JPY"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount":"-5","currency":"JPY","minor_units":-5}`, string(out))
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	for input, want := range map[string]Money{
+		`12550`:                                {MinorUnits: 12550},
+		`"125.5"`:                              {MinorUnits: 12550},
+		`{"amount":"125.50","currency":"usd"}`: {MinorUnits: 12550, Currency: "USD"},
+		`{"amount":1.234,"currency":"KWD"}`:    {MinorUnits: 1234, Currency: "KWD"},
+		`{"minor_units":7,"currency":"EUR"}`:   {MinorUnits: 7, Currency: "EUR"},
+	} {
+		var m Money
+		assert.Nil(t, json.Unmarshal([]byte(input), &m), input)
+		assert.Equal(t, want, m, input)
+	}
+
+	var m Money
+	assert.NotNil(t, json.Unmarshal([]byte(`{"amount":"1.234","currency":"USD"}`), &m))
+	assert.NotNil(t, json.Unmarshal([]byte(`"1e3"`), &m))
+}
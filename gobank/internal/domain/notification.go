@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// The notifications of the in-app inbox.
+const (
+	NotificationWelcome        = "welcome"
+	NotificationTransfer       = "transfer"
+	NotificationLogin          = "login"
+	NotificationStatementReady = "statement_ready"
+)
+
+// Notification is an entry of the account's in-app inbox, rendered in the
+// holder's language when it was added. Data tells the app what it is about,
+// like the data of a Push.
+type Notification struct {
+	ID        int64             `json:"id"`
+	AccountID int               `json:"accountId"`
+	Kind      string            `json:"kind"`
+	DedupeKey string            `json:"-"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ReadAt    *time.Time        `json:"readAt,omitempty"`
+}
+
+func NewNotification(accountID int, kind, dedupeKey, title, body string, data map[string]string) *Notification {
+	return &Notification{
+		AccountID: accountID,
+		Kind:      kind,
+		DedupeKey: dedupeKey,
+		Title:     title,
+		Body:      body,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// AccountNumber prints as its last four digits, ****4567.
+type AccountNumber int64
+
+func (n AccountNumber) Reveal() int64 {
+	return int64(n)
+}
+
+func (n AccountNumber) String() string {
+	s := strconv.FormatInt(int64(n), 10)
+	if len(s) <= 4 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// Format masks %d and %v alike.
+func (n AccountNumber) Format(f fmt.State, verb rune) {
+	f.Write([]byte(n.String()))
+}
+
+func (n AccountNumber) LogValue() slog.Value {
+	return slog.StringValue(n.String())
+}
+
+// PII is a name, email address or other text identifying a person. It prints
+// as its first character followed by ***.
+type PII string
+
+func (p PII) Reveal() string {
+	return string(p)
+}
+
+func (p PII) String() string {
+	if p == "" {
+		return ""
+	}
+	r, _ := utf8.DecodeRuneInString(string(p))
+	return string(r) + "***"
+}
+
+func (p PII) Format(f fmt.State, verb rune) {
+	f.Write([]byte(p.String()))
+}
+
+func (p PII) LogValue() slog.Value {
+	return slog.StringValue(p.String())
+}
+
+// Secret is a token or key. It never prints.
+type Secret string
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return Redacted
+}
+
+func (s Secret) Format(f fmt.State, verb rune) {
+	f.Write([]byte(Redacted))
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+var maskedTypes = map[reflect.Type]bool{
+	reflect.TypeOf(AccountNumber(0)): true,
+	reflect.TypeOf(PII("")):          true,
+	reflect.TypeOf(Secret("")):       true,
+}
+
+// masked copies v into plain maps and slices with the values of the types
+// above replaced by their masked form, so they encode masked to JSON too.
+// NewEvent runs every audit payload through it.
+func masked(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if maskedTypes[v.Type()] {
+		return fmt.Sprint(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return masked(v.Elem())
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			m[fmt.Sprint(it.Key().Interface())] = masked(it.Value())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = masked(v.Index(i))
+		}
+		return s
+	case reflect.Struct:
+		if _, ok := v.Interface().(json.Marshaler); ok {
+			return v.Interface()
+		}
+		m := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+				continue
+			}
+			m[name] = masked(v.Field(i))
+		}
+		return m
+	}
+	return v.Interface()
+}
+
+const Redacted = "[REDACTED]"
@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventPayloadsAreMasked(t *testing.T) {
+	ev, err := NewEvent(EventTransferCompleted, 1, map[string]any{"from": AccountNumber(1234567), "amount": int64(500), "to": []AccountNumber{7654321}}, time.Now())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"from":"****4567","amount":500,"to":["****4321"]}`, string(ev.Payload))
+}
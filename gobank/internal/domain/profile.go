@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnknownCountry     = errors.New("unknown country")
+	ErrInvalidPostalCode  = errors.New("invalid postal code")
+	ErrInvalidDateOfBirth = errors.New("invalid date of birth")
+	ErrUnderage           = errors.New("account holder is under age")
+	ErrKYCIncomplete      = errors.New("account profile incomplete")
+	ErrInvalidNickname    = errors.New("invalid nickname")
+)
+
+// MaxNicknameLength is the most characters a nickname may have.
+const MaxNicknameLength = 30
+
+// MinimumAge is how old an account holder has to be.
+const MinimumAge = 18
+
+// DateLayout is how dates without a time, such as DateOfBirth, are written.
+const DateLayout = "2006-01-02"
+
+// Address is a postal address. Country is the ISO 3166-1 alpha-2 code, see
+// Countries.
+type Address struct {
+	Line1      PII    `json:"line1"`
+	Line2      PII    `json:"line2,omitempty"`
+	City       PII    `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode PII    `json:"postalCode,omitempty"`
+	Country    string `json:"country"`
+}
+
+// postalCodeFormats are the postal code formats of the countries that have a
+// fixed one. Codes of other countries are only checked for length.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CZ": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// ProfileError is an invalid address or date of birth. It unwraps to one of
+// the errors above, Value is what was rejected.
+type ProfileError struct {
+	Err   error
+	Value string
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Value)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+// Normalize upper-cases the country and the postal code and trims the
+// fields, the forms Validate checks and the store keeps.
+func (a *Address) Normalize() {
+	a.Line1 = PII(strings.TrimSpace(string(a.Line1)))
+	a.Line2 = PII(strings.TrimSpace(string(a.Line2)))
+	a.City = PII(strings.TrimSpace(string(a.City)))
+	a.Region = strings.TrimSpace(a.Region)
+	a.PostalCode = PII(strings.ToUpper(strings.TrimSpace(string(a.PostalCode))))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate checks the country and, for the countries in postalCodeFormats,
+// the postal code's format.
+func (a *Address) Validate() error {
+	if _, ok := LookupCountry(a.Country); !ok {
+		return &ProfileError{Err: ErrUnknownCountry, Value: a.Country}
+	}
+	code := a.PostalCode.Reveal()
+	if format, ok := postalCodeFormats[a.Country]; ok && !format.MatchString(code) || len(code) > 12 {
+		return &ProfileError{Err: ErrInvalidPostalCode, Value: code}
+	}
+	return nil
+}
+
+// ValidateDateOfBirth checks that dob is a date in DateLayout of someone at
+// least MinimumAge years old on now.
+func ValidateDateOfBirth(dob PII, now time.Time) error {
+	born, err := time.Parse(DateLayout, dob.Reveal())
+	if err != nil || born.After(now) || born.Year() < 1900 {
+		return &ProfileError{Err: ErrInvalidDateOfBirth, Value: dob.Reveal()}
+	}
+	if now.Before(born.AddDate(MinimumAge, 0, 0)) {
+		return ErrUnderage
+	}
+	return nil
+}
+
+// ValidateNickname checks a trimmed nickname of 1 to MaxNicknameLength
+// printable characters.
+func ValidateNickname(nickname string) error {
+	n := utf8.RuneCountInString(nickname)
+	if n == 0 || n > MaxNicknameLength || !utf8.ValidString(nickname) || strings.TrimSpace(nickname) != nickname {
+		return &ProfileError{Err: ErrInvalidNickname, Value: nickname}
+	}
+	for _, r := range nickname {
+		if !unicode.IsPrint(r) {
+			return &ProfileError{Err: ErrInvalidNickname, Value: nickname}
+		}
+	}
+	return nil
+}
+
+// KYCError lists the profile fields an account still lacks before it may
+// send money. It unwraps to ErrKYCIncomplete.
+type KYCError struct {
+	Missing []string
+}
+
+func (e *KYCError) Error() string {
+	return fmt.Sprintf("%s, missing %s", ErrKYCIncomplete, strings.Join(e.Missing, ", "))
+}
+
+func (e *KYCError) Unwrap() error {
+	return ErrKYCIncomplete
+}
+
+// CheckKYC reports the profile fields a know-your-customer check needs that
+// the account doesn't have. The fields themselves were validated when they
+// were set.
+func (a *Account) CheckKYC() error {
+	var missing []string
+	if a.Email == "" {
+		missing = append(missing, "email")
+	}
+	if a.Address == nil {
+		missing = append(missing, "address")
+	}
+	if a.DateOfBirth == "" {
+		missing = append(missing, "dateOfBirth")
+	}
+	if missing != nil {
+		return &KYCError{Missing: missing}
+	}
+	return nil
+}
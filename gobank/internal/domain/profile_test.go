@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAddressValidate(t *testing.T) {
+	a := &Address{Line1: " Torstraße 1 ", City: "Berlin", PostalCode: "10119", Country: "de"}
+	a.Normalize()
+	assert.Equal(t, "DE", a.Country)
+	assert.Equal(t, PII("Torstraße 1"), a.Line1)
+	assert.Nil(t, a.Validate())
+
+	a.PostalCode = "1011"
+	assert.True(t, errors.Is(a.Validate(), ErrInvalidPostalCode))
+
+	gb := &Address{Line1: "10 Downing St", City: "London", PostalCode: "sw1a 2aa", Country: "GB"}
+	gb.Normalize()
+	assert.Nil(t, gb.Validate())
+
+	// no fixed format, nor a postal code at all
+	assert.Nil(t, (&Address{Line1: "1 Queen's Road", City: "Hong Kong", Country: "HK"}).Validate())
+	assert.True(t, errors.Is((&Address{Line1: "x", City: "y", Country: "XX"}).Validate(), ErrUnknownCountry))
+}
+
+func TestValidateDateOfBirth(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, ValidateDateOfBirth("2006-03-10", now))
+	assert.True(t, errors.Is(ValidateDateOfBirth("2006-03-11", now), ErrUnderage))
+	assert.True(t, errors.Is(ValidateDateOfBirth("2025-01-01", now), ErrInvalidDateOfBirth))
+	assert.True(t, errors.Is(ValidateDateOfBirth("10.03.1990", now), ErrInvalidDateOfBirth))
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// The projections, read tables kept up to date from the ledger by the
+// projector.
+const (
+	// ProjectionBalances is the ledger balance of each account.
+	ProjectionBalances = "balances"
+	// ProjectionMonthlyTotals is the credits and debits of each account per
+	// month of its time zone.
+	ProjectionMonthlyTotals = "monthly_totals"
+)
+
+var Projections = []string{ProjectionBalances, ProjectionMonthlyTotals}
+
+var ErrProjectionNotFound = errors.New("projection not found")
+
+// Projection is how far a projection got through the ledger.
+type Projection struct {
+	Name string `json:"name"`
+	// LastTransactionID is the last ledger entry applied, Behind the number
+	// of entries after it.
+	LastTransactionID int        `json:"lastTransactionId"`
+	Behind            int        `json:"behind"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+}
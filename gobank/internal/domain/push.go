@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// EventAccountLogin is a successful password login to the account.
+const EventAccountLogin = "account.login"
+
+var (
+	ErrDeviceNotFound = errors.New("push device not found")
+	ErrPushNotFound   = errors.New("push notification not found")
+)
+
+// The platforms devices register for, which decide the push service their
+// token is for.
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+// The push notifications accounts get.
+const (
+	PushTransfer = "transfer"
+	PushLogin    = "login"
+)
+
+// Device is a mobile app install of the holder that gets push notifications.
+// Token is the FCM registration token of Android devices and the APNs device
+// token of iOS ones, a token registered again by another account moves
+// over to it.
+type Device struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"-"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewDevice(accountID int, platform, token, name string) *Device {
+	return &Device{
+		ID:        NewUUID(),
+		AccountID: accountID,
+		Platform:  platform,
+		Token:     token,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Push is a notification for one device, queued and sent like an Email.
+// Data goes to the app along with it, to open the right screen.
+type Push struct {
+	ID         string            `json:"id"`
+	TenantID   int               `json:"tenantId"`
+	AccountID  int               `json:"accountId"`
+	DeviceID   string            `json:"deviceId"`
+	Kind       string            `json:"kind"`
+	DedupeKey  string            `json:"-"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Data       map[string]string `json:"data,omitempty"`
+	Status     string            `json:"status"`
+	Attempts   int               `json:"attempts"`
+	LastError  string            `json:"lastError,omitempty"`
+	ProviderID string            `json:"providerId,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	SentAt     *time.Time        `json:"sentAt,omitempty"`
+}
+
+func NewPush(account *Account, device *Device, kind, dedupeKey, title, body string, data map[string]string) *Push {
+	return &Push{
+		ID:        NewUUID(),
+		TenantID:  account.TenantID,
+		AccountID: account.ID,
+		DeviceID:  device.ID,
+		Kind:      kind,
+		DedupeKey: dedupeKey + ":" + device.ID,
+		Title:     title,
+		Body:      body,
+		Data:      data,
+		Status:    EmailPending,
+		CreatedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// QuoteTTL is how long a transfer quote can be executed for.
+const QuoteTTL = 2 * time.Minute
+
+// TransferQuote fixes the terms of a proposed transfer for QuoteTTL. The
+// sender executes it by passing the ID to POST /transfer.
+type TransferQuote struct {
+	ID        string        `json:"id"`
+	AccountID int           `json:"-"`
+	ToAccount AccountNumber `json:"toAccount"`
+	Amount    Money         `json:"amount"`
+	// Rate converts Amount into the recipient's currency. Transfers don't
+	// cross currencies, so for now it is always "1".
+	Rate string `json:"rate"`
+	// Fee is charged on top of Amount, Total is what leaves the account.
+	Fee       Money     `json:"fee"`
+	Total     Money     `json:"total"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewTransferQuote quotes amount to the account numbered to at the current
+// terms, a rate of 1 and no fee.
+func NewTransferQuote(from *Account, to AccountNumber, amount Money, now time.Time) *TransferQuote {
+	fee := Money{Currency: amount.Currency}
+	return &TransferQuote{
+		ID:        NewUUID(),
+		AccountID: from.ID,
+		ToAccount: to,
+		Amount:    amount,
+		Rate:      "1",
+		Fee:       fee,
+		Total:     Money{MinorUnits: amount.MinorUnits + fee.MinorUnits, Currency: amount.Currency},
+		ExpiresAt: now.Add(QuoteTTL).UTC(),
+	}
+}
+
+// Expired reports whether the quote can no longer be executed at now.
+func (q *TransferQuote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
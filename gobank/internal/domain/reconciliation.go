@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+)
+
+// ReconciliationIssue is an account whose stored balance doesn't match the
+// sum of its ledger entries, hot and archived.
+type ReconciliationIssue struct {
+	ID             int        `json:"id"`
+	TenantID       int        `json:"tenantId"`
+	AccountID      int        `json:"accountId"`
+	Currency       string     `json:"currency"`
+	AccountBalance Money      `json:"accountBalance"`
+	LedgerBalance  Money      `json:"ledgerBalance"`
+	DetectedAt     time.Time  `json:"detectedAt"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	Resolution     string     `json:"resolution,omitempty"`
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"embed"
+	"encoding/json"
+)
+
+// The reference data clients pick from, embedded so every instance serves the
+// same lists. Edit the JSON files, not code, to change them.
+//
+//go:embed reference/*.json
+var referenceFiles embed.FS
+
+// Currency is an ISO 4217 currency. MinorUnits is the number of decimals,
+// the same Money uses.
+type Currency struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minorUnits"`
+}
+
+// Country is an ISO 3166-1 country, Code the alpha-2 code.
+type Country struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type AccountType struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var (
+	currencies   = mustLoadReference[Currency]("reference/currencies.json")
+	countries    = mustLoadReference[Country]("reference/countries.json")
+	accountTypes = mustLoadReference[AccountType]("reference/account-types.json")
+)
+
+func mustLoadReference[T any](name string) []T {
+	raw, err := referenceFiles.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	var items []T
+	if err := json.Unmarshal(raw, &items); err != nil {
+		panic(name + ": " + err.Error())
+	}
+	return items
+}
+
+// Currencies returns the currencies accounts can hold. The slices returned
+// here are shared, callers must not modify them.
+func Currencies() []Currency {
+	return currencies
+}
+
+func Countries() []Country {
+	return countries
+}
+
+func AccountTypes() []AccountType {
+	return accountTypes
+}
+
+// LookupCountry finds a country by its alpha-2 code.
+func LookupCountry(code string) (Country, bool) {
+	for _, c := range countries {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Country{}, false
+}
+
+// LookupCurrency finds a currency by its ISO 4217 code.
+func LookupCurrency(code string) (Currency, bool) {
+	for _, c := range currencies {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Currency{}, false
+}
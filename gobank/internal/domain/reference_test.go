@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestReferenceData(t *testing.T) {
+	code := regexp.MustCompile(`^[A-Z]{3}$`)
+	for _, c := range Currencies() {
+		assert.Regexp(t, code, c.Code)
+		// Money formats amounts with the exponents it knows, they have to agree
+		assert.Equal(t, currencyExponent(c.Code), c.MinorUnits, c.Code)
+	}
+	for cur := range currencyExponents {
+		_, ok := LookupCurrency(cur)
+		assert.True(t, ok, cur)
+	}
+	de, ok := LookupCountry("DE")
+	assert.True(t, ok)
+	assert.Equal(t, "Germany", de.Name)
+	_, ok = LookupCountry("XX")
+	assert.False(t, ok)
+	assert.Equal(t, "checking", AccountTypes()[0].Code)
+}
@@ -0,0 +1,20 @@
+package domain
+
+type DailyReportRow struct {
+	Date           string `json:"date"`
+	Currency       string `json:"currency"`
+	NewAccounts    int    `json:"newAccounts"`
+	Transfers      int    `json:"transfers"`
+	TransferVolume Money  `json:"transferVolume"`
+	FeeRevenue     Money  `json:"feeRevenue"`
+}
+
+// LedgerTotal is what the ledger entries of one type moved in a currency on
+// a UTC day, credits less debits, and how many there were.
+type LedgerTotal struct {
+	Date     string
+	Currency string
+	Type     string
+	Amount   Money
+	Entries  int
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+const (
+	SagaRunning      = "running"
+	SagaCompleted    = "completed"
+	SagaCompensating = "compensating"
+	SagaCompensated  = "compensated"
+	// SagaStuck is a saga whose compensation failed, an operator has to
+	// finish it by hand.
+	SagaStuck = "stuck"
+)
+
+const (
+	StepDone               = "done"
+	StepFailed             = "failed"
+	StepCompensated        = "compensated"
+	StepCompensationFailed = "compensation_failed"
+)
+
+// SagaStaleAfter is how long a saga can stay running or compensating before
+// it's reported as stuck, its process having died half way.
+const SagaStaleAfter = 5 * time.Minute
+
+// Saga is the log of an operation spanning several separately committed
+// steps. Each step that was done is undone in reverse order when a later one
+// fails.
+type Saga struct {
+	ID        string     `json:"id"`
+	TenantID  int        `json:"tenantId"`
+	AccountID int        `json:"accountId"`
+	Type      string     `json:"type"`
+	Status    string     `json:"status"`
+	Steps     []SagaStep `json:"steps"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+type SagaStep struct {
+	Name   string    `json:"name"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTransferHeld is a transfer screening flagged, saved for a person to
+	// release or reject.
+	ErrTransferHeld = errors.New("transfer held for review")
+	// ErrScreeningFlagged is a transfer screening flagged where it can't be
+	// held, an authorization or a transfer of an atomic batch.
+	ErrScreeningFlagged = errors.New("transfer flagged by screening")
+	// ErrTransferNotInReview is a review of a transfer that isn't held.
+	ErrTransferNotInReview = errors.New("transfer is not in review")
+)
+
+// ScreeningParty is a side of a transfer as screening sees it.
+type ScreeningParty struct {
+	Name   string
+	Number AccountNumber
+}
+
+// ScreeningHit is what made screening flag a transfer: the party, sender or
+// recipient, and the entry of the list it matched.
+type ScreeningHit struct {
+	Party string
+	List  string
+	Entry string
+}
+
+func (h *ScreeningHit) String() string {
+	return fmt.Sprintf("%s matches %s entry %q", h.Party, h.List, h.Entry)
+}
+
+// HeldError is a transfer held for review, TransferID follows it like an
+// accepted transfer. It unwraps to ErrTransferHeld.
+type HeldError struct {
+	TransferID string
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTransferHeld, e.TransferID)
+}
+
+func (e *HeldError) Unwrap() error {
+	return ErrTransferHeld
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrSMSNotFound = errors.New("sms not found")
+	// ErrPhoneNotVerified is SMS delivery asked for before the holder
+	// confirmed they own the account's phone number.
+	ErrPhoneNotVerified = errors.New("phone number is not verified")
+	// ErrVerificationFailed is a wrong code, or one that expired or was
+	// guessed at too often.
+	ErrVerificationFailed = errors.New("verification code is wrong or expired")
+)
+
+// The text messages accounts get.
+const (
+	SMSVerificationCode = "verification_code"
+	SMSTransferAlert    = "transfer_alert"
+)
+
+// SMS is a text message waiting in the send queue or sent from it, its
+// statuses are those of emails but for EmailSuppressed.
+type SMS struct {
+	ID         string     `json:"id"`
+	TenantID   int        `json:"tenantId"`
+	AccountID  int        `json:"accountId"`
+	Kind       string     `json:"kind"`
+	DedupeKey  string     `json:"-"`
+	To         PII        `json:"to"`
+	Body       string     `json:"-"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"lastError,omitempty"`
+	ProviderID string     `json:"providerId,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	SentAt     *time.Time `json:"sentAt,omitempty"`
+}
+
+func NewSMS(account *Account, kind, dedupeKey, body string) *SMS {
+	return &SMS{
+		ID:        NewUUID(),
+		TenantID:  account.TenantID,
+		AccountID: account.ID,
+		Kind:      kind,
+		DedupeKey: dedupeKey,
+		To:        account.Phone,
+		Body:      body,
+		Status:    EmailPending,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+const (
+	VerificationCodeTTL = 10 * time.Minute
+	// VerificationResendAfter is how long a holder waits before asking for
+	// another code.
+	VerificationResendAfter = time.Minute
+	MaxVerificationAttempts = 5
+)
+
+// PhoneVerification is the one-time code last sent to the account's phone.
+// Only its hash is kept, and it only confirms the number it was sent to.
+type PhoneVerification struct {
+	AccountID int
+	Phone     PII
+	CodeHash  string
+	Attempts  int
+	SentAt    time.Time
+	ExpiresAt time.Time
+}
+
+// NewPhoneVerification draws a six-digit code for the account's phone and
+// returns it with the verification that checks it.
+func NewPhoneVerification(account *Account, now time.Time) (*PhoneVerification, string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, "", err
+	}
+	code := fmt.Sprintf("%06d", n)
+	return &PhoneVerification{
+		AccountID: account.ID,
+		Phone:     account.Phone,
+		CodeHash:  hashCode(code),
+		SentAt:    now,
+		ExpiresAt: now.Add(VerificationCodeTTL),
+	}, code, nil
+}
+
+// Check reports whether code is the one sent and still good at now.
+func (v *PhoneVerification) Check(code string, now time.Time) bool {
+	if v.Attempts >= MaxVerificationAttempts || !now.Before(v.ExpiresAt) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashCode(code)), []byte(v.CodeHash)) == 1
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPhoneVerification(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	v, code, err := NewPhoneVerification(&Account{ID: 7, Phone: "+4915123456789"}, now)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9]{6}$`), code)
+	assert.NotContains(t, v.CodeHash, code)
+	assert.Equal(t, now.Add(VerificationCodeTTL), v.ExpiresAt)
+
+	assert.True(t, v.Check(code, now.Add(time.Minute)))
+	assert.False(t, v.Check("abcdef", now))
+	assert.False(t, v.Check(code, v.ExpiresAt))
+	v.Attempts = MaxVerificationAttempts
+	assert.False(t, v.Check(code, now))
+}
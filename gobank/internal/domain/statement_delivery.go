@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrStatementDestinationNotFound = errors.New("statement destination not found")
+	ErrStatementDeliveryNotFound    = errors.New("statement delivery not found")
+)
+
+// The sinks statements are delivered to, see StatementSink in the api
+// package.
+const (
+	StatementSinkEmail = "email"
+	StatementSinkSFTP  = "sftp"
+)
+
+var StatementSinks = []string{StatementSinkEmail, StatementSinkSFTP}
+
+// How often a destination gets a statement, each one covers the period
+// since the one before.
+const (
+	StatementDaily   = "daily"
+	StatementWeekly  = "weekly"
+	StatementMonthly = "monthly"
+)
+
+var StatementFrequencies = []string{StatementDaily, StatementWeekly, StatementMonthly}
+
+// StatementDestination is where the account's statements go automatically,
+// an email address or a directory of an SFTP server. SFTP destinations log
+// in with Password, kept encrypted, or with the server's key when it is
+// empty, and only to the host whose key has the SHA256 fingerprint HostKey.
+type StatementDestination struct {
+	ID        string    `json:"id"`
+	AccountID int       `json:"accountId"`
+	Sink      string    `json:"sink"`
+	Frequency string    `json:"frequency"`
+	Email     PII       `json:"email,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Password  Secret    `json:"-"`
+	HostKey   string    `json:"hostKey,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	TenantID  int       `json:"-"`
+}
+
+func NewStatementDestination(accountID int, sink, frequency string) *StatementDestination {
+	return &StatementDestination{
+		ID:        NewUUID(),
+		AccountID: accountID,
+		Sink:      sink,
+		Frequency: frequency,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// StatementPeriod returns the start of the period of a statement of
+// frequency that ends with day, the start of a day in the account's time
+// zone. Days that don't end a period return false: weeks end on Sundays and
+// months on their last day.
+func StatementPeriod(frequency string, day time.Time) (time.Time, bool) {
+	next := day.AddDate(0, 0, 1)
+	switch frequency {
+	case StatementDaily:
+		return day, true
+	case StatementWeekly:
+		return day.AddDate(0, 0, -6), next.Weekday() == time.Monday
+	case StatementMonthly:
+		return next.AddDate(0, -1, 0), next.Day() == 1
+	}
+	return time.Time{}, false
+}
+
+// StatementDelivery is the statement of one period to one destination. There
+// is at most one per destination and period, however often its statement
+// is written. A delivery stays pending while its attempts are retried and
+// fails when the sink refuses it or the retries run out.
+type StatementDelivery struct {
+	ID            string     `json:"id"`
+	DestinationID string     `json:"destinationId"`
+	AccountID     int        `json:"accountId"`
+	From          string     `json:"from"`
+	To            string     `json:"to"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+	TenantID      int        `json:"-"`
+}
+
+func NewStatementDelivery(dest *StatementDestination, from, to time.Time) *StatementDelivery {
+	return &StatementDelivery{
+		ID:            NewUUID(),
+		DestinationID: dest.ID,
+		AccountID:     dest.AccountID,
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Status:        DeliveryPending,
+		CreatedAt:     time.Now().UTC(),
+		TenantID:      dest.TenantID,
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStatementPeriod(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	for _, c := range []struct {
+		frequency, day, from string
+		ok                   bool
+	}{
+		{StatementDaily, "2024-03-13", "2024-03-13", true},
+		{StatementWeekly, "2024-03-17", "2024-03-11", true},
+		{StatementWeekly, "2024-03-16", "", false},
+		{StatementMonthly, "2024-02-29", "2024-02-01", true},
+		{StatementMonthly, "2024-02-28", "", false},
+		{"yearly", "2024-12-31", "", false},
+	} {
+		from, ok := StatementPeriod(c.frequency, day(c.day))
+		assert.Equal(t, c.ok, ok, c.frequency+" "+c.day)
+		if ok {
+			assert.Equal(t, c.from, from.Format("2006-01-02"), c.frequency+" "+c.day)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+type AccountSummary struct {
+	Balance Money `json:"balance"`
+	// AvailableBalance is what can be spent right now, the balance less
+	// HeldBalance, ProtectedBalance and MinimumBalance.
+	AvailableBalance   Money          `json:"availableBalance"`
+	MonthToDateSpend   Money          `json:"monthToDateSpend"`
+	PendingTransfers   int            `json:"pendingTransfers"`
+	RecentTransactions []*Transaction `json:"recentTransactions"`
+	// HeldBalance is reserved by authorized holds, ProtectedBalance put
+	// aside in pots.
+	HeldBalance      Money `json:"heldBalance"`
+	ProtectedBalance Money `json:"protectedBalance"`
+	MinimumBalance   Money `json:"minimumBalance"`
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// TenantSettings are the per tenant overrides of the service defaults.
+type TenantSettings struct {
+	TenantID int    `json:"tenantId"`
+	Currency string `json:"currency"`
+	// MaxTransferAmount and DailyTransferLimit are in minor units, 0 means
+	// unlimited.
+	MaxTransferAmount  int64  `json:"maxTransferAmount"`
+	DailyTransferLimit int64  `json:"dailyTransferLimit"`
+	BrandName          string `json:"brandName"`
+	SupportEmail       string `json:"supportEmail"`
+	// RequireKYC refuses transfers from accounts whose profile lacks what
+	// Account.CheckKYC asks for.
+	RequireKYC bool `json:"requireKyc"`
+	// IBANCountry and IBANBankCode, when set, give new accounts an IBAN of
+	// the bank code and the account number, see NewIBAN.
+	IBANCountry  string    `json:"ibanCountry,omitempty"`
+	IBANBankCode string    `json:"ibanBankCode,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	// DuplicateTransferMinutes refuses a transfer of the amount the account
+	// sent the same recipient that many minutes ago or less, unless the
+	// client confirms it. 0 turns the check off.
+	DuplicateTransferMinutes int `json:"duplicateTransferMinutes"`
+	// MinimumBalance, in minor units, is the balance transfers can't take
+	// the tenant's accounts below unless an account has one of its own.
+	MinimumBalance int64 `json:"minimumBalance"`
+}
+
+// DefaultDuplicateTransferMinutes is long enough to catch a submit button
+// tapped twice and short enough not to get in the way of paying someone the
+// same amount again.
+const DefaultDuplicateTransferMinutes = 2
+
+func DefaultTenantSettings(tenantID int) *TenantSettings {
+	return &TenantSettings{
+		TenantID:                 tenantID,
+		Currency:                 "USD",
+		BrandName:                "gobank",
+		DuplicateTransferMinutes: DefaultDuplicateTransferMinutes,
+	}
+}
+
+func (t *TenantSettings) Validate() error {
+	if len(t.Currency) != 3 {
+		return fmt.Errorf("currency must be a 3 letter ISO 4217 code")
+	}
+	if t.MaxTransferAmount < 0 || t.DailyTransferLimit < 0 {
+		return fmt.Errorf("transfer limits can't be negative")
+	}
+	if t.MinimumBalance < 0 {
+		return fmt.Errorf("minimumBalance can't be negative")
+	}
+	if t.DuplicateTransferMinutes < 0 || t.DuplicateTransferMinutes > 24*60 {
+		return fmt.Errorf("duplicateTransferMinutes must be between 0 and 1440")
+	}
+	if t.IBANCountry != "" || t.IBANBankCode != "" {
+		if err := CheckIBANBank(t.IBANCountry, t.IBANBankCode); err != nil {
+			return fmt.Errorf("ibanCountry and ibanBankCode: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckTransfer enforces the tenant's transfer limits. sentToday is what the
+// account has already sent since the start of the day.
+func (t *TenantSettings) CheckTransfer(amount, sentToday int64) error {
+	if t.MaxTransferAmount > 0 && amount > t.MaxTransferAmount {
+		return &LimitError{Err: ErrTransferLimitExceeded, Limit: t.MaxTransferAmount}
+	}
+	if t.DailyTransferLimit > 0 && sentToday+amount > t.DailyTransferLimit {
+		return &LimitError{Err: ErrDailyLimitExceeded, Limit: t.DailyTransferLimit}
+	}
+	return nil
+}
+
+// TransferGuard is what the store checks again once it holds the lock on
+// the sender's row, so transfers racing each other can't all pass a check
+// each made before the others posted. A nil guard checks nothing.
+type TransferGuard struct {
+	Settings *TenantSettings
+	// DayStart is midnight in the sender's time zone, the daily limit
+	// counts what was sent from then on.
+	DayStart time.Time
+	// DuplicateSince, unless zero, refuses a transfer of the amount the
+	// sender sent the same recipient since then, or has waiting to be
+	// processed, with a *DuplicateTransferError.
+	DuplicateSince time.Time
+}
+
+const DefaultTenantSlug = "default"
+
+type Tenant struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+func NewTenant(slug, name string) (*Tenant, error) {
+	if !tenantSlugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("invalid tenant slug %s", slug)
+	}
+	return &Tenant{Slug: slug, Name: name, CreatedAt: time.Now().UTC()}, nil
+}
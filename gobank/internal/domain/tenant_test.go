@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckTransfer(t *testing.T) {
+	settings := DefaultTenantSettings(1)
+	assert.Nil(t, settings.CheckTransfer(1<<40, 1<<40))
+
+	settings.MaxTransferAmount = 10000
+	settings.DailyTransferLimit = 15000
+	assert.Nil(t, settings.CheckTransfer(10000, 5000))
+
+	err := settings.CheckTransfer(10001, 0)
+	assert.True(t, errors.Is(err, ErrTransferLimitExceeded))
+	assert.Equal(t, &LimitError{Err: ErrTransferLimitExceeded, Limit: 10000}, err)
+
+	err = settings.CheckTransfer(10000, 5001)
+	assert.True(t, errors.Is(err, ErrDailyLimitExceeded))
+	assert.Equal(t, &LimitError{Err: ErrDailyLimitExceeded, Limit: 15000}, err)
+
+	// a transfer over both limits is refused for the amount
+	err = settings.CheckTransfer(20000, 0)
+	assert.True(t, errors.Is(err, ErrTransferLimitExceeded))
+}
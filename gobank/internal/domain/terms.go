@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The documents an account holder accepts.
+const (
+	TermsOfService = "terms"
+	PrivacyPolicy  = "privacy"
+)
+
+const EventTermsAccepted = "terms.accepted"
+
+var ErrTermsNotAccepted = errors.New("current terms not accepted")
+
+// TermsVersions maps a document to its version. Documents without a current
+// version don't have to be accepted.
+type TermsVersions map[string]string
+
+type TermsAcceptance struct {
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
+// TermsStatus is what an account accepted against what's current. Pending
+// lists the documents whose current version it hasn't accepted yet.
+type TermsStatus struct {
+	Current  TermsVersions      `json:"current"`
+	Accepted []*TermsAcceptance `json:"accepted"`
+	Pending  []string           `json:"pending"`
+}
+
+func NewTermsStatus(current TermsVersions, accepted []*TermsAcceptance) *TermsStatus {
+	status := &TermsStatus{Current: current, Accepted: accepted, Pending: []string{}}
+	for doc, version := range current {
+		found := false
+		for _, a := range accepted {
+			found = found || (a.Document == doc && a.Version == version)
+		}
+		if !found {
+			status.Pending = append(status.Pending, doc)
+		}
+	}
+	sort.Strings(status.Pending)
+	return status
+}
+
+// Check fails with a *TermsError while documents are pending.
+func (s *TermsStatus) Check() error {
+	if len(s.Pending) > 0 {
+		return &TermsError{Pending: s.Pending}
+	}
+	return nil
+}
+
+// TermsError lists the documents an account has to accept before it may
+// move money. It unwraps to ErrTermsNotAccepted.
+type TermsError struct {
+	Pending []string
+}
+
+func (e *TermsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTermsNotAccepted, strings.Join(e.Pending, ", "))
+}
+
+func (e *TermsError) Unwrap() error {
+	return ErrTermsNotAccepted
+}
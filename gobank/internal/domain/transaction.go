@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// TransactionCursor is the sort key of transaction listings. Imported
+// transactions can be older than their id suggests, hence the time.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// ComputeHash chains t onto prev. created_at is hashed at the microsecond
+// precision postgres stores it with.
+func (t *Transaction) ComputeHash(prev string) string {
+	fields, _ := json.Marshal([]any{
+		prev,
+		t.AccountID,
+		t.Type,
+		t.Amount.MinorUnits,
+		t.Amount.Currency,
+		t.Counterparty,
+		t.CreatedAt.UnixMicro(),
+		t.Description,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+type LedgerVerification struct {
+	AccountID int    `json:"accountId"`
+	Entries   int    `json:"entries"`
+	Valid     bool   `json:"valid"`
+	BrokenAt  int    `json:"brokenAt,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+const (
+	TransactionTransferIn  = "transfer_in"
+	TransactionTransferOut = "transfer_out"
+	TransactionImport      = "import"
+	TransactionFee         = "fee"
+	// TransactionSandbox credits are created out of thin air by the sandbox
+	// top-up endpoint, they never exist in production.
+	TransactionSandbox = "sandbox"
+	// TransactionHistory entries are statement lines a holder imported from
+	// another bank. They happened there, so they don't post: the balance,
+	// reconciliation, the balance projection and statements leave them out.
+	TransactionHistory = "history"
+)
+
+// Transaction is a ledger row for one account. Amount is signed: credits are
+// positive and debits negative, so the sum of an account's rows is its balance.
+type Transaction struct {
+	ID           int           `json:"id"`
+	AccountID    int           `json:"accountId"`
+	Type         string        `json:"type"`
+	Amount       Money         `json:"amount"`
+	Counterparty AccountNumber `json:"counterparty"`
+	Description  string        `json:"description,omitempty"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	Hash         string        `json:"hash,omitempty"`
+	TenantID     int           `json:"-"`
+}
+
+// Posted reports whether the entry moved the account's balance.
+func (t *Transaction) Posted() bool {
+	return t.Type != TransactionHistory
+}
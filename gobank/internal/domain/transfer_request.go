@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+const (
+	TransferPending   = "pending"
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+	// TransferInReview is a transfer screening flagged, see HeldError.
+	TransferInReview = "review"
+)
+
+// TransferRequest is a transfer accepted for processing in the background.
+// It moves from pending to completed, with TransactionID set, or to failed,
+// with the API error code and its params saying why. A transfer screening
+// flags is in review, with ReviewReason saying why, until a person releases
+// it to pending or rejects it; ReviewedAt is set then.
+type TransferRequest struct {
+	ID            string
+	TenantID      int
+	AccountID     int
+	ToAccount     AccountNumber
+	Amount        Money
+	Status        string
+	TransactionID int
+	FailureCode   string
+	FailureParams map[string]any
+	ReviewReason  string
+	ReviewedAt    time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func NewTransferRequest(from *Account, to AccountNumber, amount Money, now time.Time) *TransferRequest {
+	now = now.UTC()
+	return &TransferRequest{
+		ID:        NewUUID(),
+		TenantID:  from.TenantID,
+		AccountID: from.ID,
+		ToAccount: to,
+		Amount:    amount,
+		Status:    TransferPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+)
+
+type UsageKey struct {
+	TenantID      int
+	AccountNumber int64
+	Day           time.Time
+}
+
+type Usage struct {
+	Calls     int64
+	Throttled int64
+}
+
+type DailyUsage struct {
+	Date      string `json:"date"`
+	Calls     int64  `json:"calls"`
+	Throttled int64  `json:"throttled"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewUUID(t *testing.T) {
+	id := NewUUID()
+	assert.True(t, IsUUID(id), id)
+	assert.Equal(t, byte('4'), id[14], "version")
+	assert.Contains(t, "89ab", string(id[19]), "variant")
+	assert.NotEqual(t, id, NewUUID())
+
+	assert.False(t, IsUUID(""))
+	assert.False(t, IsUUID("42"))
+	assert.False(t, IsUUID("9b2e1c4a-3f6d-4e8b-a1c2_0d3e4f5a6b7c"))
+}
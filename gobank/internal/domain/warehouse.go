@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// The datasets the warehouse export writes.
+const (
+	WarehouseAccounts     = "accounts"
+	WarehouseTransactions = "transactions"
+)
+
+// Watermark is how far the warehouse export of a dataset got: up to and
+// including the row with ID, changed at Time for datasets whose rows change.
+type Watermark struct {
+	Dataset   string
+	Time      time.Time
+	ID        int
+	UpdatedAt time.Time
+}
@@ -0,0 +1,81 @@
+package domain
+
+import "time"
+
+const (
+	WebhookActive   = "active"
+	WebhookDisabled = "disabled"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// An endpoint is disabled once it has failed WebhookDisableAfter attempts in
+// a row over at least WebhookFailingFor, so a short outage on the
+// receiver's side doesn't turn it off.
+const (
+	WebhookDisableAfter = 20
+	WebhookFailingFor   = 24 * time.Hour
+)
+
+// WebhookEndpoint is a URL an account's events are posted to. Every delivery
+// is signed with the endpoint's secret, which is only returned when the
+// endpoint is created.
+type WebhookEndpoint struct {
+	ID                  string     `json:"id"`
+	AccountID           int        `json:"accountId"`
+	URL                 string     `json:"url"`
+	Secret              Secret     `json:"secret,omitempty"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	FailingSince        *time.Time `json:"failingSince,omitempty"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	TenantID            int        `json:"-"`
+}
+
+func NewWebhookEndpoint(accountID int, url string) (*WebhookEndpoint, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookEndpoint{
+		ID:        NewUUID(),
+		AccountID: accountID,
+		URL:       url,
+		Secret:    Secret("whsec_" + secret),
+		Status:    WebhookActive,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// WebhookDelivery is one event to one endpoint. There is at most one per
+// endpoint and event however often the event is relayed, its ID goes out
+// with every attempt so the receiver can drop repeats.
+type WebhookDelivery struct {
+	ID          string           `json:"id"`
+	EndpointID  string           `json:"endpointId"`
+	EventID     int64            `json:"eventId"`
+	EventType   string           `json:"eventType"`
+	Payload     []byte           `json:"-"`
+	Status      string           `json:"status"`
+	Attempts    []WebhookAttempt `json:"attempts"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	DeliveredAt *time.Time       `json:"deliveredAt,omitempty"`
+}
+
+// WebhookAttempt is one request of a delivery. StatusCode is 0 when no
+// response came back.
+type WebhookAttempt struct {
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	At         time.Time `json:"at"`
+}
+
+func (a WebhookAttempt) Succeeded() bool {
+	return a.StatusCode >= 200 && a.StatusCode < 300
+}
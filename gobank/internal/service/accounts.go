@@ -0,0 +1,55 @@
+// Package service holds the bank's business rules between the handlers and
+// the store, so the HTTP API, the CLI and background jobs apply them the same
+// way.
+package service
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+)
+
+// SettingsSource returns the settings a tenant's accounts are held to.
+type SettingsSource interface {
+	Get(tenantID int) (*domain.TenantSettings, error)
+}
+
+type AccountStore interface {
+	CreateAccount(account *domain.Account) error
+}
+
+type AccountService struct {
+	store    AccountStore
+	settings SettingsSource
+	clock    domain.Clock
+}
+
+func NewAccountService(store AccountStore, settings SettingsSource, clock domain.Clock) *AccountService {
+	return &AccountService{store: store, settings: settings, clock: clock}
+}
+
+// Open stores a new account in the tenant's currency, with an IBAN if the
+// tenant has IBAN settings. Account numbers are random, a taken one is
+// replaced by a fresh draw a few times before giving up.
+func (s *AccountService) Open(account *domain.Account) error {
+	settings, err := s.settings.Get(account.TenantID)
+	if err != nil {
+		return err
+	}
+	account.Balance = domain.Money{Currency: settings.Currency}
+	account.CreatedAt = s.clock.Now().UTC()
+	account.UpdatedAt = account.CreatedAt
+	create := func() error {
+		if settings.IBANCountry != "" {
+			if account.IBAN, err = domain.NewIBAN(settings.IBANCountry, settings.IBANBankCode, account.Number); err != nil {
+				return err
+			}
+		}
+		return s.store.CreateAccount(account)
+	}
+	err = create()
+	for attempt := 0; attempt < 3 && errors.Is(err, domain.ErrDuplicateNumber); attempt++ {
+		account.Number = domain.NewAccountNumber()
+		err = create()
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+)
+
+type SagaStore interface {
+	// SaveSaga writes the saga's current state, creating it the first time.
+	SaveSaga(saga *domain.Saga) error
+}
+
+// SagaStep is one step of a saga. Undo compensates a Do that succeeded, it
+// is nil for steps with nothing to undo.
+type SagaStep struct {
+	Name string
+	Do   func() error
+	Undo func() error
+}
+
+// RunSaga runs the steps in order and records every transition in the
+// store. When a step fails, the steps done before it are undone in reverse
+// order and the step's error is returned. If an undo fails too the saga is
+// left stuck for an operator and both errors are returned.
+func RunSaga(store SagaStore, clock domain.Clock, sagaType string, accountID int, steps []SagaStep) error {
+	now := clock.Now().UTC()
+	saga := &domain.Saga{
+		ID:        domain.NewUUID(),
+		AccountID: accountID,
+		Type:      sagaType,
+		Status:    domain.SagaRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.SaveSaga(saga); err != nil {
+		return err
+	}
+	// record updates the log in memory before saving it, so a step counts as
+	// done for the compensation even if saving failed
+	record := func(i int, status string, err error) error {
+		step := domain.SagaStep{Name: steps[i].Name, Status: status, At: clock.Now().UTC()}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		if i < len(saga.Steps) {
+			saga.Steps[i] = step
+		} else {
+			saga.Steps = append(saga.Steps, step)
+		}
+		saga.UpdatedAt = step.At
+		return store.SaveSaga(saga)
+	}
+	fail := func(err error) error {
+		saga.Error = err.Error()
+		saga.Status = domain.SagaCompensating
+		cerr := compensate(saga, steps, record)
+		saga.UpdatedAt = clock.Now().UTC()
+		if cerr = errors.Join(cerr, store.SaveSaga(saga)); cerr != nil {
+			return errors.Join(err, cerr)
+		}
+		return err
+	}
+
+	for i, step := range steps {
+		if err := step.Do(); err != nil {
+			record(i, domain.StepFailed, err)
+			return fail(err)
+		}
+		if err := record(i, domain.StepDone, nil); err != nil {
+			return fail(err)
+		}
+	}
+	saga.Status = domain.SagaCompleted
+	saga.UpdatedAt = clock.Now().UTC()
+	return store.SaveSaga(saga)
+}
+
+// compensate undoes the saga's done steps, last first, and sets the status
+// it ends in.
+func compensate(saga *domain.Saga, steps []SagaStep, record func(int, string, error) error) error {
+	var errs []error
+	for i := len(saga.Steps) - 1; i >= 0; i-- {
+		if saga.Steps[i].Status != domain.StepDone || steps[i].Undo == nil {
+			continue
+		}
+		if err := steps[i].Undo(); err != nil {
+			errs = append(errs, err)
+			record(i, domain.StepCompensationFailed, err)
+			continue
+		}
+		record(i, domain.StepCompensated, nil)
+	}
+	saga.Status = domain.SagaCompensated
+	if len(errs) > 0 {
+		saga.Status = domain.SagaStuck
+	}
+	return errors.Join(errs...)
+}
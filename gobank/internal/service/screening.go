@@ -0,0 +1,83 @@
+package service
+
+import (
+	"bufio"
+	"github.com/iamuditg/internal/domain"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ScreeningProvider checks the parties of a transfer, against sanctions
+// lists for example, before the transfer is made. A nil hit clears it.
+type ScreeningProvider interface {
+	Screen(sender, recipient domain.ScreeningParty) (*domain.ScreeningHit, error)
+}
+
+// Denylist is the built-in ScreeningProvider, a local list of names and
+// account numbers. Names match whatever their case and spacing.
+type Denylist struct {
+	names   map[string]bool
+	numbers map[domain.AccountNumber]bool
+}
+
+// NewDenylist takes entries of digits as account numbers and anything else
+// as names.
+func NewDenylist(entries []string) *Denylist {
+	d := &Denylist{names: map[string]bool{}, numbers: map[domain.AccountNumber]bool{}}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if n, err := strconv.ParseInt(entry, 10, 64); err == nil {
+			d.numbers[domain.AccountNumber(n)] = true
+		} else if name := normalizeName(entry); name != "" {
+			d.names[name] = true
+		}
+	}
+	return d
+}
+
+// ReadDenylist reads a denylist of an entry per line, skipping blank lines
+// and # comments.
+func ReadDenylist(r io.Reader) (*Denylist, error) {
+	var entries []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return NewDenylist(entries), nil
+}
+
+func LoadDenylist(path string) (*Denylist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDenylist(f)
+}
+
+func (d *Denylist) Screen(sender, recipient domain.ScreeningParty) (*domain.ScreeningHit, error) {
+	for _, p := range []struct {
+		role  string
+		party domain.ScreeningParty
+	}{{"sender", sender}, {"recipient", recipient}} {
+		if d.numbers[p.party.Number] {
+			return &domain.ScreeningHit{Party: p.role, List: "denylist", Entry: strconv.FormatInt(p.party.Number.Reveal(), 10)}, nil
+		}
+		if name := normalizeName(p.party.Name); d.names[name] {
+			return &domain.ScreeningHit{Party: p.role, List: "denylist", Entry: name}, nil
+		}
+	}
+	return nil, nil
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
@@ -0,0 +1,417 @@
+package service
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+type TransferStore interface {
+	// SentSince sums what the account transferred out since the given time.
+	SentSince(accountID int, since time.Time) (int64, error)
+	// Transfer, AuthorizeTransfer, TransferBatch, ExecuteTransferRequest and
+	// CreateTransferRequests check the guard again under the lock on the
+	// sender's row.
+	Transfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.Transaction, error)
+	GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error)
+	// Availability is the account's balance less what's held, in pots or
+	// kept as its minimum, see domain.Availability.
+	Availability(accountID int) (domain.Availability, error)
+	CreateQuote(q *domain.TransferQuote) error
+	ClaimQuote(accountID int, id string) (*domain.TransferQuote, error)
+	ReleaseQuote(id string) error
+	AuthorizeTransfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.TransferHold, error)
+	CaptureTransfer(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error)
+	TransferBatch(from *domain.Account, orders []domain.TransferOrder, guard *domain.TransferGuard) ([]*domain.Transaction, error)
+	CreateTransferRequests(reqs []*domain.TransferRequest, jobs []*domain.Job, guard *domain.TransferGuard) error
+	ExecuteTransferRequest(from *domain.Account, id string, guard *domain.TransferGuard) (*domain.TransferRequest, error)
+	HoldTransferRequest(id, reason string) error
+	FindTransferRequest(id string) (*domain.TransferRequest, error)
+	ReviewTransferRequest(id string, release bool, job *domain.Job) (*domain.TransferRequest, error)
+	SagaStore
+}
+
+// ProcessTransferJobType is the job that processes a transfer accepted by
+// TransferService.Accept.
+const ProcessTransferJobType = "process_transfer"
+
+// TransferJob is the payload of a ProcessTransferJobType job.
+type TransferJob struct {
+	TenantID   int    `json:"tenantId"`
+	AccountID  int    `json:"accountId"`
+	TransferID string `json:"transferId"`
+}
+
+// TransferService makes transfers. With a screening provider, every
+// transfer is screened before it's made.
+type TransferService struct {
+	store     TransferStore
+	settings  SettingsSource
+	clock     domain.Clock
+	screening ScreeningProvider
+}
+
+func NewTransferService(store TransferStore, settings SettingsSource, clock domain.Clock, screening ScreeningProvider) *TransferService {
+	return &TransferService{store: store, settings: settings, clock: clock, screening: screening}
+}
+
+// Transfer posts amount from the account to the account numbered to, after
+// checking it against the tenant's limits and, where the tenant requires it,
+// the sender's KYC profile. The daily limit counts from midnight in the
+// sender's time zone. An amount without a currency is in the sender's. A
+// transfer screening flags is held for review and reported as a
+// *domain.HeldError.
+func (s *TransferService) Transfer(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.Transaction, error) {
+	return s.transfer(from, to, amount, false)
+}
+
+// TransferOnce is Transfer failing with a *domain.DuplicateTransferError
+// when the account sent amount to the same recipient within the tenant's
+// DuplicateTransferMinutes, or has such a transfer pending. Clients that
+// send idempotency keys don't need it, it's for the ones that submit twice
+// when a button is tapped twice; callers let the holder confirm and use
+// Transfer. The store looks for the duplicate with the sender's row locked,
+// so two submits racing each other can't both get through.
+func (s *TransferService) TransferOnce(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.Transaction, error) {
+	return s.transfer(from, to, amount, true)
+}
+
+func (s *TransferService) transfer(from *domain.Account, to domain.AccountNumber, amount domain.Money, once bool) (*domain.Transaction, error) {
+	amount, guard, err := s.check(from, amount)
+	if err != nil {
+		return nil, err
+	}
+	if once {
+		s.refuseDuplicates(guard)
+	}
+	hit, err := s.screen(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if hit != nil {
+		req := domain.NewTransferRequest(from, to, amount, s.clock.Now())
+		req.Status, req.ReviewReason = domain.TransferInReview, hit.String()
+		if err := s.store.CreateTransferRequests([]*domain.TransferRequest{req}, []*domain.Job{nil}, guard); err != nil {
+			return nil, err
+		}
+		return nil, &domain.HeldError{TransferID: req.ID}
+	}
+	return s.store.Transfer(from, to, amount, guard)
+}
+
+// Quote makes the checks Transfer and the store would make without posting
+// anything and saves the terms of the transfer for domain.QuoteTTL. A
+// transfer screening flags isn't quoted, it fails with
+// domain.ErrScreeningFlagged.
+func (s *TransferService) Quote(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.TransferQuote, error) {
+	amount, _, err := s.check(from, amount)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := s.store.GetAccountByNumber(to)
+	if err != nil {
+		return nil, err
+	}
+	if recipient.ID == from.ID {
+		return nil, domain.ErrSameAccount
+	}
+	if amount.Currency != from.Balance.Currency || recipient.Balance.Currency != from.Balance.Currency {
+		return nil, domain.ErrCurrencyMismatch
+	}
+	q := domain.NewTransferQuote(from, to, amount, s.clock.Now())
+	available, err := s.store.Availability(from.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := available.Check(q.Total.MinorUnits); err != nil {
+		return nil, err
+	}
+	hit, err := s.screen(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if hit != nil {
+		return nil, domain.ErrScreeningFlagged
+	}
+	return q, s.store.CreateQuote(q)
+}
+
+// ExecuteQuote transfers at the terms of the sender's quote. Limits and funds
+// are checked again, a quote only guarantees the rate and the fee. A quote
+// is executed once at most; claiming it and transferring run as a saga, so
+// a failed transfer releases the quote to be retried.
+func (s *TransferService) ExecuteQuote(from *domain.Account, id string) (*domain.Transaction, error) {
+	var q *domain.TransferQuote
+	var t *domain.Transaction
+	err := RunSaga(s.store, s.clock, "execute_quote", from.ID, []SagaStep{
+		{
+			Name: "claim quote",
+			Do: func() (err error) {
+				q, err = s.store.ClaimQuote(from.ID, id)
+				return err
+			},
+			Undo: func() error { return s.store.ReleaseQuote(q.ID) },
+		},
+		{
+			Name: "transfer",
+			Do: func() (err error) {
+				t, err = s.Transfer(from, q.ToAccount, q.Amount)
+				return err
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Authorize checks a transfer as Transfer does and reserves the amount on
+// the sender's account for domain.HoldTTL instead of making it.
+// An authorization screening flags fails with domain.ErrScreeningFlagged.
+func (s *TransferService) Authorize(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.TransferHold, error) {
+	amount, guard, err := s.check(from, amount)
+	if err != nil {
+		return nil, err
+	}
+	hit, err := s.screen(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if hit != nil {
+		return nil, domain.ErrScreeningFlagged
+	}
+	return s.store.AuthorizeTransfer(from, to, amount, guard)
+}
+
+// Capture makes the transfer of an authorized hold for amount, at most what
+// was authorized. The limits were checked when authorizing, a zero amount
+// captures the whole hold.
+func (s *TransferService) Capture(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error) {
+	if amount.MinorUnits < 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+	return s.store.CaptureTransfer(from, holdID, amount)
+}
+
+// TransferAll makes all the transfers or none. The limits apply to the
+// batch as a whole, each order counts towards the daily limit of the next.
+// A failing order is reported as a *domain.BatchItemError, one screening
+// flags with domain.ErrScreeningFlagged.
+func (s *TransferService) TransferAll(from *domain.Account, orders []domain.TransferOrder) ([]*domain.Transaction, error) {
+	guard, sentToday, err := s.limits(from)
+	if err != nil {
+		return nil, err
+	}
+	checked := make([]domain.TransferOrder, len(orders))
+	for i, o := range orders {
+		amount, err := checkTransfer(guard.Settings, from, o.Amount, sentToday)
+		if err != nil {
+			return nil, &domain.BatchItemError{Index: i, Err: err}
+		}
+		hit, err := s.screen(from, o.ToAccount)
+		if err != nil {
+			return nil, err
+		}
+		if hit != nil {
+			return nil, &domain.BatchItemError{Index: i, Err: domain.ErrScreeningFlagged}
+		}
+		checked[i] = domain.TransferOrder{ToAccount: o.ToAccount, Amount: amount}
+		sentToday += amount.MinorUnits
+	}
+	return s.store.TransferBatch(from, checked, guard)
+}
+
+// TransferEach makes the transfers one by one as Transfer would. The
+// transaction of the order at i is txs[i], or errs[i] tells why it failed.
+func (s *TransferService) TransferEach(from *domain.Account, orders []domain.TransferOrder) (txs []*domain.Transaction, errs []error) {
+	txs = make([]*domain.Transaction, len(orders))
+	errs = make([]error, len(orders))
+	for i, o := range orders {
+		txs[i], errs[i] = s.Transfer(from, o.ToAccount, o.Amount)
+	}
+	return txs, errs
+}
+
+// Accept saves a transfer to be processed by a job in the background. Only
+// the amount is checked here, everything else is when Process runs.
+func (s *TransferService) Accept(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.TransferRequest, error) {
+	return s.accept(from, to, amount, false)
+}
+
+// AcceptOnce is Accept with the duplicate check of TransferOnce.
+func (s *TransferService) AcceptOnce(from *domain.Account, to domain.AccountNumber, amount domain.Money) (*domain.TransferRequest, error) {
+	return s.accept(from, to, amount, true)
+}
+
+func (s *TransferService) accept(from *domain.Account, to domain.AccountNumber, amount domain.Money, once bool) (*domain.TransferRequest, error) {
+	reqs, err := s.acceptAll(from, []domain.TransferOrder{{ToAccount: to, Amount: amount}}, once)
+	var itemErr *domain.BatchItemError
+	if errors.As(err, &itemErr) {
+		return nil, itemErr.Err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reqs[0], nil
+}
+
+// AcceptAll saves the transfers to be processed in the background, all of
+// them or, when an amount is invalid, none. The failing order is reported as
+// a *domain.BatchItemError. Each transfer is processed on its own.
+func (s *TransferService) AcceptAll(from *domain.Account, orders []domain.TransferOrder) ([]*domain.TransferRequest, error) {
+	return s.acceptAll(from, orders, false)
+}
+
+func (s *TransferService) acceptAll(from *domain.Account, orders []domain.TransferOrder, once bool) ([]*domain.TransferRequest, error) {
+	var guard *domain.TransferGuard
+	if once {
+		settings, err := s.settings.Get(from.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		guard = &domain.TransferGuard{Settings: settings}
+		s.refuseDuplicates(guard)
+	}
+	reqs := make([]*domain.TransferRequest, len(orders))
+	jobs := make([]*domain.Job, len(orders))
+	for i, o := range orders {
+		amount := o.Amount
+		if amount.MinorUnits <= 0 {
+			return nil, &domain.BatchItemError{Index: i, Err: domain.ErrInvalidAmount}
+		}
+		if amount.Currency == "" {
+			amount.Currency = from.Balance.Currency
+		}
+		reqs[i] = domain.NewTransferRequest(from, o.ToAccount, amount, s.clock.Now())
+		job, err := domain.NewJob(ProcessTransferJobType, TransferJob{TenantID: from.TenantID, AccountID: from.ID, TransferID: reqs[i].ID}, s.clock.Now())
+		if err != nil {
+			return nil, err
+		}
+		jobs[i] = job
+	}
+	return reqs, s.store.CreateTransferRequests(reqs, jobs, guard)
+}
+
+// Process makes an accepted transfer as Transfer would. A request that
+// isn't pending anymore is returned unchanged, so the job can run twice.
+// One screening flags is put in review, one a person released isn't
+// screened again.
+func (s *TransferService) Process(from *domain.Account, req *domain.TransferRequest) (*domain.TransferRequest, error) {
+	if req.Status != domain.TransferPending {
+		return req, nil
+	}
+	_, guard, err := s.check(from, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if req.ReviewedAt.IsZero() {
+		hit, err := s.screen(from, req.ToAccount)
+		if err != nil {
+			return nil, err
+		}
+		if hit != nil {
+			if err := s.store.HoldTransferRequest(req.ID, hit.String()); err != nil {
+				return nil, err
+			}
+			req.Status, req.ReviewReason = domain.TransferInReview, hit.String()
+			return req, nil
+		}
+	}
+	return s.store.ExecuteTransferRequest(from, req.ID, guard)
+}
+
+// Review releases a transfer held for review to be processed in the
+// background, without screening it again, or rejects it.
+func (s *TransferService) Review(id string, release bool) (*domain.TransferRequest, error) {
+	req, err := s.store.FindTransferRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != domain.TransferInReview {
+		return nil, domain.ErrTransferNotInReview
+	}
+	var job *domain.Job
+	if release {
+		job, err = domain.NewJob(ProcessTransferJobType, TransferJob{TenantID: req.TenantID, AccountID: req.AccountID, TransferID: req.ID}, s.clock.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.store.ReviewTransferRequest(id, release, job)
+}
+
+// screen runs the screening provider, if there's one, over the sender and
+// the recipient. A recipient that doesn't exist is left to the store to
+// refuse.
+func (s *TransferService) screen(from *domain.Account, to domain.AccountNumber) (*domain.ScreeningHit, error) {
+	if s.screening == nil {
+		return nil, nil
+	}
+	recipient := domain.ScreeningParty{Number: to}
+	if account, err := s.store.GetAccountByNumber(to); err == nil {
+		recipient.Name = account.FirstName.Reveal() + " " + account.LastName.Reveal()
+	} else if !errors.Is(err, domain.ErrAccountNotFound) {
+		return nil, err
+	}
+	sender := domain.ScreeningParty{Name: from.FirstName.Reveal() + " " + from.LastName.Reveal(), Number: from.Number}
+	return s.screening.Screen(sender, recipient)
+}
+
+// refuseDuplicates has the store refuse a transfer the sender already made
+// within the tenant's DuplicateTransferMinutes, unless the tenant turned the
+// check off.
+func (s *TransferService) refuseDuplicates(guard *domain.TransferGuard) {
+	if minutes := guard.Settings.DuplicateTransferMinutes; minutes > 0 {
+		guard.DuplicateSince = s.clock.Now().Add(-time.Duration(minutes) * time.Minute)
+	}
+}
+
+// check applies the tenant's rules to a transfer of amount from the account
+// and returns amount in the sender's currency if it came without one, and
+// the guard the store checks the limits with again under its lock.
+func (s *TransferService) check(from *domain.Account, amount domain.Money) (domain.Money, *domain.TransferGuard, error) {
+	guard, sentToday, err := s.limits(from)
+	if err != nil {
+		return amount, nil, err
+	}
+	amount, err = checkTransfer(guard.Settings, from, amount, sentToday)
+	return amount, guard, err
+}
+
+// limits returns the guard of the sender's tenant settings and day, and
+// what the sender has sent since midnight in its time zone.
+func (s *TransferService) limits(from *domain.Account) (*domain.TransferGuard, int64, error) {
+	settings, err := s.settings.Get(from.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	loc, err := time.LoadLocation(from.Timezone)
+	if err != nil {
+		return nil, 0, err
+	}
+	guard := &domain.TransferGuard{Settings: settings, DayStart: domain.StartOfDay(s.clock.Now(), loc)}
+	sentToday, err := s.store.SentSince(from.ID, guard.DayStart)
+	if err != nil {
+		return nil, 0, err
+	}
+	return guard, sentToday, nil
+}
+
+func checkTransfer(settings *domain.TenantSettings, from *domain.Account, amount domain.Money, sentToday int64) (domain.Money, error) {
+	if amount.MinorUnits <= 0 {
+		return amount, domain.ErrInvalidAmount
+	}
+	if amount.Currency == "" {
+		amount.Currency = from.Balance.Currency
+	}
+	if err := settings.CheckTransfer(amount.MinorUnits, sentToday); err != nil {
+		return amount, err
+	}
+	if settings.RequireKYC {
+		if err := from.CheckKYC(); err != nil {
+			return amount, err
+		}
+	}
+	return amount, nil
+}
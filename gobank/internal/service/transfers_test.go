@@ -0,0 +1,542 @@
+package service
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+type settingsFunc func(tenantID int) (*domain.TenantSettings, error)
+
+func (f settingsFunc) Get(tenantID int) (*domain.TenantSettings, error) {
+	return f(tenantID)
+}
+
+type fakeStore struct {
+	since     time.Time
+	sent      int64
+	posted    []domain.Money
+	taken     map[domain.AccountNumber]bool
+	createdAs []domain.AccountNumber
+	accounts  map[domain.AccountNumber]*domain.Account
+	quotes    map[string]*domain.TransferQuote
+	claimed   map[string]bool
+	failWith  error
+	held      int64
+	jobs      []*domain.Job
+	sagas     []domain.Saga
+	requests  []*domain.TransferRequest
+	// similar is the recipient and amount of a transfer made before the
+	// test, transfers the ones Transfer made; a guard finds either after
+	// duplicatesSince
+	similar         domain.TransferOrder
+	transfers       []domain.TransferOrder
+	duplicatesSince time.Time
+	// mu stands in for the lock on the sender's row
+	mu sync.Mutex
+}
+
+func (f *fakeStore) SentSince(accountID int, since time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.since = since
+	return f.sent, nil
+}
+
+// Transfer checks the guard as the store does with the row locked, counting
+// what was posted since SentSince was read.
+func (f *fakeStore) Transfer(from *domain.Account, to domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failWith != nil {
+		return nil, f.failWith
+	}
+	if guard != nil {
+		f.since = guard.DayStart
+		sent := f.sent
+		for _, m := range f.posted {
+			sent += m.MinorUnits
+		}
+		if err := guard.Settings.CheckTransfer(amount.MinorUnits, sent); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.checkDuplicate(to, amount, guard); err != nil {
+		return nil, err
+	}
+	f.posted = append(f.posted, amount)
+	f.transfers = append(f.transfers, domain.TransferOrder{ToAccount: to, Amount: amount})
+	return &domain.Transaction{AccountID: from.ID, Amount: amount}, nil
+}
+
+func (f *fakeStore) checkDuplicate(to domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) error {
+	if guard == nil || guard.DuplicateSince.IsZero() {
+		return nil
+	}
+	f.duplicatesSince = guard.DuplicateSince
+	order := domain.TransferOrder{ToAccount: to, Amount: amount}
+	found := f.similar == order
+	for _, t := range f.transfers {
+		found = found || t == order
+	}
+	for _, req := range f.requests {
+		found = found || (domain.TransferOrder{ToAccount: req.ToAccount, Amount: req.Amount}) == order
+	}
+	if found {
+		return &domain.DuplicateTransferError{Minutes: guard.Settings.DuplicateTransferMinutes}
+	}
+	return nil
+}
+
+func (f *fakeStore) GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error) {
+	if a, ok := f.accounts[number]; ok {
+		return a, nil
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, number)
+}
+
+func (f *fakeStore) Availability(accountID int) (domain.Availability, error) {
+	for _, a := range f.accounts {
+		if a.ID == accountID {
+			return domain.Availability{Balance: a.Balance, Held: f.held}, nil
+		}
+	}
+	return domain.Availability{}, domain.NotFound(domain.ErrAccountNotFound, accountID)
+}
+
+func (f *fakeStore) CreateQuote(q *domain.TransferQuote) error {
+	f.quotes[q.ID] = q
+	return nil
+}
+
+func (f *fakeStore) ClaimQuote(accountID int, id string) (*domain.TransferQuote, error) {
+	q, ok := f.quotes[id]
+	if !ok || q.AccountID != accountID {
+		return nil, domain.NotFound(domain.ErrQuoteNotFound, id)
+	}
+	if f.claimed[id] {
+		return nil, domain.ErrQuoteExpired
+	}
+	f.claimed[id] = true
+	return q, nil
+}
+
+func (f *fakeStore) ReleaseQuote(id string) error {
+	delete(f.claimed, id)
+	return nil
+}
+
+func (f *fakeStore) AuthorizeTransfer(from *domain.Account, to domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.TransferHold, error) {
+	return &domain.TransferHold{AccountID: from.ID, ToAccount: to, Amount: amount, Status: domain.HoldAuthorized}, nil
+}
+
+func (f *fakeStore) CaptureTransfer(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error) {
+	f.posted = append(f.posted, amount)
+	return &domain.Transaction{AccountID: from.ID, Amount: amount}, nil
+}
+
+func (f *fakeStore) TransferBatch(from *domain.Account, orders []domain.TransferOrder, guard *domain.TransferGuard) ([]*domain.Transaction, error) {
+	txs := []*domain.Transaction{}
+	for _, o := range orders {
+		f.posted = append(f.posted, o.Amount)
+		txs = append(txs, &domain.Transaction{AccountID: from.ID, Amount: o.Amount})
+	}
+	return txs, nil
+}
+
+func (f *fakeStore) CreateTransferRequests(reqs []*domain.TransferRequest, jobs []*domain.Job, guard *domain.TransferGuard) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, req := range reqs {
+		if err := f.checkDuplicate(req.ToAccount, req.Amount, guard); err != nil {
+			return err
+		}
+	}
+	f.requests = append(f.requests, reqs...)
+	for _, job := range jobs {
+		if job != nil {
+			f.jobs = append(f.jobs, job)
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) FindTransferRequest(id string) (*domain.TransferRequest, error) {
+	for _, req := range f.requests {
+		if req.ID == id {
+			return req, nil
+		}
+	}
+	return nil, domain.NotFound(domain.ErrTransferRequestNotFound, id)
+}
+
+func (f *fakeStore) HoldTransferRequest(id, reason string) error {
+	return nil
+}
+
+func (f *fakeStore) ReviewTransferRequest(id string, release bool, job *domain.Job) (*domain.TransferRequest, error) {
+	req, err := f.FindTransferRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	req.Status, req.ReviewedAt = domain.TransferPending, time.Now()
+	f.jobs = append(f.jobs, job)
+	return req, nil
+}
+
+func (f *fakeStore) ExecuteTransferRequest(from *domain.Account, id string, guard *domain.TransferGuard) (*domain.TransferRequest, error) {
+	return &domain.TransferRequest{ID: id, Status: domain.TransferCompleted}, nil
+}
+
+func (f *fakeStore) SaveSaga(saga *domain.Saga) error {
+	f.sagas = append(f.sagas, *saga)
+	return nil
+}
+
+func (f *fakeStore) CreateAccount(account *domain.Account) error {
+	f.createdAs = append(f.createdAs, account.Number)
+	if f.taken[account.Number] {
+		return &domain.DuplicateError{Field: "number"}
+	}
+	return nil
+}
+
+func TestTransferChecksLimits(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.MaxTransferAmount = 10000
+		s.DailyTransferLimit = 15000
+		return s, nil
+	})
+	// 03:00 UTC is still the previous day in New York
+	now := time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)
+	store := &fakeStore{sent: 6000}
+	transfers := NewTransferService(store, settings, fixedClock(now), nil)
+	from := &domain.Account{ID: 1, Timezone: "America/New_York", Balance: domain.Money{Currency: "EUR"}}
+
+	_, err := transfers.Transfer(from, 2, domain.Money{MinorUnits: 0})
+	assert.True(t, errors.Is(err, domain.ErrInvalidAmount))
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 10001})
+	assert.True(t, errors.Is(err, domain.ErrTransferLimitExceeded))
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 9001})
+	assert.True(t, errors.Is(err, domain.ErrDailyLimitExceeded))
+	assert.Equal(t, time.Date(2024, 3, 9, 5, 0, 0, 0, time.UTC), store.since.UTC())
+	assert.Empty(t, store.posted)
+
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 9000})
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.Money{{MinorUnits: 9000, Currency: "EUR"}}, store.posted)
+	assert.Equal(t, time.Date(2024, 3, 9, 5, 0, 0, 0, time.UTC), store.since.UTC())
+
+	// SentSince still says 6000, as it would for a transfer racing the one
+	// above; the store's check under the lock counts that one
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 100})
+	assert.True(t, errors.Is(err, domain.ErrDailyLimitExceeded))
+	assert.Len(t, store.posted, 1)
+}
+
+func TestTransferOnce(t *testing.T) {
+	minutes := domain.DefaultDuplicateTransferMinutes
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.DuplicateTransferMinutes = minutes
+		return s, nil
+	})
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{similar: domain.TransferOrder{ToAccount: 2, Amount: domain.Money{MinorUnits: 500, Currency: "EUR"}}}
+	transfers := NewTransferService(store, settings, fixedClock(now), nil)
+	from := &domain.Account{ID: 1, Timezone: "UTC", Balance: domain.Money{Currency: "EUR"}}
+
+	_, err := transfers.TransferOnce(from, 2, domain.Money{MinorUnits: 500})
+	assert.Equal(t, &domain.DuplicateTransferError{Minutes: 2}, err)
+	assert.True(t, errors.Is(err, domain.ErrDuplicateTransfer))
+	assert.Equal(t, now.Add(-2*time.Minute), store.duplicatesSince)
+	_, err = transfers.AcceptOnce(from, 2, domain.Money{MinorUnits: 500})
+	assert.True(t, errors.Is(err, domain.ErrDuplicateTransfer))
+	assert.Empty(t, store.requests)
+
+	_, err = transfers.TransferOnce(from, 3, domain.Money{MinorUnits: 500})
+	assert.Nil(t, err)
+	_, err = transfers.AcceptOnce(from, 2, domain.Money{MinorUnits: 501})
+	assert.Nil(t, err)
+	_, err = transfers.TransferOnce(from, 2, domain.Money{MinorUnits: 501})
+	assert.True(t, errors.Is(err, domain.ErrDuplicateTransfer), "the accepted transfer is pending")
+
+	// confirmed by the holder, or with the check turned off
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 500})
+	assert.Nil(t, err)
+	minutes = 0
+	_, err = transfers.TransferOnce(from, 2, domain.Money{MinorUnits: 500})
+	assert.Nil(t, err)
+}
+
+// A double submit races itself: both requests pass any check made before
+// either posts, only the one under the store's lock tells them apart.
+func TestTransferOnceConcurrent(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		return domain.DefaultTenantSettings(tenantID), nil
+	})
+	store := &fakeStore{}
+	transfers := NewTransferService(store, settings, fixedClock(time.Now()), nil)
+	from := &domain.Account{ID: 1, Timezone: "UTC", Balance: domain.Money{Currency: "EUR"}}
+
+	const submits = 8
+	errs := make(chan error, submits)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := transfers.TransferOnce(from, 2, domain.Money{MinorUnits: 500})
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	made := 0
+	for err := range errs {
+		if err == nil {
+			made++
+			continue
+		}
+		assert.True(t, errors.Is(err, domain.ErrDuplicateTransfer), err)
+	}
+	assert.Equal(t, 1, made)
+	assert.Len(t, store.posted, 1)
+}
+
+func TestOpenDrawsANewNumberWhenTaken(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.Currency = "GBP"
+		return s, nil
+	})
+	store := &fakeStore{taken: map[domain.AccountNumber]bool{42: true}}
+	now := time.Date(2024, 3, 10, 3, 0, 0, 0, time.UTC)
+	account := &domain.Account{Number: 42}
+
+	assert.Nil(t, NewAccountService(store, settings, fixedClock(now)).Open(account))
+	assert.Len(t, store.createdAs, 2)
+	assert.NotEqual(t, domain.AccountNumber(42), account.Number)
+	assert.Equal(t, "GBP", account.Balance.Currency)
+	assert.Equal(t, now, account.CreatedAt)
+}
+
+func TestTransferChecksKYCWhenRequired(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.RequireKYC = tenantID == 2
+		return s, nil
+	})
+	transfers := NewTransferService(&fakeStore{}, settings, fixedClock(time.Now()), nil)
+	from := &domain.Account{ID: 1, TenantID: 1, Timezone: "UTC", Email: "ada@example.com"}
+
+	_, err := transfers.Transfer(from, 2, domain.Money{MinorUnits: 100})
+	assert.Nil(t, err)
+
+	from.TenantID = 2
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 100})
+	var kyc *domain.KYCError
+	assert.True(t, errors.As(err, &kyc))
+	assert.Equal(t, []string{"address", "dateOfBirth"}, kyc.Missing)
+}
+
+func TestQuoteThenExecute(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		return domain.DefaultTenantSettings(tenantID), nil
+	})
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	from := &domain.Account{ID: 1, Number: 10, Timezone: "UTC", Balance: domain.Money{MinorUnits: 5000, Currency: "EUR"}}
+	store := &fakeStore{
+		accounts: map[domain.AccountNumber]*domain.Account{
+			10: from,
+			20: {ID: 2, Number: 20, Balance: domain.Money{Currency: "EUR"}},
+			30: {ID: 3, Number: 30, Balance: domain.Money{Currency: "USD"}},
+		},
+		quotes:  map[string]*domain.TransferQuote{},
+		claimed: map[string]bool{},
+	}
+	transfers := NewTransferService(store, settings, fixedClock(now), nil)
+
+	_, err := transfers.Quote(from, 10, domain.Money{MinorUnits: 100})
+	assert.True(t, errors.Is(err, domain.ErrSameAccount))
+	_, err = transfers.Quote(from, 30, domain.Money{MinorUnits: 100})
+	assert.True(t, errors.Is(err, domain.ErrCurrencyMismatch))
+	_, err = transfers.Quote(from, 20, domain.Money{MinorUnits: 5001})
+	assert.True(t, errors.Is(err, domain.ErrInsufficientFunds))
+	// a hold takes funds the balance still shows
+	store.held = 3000
+	_, err = transfers.Quote(from, 20, domain.Money{MinorUnits: 2500})
+	assert.True(t, errors.Is(err, domain.ErrInsufficientFunds))
+	store.held = 0
+	assert.Empty(t, store.quotes)
+
+	q, err := transfers.Quote(from, 20, domain.Money{MinorUnits: 2500})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", q.Rate)
+	assert.Equal(t, domain.Money{MinorUnits: 2500, Currency: "EUR"}, q.Total)
+	assert.Equal(t, now.Add(domain.QuoteTTL), q.ExpiresAt)
+	assert.Empty(t, store.posted)
+
+	// a failed transfer leaves the quote to be executed later
+	store.failWith = domain.ErrInsufficientFunds
+	_, err = transfers.ExecuteQuote(from, q.ID)
+	assert.True(t, errors.Is(err, domain.ErrInsufficientFunds))
+	store.failWith = nil
+
+	_, err = transfers.ExecuteQuote(from, q.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.Money{{MinorUnits: 2500, Currency: "EUR"}}, store.posted)
+	_, err = transfers.ExecuteQuote(from, q.ID)
+	assert.True(t, errors.Is(err, domain.ErrQuoteExpired))
+}
+
+func TestAuthorizeChecksLimitsCaptureDoesNot(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.MaxTransferAmount = 10000
+		return s, nil
+	})
+	store := &fakeStore{}
+	transfers := NewTransferService(store, settings, fixedClock(time.Now()), nil)
+	from := &domain.Account{ID: 1, Timezone: "UTC", Balance: domain.Money{Currency: "EUR"}}
+
+	_, err := transfers.Authorize(from, 2, domain.Money{MinorUnits: 10001})
+	assert.True(t, errors.Is(err, domain.ErrTransferLimitExceeded))
+	hold, err := transfers.Authorize(from, 2, domain.Money{MinorUnits: 10000})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.Money{MinorUnits: 10000, Currency: "EUR"}, hold.Amount)
+
+	_, err = transfers.Capture(from, hold.ID, domain.Money{MinorUnits: -1})
+	assert.True(t, errors.Is(err, domain.ErrInvalidAmount))
+	_, err = transfers.Capture(from, hold.ID, domain.Money{MinorUnits: 8000})
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.Money{{MinorUnits: 8000}}, store.posted)
+}
+
+func TestTransferAllCountsTheBatchTowardsTheDailyLimit(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		s := domain.DefaultTenantSettings(tenantID)
+		s.DailyTransferLimit = 10000
+		return s, nil
+	})
+	store := &fakeStore{sent: 4000}
+	transfers := NewTransferService(store, settings, fixedClock(time.Now()), nil)
+	from := &domain.Account{ID: 1, Timezone: "UTC", Balance: domain.Money{Currency: "EUR"}}
+	orders := []domain.TransferOrder{
+		{ToAccount: 2, Amount: domain.Money{MinorUnits: 3000}},
+		{ToAccount: 3, Amount: domain.Money{MinorUnits: 3000}},
+		{ToAccount: 4, Amount: domain.Money{MinorUnits: 1}},
+	}
+
+	_, err := transfers.TransferAll(from, orders)
+	var itemErr *domain.BatchItemError
+	assert.True(t, errors.As(err, &itemErr))
+	assert.Equal(t, 2, itemErr.Index)
+	assert.True(t, errors.Is(err, domain.ErrDailyLimitExceeded))
+	assert.Empty(t, store.posted)
+
+	txs, err := transfers.TransferAll(from, orders[:2])
+	assert.Nil(t, err)
+	assert.Len(t, txs, 2)
+	assert.Equal(t, "EUR", store.posted[1].Currency)
+}
+
+func TestAcceptEnqueuesTheTransfer(t *testing.T) {
+	store := &fakeStore{}
+	transfers := NewTransferService(store, nil, fixedClock(time.Now()), nil)
+	from := &domain.Account{ID: 1, TenantID: 3, Balance: domain.Money{Currency: "EUR"}}
+
+	_, err := transfers.Accept(from, 2, domain.Money{})
+	assert.True(t, errors.Is(err, domain.ErrInvalidAmount))
+
+	req, err := transfers.Accept(from, 2, domain.Money{MinorUnits: 500})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.TransferPending, req.Status)
+	assert.Equal(t, "EUR", req.Amount.Currency)
+	assert.Len(t, store.jobs, 1)
+	assert.JSONEq(t, `{"tenantId": 3, "accountId": 1, "transferId": "`+req.ID+`"}`, string(store.jobs[0].Payload))
+}
+
+func TestScreeningHoldsTheTransferForReview(t *testing.T) {
+	settings := settingsFunc(func(tenantID int) (*domain.TenantSettings, error) {
+		return domain.DefaultTenantSettings(tenantID), nil
+	})
+	store := &fakeStore{accounts: map[domain.AccountNumber]*domain.Account{
+		2: {ID: 2, Number: 2, FirstName: "Ivan", LastName: "Petrov", Balance: domain.Money{Currency: "EUR"}},
+	}}
+	denylist, err := ReadDenylist(strings.NewReader("# names\n  ivan   PETROV \n4711\n"))
+	assert.Nil(t, err)
+	transfers := NewTransferService(store, settings, fixedClock(time.Now()), denylist)
+	from := &domain.Account{ID: 1, TenantID: 3, Number: 1, Timezone: "UTC", Balance: domain.Money{MinorUnits: 1000, Currency: "EUR"}}
+
+	_, err = transfers.Transfer(from, 2, domain.Money{MinorUnits: 500})
+	var held *domain.HeldError
+	if assert.ErrorAs(t, err, &held) {
+		req := store.requests[0]
+		assert.Equal(t, held.TransferID, req.ID)
+		assert.Equal(t, domain.TransferInReview, req.Status)
+		assert.Equal(t, `recipient matches denylist entry "ivan petrov"`, req.ReviewReason)
+	}
+	assert.Empty(t, store.posted)
+	assert.Empty(t, store.jobs)
+	_, err = transfers.Authorize(from, 4711, domain.Money{MinorUnits: 500})
+	assert.ErrorIs(t, err, domain.ErrScreeningFlagged)
+	store.accounts[1] = from
+	_, err = transfers.Quote(from, 2, domain.Money{MinorUnits: 500})
+	assert.ErrorIs(t, err, domain.ErrScreeningFlagged)
+
+	req, err := transfers.Review(held.TransferID, true)
+	assert.Nil(t, err)
+	assert.Len(t, store.jobs, 1)
+	_, err = transfers.Review(held.TransferID, true)
+	assert.ErrorIs(t, err, domain.ErrTransferNotInReview)
+	// released, it's not screened again
+	req, err = transfers.Process(from, req)
+	assert.Nil(t, err)
+	assert.Equal(t, domain.TransferCompleted, req.Status)
+}
+
+func TestRunSagaCompensates(t *testing.T) {
+	clock := fixedClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	var undone []string
+	step := func(name string, err, undoErr error) SagaStep {
+		return SagaStep{
+			Name: name,
+			Do:   func() error { return err },
+			Undo: func() error { undone = append(undone, name); return undoErr },
+		}
+	}
+	boom := errors.New("boom")
+
+	store := &fakeStore{}
+	err := RunSaga(store, clock, "test", 1, []SagaStep{step("a", nil, nil), step("b", nil, nil), step("c", boom, nil)})
+	assert.Equal(t, boom, err)
+	assert.Equal(t, []string{"b", "a"}, undone)
+	last := store.sagas[len(store.sagas)-1]
+	assert.Equal(t, domain.SagaCompensated, last.Status)
+	assert.Equal(t, domain.StepFailed, last.Steps[2].Status)
+	assert.Equal(t, domain.StepCompensated, last.Steps[0].Status)
+
+	undone = nil
+	store = &fakeStore{}
+	err = RunSaga(store, clock, "test", 1, []SagaStep{step("a", nil, errors.New("stuck")), step("b", boom, nil)})
+	assert.ErrorIs(t, err, boom)
+	last = store.sagas[len(store.sagas)-1]
+	assert.Equal(t, domain.SagaStuck, last.Status)
+	assert.Equal(t, domain.StepCompensationFailed, last.Steps[0].Status)
+}
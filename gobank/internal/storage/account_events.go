@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+// With account events on, every change of an account row appends an event
+// to the account's stream in the same db transaction, numbered with the
+// version the change gave the row. The stream is append-only: replaying it
+// onto the account's latest snapshot gives the row, see
+// domain.AccountStream, and replaying part of it the account as it was at
+// any time since the stream started. The rows stay the read model the rest
+// of the store reads and locks.
+
+// EnableAccountEvents turns account events on for this store and the stores
+// of its tenants.
+func (s *PostgresStore) EnableAccountEvents() {
+	s.accountEvents = true
+}
+
+// appendAccountEvent appends the event of the change the caller just made to
+// the account row in tx.
+func (s *PostgresStore) appendAccountEvent(tx *sql.Tx, accountID int, eventType string, data any) error {
+	if !s.accountEvents {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`insert into account_event (account_id, version, tenant_id, type, data, created_at)
+							 select id, version, tenant_id, $2, $3, updated_at from account where id = $1`, accountID, eventType, raw)
+	return err
+}
+
+// appendAccountOpened starts the stream of an account created in tx with the
+// row as it was stored.
+func (s *PostgresStore) appendAccountOpened(tx *sql.Tx, accountID int) error {
+	if !s.accountEvents {
+		return nil
+	}
+	rows, err := tx.Query("select "+accountColumns+" from account where id = $1", accountID)
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		rows.Close()
+		return domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	account, err := scanIntoAccount(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	return s.appendAccountEvent(tx, accountID, domain.AccountOpened, account)
+}
+
+// appendAccountClosed ends the stream of an account deleted in tx at
+// version. The stream is kept, like the account's ledger.
+func (s *PostgresStore) appendAccountClosed(tx *sql.Tx, accountID, version int) error {
+	if !s.accountEvents {
+		return nil
+	}
+	_, err := tx.Exec(`insert into account_event (account_id, version, tenant_id, type, data, created_at)
+							 values ($1, $2, $3, $4, '{}', $5)`, accountID, version, s.tenantID, domain.AccountClosed, s.clock.Now().UTC())
+	return err
+}
+
+// AccountEvents returns up to limit events of the account's stream after
+// version after, oldest first.
+func (s *PostgresStore) AccountEvents(accountID, after, limit int) ([]*domain.AccountEvent, error) {
+	rows, err := s.db.Query(`select account_id, version, type, data, created_at from account_event
+							 where account_id = $1 and tenant_id = $2 and version > $3
+							 order by version limit $4`, accountID, s.tenantID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccountEvents(rows)
+}
+
+// AccountStream loads what's needed to replay the account up to until, all
+// of its stream when until is zero: the latest snapshot taken of the state
+// by then and the events after it, or the events from the account's opening
+// without such a snapshot.
+func (s *PostgresStore) AccountStream(accountID int, until time.Time) (*domain.AccountStream, error) {
+	at := sql.NullTime{Time: until, Valid: !until.IsZero()}
+	stream := new(domain.AccountStream)
+	var from int
+	var state []byte
+	err := s.db.QueryRow(`select version, state from account_snapshot
+							 where account_id = $1 and tenant_id = $2 and ($3::timestamptz is null or state_at <= $3)
+							 order by version desc limit 1`, accountID, s.tenantID, at).Scan(&from, &state)
+	if err == sql.ErrNoRows {
+		err = s.db.QueryRow(`select version - 1 from account_event
+								 where account_id = $1 and tenant_id = $2 and type = $3
+								 order by version desc limit 1`, accountID, s.tenantID, domain.AccountOpened).Scan(&from)
+	} else if err == nil {
+		stream.Snapshot = new(domain.Account)
+		err = json.Unmarshal(state, stream.Snapshot)
+	}
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrAccountHistoryNotFound, accountID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`select account_id, version, type, data, created_at from account_event
+							 where account_id = $1 and tenant_id = $2 and version > $3
+							 and ($4::timestamptz is null or created_at <= $4)
+							 order by version`, accountID, s.tenantID, from, at)
+	if err != nil {
+		return nil, err
+	}
+	if stream.Events, err = scanAccountEvents(rows); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// AllAccountsAfter returns up to limit accounts of every tenant with an id
+// above afterID in id order.
+func (s *PostgresStore) AllAccountsAfter(afterID, limit int) ([]*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where id > $1 order by id limit $2", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := []*domain.Account{}
+	for rows.Next() {
+		account, err := scanIntoAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// SaveAccountSnapshot stores the state of an account at its version, so
+// replays start from there.
+func (s *PostgresStore) SaveAccountSnapshot(account *domain.Account) error {
+	state, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`insert into account_snapshot (account_id, version, tenant_id, state, state_at, created_at)
+							 values ($1, $2, $3, $4, $5, $6) on conflict do nothing`,
+		account.ID, account.Version, account.TenantID, state, account.UpdatedAt, s.clock.Now().UTC())
+	return err
+}
+
+func scanAccountEvents(rows *sql.Rows) ([]*domain.AccountEvent, error) {
+	defer rows.Close()
+	events := []*domain.AccountEvent{}
+	for rows.Next() {
+		ev := new(domain.AccountEvent)
+		var data []byte
+		if err := rows.Scan(&ev.AccountID, &ev.Version, &ev.Type, &data, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		ev.Data = data
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+	"slices"
+)
+
+// The sources of timeline entries, in the order entries of the same time
+// are listed newest first.
+const (
+	activityEvent       = 1
+	activityTransaction = 2
+)
+
+// ActivityBefore merges the account's events and transactions into one list,
+// newest first. Each side is cut at the cursor and the limit on its own
+// index before they are merged, so a page reads at most 2*limit rows
+// whatever the size of the history.
+func (s *PostgresStore) ActivityBefore(accountID int, kinds []string, before domain.ActivityCursor, limit int) ([]*domain.Activity, error) {
+	var after any
+	if !before.At.IsZero() {
+		after = before.At
+	}
+	rows, err := s.db.Query(`select * from (
+								(select 1 as source, o.id, o.event_type, o.payload, o.created_at, '', 0::bigint, '', 0::bigint, ''
+								 from outbox o
+								 where o.account_id = $1 and o.event_type = any($3)
+								 and ($4::timestamptz is null or (o.created_at, 1, o.id) < ($4, $5, $6))
+								 order by o.created_at desc, o.id desc limit $8)
+								union all
+								(select 2, t.id, '', null, t.created_at, t.type, t.amount, t.currency, t.counterparty, t.description
+								 from transaction t
+								 where t.account_id = $1 and t.tenant_id = $2 and $7
+								 and ($4::timestamptz is null or (t.created_at, 2, t.id) < ($4, $5, $6))
+								 order by t.created_at desc, t.id desc limit $8)
+							 ) a order by created_at desc, source desc, id desc limit $8`,
+		accountID, s.tenantID, pq.Array(domain.ActivityEventTypes(kinds)), after, before.Source, before.ID,
+		slices.Contains(kinds, domain.ActivityTransaction), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	activity := []*domain.Activity{}
+	for rows.Next() {
+		a := new(domain.Activity)
+		var eventType string
+		var details []byte
+		t := &domain.Transaction{AccountID: accountID, TenantID: s.tenantID}
+		if err := rows.Scan(&a.Source, &a.ID, &eventType, &details, &a.At, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency,
+			&t.Counterparty, &t.Description); err != nil {
+			return nil, err
+		}
+		if a.Source == activityTransaction {
+			a.Type = domain.ActivityTransaction
+			t.ID, t.CreatedAt = int(a.ID), a.At
+			a.Transaction = t
+		} else {
+			a.Type, a.Event, a.Details = domain.ActivityOf(eventType), eventType, details
+		}
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
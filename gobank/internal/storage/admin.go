@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) RecentTransfers(limit int) ([]*domain.Transaction, error) {
+	rows, err := s.db.Query(`select id, account_id, type, amount, currency, counterparty, created_at, description
+							 from transaction
+							 where tenant_id = $1 and type = $2
+							 order by id desc limit $3`, s.tenantID, domain.TransactionTransferOut, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanTransactions(rows)
+}
+
+func (s *PostgresStore) EventsBefore(beforeID int64, limit int) ([]*domain.Event, error) {
+	rows, err := s.db.Query(`select id, event_type, account_id, payload, created_at from outbox
+							 where $1 = 0 or id < $1 order by id desc limit $2`, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []*domain.Event{}
+	for rows.Next() {
+		ev := new(domain.Event)
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.AccountID, &payload, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		ev.Payload = payload
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (s *PostgresStore) AccountsAfter(afterID int, filter AccountFilter, limit int) ([]*domain.Account, error) {
+	query := "select " + accountColumns + " from account where tenant_id = $1 and id > $2"
+	args := []any{s.tenantID, afterID}
+	if !filter.CreatedFrom.IsZero() {
+		args = append(args, filter.CreatedFrom)
+		query += fmt.Sprintf(" and created_at >= $%d", len(args))
+	}
+	if !filter.CreatedTo.IsZero() {
+		args = append(args, filter.CreatedTo)
+		query += fmt.Sprintf(" and created_at < $%d", len(args))
+	}
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		query += fmt.Sprintf(" and currency = $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" order by id limit $%d", len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := []*domain.Account{}
+	for rows.Next() {
+		account, err := scanIntoAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *PostgresStore) EachAccount(fn func(*domain.Account) error) error {
+	rows, err := s.db.Query("select "+accountColumns+" from account where tenant_id = $1 order by id", s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		account, err := scanIntoAccount(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(account); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
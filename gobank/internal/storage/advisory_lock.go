@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// TryLock takes a Postgres session level advisory lock on a dedicated
+// connection. The lock lives as long as that connection, so it is released
+// automatically if this process dies.
+func (s *PostgresStore) TryLock(name string) (*Lock, error) {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "select pg_try_advisory_lock(hashtext($1))", name).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, nil
+	}
+
+	stopPing := make(chan struct{})
+	lock := newLock(func() {
+		close(stopPing)
+		conn.ExecContext(context.Background(), "select pg_advisory_unlock(hashtext($1))", name)
+		conn.Close()
+	})
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopPing:
+				return
+			case <-ticker.C:
+				if err := conn.PingContext(ctx); err != nil {
+					lock.markLost()
+					return
+				}
+			}
+		}
+	}()
+	return lock, nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) DailyTotals(accountID int, since time.Time, loc *time.Location) ([]*domain.DailyTotal, error) {
+	rows, err := s.db.Query(`select to_char(created_at at time zone $3, 'YYYY-MM-DD') as day, currency,
+							 coalesce(sum(amount) filter (where amount > 0), 0),
+							 coalesce(-sum(amount) filter (where amount < 0), 0)
+							 from transaction
+							 where account_id = $1 and tenant_id = $2 and created_at >= $4
+							 group by day, currency order by day`, accountID, s.tenantID, loc.String(), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []*domain.DailyTotal{}
+	for rows.Next() {
+		t := new(domain.DailyTotal)
+		var currency string
+		if err := rows.Scan(&t.Date, &currency, &t.Credits.MinorUnits, &t.Debits.MinorUnits); err != nil {
+			return nil, err
+		}
+		t.Credits.Currency = currency
+		t.Debits.Currency = currency
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateApiKey(key *domain.ApiKey) error {
+	key.TenantID = s.tenantID
+	_, err := s.db.Exec(`insert into api_key (id,account_id,name,secret,created_at,tenant_id,third_party)
+							 select $1,$2,$3,$4,$5,$6,$7 where exists (select 1 from account where id = $2 and tenant_id = $6)`,
+		key.ID, key.AccountID, key.Name, key.Secret, key.CreatedAt, key.TenantID, key.ThirdParty)
+	return mapUniqueViolation(err)
+}
+
+func (s *PostgresStore) GetApiKey(id string) (*domain.ApiKey, error) {
+	key := &domain.ApiKey{ID: id}
+	err := s.db.QueryRow(`select account_id, name, secret, created_at, tenant_id, third_party from api_key
+							 where id = $1 and tenant_id = $2 and revoked_at is null`, id, s.tenantID).
+		Scan(&key.AccountID, &key.Name, &key.Secret, &key.CreatedAt, &key.TenantID, &key.ThirdParty)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrApiKeyNotFound, id)
+	}
+	return key, err
+}
+
+func (s *PostgresStore) ListApiKeys(accountID int) ([]*domain.ApiKey, error) {
+	rows, err := s.db.Query(`select id, name, third_party, created_at, revoked_at from api_key
+							 where account_id = $1 and tenant_id = $2 order by created_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*domain.ApiKey{}
+	for rows.Next() {
+		key := &domain.ApiKey{AccountID: accountID, TenantID: s.tenantID}
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Name, &key.ThirdParty, &key.CreatedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *PostgresStore) RevokeApiKey(accountID int, id string) error {
+	res, err := s.db.Exec(`update api_key set revoked_at = $4
+							 where id = $1 and account_id = $2 and tenant_id = $3 and revoked_at is null`,
+		id, accountID, s.tenantID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrApiKeyNotFound, id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) UseNonce(keyID, nonce string, at time.Time) error {
+	_, err := s.db.Exec("insert into api_nonce (key_id,nonce,seen_at) values ($1,$2,$3)", keyID, nonce, at)
+	if isDuplicate(mapUniqueViolation(err), "nonce") {
+		return fmt.Errorf("%w %s", domain.ErrNonceReplayed, nonce)
+	}
+	return err
+}
+
+func (s *PostgresStore) PurgeNonces(before time.Time) (int64, error) {
+	res, err := s.db.Exec("delete from api_nonce where seen_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	BackupJobType = "backup_database"
+	backupPrefix  = "gobank-"
+)
+
+// Backuper dumps the database with pg_dump into a BlobStore and restores it
+// with pg_restore. Both binaries must be on the PATH and match the server's
+// major version.
+type Backuper struct {
+	dsn    string
+	blobs  BlobStore
+	keep   int
+	logger *slog.Logger
+}
+
+func NewBackuper(dsn string, blobs BlobStore, keep int, logger *slog.Logger) *Backuper {
+	return &Backuper{dsn: dsn, blobs: blobs, keep: keep, logger: logger}
+}
+
+func backupKey(now time.Time) string {
+	return backupPrefix + now.UTC().Format("20060102T150405Z") + ".dump"
+}
+
+// Backup streams a custom format dump into the blob store and returns its key.
+func (b *Backuper) Backup(now time.Time) (string, error) {
+	key := backupKey(now)
+	var stderr bytes.Buffer
+	cmd, err := pgCommand("pg_dump", b.dsn, "--format=custom", "--no-owner")
+	if err != nil {
+		return "", err
+	}
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	if err := b.blobs.Put(key, out); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return "", err
+	}
+	if err := cmd.Wait(); err != nil {
+		// the dump is incomplete, don't leave it around as a restore candidate
+		b.blobs.Delete(key)
+		return "", fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	b.logger.Info("database backed up", "key", key)
+	return key, nil
+}
+
+// Restore replaces the schema and data with the backup under key. The server
+// should be stopped while it runs.
+func (b *Backuper) Restore(key string) error {
+	in, err := b.blobs.Get(key)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	var stderr bytes.Buffer
+	cmd, err := pgCommand("pg_restore", b.dsn, "--clean", "--if-exists", "--no-owner", "--single-transaction")
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = in
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	b.logger.Info("database restored", "key", key)
+	return nil
+}
+
+// pgCommand returns the command running a PostgreSQL client binary on the
+// database of dsn. The password goes in PGPASSWORD rather than the
+// arguments, which anyone on the host can read in the process list.
+func pgCommand(name, dsn string, args ...string) (*exec.Cmd, error) {
+	dsn, password, err := splitPassword(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, append(args, "--dbname="+dsn)...)
+	if password != "" {
+		cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+	}
+	return cmd, nil
+}
+
+var dsnPassword = regexp.MustCompile(`(^|\s)password\s*=\s*('(?:[^'\\]|\\.)*'|\S*)`)
+
+// splitPassword takes the password out of a URL or key=value dsn and
+// returns the key=value dsn left.
+func splitPassword(dsn string) (string, string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			// the error quotes the URL, password and all
+			return "", "", errors.New("POSTGRES_URL isn't a valid URL")
+		}
+	}
+	m := dsnPassword.FindStringSubmatchIndex(dsn)
+	if m == nil {
+		return dsn, "", nil
+	}
+	password := dsn[m[4]:m[5]]
+	if strings.HasPrefix(password, "'") && strings.HasSuffix(password, "'") && len(password) > 1 {
+		password = strings.NewReplacer(`\'`, "'", `\\`, `\`).Replace(password[1 : len(password)-1])
+	}
+	return strings.TrimSpace(dsn[:m[2]] + dsn[m[1]:]), password, nil
+}
+
+// Prune deletes all but the newest keep backups, keep 0 keeps everything.
+func (b *Backuper) Prune() error {
+	if b.keep <= 0 {
+		return nil
+	}
+	keys, err := b.blobs.List(backupPrefix)
+	if err != nil {
+		return err
+	}
+	for len(keys) > b.keep {
+		if err := b.blobs.Delete(keys[0]); err != nil {
+			return err
+		}
+		b.logger.Info("deleted old backup", "key", keys[0])
+		keys = keys[1:]
+	}
+	return nil
+}
+
+func (b *Backuper) HandleJob(job *domain.Job) error {
+	if _, err := b.Backup(time.Now()); err != nil {
+		return err
+	}
+	return b.Prune()
+}
+
+// RunBackupCommand implements the backup and restore subcommands.
+func RunBackupCommand(b *Backuper, args []string, stdout io.Writer) error {
+	switch args[0] {
+	case "backup":
+		key, err := b.Backup(time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, key)
+		return nil
+	case "restore":
+		if len(args) != 2 {
+			keys, err := b.blobs.List(backupPrefix)
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("usage: gobank restore <key>, available backups: %s", strings.Join(keys, ", "))
+		}
+		return b.Restore(args[1])
+	default:
+		return fmt.Errorf("unknown command %s", args[0])
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirBlobStore(t *testing.T) {
+	blobs, err := NewDirBlobStore(t.TempDir())
+	assert.Nil(t, err)
+
+	assert.Nil(t, blobs.Put("a.dump", strings.NewReader("hello")))
+	r, err := blobs.Get("a.dump")
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(body))
+
+	for _, key := range []string{"", "../a.dump", "x/y", ".hidden"} {
+		assert.NotNil(t, blobs.Put(key, strings.NewReader("")), key)
+	}
+	keys, err := blobs.List("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.dump"}, keys)
+
+	day, err := blobs.Partition("transactions/date=2024-05-01")
+	assert.Nil(t, err)
+	assert.Nil(t, day.Put("part-1.parquet", strings.NewReader("")))
+	keys, _ = blobs.List("")
+	assert.Equal(t, []string{"a.dump"}, keys)
+	for _, path := range []string{"", "a//b", "../a", "a/.hidden"} {
+		_, err := blobs.Partition(path)
+		assert.NotNil(t, err, path)
+	}
+}
+
+func TestBackuperPrune(t *testing.T) {
+	blobs, _ := NewDirBlobStore(t.TempDir())
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		blobs.Put(backupKey(start.AddDate(0, 0, i)), strings.NewReader("dump"))
+	}
+	blobs.Put("notes.txt", strings.NewReader("keep me"))
+
+	b := NewBackuper("", blobs, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Nil(t, b.Prune())
+	keys, _ := blobs.List("")
+	assert.Equal(t, []string{"gobank-20240303T020000Z.dump", "gobank-20240304T020000Z.dump", "notes.txt"}, keys)
+}
+
+func TestPgCommandPassesThePasswordInTheEnvironment(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"postgres://bob:s3cr%40t@db:5432/gobank?sslmode=disable": "s3cr@t",
+		"host=db user=bob password=s3cret dbname=gobank":         "s3cret",
+		`password = 'it\'s a secret' host=db`:                    "it's a secret",
+		"host=db user=bob dbname=gobank":                         "",
+	} {
+		cmd, err := pgCommand("pg_dump", dsn, "--format=custom")
+		if !assert.Nil(t, err, dsn) {
+			continue
+		}
+		args := strings.Join(cmd.Args, " ")
+		assert.Contains(t, args, "--dbname=", dsn)
+		assert.NotContains(t, args, "password", dsn)
+		if want == "" {
+			assert.Nil(t, cmd.Env, dsn)
+			continue
+		}
+		assert.NotContains(t, args, want, dsn)
+		assert.Contains(t, cmd.Env, "PGPASSWORD="+want, dsn)
+	}
+
+	_, err := pgCommand("pg_dump", "postgres://bob:s3cret@db:port/gobank")
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "s3cret")
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+// availability reads what of the account's balance is spoken for: the
+// authorized holds other than exceptHold, the pots, and the account's
+// minimum balance or else its tenant's. Callers that go on to spend hold the
+// account row lock.
+func (s *PostgresStore) availability(q rowQuerier, accountID int, exceptHold string) (domain.Availability, error) {
+	var a domain.Availability
+	err := q.QueryRow(`select a.balance, a.currency,
+							 coalesce((select sum(h.amount) from transfer_hold h
+								where h.account_id = a.id and h.status = 'authorized' and h.expires_at > $3 and h.id::text <> $4), 0),
+							 coalesce((select sum(p.amount) from pot p where p.account_id = a.id), 0),
+							 coalesce(a.minimum_balance, t.minimum_balance, 0)
+							 from account a left join tenant_settings t on t.tenant_id = a.tenant_id
+							 where a.id = $1 and a.tenant_id = $2`,
+		accountID, s.tenantID, s.clock.Now().UTC(), exceptHold).
+		Scan(&a.Balance.MinorUnits, &a.Balance.Currency, &a.Held, &a.Protected, &a.Minimum)
+	if err == sql.ErrNoRows {
+		return a, domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	return a, err
+}
+
+func (s *PostgresStore) Availability(accountID int) (domain.Availability, error) {
+	return s.availability(s.db, accountID, "")
+}
+
+// SetMinimumBalance sets the balance transfers can't take the account below,
+// nil leaves it to the tenant's settings.
+func (s *PostgresStore) SetMinimumBalance(accountID int, minimum *int64) error {
+	res, err := s.db.Exec("update account set minimum_balance = $3 where id = $1 and tenant_id = $2", accountID, s.tenantID, minimum)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListPots(accountID int) ([]*domain.Pot, error) {
+	rows, err := s.db.Query(`select id, name, amount, currency, created_at, updated_at from pot
+							 where account_id = $1 and tenant_id = $2 order by created_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pots := []*domain.Pot{}
+	for rows.Next() {
+		p := &domain.Pot{AccountID: accountID}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Amount.MinorUnits, &p.Amount.Currency, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		pots = append(pots, p)
+	}
+	return pots, rows.Err()
+}
+
+// CreatePot puts the pot's amount aside if the available balance covers it.
+func (s *PostgresStore) CreatePot(p *domain.Pot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.lockAvailable(tx, p.AccountID, p.Amount.MinorUnits); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`insert into pot (id,tenant_id,account_id,name,amount,currency,created_at,updated_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8)`,
+		p.ID, s.tenantID, p.AccountID, p.Name, p.Amount.MinorUnits, p.Amount.Currency, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePot renames the pot and sets its amount. Putting more aside needs
+// the available balance to cover the difference, taking it out doesn't. The
+// pot row is locked before its amount is read, so updates racing each other
+// can't both put aside what only one of them was checked for.
+func (s *PostgresStore) UpdatePot(p *domain.Pot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int64
+	err = tx.QueryRow("select amount from pot where id = $1 and account_id = $2 and tenant_id = $3 for update",
+		p.ID, p.AccountID, s.tenantID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return domain.NotFound(domain.ErrPotNotFound, p.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if more := p.Amount.MinorUnits - current; more > 0 {
+		if err := s.lockAvailable(tx, p.AccountID, more); err != nil {
+			return err
+		}
+	}
+	err = tx.QueryRow(`update pot set name = $2, amount = $3, updated_at = $4 where id = $1
+							 returning currency, created_at`, p.ID, p.Name, p.Amount.MinorUnits, p.UpdatedAt).
+		Scan(&p.Amount.Currency, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetPot(accountID int, id string) (*domain.Pot, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	p := &domain.Pot{ID: id, AccountID: accountID}
+	err := s.db.QueryRow(`select name, amount, currency, created_at, updated_at from pot
+							 where id = $1 and account_id = $2 and tenant_id = $3`, id, accountID, s.tenantID).
+		Scan(&p.Name, &p.Amount.MinorUnits, &p.Amount.Currency, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	return p, err
+}
+
+// DeletePot releases the pot's amount into the available balance.
+func (s *PostgresStore) DeletePot(accountID int, id string) error {
+	if !domain.IsUUID(id) {
+		return domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	res, err := s.db.Exec("delete from pot where id = $1 and account_id = $2 and tenant_id = $3", id, accountID, s.tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrPotNotFound, id)
+	}
+	return nil
+}
+
+// lockAvailable locks the account row, as transfers do, and checks the
+// available balance covers amount.
+func (s *PostgresStore) lockAvailable(tx *sql.Tx, accountID int, amount int64) error {
+	var id int
+	err := tx.QueryRow("select id from account where id = $1 and tenant_id = $2 for update", accountID, s.tenantID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	if err != nil {
+		return err
+	}
+	a, err := s.availability(tx, accountID, "")
+	if err != nil {
+		return err
+	}
+	return a.Check(amount)
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+const batchFileColumns = `id, tenant_id, name, format, sha256, status, accepted, rejected, ack, received_at, processed_at`
+
+func scanBatchFile(row interface{ Scan(...any) error }) (*domain.BatchFile, error) {
+	f := &domain.BatchFile{}
+	var processedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.TenantID, &f.Name, &f.Format, &f.SHA256, &f.Status, &f.Accepted, &f.Rejected, &f.Ack, &f.ReceivedAt, &processedAt)
+	f.ProcessedAt = nullTime(processedAt)
+	return f, err
+}
+
+func (s *PostgresStore) ClaimBatchFile(f *domain.BatchFile) (*domain.BatchFile, bool, error) {
+	f.TenantID = s.tenantID
+	res, err := s.db.Exec(`insert into batch_file (id,tenant_id,name,format,sha256,status,received_at) values ($1,$2,$3,$4,$5,$6,$7)
+							 on conflict (tenant_id, name, sha256) do nothing`,
+		f.ID, f.TenantID, f.Name, f.Format, f.SHA256, f.Status, f.ReceivedAt)
+	if err != nil {
+		return nil, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return f, true, nil
+	}
+	existing, err := scanBatchFile(s.db.QueryRow(`select `+batchFileColumns+` from batch_file where tenant_id = $1 and name = $2 and sha256 = $3`, s.tenantID, f.Name, f.SHA256))
+	return existing, false, err
+}
+
+func (s *PostgresStore) CompleteBatchFile(f *domain.BatchFile) error {
+	_, err := s.db.Exec("update batch_file set status = $2, accepted = $3, rejected = $4, ack = $5, processed_at = $6 where id = $1",
+		f.ID, f.Status, f.Accepted, f.Rejected, f.Ack, f.ProcessedAt)
+	return err
+}
+
+func (s *PostgresStore) ListBatchFiles(limit int) ([]*domain.BatchFile, error) {
+	rows, err := s.db.Query(`select `+batchFileColumns+` from batch_file where tenant_id = $1 order by received_at desc limit $2`, s.tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []*domain.BatchFile{}
+	for rows.Next() {
+		f, err := scanBatchFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
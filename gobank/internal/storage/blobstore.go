@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BlobStore keeps opaque objects, such as database backups, under flat keys.
+// DirBlobStore is the only implementation, an object storage bucket mounted
+// as a directory works the same way.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// List returns the keys starting with prefix in lexical order.
+	List(prefix string) ([]string, error)
+}
+
+// PartitionedBlobStore groups objects under paths of partitions, like the
+// directories of a hive-style partitioned table: transactions/date=2024-05-01.
+type PartitionedBlobStore interface {
+	BlobStore
+	Partition(path string) (BlobStore, error)
+}
+
+type DirBlobStore struct {
+	dir string
+}
+
+func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DirBlobStore{dir: dir}, nil
+}
+
+func (d *DirBlobStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(d.dir, key), nil
+}
+
+// Partition returns the store of the subdirectory path, a slash separated
+// path of partitions.
+func (d *DirBlobStore) Partition(path string) (BlobStore, error) {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") || strings.Contains(segment, `\`) {
+			return nil, fmt.Errorf("invalid partition %q", path)
+		}
+	}
+	return NewDirBlobStore(filepath.Join(d.dir, filepath.FromSlash(path)))
+}
+
+// Put writes to a temporary file first so that a failed or interrupted
+// upload never shows up under key.
+func (d *DirBlobStore) Put(key string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *DirBlobStore) Get(key string) (io.ReadCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (d *DirBlobStore) Delete(key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (d *DirBlobStore) List(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") && strings.HasPrefix(e.Name(), prefix) {
+			keys = append(keys, e.Name())
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
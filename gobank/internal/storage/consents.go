@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+	"time"
+)
+
+// CreateConsent saves the consent for one of the account's third-party keys
+// and records that it was granted.
+func (s *PostgresStore) CreateConsent(c *domain.Consent) error {
+	c.TenantID = s.tenantID
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into consent (id,tenant_id,account_id,api_key_id,purpose,scopes,status,created_at,expires_at)
+							 select $1,$2,$3,$4,$5,$6,$7,$8,$9 where exists (select 1 from api_key
+							 where id = $4 and account_id = $3 and tenant_id = $2 and third_party and revoked_at is null)`,
+		c.ID, c.TenantID, c.AccountID, c.ApiKeyID, c.Purpose, pq.Array(c.Scopes), c.Status, c.CreatedAt, c.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrApiKeyNotFound, c.ApiKeyID)
+	}
+	if err := insertConsentEvent(tx, c, domain.EventConsentGranted, s.clock.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) ListConsents(accountID int) ([]*domain.Consent, error) {
+	rows, err := s.db.Query(consentQuery+" where account_id = $1 and tenant_id = $2 order by created_at desc", accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanConsents(rows)
+}
+
+func (s *PostgresStore) ActiveConsents(apiKeyID string) ([]*domain.Consent, error) {
+	rows, err := s.db.Query(consentQuery+" where api_key_id = $1 and tenant_id = $2 and status = 'active' and expires_at > $3",
+		apiKeyID, s.tenantID, s.clock.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return s.scanConsents(rows)
+}
+
+func (s *PostgresStore) RevokeConsent(accountID int, id string) (*domain.Consent, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrConsentNotFound, id)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`update consent set status = $4, revoked_at = $5
+							 where id = $1 and account_id = $2 and tenant_id = $3 and status = 'active'
+							 returning id, account_id, api_key_id, purpose, scopes, status, created_at, expires_at, revoked_at`,
+		id, accountID, s.tenantID, domain.ConsentRevoked, s.clock.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.scanConsents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(consents) == 0 {
+		return nil, domain.NotFound(domain.ErrConsentNotFound, id)
+	}
+	if err := insertConsentEvent(tx, consents[0], domain.EventConsentRevoked, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	return consents[0], tx.Commit()
+}
+
+func insertConsentEvent(tx *sql.Tx, c *domain.Consent, eventType string, now time.Time) error {
+	ev, err := domain.NewEvent(eventType, c.AccountID, map[string]any{
+		"consentId": c.ID,
+		"apiKeyId":  c.ApiKeyID,
+		"purpose":   c.Purpose,
+		"scopes":    c.Scopes,
+		"expiresAt": c.ExpiresAt,
+	}, now)
+	if err != nil {
+		return err
+	}
+	return insertOutboxEvent(tx, ev)
+}
+
+const consentQuery = `select id, account_id, api_key_id, purpose, scopes, status, created_at, expires_at, revoked_at from consent`
+
+func (s *PostgresStore) scanConsents(rows *sql.Rows) ([]*domain.Consent, error) {
+	defer rows.Close()
+	consents := []*domain.Consent{}
+	now := s.clock.Now()
+	for rows.Next() {
+		c := &domain.Consent{TenantID: s.tenantID}
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.AccountID, &c.ApiKeyID, &c.Purpose, pq.Array(&c.Scopes), &c.Status, &c.CreatedAt, &c.ExpiresAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		c.RevokedAt = nullTime(revokedAt)
+		if c.Status == domain.ConsentActive && !now.Before(c.ExpiresAt) {
+			c.Status = domain.ConsentExpired
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/lib/pq"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MetricsSink is where the store records its statement metrics.
+type MetricsSink interface {
+	Help(name, help string)
+	Inc(name string, labelPairs ...string)
+	Observe(name string, d time.Duration, labelPairs ...string)
+}
+
+// instrumentedConnector wraps the pq connector so every statement is timed,
+// recorded in the db_statement_duration_seconds histogram and logged when it
+// is slower than the configured threshold.
+type instrumentedConnector struct {
+	driver.Connector
+	metrics   MetricsSink
+	logger    *slog.Logger
+	threshold func() time.Duration
+}
+
+func openInstrumentedDB(dsn string, metrics MetricsSink, logger *slog.Logger, threshold func() time.Duration) (*sql.DB, error) {
+	connector, err := pq.NewConnector(withUTCSession(dsn))
+	if err != nil {
+		return nil, err
+	}
+	metrics.Help("db_statement_duration_seconds", "Latency of SQL statements by statement.")
+	metrics.Help("db_slow_statements_total", "SQL statements slower than the slow query threshold.")
+	return sql.OpenDB(&instrumentedConnector{
+		Connector: connector,
+		metrics:   metrics,
+		logger:    logger,
+		threshold: threshold,
+	}), nil
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, c: c}, nil
+}
+
+type instrumentedConn struct {
+	driver.Conn
+	c *instrumentedConnector
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	c.c.record(query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	c.c.record(query, args, time.Since(start), err)
+	return res, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *instrumentedConnector) record(query string, args []driver.NamedValue, d time.Duration, err error) {
+	statement := normalizeStatement(query)
+	c.metrics.Observe("db_statement_duration_seconds", d, "statement", statement)
+	if threshold := c.threshold(); threshold > 0 && d >= threshold {
+		c.metrics.Inc("db_slow_statements_total", "statement", statement)
+		c.logger.Warn("slow query",
+			"statement", statement,
+			"args", sanitizeArgs(args),
+			"duration", d,
+			"error", err)
+	}
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// normalizeStatement collapses whitespace and shortens the statement so it
+// can be used as a metric label. Queries only use placeholders so the label
+// set stays bounded.
+func normalizeStatement(query string) string {
+	s := strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+	if len(s) > 120 {
+		s = s[:120]
+	}
+	return s
+}
+
+// sanitizeArgs describes query arguments without exposing their values,
+// which may contain names, account numbers or password hashes.
+func sanitizeArgs(args []driver.NamedValue) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.Value.(type) {
+		case nil:
+			out[i] = "null"
+		case string:
+			out[i] = fmt.Sprintf("string(%d)", len(v))
+		case []byte:
+			out[i] = fmt.Sprintf("bytes(%d)", len(v))
+		default:
+			out[i] = fmt.Sprintf("%T", v)
+		}
+	}
+	return out
+}
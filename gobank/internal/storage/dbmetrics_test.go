@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSanitizeArgs(t *testing.T) {
+	args := []driver.NamedValue{{Value: "anthony"}, {Value: int64(42)}, {Value: nil}}
+	assert.Equal(t, []string{"string(7)", "int64", "null"}, sanitizeArgs(args))
+}
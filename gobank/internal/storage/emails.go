@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"strings"
+	"time"
+)
+
+const SendEmailJobType = "send_email"
+
+// EmailJob is the payload of a SendEmailJobType job.
+type EmailJob struct {
+	EmailID string `json:"emailId"`
+}
+
+// QueueEmail saves the email with the job that sends it, in one db
+// transaction. An email whose dedupe key was queued before adds nothing and
+// reports false.
+func (s *PostgresStore) QueueEmail(e *domain.Email) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into email_message
+							 (id,tenant_id,account_id,kind,dedupe_key,recipient,subject,body_text,body_html,status,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+							 on conflict (dedupe_key) do nothing`,
+		e.ID, e.TenantID, e.AccountID, e.Kind, e.DedupeKey, e.To.Reveal(), e.Subject, e.Text, e.HTML, e.Status, e.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	job, err := domain.NewJob(SendEmailJobType, EmailJob{EmailID: e.ID}, s.clock.Now())
+	if err != nil {
+		return false, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetEmail returns an email of any tenant.
+func (s *PostgresStore) GetEmail(id string) (*domain.Email, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrEmailNotFound, id)
+	}
+	e := &domain.Email{ID: id}
+	var providerID sql.NullString
+	var sentAt sql.NullTime
+	err := s.db.QueryRow(`select tenant_id, account_id, kind, dedupe_key, recipient, subject, body_text, body_html,
+							 status, attempts, last_error, provider_id, created_at, sent_at
+							 from email_message where id = $1`, id).
+		Scan(&e.TenantID, &e.AccountID, &e.Kind, &e.DedupeKey, &e.To, &e.Subject, &e.Text, &e.HTML,
+			&e.Status, &e.Attempts, &e.LastError, &providerID, &e.CreatedAt, &sentAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrEmailNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.ProviderID = providerID.String
+	e.SentAt = nullTime(sentAt)
+	return e, nil
+}
+
+// RecordEmailAttempt saves the status, attempt count, error and provider id
+// an attempt left the email with.
+func (s *PostgresStore) RecordEmailAttempt(e *domain.Email) error {
+	_, err := s.db.Exec(`update email_message set status = $2, attempts = $3, last_error = $4, provider_id = nullif($5, ''), sent_at = $6
+							 where id = $1`,
+		e.ID, e.Status, e.Attempts, e.LastError, e.ProviderID, e.SentAt)
+	return err
+}
+
+// SuppressEmail puts the address on the tenant's suppression list, an
+// address that is on it already keeps its reason.
+func (s *PostgresStore) SuppressEmail(sup *domain.EmailSuppression) error {
+	_, err := s.db.Exec(`insert into email_suppression (tenant_id,address,reason,created_at) values ($1,$2,$3,$4)
+							 on conflict (tenant_id, address) do nothing`,
+		s.tenantID, strings.ToLower(sup.Address.Reveal()), sup.Reason, sup.CreatedAt)
+	return err
+}
+
+// SuppressBouncedEmail puts the recipient of the message the provider knows
+// as providerID on its tenant's suppression list. Messages that weren't
+// sent from here are ignored.
+func (s *PostgresStore) SuppressBouncedEmail(providerID string, address domain.PII, reason string, at time.Time) error {
+	_, err := s.db.Exec(`insert into email_suppression (tenant_id,address,reason,created_at)
+							 select tenant_id, lower($2), $3, $4 from email_message where provider_id = $1 limit 1
+							 on conflict (tenant_id, address) do nothing`,
+		providerID, address.Reveal(), reason, at)
+	return err
+}
+
+func (s *PostgresStore) EmailSuppressed(address domain.PII) (bool, error) {
+	var suppressed bool
+	err := s.db.QueryRow("select exists(select 1 from email_suppression where tenant_id = $1 and address = lower($2))",
+		s.tenantID, address.Reveal()).Scan(&suppressed)
+	return suppressed, err
+}
+
+func (s *PostgresStore) ListEmailSuppressions() ([]*domain.EmailSuppression, error) {
+	rows, err := s.db.Query("select address, reason, created_at from email_suppression where tenant_id = $1 order by created_at desc", s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	suppressions := []*domain.EmailSuppression{}
+	for rows.Next() {
+		sup := &domain.EmailSuppression{}
+		if err := rows.Scan(&sup.Address, &sup.Reason, &sup.CreatedAt); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, sup)
+	}
+	return suppressions, rows.Err()
+}
+
+func (s *PostgresStore) DeleteEmailSuppression(address domain.PII) error {
+	res, err := s.db.Exec("delete from email_suppression where tenant_id = $1 and address = lower($2)", s.tenantID, address.Reveal())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrSuppressionNotFound, address)
+	}
+	return nil
+}
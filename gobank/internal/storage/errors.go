@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"errors"
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+)
+
+func isDuplicate(err error, field string) bool {
+	var dup *domain.DuplicateError
+	return errors.As(err, &dup) && dup.Field == field
+}
+
+// uniqueConstraintFields maps unique constraints and indexes to the API field
+// they protect.
+var uniqueConstraintFields = map[string]string{
+	"account_tenant_number_idx": "number",
+	"account_tenant_email_idx":  "email",
+	"account_tenant_phone_idx":  "phone",
+	"account_uuid_idx":          "uuid",
+	"account_iban_idx":          "iban",
+	"tenant_slug_key":           "slug",
+	"api_nonce_pkey":            "nonce",
+}
+
+// mapUniqueViolation turns Postgres unique violations (23505) into a
+// domain.DuplicateError and returns any other error unchanged.
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+	field, ok := uniqueConstraintFields[pqErr.Constraint]
+	if !ok {
+		field = pqErr.Constraint
+	}
+	return &domain.DuplicateError{Field: field}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMapUniqueViolation(t *testing.T) {
+	err := mapUniqueViolation(fmt.Errorf("insert account: %w", &pq.Error{Code: "23505", Constraint: "account_tenant_email_idx"}))
+	assert.Equal(t, &domain.DuplicateError{Field: "email"}, err)
+	assert.True(t, errors.Is(err, domain.ErrDuplicateEmail))
+	assert.True(t, isDuplicate(err, "email"))
+	assert.False(t, isDuplicate(err, "number"))
+
+	// an IBAN is made of the number, so it's the number that's taken
+	assert.True(t, errors.Is(mapUniqueViolation(&pq.Error{Code: "23505", Constraint: "account_iban_idx"}), domain.ErrDuplicateNumber))
+
+	// a constraint without an API field is still a conflict, named as is
+	assert.Equal(t, &domain.DuplicateError{Field: "batch_file_name_sha256_idx"}, mapUniqueViolation(&pq.Error{Code: "23505", Constraint: "batch_file_name_sha256_idx"}))
+
+	other := &pq.Error{Code: "23503", Constraint: "account_tenant_id_fkey"}
+	assert.Equal(t, error(other), mapUniqueViolation(other))
+	assert.Nil(t, mapUniqueViolation(nil))
+}
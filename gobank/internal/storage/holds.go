@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) AuthorizeTransfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.TransferHold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockTransfer(tx, from.ID, toNumber, amount, "", guard); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	hold := &domain.TransferHold{
+		ID:        domain.NewUUID(),
+		AccountID: from.ID,
+		ToAccount: toNumber,
+		Amount:    amount,
+		Status:    domain.HoldAuthorized,
+		CreatedAt: now,
+		ExpiresAt: now.Add(domain.HoldTTL),
+	}
+	_, err = tx.Exec(`insert into transfer_hold (id,tenant_id,account_id,to_number,amount,currency,status,created_at,expires_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		hold.ID, s.tenantID, hold.AccountID, hold.ToAccount, amount.MinorUnits, amount.Currency, hold.Status, hold.CreatedAt, hold.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := domain.NewEvent(domain.EventTransferAuthorized, from.ID, map[string]any{
+		"holdId":   hold.ID,
+		"from":     from.Number,
+		"to":       toNumber,
+		"amount":   amount.MinorUnits,
+		"currency": amount.Currency,
+	}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return nil, err
+	}
+	return hold, tx.Commit()
+}
+
+func (s *PostgresStore) CaptureTransfer(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := s.lockHold(tx, from.ID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != domain.HoldAuthorized {
+		return nil, domain.ErrHoldClosed
+	}
+	if amount.MinorUnits == 0 {
+		amount = hold.Amount
+	}
+	if amount.Currency == "" {
+		amount.Currency = hold.Amount.Currency
+	}
+	if amount.MinorUnits < 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+	if amount.Currency != hold.Amount.Currency {
+		return nil, domain.ErrCurrencyMismatch
+	}
+	if amount.MinorUnits > hold.Amount.MinorUnits {
+		return nil, domain.ErrCaptureExceedsHold
+	}
+	toID, err := s.lockTransfer(tx, from.ID, hold.ToAccount, amount, hold.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.postTransfer(tx, from, toID, hold.ToAccount, amount)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec("update transfer_hold set status = $2, captured = $3, transaction_id = $4, settled_at = $5 where id = $1",
+		hold.ID, domain.HoldCaptured, amount.MinorUnits, out.ID, out.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+func (s *PostgresStore) VoidTransfer(accountID int, holdID string) (*domain.TransferHold, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	hold, err := s.lockHold(tx, accountID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != domain.HoldAuthorized {
+		return nil, domain.ErrHoldClosed
+	}
+	now := s.clock.Now().UTC()
+	if _, err := tx.Exec("update transfer_hold set status = $2, settled_at = $3 where id = $1", hold.ID, domain.HoldVoided, now); err != nil {
+		return nil, err
+	}
+	hold.Status = domain.HoldVoided
+	hold.SettledAt = &now
+	ev, err := domain.NewEvent(domain.EventTransferVoided, accountID, map[string]any{
+		"holdId":   hold.ID,
+		"amount":   hold.Amount.MinorUnits,
+		"currency": hold.Amount.Currency,
+	}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return nil, err
+	}
+	return hold, tx.Commit()
+}
+
+func (s *PostgresStore) GetHold(accountID int, holdID string) (*domain.TransferHold, error) {
+	if !domain.IsUUID(holdID) {
+		return nil, domain.NotFound(domain.ErrHoldNotFound, holdID)
+	}
+	return s.scanHold(s.db.QueryRow(holdQuery, holdID, accountID, s.tenantID), holdID)
+}
+
+const holdQuery = `select id, account_id, to_number, amount, currency, status, captured, transaction_id, created_at, expires_at, settled_at
+							 from transfer_hold where id = $1 and account_id = $2 and tenant_id = $3`
+
+// lockHold reads the account's hold and locks it until tx ends.
+func (s *PostgresStore) lockHold(tx *sql.Tx, accountID int, holdID string) (*domain.TransferHold, error) {
+	if !domain.IsUUID(holdID) {
+		return nil, domain.NotFound(domain.ErrHoldNotFound, holdID)
+	}
+	return s.scanHold(tx.QueryRow(holdQuery+" for update", holdID, accountID, s.tenantID), holdID)
+}
+
+func (s *PostgresStore) scanHold(row *sql.Row, holdID string) (*domain.TransferHold, error) {
+	hold := &domain.TransferHold{}
+	var captured, transactionID sql.NullInt64
+	var settledAt sql.NullTime
+	err := row.Scan(&hold.ID, &hold.AccountID, &hold.ToAccount, &hold.Amount.MinorUnits, &hold.Amount.Currency, &hold.Status,
+		&captured, &transactionID, &hold.CreatedAt, &hold.ExpiresAt, &settledAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrHoldNotFound, holdID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if captured.Valid {
+		hold.Captured = &domain.Money{MinorUnits: captured.Int64, Currency: hold.Amount.Currency}
+	}
+	hold.TransactionID = int(transactionID.Int64)
+	if settledAt.Valid {
+		hold.SettledAt = &settledAt.Time
+	}
+	if hold.Status == domain.HoldAuthorized && !s.clock.Now().Before(hold.ExpiresAt) {
+		hold.Status = domain.HoldExpired
+	}
+	return hold, nil
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateImpersonation(imp *domain.Impersonation) error {
+	imp.TenantID = s.tenantID
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into impersonation (id,tenant_id,account_id,requested_by,reason,status,requires_approval,created_at,expires_at)
+							 select $1,$2,$3,$4,$5,$6,$7,$8,$9 where exists (select 1 from account where id = $3 and tenant_id = $2)`,
+		imp.ID, imp.TenantID, imp.AccountID, imp.RequestedBy, imp.Reason, imp.Status, imp.RequiresApproval, imp.CreatedAt, imp.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrAccountNotFound, imp.AccountID)
+	}
+	if err := insertImpersonationEvent(tx, imp, domain.EventImpersonationRequested, map[string]any{"reason": imp.Reason}, s.clock.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetImpersonation(id string) (*domain.Impersonation, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrImpersonationNotFound, id)
+	}
+	imp, err := s.scanImpersonation(s.db.QueryRow(impersonationQuery+" where id = $1 and tenant_id = $2", id, s.tenantID))
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrImpersonationNotFound, id)
+	}
+	return imp, err
+}
+
+func (s *PostgresStore) ListImpersonations(accountID int) ([]*domain.Impersonation, error) {
+	rows, err := s.db.Query(impersonationQuery+" where account_id = $1 and tenant_id = $2 order by created_at desc limit 100", accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	imps := []*domain.Impersonation{}
+	for rows.Next() {
+		imp, err := s.scanImpersonation(rows)
+		if err != nil {
+			return nil, err
+		}
+		imps = append(imps, imp)
+	}
+	return imps, rows.Err()
+}
+
+func (s *PostgresStore) DecideImpersonation(accountID int, id string, approve bool) (*domain.Impersonation, error) {
+	status, eventType := domain.ImpersonationDenied, domain.EventImpersonationDenied
+	if approve {
+		status, eventType = domain.ImpersonationApproved, domain.EventImpersonationApproved
+	}
+	return s.settleImpersonation(accountID, id, []string{domain.ImpersonationPending}, status, eventType)
+}
+
+func (s *PostgresStore) RevokeImpersonation(id string) (*domain.Impersonation, error) {
+	return s.settleImpersonation(0, id, []string{domain.ImpersonationPending, domain.ImpersonationApproved},
+		domain.ImpersonationRevoked, domain.EventImpersonationRevoked)
+}
+
+// settleImpersonation moves an impersonation in one of the from statuses to
+// status, recording eventType. accountID 0 matches any account.
+func (s *PostgresStore) settleImpersonation(accountID int, id string, from []string, status, eventType string) (*domain.Impersonation, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrImpersonationNotFound, id)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	imp, err := s.scanImpersonation(tx.QueryRow(impersonationQuery+" where id = $1 and tenant_id = $2 and ($3 = 0 or account_id = $3) for update",
+		id, s.tenantID, accountID))
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrImpersonationNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	allowed := false
+	for _, st := range from {
+		allowed = allowed || imp.Status == st
+	}
+	if !allowed {
+		return nil, domain.ErrImpersonationInactive
+	}
+	now := s.clock.Now().UTC()
+	if _, err := tx.Exec("update impersonation set status = $2, decided_at = $3 where id = $1", id, status, now); err != nil {
+		return nil, err
+	}
+	imp.Status = status
+	imp.DecidedAt = &now
+	if err := insertImpersonationEvent(tx, imp, eventType, nil, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	return imp, tx.Commit()
+}
+
+// RecordImpersonationEvent adds an event of the impersonation to the audit
+// trail.
+func (s *PostgresStore) RecordImpersonationEvent(imp *domain.Impersonation, eventType string, details map[string]any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertImpersonationEvent(tx, imp, eventType, details, s.clock.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertImpersonationEvent(tx *sql.Tx, imp *domain.Impersonation, eventType string, details map[string]any, now time.Time) error {
+	payload := map[string]any{
+		"impersonationId": imp.ID,
+		"requestedBy":     imp.RequestedBy,
+		"status":          imp.Status,
+		"expiresAt":       imp.ExpiresAt,
+	}
+	for k, v := range details {
+		payload[k] = v
+	}
+	ev, err := domain.NewEvent(eventType, imp.AccountID, payload, now)
+	if err != nil {
+		return err
+	}
+	return insertOutboxEvent(tx, ev)
+}
+
+const impersonationQuery = `select id, tenant_id, account_id, requested_by, reason, status, requires_approval, created_at, expires_at, decided_at
+							 from impersonation`
+
+func (s *PostgresStore) scanImpersonation(row interface{ Scan(dest ...any) error }) (*domain.Impersonation, error) {
+	imp := &domain.Impersonation{}
+	var decidedAt sql.NullTime
+	err := row.Scan(&imp.ID, &imp.TenantID, &imp.AccountID, &imp.RequestedBy, &imp.Reason, &imp.Status, &imp.RequiresApproval,
+		&imp.CreatedAt, &imp.ExpiresAt, &decidedAt)
+	if err != nil {
+		return nil, err
+	}
+	imp.DecidedAt = nullTime(decidedAt)
+	if imp.Status == domain.ImpersonationApproved && !s.clock.Now().Before(imp.ExpiresAt) {
+		imp.Status = domain.ImpersonationExpired
+	}
+	return imp, nil
+}
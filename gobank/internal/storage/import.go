@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"strings"
+)
+
+const importBatchSize = 500
+
+func (s *PostgresStore) ImportTransactions(accountID int, txs []*domain.Transaction) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var currency string
+	err = tx.QueryRow("select currency from account where id = $1 and tenant_id = $2 for update", accountID, s.tenantID).Scan(&currency)
+	if err != nil {
+		return domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	prev, err := lastLedgerHash(tx, accountID)
+	if err != nil {
+		return err
+	}
+	var sum int64
+	posting := 0
+	for start := 0; start < len(txs); start += importBatchSize {
+		batch := txs[start:min(start+importBatchSize, len(txs))]
+		values := make([]string, 0, len(batch))
+		args := make([]any, 0, len(batch)*9)
+		for i, t := range batch {
+			if t.Amount.Currency != currency {
+				return domain.ErrCurrencyMismatch
+			}
+			// ids follow the order of the values list, which keeps the chain in order
+			t.AccountID = accountID
+			t.Hash = t.ComputeHash(prev)
+			prev = t.Hash
+			n := i * 9
+			values = append(values, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9))
+			args = append(args, accountID, t.Type, t.Amount.MinorUnits, t.CreatedAt, s.tenantID, t.Amount.Currency, t.Description, t.Hash, t.Counterparty)
+			if t.Posted() {
+				sum += t.Amount.MinorUnits
+				posting++
+			}
+		}
+		query := `insert into transaction (account_id,type,amount,created_at,tenant_id,currency,description,hash,counterparty)
+								values ` + strings.Join(values, ",")
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	if posting == 0 {
+		return tx.Commit()
+	}
+	if _, err := tx.Exec("update account set balance = balance + $2, version = version + 1, updated_at = now() where id = $1", accountID, sum); err != nil {
+		return err
+	}
+	if err := s.appendAccountEvent(tx, accountID, domain.AccountBalanceChanged, domain.BalanceChange{Amount: sum, Entries: posting}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
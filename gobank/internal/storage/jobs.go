@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateJobTable() error {
+	query := `create table if not exists jobs (
+    			id serial primary key,
+    			type varchar(100) not null,
+    			payload jsonb,
+    			status varchar(20) not null,
+    			attempts integer default 0,
+    			max_attempts integer default 5,
+    			last_error text,
+    			run_at timestamp not null,
+    			locked_until timestamp,
+    			created_at timestamp
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("create index if not exists jobs_status_run_at_idx on jobs (status, run_at)")
+	return err
+}
+
+func (s *PostgresStore) EnqueueJob(job *domain.Job) error {
+	query := `insert into jobs
+							 (type,payload,status,max_attempts,run_at,created_at)
+								values ($1,$2,$3,$4,$5,$6) returning id`
+	return s.db.QueryRow(query, job.Type, []byte(job.Payload), job.Status, job.MaxAttempts, job.RunAt, job.CreatedAt).Scan(&job.ID)
+}
+
+// enqueueJobTx enqueues the job as part of tx, so it only runs if whatever
+// tx records commits.
+func enqueueJobTx(tx *sql.Tx, job *domain.Job) error {
+	return tx.QueryRow(`insert into jobs (type,payload,status,max_attempts,run_at,created_at)
+							 values ($1,$2,$3,$4,$5,$6) returning id`,
+		job.Type, string(job.Payload), job.Status, job.MaxAttempts, job.RunAt, job.CreatedAt).Scan(&job.ID)
+}
+
+func (s *PostgresStore) LeaseJob(lease time.Duration) (*domain.Job, error) {
+	query := `update jobs set status = 'running', attempts = attempts + 1, locked_until = $1
+				where id = (
+					select id from jobs
+					where (status = 'pending' and run_at <= $2)
+					   or (status = 'running' and locked_until < $2)
+					order by run_at
+					limit 1
+					for update skip locked)
+				returning *`
+	now := time.Now().UTC()
+	rows, err := s.db.Query(query, now.Add(lease), now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoJob(rows)
+	}
+	return nil, rows.Err()
+}
+
+// CompleteJob marks the job done. The attempt is the lease: every lease
+// counts one up, so a worker whose lease ran out and was taken over can't
+// finish the job under the worker that has it now.
+func (s *PostgresStore) CompleteJob(id, attempt int) error {
+	res, err := s.db.Exec(`update jobs set status = 'done', locked_until = null
+							 where id = $1 and status = 'running' and attempts = $2`, id, attempt)
+	if err != nil {
+		return err
+	}
+	return leaseHeld(res)
+}
+
+func (s *PostgresStore) FailJob(id, attempt int, reason string, retryAt time.Time, dead bool) error {
+	status := domain.JobPending
+	if dead {
+		status = domain.JobDead
+	}
+	res, err := s.db.Exec(`update jobs set status = $3, last_error = $4, run_at = $5, locked_until = null
+							 where id = $1 and status = 'running' and attempts = $2`, id, attempt, status, reason, retryAt)
+	if err != nil {
+		return err
+	}
+	return leaseHeld(res)
+}
+
+// leaseHeld tells apart a job update that matched no row, the lease was
+// lost.
+func leaseHeld(res sql.Result) error {
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrJobLeaseLost
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListJobs(status string) ([]*domain.Job, error) {
+	rows, err := s.db.Query("select * from jobs where ($1 = '' or status = $1) order by id desc limit 100", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := []*domain.Job{}
+	for rows.Next() {
+		job, err := scanIntoJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *PostgresStore) RetryJob(id int) error {
+	res, err := s.db.Exec(`update jobs set status = 'pending', attempts = 0, run_at = $2, locked_until = null
+							 where id = $1 and status = 'dead'`, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrJobNotFound, id)
+	}
+	return nil
+}
+
+func scanIntoJob(rows *sql.Rows) (*domain.Job, error) {
+	job := new(domain.Job)
+	var payload []byte
+	var lastError sql.NullString
+	err := rows.Scan(
+		&job.ID,
+		&job.Type,
+		&payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&lastError,
+		&job.RunAt,
+		&job.LockedUntil,
+		&job.CreatedAt)
+	job.Payload = payload
+	job.LastError = lastError.String
+	return job, err
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Locker hands out named locks shared by every instance of the service.
+type Locker interface {
+	// TryLock acquires the named lock without waiting. It returns nil when
+	// another instance holds it.
+	TryLock(name string) (*Lock, error)
+}
+
+type Lock struct {
+	lost    chan struct{}
+	once    sync.Once
+	release func()
+}
+
+func newLock(release func()) *Lock {
+	return &Lock{lost: make(chan struct{}), release: release}
+}
+
+// Lost is closed when the lock can no longer be guaranteed, for example
+// because the connection holding it died.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *Lock) markLost() {
+	l.once.Do(func() { close(l.lost) })
+}
+
+func (l *Lock) Release() {
+	l.markLost()
+	l.release()
+}
+
+// lockRetry is how long RunExclusive waits before trying the lock again.
+var lockRetry = 10 * time.Second
+
+// RunExclusive runs fn on at most one instance at a time. Instances that
+// don't hold the lock keep retrying so another one takes over when the
+// leader stops or dies. fn must return once its stop channel is closed.
+func RunExclusive(locker Locker, name string, logger *slog.Logger, stop <-chan struct{}, fn func(stop <-chan struct{})) {
+	for {
+		lock, err := locker.TryLock(name)
+		if err != nil {
+			logger.Error("acquiring lock failed", "lock", name, "error", err)
+		}
+		if lock != nil {
+			logger.Info("acquired lock", "lock", name)
+			runLocked(lock, stop, fn)
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+func runLocked(lock *Lock, stop <-chan struct{}, fn func(stop <-chan struct{})) {
+	defer lock.Release()
+	inner := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(inner)
+	}()
+	select {
+	case <-stop:
+	case <-lock.Lost():
+	case <-done:
+		return
+	}
+	close(inner)
+	<-done
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLocker hands out the locks of grants in turn, a nil one as if another
+// instance held the lock.
+type fakeLocker struct {
+	mu     sync.Mutex
+	grants []*Lock
+	tries  int
+}
+
+func (f *fakeLocker) TryLock(name string) (*Lock, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tries++
+	if len(f.grants) == 0 {
+		return nil, errors.New("no connection")
+	}
+	lock := f.grants[0]
+	f.grants = f.grants[1:]
+	return lock, nil
+}
+
+func TestRunLockedStopsWhenTheLockIsLost(t *testing.T) {
+	released := make(chan struct{})
+	lock := newLock(func() { close(released) })
+	started, stopped := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		runLocked(lock, make(chan struct{}), func(stop <-chan struct{}) {
+			close(started)
+			<-stop
+			close(stopped)
+		})
+		close(done)
+	}()
+
+	<-started
+	lock.markLost()
+	for _, ch := range []chan struct{}{stopped, done, released} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("runLocked didn't stop fn and release the lock")
+		}
+	}
+}
+
+func TestRunExclusiveTakesTheLockAgain(t *testing.T) {
+	defer func(retry time.Duration) { lockRetry = retry }(lockRetry)
+	lockRetry = time.Millisecond
+	var releases sync.WaitGroup
+	releases.Add(2)
+	first, second := newLock(releases.Done), newLock(releases.Done)
+	// held elsewhere first, then ours, lost, and ours again
+	locker := &fakeLocker{grants: []*Lock{nil, first, nil, second}}
+	runs := make(chan int)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		RunExclusive(locker, "worker", slog.New(slog.NewTextHandler(io.Discard, nil)), stop, func(fnStop <-chan struct{}) {
+			locker.mu.Lock()
+			tries := locker.tries
+			locker.mu.Unlock()
+			runs <- tries
+			<-fnStop
+		})
+		close(done)
+	}()
+
+	assert.Equal(t, 2, <-runs)
+	first.markLost()
+	assert.Equal(t, 4, <-runs)
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunExclusive didn't return once stopped")
+	}
+	releases.Wait()
+	assert.Equal(t, 4, locker.tries)
+}
@@ -0,0 +1,699 @@
+package storage
+
+import (
+	"time"
+)
+
+// Migration is a versioned schema change applied once, in order, on startup.
+// The tables created by Init are the baseline; every change on top of them
+// goes here. Never edit a migration that has shipped, add a new one.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "account column types and indexes",
+		SQL: `
+			alter table account alter column number drop default;
+			alter table account alter column number type bigint;
+			drop sequence if exists account_number_seq;
+			alter table account alter column balance drop default;
+			alter table account alter column balance type bigint;
+			alter table account alter column balance set default 0;
+			alter table account alter column balance set not null;
+			drop sequence if exists account_balance_seq;
+			create unique index if not exists account_tenant_number_idx on account (tenant_id, number);
+			create index if not exists account_tenant_last_name_idx on account (tenant_id, lower(last_name));
+			create index if not exists account_tenant_created_at_idx on account (tenant_id, created_at);
+			create index if not exists transaction_account_created_at_idx on transaction (account_id, created_at);
+			create index if not exists transaction_created_at_idx on transaction (created_at);`,
+	},
+	{
+		Version: 2,
+		Name:    "account email",
+		SQL: `
+			alter table account add column if not exists email varchar(254);
+			create unique index if not exists account_tenant_email_idx on account (tenant_id, lower(email)) where email is not null;`,
+	},
+	{
+		Version: 3,
+		Name:    "currencies",
+		SQL: `
+			alter table account add column if not exists currency char(3) not null default 'USD';
+			alter table transaction add column if not exists currency char(3) not null default 'USD';
+			alter table transaction_archive add column if not exists currency char(3) not null default 'USD';`,
+	},
+	{
+		Version: 4,
+		Name:    "timestamptz and account time zone",
+		SQL: `
+			alter table account alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table transaction alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table transaction_archive alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table transaction_archive alter column archived_at type timestamptz using archived_at at time zone 'UTC';
+			alter table jobs alter column run_at type timestamptz using run_at at time zone 'UTC';
+			alter table jobs alter column locked_until type timestamptz using locked_until at time zone 'UTC';
+			alter table jobs alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table outbox alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table outbox alter column published_at type timestamptz using published_at at time zone 'UTC';
+			alter table tenant alter column created_at type timestamptz using created_at at time zone 'UTC';
+			alter table tenant_settings alter column updated_at type timestamptz using updated_at at time zone 'UTC';
+			alter table schema_migrations alter column applied_at type timestamptz using applied_at at time zone 'UTC';
+			alter table account add column if not exists timezone varchar(64) not null default 'UTC';`,
+	},
+	{
+		Version: 5,
+		Name:    "account language",
+		SQL:     `alter table account add column if not exists language varchar(8) not null default ''`,
+	},
+	{
+		Version: 6,
+		Name:    "transaction feed index",
+		SQL:     `create index if not exists transaction_account_id_idx on transaction (account_id, id)`,
+	},
+	{
+		Version: 7,
+		Name:    "account version",
+		SQL: `
+			alter table account add column if not exists version integer not null default 1;
+			alter table account add column if not exists updated_at timestamptz;
+			update account set updated_at = created_at where updated_at is null;
+			alter table account alter column updated_at set default now();
+			alter table account alter column updated_at set not null;`,
+	},
+	{
+		Version: 8,
+		Name:    "transaction description",
+		SQL: `
+			alter table transaction add column if not exists description varchar(255) not null default '';
+			alter table transaction_archive add column if not exists description varchar(255) not null default '';`,
+	},
+	{
+		Version: 9,
+		Name:    "api usage",
+		SQL: `
+			create table if not exists api_usage (
+				tenant_id integer not null references tenant(id),
+				account_number bigint not null,
+				day date not null,
+				calls bigint not null default 0,
+				throttled bigint not null default 0,
+				primary key (tenant_id, account_number, day)
+			);`,
+	},
+	{
+		Version: 10,
+		Name:    "api keys",
+		SQL: `
+			create table if not exists api_key (
+				id varchar(40) primary key,
+				account_id integer not null references account(id) on delete cascade,
+				name varchar(100) not null default '',
+				secret varchar(64) not null,
+				created_at timestamptz not null,
+				revoked_at timestamptz,
+				tenant_id integer not null references tenant(id)
+			);
+			create index if not exists api_key_account_idx on api_key (account_id);
+			create table if not exists api_nonce (
+				key_id varchar(40) not null references api_key(id) on delete cascade,
+				nonce varchar(64) not null,
+				seen_at timestamptz not null,
+				primary key (key_id, nonce)
+			);
+			create index if not exists api_nonce_seen_at_idx on api_nonce (seen_at);`,
+	},
+	{
+		Version: 11,
+		Name:    "transaction listing index",
+		SQL:     `create index if not exists transaction_account_created_at_id_idx on transaction (account_id, created_at desc, id desc)`,
+	},
+	{
+		Version: 12,
+		Name:    "reconciliation issues",
+		SQL: `
+			create table if not exists reconciliation_issue (
+				id serial primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				currency char(3) not null,
+				account_balance bigint not null,
+				ledger_balance bigint not null,
+				detected_at timestamptz not null,
+				resolved_at timestamptz,
+				resolution varchar(255) not null default ''
+			);
+			create unique index if not exists reconciliation_issue_open_idx on reconciliation_issue (account_id) where resolved_at is null;`,
+	},
+	{
+		Version: 13,
+		Name:    "ledger hash chain",
+		SQL: `
+			alter table transaction add column if not exists hash varchar(64) not null default '';
+			alter table transaction_archive add column if not exists hash varchar(64) not null default '';`,
+	},
+	{
+		Version: 14,
+		Name:    "account uuid",
+		SQL: `
+			alter table account add column if not exists uuid uuid not null default gen_random_uuid();
+			create unique index if not exists account_uuid_idx on account (uuid);`,
+	},
+	{
+		Version: 15,
+		Name:    "account profile and kyc setting",
+		SQL: `
+			alter table account add column if not exists address jsonb;
+			alter table account add column if not exists date_of_birth date;
+			alter table tenant_settings add column if not exists require_kyc boolean not null default false;`,
+	},
+	{
+		Version: 16,
+		Name:    "transfer quotes",
+		SQL: `
+			create table if not exists transfer_quote (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				to_number bigint not null,
+				amount bigint not null,
+				currency char(3) not null,
+				rate numeric not null,
+				fee bigint not null,
+				expires_at timestamptz not null,
+				executed_at timestamptz
+			);
+			create index if not exists transfer_quote_expires_at_idx on transfer_quote (expires_at);`,
+	},
+	{
+		Version: 17,
+		Name:    "transfer holds",
+		SQL: `
+			create table if not exists transfer_hold (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				to_number bigint not null,
+				amount bigint not null,
+				currency char(3) not null,
+				status varchar(16) not null,
+				captured bigint,
+				transaction_id integer,
+				created_at timestamptz not null,
+				expires_at timestamptz not null,
+				settled_at timestamptz
+			);
+			create index if not exists transfer_hold_authorized_idx on transfer_hold (account_id) where status = 'authorized';`,
+	},
+	{
+		Version: 18,
+		Name:    "async transfers",
+		SQL: `
+			create table if not exists transfer_request (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				to_number bigint not null,
+				amount bigint not null,
+				currency char(3) not null,
+				status varchar(16) not null,
+				transaction_id integer,
+				failure_code varchar(64),
+				failure_params jsonb,
+				created_at timestamptz not null,
+				updated_at timestamptz not null
+			);`,
+	},
+	{
+		Version: 19,
+		Name:    "sagas",
+		SQL: `
+			create table if not exists saga (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null,
+				type varchar(64) not null,
+				status varchar(16) not null,
+				steps jsonb not null,
+				error text not null default '',
+				created_at timestamptz not null,
+				updated_at timestamptz not null
+			);
+			create index if not exists saga_open_idx on saga (updated_at) where status in ('running', 'compensating', 'stuck');`,
+	},
+	{
+		Version: 20,
+		Name:    "webhooks",
+		SQL: `
+			create table if not exists webhook_endpoint (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				url text not null,
+				secret varchar(100) not null,
+				status varchar(16) not null,
+				consecutive_failures integer not null default 0,
+				failing_since timestamptz,
+				disabled_at timestamptz,
+				created_at timestamptz not null
+			);
+			create index if not exists webhook_endpoint_account_idx on webhook_endpoint (account_id);
+			create table if not exists webhook_delivery (
+				id uuid primary key,
+				endpoint_id uuid not null references webhook_endpoint(id) on delete cascade,
+				event_id bigint not null,
+				event_type varchar(64) not null,
+				payload jsonb not null,
+				status varchar(16) not null,
+				created_at timestamptz not null,
+				delivered_at timestamptz,
+				unique (endpoint_id, event_id)
+			);
+			create table if not exists webhook_attempt (
+				id bigserial primary key,
+				delivery_id uuid not null references webhook_delivery(id) on delete cascade,
+				status_code integer not null,
+				error text not null default '',
+				duration_ms bigint not null,
+				attempted_at timestamptz not null
+			);
+			create index if not exists webhook_attempt_delivery_idx on webhook_attempt (delivery_id);`,
+	},
+	{
+		Version: 21,
+		Name:    "impersonation",
+		SQL: `
+			create table if not exists impersonation (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				requested_by varchar(100) not null,
+				reason text not null,
+				status varchar(16) not null,
+				requires_approval boolean not null,
+				created_at timestamptz not null,
+				expires_at timestamptz not null,
+				decided_at timestamptz
+			);
+			create index if not exists impersonation_account_idx on impersonation (account_id);`,
+	},
+	{
+		Version: 22,
+		Name:    "terms acceptance",
+		SQL: `
+			create table if not exists terms_acceptance (
+				account_id integer not null references account(id) on delete cascade,
+				document varchar(32) not null,
+				version varchar(64) not null,
+				accepted_at timestamptz not null,
+				primary key (account_id, document, version)
+			);`,
+	},
+	{
+		Version: 23,
+		Name:    "consents",
+		SQL: `
+			alter table api_key add column if not exists third_party boolean not null default false;
+			create table if not exists consent (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				api_key_id varchar(40) not null references api_key(id) on delete cascade,
+				purpose text not null,
+				scopes text[] not null,
+				status varchar(16) not null,
+				created_at timestamptz not null,
+				expires_at timestamptz not null,
+				revoked_at timestamptz
+			);
+			create index if not exists consent_key_idx on consent (api_key_id) where status = 'active';`,
+	},
+	{
+		Version: 24,
+		Name:    "transfer screening",
+		SQL: `
+			alter table transfer_request add column if not exists review_reason text;
+			alter table transfer_request add column if not exists reviewed_at timestamptz;
+			create index if not exists transfer_request_review_idx on transfer_request (tenant_id) where status = 'review';`,
+	},
+	{
+		Version: 25,
+		Name:    "iban",
+		SQL: `
+			alter table tenant_settings add column if not exists iban_country char(2);
+			alter table tenant_settings add column if not exists iban_bank_code varchar(30);
+			alter table account add column if not exists iban varchar(34);
+			create unique index if not exists account_iban_idx on account (iban);`,
+	},
+	{
+		Version: 26,
+		Name:    "account phone",
+		SQL: `
+			alter table account add column if not exists phone varchar(16);
+			create unique index if not exists account_tenant_phone_idx on account (tenant_id, phone) where phone is not null;`,
+	},
+	{
+		Version: 27,
+		Name:    "account nickname and avatar",
+		SQL: `
+			alter table account add column if not exists nickname varchar(30) not null default '';
+			alter table account add column if not exists avatar varchar(100) not null default '';`,
+	},
+	{
+		Version: 28,
+		Name:    "account locale",
+		SQL:     `alter table account add column if not exists locale varchar(10) not null default ''`,
+	},
+	{
+		Version: 29,
+		Name:    "email",
+		SQL: `
+			create table if not exists email_message (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				kind varchar(32) not null,
+				dedupe_key varchar(200) not null unique,
+				recipient text not null,
+				subject text not null,
+				body_text text not null,
+				body_html text not null,
+				status varchar(16) not null,
+				attempts integer not null default 0,
+				last_error text not null default '',
+				provider_id varchar(200),
+				created_at timestamptz not null,
+				sent_at timestamptz
+			);
+			create index if not exists email_message_provider_idx on email_message (provider_id) where provider_id is not null;
+			create table if not exists email_suppression (
+				tenant_id integer not null references tenant(id),
+				address text not null,
+				reason varchar(16) not null,
+				created_at timestamptz not null,
+				primary key (tenant_id, address)
+			);`,
+	},
+	{
+		Version: 30,
+		Name:    "sms",
+		SQL: `
+			alter table account add column if not exists phone_verified boolean not null default false;
+			alter table account add column if not exists sms_alerts boolean not null default false;
+			create table if not exists phone_verification (
+				account_id integer primary key references account(id) on delete cascade,
+				phone varchar(16) not null,
+				code_hash char(64) not null,
+				attempts integer not null default 0,
+				sent_at timestamptz not null,
+				expires_at timestamptz not null
+			);
+			create table if not exists sms_message (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				kind varchar(32) not null,
+				dedupe_key varchar(200) not null unique,
+				recipient varchar(16) not null,
+				body text not null,
+				status varchar(16) not null,
+				attempts integer not null default 0,
+				last_error text not null default '',
+				provider_id varchar(200),
+				created_at timestamptz not null,
+				sent_at timestamptz
+			);`,
+	},
+	{
+		Version: 31,
+		Name:    "push",
+		SQL: `
+			create table if not exists push_device (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				platform varchar(8) not null,
+				token text not null,
+				name varchar(60) not null default '',
+				created_at timestamptz not null,
+				unique (platform, token)
+			);
+			create index if not exists push_device_account_idx on push_device (account_id);
+			create table if not exists push_message (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				device_id uuid not null references push_device(id) on delete cascade,
+				kind varchar(32) not null,
+				dedupe_key varchar(200) not null unique,
+				title text not null,
+				body text not null,
+				data jsonb,
+				status varchar(16) not null,
+				attempts integer not null default 0,
+				last_error text not null default '',
+				provider_id varchar(200),
+				created_at timestamptz not null,
+				sent_at timestamptz
+			);`,
+	},
+	{
+		Version: 32,
+		Name:    "notifications",
+		SQL: `
+			create table if not exists notification (
+				id bigserial primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				kind varchar(32) not null,
+				dedupe_key varchar(200) not null unique,
+				title text not null,
+				body text not null,
+				data jsonb,
+				created_at timestamptz not null,
+				read_at timestamptz
+			);
+			create index if not exists notification_account_idx on notification (account_id, id desc);
+			create index if not exists notification_unread_idx on notification (account_id) where read_at is null;`,
+	},
+	{
+		Version: 33,
+		Name:    "activity",
+		SQL:     `create index if not exists outbox_account_created_at_id_idx on outbox (account_id, created_at desc, id desc)`,
+	},
+	{
+		Version: 34,
+		Name:    "duplicate transfers",
+		SQL:     `alter table tenant_settings add column if not exists duplicate_transfer_minutes integer not null default 2`,
+	},
+	{
+		Version: 35,
+		Name:    "minimum balances and pots",
+		SQL: `
+			alter table tenant_settings add column if not exists minimum_balance bigint not null default 0;
+			alter table account add column if not exists minimum_balance bigint;
+			create table if not exists pot (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				name varchar(60) not null,
+				amount bigint not null check (amount >= 0),
+				currency char(3) not null,
+				created_at timestamptz not null,
+				updated_at timestamptz not null
+			);
+			create index if not exists pot_account_idx on pot (account_id);`,
+	},
+	{
+		Version: 36,
+		Name:    "statement deliveries",
+		SQL: `
+			create table if not exists statement_destination (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				account_id integer not null references account(id) on delete cascade,
+				sink varchar(16) not null,
+				frequency varchar(16) not null,
+				email text not null default '',
+				url text not null default '',
+				password text not null default '',
+				host_key varchar(100) not null default '',
+				created_at timestamptz not null
+			);
+			create index if not exists statement_destination_account_idx on statement_destination (account_id);
+			create table if not exists statement_delivery (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				destination_id uuid not null references statement_destination(id) on delete cascade,
+				account_id integer not null,
+				period_start date not null,
+				period_end date not null,
+				status varchar(16) not null,
+				attempts integer not null default 0,
+				last_error text not null default '',
+				created_at timestamptz not null,
+				delivered_at timestamptz,
+				unique (destination_id, period_start, period_end)
+			);`,
+	},
+	{
+		Version: 37,
+		Name:    "batch files",
+		SQL: `
+			create table if not exists batch_file (
+				id uuid primary key,
+				tenant_id integer not null references tenant(id),
+				name text not null,
+				format varchar(16) not null,
+				sha256 char(64) not null,
+				status varchar(16) not null,
+				accepted integer not null default 0,
+				rejected integer not null default 0,
+				ack bytea,
+				received_at timestamptz not null,
+				processed_at timestamptz,
+				unique (tenant_id, sha256)
+			);`,
+	},
+	{
+		Version: 38,
+		Name:    "warehouse watermarks",
+		SQL: `
+			create table if not exists warehouse_watermark (
+				dataset varchar(32) primary key,
+				last_time timestamptz,
+				last_id integer not null default 0,
+				updated_at timestamptz not null
+			);
+			create index if not exists account_updated_at_idx on account (updated_at, id);`,
+	},
+	{
+		Version: 39,
+		Name:    "projections",
+		SQL: `
+			create table if not exists projection_checkpoint (
+				name varchar(32) primary key,
+				last_id integer not null default 0,
+				updated_at timestamptz
+			);
+			insert into projection_checkpoint (name) values ('balances'), ('monthly_totals') on conflict do nothing;
+			create table if not exists balance_projection (
+				account_id integer primary key references account(id) on delete cascade,
+				tenant_id integer not null,
+				currency char(3) not null,
+				balance bigint not null,
+				transactions integer not null,
+				last_transaction_at timestamptz not null
+			);
+			create index if not exists balance_projection_tenant_idx on balance_projection (tenant_id, currency);
+			create table if not exists monthly_total_projection (
+				account_id integer not null references account(id) on delete cascade,
+				month char(7) not null,
+				tenant_id integer not null,
+				currency char(3) not null,
+				credits bigint not null,
+				debits bigint not null,
+				transactions integer not null,
+				primary key (account_id, month, currency)
+			);`,
+	},
+	{
+		Version: 40,
+		Name:    "account event streams",
+		SQL: `
+			create table if not exists account_event (
+				account_id integer not null,
+				version integer not null,
+				tenant_id integer not null,
+				type varchar(32) not null,
+				data jsonb not null,
+				created_at timestamptz not null,
+				primary key (account_id, version)
+			);
+			create table if not exists account_snapshot (
+				account_id integer not null,
+				version integer not null,
+				tenant_id integer not null,
+				state jsonb not null,
+				state_at timestamptz not null,
+				created_at timestamptz not null,
+				primary key (account_id, version)
+			);`,
+	},
+	{
+		Version: 41,
+		Name:    "ledger transaction ids",
+		SQL: `
+			alter table transaction add column if not exists xid bigint not null default 0;
+			alter table transaction alter column xid set default pg_current_xact_id()::text::bigint;
+			alter table transaction_archive add column if not exists xid bigint not null default 0;
+			create index if not exists transaction_xid_idx on transaction (xid, id);
+			alter table projection_checkpoint add column if not exists last_xid bigint not null default 0;`,
+	},
+	{
+		Version: 42,
+		Name:    "batch file names",
+		SQL: `
+			alter table batch_file drop constraint if exists batch_file_tenant_id_sha256_key;
+			create unique index if not exists batch_file_name_sha256_idx on batch_file (tenant_id, name, sha256);
+			update batch_file set status = 'acknowledged' where status = 'processed';`,
+	},
+	{
+		Version: 43,
+		Name:    "no default tenant",
+		SQL: `
+			alter table account alter column tenant_id drop default;
+			alter table transaction alter column tenant_id drop default;
+			alter table transaction_archive alter column tenant_id drop default;`,
+	},
+}
+
+// Migrate applies the pending migrations. Each one runs in its own db
+// transaction, serialised across instances by an advisory lock.
+func (s *PostgresStore) Migrate() error {
+	_, err := s.db.Exec(`create table if not exists schema_migrations (
+    			version integer primary key,
+    			name varchar(200),
+    			applied_at timestamp
+				)`)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if err := s.applyMigration(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) applyMigration(m Migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("select pg_advisory_xact_lock(hashtext('schema_migrations'))"); err != nil {
+		return err
+	}
+	var applied bool
+	if err := tx.QueryRow("select exists(select 1 from schema_migrations where version = $1)", m.Version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("insert into schema_migrations (version,name,applied_at) values ($1,$2,$3)", m.Version, m.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.logger.Info("applied migration", "version", m.Version, "name", m.Name)
+	return tx.Commit()
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, m.Name)
+		assert.NotEmpty(t, m.SQL)
+	}
+}
+
+// A row written without a tenant has to fail rather than land in whichever
+// tenant a column default names.
+func TestTenantColumnsHaveNoDefault(t *testing.T) {
+	defaulted := regexp.MustCompile(`tenant_id integer[^,\n]*default`)
+	dropped := false
+	for _, m := range migrations {
+		assert.False(t, defaulted.MatchString(m.SQL), m.Name)
+		dropped = dropped || strings.Contains(m.SQL, "alter table account alter column tenant_id drop default")
+	}
+	assert.True(t, dropped)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+const notificationColumns = "id, account_id, kind, dedupe_key, title, body, data, created_at, read_at"
+
+func (s *PostgresStore) AddNotification(n *domain.Notification) error {
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		return err
+	}
+	err = s.db.QueryRow(`insert into notification (tenant_id,account_id,kind,dedupe_key,title,body,data,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8)
+							 on conflict (dedupe_key) do nothing
+							 returning id`,
+		s.tenantID, n.AccountID, n.Kind, n.DedupeKey, n.Title, n.Body, string(data), n.CreatedAt).Scan(&n.ID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func (s *PostgresStore) NotificationsBefore(accountID int, beforeID int64, unreadOnly bool, limit int) ([]*domain.Notification, error) {
+	rows, err := s.db.Query(`select `+notificationColumns+` from notification
+							 where account_id = $1 and tenant_id = $2 and ($3 = 0 or id < $3) and (not $4 or read_at is null)
+							 order by id desc limit $5`, accountID, s.tenantID, beforeID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notifications := []*domain.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (s *PostgresStore) UnreadNotifications(accountID int) (int, error) {
+	var n int
+	err := s.db.QueryRow("select count(*) from notification where account_id = $1 and tenant_id = $2 and read_at is null",
+		accountID, s.tenantID).Scan(&n)
+	return n, err
+}
+
+// MarkNotificationRead keeps the time a notification was first read.
+func (s *PostgresStore) MarkNotificationRead(accountID int, id int64) (*domain.Notification, error) {
+	n, err := scanNotification(s.db.QueryRow(`update notification set read_at = coalesce(read_at, $4)
+							 where id = $1 and account_id = $2 and tenant_id = $3
+							 returning `+notificationColumns, id, accountID, s.tenantID, time.Now().UTC()))
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrNotificationNotFound, id)
+	}
+	return n, err
+}
+
+func (s *PostgresStore) MarkAllNotificationsRead(accountID int) (int64, error) {
+	res, err := s.db.Exec("update notification set read_at = $3 where account_id = $1 and tenant_id = $2 and read_at is null",
+		accountID, s.tenantID, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanNotification(row interface{ Scan(dest ...any) error }) (*domain.Notification, error) {
+	n := new(domain.Notification)
+	var data []byte
+	var readAt sql.NullTime
+	if err := row.Scan(&n.ID, &n.AccountID, &n.Kind, &n.DedupeKey, &n.Title, &n.Body, &data, &n.CreatedAt, &readAt); err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &n.Data); err != nil {
+			return nil, err
+		}
+	}
+	n.ReadAt = nullTime(readAt)
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateOutboxTable() error {
+	query := `create table if not exists outbox (
+    			id bigserial primary key,
+    			event_type varchar(100) not null,
+    			account_id integer,
+    			payload jsonb,
+    			created_at timestamp not null,
+    			published_at timestamp
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("create index if not exists outbox_unpublished_idx on outbox (id) where published_at is null")
+	return err
+}
+
+func insertOutboxEvent(tx *sql.Tx, ev *domain.Event) error {
+	query := `insert into outbox
+							 (event_type,account_id,payload,created_at)
+								values ($1,$2,$3,$4) returning id`
+	return tx.QueryRow(query, ev.Type, ev.AccountID, []byte(ev.Payload), ev.CreatedAt).Scan(&ev.ID)
+}
+
+func (s *PostgresStore) RecordEvent(ev *domain.Event) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) RelayOutbox(limit int, publish func(ev *domain.Event) error) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`select id,event_type,account_id,payload,created_at from outbox
+							 where published_at is null order by id limit $1 for update skip locked`, limit)
+	if err != nil {
+		return 0, err
+	}
+	events := []*domain.Event{}
+	for rows.Next() {
+		ev := new(domain.Event)
+		var payload []byte
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.AccountID, &payload, &ev.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ev.Payload = payload
+		events = append(events, ev)
+	}
+	rows.Close()
+
+	published := 0
+	for _, ev := range events {
+		// stop at the first failure so events are never published out of order
+		if err := publish(ev); err != nil {
+			break
+		}
+		if _, err := tx.Exec("update outbox set published_at = $2 where id = $1", ev.ID, time.Now().UTC()); err != nil {
+			return 0, err
+		}
+		published++
+	}
+	return published, tx.Commit()
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+)
+
+// ledgerEntries are the hot and archived transactions, so a projection
+// rebuilt from scratch sees the whole ledger. xid is the id of the db
+// transaction that wrote the entry, 0 for those from before it was kept.
+const ledgerEntries = `(select xid, id, account_id, tenant_id, type, amount, currency, created_at from transaction
+						 union all
+						 select xid, id, account_id, tenant_id, type, amount, currency, created_at from transaction_archive) e`
+
+// postedEntry leaves out the history entries, which don't move a balance.
+const postedEntry = `e.type <> '` + domain.TransactionHistory + `'`
+
+// projection is a read table and the statement that applies the ledger
+// entries with an (xid, id) in (($1, $2), ($3, $4)] to it. Entries of deleted accounts are
+// left out.
+type projection struct {
+	table string
+	apply string
+}
+
+var projections = map[string]projection{
+	domain.ProjectionBalances: {
+		table: "balance_projection",
+		apply: `insert into balance_projection (account_id, tenant_id, currency, balance, transactions, last_transaction_at)
+					select e.account_id, e.tenant_id, e.currency, sum(e.amount), count(*), max(e.created_at)
+					from ` + ledgerEntries + ` join account a on a.id = e.account_id
+					where (e.xid, e.id) > ($1, $2) and (e.xid, e.id) <= ($3, $4) and ` + postedEntry + `
+					group by e.account_id, e.tenant_id, e.currency
+				on conflict (account_id) do update set
+					balance = balance_projection.balance + excluded.balance,
+					transactions = balance_projection.transactions + excluded.transactions,
+					last_transaction_at = greatest(balance_projection.last_transaction_at, excluded.last_transaction_at)`,
+	},
+	domain.ProjectionMonthlyTotals: {
+		table: "monthly_total_projection",
+		apply: `insert into monthly_total_projection (account_id, month, tenant_id, currency, credits, debits, transactions)
+					select e.account_id, ` + accountMonth + `, e.tenant_id, e.currency,
+					coalesce(sum(e.amount) filter (where e.amount > 0), 0),
+					coalesce(-sum(e.amount) filter (where e.amount < 0), 0),
+					count(*)
+					from ` + ledgerEntries + ` join account a on a.id = e.account_id
+					where (e.xid, e.id) > ($1, $2) and (e.xid, e.id) <= ($3, $4) and ` + postedEntry + `
+					group by 1, 2, 3, 4
+				on conflict (account_id, month, currency) do update set
+					credits = monthly_total_projection.credits + excluded.credits,
+					debits = monthly_total_projection.debits + excluded.debits,
+					transactions = monthly_total_projection.transactions + excluded.transactions`,
+	},
+}
+
+// accountMonth is the month of entry e in the time zone of its account a.
+const accountMonth = `to_char(e.created_at at time zone coalesce(nullif(a.timezone, ''), 'UTC'), 'YYYY-MM')`
+
+// Project applies the ledger entries after the projection's checkpoint in
+// ledgerKey order, up to limit of them, and moves the checkpoint past them
+// in the same db transaction. Ids are handed out before commit, so it only
+// goes as far as the entries of db transactions that are all over, see
+// committedBefore.
+func (s *PostgresStore) Project(name string, limit int) (int, error) {
+	p, ok := projections[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown projection %q", name)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var from ledgerKey
+	if err := tx.QueryRow("select last_xid, last_id from projection_checkpoint where name = $1 for update", name).Scan(&from.xid, &from.id); err != nil {
+		return 0, err
+	}
+	var xmin int64
+	if err := tx.QueryRow("select pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&xmin); err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(`select e.xid, e.id from `+ledgerEntries+` where (e.xid, e.id) > ($1, $2)
+							 order by e.xid, e.id limit $3`, from.xid, from.id, limit)
+	if err != nil {
+		return 0, err
+	}
+	var entries []ledgerKey
+	for rows.Next() {
+		var k ledgerKey
+		if err := rows.Scan(&k.xid, &k.id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		entries = append(entries, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	entries = committedBefore(entries, xmin)
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	to := entries[len(entries)-1]
+	if _, err := tx.Exec(p.apply, from.xid, from.id, to.xid, to.id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("update projection_checkpoint set last_xid = $2, last_id = $3, updated_at = $4 where name = $1",
+		name, to.xid, to.id, s.clock.Now().UTC()); err != nil {
+		return 0, err
+	}
+	return len(entries), tx.Commit()
+}
+
+// ledgerKey orders the ledger for the projections: by the db transaction
+// that wrote an entry, then by id.
+type ledgerKey struct {
+	xid int64
+	id  int
+}
+
+// committedBefore returns the leading entries written by db transactions
+// older than xmin, the oldest one still running. Every db transaction
+// before xmin has committed or rolled back, so no entry can turn up later
+// in front of those; one that's newer may still be followed by an entry
+// with a lower key that isn't committed yet.
+func committedBefore(entries []ledgerKey, xmin int64) []ledgerKey {
+	n := 0
+	for n < len(entries) && entries[n].xid < xmin {
+		n++
+	}
+	return entries[:n]
+}
+
+// ResetProjection empties the projection's table and checkpoint, so the
+// projector builds it again from the start of the ledger.
+func (s *PostgresStore) ResetProjection(name string) error {
+	p, ok := projections[name]
+	if !ok {
+		return domain.NotFound(domain.ErrProjectionNotFound, name)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("update projection_checkpoint set last_xid = 0, last_id = 0, updated_at = null where name = $1", name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from " + p.table); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) Projections() ([]*domain.Projection, error) {
+	rows, err := s.db.Query(`select name, last_id, updated_at,
+							 (select count(*) from ` + ledgerEntries + ` where (e.xid, e.id) > (c.last_xid, c.last_id))
+							 from projection_checkpoint c order by name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []*domain.Projection{}
+	for rows.Next() {
+		p := new(domain.Projection)
+		if err := rows.Scan(&p.Name, &p.LastTransactionID, &p.UpdatedAt, &p.Behind); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// monthlyTotalsQuery reads the monthly projection of an account and adds
+// the entries it hasn't got to yet, in the same statement so none is counted
+// twice. Like the projection it leaves out history entries.
+const monthlyTotalsQuery = `select month, currency, sum(credits), sum(debits), sum(transactions) from (
+								select month, currency, credits, debits, transactions from monthly_total_projection
+								where account_id = $1 and tenant_id = $2
+								union all
+								select ` + accountMonth + `, e.currency,
+								coalesce(sum(e.amount) filter (where e.amount > 0), 0),
+								coalesce(-sum(e.amount) filter (where e.amount < 0), 0),
+								count(*)
+								from transaction e join account a on a.id = e.account_id
+								where e.account_id = $1 and e.tenant_id = $2
+								and (e.xid, e.id) > (select last_xid, last_id from projection_checkpoint where name = $4)
+								and ` + postedEntry + `
+								group by 1, 2
+							 ) totals where month >= $3
+							 group by month, currency order by month`
+
+// MonthlyTotals reads the monthly projection and adds the account's entries
+// it hasn't got to yet, see monthlyTotalsQuery.
+func (s *PostgresStore) MonthlyTotals(accountID int, since string) ([]*domain.MonthlyTotal, error) {
+	rows, err := s.db.Query(monthlyTotalsQuery, accountID, s.tenantID, since, domain.ProjectionMonthlyTotals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []*domain.MonthlyTotal{}
+	for rows.Next() {
+		t := new(domain.MonthlyTotal)
+		var currency string
+		if err := rows.Scan(&t.Month, &currency, &t.Credits.MinorUnits, &t.Debits.MinorUnits, &t.Transactions); err != nil {
+			return nil, err
+		}
+		t.Credits.Currency = currency
+		t.Debits.Currency = currency
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// balanceTotalsQuery reads the balance projection the same way, per
+// currency.
+const balanceTotalsQuery = `select currency, count(distinct account_id), sum(balance) from (
+								select account_id, currency, balance from balance_projection where tenant_id = $1
+								union all
+								select e.account_id, e.currency, sum(e.amount) from transaction e
+								join account a on a.id = e.account_id
+								where e.tenant_id = $1 and (e.xid, e.id) > (select last_xid, last_id from projection_checkpoint where name = $2)
+								and ` + postedEntry + `
+								group by e.account_id, e.currency
+							 ) balances group by currency order by currency`
+
+// BalanceTotals reads the balance projection, see balanceTotalsQuery.
+func (s *PostgresStore) BalanceTotals() ([]*domain.BalanceTotal, error) {
+	rows, err := s.db.Query(balanceTotalsQuery, s.tenantID, domain.ProjectionBalances)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []*domain.BalanceTotal{}
+	for rows.Next() {
+		t := new(domain.BalanceTotal)
+		if err := rows.Scan(&t.Total.Currency, &t.Accounts, &t.Total.MinorUnits); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
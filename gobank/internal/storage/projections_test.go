@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestProjectionsHaveCheckpoints(t *testing.T) {
+	sql := ""
+	for _, m := range migrations {
+		sql += m.SQL
+	}
+	assert.Len(t, projections, len(domain.Projections))
+	for _, name := range domain.Projections {
+		p, ok := projections[name]
+		if assert.True(t, ok, name) {
+			assert.True(t, strings.Contains(sql, "create table if not exists "+p.table+" ("), name)
+		}
+		assert.True(t, strings.Contains(sql, "('"+name+"')"), name)
+	}
+}
+
+// Imported history entries don't move a balance, a projection counting them
+// would disagree with the totals read straight from the ledger.
+func TestProjectionsLeaveOutHistory(t *testing.T) {
+	for name, p := range projections {
+		assert.Contains(t, p.apply, postedEntry, name)
+	}
+	assert.Contains(t, monthlyTotalsQuery, postedEntry)
+	assert.Contains(t, balanceTotalsQuery, postedEntry)
+}
+
+// Entry 10 is handed out to a db transaction that commits after the one
+// that got 11. No pass may move past 10 before it's committed.
+func TestProjectionOutOfOrderCommit(t *testing.T) {
+	type entry struct {
+		key       ledgerKey
+		committed bool
+	}
+	ledger := []*entry{{ledgerKey{200, 10}, false}, {ledgerKey{150, 11}, true}, {ledgerKey{210, 12}, true}}
+	var projected []int
+	checkpoint := ledgerKey{}
+	pass := func(xmin int64) {
+		var visible []ledgerKey
+		for _, e := range ledger {
+			k := e.key
+			if e.committed && (k.xid > checkpoint.xid || k.xid == checkpoint.xid && k.id > checkpoint.id) {
+				visible = append(visible, k)
+			}
+		}
+		sort.Slice(visible, func(i, j int) bool {
+			return visible[i].xid < visible[j].xid || visible[i].xid == visible[j].xid && visible[i].id < visible[j].id
+		})
+		for _, k := range committedBefore(visible, xmin) {
+			projected = append(projected, k.id)
+			checkpoint = k
+		}
+	}
+
+	// 200 is still running, 12 waits for it too
+	pass(200)
+	assert.Equal(t, []int{11}, projected)
+	ledger[0].committed = true
+	pass(300)
+	assert.Equal(t, []int{11, 10, 12}, projected)
+	pass(300)
+	assert.Equal(t, []int{11, 10, 12}, projected)
+}
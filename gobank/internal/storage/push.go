@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+)
+
+const SendPushJobType = "send_push"
+
+// PushJob is the payload of a SendPushJobType job.
+type PushJob struct {
+	PushID string `json:"pushId"`
+}
+
+// RegisterDevice saves the device, or moves its token over to the device's
+// account if it is registered already, and sets the device's id to the one
+// the token has.
+func (s *PostgresStore) RegisterDevice(d *domain.Device) error {
+	return s.db.QueryRow(`insert into push_device (id,tenant_id,account_id,platform,token,name,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7)
+							 on conflict (platform, token) do update set tenant_id = excluded.tenant_id,
+							 account_id = excluded.account_id, name = excluded.name
+							 returning id, created_at`,
+		d.ID, s.tenantID, d.AccountID, d.Platform, d.Token, d.Name, d.CreatedAt).Scan(&d.ID, &d.CreatedAt)
+}
+
+func (s *PostgresStore) ListDevices(accountID int) ([]*domain.Device, error) {
+	rows, err := s.db.Query(`select id, platform, token, name, created_at from push_device
+							 where account_id = $1 and tenant_id = $2 order by created_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	devices := []*domain.Device{}
+	for rows.Next() {
+		d := &domain.Device{AccountID: accountID}
+		if err := rows.Scan(&d.ID, &d.Platform, &d.Token, &d.Name, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func (s *PostgresStore) DeleteDevice(accountID int, id string) error {
+	if !domain.IsUUID(id) {
+		return domain.NotFound(domain.ErrDeviceNotFound, id)
+	}
+	res, err := s.db.Exec("delete from push_device where id = $1 and account_id = $2 and tenant_id = $3", id, accountID, s.tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrDeviceNotFound, id)
+	}
+	return nil
+}
+
+// ForgetDevice deletes a device of any tenant, whose token the push service
+// says is no longer valid.
+func (s *PostgresStore) ForgetDevice(id string) error {
+	_, err := s.db.Exec("delete from push_device where id = $1", id)
+	return err
+}
+
+// GetDevice returns a device of any tenant.
+func (s *PostgresStore) GetDevice(id string) (*domain.Device, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrDeviceNotFound, id)
+	}
+	d := &domain.Device{ID: id}
+	err := s.db.QueryRow("select account_id, platform, token, name, created_at from push_device where id = $1", id).
+		Scan(&d.AccountID, &d.Platform, &d.Token, &d.Name, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrDeviceNotFound, id)
+	}
+	return d, err
+}
+
+// QueuePush saves the notification with the job that sends it, like
+// QueueEmail.
+func (s *PostgresStore) QueuePush(p *domain.Push) (bool, error) {
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return false, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into push_message (id,tenant_id,account_id,device_id,kind,dedupe_key,title,body,data,status,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+							 on conflict (dedupe_key) do nothing`,
+		p.ID, p.TenantID, p.AccountID, p.DeviceID, p.Kind, p.DedupeKey, p.Title, p.Body, string(data), p.Status, p.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	job, err := domain.NewJob(SendPushJobType, PushJob{PushID: p.ID}, s.clock.Now())
+	if err != nil {
+		return false, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetPush returns a notification of any tenant.
+func (s *PostgresStore) GetPush(id string) (*domain.Push, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrPushNotFound, id)
+	}
+	p := &domain.Push{ID: id}
+	var data []byte
+	var providerID sql.NullString
+	var sentAt sql.NullTime
+	err := s.db.QueryRow(`select tenant_id, account_id, device_id, kind, dedupe_key, title, body, data,
+							 status, attempts, last_error, provider_id, created_at, sent_at
+							 from push_message where id = $1`, id).
+		Scan(&p.TenantID, &p.AccountID, &p.DeviceID, &p.Kind, &p.DedupeKey, &p.Title, &p.Body, &data,
+			&p.Status, &p.Attempts, &p.LastError, &providerID, &p.CreatedAt, &sentAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrPushNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &p.Data); err != nil {
+			return nil, err
+		}
+	}
+	p.ProviderID = providerID.String
+	p.SentAt = nullTime(sentAt)
+	return p, nil
+}
+
+// RecordPushAttempt saves the status, attempt count, error and provider id
+// an attempt left the notification with.
+func (s *PostgresStore) RecordPushAttempt(p *domain.Push) error {
+	_, err := s.db.Exec(`update push_message set status = $2, attempts = $3, last_error = $4, provider_id = nullif($5, ''), sent_at = $6
+							 where id = $1`,
+		p.ID, p.Status, p.Attempts, p.LastError, p.ProviderID, p.SentAt)
+	return err
+}
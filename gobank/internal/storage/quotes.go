@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateQuote(q *domain.TransferQuote) error {
+	_, err := s.db.Exec(`insert into transfer_quote (id,tenant_id,account_id,to_number,amount,currency,rate,fee,expires_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		q.ID, s.tenantID, q.AccountID, q.ToAccount, q.Amount.MinorUnits, q.Amount.Currency, q.Rate, q.Fee.MinorUnits, q.ExpiresAt)
+	return err
+}
+
+func (s *PostgresStore) ClaimQuote(accountID int, id string) (*domain.TransferQuote, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrQuoteNotFound, id)
+	}
+	q := &domain.TransferQuote{ID: id, AccountID: accountID}
+	var executed sql.NullTime
+	err := s.db.QueryRow(`select to_number, amount, currency, rate::text, fee, expires_at, executed_at from transfer_quote
+							 where id = $1 and account_id = $2 and tenant_id = $3`, id, accountID, s.tenantID).
+		Scan(&q.ToAccount, &q.Amount.MinorUnits, &q.Amount.Currency, &q.Rate, &q.Fee.MinorUnits, &q.ExpiresAt, &executed)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrQuoteNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().UTC()
+	if executed.Valid || q.Expired(now) {
+		return nil, domain.ErrQuoteExpired
+	}
+	// the executed_at condition makes concurrent claims of one quote race
+	// for a single row update
+	res, err := s.db.Exec("update transfer_quote set executed_at = $2 where id = $1 and executed_at is null", id, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, domain.ErrQuoteExpired
+	}
+	q.Fee.Currency = q.Amount.Currency
+	q.Total = domain.Money{MinorUnits: q.Amount.MinorUnits + q.Fee.MinorUnits, Currency: q.Amount.Currency}
+	return q, nil
+}
+
+func (s *PostgresStore) ReleaseQuote(id string) error {
+	_, err := s.db.Exec("update transfer_quote set executed_at = null where id = $1 and tenant_id = $2", id, s.tenantID)
+	return err
+}
+
+func (s *PostgresStore) PurgeQuotes(before time.Time) (int64, error) {
+	res, err := s.db.Exec("delete from transfer_quote where expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
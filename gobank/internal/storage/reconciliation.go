@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+// Reconcile runs across all tenants. Balance and ledger are read by the same
+// statement, so a transfer committing halfway through can't show up as a
+// discrepancy. History entries don't post, so they aren't part of the
+// ledger balance.
+func (s *PostgresStore) Reconcile(now time.Time) ([]*domain.ReconciliationIssue, error) {
+	rows, err := s.db.Query(`insert into reconciliation_issue
+							 (tenant_id,account_id,currency,account_balance,ledger_balance,detected_at)
+								select a.tenant_id, a.id, a.currency, a.balance, coalesce(l.total, 0), $1
+								from account a left join (
+									select account_id, sum(amount) as total from (
+										select account_id, type, amount from transaction
+										union all
+										select account_id, type, amount from transaction_archive
+									) entries where type <> $2 group by account_id
+								) l on l.account_id = a.id
+								where a.balance <> coalesce(l.total, 0)
+							 on conflict (account_id) where resolved_at is null
+							 do update set account_balance = excluded.account_balance, ledger_balance = excluded.ledger_balance
+							 returning `+reconciliationColumns, now, domain.TransactionHistory)
+	if err != nil {
+		return nil, err
+	}
+	return scanReconciliationIssues(rows)
+}
+
+func (s *PostgresStore) ListReconciliationIssues(status string) ([]*domain.ReconciliationIssue, error) {
+	rows, err := s.db.Query(`select `+reconciliationColumns+` from reconciliation_issue
+							 where $1 = '' or ($1 = 'open') = (resolved_at is null)
+							 order by id desc limit 100`, status)
+	if err != nil {
+		return nil, err
+	}
+	return scanReconciliationIssues(rows)
+}
+
+func (s *PostgresStore) ResolveReconciliationIssue(id int, resolution string, now time.Time) error {
+	res, err := s.db.Exec(`update reconciliation_issue set resolved_at = $2, resolution = $3
+							 where id = $1 and resolved_at is null`, id, now, resolution)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrIssueNotFound, id)
+	}
+	return nil
+}
+
+const reconciliationColumns = "id,tenant_id,account_id,currency,account_balance,ledger_balance,detected_at,resolved_at,resolution"
+
+func scanReconciliationIssues(rows *sql.Rows) ([]*domain.ReconciliationIssue, error) {
+	defer rows.Close()
+	issues := []*domain.ReconciliationIssue{}
+	for rows.Next() {
+		issue := new(domain.ReconciliationIssue)
+		if err := rows.Scan(
+			&issue.ID,
+			&issue.TenantID,
+			&issue.AccountID,
+			&issue.Currency,
+			&issue.AccountBalance.MinorUnits,
+			&issue.LedgerBalance.MinorUnits,
+			&issue.DetectedAt,
+			&issue.ResolvedAt,
+			&issue.Resolution); err != nil {
+			return nil, err
+		}
+		issue.AccountBalance.Currency = issue.Currency
+		issue.LedgerBalance.Currency = issue.Currency
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
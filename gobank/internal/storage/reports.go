@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) DailyReport(from, to time.Time) ([]*domain.DailyReportRow, error) {
+	rows, err := s.db.Query(`with accounts as (
+								select (created_at at time zone 'UTC')::date as day, currency, count(*) as n
+								from account where tenant_id = $1 and created_at >= $2 and created_at < $3
+								group by 1, 2
+							 ), transfers as (
+								select (created_at at time zone 'UTC')::date as day, currency,
+								count(*) filter (where type = $4) as n,
+								coalesce(-sum(amount) filter (where type = $4), 0) as volume,
+								coalesce(-sum(amount) filter (where type = $5), 0) as fees
+								from transaction where tenant_id = $1 and created_at >= $2 and created_at < $3
+								group by 1, 2
+							 )
+							 select to_char(coalesce(a.day, t.day), 'YYYY-MM-DD') as day, coalesce(a.currency, t.currency) as currency,
+							 coalesce(a.n, 0), coalesce(t.n, 0), coalesce(t.volume, 0), coalesce(t.fees, 0)
+							 from accounts a full join transfers t on a.day = t.day and a.currency = t.currency
+							 order by day, currency`, s.tenantID, from, to, domain.TransactionTransferOut, domain.TransactionFee)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := []*domain.DailyReportRow{}
+	for rows.Next() {
+		row := new(domain.DailyReportRow)
+		if err := rows.Scan(&row.Date, &row.Currency, &row.NewAccounts, &row.Transfers, &row.TransferVolume.MinorUnits, &row.FeeRevenue.MinorUnits); err != nil {
+			return nil, err
+		}
+		row.TransferVolume.Currency = row.Currency
+		row.FeeRevenue.Currency = row.Currency
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
+
+// LedgerTotals sums the hot and archived ledger entries of [from, to) by UTC
+// day, currency and type, in that order. An empty currency sums all of them.
+func (s *PostgresStore) LedgerTotals(from, to time.Time, currency string) ([]*domain.LedgerTotal, error) {
+	rows, err := s.db.Query(`select to_char((created_at at time zone 'UTC')::date, 'YYYY-MM-DD'), currency, type, sum(amount), count(*) from (
+								select created_at, currency, type, amount from transaction
+								where tenant_id = $1 and created_at >= $2 and created_at < $3
+								union all
+								select created_at, currency, type, amount from transaction_archive
+								where tenant_id = $1 and created_at >= $2 and created_at < $3
+							 ) entries where $4 = '' or currency = $4
+							 group by 1, 2, 3 order by 1, 2, 3`, s.tenantID, from, to, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	totals := []*domain.LedgerTotal{}
+	for rows.Next() {
+		t := new(domain.LedgerTotal)
+		if err := rows.Scan(&t.Date, &t.Currency, &t.Type, &t.Amount.MinorUnits, &t.Entries); err != nil {
+			return nil, err
+		}
+		t.Amount.Currency = t.Currency
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) SaveSaga(saga *domain.Saga) error {
+	saga.TenantID = s.tenantID
+	steps, err := json.Marshal(saga.Steps)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`insert into saga (id,tenant_id,account_id,type,status,steps,error,created_at,updated_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+							 on conflict (id) do update set status = excluded.status, steps = excluded.steps,
+							 error = excluded.error, updated_at = excluded.updated_at`,
+		saga.ID, saga.TenantID, saga.AccountID, saga.Type, saga.Status, string(steps), saga.Error, saga.CreatedAt, saga.UpdatedAt)
+	return err
+}
+
+func (s *PostgresStore) StuckSagas() ([]*domain.Saga, error) {
+	staleBefore := s.clock.Now().UTC().Add(-domain.SagaStaleAfter)
+	rows, err := s.db.Query(`select id, tenant_id, account_id, type, status, steps, error, created_at, updated_at from saga
+							 where status = 'stuck' or (status in ('running', 'compensating') and updated_at < $1)
+							 order by updated_at limit 100`, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sagas := []*domain.Saga{}
+	for rows.Next() {
+		saga := &domain.Saga{}
+		var steps string
+		if err := rows.Scan(&saga.ID, &saga.TenantID, &saga.AccountID, &saga.Type, &saga.Status, &steps, &saga.Error, &saga.CreatedAt, &saga.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(steps), &saga.Steps); err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, saga)
+	}
+	return sagas, rows.Err()
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) TopUp(accountID int, amount domain.Money, description string) (*domain.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currency string
+	err = tx.QueryRow("select currency from account where id = $1 and tenant_id = $2 for update", accountID, s.tenantID).Scan(&currency)
+	if err != nil {
+		return nil, domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	if amount.Currency != currency {
+		return nil, domain.ErrCurrencyMismatch
+	}
+	now := s.clock.Now().UTC()
+	if _, err := tx.Exec("update account set balance = balance + $2, version = version + 1, updated_at = $3 where id = $1", accountID, amount.MinorUnits, now); err != nil {
+		return nil, err
+	}
+	t := &domain.Transaction{AccountID: accountID, TenantID: s.tenantID, Type: domain.TransactionSandbox, Amount: amount, Description: description, CreatedAt: now}
+	if err := insertTransaction(tx, t); err != nil {
+		return nil, err
+	}
+	change := domain.BalanceChange{Amount: amount.MinorUnits, TransactionID: t.ID, Entries: 1}
+	if err := s.appendAccountEvent(tx, accountID, domain.AccountBalanceChanged, change); err != nil {
+		return nil, err
+	}
+	ev, err := domain.NewEvent(domain.EventSandboxTopUp, accountID, map[string]any{
+		"transactionId": t.ID,
+		"amount":        amount.MinorUnits,
+		"currency":      amount.Currency,
+	}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return nil, err
+	}
+	return t, tx.Commit()
+}
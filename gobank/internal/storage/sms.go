@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+const SendSMSJobType = "send_sms"
+
+// SMSJob is the payload of a SendSMSJobType job.
+type SMSJob struct {
+	SMSID string `json:"smsId"`
+}
+
+// QueueSMS saves the message with the job that sends it, in one db
+// transaction. A message whose dedupe key was queued before adds nothing and
+// reports false.
+func (s *PostgresStore) QueueSMS(m *domain.SMS) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into sms_message (id,tenant_id,account_id,kind,dedupe_key,recipient,body,status,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+							 on conflict (dedupe_key) do nothing`,
+		m.ID, m.TenantID, m.AccountID, m.Kind, m.DedupeKey, m.To.Reveal(), m.Body, m.Status, m.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	job, err := domain.NewJob(SendSMSJobType, SMSJob{SMSID: m.ID}, s.clock.Now())
+	if err != nil {
+		return false, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetSMS returns a message of any tenant.
+func (s *PostgresStore) GetSMS(id string) (*domain.SMS, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrSMSNotFound, id)
+	}
+	m := &domain.SMS{ID: id}
+	var providerID sql.NullString
+	var sentAt sql.NullTime
+	err := s.db.QueryRow(`select tenant_id, account_id, kind, dedupe_key, recipient, body,
+							 status, attempts, last_error, provider_id, created_at, sent_at
+							 from sms_message where id = $1`, id).
+		Scan(&m.TenantID, &m.AccountID, &m.Kind, &m.DedupeKey, &m.To, &m.Body,
+			&m.Status, &m.Attempts, &m.LastError, &providerID, &m.CreatedAt, &sentAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrSMSNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.ProviderID = providerID.String
+	m.SentAt = nullTime(sentAt)
+	return m, nil
+}
+
+// RecordSMSAttempt saves the status, attempt count, error and provider id
+// an attempt left the message with.
+func (s *PostgresStore) RecordSMSAttempt(m *domain.SMS) error {
+	_, err := s.db.Exec(`update sms_message set status = $2, attempts = $3, last_error = $4, provider_id = nullif($5, ''), sent_at = $6
+							 where id = $1`,
+		m.ID, m.Status, m.Attempts, m.LastError, m.ProviderID, m.SentAt)
+	return err
+}
+
+// SavePhoneVerification replaces the account's verification, so only the
+// code sent last is good.
+func (s *PostgresStore) SavePhoneVerification(v *domain.PhoneVerification) error {
+	_, err := s.db.Exec(`insert into phone_verification (account_id,phone,code_hash,attempts,sent_at,expires_at)
+							 select $1,$2,$3,$4,$5,$6 where exists (select 1 from account where id = $1 and tenant_id = $7)
+							 on conflict (account_id) do update set phone = excluded.phone, code_hash = excluded.code_hash,
+							 attempts = excluded.attempts, sent_at = excluded.sent_at, expires_at = excluded.expires_at`,
+		v.AccountID, v.Phone.Reveal(), v.CodeHash, v.Attempts, v.SentAt, v.ExpiresAt, s.tenantID)
+	return err
+}
+
+// GetPhoneVerification returns the account's verification, nil when no code
+// was sent since the last one was confirmed.
+func (s *PostgresStore) GetPhoneVerification(accountID int) (*domain.PhoneVerification, error) {
+	v := &domain.PhoneVerification{AccountID: accountID}
+	err := s.db.QueryRow(`select v.phone, v.code_hash, v.attempts, v.sent_at, v.expires_at
+							 from phone_verification v join account a on a.id = v.account_id
+							 where v.account_id = $1 and a.tenant_id = $2`, accountID, s.tenantID).
+		Scan(&v.Phone, &v.CodeHash, &v.Attempts, &v.SentAt, &v.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+// CountVerificationAttempt counts a wrong guess at the account's code.
+func (s *PostgresStore) CountVerificationAttempt(accountID int) error {
+	_, err := s.db.Exec("update phone_verification set attempts = attempts + 1 where account_id = $1", accountID)
+	return err
+}
+
+// ConfirmPhone marks the account's phone verified and drops its
+// verification, unless the phone changed since the code was sent to it.
+func (s *PostgresStore) ConfirmPhone(account *domain.Account, phone domain.PII) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now().UTC()
+	err = tx.QueryRow(`update account set phone_verified = true, version = version + 1, updated_at = $3
+							 where id = $1 and tenant_id = $2 and phone = $4 returning version`,
+		account.ID, s.tenantID, now, phone.Reveal()).Scan(&account.Version)
+	if err == sql.ErrNoRows {
+		return domain.ErrVerificationFailed
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("delete from phone_verification where account_id = $1", account.ID); err != nil {
+		return err
+	}
+	account.PhoneVerified = true
+	account.UpdatedAt = now
+	if err := s.appendAccountEvent(tx, account.ID, domain.AccountPhoneVerified, struct{}{}); err != nil {
+		return err
+	}
+	ev, err := domain.NewEvent(domain.EventAccountUpdated, account.ID, map[string]int{"version": account.Version}, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
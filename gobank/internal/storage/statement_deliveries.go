@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+const DeliverStatementJobType = "deliver_statement"
+
+// StatementDeliveryJob is the payload of a DeliverStatementJobType job.
+type StatementDeliveryJob struct {
+	DeliveryID string `json:"deliveryId"`
+}
+
+func (s *PostgresStore) CreateStatementDestination(d *domain.StatementDestination) error {
+	d.TenantID = s.tenantID
+	res, err := s.db.Exec(`insert into statement_destination (id,tenant_id,account_id,sink,frequency,email,url,password,host_key,created_at)
+							 select $1,$2,$3,$4,$5,$6,$7,$8,$9,$10 where exists (select 1 from account where id = $3 and tenant_id = $2)`,
+		d.ID, d.TenantID, d.AccountID, d.Sink, d.Frequency, d.Email.Reveal(), d.URL, d.Password.Reveal(), d.HostKey, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrAccountNotFound, d.AccountID)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListStatementDestinations(accountID int) ([]*domain.StatementDestination, error) {
+	rows, err := s.db.Query(`select id, sink, frequency, email, url, host_key, created_at from statement_destination
+							 where account_id = $1 and tenant_id = $2 order by created_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	destinations := []*domain.StatementDestination{}
+	for rows.Next() {
+		d := &domain.StatementDestination{AccountID: accountID, TenantID: s.tenantID}
+		if err := rows.Scan(&d.ID, &d.Sink, &d.Frequency, &d.Email, &d.URL, &d.HostKey, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, rows.Err()
+}
+
+func (s *PostgresStore) DeleteStatementDestination(accountID int, id string) error {
+	if !domain.IsUUID(id) {
+		return domain.NotFound(domain.ErrStatementDestinationNotFound, id)
+	}
+	res, err := s.db.Exec("delete from statement_destination where id = $1 and account_id = $2 and tenant_id = $3", id, accountID, s.tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrStatementDestinationNotFound, id)
+	}
+	return nil
+}
+
+// QueueStatementDelivery saves the delivery with its job in one db
+// transaction, so a statement written again, or its event relayed again,
+// doesn't deliver it twice.
+func (s *PostgresStore) QueueStatementDelivery(d *domain.StatementDelivery) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`insert into statement_delivery (id,tenant_id,destination_id,account_id,period_start,period_end,status,created_at)
+							 values ($1,$2,$3,$4,$5,$6,$7,$8)
+							 on conflict (destination_id, period_start, period_end) do nothing`,
+		d.ID, d.TenantID, d.DestinationID, d.AccountID, d.From, d.To, d.Status, d.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	job, err := domain.NewJob(DeliverStatementJobType, StatementDeliveryJob{DeliveryID: d.ID}, s.clock.Now())
+	if err != nil {
+		return false, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *PostgresStore) ListStatementDeliveries(accountID int, destinationID string, limit int) ([]*domain.StatementDelivery, error) {
+	if err := s.checkStatementDestination(s.db, accountID, destinationID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`select `+statementDeliveryColumns+` from statement_delivery
+							 where destination_id = $1 order by period_end desc, created_at desc limit $2`, destinationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []*domain.StatementDelivery{}
+	for rows.Next() {
+		d, err := scanStatementDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+const statementDeliveryColumns = `id, tenant_id, destination_id, account_id, to_char(period_start, 'YYYY-MM-DD'), to_char(period_end, 'YYYY-MM-DD'),
+							 status, attempts, last_error, created_at, delivered_at`
+
+func scanStatementDelivery(row interface{ Scan(...any) error }) (*domain.StatementDelivery, error) {
+	d := &domain.StatementDelivery{}
+	var deliveredAt sql.NullTime
+	err := row.Scan(&d.ID, &d.TenantID, &d.DestinationID, &d.AccountID, &d.From, &d.To,
+		&d.Status, &d.Attempts, &d.LastError, &d.CreatedAt, &deliveredAt)
+	d.DeliveredAt = nullTime(deliveredAt)
+	return d, err
+}
+
+func (s *PostgresStore) GetStatementDelivery(id string) (*domain.StatementDelivery, *domain.StatementDestination, error) {
+	if !domain.IsUUID(id) {
+		return nil, nil, domain.NotFound(domain.ErrStatementDeliveryNotFound, id)
+	}
+	d, err := scanStatementDelivery(s.db.QueryRow(`select `+statementDeliveryColumns+` from statement_delivery where id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil, domain.NotFound(domain.ErrStatementDeliveryNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	dest := &domain.StatementDestination{ID: d.DestinationID, AccountID: d.AccountID, TenantID: d.TenantID}
+	err = s.db.QueryRow(`select sink, frequency, email, url, password, host_key, created_at from statement_destination where id = $1`, d.DestinationID).
+		Scan(&dest.Sink, &dest.Frequency, &dest.Email, &dest.URL, &dest.Password, &dest.HostKey, &dest.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, domain.NotFound(domain.ErrStatementDeliveryNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, dest, nil
+}
+
+// RecordStatementAttempt saves the status, attempt count and error an
+// attempt left the delivery with.
+func (s *PostgresStore) RecordStatementAttempt(d *domain.StatementDelivery) error {
+	_, err := s.db.Exec("update statement_delivery set status = $2, attempts = $3, last_error = $4, delivered_at = $5 where id = $1",
+		d.ID, d.Status, d.Attempts, d.LastError, d.DeliveredAt)
+	return err
+}
+
+func (s *PostgresStore) RetryStatementDelivery(accountID int, destinationID, deliveryID string) (*domain.Job, error) {
+	if !domain.IsUUID(deliveryID) {
+		return nil, domain.NotFound(domain.ErrStatementDeliveryNotFound, deliveryID)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkStatementDestination(tx, accountID, destinationID); err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec("update statement_delivery set status = $3, delivered_at = null where id = $1 and destination_id = $2",
+		deliveryID, destinationID, domain.DeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, domain.NotFound(domain.ErrStatementDeliveryNotFound, deliveryID)
+	}
+	job, err := domain.NewJob(DeliverStatementJobType, StatementDeliveryJob{DeliveryID: deliveryID}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+// checkStatementDestination fails with ErrStatementDestinationNotFound
+// unless the destination belongs to the account.
+func (s *PostgresStore) checkStatementDestination(q rowQuerier, accountID int, id string) error {
+	var exists bool
+	if domain.IsUUID(id) {
+		err := q.QueryRow("select exists(select 1 from statement_destination where id = $1 and account_id = $2 and tenant_id = $3)",
+			id, accountID, s.tenantID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+	}
+	if !exists {
+		return domain.NotFound(domain.ErrStatementDestinationNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,694 @@
+// Package storage persists the domain types in Postgres and keeps backups
+// in a blob store.
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+	"github.com/joho/godotenv"
+	"github.com/lib/pq"
+	"log/slog"
+	"os"
+	"time"
+)
+
+type Storage interface {
+	CreateAccount(account *domain.Account) error
+	// DeleteAccount deletes the account if it's still at version, 0 deletes
+	// it regardless.
+	DeleteAccount(id, version int) error
+	// UpdateAccount saves the profile fields if the account is still at
+	// account.Version and bumps the version.
+	UpdateAccount(account *domain.Account) error
+	GetAccountById(id int) (*domain.Account, error)
+	GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error)
+	GetAccountByIBAN(iban string) (*domain.Account, error)
+	// GetAccountByAlias finds the account of an email address or phone number.
+	GetAccountByAlias(alias domain.PII) (*domain.Account, error)
+	GetAccountByUUID(uuid string) (*domain.Account, error)
+	// FindAccount looks an account of any tenant up by its id, for the
+	// background work that only has an event's account id to go by.
+	FindAccount(id int) (*domain.Account, error)
+	Transfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.Transaction, error)
+	// TransferLegs returns the outgoing transaction of a transfer, of any
+	// tenant, and the incoming one on the recipient's account, nil if that
+	// account is gone.
+	TransferLegs(outID int) (out, in *domain.Transaction, err error)
+	ArchiveStore
+	JobStore
+	OutboxStore
+	Locker
+	TenantStore
+	TenantSettingsStore
+	AnalyticsStore
+	ActivityStore
+	FeedStore
+	ImportStore
+	ExportStore
+	UsageStore
+	ApiKeyStore
+	AdminStore
+	AccountExportStore
+	StreamStore
+	SummaryStore
+	ReportStore
+	ReconciliationStore
+	LedgerStore
+	SandboxStore
+	QuoteStore
+	HoldStore
+	BatchTransferStore
+	TransferRequestStore
+	SagaStore
+	WebhookStore
+	EmailStore
+	SMSStore
+	PushStore
+	NotificationStore
+	BalanceStore
+	StatementDeliveryStore
+	BatchFileStore
+	WarehouseStore
+	ProjectionStore
+	AccountEventStore
+	ImpersonationStore
+	TermsStore
+	ConsentStore
+	SentSince(accountID int, since time.Time) (int64, error)
+	// ForTenant returns a Storage whose account and transaction queries are
+	// scoped to the given tenant.
+	ForTenant(tenantID int) Storage
+}
+
+type PostgresStore struct {
+	db       *sql.DB
+	tenantID int
+	clock    domain.Clock
+	logger   *slog.Logger
+	// accountEvents is whether account changes are appended to the account
+	// event streams, see account_events.go.
+	accountEvents bool
+}
+
+func NewPostgresStore(logger *slog.Logger, metrics MetricsSink, clock domain.Clock, slowQuery func() time.Duration) (*PostgresStore, error) {
+	err := godotenv.Load(".env")
+	if err != nil {
+		return nil, err
+	}
+	dbCon, err := openInstrumentedDB(os.Getenv("POSTGRES_URL"), metrics, logger, slowQuery)
+	if err != nil {
+		return nil, err
+	}
+	err = dbCon.Ping()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("successfully connected to DB")
+	return &PostgresStore{db: dbCon, clock: clock, logger: logger}, nil
+}
+
+func (s *PostgresStore) ForTenant(tenantID int) Storage {
+	return &PostgresStore{db: s.db, tenantID: tenantID, clock: s.clock, logger: s.logger.With("tenant_id", tenantID), accountEvents: s.accountEvents}
+}
+
+// Close closes the connection pool shared by every tenant's store.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *PostgresStore) Init() error {
+	if err := s.CreateTenantTable(); err != nil {
+		return err
+	}
+	tenant, err := s.GetTenantBySlug(domain.DefaultTenantSlug)
+	if err != nil {
+		return err
+	}
+	s.tenantID = tenant.ID
+	if err := s.CreateTenantSettingsTable(); err != nil {
+		return err
+	}
+	if err := s.CreateAccountTable(); err != nil {
+		return err
+	}
+	if err := s.CreateTransactionTables(); err != nil {
+		return err
+	}
+	if err := s.CreateJobTable(); err != nil {
+		return err
+	}
+	if err := s.CreateOutboxTable(); err != nil {
+		return err
+	}
+	return s.Migrate()
+}
+
+func (s *PostgresStore) CreateAccountTable() error {
+	query := `create table if not exists account (
+    			id serial primary key,
+                first_name varchar(50),
+    			last_name varchar(50),
+    			number serial,
+    			encrypted_password varchar(500),
+    			balance serial,
+    			created_at timestamp,
+    			tenant_id integer not null references tenant(id)
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	return s.addTenantColumn("account", "references tenant(id)")
+}
+
+// addTenantColumn gives a table from before tenants a tenant_id, filling it
+// in with the default tenant for the rows already there. The column has no
+// default, so a row inserted without a tenant fails the not null.
+func (s *PostgresStore) addTenantColumn(table, constraint string) error {
+	if _, err := s.db.Exec("alter table " + table + " add column if not exists tenant_id integer " + constraint); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("update "+table+" set tenant_id = $1 where tenant_id is null", s.tenantID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("alter table " + table + " alter column tenant_id set not null")
+	return err
+}
+
+func (s *PostgresStore) CreateTransactionTables() error {
+	query := `create table if not exists transaction (
+    			id serial primary key,
+    			account_id integer references account(id) on delete cascade,
+    			type varchar(30),
+    			amount bigint,
+    			counterparty bigint,
+    			created_at timestamp,
+    			tenant_id integer not null references tenant(id)
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	if err := s.addTenantColumn("transaction", "references tenant(id)"); err != nil {
+		return err
+	}
+	query = `create table if not exists transaction_archive (
+    			like transaction including defaults,
+    			archived_at timestamp default now()
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	return s.addTenantColumn("transaction_archive", "")
+}
+
+func (s *PostgresStore) CreateAccount(account *domain.Account) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `insert into account 
+							 (first_name,last_name,number,encrypted_password,balance,created_at,tenant_id,email,currency,timezone,language,updated_at,version,uuid,address,date_of_birth,iban,phone,nickname,avatar,locale) 
+								values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,nullif($17,''),$18,$19,$20,$21) returning id`
+	account.TenantID = s.tenantID
+	if account.UUID == "" {
+		account.UUID = domain.NewUUID()
+	}
+	email := sql.NullString{String: account.Email.Reveal(), Valid: account.Email != ""}
+	phone := sql.NullString{String: account.Phone.Reveal(), Valid: account.Phone != ""}
+	address, dob, err := profileColumns(account)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(query, account.FirstName, account.LastName, account.Number, account.EncryptedPassword, account.Balance.MinorUnits, account.CreatedAt, account.TenantID, email, account.Balance.Currency, account.Timezone, account.Language, account.UpdatedAt, account.Version, account.UUID, address, dob, account.IBAN, phone, account.Nickname, account.Avatar, account.Locale).Scan(&account.ID)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	if err := s.appendAccountOpened(tx, account.ID); err != nil {
+		return err
+	}
+	ev, err := domain.NewEvent(domain.EventAccountCreated, account.ID, map[string]domain.AccountNumber{"number": account.Number}, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) UpdateAccount(account *domain.Account) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now().UTC()
+	email := sql.NullString{String: account.Email.Reveal(), Valid: account.Email != ""}
+	phone := sql.NullString{String: account.Phone.Reveal(), Valid: account.Phone != ""}
+	address, dob, err := profileColumns(account)
+	if err != nil {
+		return err
+	}
+	// a new phone number isn't verified, and SMS alerts stay off until it is
+	err = tx.QueryRow(`update account set first_name = $3, last_name = $4, email = $5, timezone = $6, language = $7,
+							 version = version + 1, updated_at = $8, address = $10, date_of_birth = $11, phone = $12, nickname = $13, avatar = $14, locale = $15,
+							 phone_verified = phone_verified and phone is not distinct from $12,
+							 sms_alerts = $16 and phone_verified and phone is not distinct from $12
+							 where id = $1 and tenant_id = $2 and version = $9 returning version, phone_verified, sms_alerts`,
+		account.ID, s.tenantID, account.FirstName, account.LastName, email, account.Timezone, account.Language, now, account.Version, address, dob, phone, account.Nickname, account.Avatar, account.Locale, account.SMSAlerts).
+		Scan(&account.Version, &account.PhoneVerified, &account.SMSAlerts)
+	if err == sql.ErrNoRows {
+		return domain.ErrVersionConflict
+	}
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	account.UpdatedAt = now
+	if err := s.appendAccountEvent(tx, account.ID, domain.AccountProfileChanged, domain.ProfileOf(account)); err != nil {
+		return err
+	}
+	ev, err := domain.NewEvent(domain.EventAccountUpdated, account.ID, map[string]int{"version": account.Version}, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) DeleteAccount(id, version int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deleted int
+	err = tx.QueryRow("delete from account where id = $1 and tenant_id = $2 and ($3 = 0 or version = $3) returning version", id, s.tenantID, version).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		if version != 0 {
+			return domain.ErrVersionConflict
+		}
+		return domain.NotFound(domain.ErrAccountNotFound, id)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.appendAccountClosed(tx, id, deleted+1); err != nil {
+		return err
+	}
+	ev, err := domain.NewEvent(domain.EventAccountDeleted, id, map[string]int{"id": id}, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Transfer moves amount from the given account to the account with toNumber,
+// recording a ledger row on each side and a transfer.completed outbox event.
+// It returns the sender's transaction.
+func (s *PostgresStore) Transfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	toID, err := s.lockTransfer(tx, from.ID, toNumber, amount, "", guard)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.postTransfer(tx, from, toID, toNumber, amount)
+	if err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+// TransferBatch posts the orders in one db transaction. The guard is checked
+// for each order with the ones before it counted as sent.
+func (s *PostgresStore) TransferBatch(from *domain.Account, orders []domain.TransferOrder, guard *domain.TransferGuard) ([]*domain.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// lock every row of the batch up front and in id order, the same order
+	// lockTransfer uses, so two batches can't deadlock on each other
+	numbers := make([]int64, len(orders))
+	for i, o := range orders {
+		numbers[i] = int64(o.ToAccount)
+	}
+	_, err = tx.Exec(`select id from account where tenant_id = $3 and (id = $1 or number = any($2)) order by id for update`,
+		from.ID, pq.Array(numbers), s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*domain.Transaction, 0, len(orders))
+	for i, o := range orders {
+		toID, err := s.lockTransfer(tx, from.ID, o.ToAccount, o.Amount, "", guard)
+		if err != nil {
+			return nil, &domain.BatchItemError{Index: i, Err: err}
+		}
+		out, err := s.postTransfer(tx, from, toID, o.ToAccount, o.Amount)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, out)
+	}
+	return txs, tx.Commit()
+}
+
+// lockTransfer locks the rows of both sides of a transfer and checks that it
+// can be made from the sender's available funds, see availability; the hold
+// exceptHold isn't counted. The guard is checked against what the sender
+// sent with the row locked, so concurrent transfers can't all pass its
+// limits, nor a double submit its duplicate check. It returns the
+// recipient's id.
+func (s *PostgresStore) lockTransfer(tx *sql.Tx, fromID int, toNumber domain.AccountNumber, amount domain.Money, exceptHold string, guard *domain.TransferGuard) (int, error) {
+	// lock both rows in id order so concurrent opposite transfers can't deadlock
+	rows, err := tx.Query(`select id, number, balance, currency from account
+							 where tenant_id = $3 and (id = $1 or number = $2) order by id for update`, fromID, toNumber, s.tenantID)
+	if err != nil {
+		return 0, err
+	}
+	var fromBalance domain.Money
+	toID := 0
+	toCurrency := ""
+	for rows.Next() {
+		var id int
+		var number domain.AccountNumber
+		var balance domain.Money
+		if err := rows.Scan(&id, &number, &balance.MinorUnits, &balance.Currency); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if id == fromID {
+			fromBalance = balance
+		}
+		if number == toNumber {
+			toID = id
+			toCurrency = balance.Currency
+		}
+	}
+	rows.Close()
+	if toID == 0 {
+		return 0, domain.NotFound(domain.ErrAccountNotFound, toNumber)
+	}
+	if toID == fromID {
+		return 0, domain.ErrSameAccount
+	}
+	if amount.Currency != fromBalance.Currency || toCurrency != fromBalance.Currency {
+		return 0, domain.ErrCurrencyMismatch
+	}
+	if guard != nil {
+		sent, err := s.sentSince(tx, fromID, guard.DayStart)
+		if err != nil {
+			return 0, err
+		}
+		if err := guard.Settings.CheckTransfer(amount.MinorUnits, sent); err != nil {
+			return 0, err
+		}
+		if err := s.checkDuplicate(tx, fromID, toNumber, amount, guard); err != nil {
+			return 0, err
+		}
+	}
+	available, err := s.availability(tx, fromID, exceptHold)
+	if err != nil {
+		return 0, err
+	}
+	if err := available.Check(amount.MinorUnits); err != nil {
+		return 0, err
+	}
+	return toID, nil
+}
+
+// checkDuplicate fails with a *domain.DuplicateTransferError if the guard
+// looks for duplicates and the account sent amount to the account numbered
+// to since guard.DuplicateSince, or has such a transfer waiting to be
+// processed. The caller holds the lock on the account's row.
+func (s *PostgresStore) checkDuplicate(tx *sql.Tx, accountID int, to domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) error {
+	if guard == nil || guard.DuplicateSince.IsZero() {
+		return nil
+	}
+	// $5 is the amount as the sender's transfer_out stores it, a debit, so
+	// negative. A transfer_request stores what is to be sent, positive, hence
+	// the -$5 it is compared with.
+	var found bool
+	err := tx.QueryRow(`select exists (
+								select 1 from transaction
+								where account_id = $1 and tenant_id = $2 and type = $3 and counterparty = $4
+								and amount = $5 and currency = $6 and created_at >= $7
+							 ) or exists (
+								select 1 from transfer_request
+								where account_id = $1 and tenant_id = $2 and status in ($8, $9) and to_number = $4
+								and amount = -$5 and currency = $6 and created_at >= $7
+							 )`,
+		accountID, s.tenantID, domain.TransactionTransferOut, to, -amount.MinorUnits, amount.Currency, guard.DuplicateSince,
+		domain.TransferPending, domain.TransferInReview).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found {
+		return &domain.DuplicateTransferError{Minutes: guard.Settings.DuplicateTransferMinutes}
+	}
+	return nil
+}
+
+// postTransfer books a transfer lockTransfer checked and returns the sender's
+// transaction.
+func (s *PostgresStore) postTransfer(tx *sql.Tx, from *domain.Account, toID int, toNumber domain.AccountNumber, amount domain.Money) (*domain.Transaction, error) {
+	now := s.clock.Now().UTC()
+	if _, err := tx.Exec("update account set balance = balance - $2, version = version + 1, updated_at = $3 where id = $1", from.ID, amount.MinorUnits, now); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("update account set balance = balance + $2, version = version + 1, updated_at = $3 where id = $1", toID, amount.MinorUnits, now); err != nil {
+		return nil, err
+	}
+
+	debit := domain.Money{MinorUnits: -amount.MinorUnits, Currency: amount.Currency}
+	out := &domain.Transaction{AccountID: from.ID, TenantID: s.tenantID, Type: domain.TransactionTransferOut, Amount: debit, Counterparty: toNumber, CreatedAt: now}
+	in := &domain.Transaction{AccountID: toID, TenantID: s.tenantID, Type: domain.TransactionTransferIn, Amount: amount, Counterparty: from.Number, CreatedAt: now}
+	for _, t := range []*domain.Transaction{out, in} {
+		if err := insertTransaction(tx, t); err != nil {
+			return nil, err
+		}
+		change := domain.BalanceChange{Amount: t.Amount.MinorUnits, TransactionID: t.ID, Entries: 1}
+		if err := s.appendAccountEvent(tx, t.AccountID, domain.AccountBalanceChanged, change); err != nil {
+			return nil, err
+		}
+	}
+
+	ev, err := domain.NewEvent(domain.EventTransferCompleted, from.ID, map[string]any{
+		"transactionId": out.ID,
+		"from":          from.Number,
+		"to":            toNumber,
+		"amount":        amount.MinorUnits,
+		"currency":      amount.Currency,
+	}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOutboxEvent(tx, ev); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// insertTransaction chains t onto the account's last ledger entry. The caller
+// must hold the account row lock so that no other entry slips in between.
+func insertTransaction(tx *sql.Tx, t *domain.Transaction) error {
+	prev, err := lastLedgerHash(tx, t.AccountID)
+	if err != nil {
+		return err
+	}
+	t.Hash = t.ComputeHash(prev)
+	query := `insert into transaction
+							 (account_id,type,amount,counterparty,created_at,tenant_id,currency,description,hash)
+								values ($1,$2,$3,$4,$5,$6,$7,$8,$9) returning id`
+	return tx.QueryRow(query, t.AccountID, t.Type, t.Amount.MinorUnits, t.Counterparty, t.CreatedAt, t.TenantID, t.Amount.Currency, t.Description, t.Hash).Scan(&t.ID)
+}
+
+func lastLedgerHash(tx *sql.Tx, accountID int) (string, error) {
+	var hash string
+	err := tx.QueryRow(`select hash from (
+								select id, hash from transaction where account_id = $1
+								union all
+								select id, hash from transaction_archive where account_id = $1
+							 ) entries order by id desc limit 1`, accountID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+func (s *PostgresStore) GetAccountById(id int) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where id = $1 and tenant_id = $2", id, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, id)
+}
+
+const accountColumns = "id, first_name, last_name, number, encrypted_password, balance, created_at, tenant_id, email, currency, timezone, language, updated_at, version, uuid, address, to_char(date_of_birth, 'YYYY-MM-DD'), coalesce(iban, ''), coalesce(phone, ''), nickname, avatar, locale, phone_verified, sms_alerts"
+
+// profileColumns returns the address and date of birth as written to the
+// account table, nulls when they aren't set. The address goes as a string,
+// lib/pq would send []byte as bytea.
+func profileColumns(account *domain.Account) (address, dob sql.NullString, err error) {
+	if account.Address != nil {
+		data, err := json.Marshal(account.Address)
+		if err != nil {
+			return address, dob, err
+		}
+		address = sql.NullString{String: string(data), Valid: true}
+	}
+	return address, sql.NullString{String: account.DateOfBirth.Reveal(), Valid: account.DateOfBirth != ""}, nil
+}
+
+func scanIntoAccount(rows *sql.Rows) (*domain.Account, error) {
+	account := new(domain.Account)
+	var email, dob sql.NullString
+	var address []byte
+	err := rows.Scan(
+		&account.ID,
+		&account.FirstName,
+		&account.LastName,
+		&account.Number,
+		&account.EncryptedPassword,
+		&account.Balance.MinorUnits,
+		&account.CreatedAt,
+		&account.TenantID,
+		&email,
+		&account.Balance.Currency,
+		&account.Timezone,
+		&account.Language,
+		&account.UpdatedAt,
+		&account.Version,
+		&account.UUID,
+		&address,
+		&dob,
+		&account.IBAN,
+		&account.Phone,
+		&account.Nickname,
+		&account.Avatar,
+		&account.Locale,
+		&account.PhoneVerified,
+		&account.SMSAlerts)
+	if err != nil {
+		return nil, err
+	}
+	account.Email = domain.PII(email.String)
+	account.DateOfBirth = domain.PII(dob.String)
+	if address != nil {
+		account.Address = new(domain.Address)
+		if err := json.Unmarshal(address, account.Address); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+func (s *PostgresStore) GetAccountByNumber(number domain.AccountNumber) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where number = $1 and tenant_id = $2", number, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, number)
+}
+
+// GetAccountByIBAN looks an account up by its IBAN in electronic form.
+func (s *PostgresStore) GetAccountByIBAN(iban string) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where iban = $1 and tenant_id = $2", iban, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, iban)
+}
+
+// GetAccountByAlias looks an account up by its email address, ignoring
+// case, or its phone number in E.164.
+func (s *PostgresStore) GetAccountByAlias(alias domain.PII) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where (lower(email) = lower($1) or phone = $1) and tenant_id = $2", alias.Reveal(), s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, alias)
+}
+
+// GetAccountByUUID looks an account up by the public identifier clients use
+// in URLs.
+func (s *PostgresStore) GetAccountByUUID(uuid string) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where uuid = $1 and tenant_id = $2", uuid, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, uuid)
+}
+
+func (s *PostgresStore) FindAccount(id int) (*domain.Account, error) {
+	rows, err := s.db.Query("select "+accountColumns+" from account where id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		return scanIntoAccount(rows)
+	}
+	return nil, domain.NotFound(domain.ErrAccountNotFound, id)
+}
+
+// ArchiveTransactions moves every transaction created before the cutoff into
+// transaction_archive and removes it from the hot table in a single db transaction.
+func (s *PostgresStore) ArchiveTransactions(before time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`insert into transaction_archive
+							 (id,account_id,type,amount,counterparty,created_at,tenant_id,currency,description,hash,xid)
+								select id,account_id,type,amount,counterparty,created_at,tenant_id,currency,description,hash,xid
+								from transaction where created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("delete from transaction where created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return moved, tx.Commit()
+}
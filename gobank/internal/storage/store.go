@@ -0,0 +1,409 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+// AccountFilter narrows admin account listings. Zero values don't filter.
+type AccountFilter struct {
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Currency    string
+}
+
+type AccountExportStore interface {
+	// AccountsAfter returns up to limit accounts with an id above afterID in id
+	// order, so a whole table can be walked in constant memory.
+	AccountsAfter(afterID int, filter AccountFilter, limit int) ([]*domain.Account, error)
+}
+
+type AdminStore interface {
+	// RecentTransfers returns the tenant's latest outgoing transfers.
+	RecentTransfers(limit int) ([]*domain.Transaction, error)
+	// EventsBefore returns outbox events, published or not, with an id below
+	// beforeID (0 for the newest), newest first. They double as the audit trail
+	// of account changes and transfers.
+	EventsBefore(beforeID int64, limit int) ([]*domain.Event, error)
+}
+
+type ActivityStore interface {
+	// ActivityBefore returns the account's timeline entries of the given
+	// kinds that sort below the cursor, newest first.
+	ActivityBefore(accountID int, kinds []string, before domain.ActivityCursor, limit int) ([]*domain.Activity, error)
+}
+
+type AnalyticsStore interface {
+	// DailyTotals sums the account's credits and debits per calendar day in
+	// the given time zone.
+	DailyTotals(accountID int, since time.Time, loc *time.Location) ([]*domain.DailyTotal, error)
+	// MonthlyTotals returns the account's credits and debits per month of
+	// its time zone from the month since, YYYY-MM, on, oldest first.
+	MonthlyTotals(accountID int, since string) ([]*domain.MonthlyTotal, error)
+	// BalanceTotals sums the ledger balances of the tenant's accounts per
+	// currency.
+	BalanceTotals() ([]*domain.BalanceTotal, error)
+}
+
+// AccountEventStore reads the account event streams, see
+// account_events.go.
+type AccountEventStore interface {
+	AccountEvents(accountID, after, limit int) ([]*domain.AccountEvent, error)
+	AccountStream(accountID int, until time.Time) (*domain.AccountStream, error)
+	AllAccountsAfter(afterID, limit int) ([]*domain.Account, error)
+	SaveAccountSnapshot(account *domain.Account) error
+}
+
+// ProjectionStore maintains the projections of every tenant, see
+// projections.go.
+type ProjectionStore interface {
+	Project(name string, limit int) (int, error)
+	ResetProjection(name string) error
+	Projections() ([]*domain.Projection, error)
+}
+
+type ConsentStore interface {
+	// CreateConsent fails with ErrApiKeyNotFound unless the key is an active
+	// third-party key of the account.
+	CreateConsent(c *domain.Consent) error
+	ListConsents(accountID int) ([]*domain.Consent, error)
+	// ActiveConsents returns the key's consents that are neither revoked nor
+	// expired.
+	ActiveConsents(apiKeyID string) ([]*domain.Consent, error)
+	RevokeConsent(accountID int, id string) (*domain.Consent, error)
+}
+
+type ApiKeyStore interface {
+	CreateApiKey(key *domain.ApiKey) error
+	// GetApiKey returns an active key.
+	GetApiKey(id string) (*domain.ApiKey, error)
+	ListApiKeys(accountID int) ([]*domain.ApiKey, error)
+	RevokeApiKey(accountID int, id string) error
+	// UseNonce records a nonce of a key and fails with a replay error if it
+	// was seen before.
+	UseNonce(keyID, nonce string, at time.Time) error
+	PurgeNonces(before time.Time) (int64, error)
+}
+
+type ArchiveStore interface {
+	ArchiveTransactions(before time.Time) (int64, error)
+}
+
+type OutboxStore interface {
+	// RelayOutbox hands unpublished events to publish in id order and marks
+	// the ones that succeeded as published.
+	RelayOutbox(limit int, publish func(ev *domain.Event) error) (int, error)
+	// RecordEvent adds an event that comes with no other change to the
+	// outbox.
+	RecordEvent(ev *domain.Event) error
+}
+
+type ExportStore interface {
+	// TransactionsBetween returns the account's transactions created in
+	// [from, to), oldest first.
+	TransactionsBetween(accountID int, from, to time.Time) ([]*domain.Transaction, error)
+}
+
+type FeedStore interface {
+	// TransactionsAfter returns up to limit of the account's transactions with
+	// an id above cursor, oldest first.
+	TransactionsAfter(accountID, cursor, limit int) ([]*domain.Transaction, error)
+	// TransactionsBefore returns up to limit of the account's transactions
+	// sorted before the cursor, newest first. A zero cursor starts at the newest.
+	TransactionsBefore(accountID int, before domain.TransactionCursor, limit int) ([]*domain.Transaction, error)
+	// LastTransactionID returns the id of the account's newest transaction,
+	// 0 when it has none.
+	LastTransactionID(accountID int) (int, error)
+}
+
+type ImportStore interface {
+	// ImportTransactions inserts the rows in batches and moves the account's
+	// balance by the sum of those that post, all in one db transaction. Rows
+	// of type history are recorded but leave the balance alone.
+	ImportTransactions(accountID int, txs []*domain.Transaction) error
+}
+
+type JobStore interface {
+	EnqueueJob(job *domain.Job) error
+	// LeaseJob claims the next runnable job for the lease duration. It returns
+	// nil when nothing is due.
+	LeaseJob(lease time.Duration) (*domain.Job, error)
+	// CompleteJob and FailJob finish the lease of the job with the given
+	// attempt, the number LeaseJob handed it out with. They fail with
+	// domain.ErrJobLeaseLost once the job was leased again.
+	CompleteJob(id, attempt int) error
+	FailJob(id, attempt int, reason string, retryAt time.Time, dead bool) error
+	ListJobs(status string) ([]*domain.Job, error)
+	RetryJob(id int) error
+}
+
+// Every ledger entry stores a hash over its own fields and the hash of the
+// account's previous entry, so editing, deleting or reordering history breaks
+// the chain from that entry on. Entries written before the chain was
+// introduced have no hash and are skipped.
+type LedgerStore interface {
+	// EachLedgerEntry calls fn for the account's hot and archived entries in
+	// insertion order, with Hash filled in.
+	EachLedgerEntry(accountID int, fn func(*domain.Transaction) error) error
+}
+
+type StreamStore interface {
+	// EachAccount calls fn for every account of the tenant in id order,
+	// reading one row at a time. An error from fn stops the iteration.
+	EachAccount(fn func(*domain.Account) error) error
+	// EachTransactionBetween is TransactionsBetween one row at a time.
+	EachTransactionBetween(accountID int, from, to time.Time, fn func(*domain.Transaction) error) error
+}
+
+type ReconciliationStore interface {
+	// Reconcile records an open issue for every account whose balance is off
+	// and returns them. Accounts that already have an open issue get its
+	// balances refreshed instead of a second issue.
+	Reconcile(now time.Time) ([]*domain.ReconciliationIssue, error)
+	ListReconciliationIssues(status string) ([]*domain.ReconciliationIssue, error)
+	ResolveReconciliationIssue(id int, resolution string, now time.Time) error
+}
+
+type ReportStore interface {
+	// DailyReport aggregates new accounts, transfers and fees per UTC day and
+	// currency in [from, to). Days without activity are left out.
+	DailyReport(from, to time.Time) ([]*domain.DailyReportRow, error)
+	// LedgerTotals sums the ledger entries of [from, to), archived ones
+	// included, by UTC day, currency and type. An empty currency sums all.
+	LedgerTotals(from, to time.Time, currency string) ([]*domain.LedgerTotal, error)
+}
+
+type SandboxStore interface {
+	// TopUp credits the account with amount as a sandbox transaction.
+	TopUp(accountID int, amount domain.Money, description string) (*domain.Transaction, error)
+}
+
+type QuoteStore interface {
+	CreateQuote(q *domain.TransferQuote) error
+	// ClaimQuote marks the account's quote executed and returns it, or fails
+	// with ErrQuoteExpired if it expired or was executed before.
+	ClaimQuote(accountID int, id string) (*domain.TransferQuote, error)
+	// ReleaseQuote undoes a claim whose transfer didn't go through.
+	ReleaseQuote(id string) error
+	// PurgeQuotes deletes the quotes of all tenants that expired before.
+	PurgeQuotes(before time.Time) (int64, error)
+}
+
+type BatchTransferStore interface {
+	// TransferBatch makes all the transfers in one db transaction or, failing
+	// with a *domain.BatchItemError, none of them.
+	TransferBatch(from *domain.Account, orders []domain.TransferOrder, guard *domain.TransferGuard) ([]*domain.Transaction, error)
+}
+
+type TransferRequestStore interface {
+	// CreateTransferRequests saves the pending requests together with the
+	// jobs that process them, jobs[i] processing reqs[i]. The requests are of
+	// one account; a guard looking for duplicates is checked under the lock
+	// on its row.
+	CreateTransferRequests(reqs []*domain.TransferRequest, jobs []*domain.Job, guard *domain.TransferGuard) error
+	GetTransferRequest(accountID int, id string) (*domain.TransferRequest, error)
+	// ExecuteTransferRequest makes the pending transfer and marks it completed
+	// in one db transaction. A request that isn't pending is returned as is.
+	ExecuteTransferRequest(from *domain.Account, id string, guard *domain.TransferGuard) (*domain.TransferRequest, error)
+	// FailTransferRequest marks the request failed if it's still pending.
+	FailTransferRequest(id, code string, params map[string]any) error
+	// FindTransferRequest looks a request up by id alone, for the admin
+	// review. ListTransferRequests returns the tenant's in status, oldest
+	// first.
+	FindTransferRequest(id string) (*domain.TransferRequest, error)
+	ListTransferRequests(status string) ([]*domain.TransferRequest, error)
+	// HoldTransferRequest puts a pending request screening flagged in
+	// review, ReviewTransferRequest ends the review.
+	HoldTransferRequest(id, reason string) error
+	ReviewTransferRequest(id string, release bool, job *domain.Job) (*domain.TransferRequest, error)
+}
+
+type SagaStore interface {
+	SaveSaga(saga *domain.Saga) error
+	// StuckSagas lists the sagas of all tenants left stuck or running for
+	// longer than domain.SagaStaleAfter, oldest first.
+	StuckSagas() ([]*domain.Saga, error)
+}
+
+type WebhookStore interface {
+	CreateWebhook(ep *domain.WebhookEndpoint) error
+	// ListWebhooks returns the account's endpoints without their secrets.
+	ListWebhooks(accountID int) ([]*domain.WebhookEndpoint, error)
+	DeleteWebhook(accountID int, id string) error
+	// EnableWebhook turns a disabled endpoint back on and clears its failure
+	// streak.
+	EnableWebhook(accountID int, id string) error
+	// ListDeliveries returns the endpoint's latest deliveries, newest first,
+	// with their attempts.
+	ListDeliveries(accountID int, endpointID string, limit int) ([]*domain.WebhookDelivery, error)
+	// RedeliverWebhook sets the delivery pending again and enqueues the job
+	// that makes it.
+	RedeliverWebhook(accountID int, endpointID, deliveryID string) (*domain.Job, error)
+	QueueWebhookDeliveries(ev *domain.Event, payload []byte) (int, error)
+	GetDelivery(id string) (*domain.WebhookDelivery, *domain.WebhookEndpoint, error)
+	RecordWebhookAttempt(deliveryID string, attempt domain.WebhookAttempt) (bool, error)
+}
+
+type EmailStore interface {
+	QueueEmail(e *domain.Email) (bool, error)
+	GetEmail(id string) (*domain.Email, error)
+	RecordEmailAttempt(e *domain.Email) error
+	SuppressEmail(sup *domain.EmailSuppression) error
+	SuppressBouncedEmail(providerID string, address domain.PII, reason string, at time.Time) error
+	// EmailSuppressed reports whether the address, whatever its case, is on
+	// the tenant's suppression list.
+	EmailSuppressed(address domain.PII) (bool, error)
+	// ListEmailSuppressions returns the tenant's list, newest first.
+	ListEmailSuppressions() ([]*domain.EmailSuppression, error)
+	DeleteEmailSuppression(address domain.PII) error
+}
+
+type SMSStore interface {
+	QueueSMS(m *domain.SMS) (bool, error)
+	GetSMS(id string) (*domain.SMS, error)
+	RecordSMSAttempt(m *domain.SMS) error
+	SavePhoneVerification(v *domain.PhoneVerification) error
+	GetPhoneVerification(accountID int) (*domain.PhoneVerification, error)
+	CountVerificationAttempt(accountID int) error
+	ConfirmPhone(account *domain.Account, phone domain.PII) error
+}
+
+type PushStore interface {
+	RegisterDevice(d *domain.Device) error
+	ListDevices(accountID int) ([]*domain.Device, error)
+	DeleteDevice(accountID int, id string) error
+	GetDevice(id string) (*domain.Device, error)
+	ForgetDevice(id string) error
+	QueuePush(p *domain.Push) (bool, error)
+	GetPush(id string) (*domain.Push, error)
+	RecordPushAttempt(p *domain.Push) error
+}
+
+type NotificationStore interface {
+	// AddNotification adds the notification unless one with its dedupe key
+	// was added before.
+	AddNotification(n *domain.Notification) error
+	// NotificationsBefore returns the account's notifications with an id
+	// below beforeID (0 for the newest), newest first.
+	NotificationsBefore(accountID int, beforeID int64, unreadOnly bool, limit int) ([]*domain.Notification, error)
+	UnreadNotifications(accountID int) (int, error)
+	MarkNotificationRead(accountID int, id int64) (*domain.Notification, error)
+	// MarkAllNotificationsRead returns how many notifications were unread.
+	MarkAllNotificationsRead(accountID int) (int64, error)
+}
+
+type BalanceStore interface {
+	// Availability breaks the account's balance down into what is held, put
+	// aside in pots and kept as its minimum balance.
+	Availability(accountID int) (domain.Availability, error)
+	SetMinimumBalance(accountID int, minimum *int64) error
+	ListPots(accountID int) ([]*domain.Pot, error)
+	GetPot(accountID int, id string) (*domain.Pot, error)
+	// CreatePot and UpdatePot fail like transfers when the available balance
+	// doesn't cover what goes into the pot.
+	CreatePot(p *domain.Pot) error
+	UpdatePot(p *domain.Pot) error
+	DeletePot(accountID int, id string) error
+}
+
+type StatementDeliveryStore interface {
+	CreateStatementDestination(d *domain.StatementDestination) error
+	// ListStatementDestinations returns the account's destinations without
+	// their passwords.
+	ListStatementDestinations(accountID int) ([]*domain.StatementDestination, error)
+	DeleteStatementDestination(accountID int, id string) error
+	// QueueStatementDelivery saves the delivery with the job that makes it,
+	// unless the destination got one of the period before, and reports
+	// whether it did.
+	QueueStatementDelivery(d *domain.StatementDelivery) (bool, error)
+	// ListStatementDeliveries returns the destination's latest deliveries,
+	// newest first.
+	ListStatementDeliveries(accountID int, destinationID string, limit int) ([]*domain.StatementDelivery, error)
+	// GetStatementDelivery returns the delivery, of any tenant, and its
+	// destination with the password.
+	GetStatementDelivery(id string) (*domain.StatementDelivery, *domain.StatementDestination, error)
+	RecordStatementAttempt(d *domain.StatementDelivery) error
+	// RetryStatementDelivery sets the delivery pending again and enqueues
+	// the job that makes it.
+	RetryStatementDelivery(accountID int, destinationID, deliveryID string) (*domain.Job, error)
+}
+
+type BatchFileStore interface {
+	// ClaimBatchFile saves the file as received and reports true, unless the
+	// tenant has one with the same name and content. Then it returns that
+	// one.
+	ClaimBatchFile(f *domain.BatchFile) (*domain.BatchFile, bool, error)
+	// CompleteBatchFile saves the status, counts and acknowledgement of a
+	// claimed file.
+	CompleteBatchFile(f *domain.BatchFile) error
+	// ListBatchFiles returns the latest files, newest first.
+	ListBatchFiles(limit int) ([]*domain.BatchFile, error)
+}
+
+// WarehouseStore reads the rows of every tenant the warehouse export hasn't
+// written yet.
+type WarehouseStore interface {
+	// WarehouseWatermark returns how far the export of the dataset got, a
+	// zero watermark before it started.
+	WarehouseWatermark(dataset string) (*domain.Watermark, error)
+	SaveWarehouseWatermark(w *domain.Watermark) error
+	TransactionsAfterID(afterID, limit int) ([]*domain.Transaction, error)
+	AccountsChangedAfter(after *domain.Watermark, until time.Time, limit int) ([]*domain.Account, error)
+}
+
+type ImpersonationStore interface {
+	// CreateImpersonation saves the impersonation and records that it was
+	// requested.
+	CreateImpersonation(imp *domain.Impersonation) error
+	GetImpersonation(id string) (*domain.Impersonation, error)
+	ListImpersonations(accountID int) ([]*domain.Impersonation, error)
+	// DecideImpersonation approves or denies the account's pending
+	// impersonation.
+	DecideImpersonation(accountID int, id string, approve bool) (*domain.Impersonation, error)
+	// RevokeImpersonation ends a pending or approved impersonation.
+	RevokeImpersonation(id string) (*domain.Impersonation, error)
+	RecordImpersonationEvent(imp *domain.Impersonation, eventType string, details map[string]any) error
+}
+
+type TermsStore interface {
+	AcceptTerms(accountID int, accepted []*domain.TermsAcceptance) error
+	// AcceptedTerms lists every version the account accepted, oldest first.
+	AcceptedTerms(accountID int) ([]*domain.TermsAcceptance, error)
+}
+
+type HoldStore interface {
+	// AuthorizeTransfer reserves amount on the sender's account for a
+	// transfer to toNumber, checking it as Transfer would.
+	AuthorizeTransfer(from *domain.Account, toNumber domain.AccountNumber, amount domain.Money, guard *domain.TransferGuard) (*domain.TransferHold, error)
+	// CaptureTransfer makes the transfer of the sender's hold for amount,
+	// which must not exceed the hold. A zero amount captures all of it.
+	CaptureTransfer(from *domain.Account, holdID string, amount domain.Money) (*domain.Transaction, error)
+	// VoidTransfer releases the hold without transferring anything.
+	VoidTransfer(accountID int, holdID string) (*domain.TransferHold, error)
+	GetHold(accountID int, holdID string) (*domain.TransferHold, error)
+}
+
+type SummaryStore interface {
+	// AccountSummary fills in everything but the month to date spend and the
+	// recent transactions with a single query.
+	AccountSummary(accountID int) (*domain.AccountSummary, error)
+}
+
+type TenantSettingsStore interface {
+	// GetTenantSettings returns nil when the tenant has no overrides.
+	GetTenantSettings(tenantID int) (*domain.TenantSettings, error)
+	SaveTenantSettings(settings *domain.TenantSettings) error
+}
+
+type TenantStore interface {
+	CreateTenant(tenant *domain.Tenant) error
+	ListTenants() ([]*domain.Tenant, error)
+	GetTenantBySlug(slug string) (*domain.Tenant, error)
+}
+
+type UsageStore interface {
+	// RecordUsage adds the counts to the stored daily totals.
+	RecordUsage(usage map[domain.UsageKey]domain.Usage) error
+	// GetUsage returns the daily totals of an account since the given day.
+	GetUsage(accountNumber domain.AccountNumber, since time.Time) ([]*domain.DailyUsage, error)
+}
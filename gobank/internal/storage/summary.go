@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) AccountSummary(accountID int) (*domain.AccountSummary, error) {
+	summary := new(domain.AccountSummary)
+	var currency string
+	err := s.db.QueryRow("select balance, currency from account where id = $1 and tenant_id = $2",
+		accountID, s.tenantID).Scan(&summary.Balance.MinorUnits, &currency)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrAccountNotFound, accountID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary.Balance.Currency = currency
+	summary.MonthToDateSpend.Currency = currency
+	a, err := s.availability(s.db, accountID, "")
+	if err != nil {
+		return nil, err
+	}
+	summary.AvailableBalance = a.Available()
+	summary.HeldBalance = domain.Money{MinorUnits: a.Held, Currency: currency}
+	summary.ProtectedBalance = domain.Money{MinorUnits: a.Protected, Currency: currency}
+	summary.MinimumBalance = domain.Money{MinorUnits: a.Minimum, Currency: currency}
+	return summary, nil
+}
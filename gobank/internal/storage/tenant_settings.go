@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) CreateTenantSettingsTable() error {
+	query := `create table if not exists tenant_settings (
+    			tenant_id integer primary key references tenant(id) on delete cascade,
+    			currency char(3) not null,
+    			max_transfer_amount bigint not null default 0,
+    			daily_transfer_limit bigint not null default 0,
+    			brand_name varchar(100),
+    			support_email varchar(200),
+    			updated_at timestamp
+				)`
+	_, err := s.db.Exec(query)
+	return err
+}
+
+func (s *PostgresStore) GetTenantSettings(tenantID int) (*domain.TenantSettings, error) {
+	t := new(domain.TenantSettings)
+	err := s.db.QueryRow(`select tenant_id, currency, max_transfer_amount, daily_transfer_limit,
+							 brand_name, support_email, updated_at, require_kyc, coalesce(iban_country, ''), coalesce(iban_bank_code, ''),
+							 duplicate_transfer_minutes, minimum_balance
+							 from tenant_settings where tenant_id = $1`, tenantID).
+		Scan(&t.TenantID, &t.Currency, &t.MaxTransferAmount, &t.DailyTransferLimit, &t.BrandName, &t.SupportEmail, &t.UpdatedAt, &t.RequireKYC, &t.IBANCountry, &t.IBANBankCode,
+			&t.DuplicateTransferMinutes, &t.MinimumBalance)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *PostgresStore) SaveTenantSettings(t *domain.TenantSettings) error {
+	query := `insert into tenant_settings
+							 (tenant_id,currency,max_transfer_amount,daily_transfer_limit,brand_name,support_email,updated_at,require_kyc,iban_country,iban_bank_code,
+							  duplicate_transfer_minutes,minimum_balance)
+								values ($1,$2,$3,$4,$5,$6,$7,$8,nullif($9,''),nullif($10,''),$11,$12)
+							 on conflict (tenant_id) do update set
+								currency = excluded.currency,
+								max_transfer_amount = excluded.max_transfer_amount,
+								daily_transfer_limit = excluded.daily_transfer_limit,
+								brand_name = excluded.brand_name,
+								support_email = excluded.support_email,
+								updated_at = excluded.updated_at,
+								require_kyc = excluded.require_kyc,
+								iban_country = excluded.iban_country,
+								iban_bank_code = excluded.iban_bank_code,
+								duplicate_transfer_minutes = excluded.duplicate_transfer_minutes,
+								minimum_balance = excluded.minimum_balance`
+	_, err := s.db.Exec(query, t.TenantID, t.Currency, t.MaxTransferAmount, t.DailyTransferLimit, t.BrandName, t.SupportEmail, t.UpdatedAt, t.RequireKYC, t.IBANCountry, t.IBANBankCode,
+		t.DuplicateTransferMinutes, t.MinimumBalance)
+	return err
+}
+
+// SentSince sums what the account transferred out since the given time.
+func (s *PostgresStore) SentSince(accountID int, since time.Time) (int64, error) {
+	return s.sentSince(s.db, accountID, since)
+}
+
+func (s *PostgresStore) sentSince(q rowQuerier, accountID int, since time.Time) (int64, error) {
+	var sent int64
+	err := q.QueryRow(`select coalesce(-sum(amount), 0) from transaction
+							 where account_id = $1 and tenant_id = $2 and type = $3 and created_at >= $4`,
+		accountID, s.tenantID, domain.TransactionTransferOut, since).Scan(&sent)
+	return sent, err
+}
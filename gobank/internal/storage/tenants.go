@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) CreateTenantTable() error {
+	query := `create table if not exists tenant (
+    			id serial primary key,
+    			slug varchar(63) not null unique,
+    			name varchar(100),
+    			created_at timestamp
+				)`
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`insert into tenant (slug,name,created_at) values ($1,'Default',now())
+							 on conflict (slug) do nothing`, domain.DefaultTenantSlug)
+	return err
+}
+
+func (s *PostgresStore) CreateTenant(tenant *domain.Tenant) error {
+	query := `insert into tenant (slug,name,created_at) values ($1,$2,$3) returning id`
+	err := s.db.QueryRow(query, tenant.Slug, tenant.Name, tenant.CreatedAt).Scan(&tenant.ID)
+	return mapUniqueViolation(err)
+}
+
+func (s *PostgresStore) ListTenants() ([]*domain.Tenant, error) {
+	rows, err := s.db.Query("select id, slug, name, created_at from tenant order by id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tenants := []*domain.Tenant{}
+	for rows.Next() {
+		tenant := new(domain.Tenant)
+		if err := rows.Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.CreatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+func (s *PostgresStore) GetTenantBySlug(slug string) (*domain.Tenant, error) {
+	tenant := new(domain.Tenant)
+	err := s.db.QueryRow("select id, slug, name, created_at from tenant where slug = $1", slug).
+		Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrTenantNotFound, slug)
+	}
+	return tenant, err
+}
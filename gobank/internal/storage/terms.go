@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+)
+
+// AcceptTerms records the acceptances, each with a terms.accepted event.
+// Accepting a version twice keeps the first acceptance.
+func (s *PostgresStore) AcceptTerms(accountID int, accepted []*domain.TermsAcceptance) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, a := range accepted {
+		res, err := tx.Exec(`insert into terms_acceptance (account_id,document,version,accepted_at)
+							 select $1,$2,$3,$4 where exists (select 1 from account where id = $1 and tenant_id = $5)
+							 on conflict do nothing`,
+			accountID, a.Document, a.Version, a.AcceptedAt, s.tenantID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		ev, err := domain.NewEvent(domain.EventTermsAccepted, accountID, a, s.clock.Now())
+		if err != nil {
+			return err
+		}
+		if err := insertOutboxEvent(tx, ev); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) AcceptedTerms(accountID int) ([]*domain.TermsAcceptance, error) {
+	rows, err := s.db.Query(`select t.document, t.version, t.accepted_at from terms_acceptance t
+							 join account a on a.id = t.account_id
+							 where t.account_id = $1 and a.tenant_id = $2 order by t.accepted_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accepted := []*domain.TermsAcceptance{}
+	for rows.Next() {
+		a := &domain.TermsAcceptance{}
+		if err := rows.Scan(&a.Document, &a.Version, &a.AcceptedAt); err != nil {
+			return nil, err
+		}
+		accepted = append(accepted, a)
+	}
+	return accepted, rows.Err()
+}
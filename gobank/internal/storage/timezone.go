@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+)
+
+// withUTCSession makes Postgres return timestamptz values in UTC.
+func withUTCSession(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", "UTC")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	if strings.Contains(dsn, "timezone=") {
+		return dsn
+	}
+	return strings.TrimSpace(dsn + " timezone=UTC")
+}
@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithUTCSession(t *testing.T) {
+	assert.Equal(t, "postgres://u@h/db?sslmode=disable&timezone=UTC", withUTCSession("postgres://u@h/db?sslmode=disable"))
+	assert.Equal(t, "host=h timezone=UTC", withUTCSession("host=h"))
+	assert.Equal(t, "host=h timezone=Europe/Paris", withUTCSession("host=h timezone=Europe/Paris"))
+}
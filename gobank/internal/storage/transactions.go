@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) TransactionsAfter(accountID, cursor, limit int) ([]*domain.Transaction, error) {
+	rows, err := s.db.Query(`select id, account_id, type, amount, currency, counterparty, created_at, description
+							 from transaction
+							 where account_id = $1 and tenant_id = $2 and id > $3
+							 order by id limit $4`, accountID, s.tenantID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanTransactions(rows)
+}
+
+func (s *PostgresStore) scanTransactions(rows *sql.Rows) ([]*domain.Transaction, error) {
+	txs := []*domain.Transaction{}
+	for rows.Next() {
+		t := &domain.Transaction{TenantID: s.tenantID}
+		if err := rows.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency, &t.Counterparty, &t.CreatedAt, &t.Description); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+func (s *PostgresStore) TransferLegs(outID int) (out, in *domain.Transaction, err error) {
+	rows, err := s.db.Query(`select id, account_id, type, amount, currency, counterparty, created_at, description, tenant_id
+							 from transaction where id = $1 and type = $2
+							 union all
+							 select i.id, i.account_id, i.type, i.amount, i.currency, i.counterparty, i.created_at, i.description, i.tenant_id
+							 from transaction o
+							 join account sender on sender.id = o.account_id
+							 join account recipient on recipient.number = o.counterparty and recipient.tenant_id = o.tenant_id
+							 join transaction i on i.account_id = recipient.id and i.type = $3 and i.counterparty = sender.number and i.created_at = o.created_at
+							 where o.id = $1 and o.type = $2`,
+		outID, domain.TransactionTransferOut, domain.TransactionTransferIn)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &domain.Transaction{}
+		if err := rows.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency, &t.Counterparty, &t.CreatedAt, &t.Description, &t.TenantID); err != nil {
+			return nil, nil, err
+		}
+		if t.Type == domain.TransactionTransferOut {
+			out = t
+		} else {
+			in = t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if out == nil {
+		return nil, nil, domain.NotFound(domain.ErrTransactionNotFound, outID)
+	}
+	return out, in, nil
+}
+
+func (s *PostgresStore) LastTransactionID(accountID int) (int, error) {
+	var id int
+	err := s.db.QueryRow("select coalesce(max(id), 0) from transaction where account_id = $1 and tenant_id = $2", accountID, s.tenantID).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) TransactionsBetween(accountID int, from, to time.Time) ([]*domain.Transaction, error) {
+	txs := []*domain.Transaction{}
+	err := s.EachTransactionBetween(accountID, from, to, func(t *domain.Transaction) error {
+		txs = append(txs, t)
+		return nil
+	})
+	return txs, err
+}
+
+func (s *PostgresStore) EachTransactionBetween(accountID int, from, to time.Time, fn func(*domain.Transaction) error) error {
+	rows, err := s.db.Query(`select id, account_id, type, amount, currency, counterparty, created_at, description
+							 from transaction
+							 where account_id = $1 and tenant_id = $2 and created_at >= $3 and created_at < $4
+							 order by created_at, id`, accountID, s.tenantID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &domain.Transaction{TenantID: s.tenantID}
+		if err := rows.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency, &t.Counterparty, &t.CreatedAt, &t.Description); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *PostgresStore) TransactionsBefore(accountID int, before domain.TransactionCursor, limit int) ([]*domain.Transaction, error) {
+	query := `select id, account_id, type, amount, currency, counterparty, created_at, description
+							 from transaction
+							 where account_id = $1 and tenant_id = $2`
+	args := []any{accountID, s.tenantID}
+	if before.ID != 0 {
+		query += " and (created_at, id) < ($3, $4)"
+		args = append(args, before.CreatedAt, before.ID)
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" order by created_at desc, id desc limit $%d", len(args))
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.scanTransactions(rows)
+}
+
+func (s *PostgresStore) EachLedgerEntry(accountID int, fn func(*domain.Transaction) error) error {
+	rows, err := s.db.Query(`select id, account_id, type, amount, currency, counterparty, created_at, description, hash from (
+								select id, account_id, type, amount, currency, counterparty, created_at, description, hash
+								from transaction where account_id = $1 and tenant_id = $2
+								union all
+								select id, account_id, type, amount, currency, counterparty, created_at, description, hash
+								from transaction_archive where account_id = $1 and tenant_id = $2
+							 ) entries order by id`, accountID, s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &domain.Transaction{TenantID: s.tenantID}
+		if err := rows.Scan(&t.ID, &t.AccountID, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency, &t.Counterparty, &t.CreatedAt, &t.Description, &t.Hash); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/iamuditg/internal/domain"
+)
+
+func (s *PostgresStore) CreateTransferRequests(reqs []*domain.TransferRequest, jobs []*domain.Job, guard *domain.TransferGuard) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if guard != nil && !guard.DuplicateSince.IsZero() && len(reqs) > 0 {
+		// the lock lockTransfer takes, so a transfer made and one accepted
+		// at the same time see each other
+		if _, err := tx.Exec("select id from account where id = $1 and tenant_id = $2 for update", reqs[0].AccountID, s.tenantID); err != nil {
+			return err
+		}
+	}
+	for i, req := range reqs {
+		if err := s.checkDuplicate(tx, req.AccountID, req.ToAccount, req.Amount, guard); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`insert into transfer_request (id,tenant_id,account_id,to_number,amount,currency,status,review_reason,created_at,updated_at)
+								 values ($1,$2,$3,$4,$5,$6,$7,nullif($8,''),$9,$10)`,
+			req.ID, s.tenantID, req.AccountID, req.ToAccount, req.Amount.MinorUnits, req.Amount.Currency, req.Status, req.ReviewReason, req.CreatedAt, req.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if jobs[i] == nil {
+			continue
+		}
+		if err := enqueueJobTx(tx, jobs[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetTransferRequest(accountID int, id string) (*domain.TransferRequest, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrTransferRequestNotFound, id)
+	}
+	return scanTransferRequest(s.db.QueryRow(transferRequestQuery+" and account_id = $3", id, s.tenantID, accountID), id)
+}
+
+func (s *PostgresStore) FindTransferRequest(id string) (*domain.TransferRequest, error) {
+	if !domain.IsUUID(id) {
+		return nil, domain.NotFound(domain.ErrTransferRequestNotFound, id)
+	}
+	return scanTransferRequest(s.db.QueryRow(transferRequestQuery, id, s.tenantID), id)
+}
+
+func (s *PostgresStore) ListTransferRequests(status string) ([]*domain.TransferRequest, error) {
+	rows, err := s.db.Query(transferRequestColumns+" where tenant_id = $1 and status = $2 order by created_at", s.tenantID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reqs := []*domain.TransferRequest{}
+	for rows.Next() {
+		req, err := scanTransferRequest(rows, "")
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, rows.Err()
+}
+
+func (s *PostgresStore) ExecuteTransferRequest(from *domain.Account, id string, guard *domain.TransferGuard) (*domain.TransferRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	req, err := scanTransferRequest(tx.QueryRow(transferRequestQuery+" and account_id = $3 for update", id, s.tenantID, from.ID), id)
+	if err != nil || req.Status != domain.TransferPending {
+		return req, err
+	}
+	toID, err := s.lockTransfer(tx, from.ID, req.ToAccount, req.Amount, "", guard)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.postTransfer(tx, from, toID, req.ToAccount, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	req.Status = domain.TransferCompleted
+	req.TransactionID = out.ID
+	req.UpdatedAt = out.CreatedAt
+	_, err = tx.Exec("update transfer_request set status = $2, transaction_id = $3, updated_at = $4 where id = $1",
+		req.ID, req.Status, req.TransactionID, req.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return req, tx.Commit()
+}
+
+func (s *PostgresStore) FailTransferRequest(id, code string, params map[string]any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`update transfer_request set status = $2, failure_code = $3, failure_params = $4, updated_at = $5
+							 where id = $1 and tenant_id = $6 and status = 'pending'`,
+		id, domain.TransferFailed, code, string(raw), s.clock.Now().UTC(), s.tenantID)
+	return err
+}
+
+// HoldTransferRequest puts a pending request in review.
+func (s *PostgresStore) HoldTransferRequest(id, reason string) error {
+	_, err := s.db.Exec(`update transfer_request set status = $2, review_reason = $3, updated_at = $4
+							 where id = $1 and tenant_id = $5 and status = 'pending'`,
+		id, domain.TransferInReview, reason, s.clock.Now().UTC(), s.tenantID)
+	return err
+}
+
+// ReviewTransferRequest releases a request in review to pending and
+// enqueues job to process it, or fails it.
+func (s *PostgresStore) ReviewTransferRequest(id string, release bool, job *domain.Job) (*domain.TransferRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	req, err := scanTransferRequest(tx.QueryRow(transferRequestQuery+" for update", id, s.tenantID), id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != domain.TransferInReview {
+		return nil, domain.ErrTransferNotInReview
+	}
+	req.ReviewedAt = s.clock.Now().UTC()
+	req.UpdatedAt = req.ReviewedAt
+	if release {
+		req.Status = domain.TransferPending
+		if err := enqueueJobTx(tx, job); err != nil {
+			return nil, err
+		}
+	} else {
+		req.Status = domain.TransferFailed
+	}
+	_, err = tx.Exec("update transfer_request set status = $2, reviewed_at = $3, updated_at = $3 where id = $1",
+		req.ID, req.Status, req.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	return req, tx.Commit()
+}
+
+const transferRequestColumns = `select id, tenant_id, account_id, to_number, amount, currency, status, transaction_id, failure_code, failure_params,
+							 review_reason, reviewed_at, created_at, updated_at from transfer_request`
+
+const transferRequestQuery = transferRequestColumns + " where id = $1 and tenant_id = $2"
+
+func scanTransferRequest(row interface{ Scan(dest ...any) error }, id string) (*domain.TransferRequest, error) {
+	req := &domain.TransferRequest{}
+	var transactionID sql.NullInt64
+	var code, params, reason sql.NullString
+	var reviewedAt sql.NullTime
+	err := row.Scan(&req.ID, &req.TenantID, &req.AccountID, &req.ToAccount, &req.Amount.MinorUnits, &req.Amount.Currency, &req.Status,
+		&transactionID, &code, &params, &reason, &reviewedAt, &req.CreatedAt, &req.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.ErrTransferRequestNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.TransactionID = int(transactionID.Int64)
+	req.FailureCode = code.String
+	req.ReviewReason = reason.String
+	req.ReviewedAt = reviewedAt.Time
+	if params.Valid {
+		if err := json.Unmarshal([]byte(params.String), &req.FailureParams); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) RecordUsage(usage map[domain.UsageKey]domain.Usage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for k, u := range usage {
+		_, err := tx.Exec(`insert into api_usage (tenant_id,account_number,day,calls,throttled)
+								values ($1,$2,$3,$4,$5)
+								on conflict (tenant_id, account_number, day)
+								do update set calls = api_usage.calls + excluded.calls, throttled = api_usage.throttled + excluded.throttled`,
+			k.TenantID, k.AccountNumber, k.Day, u.Calls, u.Throttled)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetUsage(accountNumber domain.AccountNumber, since time.Time) ([]*domain.DailyUsage, error) {
+	rows, err := s.db.Query(`select to_char(day, 'YYYY-MM-DD'), calls, throttled from api_usage
+							 where tenant_id = $1 and account_number = $2 and day >= $3 order by day`, s.tenantID, accountNumber, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := []*domain.DailyUsage{}
+	for rows.Next() {
+		u := new(domain.DailyUsage)
+		if err := rows.Scan(&u.Date, &u.Calls, &u.Throttled); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"time"
+)
+
+func (s *PostgresStore) WarehouseWatermark(dataset string) (*domain.Watermark, error) {
+	w := &domain.Watermark{Dataset: dataset}
+	var t, updatedAt sql.NullTime
+	err := s.db.QueryRow("select last_time, last_id, updated_at from warehouse_watermark where dataset = $1", dataset).Scan(&t, &w.ID, &updatedAt)
+	if err == sql.ErrNoRows {
+		return w, nil
+	}
+	w.Time, w.UpdatedAt = t.Time, updatedAt.Time
+	return w, err
+}
+
+func (s *PostgresStore) SaveWarehouseWatermark(w *domain.Watermark) error {
+	var t *time.Time
+	if !w.Time.IsZero() {
+		t = &w.Time
+	}
+	_, err := s.db.Exec(`insert into warehouse_watermark (dataset, last_time, last_id, updated_at) values ($1,$2,$3,$4)
+							 on conflict (dataset) do update set last_time = $2, last_id = $3, updated_at = $4`,
+		w.Dataset, t, w.ID, w.UpdatedAt)
+	return err
+}
+
+// TransactionsAfterID returns up to limit hot and archived transactions of
+// every tenant with an id above afterID, in id order.
+func (s *PostgresStore) TransactionsAfterID(afterID, limit int) ([]*domain.Transaction, error) {
+	rows, err := s.db.Query(`select id, account_id, tenant_id, type, amount, currency, created_at from (
+								select id, account_id, tenant_id, type, amount, currency, created_at from transaction where id > $1
+								union all
+								select id, account_id, tenant_id, type, amount, currency, created_at from transaction_archive where id > $1
+							 ) entries order by id limit $2`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	txs := []*domain.Transaction{}
+	for rows.Next() {
+		t := new(domain.Transaction)
+		if err := rows.Scan(&t.ID, &t.AccountID, &t.TenantID, &t.Type, &t.Amount.MinorUnits, &t.Amount.Currency, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// AccountsChangedAfter returns up to limit accounts of every tenant changed
+// after the watermark and before until, in the order of their update time
+// and id.
+func (s *PostgresStore) AccountsChangedAfter(after *domain.Watermark, until time.Time, limit int) ([]*domain.Account, error) {
+	rows, err := s.db.Query(`select `+accountColumns+` from account
+							 where (updated_at, id) > ($1, $2) and updated_at < $3
+							 order by updated_at, id limit $4`, after.Time, after.ID, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	accounts := []*domain.Account{}
+	for rows.Next() {
+		account, err := scanIntoAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"database/sql"
+	"github.com/iamuditg/internal/domain"
+	"github.com/lib/pq"
+	"time"
+)
+
+const DeliverWebhookJobType = "deliver_webhook"
+
+// WebhookJob is the payload of a DeliverWebhookJobType job.
+type WebhookJob struct {
+	DeliveryID string `json:"deliveryId"`
+}
+
+func (s *PostgresStore) CreateWebhook(ep *domain.WebhookEndpoint) error {
+	ep.TenantID = s.tenantID
+	res, err := s.db.Exec(`insert into webhook_endpoint (id,tenant_id,account_id,url,secret,status,created_at)
+							 select $1,$2,$3,$4,$5,$6,$7 where exists (select 1 from account where id = $3 and tenant_id = $2)`,
+		ep.ID, ep.TenantID, ep.AccountID, ep.URL, ep.Secret, ep.Status, ep.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrAccountNotFound, ep.AccountID)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListWebhooks(accountID int) ([]*domain.WebhookEndpoint, error) {
+	rows, err := s.db.Query(`select id, url, status, consecutive_failures, failing_since, disabled_at, created_at
+							 from webhook_endpoint where account_id = $1 and tenant_id = $2 order by created_at`, accountID, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	endpoints := []*domain.WebhookEndpoint{}
+	for rows.Next() {
+		ep := &domain.WebhookEndpoint{AccountID: accountID, TenantID: s.tenantID}
+		var failingSince, disabledAt sql.NullTime
+		if err := rows.Scan(&ep.ID, &ep.URL, &ep.Status, &ep.ConsecutiveFailures, &failingSince, &disabledAt, &ep.CreatedAt); err != nil {
+			return nil, err
+		}
+		ep.FailingSince = nullTime(failingSince)
+		ep.DisabledAt = nullTime(disabledAt)
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, rows.Err()
+}
+
+func (s *PostgresStore) DeleteWebhook(accountID int, id string) error {
+	if !domain.IsUUID(id) {
+		return domain.NotFound(domain.ErrWebhookNotFound, id)
+	}
+	res, err := s.db.Exec("delete from webhook_endpoint where id = $1 and account_id = $2 and tenant_id = $3", id, accountID, s.tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) EnableWebhook(accountID int, id string) error {
+	if !domain.IsUUID(id) {
+		return domain.NotFound(domain.ErrWebhookNotFound, id)
+	}
+	res, err := s.db.Exec(`update webhook_endpoint set status = $4, consecutive_failures = 0, failing_since = null, disabled_at = null
+							 where id = $1 and account_id = $2 and tenant_id = $3`,
+		id, accountID, s.tenantID, domain.WebhookActive)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.NotFound(domain.ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+func (s *PostgresStore) ListDeliveries(accountID int, endpointID string, limit int) ([]*domain.WebhookDelivery, error) {
+	if err := s.checkWebhook(s.db, accountID, endpointID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`select id, endpoint_id, event_id, event_type, status, created_at, delivered_at
+							 from webhook_delivery where endpoint_id = $1 order by created_at desc limit $2`, endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []*domain.WebhookDelivery{}
+	byID := map[string]*domain.WebhookDelivery{}
+	ids := []string{}
+	for rows.Next() {
+		d := &domain.WebhookDelivery{Attempts: []domain.WebhookAttempt{}}
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.CreatedAt, &deliveredAt); err != nil {
+			return nil, err
+		}
+		d.DeliveredAt = nullTime(deliveredAt)
+		deliveries = append(deliveries, d)
+		byID[d.ID] = d
+		ids = append(ids, d.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	attempts, err := s.db.Query(`select delivery_id, status_code, error, duration_ms, attempted_at from webhook_attempt
+							 where delivery_id = any($1) order by id`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer attempts.Close()
+	for attempts.Next() {
+		var deliveryID string
+		var a domain.WebhookAttempt
+		if err := attempts.Scan(&deliveryID, &a.StatusCode, &a.Error, &a.DurationMs, &a.At); err != nil {
+			return nil, err
+		}
+		byID[deliveryID].Attempts = append(byID[deliveryID].Attempts, a)
+	}
+	return deliveries, attempts.Err()
+}
+
+func (s *PostgresStore) RedeliverWebhook(accountID int, endpointID, deliveryID string) (*domain.Job, error) {
+	if !domain.IsUUID(deliveryID) {
+		return nil, domain.NotFound(domain.ErrDeliveryNotFound, deliveryID)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkWebhook(tx, accountID, endpointID); err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec("update webhook_delivery set status = $3 where id = $1 and endpoint_id = $2",
+		deliveryID, endpointID, domain.DeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, domain.NotFound(domain.ErrDeliveryNotFound, deliveryID)
+	}
+	job, err := domain.NewJob(DeliverWebhookJobType, WebhookJob{DeliveryID: deliveryID}, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := enqueueJobTx(tx, job); err != nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+// rowQuerier is a *sql.DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// checkWebhook fails with ErrWebhookNotFound unless the endpoint belongs to
+// the account.
+func (s *PostgresStore) checkWebhook(q rowQuerier, accountID int, id string) error {
+	var exists bool
+	if domain.IsUUID(id) {
+		err := q.QueryRow("select exists(select 1 from webhook_endpoint where id = $1 and account_id = $2 and tenant_id = $3)",
+			id, accountID, s.tenantID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+	}
+	if !exists {
+		return domain.NotFound(domain.ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+// QueueWebhookDeliveries records a delivery of the event to each of its
+// account's active endpoints, with the job that makes it, in one db
+// transaction. An event seen before adds nothing, so relaying it again
+// doesn't post it twice.
+func (s *PostgresStore) QueueWebhookDeliveries(ev *domain.Event, payload []byte) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`insert into webhook_delivery (id,endpoint_id,event_id,event_type,payload,status,created_at)
+							 select gen_random_uuid(), id, $2, $3, $4, $5, $6 from webhook_endpoint
+							 where account_id = $1 and status = 'active'
+							 on conflict (endpoint_id, event_id) do nothing
+							 returning id`,
+		ev.AccountID, ev.ID, ev.Type, string(payload), domain.DeliveryPending, s.clock.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		job, err := domain.NewJob(DeliverWebhookJobType, WebhookJob{DeliveryID: id}, s.clock.Now())
+		if err != nil {
+			return 0, err
+		}
+		if err := enqueueJobTx(tx, job); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit()
+}
+
+// GetDelivery returns the delivery, of any tenant, and its endpoint with the
+// secret.
+func (s *PostgresStore) GetDelivery(id string) (*domain.WebhookDelivery, *domain.WebhookEndpoint, error) {
+	d := &domain.WebhookDelivery{ID: id}
+	ep := &domain.WebhookEndpoint{}
+	var payload string
+	err := s.db.QueryRow(`select d.event_id, d.event_type, d.payload, d.status, d.created_at,
+							 e.id, e.tenant_id, e.account_id, e.url, e.secret, e.status, e.consecutive_failures
+							 from webhook_delivery d join webhook_endpoint e on e.id = d.endpoint_id where d.id = $1`, id).
+		Scan(&d.EventID, &d.EventType, &payload, &d.Status, &d.CreatedAt,
+			&ep.ID, &ep.TenantID, &ep.AccountID, &ep.URL, &ep.Secret, &ep.Status, &ep.ConsecutiveFailures)
+	if err == sql.ErrNoRows {
+		return nil, nil, domain.NotFound(domain.ErrDeliveryNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	d.EndpointID = ep.ID
+	d.Payload = []byte(payload)
+	return d, ep, nil
+}
+
+// RecordWebhookAttempt saves the attempt, sets the delivery's status from
+// it and keeps the endpoint's failure streak, disabling the endpoint when
+// the streak is long enough. It reports whether it did.
+func (s *PostgresStore) RecordWebhookAttempt(deliveryID string, a domain.WebhookAttempt) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`insert into webhook_attempt (delivery_id,status_code,error,duration_ms,attempted_at) values ($1,$2,$3,$4,$5)`,
+		deliveryID, a.StatusCode, a.Error, a.DurationMs, a.At)
+	if err != nil {
+		return false, err
+	}
+	var endpointID string
+	if a.Succeeded() {
+		err = tx.QueryRow("update webhook_delivery set status = $2, delivered_at = $3 where id = $1 returning endpoint_id",
+			deliveryID, domain.DeliverySucceeded, a.At).Scan(&endpointID)
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec("update webhook_endpoint set consecutive_failures = 0, failing_since = null where id = $1", endpointID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+	err = tx.QueryRow("update webhook_delivery set status = $2 where id = $1 and status <> $3 returning endpoint_id",
+		deliveryID, domain.DeliveryFailed, domain.DeliverySucceeded).Scan(&endpointID)
+	if err == sql.ErrNoRows {
+		return false, tx.Commit()
+	}
+	if err != nil {
+		return false, err
+	}
+	var disabled bool
+	err = tx.QueryRow(`update webhook_endpoint set consecutive_failures = consecutive_failures + 1,
+							 failing_since = coalesce(failing_since, $2),
+							 status = case when consecutive_failures + 1 >= $3 and coalesce(failing_since, $2) <= $4 then $5 else status end,
+							 disabled_at = case when status = $6 and consecutive_failures + 1 >= $3 and coalesce(failing_since, $2) <= $4 then $2 else disabled_at end
+							 where id = $1 returning coalesce(disabled_at = $2, false)`,
+		endpointID, a.At, domain.WebhookDisableAfter, a.At.Add(-domain.WebhookFailingFor), domain.WebhookDisabled, domain.WebhookActive).
+		Scan(&disabled)
+	if err != nil {
+		return false, err
+	}
+	return disabled, tx.Commit()
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -1,47 +0,0 @@
-package main
-
-import (
-	"flag"
-	"fmt"
-	"log"
-)
-
-func seedAccount(store Storage, fname, lname, pw string) *Account {
-	acc, err := NewAccount(fname, lname, pw)
-	if err != nil {
-		log.Fatal(err)
-	}
-	if err := store.CreateAccount(acc); err != nil {
-		log.Fatal(err)
-	}
-	fmt.Println("new account", acc.Number)
-	return acc
-}
-
-func seedAccounts(s Storage) {
-	seedAccount(s, "anthony", "GG", "hunter888")
-}
-
-// 8498081
-func main() {
-	seed := flag.Bool("seed", false, "seed the db")
-	flag.Parse()
-
-	store, err := NewPostgresStore()
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = store.Init()
-	if err != nil {
-		log.Fatalf("%v", err)
-	}
-	fmt.Printf("%+v\n", store)
-
-	if *seed {
-		println("Seeding the database")
-		seedAccounts(store)
-	}
-
-	server := NewAPIServer(":3000", store)
-	server.Run()
-}
@@ -1,129 +0,0 @@
-package main
-
-import (
-	"database/sql"
-	"fmt"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
-	"os"
-)
-
-type Storage interface {
-	CreateAccount(account *Account) error
-	DeleteAccount(int) error
-	UpdateAccount(account *Account) error
-	GetAccount() ([]*Account, error)
-	GetAccountById(id int) (*Account, error)
-	GetAccountByNumber(number int) (*Account, error)
-}
-
-type PostgresStore struct {
-	db *sql.DB
-}
-
-func NewPostgresStore() (*PostgresStore, error) {
-	err := godotenv.Load(".env")
-	if err != nil {
-		return nil, err
-	}
-	dbCon, err := sql.Open("postgres", os.Getenv("POSTGRES_URL"))
-	if err != nil {
-		return nil, err
-	}
-	err = dbCon.Ping()
-	if err != nil {
-		return nil, err
-	}
-	fmt.Println("Successful connected to DB")
-	return &PostgresStore{dbCon}, nil
-}
-
-func (s *PostgresStore) Init() error {
-	return s.CreateAccountTable()
-}
-
-func (s *PostgresStore) CreateAccountTable() error {
-	query := `create table if not exists account (
-    			id serial primary key,
-                first_name varchar(50),
-    			last_name varchar(50),
-    			number serial,
-    			encrypted_password varchar(500),
-    			balance serial,
-    			created_at timestamp
-				)`
-	_, err := s.db.Exec(query)
-	return err
-}
-
-func (s *PostgresStore) CreateAccount(account *Account) error {
-	query := `insert into account 
-							 (first_name,last_name,number,encrypted_password,balance,created_at) 
-								values ($1,$2,$3,$4,$5,$6)`
-	_, err := s.db.Query(query, account.FirstName, account.LastName, account.Number, account.EncryptedPassword, account.Balance, account.CreatedAt)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *PostgresStore) UpdateAccount(account *Account) error {
-	return nil
-}
-
-func (s *PostgresStore) DeleteAccount(id int) error {
-	_, err := s.db.Query("delete from account where id = $1", id)
-	return err
-}
-
-func (s *PostgresStore) GetAccountById(id int) (*Account, error) {
-	rows, err := s.db.Query("select * from account where id = $1", id)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		return scanIntoAccount(rows)
-	}
-	return nil, fmt.Errorf("account %d not found", id)
-}
-
-func (s *PostgresStore) GetAccount() ([]*Account, error) {
-	rows, err := s.db.Query("select * from account")
-	if err != nil {
-		return nil, err
-	}
-	accounts := []*Account{}
-	for rows.Next() {
-		account, err := scanIntoAccount(rows)
-		if err != nil {
-			return nil, err
-		}
-		accounts = append(accounts, account)
-	}
-	return accounts, nil
-}
-
-func scanIntoAccount(rows *sql.Rows) (*Account, error) {
-	account := new(Account)
-	err := rows.Scan(
-		&account.ID,
-		&account.FirstName,
-		&account.LastName,
-		&account.Number,
-		&account.EncryptedPassword,
-		&account.Balance,
-		&account.CreatedAt)
-	return account, err
-}
-
-func (s *PostgresStore) GetAccountByNumber(number int) (*Account, error) {
-	rows, err := s.db.Query("select * from account where number = $1", number)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		return scanIntoAccount(rows)
-	}
-	return nil, fmt.Errorf("account number %d not found", number)
-}
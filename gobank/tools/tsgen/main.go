@@ -0,0 +1,376 @@
+// tsgen writes a TypeScript client for an OpenAPI 3.1 document, the one
+// `gobank openapi` generates:
+//
+//	go run ./tools/tsgen -o dist/gobank-client.ts dist/openapi.json
+//
+// Every component schema becomes a type and every operation a method of
+// GobankClient named after its operationId. The client authenticates with a
+// JWT or the admin token; signed API key requests are left to server side
+// code, the Go client does those.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Document struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Parameters  []Parameter           `json:"parameters"`
+	RequestBody *Body                 `json:"requestBody"`
+	Responses   map[string]*Body      `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type Body struct {
+	Content map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	Description          string             `json:"description"`
+}
+
+// types accepts both "type": "string" and "type": ["string", "number"].
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+func main() {
+	out := flag.String("o", "", "output file, stdout when empty")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: tsgen [-o file.ts] openapi.json")
+	}
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(&doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(doc *Document) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by tsgen from the %s %s OpenAPI document. DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		s := doc.Components.Schemas[name]
+		if s.Description != "" {
+			fmt.Fprintf(&b, "/** %s */\n", s.Description)
+		}
+		fmt.Fprintf(&b, "export type %s = %s;\n\n", name, tsType(s, ""))
+	}
+	b.WriteString(runtime)
+
+	for _, p := range sortedKeys(doc.Paths) {
+		for _, method := range []string{"get", "post", "put", "patch", "delete"} {
+			op, ok := doc.Paths[p][method]
+			if !ok {
+				continue
+			}
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", strings.ToUpper(method), p)
+			}
+			writeMethod(&b, strings.ToUpper(method), p, op)
+		}
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+var pathParam = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+func writeMethod(b *bytes.Buffer, method, path string, op *Operation) {
+	var args, query []string
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			args = append(args, fmt.Sprintf("%s: %s", p.Name, tsType(p.Schema, "  ")))
+		case "query":
+			query = append(query, fmt.Sprintf("%s?: %s", p.Name, tsType(p.Schema, "  ")))
+		}
+	}
+	hasBody, rawBody := op.RequestBody != nil, false
+	if hasBody {
+		if c, ok := op.RequestBody.Content["application/json"]; ok && c.Schema != nil {
+			args = append(args, "body: "+tsType(c.Schema, "  "))
+		} else {
+			rawBody = true
+			args = append(args, "body: string | Blob", "contentType: "+unionOf(quoteAll(sortedKeys(op.RequestBody.Content))))
+		}
+	}
+	if len(query) > 0 {
+		args = append(args, "query: { "+strings.Join(query, "; ")+" } = {}")
+	}
+
+	result, decode := "Response", false
+	for _, status := range sortedKeys(op.Responses) {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		if c, ok := op.Responses[status].Content["application/json"]; ok && c.Schema != nil {
+			result, decode = tsType(c.Schema, "  "), true
+		}
+	}
+	auth := `""`
+	for _, sec := range op.Security {
+		if _, ok := sec["admin"]; ok {
+			auth = `"admin"`
+		} else if _, ok := sec["jwt"]; ok {
+			auth = `"account"`
+		}
+	}
+
+	tsPath := "`" + pathParam.ReplaceAllString(path, "$${encodeURIComponent($1)}") + "`"
+	opts := []string{"auth: " + auth}
+	if len(query) > 0 {
+		opts = append(opts, "query")
+	}
+	if hasBody {
+		opts = append(opts, "body")
+	}
+	if rawBody {
+		opts = append(opts, "contentType")
+	}
+
+	if op.Summary != "" {
+		fmt.Fprintf(b, "  /** %s */\n", op.Summary)
+	}
+	call := fmt.Sprintf("this.request(%q, %s, { %s })", method, tsPath, strings.Join(opts, ", "))
+	if decode {
+		fmt.Fprintf(b, "  async %s(%s): Promise<%s> {\n    return (await %s).json();\n  }\n\n", op.OperationID, strings.Join(args, ", "), result, call)
+	} else {
+		fmt.Fprintf(b, "  %s(%s): Promise<Response> {\n    return %s;\n  }\n\n", op.OperationID, strings.Join(args, ", "), call)
+	}
+}
+
+// tsType renders a schema as a TypeScript type, indent is the indentation of
+// the line it starts on.
+func tsType(s *Schema, indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+	if s.Ref != "" {
+		return s.Ref[strings.LastIndexByte(s.Ref, '/')+1:]
+	}
+	if len(s.Enum) > 0 {
+		var lits []string
+		for _, v := range s.Enum {
+			lit, _ := json.Marshal(v)
+			lits = append(lits, string(lit))
+		}
+		return unionOf(lits)
+	}
+	if len(s.OneOf) > 0 || (len(s.AnyOf) > 0 && len(s.Properties) == 0) {
+		var members []string
+		for _, m := range append(s.OneOf, s.AnyOf...) {
+			members = append(members, tsType(m, indent))
+		}
+		return unionOf(members)
+	}
+	var members []string
+	for _, t := range s.Type {
+		members = append(members, tsPrimitive(t, s, indent))
+	}
+	if len(members) == 0 {
+		if len(s.Properties) > 0 {
+			return tsPrimitive("object", s, indent)
+		}
+		return "unknown"
+	}
+	return unionOf(members)
+}
+
+func tsPrimitive(t string, s *Schema, indent string) string {
+	switch t {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		item := tsType(s.Items, indent)
+		if strings.ContainsAny(item, " |") {
+			return "Array<" + item + ">"
+		}
+		return item + "[]"
+	case "object":
+		if len(s.Properties) == 0 {
+			var extra Schema
+			if json.Unmarshal(s.AdditionalProperties, &extra) == nil {
+				return "Record<string, " + tsType(&extra, indent) + ">"
+			}
+			return "Record<string, unknown>"
+		}
+		required := map[string]bool{}
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, name := range sortedKeys(s.Properties) {
+			opt := "?"
+			if required[name] {
+				opt = ""
+			}
+			fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, name, opt, tsType(s.Properties[name], indent+"  "))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	}
+	return "unknown"
+}
+
+func unionOf(members []string) string {
+	return strings.Join(members, " | ")
+}
+
+func quoteAll(s []string) []string {
+	q := make([]string, len(s))
+	for i, v := range s {
+		q[i] = fmt.Sprintf("%q", v)
+	}
+	return q
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runtime is the hand written part of the client, the generated methods
+// are appended to GobankClient.
+const runtime = `export class RequestError extends Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+    readonly field?: string,
+    readonly violations?: SchemaViolation[],
+  ) {
+    super(message);
+  }
+}
+
+export interface ClientOptions {
+  /** JWT from login, sent as x-jwt-token. */
+  token?: string;
+  /** Sent as x-admin-token to the admin endpoints. */
+  adminToken?: string;
+  /** Tenant slug, sent as X-Tenant. */
+  tenant?: string;
+  fetch?: typeof fetch;
+}
+
+type RequestOptions = {
+  auth: "" | "account" | "admin";
+  query?: Record<string, string | number | undefined>;
+  body?: unknown;
+  contentType?: string;
+};
+
+export class GobankClient {
+  constructor(
+    private readonly baseUrl: string,
+    private readonly options: ClientOptions = {},
+  ) {}
+
+  setToken(token: string): void {
+    this.options.token = token;
+  }
+
+  private async request(method: string, path: string, opts: RequestOptions): Promise<Response> {
+    const url = new URL(this.baseUrl.replace(/\/$/, "") + path);
+    for (const [k, v] of Object.entries(opts.query ?? {})) {
+      if (v !== undefined && v !== "") url.searchParams.set(k, String(v));
+    }
+    const headers: Record<string, string> = { Accept: "application/json" };
+    if (this.options.tenant) headers["X-Tenant"] = this.options.tenant;
+    if (opts.auth === "account" && this.options.token) headers["x-jwt-token"] = this.options.token;
+    if (opts.auth === "admin" && this.options.adminToken) headers["x-admin-token"] = this.options.adminToken;
+    let body: BodyInit | undefined;
+    if (opts.contentType) {
+      headers["Content-Type"] = opts.contentType;
+      body = opts.body as BodyInit;
+    } else if (opts.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+      body = JSON.stringify(opts.body);
+    }
+    const res = await (this.options.fetch ?? fetch)(url, { method, headers, body });
+    if (!res.ok) {
+      const err = await res.json().catch(() => ({}));
+      throw new RequestError(res.status, err.code ?? "", err.error ?? res.statusText, err.field, err.violations);
+    }
+    return res;
+  }
+
+`
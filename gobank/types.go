@@ -1,56 +0,0 @@
-package main
-
-import (
-	"golang.org/x/crypto/bcrypt"
-	"math/rand"
-	"time"
-)
-
-type LoginResponse struct {
-	Number int64  `json:"number"`
-	Token  string `json:"token"`
-}
-
-type LoginRequest struct {
-	Number   int64  `json:"number"`
-	Password string `json:"password"`
-}
-
-type TransferAccount struct {
-	ToAccount int `json:"toAccount"`
-	Amount    int `json:"amount"`
-}
-
-type Account struct {
-	ID                int       `json:"id"`
-	FirstName         string    `json:"firstName"`
-	LastName          string    `json:"lastName"`
-	Number            int64     `json:"number"`
-	EncryptedPassword string    `-`
-	Balance           int64     `json:"balance"`
-	CreatedAt         time.Time `json:"createdAt"`
-}
-
-func (a *Account) ValidatePassword(pw string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(a.EncryptedPassword), []byte(pw)) == nil
-}
-
-type CreateAccountRequest struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Password  string `json:"password"`
-}
-
-func NewAccount(firstName, lastName, password string) (*Account, error) {
-	encpw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
-	}
-	return &Account{
-		FirstName:         firstName,
-		LastName:          lastName,
-		EncryptedPassword: string(encpw),
-		Number:            int64(rand.Intn(10000000)),
-		CreatedAt:         time.Now().UTC(),
-	}, nil
-}